@@ -0,0 +1,64 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ClusterConfiguration is the Schema for the clusterconfigurations API, it holds the cluster-level settings of the
+// instance groups referencing it with spec.clusterRef
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=clusterconfigurations,scope=Cluster,shortName=clustercfg
+// +kubebuilder:printcolumn:name="Cluster Name",type="string",JSONPath=".spec.clusterName",description="name of the EKS cluster"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="time passed since clusterconfiguration creation"
+type ClusterConfiguration struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec ClusterConfigurationSpec `json:"spec"`
+}
+
+// ClusterConfigurationList contains a list of ClusterConfiguration
+// +kubebuilder:object:root=true
+type ClusterConfigurationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterConfiguration `json:"items"`
+}
+
+// ClusterConfigurationSpec defines the cluster-level settings of instance groups of the eks provisioner, the cluster
+// name and subnets are used when the instance group does not set them, and the security groups are added to those of
+// the instance group
+type ClusterConfigurationSpec struct {
+	ClusterName    string   `json:"clusterName"`
+	Subnets        []string `json:"subnets,omitempty"`
+	SecurityGroups []string `json:"securityGroups,omitempty"`
+	// Bootstrap overrides the cluster endpoint and certificate authority passed to the bootstrap script of nodes
+	Bootstrap *ClusterBootstrapSpec `json:"bootstrap,omitempty"`
+}
+
+// ClusterBootstrapSpec defines the cluster data which nodes are bootstrapped with instead of describing the cluster
+type ClusterBootstrapSpec struct {
+	// APIServerEndpoint is the endpoint of the cluster's API server
+	APIServerEndpoint string `json:"apiServerEndpoint,omitempty"`
+	// CertificateAuthority is the base64 encoded certificate authority data of the cluster
+	CertificateAuthority string `json:"certificateAuthority,omitempty"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterConfiguration{}, &ClusterConfigurationList{})
+}
@@ -16,14 +16,22 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"crypto/sha256"
+	"encoding/json"
 	"fmt"
 	"reflect"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/keikoproj/instance-manager/controllers/common"
 	awsprovider "github.com/keikoproj/instance-manager/controllers/providers/aws"
 
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/ghodss/yaml"
 	"github.com/pkg/errors"
+	"github.com/robfig/cron/v3"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -47,8 +55,11 @@ const (
 	ReconcileModified  ReconcileState = "ReconcileModified"
 
 	// End States
-	ReconcileReady ReconcileState = "Ready"
-	ReconcileErr   ReconcileState = "Error"
+	ReconcileReady     ReconcileState = "Ready"
+	ReconcileErr       ReconcileState = "Error"
+	ReconcileSuspended ReconcileState = "Suspended"
+	ReconcileDeferred  ReconcileState = "Deferred"
+	ReconcilePlanned   ReconcileState = "Planned"
 
 	// Userdata bootstrap stages
 	PreBootstrapStage  = "PreBootstrap"
@@ -63,20 +74,144 @@ const (
 	EKSManagedProvisionerName = "eks-managed"
 	EKSFargateProvisionerName = "eks-fargate"
 
-	NodesReady InstanceGroupConditionType = "NodesReady"
+	// MaxFargateSelectors is the number of selectors a fargate profile can have
+	MaxFargateSelectors = 5
+	// MaxFargateSelectorLabels is the number of labels a selector of a fargate profile can have
+	MaxFargateSelectorLabels = 5
+
+	NodesReady       InstanceGroupConditionType = "NodesReady"
+	Degraded         InstanceGroupConditionType = "Degraded"
+	NodeGroupHealthy InstanceGroupConditionType = "NodeGroupHealthy"
+	Deprecated       InstanceGroupConditionType = "Deprecated"
+	// ZonesImbalanced is true while the instances have been imbalanced across availability zones for longer than
+	// the threshold of the instance group
+	ZonesImbalanced InstanceGroupConditionType = "ZonesImbalanced"
+	// ReconcileFailed is true while reconciles fail, its reason is the class of the error
+	ReconcileFailed InstanceGroupConditionType = "ReconcileFailed"
 
 	ForbidConcurrencyPolicy  = "forbid"
 	AllowConcurrencyPolicy   = "allow"
 	ReplaceConcurrencyPolicy = "replace"
 
+	FailPDBStallPolicy = "Fail"
+	WaitPDBStallPolicy = "Wait"
+
+	RollingUpgradeAPIVersion     = "upgrademgr.keikoproj.io/v1alpha1"
+	RollingUpgradeKind           = "RollingUpgrade"
+	RollingUpgradeCRDName        = "rollingupgrades"
+	RollingUpgradeStatusJSONPath = ".status.currentStatus"
+	RollingUpgradeStatusSuccess  = "completed"
+	RollingUpgradeStatusFailure  = "error"
+
+	ManagedDesiredCapacityPolicy     = "Managed"
+	IgnoreDesiredCapacityPolicy      = "Ignore"
+	InitialOnlyDesiredCapacityPolicy = "InitialOnly"
+
+	RetainReplacementPolicy   = "Retain"
+	RecreateReplacementPolicy = "Recreate"
+
+	CriticalReconcilePriority = "Critical"
+	HighReconcilePriority     = "High"
+	NormalReconcilePriority   = "Normal"
+	LowReconcilePriority      = "Low"
+
+	DefaultReconcilePolicy = "Default"
+	StrictReconcilePolicy  = "Strict"
+
+	OldestFirstRotationOrder = "OldestFirst"
+	ZoneByZoneRotationOrder  = "ZoneByZone"
+
 	FileSystemTypeXFS  = "xfs"
 	FileSystemTypeEXT4 = "ext4"
 
+	InstanceStorePolicyRaid0 = "RAID0"
+
+	EC2HealthCheckType = "EC2"
+	ELBHealthCheckType = "ELB"
+
+	// ProtectionReasonNodeAnnotation is the reason of instances whose nodes are annotated for scale-in protection
+	ProtectionReasonNodeAnnotation = "NodeAnnotation"
+	// ProtectionReasonNewInstancesProtected is the reason of instances of scaling groups which protect new instances
+	ProtectionReasonNewInstancesProtected = "NewInstancesProtected"
+	// ProtectionReasonExternal is the reason of instances which were protected outside of the controller
+	ProtectionReasonExternal = "External"
+
+	AZRebalanceProcess  = "AZRebalance"
+	AZRebalanceEnabled  = "enabled"
+	AZRebalanceDisabled = "disabled"
+
+	DefaultHealthCheckGracePeriodSeconds = 300
+
+	EKSBootstrapProvider            = "eks-bootstrap"
+	BottlerocketBootstrapProvider   = "bottlerocket"
+	NodeadmBootstrapProvider        = "nodeadm"
+	CustomTemplateBootstrapProvider = "custom-template"
+
 	LifecycleHookResultAbandon           = "ABANDON"
 	LifecycleHookResultContinue          = "CONTINUE"
 	LifecycleHookTransitionLaunch        = "Launch"
 	LifecycleHookTransitionTerminate     = "Terminate"
 	LifecycleHookDefaultHeartbeatTimeout = 300
+
+	DefaultDrainTimeoutSeconds = 300
+
+	DefaultAlarmComparisonOperator = "LessThanThreshold"
+	DefaultAlarmStatistic          = "Average"
+	DefaultAlarmEvaluationPeriods  = 5
+
+	DefaultNotReadyThreshold            = 10 * time.Minute
+	DefaultMaxUnhealthyNodeReplacements = 1
+
+	DefaultNodeJoinTimeout = 15 * time.Minute
+
+	DefaultZoneImbalanceMaxSkew   = 1
+	DefaultZoneImbalanceThreshold = 30 * time.Minute
+
+	DefaultVerificationTimeoutSeconds = 30
+
+	DefaultPreDrainTimeoutSeconds = 300
+
+	DefaultKubeconfigSecretKey = "kubeconfig"
+
+	// MachinePoolKind is the kind of the Cluster API resource which owns instance groups reconciled as its
+	// infrastructure
+	MachinePoolKind = "MachinePool"
+
+	DeletionProtectionAnnotationKey = "instancemgr.keikoproj.io/deletion-protection"
+	DeletionProtectionEnabled       = "enabled"
+
+	SuspendAnnotationKey = "instancemgr.keikoproj.io/suspend"
+
+	// DryRunAnnotationKey plans the changes to cloud resources without making them when set to 'true'
+	DryRunAnnotationKey = "instancemgr.keikoproj.io/dry-run"
+
+	// ExportAnnotationKey exports the resolved cloud resources of an instance group in the given format to a
+	// ConfigMap, the annotation is removed once the export is written
+	ExportAnnotationKey        = "instancemgr.keikoproj.io/export"
+	ExportTimeAnnotationKey    = "instancemgr.keikoproj.io/export-time"
+	CloudFormationExportFormat = "cloudformation"
+	TerraformExportFormat      = "terraform"
+
+	// RotateAnnotationKey forces a new launch configuration and a rotation of all nodes without drift, either once
+	// with the value 'true', which is removed when handled, or whenever a timestamp value changes
+	RotateAnnotationKey = "instancemgr.keikoproj.io/rotate"
+
+	// ForceDeleteAnnotationKey overrides the deletion guard of protected workloads when set to 'true'
+	ForceDeleteAnnotationKey = "instancemgr.keikoproj.io/force-delete"
+
+	// ExcludeFromRotationAnnotationKey is a node annotation, nodes annotated with 'true' are not rotated by the
+	// controller and are left for manual replacement
+	ExcludeFromRotationAnnotationKey = "instancemgr.keikoproj.io/exclude-from-rotation"
+
+	// RollbackAnnotationKey rolls the configuration of an instance group back to a revision of its history, the
+	// value is a revision number or 'previous', the annotation is removed once the rollback is applied
+	RollbackAnnotationKey   = "instancemgr.keikoproj.io/rollback"
+	PreviousRollbackRequest = "previous"
+
+	// LogLevelAnnotationKey sets the log level of an instance group's reconciles, 'debug' writes the debug log lines
+	// of the instance group while the controller logs at the info level
+	LogLevelAnnotationKey = "instancemgr.keikoproj.io/log-level"
+	LogLevelDebug         = "debug"
 )
 
 var (
@@ -95,9 +230,44 @@ var (
 	}
 
 	AllowedFileSystemTypes            = []string{FileSystemTypeXFS, FileSystemTypeEXT4}
+	AllowedInstanceStorePolicies      = []string{InstanceStorePolicyRaid0}
+	AllowedHealthCheckTypes           = []string{EC2HealthCheckType, ELBHealthCheckType}
+	AllowedAZRebalancePolicies        = []string{AZRebalanceEnabled, AZRebalanceDisabled}
+	BootstrapProviders                = []string{EKSBootstrapProvider, BottlerocketBootstrapProvider, NodeadmBootstrapProvider, CustomTemplateBootstrapProvider}
+	AllowedReadinessGateStatuses      = []string{string(corev1.ConditionTrue), string(corev1.ConditionFalse), string(corev1.ConditionUnknown)}
+	AllowedPDBStallPolicies           = []string{FailPDBStallPolicy, WaitPDBStallPolicy}
+	AllowedRotationOrders             = []string{OldestFirstRotationOrder, ZoneByZoneRotationOrder}
+	AllowedReconcilePriorities        = []string{CriticalReconcilePriority, HighReconcilePriority, NormalReconcilePriority, LowReconcilePriority}
+	AllowedExportFormats              = []string{CloudFormationExportFormat, TerraformExportFormat}
+	AllowedReconcilePolicies          = []string{DefaultReconcilePolicy, StrictReconcilePolicy}
+	AllowedDesiredCapacityPolicies    = []string{ManagedDesiredCapacityPolicy, IgnoreDesiredCapacityPolicy, InitialOnlyDesiredCapacityPolicy}
+	AllowedReplacementPolicies        = []string{RetainReplacementPolicy, RecreateReplacementPolicy}
+	AllowedRollingUpgradeTypes        = []string{"randomUpdate", "uniformAcrossAzUpdate"}
+	AllowedRollingUpgradeModes        = []string{"eager", "lazy"}
 	LifecycleHookAllowedTransitions   = []string{LifecycleHookTransitionLaunch, LifecycleHookTransitionTerminate}
 	LifecycleHookAllowedDefaultResult = []string{LifecycleHookResultAbandon, LifecycleHookResultContinue}
 	log                               = ctrl.Log.WithName("v1alpha1")
+
+	// SecretReferencePattern matches a reference to a key of a secret in the instance group's namespace within userData
+	// stages, e.g. {{ secret "registry-credentials" "password" }}
+	SecretReferencePattern = regexp.MustCompile(`\{\{\s*secret\s+"([^"]+)"\s+"([^"]+)"\s*\}\}`)
+
+	// SharedRoleNamePattern matches the characters which are allowed in IAM role names
+	SharedRoleNamePattern = regexp.MustCompile(`^[\w+=,.@-]+$`)
+
+	// SpotPricePercentagePattern matches spot prices which are a percentage of the on-demand price
+	SpotPricePercentagePattern = regexp.MustCompile(`^([0-9]+(\.[0-9]+)?)%$`)
+
+	// AlarmNamePattern matches the names of alarms, which are appended to the resource name of the instance group
+	AlarmNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+	// FargateNamespacePattern matches the namespaces of fargate profile selectors, which may contain the wildcards
+	// * and ?, e.g. team-* or prod-?
+	FargateNamespacePattern = regexp.MustCompile(`^[a-z0-9*?]([-a-z0-9*?]*[a-z0-9*?])?$`)
+
+	// FargateLabelPattern matches the label keys and values of fargate profile selectors, which may contain the
+	// wildcards * and ?
+	FargateLabelPattern = regexp.MustCompile(`^([a-z0-9.-]+/)?[A-Za-z0-9*?]([-A-Za-z0-9_.*?]*[A-Za-z0-9*?])?$`)
 )
 
 // InstanceGroup is the Schema for the instancegroups API
@@ -133,16 +303,165 @@ type AwsUpgradeStrategy struct {
 	Type              string                 `json:"type,omitempty"`
 	CRDType           *CRDUpdateStrategy     `json:"crd,omitempty"`
 	RollingUpdateType *RollingUpdateStrategy `json:"rollingUpdate,omitempty"`
+	Drain             *DrainSpec             `json:"drain,omitempty"`
+}
+
+// DrainSpec defines how nodes are drained by the controller before they are removed
+type DrainSpec struct {
+	Disabled       bool  `json:"disabled,omitempty"`
+	TimeoutSeconds int64 `json:"timeoutSeconds,omitempty"`
+	Force          bool  `json:"force,omitempty"`
+	// GracePeriodSeconds overrides the termination grace period of evicted pods
+	GracePeriodSeconds *int64 `json:"gracePeriodSeconds,omitempty"`
+	// SkipDaemonSets ignores DaemonSet pods, otherwise nodes running them are not drained (default true)
+	SkipDaemonSets *bool `json:"skipDaemonSets,omitempty"`
+	// DeleteEmptyDirData evicts pods using emptyDir volumes, otherwise nodes running them are not drained (default true)
+	DeleteEmptyDirData *bool `json:"deleteEmptyDirData,omitempty"`
+	// PDBStallPolicy is either Fail or Wait, and defines whether draining fails or keeps waiting when evictions are
+	// still refused by a PodDisruptionBudget once the timeout expires (default Fail)
+	PDBStallPolicy string `json:"pdbStallPolicy,omitempty"`
+	// PreDrain runs before each node is cordoned and drained
+	PreDrain *PreDrainHook `json:"preDrain,omitempty"`
+}
+
+// PreDrainHook is either an annotation which is added to the node, a URL which is called, or a node condition which is
+// waited for, the node is drained once the hook completes or times out
+type PreDrainHook struct {
+	// Annotation is added to the node with the value 'requested', the hook completes when it is removed
+	Annotation string `json:"annotation,omitempty"`
+	// URL receives a POST request with the node, the hook completes on a 2xx response
+	URL string `json:"url,omitempty"`
+	// Condition is a node condition which must have the required status for the hook to complete
+	Condition *NodeReadinessGate `json:"condition,omitempty"`
+	// TimeoutSeconds is how long to wait for the hook to complete before the node is drained
+	TimeoutSeconds int64 `json:"timeoutSeconds,omitempty"`
+}
+
+func (h *PreDrainHook) Validate() error {
+	var configured int
+	for _, ok := range []bool{!common.StringEmpty(h.Annotation), !common.StringEmpty(h.URL), h.Condition != nil} {
+		if ok {
+			configured++
+		}
+	}
+	if configured != 1 {
+		return errors.Errorf("validation failed, exactly one of 'strategy.drain.preDrain' annotation, url or condition is required")
+	}
+	if h.TimeoutSeconds < 0 {
+		return errors.Errorf("validation failed, 'strategy.drain.preDrain.timeoutSeconds' must be a positive value")
+	}
+	if h.Condition != nil {
+		if h.Condition.Type == "" {
+			return errors.Errorf("validation failed, 'strategy.drain.preDrain.condition' type is a required parameter")
+		}
+		if h.Condition.Status == "" {
+			h.Condition.Status = corev1.ConditionTrue
+		}
+		if !common.ContainsString(AllowedReadinessGateStatuses, string(h.Condition.Status)) {
+			return errors.Errorf("validation failed, pre-drain condition '%v' status must be one of %+v", h.Condition.Type, AllowedReadinessGateStatuses)
+		}
+	}
+	return nil
+}
+
+func (h *PreDrainHook) GetTimeoutSeconds() int64 {
+	if h.TimeoutSeconds == 0 {
+		return DefaultPreDrainTimeoutSeconds
+	}
+	return h.TimeoutSeconds
 }
 
 type RollingUpdateStrategy struct {
 	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+	// ReadinessGates are node conditions which new nodes must satisfy, in addition to being ready, before the next
+	// batch of instances is rotated
+	ReadinessGates []NodeReadinessGate `json:"readinessGates,omitempty"`
+	// Verification runs after each batch of instances is rotated and its nodes are ready, the rotation is halted if
+	// it fails
+	Verification *VerificationHook `json:"verification,omitempty"`
+	// Order is the order in which instances are rotated, either OldestFirst, or ZoneByZone which rotates one
+	// availability zone at a time, oldest first within the zone (default scaling group order)
+	Order string `json:"order,omitempty"`
+	// CooldownSeconds is the minimum time between the termination of a batch and the next batch
+	CooldownSeconds int64 `json:"cooldownSeconds,omitempty"`
+}
+
+// VerificationHook is either a Job which is created in the instance group's namespace, or a URL which is called
+type VerificationHook struct {
+	// Job is a batch/v1 Job in yaml, templated with the instance group, it succeeds when the Job completes
+	Job string `json:"job,omitempty"`
+	// URL receives a POST request with the instance group and batch, it succeeds on a 2xx response
+	URL string `json:"url,omitempty"`
+	// TimeoutSeconds is the timeout of the URL request
+	TimeoutSeconds int64 `json:"timeoutSeconds,omitempty"`
+}
+
+func (h *VerificationHook) Validate() error {
+	if common.StringEmpty(h.Job) == common.StringEmpty(h.URL) {
+		return errors.Errorf("validation failed, exactly one of 'strategy.rollingUpdate.verification' job or url is required")
+	}
+	if h.TimeoutSeconds < 0 {
+		return errors.Errorf("validation failed, 'strategy.rollingUpdate.verification.timeoutSeconds' must be a positive value")
+	}
+	if h.TimeoutSeconds == 0 {
+		h.TimeoutSeconds = DefaultVerificationTimeoutSeconds
+	}
+	return nil
+}
+
+// NodeReadinessGate is a node condition type and the status it must have
+type NodeReadinessGate struct {
+	Type   corev1.NodeConditionType `json:"type"`
+	Status corev1.ConditionStatus   `json:"status,omitempty"`
+}
+
+func (s *RollingUpdateStrategy) Validate() error {
+	for i, gate := range s.ReadinessGates {
+		if gate.Type == "" {
+			return errors.Errorf("validation failed, 'strategy.rollingUpdate.readinessGates' type is a required parameter")
+		}
+		if gate.Status == "" {
+			s.ReadinessGates[i].Status = corev1.ConditionTrue
+			continue
+		}
+		if !common.ContainsString(AllowedReadinessGateStatuses, string(gate.Status)) {
+			return errors.Errorf("validation failed, readiness gate '%v' status must be one of %+v", gate.Type, AllowedReadinessGateStatuses)
+		}
+	}
+	if s.Verification != nil {
+		if err := s.Verification.Validate(); err != nil {
+			return err
+		}
+	}
+	if s.Order != "" && !common.ContainsEqualFold(AllowedRotationOrders, s.Order) {
+		return errors.Errorf("validation failed, 'strategy.rollingUpdate.order' must be one of %+v", AllowedRotationOrders)
+	}
+	if s.CooldownSeconds < 0 {
+		return errors.Errorf("validation failed, 'strategy.rollingUpdate.cooldownSeconds' must be a positive value")
+	}
+	return nil
 }
 
 func (s *RollingUpdateStrategy) GetMaxUnavailable() *intstr.IntOrString {
 	return s.MaxUnavailable
 }
 
+func (s *RollingUpdateStrategy) GetReadinessGates() []NodeReadinessGate {
+	return s.ReadinessGates
+}
+
+func (s *RollingUpdateStrategy) GetVerification() *VerificationHook {
+	return s.Verification
+}
+
+func (s *RollingUpdateStrategy) GetOrder() string {
+	return s.Order
+}
+
+func (s *RollingUpdateStrategy) GetCooldownSeconds() int64 {
+	return s.CooldownSeconds
+}
+
 func (s *RollingUpdateStrategy) SetMaxUnavailable(value *intstr.IntOrString) {
 	s.MaxUnavailable = value
 }
@@ -154,6 +473,43 @@ type CRDUpdateStrategy struct {
 	StatusJSONPath      string `json:"statusJSONPath,omitempty"`
 	StatusSuccessString string `json:"statusSuccessString,omitempty"`
 	StatusFailureString string `json:"statusFailureString,omitempty"`
+	// RollingUpgrade submits an upgrade-manager RollingUpgrade for the scaling group instead of the spec document
+	RollingUpgrade *RollingUpgradeSpec `json:"rollingUpgrade,omitempty"`
+}
+
+// RollingUpgradeSpec is the spec of an upgrade-manager RollingUpgrade, the scaling group name is set by the controller
+type RollingUpgradeSpec struct {
+	// +kubebuilder:validation:Minimum=0
+	NodeIntervalSeconds int64 `json:"nodeIntervalSeconds,omitempty"`
+	// +kubebuilder:validation:Minimum=0
+	PostDrainDelaySeconds int64                       `json:"postDrainDelaySeconds,omitempty"`
+	IgnoreDrainFailures   bool                        `json:"ignoreDrainFailures,omitempty"`
+	ForceRefresh          bool                        `json:"forceRefresh,omitempty"`
+	PreDrain              *RollingUpgradeScript       `json:"preDrain,omitempty"`
+	PostDrain             *RollingUpgradePostDrain    `json:"postDrain,omitempty"`
+	PostTerminate         *RollingUpgradeScript       `json:"postTerminate,omitempty"`
+	Strategy              *RollingUpgradeStrategySpec `json:"strategy,omitempty"`
+}
+
+type RollingUpgradeScript struct {
+	Script string `json:"script,omitempty"`
+}
+
+type RollingUpgradePostDrain struct {
+	Script string `json:"script,omitempty"`
+	// +kubebuilder:validation:Minimum=0
+	WaitSeconds    int64  `json:"waitSeconds,omitempty"`
+	PostWaitScript string `json:"postWaitScript,omitempty"`
+}
+
+type RollingUpgradeStrategySpec struct {
+	// +kubebuilder:validation:Enum=randomUpdate;uniformAcrossAzUpdate
+	Type string `json:"type,omitempty"`
+	// +kubebuilder:validation:Enum=eager;lazy
+	Mode           string              `json:"mode,omitempty"`
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+	// +kubebuilder:validation:Minimum=0
+	DrainTimeout int64 `json:"drainTimeout,omitempty"`
 }
 
 // InstanceGroupSpec defines the schema of resource Spec
@@ -163,6 +519,51 @@ type InstanceGroupSpec struct {
 	EKSFargateSpec     *EKSFargateSpec    `json:"eks-fargate,omitempty"`
 	EKSSpec            *EKSSpec           `json:"eks,omitempty"`
 	AwsUpgradeStrategy AwsUpgradeStrategy `json:"strategy,omitempty"`
+	// Suspend stops all changes to cloud resources while discovery and status updates continue
+	Suspend bool `json:"suspend,omitempty"`
+	// ChangeWindows restricts changes to cloud resources to recurring windows, changes detected outside
+	// of a window are deferred until the next window opens
+	ChangeWindows []ChangeWindow `json:"changeWindows,omitempty"`
+	// ReconcilePriority orders reconciles when many instance groups are queued, groups with a higher priority are
+	// reconciled first
+	// +kubebuilder:validation:Enum=Critical;High;Normal;Low
+	ReconcilePriority string `json:"reconcilePriority,omitempty"`
+	// ReconcileInterval is the interval at which the instance group is reconciled while no changes are made to it,
+	// e.g. 30m, the controller keeps it within its configured minimum and maximum interval
+	ReconcileInterval string `json:"reconcileInterval,omitempty"`
+	// ReconcilePolicy controls how changes made to cloud resources outside of the controller are handled, Strict
+	// reverts changes to the size, tags and launch configuration of the scaling group at every reconcile
+	// +kubebuilder:validation:Enum=Default;Strict
+	ReconcilePolicy string `json:"reconcilePolicy,omitempty"`
+	// TemplateRef is the name of an InstanceGroupTemplate whose configuration is inherited by the instance group,
+	// values set on the instance group take precedence
+	TemplateRef string `json:"templateRef,omitempty"`
+	// ClusterRef is the name of a ClusterConfiguration which provides the cluster name, subnets, security groups and
+	// bootstrap settings of the instance group
+	ClusterRef string `json:"clusterRef,omitempty"`
+	// KubeconfigSecretRef refers to a Secret with the kubeconfig of the cluster the instance group's nodes join, when
+	// it is not the cluster the controller runs in
+	KubeconfigSecretRef *KubeconfigSecretReference `json:"kubeconfigSecretRef,omitempty"`
+	// ProviderIDList is set by the controller when the instance group is the infrastructure of a Cluster API
+	// MachinePool, it lists the provider IDs of the instances of the scaling group
+	ProviderIDList []string `json:"providerIDList,omitempty"`
+}
+
+// KubeconfigSecretReference refers to a kubeconfig in a Secret in the namespace of the instance group
+type KubeconfigSecretReference struct {
+	Name string `json:"name"`
+	// Key is the key of the kubeconfig in the Secret (default kubeconfig)
+	Key string `json:"key,omitempty"`
+}
+
+// ChangeWindow defines a recurring period of time in which changes to cloud resources are allowed
+type ChangeWindow struct {
+	// Schedule is a cron expression for the start of the window
+	Schedule string `json:"schedule"`
+	// Duration is the length of the window, e.g. 2h
+	Duration string `json:"duration"`
+	// TimeZone is the IANA time zone of the schedule, defaults to UTC
+	TimeZone string `json:"timeZone,omitempty"`
 }
 
 type EKSManagedSpec struct {
@@ -172,9 +573,19 @@ type EKSManagedSpec struct {
 }
 
 type EKSSpec struct {
-	MaxSize          int64             `json:"maxSize,omitempty"`
-	MinSize          int64             `json:"minSize,omitempty"`
-	EKSConfiguration *EKSConfiguration `json:"configuration"`
+	MaxSize int64 `json:"maxSize,omitempty"`
+	MinSize int64 `json:"minSize,omitempty"`
+	// DesiredCapacityPolicy controls the ownership of the scaling group's desired capacity, Managed resets it to minSize
+	// on every reconcile, InitialOnly sets it to minSize only when the scaling group is created, and Ignore never sets it
+	// so that it is left entirely to external scalers such as cluster-autoscaler (default InitialOnly)
+	// +kubebuilder:validation:Enum=Managed;Ignore;InitialOnly
+	DesiredCapacityPolicy string `json:"desiredCapacityPolicy,omitempty"`
+	// ReplacementPolicy controls changes which require a new scaling group, such as a new resource name, Retain keeps
+	// the existing scaling group, Recreate creates the replacement scaling group, migrates the nodes to it in batches
+	// and deletes the existing one (default Retain)
+	// +kubebuilder:validation:Enum=Retain;Recreate
+	ReplacementPolicy string            `json:"replacementPolicy,omitempty"`
+	EKSConfiguration  *EKSConfiguration `json:"configuration"`
 }
 
 type EKSConfiguration struct {
@@ -197,6 +608,137 @@ type EKSConfiguration struct {
 	ManagedPolicies             []string            `json:"managedPolicies,omitempty"`
 	MetricsCollection           []string            `json:"metricsCollection,omitempty"`
 	LifecycleHooks              []LifecycleHookSpec `json:"lifecycleHooks,omitempty"`
+	// ExistingInstanceProfileArn adopts an existing instance profile by ARN, including profiles with a path, the role of
+	// the profile is used and neither is created or deleted by the controller
+	ExistingInstanceProfileArn string `json:"instanceProfileArn,omitempty"`
+	// SharedRoleName is the name of a controller-created role and instance profile which is shared by all instance
+	// groups of the cluster with the same sharedRoleName, the role is deleted with the last instance group using it
+	SharedRoleName string `json:"sharedRoleName,omitempty"`
+	// NewInstancesProtectedFromScaleIn protects all new instances of the scaling group from scale-in, for groups
+	// whose instances are terminated by an external scheduler
+	NewInstancesProtectedFromScaleIn bool `json:"newInstancesProtectedFromScaleIn,omitempty"`
+	// HealthCheckType is the health check of the scaling group's instances, ELB also replaces instances which fail the
+	// health checks of the load balancers and target groups they are registered with, defaults to EC2
+	HealthCheckType string `json:"healthCheckType,omitempty"`
+	// HealthCheckGracePeriod is the number of seconds after an instance is launched before its health is checked, it is
+	// left to the scaling group unless it is set or the health check type is ELB, which defaults it to 300
+	HealthCheckGracePeriod *int64 `json:"healthCheckGracePeriod,omitempty"`
+	// InstanceStorePolicy configures the instance store volumes of the instance type, RAID0 combines all NVMe
+	// instance store volumes into a single array which backs the kubelet, docker and containerd data directories
+	InstanceStorePolicy string `json:"instanceStorePolicy,omitempty"`
+	// AutoUpgrade resolves the image from the EKS optimized image of the cluster version, when the cluster is upgraded
+	// the image is resolved again and nodes are rotated
+	AutoUpgrade bool `json:"autoUpgrade,omitempty"`
+	// ImagePipelineArn resolves the image from the latest available image of an EC2 Image Builder pipeline, when
+	// the pipeline builds a new image the nodes are rotated
+	ImagePipelineArn string `json:"imagePipelineArn,omitempty"`
+	// APIServerEndpoint and CertificateAuthority are passed to the bootstrap script, so that nodes do not describe
+	// the cluster when they boot
+	APIServerEndpoint    string `json:"apiServerEndpoint,omitempty"`
+	CertificateAuthority string `json:"certificateAuthority,omitempty"`
+	// BootstrapProvider renders the user data which bootstraps nodes, eks-bootstrap runs the bootstrap script of
+	// the EKS optimized image, bottlerocket and nodeadm render the settings of Bottlerocket and Amazon Linux 2023
+	// images, and custom-template renders bootstrapTemplate
+	BootstrapProvider string `json:"bootstrapProvider,omitempty"`
+	// BootstrapTemplate is the Go template of the user data of the custom-template bootstrap provider
+	BootstrapTemplate string `json:"bootstrapTemplate,omitempty"`
+	// ClusterCIDR is the service CIDR of the cluster, it is required by the nodeadm bootstrap provider
+	ClusterCIDR string `json:"clusterCIDR,omitempty"`
+	// Notifications sends notifications of the scaling group's activities to SNS topics
+	Notifications []NotificationSpec `json:"notifications,omitempty"`
+	// Alarms are CloudWatch alarms on the group metrics of the scaling group, they are created and deleted with it
+	Alarms []AlarmSpec `json:"alarms,omitempty"`
+	// CommitmentAdvisor reports the reserved instances and savings plans which cover the instance type, and the
+	// candidate instance types it could be replaced with, in the status
+	CommitmentAdvisor *CommitmentAdvisorSpec `json:"commitmentAdvisor,omitempty"`
+	// UnhealthyNodeReplacement replaces the instances of nodes which stay not ready, their nodes are drained before
+	// the instances are terminated and the scaling group launches their replacements
+	UnhealthyNodeReplacement *UnhealthyNodeReplacementSpec `json:"unhealthyNodeReplacement,omitempty"`
+	// BootstrapFailureDetection reports the instances which are in service but whose nodes did not join the cluster
+	// within the join timeout, and optionally terminates them so that the scaling group retries
+	BootstrapFailureDetection *BootstrapFailureDetectionSpec `json:"bootstrapFailureDetection,omitempty"`
+	// ZoneImbalance configures when the instances of the scaling group are imbalanced across availability zones, and
+	// optionally publishes a warning event when the imbalance is sustained
+	ZoneImbalance *ZoneImbalanceSpec `json:"zoneImbalance,omitempty"`
+	// AZRebalance is the policy of the scaling group's AZRebalance process, disabled suspends it so that instances are
+	// never terminated to rebalance zones and enabled resumes it, regardless of suspendProcesses. When it is unset the
+	// process is only suspended if it is in suspendProcesses
+	// +kubebuilder:validation:Enum=enabled;disabled
+	AZRebalance string `json:"azRebalance,omitempty"`
+}
+
+// BootstrapFailureDetectionSpec configures how instances whose nodes do not join the cluster are detected and handled
+type BootstrapFailureDetectionSpec struct {
+	// JoinTimeout is how long after its launch an instance's node must join the cluster, defaults to 15m
+	JoinTimeout string `json:"joinTimeout,omitempty"`
+	// ConsoleOutput reports the tail of the EC2 console output of failed instances in the status
+	ConsoleOutput bool `json:"consoleOutput,omitempty"`
+	// TerminateFailedInstances terminates failed instances, the scaling group launches their replacements
+	TerminateFailedInstances bool `json:"terminateFailedInstances,omitempty"`
+}
+
+// ZoneImbalanceSpec configures the detection of instances which stay imbalanced across availability zones, e.g. after
+// launches failed with insufficient capacity in a zone
+type ZoneImbalanceSpec struct {
+	// MaxSkew is the largest difference between the number of instances of two zones which is balanced, defaults to 1
+	MaxSkew int `json:"maxSkew,omitempty"`
+	// Threshold is how long the instances must be imbalanced before the imbalance is reported, defaults to 30m
+	Threshold string `json:"threshold,omitempty"`
+	// WarningEvents publishes a warning event when the instances have been imbalanced for longer than the threshold
+	WarningEvents bool `json:"warningEvents,omitempty"`
+}
+
+// UnhealthyNodeReplacementSpec configures when the instances of nodes which are not ready are replaced
+type UnhealthyNodeReplacementSpec struct {
+	// NotReadyThreshold is how long a node must be not ready before its instance is replaced, defaults to 10m
+	NotReadyThreshold string `json:"notReadyThreshold,omitempty"`
+	// MaxReplacements is the maximum number of instances which are replaced at the same time, defaults to 1
+	MaxReplacements int `json:"maxReplacements,omitempty"`
+}
+
+// CommitmentAdvisorSpec configures the instance types whose coverage by reserved instances and savings plans is
+// reported in addition to the instance type of the instance group
+type CommitmentAdvisorSpec struct {
+	// CandidateInstanceTypes are instance types the instance group could run on instead, in order of preference
+	CandidateInstanceTypes []string `json:"candidateInstanceTypes,omitempty"`
+}
+
+// InstanceTypeCommitment is the coverage of an instance type by reserved instances and savings plans
+type InstanceTypeCommitment struct {
+	InstanceType string `json:"instanceType"`
+	// ReservedInstances is the number of instances of the instance type covered by reserved instances, including
+	// size flexible reserved instances of its family
+	ReservedInstances int64 `json:"reservedInstances,omitempty"`
+	// SavingsPlans are the IDs of the savings plans which apply to the instance type
+	SavingsPlans []string `json:"savingsPlans,omitempty"`
+	// Covered is true if reserved instances or an EC2 instance savings plan of the instance family cover the instance
+	// type, compute savings plans apply to all instance types and do not cover a specific type
+	Covered bool `json:"covered,omitempty"`
+}
+
+// AlarmSpec defines a CloudWatch alarm on a group metric of the scaling group, the metric is collected even when it
+// is not in metricsCollection
+type AlarmSpec struct {
+	Name   string `json:"name"`
+	Metric string `json:"metric"`
+	// ComparisonOperator compares the statistic of the metric with the threshold, defaults to LessThanThreshold
+	ComparisonOperator string `json:"comparisonOperator,omitempty"`
+	// Threshold defaults to the min size of the instance group
+	Threshold *int64 `json:"threshold,omitempty"`
+	// Statistic of the metric over each minute, defaults to Average
+	Statistic string `json:"statistic,omitempty"`
+	// EvaluationPeriods is the number of minutes the threshold must be breached for, defaults to 5
+	EvaluationPeriods int64 `json:"evaluationPeriods,omitempty"`
+	// Actions are the ARNs of the actions executed when the alarm fires, e.g. SNS topics
+	Actions []string `json:"actions,omitempty"`
+}
+
+// NotificationSpec defines an SNS topic which notifications of a scaling group are sent to, and their types
+type NotificationSpec struct {
+	TopicArn string `json:"topicArn"`
+	// Types are the notification types sent to the topic, e.g. autoscaling:EC2_INSTANCE_LAUNCH, defaults to the
+	// notifications of launches and terminations and their failures
+	Types []string `json:"types,omitempty"`
 }
 
 type LifecycleHookSpec struct {
@@ -207,6 +749,9 @@ type LifecycleHookSpec struct {
 	NotificationArn  string `json:"notificationArn,omitempty"`
 	Metadata         string `json:"metadata,omitempty"`
 	RoleArn          string `json:"roleArn,omitempty"`
+	// LifecycleManager marks a terminate hook which is handled by keikoproj/lifecycle-manager, the notification target and
+	// role default to the ones configured on the controller
+	LifecycleManager bool `json:"lifecycleManager,omitempty"`
 }
 
 type UserDataStage struct {
@@ -215,6 +760,12 @@ type UserDataStage struct {
 	Data  string `json:"data"`
 }
 
+// SecretReference is a reference to a key of a secret in the instance group's namespace
+type SecretReference struct {
+	Name string
+	Key  string
+}
+
 type NodeVolume struct {
 	Name                string                  `json:"name"`
 	Type                string                  `json:"type"`
@@ -238,6 +789,12 @@ type EKSFargateSpec struct {
 	Subnets             []string              `json:"subnets,omitempty"`
 	Selectors           []EKSFargateSelectors `json:"selectors"`
 	Tags                []map[string]string   `json:"tags,omitempty"`
+	// ManagedPolicies are the names or ARNs of policies which are attached to the pod execution role in addition to
+	// AmazonEKSFargatePodExecutionRolePolicy, when the provisioner creates the role
+	ManagedPolicies []string `json:"managedPolicies,omitempty"`
+	// PermissionsBoundary is the ARN of the policy which is set as the permissions boundary of the pod execution
+	// role, when the provisioner creates the role
+	PermissionsBoundary string `json:"permissionsBoundary,omitempty"`
 }
 
 type EKSManagedConfiguration struct {
@@ -275,6 +832,160 @@ type InstanceGroupStatus struct {
 	Conditions                    []InstanceGroupCondition `json:"conditions,omitempty"`
 	Provisioner                   string                   `json:"provisioner,omitempty"`
 	Strategy                      string                   `json:"strategy,omitempty"`
+	PendingChanges                []string                 `json:"pendingChanges,omitempty"`
+	PendingManualReplacement      []string                 `json:"pendingManualReplacement,omitempty"`
+	NextChangeWindow              *metav1.Time             `json:"nextChangeWindow,omitempty"`
+	AcceleratorCount              int                      `json:"acceleratorCount,omitempty"`
+	RootDeviceName                string                   `json:"rootDeviceName,omitempty"`
+	ImageParameter                string                   `json:"imageParameter,omitempty"`
+	ResolvedImage                 string                   `json:"resolvedImage,omitempty"`
+	ImageVersion                  string                   `json:"imageVersion,omitempty"`
+	ResolvedSpotPrice             string                   `json:"resolvedSpotPrice,omitempty"`
+	Rotation                      *RotationStatus          `json:"rotation,omitempty"`
+	Backoff                       *BackoffStatus           `json:"backoff,omitempty"`
+	Plan                          []PlannedChange          `json:"plan,omitempty"`
+	RotationCounter               int                      `json:"rotationCounter,omitempty"`
+	RotateRequest                 string                   `json:"rotateRequest,omitempty"`
+	RetiringScalingGroups         []string                 `json:"retiringScalingGroups,omitempty"`
+	// Ready is set when the instance group is the infrastructure of a Cluster API MachinePool and its scaling group
+	// is provisioned
+	Ready bool `json:"ready,omitempty"`
+	// Replicas is the number of instances of the scaling group of an instance group which is the infrastructure of
+	// a Cluster API MachinePool
+	Replicas int32 `json:"replicas,omitempty"`
+	// ObservedGeneration is the generation of the spec which was last applied to the cloud resources
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// ConfigurationHash is a hash of the resolved configuration of the observed generation, including the values
+	// inherited from references and defaults, the resolved image and the rotation counter
+	ConfigurationHash string `json:"configurationHash,omitempty"`
+	// RolloutHash is the configuration hash which was last rolled out to all nodes, the rollout of the observed
+	// generation is complete when it is equal to the configuration hash
+	RolloutHash string `json:"rolloutHash,omitempty"`
+	// Revisions is the bounded history of configurations which were rolled out to all nodes, oldest first
+	Revisions []ConfigurationRevision `json:"revisions,omitempty"`
+	// FargateProfileStatus is the last observed status of the fargate profile of an eks-fargate instance group
+	FargateProfileStatus string `json:"fargateProfileStatus,omitempty"`
+	// ZoneTypes are the types of the zones of the instance group's subnets, availability-zone, local-zone,
+	// wavelength-zone or outpost
+	ZoneTypes []string `json:"zoneTypes,omitempty"`
+	// Commitments are the coverage of the instance type and candidate instance types of the commitment advisor,
+	// PreferredInstanceType is the instance type if it is covered, otherwise the first covered candidate
+	Commitments           []InstanceTypeCommitment `json:"commitments,omitempty"`
+	PreferredInstanceType string                   `json:"preferredInstanceType,omitempty"`
+	// UnhealthyInstances are the instances whose nodes are not ready and which are being replaced
+	UnhealthyInstances []string `json:"unhealthyInstances,omitempty"`
+	// UnhealthyNodeReplacements is the number of instances which were replaced since their nodes were not ready
+	UnhealthyNodeReplacements int `json:"unhealthyNodeReplacements,omitempty"`
+	// BootstrapFailures are the instances whose nodes did not join the cluster within the join timeout
+	BootstrapFailures []BootstrapFailure `json:"bootstrapFailures,omitempty"`
+	// LifecycleDistribution is the split of the instances of the scaling group between spot and on-demand instances
+	LifecycleDistribution *LifecycleDistribution `json:"lifecycleDistribution,omitempty"`
+	// ZoneDistribution is the number of instances of the scaling group in each zone of its subnets
+	ZoneDistribution []ZoneCapacity `json:"zoneDistribution,omitempty"`
+	// ZoneImbalancedSince is when the instances of the scaling group became imbalanced across zones, it is cleared
+	// when they are balanced again
+	ZoneImbalancedSince *metav1.Time `json:"zoneImbalancedSince,omitempty"`
+	// AZRebalance is the AZRebalance policy which was last applied to the scaling group, a change of the process outside
+	// of the controller while the policy is applied is drift and is reverted
+	AZRebalance string `json:"azRebalance,omitempty"`
+	// ProtectedInstances are the instances of the scaling group which are protected from scale-in, and why
+	ProtectedInstances []ProtectedInstance `json:"protectedInstances,omitempty"`
+	// ScaleInProtectedInstances are the instances which the controller protected from scale-in since their nodes were
+	// annotated, the controller only removes the protection of these instances
+	ScaleInProtectedInstances []string `json:"scaleInProtectedInstances,omitempty"`
+}
+
+// BootstrapFailure is an instance whose node did not join the cluster
+type BootstrapFailure struct {
+	InstanceID string      `json:"instanceId"`
+	LaunchTime metav1.Time `json:"launchTime,omitempty"`
+	// ConsoleOutput is the tail of the EC2 console output of the instance, when enabled
+	ConsoleOutput string `json:"consoleOutput,omitempty"`
+}
+
+// LifecycleDistribution is the number of spot and on-demand instances of a scaling group, instances which are
+// terminating are not counted
+type LifecycleDistribution struct {
+	SpotInstances     int `json:"spotInstances"`
+	OnDemandInstances int `json:"onDemandInstances"`
+	// SpotPercentage is the percentage of the instances which are spot instances, rounded down
+	SpotPercentage int `json:"spotPercentage"`
+}
+
+// ProtectedInstance is an instance which is protected from scale-in, the reason is one of NodeAnnotation,
+// NewInstancesProtected or External
+type ProtectedInstance struct {
+	InstanceID string `json:"instanceId"`
+	Node       string `json:"node,omitempty"`
+	Reason     string `json:"reason"`
+}
+
+// ZoneCapacity is the number of instances of a scaling group in a zone, instances which are terminating are not
+// counted
+type ZoneCapacity struct {
+	Zone      string `json:"zone"`
+	Instances int    `json:"instances"`
+}
+
+// ConfigurationRevision is a resolved configuration of an instance group which was rolled out to all nodes, the
+// configuration of each revision is stored in a ConfigMap so the instance group can be rolled back to it
+type ConfigurationRevision struct {
+	Revision int `json:"revision"`
+	// ConfigurationHash is the hash of the resolved configuration of the revision
+	ConfigurationHash string `json:"configurationHash"`
+	// Image is the image the nodes of the revision run
+	Image string `json:"image,omitempty"`
+	// InstanceType is the instance type of the nodes of the revision
+	InstanceType string `json:"instanceType,omitempty"`
+	// UserDataHash is the hash of the user data stages of the revision
+	UserDataHash string `json:"userDataHash,omitempty"`
+	// RolledOutAt is the time the revision was rolled out to all nodes
+	RolledOutAt metav1.Time `json:"rolledOutAt,omitempty"`
+}
+
+// PlannedChange is a change to a cloud resource which a reconcile would make, it is published instead of being
+// made while the instance group is in dry-run
+type PlannedChange struct {
+	// Resource is the kind of the cloud resource, e.g. LaunchConfiguration, ScalingGroup or Nodes
+	Resource string `json:"resource"`
+	// Action is the change to the resource, e.g. Create, Update, Delete or Rotate
+	Action string `json:"action"`
+	// Detail describes the change, e.g. the fields which would be updated
+	Detail string `json:"detail,omitempty"`
+}
+
+// String returns a short description of the change
+func (c PlannedChange) String() string {
+	if c.Detail == "" {
+		return fmt.Sprintf("%v %v", c.Action, c.Resource)
+	}
+	return fmt.Sprintf("%v %v (%v)", c.Action, c.Resource, c.Detail)
+}
+
+// RotationStatus is the progress of a node rotation, it is cleared when the rotation completes
+type RotationStatus struct {
+	Strategy      string       `json:"strategy,omitempty"`
+	StartedAt     *metav1.Time `json:"startedAt,omitempty"`
+	TotalNodes    int          `json:"totalNodes,omitempty"`
+	RotatedNodes  int          `json:"rotatedNodes,omitempty"`
+	CurrentBatch  int          `json:"currentBatch,omitempty"`
+	VerifiedBatch int          `json:"verifiedBatch,omitempty"`
+	LastBatchTime *metav1.Time `json:"lastBatchTime,omitempty"`
+	LastError     string       `json:"lastError,omitempty"`
+}
+
+// BackoffStatus is the history of consecutive reconcile failures, it is used to resume the requeue backoff after
+// a controller restart and is cleared when a reconcile succeeds
+type BackoffStatus struct {
+	LastFailureTime     *metav1.Time `json:"lastFailureTime,omitempty"`
+	LastFailureClass    string       `json:"lastFailureClass,omitempty"`
+	ConsecutiveFailures int          `json:"consecutiveFailures,omitempty"`
+	// LastFailureCode and LastFailureRequestID are the error code and request ID of the failed AWS API call, they
+	// are empty when the reconcile did not fail on an AWS API call
+	LastFailureCode      string `json:"lastFailureCode,omitempty"`
+	LastFailureRequestID string `json:"lastFailureRequestId,omitempty"`
+	// LastFailureMessage is the error of the last failed reconcile
+	LastFailureMessage string `json:"lastFailureMessage,omitempty"`
 }
 
 type InstanceGroupConditionType string
@@ -288,8 +999,10 @@ func NewInstanceGroupCondition(cType InstanceGroupConditionType, status corev1.C
 
 // InstanceGroupConditions describes the conditions of the InstanceGroup
 type InstanceGroupCondition struct {
-	Type   InstanceGroupConditionType `json:"type,omitempty"`
-	Status corev1.ConditionStatus     `json:"status,omitempty"`
+	Type    InstanceGroupConditionType `json:"type,omitempty"`
+	Status  corev1.ConditionStatus     `json:"status,omitempty"`
+	Reason  string                     `json:"reason,omitempty"`
+	Message string                     `json:"message,omitempty"`
 }
 
 func (ig *InstanceGroup) GetEKSConfiguration() *EKSConfiguration {
@@ -358,6 +1071,14 @@ func (c *EKSConfiguration) Validate() error {
 		if !common.ContainsEqualFold(LifecycleHookAllowedTransitions, h.Lifecycle) {
 			return errors.Errorf("validation failed, 'lifecycle' is a required parameter and must be in %+v", LifecycleHookAllowedTransitions)
 		}
+		if h.LifecycleManager {
+			if !strings.EqualFold(h.Lifecycle, LifecycleHookTransitionTerminate) {
+				return errors.Errorf("validation failed, lifecycle-manager hook '%v' must have a '%v' lifecycle", h.Name, LifecycleHookTransitionTerminate)
+			}
+			if common.StringEmpty(h.NotificationArn) || common.StringEmpty(h.RoleArn) {
+				return errors.Errorf("validation failed, lifecycle-manager hook '%v' requires 'notificationArn' and 'roleArn', or controller defaults", h.Name)
+			}
+		}
 		if strings.EqualFold(h.Lifecycle, LifecycleHookTransitionLaunch) {
 			h.Lifecycle = awsprovider.LifecycleHookTransitionLaunch
 		} else if strings.EqualFold(h.Lifecycle, LifecycleHookTransitionTerminate) {
@@ -366,19 +1087,146 @@ func (c *EKSConfiguration) Validate() error {
 		if common.StringEmpty(h.Name) {
 			return errors.Errorf("validation failed, 'name' is a required parameter")
 		}
-		if !common.StringEmpty(h.NotificationArn) && !strings.HasPrefix(h.NotificationArn, awsprovider.ARNPrefix) {
+		if !common.StringEmpty(h.NotificationArn) && !arn.IsARN(h.NotificationArn) {
 			return errors.Errorf("validation failed, 'notificationArn' must be a valid IAM role ARN")
 		}
-		if !common.StringEmpty(h.RoleArn) && !strings.HasPrefix(h.NotificationArn, awsprovider.ARNPrefix) {
+		if !common.StringEmpty(h.RoleArn) && !awsprovider.IsIAMResourceArn(h.RoleArn, "role") {
 			return errors.Errorf("validation failed, 'roleArn' must be a valid IAM role ARN")
 		}
 		hooks = append(hooks, h)
 	}
 	c.SetLifecycleHooks(hooks)
 
-	if common.StringEmpty(c.Image) {
+	if c.HasExistingInstanceProfile() {
+		if !awsprovider.IsIAMResourceArn(c.ExistingInstanceProfileArn, "instance-profile") {
+			return errors.Errorf("validation failed, 'instanceProfileArn' must be a valid IAM instance profile ARN")
+		}
+		if !common.StringEmpty(c.ExistingInstanceProfileName) {
+			return errors.Errorf("validation failed, 'instanceProfileArn' and 'instanceProfileName' are mutually exclusive")
+		}
+	}
+
+	if c.HasSharedRole() {
+		if c.HasExistingRole() {
+			return errors.Errorf("validation failed, 'sharedRoleName' is mutually exclusive with 'roleName' and 'instanceProfileArn'")
+		}
+		if !SharedRoleNamePattern.MatchString(c.SharedRoleName) {
+			return errors.Errorf("validation failed, 'sharedRoleName' must match %v", SharedRoleNamePattern.String())
+		}
+	}
+
+	// the image is resolved by the controller when it is upgraded automatically or built by an image pipeline
+	if common.StringEmpty(c.Image) && !c.IsAutoUpgrade() && !c.HasImagePipeline() {
 		return errors.Errorf("validation failed, 'image' is a required parameter")
 	}
+	topics := make([]string, 0)
+	for i, n := range c.Notifications {
+		topic, err := arn.Parse(n.TopicArn)
+		if err != nil || topic.Service != "sns" {
+			return errors.Errorf("validation failed, notification 'topicArn' must be a valid SNS topic ARN")
+		}
+		if common.ContainsString(topics, n.TopicArn) {
+			return errors.Errorf("validation failed, notification topic '%v' is configured more than once", n.TopicArn)
+		}
+		topics = append(topics, n.TopicArn)
+		for _, t := range n.Types {
+			if !common.ContainsString(awsprovider.AllowedNotificationTypes, t) {
+				return errors.Errorf("validation failed, notification type '%v' must be one of %+v", t, awsprovider.AllowedNotificationTypes)
+			}
+		}
+		if len(n.Types) == 0 {
+			c.Notifications[i].Types = append([]string{}, awsprovider.DefaultNotificationTypes...)
+		}
+	}
+
+	alarms := make([]string, 0)
+	for i, a := range c.Alarms {
+		if !AlarmNamePattern.MatchString(a.Name) {
+			return errors.Errorf("validation failed, alarm 'name' must match %v", AlarmNamePattern.String())
+		}
+		if common.ContainsString(alarms, a.Name) {
+			return errors.Errorf("validation failed, alarm '%v' is configured more than once", a.Name)
+		}
+		alarms = append(alarms, a.Name)
+		if !common.ContainsString(awsprovider.DefaultAutoscalingMetrics, a.Metric) {
+			return errors.Errorf("validation failed, alarm 'metric' must be one of %+v", awsprovider.DefaultAutoscalingMetrics)
+		}
+		if common.StringEmpty(a.ComparisonOperator) {
+			c.Alarms[i].ComparisonOperator = DefaultAlarmComparisonOperator
+		} else if !common.ContainsString(awsprovider.AllowedAlarmComparisonOperators, a.ComparisonOperator) {
+			return errors.Errorf("validation failed, alarm 'comparisonOperator' must be one of %+v", awsprovider.AllowedAlarmComparisonOperators)
+		}
+		if common.StringEmpty(a.Statistic) {
+			c.Alarms[i].Statistic = DefaultAlarmStatistic
+		} else if !common.ContainsString(awsprovider.AllowedAlarmStatistics, a.Statistic) {
+			return errors.Errorf("validation failed, alarm 'statistic' must be one of %+v", awsprovider.AllowedAlarmStatistics)
+		}
+		if a.EvaluationPeriods < 0 {
+			return errors.Errorf("validation failed, alarm 'evaluationPeriods' must be positive")
+		}
+		if a.EvaluationPeriods == 0 {
+			c.Alarms[i].EvaluationPeriods = DefaultAlarmEvaluationPeriods
+		}
+		for _, action := range a.Actions {
+			if !arn.IsARN(action) {
+				return errors.Errorf("validation failed, alarm action '%v' must be a valid ARN", action)
+			}
+		}
+	}
+
+	if strings.HasSuffix(c.SpotPrice, "%") {
+		if percentage := c.GetSpotPricePercentage(); percentage <= 0 || percentage > 100 {
+			return errors.Errorf("validation failed, 'spotPrice' must be a percentage greater than 0%% and at most 100%%")
+		}
+	}
+
+	candidates := make([]string, 0)
+	for _, instanceType := range c.GetCommitmentCandidates() {
+		if common.StringEmpty(instanceType) {
+			return errors.Errorf("validation failed, commitment advisor 'candidateInstanceTypes' must not be empty")
+		}
+		if common.ContainsString(candidates, instanceType) {
+			return errors.Errorf("validation failed, candidate instance type '%v' is configured more than once", instanceType)
+		}
+		candidates = append(candidates, instanceType)
+	}
+
+	if r := c.UnhealthyNodeReplacement; r != nil {
+		if !common.StringEmpty(r.NotReadyThreshold) {
+			if threshold, err := time.ParseDuration(r.NotReadyThreshold); err != nil || threshold <= 0 {
+				return errors.Errorf("validation failed, unhealthy node replacement 'notReadyThreshold' must be a positive duration")
+			}
+		}
+		if r.MaxReplacements < 0 {
+			return errors.Errorf("validation failed, unhealthy node replacement 'maxReplacements' must be positive")
+		}
+	}
+
+	if d := c.BootstrapFailureDetection; d != nil && !common.StringEmpty(d.JoinTimeout) {
+		if timeout, err := time.ParseDuration(d.JoinTimeout); err != nil || timeout <= 0 {
+			return errors.Errorf("validation failed, bootstrap failure detection 'joinTimeout' must be a positive duration")
+		}
+	}
+
+	if z := c.ZoneImbalance; z != nil {
+		if !common.StringEmpty(z.Threshold) {
+			if threshold, err := time.ParseDuration(z.Threshold); err != nil || threshold <= 0 {
+				return errors.Errorf("validation failed, zone imbalance 'threshold' must be a positive duration")
+			}
+		}
+		if z.MaxSkew < 0 {
+			return errors.Errorf("validation failed, zone imbalance 'maxSkew' must be positive")
+		}
+	}
+
+	if c.HasImagePipeline() {
+		if !awsprovider.IsImagePipelineArn(c.ImagePipelineArn) {
+			return errors.Errorf("validation failed, 'imagePipelineArn' must be a valid image pipeline ARN")
+		}
+		if c.IsAutoUpgrade() {
+			return errors.Errorf("validation failed, 'imagePipelineArn' and 'autoUpgrade' are mutually exclusive")
+		}
+	}
 	if common.StringEmpty(c.InstanceType) {
 		return errors.Errorf("validation failed, 'instanceType' is a required parameter")
 	}
@@ -386,6 +1234,54 @@ func (c *EKSConfiguration) Validate() error {
 		return errors.Errorf("validation failed, 'keyPair' is a required parameter")
 	}
 
+	if !common.StringEmpty(c.HealthCheckType) {
+		if !common.ContainsEqualFold(AllowedHealthCheckTypes, c.HealthCheckType) {
+			return errors.Errorf("validation failed, 'healthCheckType' must be one of %+v", AllowedHealthCheckTypes)
+		}
+		c.HealthCheckType = strings.ToUpper(c.HealthCheckType)
+	}
+	if c.HealthCheckGracePeriod != nil && *c.HealthCheckGracePeriod < 0 {
+		return errors.Errorf("validation failed, 'healthCheckGracePeriod' must be positive")
+	}
+
+	if !common.StringEmpty(c.AZRebalance) {
+		if !common.ContainsEqualFold(AllowedAZRebalancePolicies, c.AZRebalance) {
+			return errors.Errorf("validation failed, 'azRebalance' must be one of %+v", AllowedAZRebalancePolicies)
+		}
+		c.AZRebalance = strings.ToLower(c.AZRebalance)
+		if c.IsAZRebalanceEnabled() && common.ContainsEqualFold(c.SuspendedProcesses, AZRebalanceProcess) {
+			return errors.Errorf("validation failed, 'suspendProcesses' cannot contain %v when 'azRebalance' is enabled", AZRebalanceProcess)
+		}
+	}
+
+	if !common.StringEmpty(c.InstanceStorePolicy) {
+		if !common.ContainsEqualFold(AllowedInstanceStorePolicies, c.InstanceStorePolicy) {
+			return errors.Errorf("validation failed, 'instanceStorePolicy' must be one of %+v", AllowedInstanceStorePolicies)
+		}
+		c.InstanceStorePolicy = strings.ToUpper(c.InstanceStorePolicy)
+	}
+
+	if !common.StringEmpty(c.BootstrapProvider) {
+		if !common.ContainsEqualFold(BootstrapProviders, c.BootstrapProvider) {
+			return errors.Errorf("validation failed, 'bootstrapProvider' must be one of %+v", BootstrapProviders)
+		}
+		c.BootstrapProvider = strings.ToLower(c.BootstrapProvider)
+	}
+	switch c.GetBootstrapProvider() {
+	case CustomTemplateBootstrapProvider:
+		if common.StringEmpty(c.BootstrapTemplate) {
+			return errors.Errorf("validation failed, 'bootstrapTemplate' is required by the %v bootstrap provider", CustomTemplateBootstrapProvider)
+		}
+	case NodeadmBootstrapProvider:
+		if common.StringEmpty(c.ClusterCIDR) {
+			return errors.Errorf("validation failed, 'clusterCIDR' is required by the %v bootstrap provider", NodeadmBootstrapProvider)
+		}
+	case BottlerocketBootstrapProvider:
+		if !common.StringEmpty(c.InstanceStorePolicy) {
+			return errors.Errorf("validation failed, 'instanceStorePolicy' is not supported by the %v bootstrap provider", BottlerocketBootstrapProvider)
+		}
+	}
+
 	for _, v := range c.Volumes {
 		if !common.ContainsEqualFold(awsprovider.AllowedVolumeTypes, v.Type) {
 			return errors.Errorf("validation failed, volume type '%v' is unsuppoeted", v.Type)
@@ -416,6 +1312,22 @@ func (c *EKSConfiguration) Validate() error {
 	}
 	return nil
 }
+
+// RegisterBootstrapProvider adds the name of a bootstrap provider to the providers instance groups are validated
+// against
+func RegisterBootstrapProvider(name string) {
+	if !common.ContainsEqualFold(BootstrapProviders, name) {
+		BootstrapProviders = append(BootstrapProviders, name)
+	}
+}
+
+// RegisterProvisioner adds the name of a provisioner to the provisioners instance groups are validated against
+func RegisterProvisioner(name string) {
+	if !common.ContainsEqualFold(Provisioners, name) {
+		Provisioners = append(Provisioners, name)
+	}
+}
+
 func (ig *InstanceGroup) Validate() error {
 	s := ig.Spec
 
@@ -432,7 +1344,27 @@ func (ig *InstanceGroup) Validate() error {
 		}
 	}
 
+	if s.TemplateRef != "" && !strings.EqualFold(s.Provisioner, EKSProvisionerName) {
+		return errors.Errorf("validation failed, templateRef is only supported by the %v provisioner", EKSProvisionerName)
+	}
+
+	if s.ClusterRef != "" && !strings.EqualFold(s.Provisioner, EKSProvisionerName) {
+		return errors.Errorf("validation failed, clusterRef is only supported by the %v provisioner", EKSProvisionerName)
+	}
+
+	if s.KubeconfigSecretRef != nil {
+		if !strings.EqualFold(s.Provisioner, EKSProvisionerName) {
+			return errors.Errorf("validation failed, kubeconfigSecretRef is only supported by the %v provisioner", EKSProvisionerName)
+		}
+		if common.StringEmpty(s.KubeconfigSecretRef.Name) {
+			return errors.Errorf("validation failed, kubeconfigSecretRef.name is required")
+		}
+	}
+
 	if strings.EqualFold(s.Provisioner, EKSProvisionerName) {
+		if err := s.EKSSpec.Validate(); err != nil {
+			return err
+		}
 		config := ig.GetEKSConfiguration()
 		if err := config.Validate(); err != nil {
 			return err
@@ -461,6 +1393,38 @@ func (ig *InstanceGroup) Validate() error {
 		s.AwsUpgradeStrategy.RollingUpdateType = DefaultRollingUpdateStrategy
 	}
 
+	if strings.EqualFold(s.AwsUpgradeStrategy.Type, RollingUpdateStrategyName) {
+		if err := s.AwsUpgradeStrategy.RollingUpdateType.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if s.AwsUpgradeStrategy.Drain != nil {
+		if err := s.AwsUpgradeStrategy.Drain.Validate(); err != nil {
+			return err
+		}
+	}
+
+	for _, w := range s.ChangeWindows {
+		if err := w.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if s.ReconcilePriority != "" && !common.ContainsString(AllowedReconcilePriorities, s.ReconcilePriority) {
+		return errors.Errorf("validation failed, 'reconcilePriority' must be one of %+v", AllowedReconcilePriorities)
+	}
+
+	if s.ReconcilePolicy != "" && !common.ContainsString(AllowedReconcilePolicies, s.ReconcilePolicy) {
+		return errors.Errorf("validation failed, 'reconcilePolicy' must be one of %+v", AllowedReconcilePolicies)
+	}
+
+	if s.ReconcileInterval != "" {
+		if d, err := time.ParseDuration(s.ReconcileInterval); err != nil || d <= 0 {
+			return errors.Errorf("validation failed, 'reconcileInterval' '%v' is not a valid duration", s.ReconcileInterval)
+		}
+	}
+
 	return nil
 }
 func (c *EKSConfiguration) GetRoleName() string {
@@ -472,8 +1436,12 @@ func (c *EKSConfiguration) GetLifecycleHooks() []LifecycleHookSpec {
 func (c *EKSConfiguration) SetLifecycleHooks(hooks []LifecycleHookSpec) {
 	c.LifecycleHooks = hooks
 }
+
+// ExistInSlice returns true if an equal hook exists in a slice, hooks are compared by their scaling group attributes only
 func (h LifecycleHookSpec) ExistInSlice(hooks []LifecycleHookSpec) bool {
+	h.LifecycleManager = false
 	for _, hook := range hooks {
+		hook.LifecycleManager = false
 		if reflect.DeepEqual(hook, h) {
 			return true
 		}
@@ -484,7 +1452,19 @@ func (c *EKSConfiguration) GetInstanceProfileName() string {
 	return c.ExistingInstanceProfileName
 }
 func (c *EKSConfiguration) HasExistingRole() bool {
-	return c.ExistingRoleName != ""
+	return c.ExistingRoleName != "" || c.HasExistingInstanceProfile()
+}
+func (c *EKSConfiguration) GetInstanceProfileArn() string {
+	return c.ExistingInstanceProfileArn
+}
+func (c *EKSConfiguration) HasExistingInstanceProfile() bool {
+	return c.ExistingInstanceProfileArn != ""
+}
+func (c *EKSConfiguration) GetSharedRoleName() string {
+	return c.SharedRoleName
+}
+func (c *EKSConfiguration) HasSharedRole() bool {
+	return c.SharedRoleName != ""
 }
 func (c *EKSConfiguration) SetRoleName(role string) {
 	c.ExistingRoleName = role
@@ -516,9 +1496,137 @@ func (c *EKSConfiguration) GetManagedPolicies() []string {
 func (c *EKSConfiguration) GetUserData() []UserDataStage {
 	return c.UserData
 }
+
+// GetSecretReferences returns the unique secret references in the userData stages, stages which fail to decode are
+// skipped
+func (c *EKSConfiguration) GetSecretReferences() []SecretReference {
+	var (
+		references = make([]SecretReference, 0)
+		seen       = make(map[SecretReference]bool)
+	)
+
+	for _, stage := range c.UserData {
+		data, err := common.GetDecodedString(stage.Data)
+		if err != nil {
+			continue
+		}
+		for _, match := range SecretReferencePattern.FindAllStringSubmatch(data, -1) {
+			reference := SecretReference{Name: match[1], Key: match[2]}
+			if seen[reference] {
+				continue
+			}
+			seen[reference] = true
+			references = append(references, reference)
+		}
+	}
+	return references
+}
 func (c *EKSConfiguration) SetManagedPolicies(policies []string) {
 	c.ManagedPolicies = policies
 }
+func (c *EKSConfiguration) IsNewInstancesProtectedFromScaleIn() bool {
+	return c.NewInstancesProtectedFromScaleIn
+}
+func (c *EKSConfiguration) GetHealthCheckType() string {
+	if common.StringEmpty(c.HealthCheckType) {
+		return EC2HealthCheckType
+	}
+	return c.HealthCheckType
+}
+func (c *EKSConfiguration) SetHealthCheckType(healthCheckType string) {
+	c.HealthCheckType = healthCheckType
+}
+
+// GetHealthCheckGracePeriod returns the health check grace period in seconds, or nil when it is left to the scaling
+// group
+func (c *EKSConfiguration) GetHealthCheckGracePeriod() *int64 {
+	if c.HealthCheckGracePeriod != nil {
+		return c.HealthCheckGracePeriod
+	}
+	if strings.EqualFold(c.GetHealthCheckType(), ELBHealthCheckType) {
+		gracePeriod := int64(DefaultHealthCheckGracePeriodSeconds)
+		return &gracePeriod
+	}
+	return nil
+}
+func (c *EKSConfiguration) GetInstanceStorePolicy() string {
+	return c.InstanceStorePolicy
+}
+func (c *EKSConfiguration) SetInstanceStorePolicy(policy string) {
+	c.InstanceStorePolicy = policy
+}
+func (c *EKSConfiguration) IsAutoUpgrade() bool {
+	return c.AutoUpgrade
+}
+func (c *EKSConfiguration) HasImagePipeline() bool {
+	return c.ImagePipelineArn != ""
+}
+func (c *EKSConfiguration) GetImagePipelineArn() string {
+	return c.ImagePipelineArn
+}
+func (c *EKSConfiguration) SetImagePipelineArn(arn string) {
+	c.ImagePipelineArn = arn
+}
+func (c *EKSConfiguration) GetAPIServerEndpoint() string {
+	return c.APIServerEndpoint
+}
+func (c *EKSConfiguration) SetAPIServerEndpoint(endpoint string) {
+	c.APIServerEndpoint = endpoint
+}
+func (c *EKSConfiguration) GetCertificateAuthority() string {
+	return c.CertificateAuthority
+}
+func (c *EKSConfiguration) SetCertificateAuthority(ca string) {
+	c.CertificateAuthority = ca
+}
+func (c *EKSConfiguration) GetBootstrapProvider() string {
+	if c.BootstrapProvider == "" {
+		return EKSBootstrapProvider
+	}
+	return c.BootstrapProvider
+}
+func (c *EKSConfiguration) SetBootstrapProvider(provider string) {
+	c.BootstrapProvider = provider
+}
+func (c *EKSConfiguration) GetBootstrapTemplate() string {
+	return c.BootstrapTemplate
+}
+func (c *EKSConfiguration) GetClusterCIDR() string {
+	return c.ClusterCIDR
+}
+func (c *EKSConfiguration) GetNotifications() []NotificationSpec {
+	return c.Notifications
+}
+func (c *EKSConfiguration) SetNotifications(notifications []NotificationSpec) {
+	c.Notifications = notifications
+}
+func (c *EKSConfiguration) HasCommitmentAdvisor() bool {
+	return c.CommitmentAdvisor != nil
+}
+func (c *EKSConfiguration) GetCommitmentCandidates() []string {
+	if c.CommitmentAdvisor == nil {
+		return []string{}
+	}
+	return c.CommitmentAdvisor.CandidateInstanceTypes
+}
+func (c *EKSConfiguration) GetUnhealthyNodeReplacement() *UnhealthyNodeReplacementSpec {
+	return c.UnhealthyNodeReplacement
+}
+func (c *EKSConfiguration) GetBootstrapFailureDetection() *BootstrapFailureDetectionSpec {
+	return c.BootstrapFailureDetection
+}
+func (c *EKSConfiguration) GetZoneImbalance() *ZoneImbalanceSpec {
+	if c.ZoneImbalance == nil {
+		return &ZoneImbalanceSpec{}
+	}
+	return c.ZoneImbalance
+}
+func (c *EKSConfiguration) GetAlarms() []AlarmSpec {
+	return c.Alarms
+}
+func (c *EKSConfiguration) SetAlarms(alarms []AlarmSpec) {
+	c.Alarms = alarms
+}
 func (c *EKSConfiguration) GetMetricsCollection() []string {
 	return c.MetricsCollection
 }
@@ -558,12 +1666,37 @@ func (c *EKSConfiguration) GetSuspendProcesses() []string {
 	}
 	return c.SuspendedProcesses
 }
+func (c *EKSConfiguration) GetAZRebalance() string {
+	return c.AZRebalance
+}
+func (c *EKSConfiguration) IsAZRebalanceEnabled() bool {
+	return strings.EqualFold(c.AZRebalance, AZRebalanceEnabled)
+}
+func (c *EKSConfiguration) IsAZRebalanceDisabled() bool {
+	return strings.EqualFold(c.AZRebalance, AZRebalanceDisabled)
+}
 func (c *EKSConfiguration) GetSpotPrice() string {
 	return c.SpotPrice
 }
 func (c *EKSConfiguration) SetSpotPrice(price string) {
 	c.SpotPrice = price
 }
+
+// IsSpotPricePercentage returns true if the spot price is a percentage of the on-demand price
+func (c *EKSConfiguration) IsSpotPricePercentage() bool {
+	return SpotPricePercentagePattern.MatchString(c.SpotPrice)
+}
+
+// GetSpotPricePercentage returns the percentage of the on-demand price which the spot price is, or 0 if the spot
+// price is not a percentage
+func (c *EKSConfiguration) GetSpotPricePercentage() float64 {
+	match := SpotPricePercentagePattern.FindStringSubmatch(c.SpotPrice)
+	if match == nil {
+		return 0
+	}
+	percentage, _ := strconv.ParseFloat(match[1], 64)
+	return percentage
+}
 func (c *EKSConfiguration) SetSubnets(subnets []string) {
 	c.Subnets = subnets
 }
@@ -576,11 +1709,51 @@ func (spec *EKSSpec) GetMaxSize() int64 {
 func (spec *EKSSpec) GetMinSize() int64 {
 	return spec.MinSize
 }
-
-func (conf *EKSManagedConfiguration) SetSubnets(subnets []string) {
-	conf.Subnets = subnets
+func (spec *EKSSpec) GetDesiredCapacityPolicy() string {
+	return spec.DesiredCapacityPolicy
 }
-
+func (spec *EKSSpec) SetDesiredCapacityPolicy(policy string) {
+	spec.DesiredCapacityPolicy = policy
+}
+func (spec *EKSSpec) IsDesiredCapacityManaged() bool {
+	return strings.EqualFold(spec.DesiredCapacityPolicy, ManagedDesiredCapacityPolicy)
+}
+func (spec *EKSSpec) IsDesiredCapacityIgnored() bool {
+	return strings.EqualFold(spec.DesiredCapacityPolicy, IgnoreDesiredCapacityPolicy)
+}
+func (spec *EKSSpec) GetReplacementPolicy() string {
+	return spec.ReplacementPolicy
+}
+func (spec *EKSSpec) SetReplacementPolicy(policy string) {
+	spec.ReplacementPolicy = policy
+}
+func (spec *EKSSpec) IsRecreateReplacementPolicy() bool {
+	return strings.EqualFold(spec.ReplacementPolicy, RecreateReplacementPolicy)
+}
+
+func (spec *EKSSpec) Validate() error {
+	if common.StringEmpty(spec.DesiredCapacityPolicy) {
+		spec.SetDesiredCapacityPolicy(InitialOnlyDesiredCapacityPolicy)
+	}
+
+	if !common.ContainsEqualFold(AllowedDesiredCapacityPolicies, spec.DesiredCapacityPolicy) {
+		return errors.Errorf("validation failed, 'eks.desiredCapacityPolicy' must be one of %+v", AllowedDesiredCapacityPolicies)
+	}
+
+	if common.StringEmpty(spec.ReplacementPolicy) {
+		spec.SetReplacementPolicy(RetainReplacementPolicy)
+	}
+
+	if !common.ContainsEqualFold(AllowedReplacementPolicies, spec.ReplacementPolicy) {
+		return errors.Errorf("validation failed, 'eks.replacementPolicy' must be one of %+v", AllowedReplacementPolicies)
+	}
+	return nil
+}
+
+func (conf *EKSManagedConfiguration) SetSubnets(subnets []string) {
+	conf.Subnets = subnets
+}
+
 func (conf *EKSManagedConfiguration) SetClusterName(name string) {
 	conf.EksClusterName = name
 }
@@ -613,6 +1786,193 @@ func (s *AwsUpgradeStrategy) SetRollingUpdateType(ru *RollingUpdateStrategy) {
 	s.RollingUpdateType = ru
 }
 
+func (s *AwsUpgradeStrategy) GetDrain() *DrainSpec {
+	if s.Drain == nil {
+		return &DrainSpec{}
+	}
+	return s.Drain
+}
+
+func (s *AwsUpgradeStrategy) SetDrain(drain *DrainSpec) {
+	s.Drain = drain
+}
+
+func (d *DrainSpec) IsEnabled() bool {
+	return !d.Disabled
+}
+
+func (d *DrainSpec) IsForce() bool {
+	return d.Force
+}
+
+func (d *DrainSpec) GetTimeoutSeconds() int64 {
+	if d.TimeoutSeconds == 0 {
+		return DefaultDrainTimeoutSeconds
+	}
+	return d.TimeoutSeconds
+}
+
+func (r *UnhealthyNodeReplacementSpec) GetNotReadyThreshold() time.Duration {
+	threshold, err := time.ParseDuration(r.NotReadyThreshold)
+	if err != nil || threshold <= 0 {
+		return DefaultNotReadyThreshold
+	}
+	return threshold
+}
+
+func (r *UnhealthyNodeReplacementSpec) GetMaxReplacements() int {
+	if r.MaxReplacements <= 0 {
+		return DefaultMaxUnhealthyNodeReplacements
+	}
+	return r.MaxReplacements
+}
+
+func (d *BootstrapFailureDetectionSpec) GetJoinTimeout() time.Duration {
+	timeout, err := time.ParseDuration(d.JoinTimeout)
+	if err != nil || timeout <= 0 {
+		return DefaultNodeJoinTimeout
+	}
+	return timeout
+}
+
+func (d *BootstrapFailureDetectionSpec) IsConsoleOutputEnabled() bool {
+	return d.ConsoleOutput
+}
+
+func (d *BootstrapFailureDetectionSpec) IsTerminateFailedInstances() bool {
+	return d.TerminateFailedInstances
+}
+
+func (z *ZoneImbalanceSpec) GetMaxSkew() int {
+	if z.MaxSkew <= 0 {
+		return DefaultZoneImbalanceMaxSkew
+	}
+	return z.MaxSkew
+}
+
+func (z *ZoneImbalanceSpec) GetThreshold() time.Duration {
+	threshold, err := time.ParseDuration(z.Threshold)
+	if err != nil || threshold <= 0 {
+		return DefaultZoneImbalanceThreshold
+	}
+	return threshold
+}
+
+func (z *ZoneImbalanceSpec) IsWarningEvents() bool {
+	return z.WarningEvents
+}
+
+func (d *DrainSpec) GetPreDrain() *PreDrainHook {
+	return d.PreDrain
+}
+
+func (d *DrainSpec) GetGracePeriodSeconds() *int64 {
+	return d.GracePeriodSeconds
+}
+
+// IsWaitOnPDBStall returns true if draining should keep waiting for evictions refused by a PodDisruptionBudget once the
+// timeout expires
+func (d *DrainSpec) IsWaitOnPDBStall() bool {
+	return strings.EqualFold(d.GetPDBStallPolicy(), WaitPDBStallPolicy)
+}
+
+func (d *DrainSpec) IsSkipDaemonSets() bool {
+	if d.SkipDaemonSets == nil {
+		return true
+	}
+	return *d.SkipDaemonSets
+}
+
+func (d *DrainSpec) IsDeleteEmptyDirData() bool {
+	if d.DeleteEmptyDirData == nil {
+		return true
+	}
+	return *d.DeleteEmptyDirData
+}
+
+func (d *DrainSpec) GetPDBStallPolicy() string {
+	if d.PDBStallPolicy == "" {
+		return FailPDBStallPolicy
+	}
+	return d.PDBStallPolicy
+}
+
+func (d *DrainSpec) Validate() error {
+	if d.TimeoutSeconds < 0 {
+		return errors.Errorf("validation failed, 'strategy.drain.timeoutSeconds' must be a positive value")
+	}
+	if d.GracePeriodSeconds != nil && *d.GracePeriodSeconds < 0 {
+		return errors.Errorf("validation failed, 'strategy.drain.gracePeriodSeconds' must be a positive value")
+	}
+	if d.PDBStallPolicy != "" && !common.ContainsEqualFold(AllowedPDBStallPolicies, d.PDBStallPolicy) {
+		return errors.Errorf("validation failed, 'strategy.drain.pdbStallPolicy' must be one of %+v", AllowedPDBStallPolicies)
+	}
+	if d.PreDrain != nil {
+		if err := d.PreDrain.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w ChangeWindow) Validate() error {
+	if _, err := cron.ParseStandard(w.Schedule); err != nil {
+		return errors.Wrapf(err, "validation failed, change window schedule '%v' is invalid", w.Schedule)
+	}
+	if d, err := time.ParseDuration(w.Duration); err != nil || d <= 0 {
+		return errors.Errorf("validation failed, change window duration '%v' is invalid", w.Duration)
+	}
+	if _, err := time.LoadLocation(w.TimeZone); err != nil {
+		return errors.Wrapf(err, "validation failed, change window time zone '%v' is invalid", w.TimeZone)
+	}
+	return nil
+}
+
+// IsOpen returns true if the window is open at the given time, and the next time the window opens
+func (w ChangeWindow) IsOpen(now time.Time) (bool, time.Time, error) {
+	schedule, err := cron.ParseStandard(w.Schedule)
+	if err != nil {
+		return false, time.Time{}, err
+	}
+	duration, err := time.ParseDuration(w.Duration)
+	if err != nil {
+		return false, time.Time{}, err
+	}
+	location, err := time.LoadLocation(w.TimeZone)
+	if err != nil {
+		return false, time.Time{}, err
+	}
+
+	now = now.In(location)
+	// the window is open if it started less than duration ago
+	lastStart := schedule.Next(now.Add(-duration))
+	return !lastStart.After(now), schedule.Next(now), nil
+}
+
+// IsChangeWindowOpen returns true if changes to cloud resources are allowed at the given time, and the next time
+// a change window opens. Instance groups without change windows always allow changes
+func (ig *InstanceGroup) IsChangeWindowOpen(now time.Time) (bool, time.Time) {
+	var next time.Time
+	if len(ig.Spec.ChangeWindows) == 0 {
+		return true, next
+	}
+
+	for _, w := range ig.Spec.ChangeWindows {
+		open, nextStart, err := w.IsOpen(now)
+		if err != nil {
+			log.Error(err, "failed to evaluate change window", "instancegroup", ig.NamespacedName(), "schedule", w.Schedule)
+			continue
+		}
+		if open {
+			return true, next
+		}
+		if next.IsZero() || nextStart.Before(next) {
+			next = nextStart
+		}
+	}
+	return false, next
+}
+
 func (s *AwsUpgradeStrategy) GetCRDType() *CRDUpdateStrategy {
 	return s.CRDType
 }
@@ -622,6 +1982,32 @@ func (s *AwsUpgradeStrategy) SetCRDType(crd *CRDUpdateStrategy) {
 }
 
 func (c *CRDUpdateStrategy) Validate() error {
+	if c.RollingUpgrade != nil {
+		if c.GetSpec() != "" {
+			return errors.New("spec and rollingUpgrade are mutually exclusive")
+		}
+		if err := c.RollingUpgrade.Validate(); err != nil {
+			return err
+		}
+		spec, err := c.RollingUpgrade.GetCustomResource()
+		if err != nil {
+			return errors.Wrap(err, "failed to render rollingUpgrade")
+		}
+		c.SetSpec(spec)
+		if c.GetCRDName() == "" {
+			c.SetCRDName(RollingUpgradeCRDName)
+		}
+		if c.GetStatusJSONPath() == "" {
+			c.SetStatusJSONPath(RollingUpgradeStatusJSONPath)
+		}
+		if c.GetStatusSuccessString() == "" {
+			c.SetStatusSuccessString(RollingUpgradeStatusSuccess)
+		}
+		if c.GetStatusFailureString() == "" {
+			c.SetStatusFailureString(RollingUpgradeStatusFailure)
+		}
+	}
+
 	if c.GetSpec() == "" {
 		return errors.New("spec is empty")
 	}
@@ -650,6 +2036,62 @@ func (c *CRDUpdateStrategy) Validate() error {
 	return nil
 }
 
+func (s *RollingUpgradeSpec) Validate() error {
+	if s.NodeIntervalSeconds < 0 || s.PostDrainDelaySeconds < 0 {
+		return errors.New("rollingUpgrade nodeIntervalSeconds and postDrainDelaySeconds must be positive values")
+	}
+	if s.PostDrain != nil && s.PostDrain.WaitSeconds < 0 {
+		return errors.New("rollingUpgrade postDrain.waitSeconds must be a positive value")
+	}
+	if strategy := s.Strategy; strategy != nil {
+		if strategy.Type != "" && !common.ContainsString(AllowedRollingUpgradeTypes, strategy.Type) {
+			return errors.Errorf("rollingUpgrade strategy.type must be one of %+v", AllowedRollingUpgradeTypes)
+		}
+		if strategy.Mode != "" && !common.ContainsString(AllowedRollingUpgradeModes, strategy.Mode) {
+			return errors.Errorf("rollingUpgrade strategy.mode must be one of %+v", AllowedRollingUpgradeModes)
+		}
+		if strategy.DrainTimeout < 0 {
+			return errors.New("rollingUpgrade strategy.drainTimeout must be a positive value")
+		}
+	}
+	return nil
+}
+
+// GetCustomResource returns the RollingUpgrade document, templated with the instance group's name, namespace and
+// active scaling group
+func (s *RollingUpgradeSpec) GetCustomResource() (string, error) {
+	body, err := json.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+
+	spec := make(map[string]interface{})
+	if err := json.Unmarshal(body, &spec); err != nil {
+		return "", err
+	}
+	spec["asgName"] = "{{ .InstanceGroup.Status.ActiveScalingGroupName }}"
+
+	resource := map[string]interface{}{
+		"apiVersion": RollingUpgradeAPIVersion,
+		"kind":       RollingUpgradeKind,
+		"metadata": map[string]interface{}{
+			"name":      "{{ .InstanceGroup.Name }}",
+			"namespace": "{{ .InstanceGroup.Namespace }}",
+		},
+		"spec": spec,
+	}
+
+	out, err := yaml.Marshal(resource)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func (c *CRDUpdateStrategy) GetRollingUpgrade() *RollingUpgradeSpec {
+	return c.RollingUpgrade
+}
+
 func (c *CRDUpdateStrategy) GetSpec() string {
 	return c.Spec
 }
@@ -739,6 +2181,22 @@ func (status *InstanceGroupStatus) SetProvisioner(provisioner string) {
 	status.Provisioner = provisioner
 }
 
+func (status *InstanceGroupStatus) GetZoneTypes() []string {
+	return status.ZoneTypes
+}
+
+func (status *InstanceGroupStatus) SetZoneTypes(zoneTypes []string) {
+	status.ZoneTypes = zoneTypes
+}
+
+func (status *InstanceGroupStatus) GetFargateProfileStatus() string {
+	return status.FargateProfileStatus
+}
+
+func (status *InstanceGroupStatus) SetFargateProfileStatus(profileStatus string) {
+	status.FargateProfileStatus = profileStatus
+}
+
 func (status *InstanceGroupStatus) GetNodesArn() string {
 	return status.NodesArn
 }
@@ -755,6 +2213,83 @@ func (status *InstanceGroupStatus) SetStrategyResourceName(name string) {
 	status.StrategyResourceName = name
 }
 
+func (status *InstanceGroupStatus) GetRotation() *RotationStatus {
+	return status.Rotation
+}
+
+func (status *InstanceGroupStatus) SetRotation(rotation *RotationStatus) {
+	status.Rotation = rotation
+}
+
+func (status *InstanceGroupStatus) GetBackoff() *BackoffStatus {
+	return status.Backoff
+}
+
+func (status *InstanceGroupStatus) SetBackoff(backoff *BackoffStatus) {
+	status.Backoff = backoff
+}
+
+func (b *BackoffStatus) GetLastFailureTime() *metav1.Time {
+	return b.LastFailureTime
+}
+
+func (b *BackoffStatus) GetLastFailureClass() string {
+	return b.LastFailureClass
+}
+
+func (b *BackoffStatus) GetConsecutiveFailures() int {
+	return b.ConsecutiveFailures
+}
+
+func (b *BackoffStatus) GetLastFailureCode() string {
+	return b.LastFailureCode
+}
+
+func (b *BackoffStatus) GetLastFailureRequestID() string {
+	return b.LastFailureRequestID
+}
+
+func (b *BackoffStatus) GetLastFailureMessage() string {
+	return b.LastFailureMessage
+}
+
+func (r *RotationStatus) SetNodes(total, rotated int) {
+	r.TotalNodes = total
+	r.RotatedNodes = rotated
+}
+
+func (r *RotationStatus) GetCurrentBatch() int {
+	return r.CurrentBatch
+}
+
+func (r *RotationStatus) SetCurrentBatch(batch int) {
+	r.CurrentBatch = batch
+}
+
+func (r *RotationStatus) GetVerifiedBatch() int {
+	return r.VerifiedBatch
+}
+
+func (r *RotationStatus) SetVerifiedBatch(batch int) {
+	r.VerifiedBatch = batch
+}
+
+func (r *RotationStatus) GetLastBatchTime() *metav1.Time {
+	return r.LastBatchTime
+}
+
+func (r *RotationStatus) SetLastBatchTime(t *metav1.Time) {
+	r.LastBatchTime = t
+}
+
+func (r *RotationStatus) GetLastError() string {
+	return r.LastError
+}
+
+func (r *RotationStatus) SetLastError(err error) {
+	r.LastError = err.Error()
+}
+
 func (status *InstanceGroupStatus) GetCurrentMin() int {
 	return status.CurrentMin
 }
@@ -787,6 +2322,277 @@ func (status *InstanceGroupStatus) SetLifecycle(phase string) {
 	status.Lifecycle = phase
 }
 
+func (status *InstanceGroupStatus) GetPendingChanges() []string {
+	return status.PendingChanges
+}
+
+func (status *InstanceGroupStatus) SetPendingChanges(changes []string) {
+	status.PendingChanges = changes
+}
+
+func (status *InstanceGroupStatus) GetPlan() []PlannedChange {
+	return status.Plan
+}
+
+func (status *InstanceGroupStatus) SetPlan(plan []PlannedChange) {
+	status.Plan = plan
+}
+
+func (status *InstanceGroupStatus) GetRotationCounter() int {
+	return status.RotationCounter
+}
+
+func (status *InstanceGroupStatus) SetRotationCounter(counter int) {
+	status.RotationCounter = counter
+}
+
+func (status *InstanceGroupStatus) GetRotateRequest() string {
+	return status.RotateRequest
+}
+
+func (status *InstanceGroupStatus) SetRotateRequest(request string) {
+	status.RotateRequest = request
+}
+
+func (status *InstanceGroupStatus) GetRetiringScalingGroups() []string {
+	return status.RetiringScalingGroups
+}
+
+func (status *InstanceGroupStatus) SetRetiringScalingGroups(names []string) {
+	status.RetiringScalingGroups = names
+}
+
+func (status *InstanceGroupStatus) GetReady() bool {
+	return status.Ready
+}
+
+func (status *InstanceGroupStatus) SetReady(ready bool) {
+	status.Ready = ready
+}
+
+func (status *InstanceGroupStatus) GetReplicas() int32 {
+	return status.Replicas
+}
+
+func (status *InstanceGroupStatus) SetReplicas(replicas int32) {
+	status.Replicas = replicas
+}
+
+func (status *InstanceGroupStatus) GetObservedGeneration() int64 {
+	return status.ObservedGeneration
+}
+
+func (status *InstanceGroupStatus) SetObservedGeneration(generation int64) {
+	status.ObservedGeneration = generation
+}
+
+func (status *InstanceGroupStatus) GetConfigurationHash() string {
+	return status.ConfigurationHash
+}
+
+func (status *InstanceGroupStatus) SetConfigurationHash(hash string) {
+	status.ConfigurationHash = hash
+}
+
+func (status *InstanceGroupStatus) GetRolloutHash() string {
+	return status.RolloutHash
+}
+
+func (status *InstanceGroupStatus) SetRolloutHash(hash string) {
+	status.RolloutHash = hash
+}
+
+func (status *InstanceGroupStatus) GetRevisions() []ConfigurationRevision {
+	return status.Revisions
+}
+
+// GetLatestRevision returns the latest revision of the history, or nil when there is no history
+func (status *InstanceGroupStatus) GetLatestRevision() *ConfigurationRevision {
+	if len(status.Revisions) == 0 {
+		return nil
+	}
+	return &status.Revisions[len(status.Revisions)-1]
+}
+
+// GetRevision returns a revision of the history, or nil when the history does not have it
+func (status *InstanceGroupStatus) GetRevision(revision int) *ConfigurationRevision {
+	for i := range status.Revisions {
+		if status.Revisions[i].Revision == revision {
+			return &status.Revisions[i]
+		}
+	}
+	return nil
+}
+
+// AddRevision adds a revision to the history, the oldest revisions are removed so that at most limit revisions
+// are kept
+func (status *InstanceGroupStatus) AddRevision(revision ConfigurationRevision, limit int) {
+	status.Revisions = append(status.Revisions, revision)
+	if limit > 0 && len(status.Revisions) > limit {
+		status.Revisions = status.Revisions[len(status.Revisions)-limit:]
+	}
+}
+
+func (status *InstanceGroupStatus) GetPendingManualReplacement() []string {
+	return status.PendingManualReplacement
+}
+
+func (status *InstanceGroupStatus) SetPendingManualReplacement(nodes []string) {
+	status.PendingManualReplacement = nodes
+}
+
+func (status *InstanceGroupStatus) SetNextChangeWindow(t *metav1.Time) {
+	status.NextChangeWindow = t
+}
+
+func (status *InstanceGroupStatus) GetAcceleratorCount() int {
+	return status.AcceleratorCount
+}
+
+func (status *InstanceGroupStatus) SetAcceleratorCount(count int) {
+	status.AcceleratorCount = count
+}
+
+func (status *InstanceGroupStatus) GetRootDeviceName() string {
+	return status.RootDeviceName
+}
+
+func (status *InstanceGroupStatus) SetRootDeviceName(name string) {
+	status.RootDeviceName = name
+}
+
+func (status *InstanceGroupStatus) GetImageParameter() string {
+	return status.ImageParameter
+}
+
+func (status *InstanceGroupStatus) SetImageParameter(name string) {
+	status.ImageParameter = name
+}
+
+func (status *InstanceGroupStatus) GetResolvedImage() string {
+	return status.ResolvedImage
+}
+
+func (status *InstanceGroupStatus) SetResolvedImage(image string) {
+	status.ResolvedImage = image
+}
+
+func (status *InstanceGroupStatus) GetImageVersion() string {
+	return status.ImageVersion
+}
+
+func (status *InstanceGroupStatus) SetImageVersion(version string) {
+	status.ImageVersion = version
+}
+
+func (status *InstanceGroupStatus) GetResolvedSpotPrice() string {
+	return status.ResolvedSpotPrice
+}
+
+func (status *InstanceGroupStatus) SetResolvedSpotPrice(price string) {
+	status.ResolvedSpotPrice = price
+}
+
+func (status *InstanceGroupStatus) GetCommitments() []InstanceTypeCommitment {
+	return status.Commitments
+}
+
+func (status *InstanceGroupStatus) SetCommitments(commitments []InstanceTypeCommitment) {
+	status.Commitments = commitments
+}
+
+func (status *InstanceGroupStatus) GetUnhealthyInstances() []string {
+	return status.UnhealthyInstances
+}
+
+func (status *InstanceGroupStatus) SetUnhealthyInstances(instanceIds []string) {
+	status.UnhealthyInstances = instanceIds
+}
+
+func (status *InstanceGroupStatus) GetUnhealthyNodeReplacements() int {
+	return status.UnhealthyNodeReplacements
+}
+
+func (status *InstanceGroupStatus) IncrementUnhealthyNodeReplacements(count int) {
+	status.UnhealthyNodeReplacements += count
+}
+
+func (status *InstanceGroupStatus) GetBootstrapFailures() []BootstrapFailure {
+	return status.BootstrapFailures
+}
+
+func (status *InstanceGroupStatus) SetBootstrapFailures(failures []BootstrapFailure) {
+	status.BootstrapFailures = failures
+}
+
+func (status *InstanceGroupStatus) GetLifecycleDistribution() *LifecycleDistribution {
+	return status.LifecycleDistribution
+}
+
+func (status *InstanceGroupStatus) SetLifecycleDistribution(distribution *LifecycleDistribution) {
+	status.LifecycleDistribution = distribution
+}
+
+// NewLifecycleDistribution returns the distribution of a number of spot and on-demand instances
+func NewLifecycleDistribution(spot, onDemand int) *LifecycleDistribution {
+	distribution := &LifecycleDistribution{
+		SpotInstances:     spot,
+		OnDemandInstances: onDemand,
+	}
+	if total := spot + onDemand; total > 0 {
+		distribution.SpotPercentage = spot * 100 / total
+	}
+	return distribution
+}
+
+func (status *InstanceGroupStatus) GetZoneDistribution() []ZoneCapacity {
+	return status.ZoneDistribution
+}
+
+func (status *InstanceGroupStatus) SetZoneDistribution(distribution []ZoneCapacity) {
+	status.ZoneDistribution = distribution
+}
+
+func (status *InstanceGroupStatus) GetZoneImbalancedSince() *metav1.Time {
+	return status.ZoneImbalancedSince
+}
+
+func (status *InstanceGroupStatus) SetZoneImbalancedSince(since *metav1.Time) {
+	status.ZoneImbalancedSince = since
+}
+
+func (status *InstanceGroupStatus) GetProtectedInstances() []ProtectedInstance {
+	return status.ProtectedInstances
+}
+
+func (status *InstanceGroupStatus) SetProtectedInstances(instances []ProtectedInstance) {
+	status.ProtectedInstances = instances
+}
+
+func (status *InstanceGroupStatus) GetScaleInProtectedInstances() []string {
+	return status.ScaleInProtectedInstances
+}
+
+func (status *InstanceGroupStatus) SetScaleInProtectedInstances(instances []string) {
+	status.ScaleInProtectedInstances = instances
+}
+
+func (status *InstanceGroupStatus) GetAZRebalance() string {
+	return status.AZRebalance
+}
+
+func (status *InstanceGroupStatus) SetAZRebalance(policy string) {
+	status.AZRebalance = policy
+}
+
+func (status *InstanceGroupStatus) GetPreferredInstanceType() string {
+	return status.PreferredInstanceType
+}
+
+func (status *InstanceGroupStatus) SetPreferredInstanceType(instanceType string) {
+	status.PreferredInstanceType = instanceType
+}
+
 func (status *InstanceGroupStatus) GetConditions() []InstanceGroupCondition {
 	return status.Conditions
 }
@@ -795,6 +2601,37 @@ func (status *InstanceGroupStatus) SetConditions(conditions []InstanceGroupCondi
 	status.Conditions = conditions
 }
 
+// SetCondition adds a condition, or replaces the existing condition of the same type
+func (status *InstanceGroupStatus) SetCondition(condition InstanceGroupCondition) {
+	for i, c := range status.Conditions {
+		if c.Type == condition.Type {
+			status.Conditions[i] = condition
+			return
+		}
+	}
+	status.Conditions = append(status.Conditions, condition)
+}
+
+// RemoveCondition removes the condition of the given type if it exists
+func (status *InstanceGroupStatus) RemoveCondition(cType InstanceGroupConditionType) {
+	for i, c := range status.Conditions {
+		if c.Type == cType {
+			status.Conditions = append(status.Conditions[:i], status.Conditions[i+1:]...)
+			return
+		}
+	}
+}
+
+// GetCondition returns the condition of the given type, or nil if it does not exist
+func (status *InstanceGroupStatus) GetCondition(cType InstanceGroupConditionType) *InstanceGroupCondition {
+	for i, c := range status.Conditions {
+		if c.Type == cType {
+			return &status.Conditions[i]
+		}
+	}
+	return nil
+}
+
 func (strategy *AwsUpgradeStrategy) GetType() string {
 	return strategy.Type
 }
@@ -803,6 +2640,216 @@ func (strategy *AwsUpgradeStrategy) SetType(strategyType string) {
 	strategy.Type = strategyType
 }
 
+// IsDeletionProtected returns true if the instance group is annotated to prevent deletion of its cloud resources
+func (ig *InstanceGroup) IsDeletionProtected() bool {
+	return strings.EqualFold(ig.GetAnnotations()[DeletionProtectionAnnotationKey], DeletionProtectionEnabled)
+}
+
+// IsForceDelete returns true if the instance group is annotated to be deleted even when its nodes run protected workloads
+func (ig *InstanceGroup) IsForceDelete() bool {
+	return strings.EqualFold(ig.GetAnnotations()[ForceDeleteAnnotationKey], "true")
+}
+
+// IsSuspended returns true if changes to cloud resources are suspended, either by spec.suspend or by annotation
+func (ig *InstanceGroup) IsSuspended() bool {
+	if ig.Spec.Suspend {
+		return true
+	}
+	return strings.EqualFold(ig.GetAnnotations()[SuspendAnnotationKey], "true")
+}
+
+// IsDryRun returns true if changes to cloud resources are planned without being made
+func (ig *InstanceGroup) IsDryRun() bool {
+	return strings.EqualFold(ig.GetAnnotations()[DryRunAnnotationKey], "true")
+}
+
+// IsDebugLogging returns true when the instance group is annotated to write debug log lines
+func (ig *InstanceGroup) IsDebugLogging() bool {
+	return strings.EqualFold(ig.GetAnnotations()[LogLevelAnnotationKey], LogLevelDebug)
+}
+
+// GetRotateRequest returns the value of the rotate annotation of the instance group, or an empty string
+func (ig *InstanceGroup) GetRotateRequest() string {
+	return ig.GetAnnotations()[RotateAnnotationKey]
+}
+
+// HandleRotateRequest bumps the rotation counter when the instance group has a rotate request which was not handled
+// yet, and returns true if it was bumped, the handled request is recorded in the status until the annotation is removed
+func (ig *InstanceGroup) HandleRotateRequest() bool {
+	var (
+		status  = ig.GetStatus()
+		request = ig.GetRotateRequest()
+	)
+
+	if request == "" || strings.EqualFold(request, "false") {
+		status.SetRotateRequest("")
+		return false
+	}
+
+	if request == status.GetRotateRequest() {
+		return false
+	}
+
+	status.SetRotationCounter(status.GetRotationCounter() + 1)
+	status.SetRotateRequest(request)
+	return true
+}
+
+// HashConfiguration returns a hash of the instance group's configuration, it should be called on the resolved
+// instance group so that inherited and default values are included, provider IDs set by the controller are not
+func (ig *InstanceGroup) HashConfiguration() string {
+	spec := ig.Spec.DeepCopy()
+	spec.ProviderIDList = nil
+
+	payload, err := json.Marshal(struct {
+		Spec            *InstanceGroupSpec `json:"spec"`
+		ResolvedImage   string             `json:"resolvedImage"`
+		RotationCounter int                `json:"rotationCounter"`
+	}{spec, ig.Status.ResolvedImage, ig.Status.RotationCounter})
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(payload))
+}
+
+// IsRolledOut returns true when the latest generation of the spec was applied and its configuration was rolled out
+// to all nodes
+func (ig *InstanceGroup) IsRolledOut() bool {
+	status := ig.GetStatus()
+	return status.GetObservedGeneration() == ig.GetGeneration() &&
+		status.GetRolloutHash() != "" && status.GetRolloutHash() == status.GetConfigurationHash()
+}
+
+// GetExportFormat returns the format the instance group's cloud resources should be exported in, or an empty string
+func (ig *InstanceGroup) GetExportFormat() string {
+	return strings.ToLower(ig.GetAnnotations()[ExportAnnotationKey])
+}
+
+// GetExportName returns the name of the ConfigMap the instance group's cloud resources are exported to
+func (ig *InstanceGroup) GetExportName() string {
+	return fmt.Sprintf("%v-export", ig.GetName())
+}
+
+// GetRevisionsName returns the name of the ConfigMap the configurations of the instance group's revisions are
+// stored in
+func (ig *InstanceGroup) GetRevisionsName() string {
+	return fmt.Sprintf("%v-revisions", ig.GetName())
+}
+
+// GetRollbackRequest returns the revision the instance group should be rolled back to, or an empty string
+func (ig *InstanceGroup) GetRollbackRequest() string {
+	return ig.GetAnnotations()[RollbackAnnotationKey]
+}
+
+// GetRollbackRevision returns the revision of the history a rollback request refers to, 'previous' refers to the
+// latest revision whose configuration differs from the current configuration
+func (ig *InstanceGroup) GetRollbackRevision(request string) (*ConfigurationRevision, error) {
+	status := ig.GetStatus()
+	if strings.EqualFold(request, PreviousRollbackRequest) {
+		revisions := status.GetRevisions()
+		for i := len(revisions) - 1; i >= 0; i-- {
+			if revisions[i].ConfigurationHash != status.GetConfigurationHash() {
+				return &revisions[i], nil
+			}
+		}
+		return nil, errors.New("there is no previous revision in the revision history")
+	}
+
+	number, err := strconv.Atoi(request)
+	if err != nil {
+		return nil, errors.Errorf("rollback request '%v' is not a revision number or '%v'", request, PreviousRollbackRequest)
+	}
+	revision := status.GetRevision(number)
+	if revision == nil {
+		return nil, errors.Errorf("revision %v is not in the revision history", number)
+	}
+	return revision, nil
+}
+
+// GetReconcilePriority returns the reconcile priority of the instance group, defaults to Normal
+func (ig *InstanceGroup) GetReconcilePriority() string {
+	if ig.Spec.ReconcilePriority == "" {
+		return NormalReconcilePriority
+	}
+	return ig.Spec.ReconcilePriority
+}
+
+// IsStrictReconcilePolicy returns true if changes made outside of the controller are reverted at every reconcile
+func (ig *InstanceGroup) IsStrictReconcilePolicy() bool {
+	return ig.Spec.ReconcilePolicy == StrictReconcilePolicy
+}
+
+// GetReconcileInterval returns the reconcile interval of the instance group, or 0 if it is not set
+func (ig *InstanceGroup) GetReconcileInterval() time.Duration {
+	d, err := time.ParseDuration(ig.Spec.ReconcileInterval)
+	if err != nil || d <= 0 {
+		return 0
+	}
+	return d
+}
+
+// GetTemplateRef returns the name of the InstanceGroupTemplate the instance group inherits configuration from
+func (ig *InstanceGroup) GetTemplateRef() string {
+	return ig.Spec.TemplateRef
+}
+
+// GetClusterRef returns the name of the ClusterConfiguration of the instance group
+func (ig *InstanceGroup) GetClusterRef() string {
+	return ig.Spec.ClusterRef
+}
+
+// GetKubeconfigSecretRef returns the reference to the kubeconfig of the instance group's cluster, or nil when the
+// nodes join the cluster the controller runs in
+func (ig *InstanceGroup) GetKubeconfigSecretRef() *KubeconfigSecretReference {
+	return ig.Spec.KubeconfigSecretRef
+}
+
+// GetProviderIDList returns the provider IDs of the instances reported to the instance group's MachinePool
+func (ig *InstanceGroup) GetProviderIDList() []string {
+	return ig.Spec.ProviderIDList
+}
+
+// GetMachinePoolOwner returns the owner reference of the Cluster API MachinePool the instance group is the
+// infrastructure of, or nil when it is not owned by a MachinePool
+func (ig *InstanceGroup) GetMachinePoolOwner() *metav1.OwnerReference {
+	for i, owner := range ig.GetOwnerReferences() {
+		if owner.Kind != MachinePoolKind {
+			continue
+		}
+		if group := strings.Split(owner.APIVersion, "/")[0]; strings.HasSuffix(group, "cluster.x-k8s.io") {
+			return &ig.OwnerReferences[i]
+		}
+	}
+	return nil
+}
+
+// GetKey returns the key of the kubeconfig in the Secret, defaults to kubeconfig
+func (r *KubeconfigSecretReference) GetKey() string {
+	if r.Key == "" {
+		return DefaultKubeconfigSecretKey
+	}
+	return r.Key
+}
+
+// GetClusterName returns the name of the EKS cluster of the instance group's provisioner
+func (ig *InstanceGroup) GetClusterName() string {
+	switch strings.ToLower(ig.Spec.Provisioner) {
+	case EKSProvisionerName:
+		if ig.Spec.EKSSpec != nil && ig.Spec.EKSSpec.EKSConfiguration != nil {
+			return ig.Spec.EKSSpec.EKSConfiguration.GetClusterName()
+		}
+	case EKSManagedProvisionerName:
+		if ig.Spec.EKSManagedSpec != nil && ig.Spec.EKSManagedSpec.EKSManagedConfiguration != nil {
+			return ig.Spec.EKSManagedSpec.EKSManagedConfiguration.EksClusterName
+		}
+	case EKSFargateProvisionerName:
+		if ig.Spec.EKSFargateSpec != nil {
+			return ig.Spec.EKSFargateSpec.GetClusterName()
+		}
+	}
+	return ""
+}
+
 func (ig *InstanceGroup) GetState() ReconcileState {
 	return ReconcileState(ig.Status.CurrentState)
 }
@@ -825,6 +2872,41 @@ func (ig *InstanceGroup) GetEKSFargateSpec() *EKSFargateSpec {
 }
 
 func (spec *EKSFargateSpec) Validate() error {
+	if spec == nil {
+		return nil
+	}
+
+	if spec.PodExecutionRoleArn != "" && (len(spec.ManagedPolicies) > 0 || spec.PermissionsBoundary != "") {
+		return errors.New("validation failed, 'managedPolicies' and 'permissionsBoundary' cannot be set with 'podExecutionRoleArn'")
+	}
+
+	if spec.PermissionsBoundary != "" && !strings.HasPrefix(spec.PermissionsBoundary, "arn:") {
+		return errors.Errorf("validation failed, permissionsBoundary '%v' is not a policy ARN", spec.PermissionsBoundary)
+	}
+
+	if len(spec.Selectors) > MaxFargateSelectors {
+		return errors.Errorf("validation failed, a fargate profile can have at most %v selectors", MaxFargateSelectors)
+	}
+
+	for _, selector := range spec.Selectors {
+		if selector.Namespace == "" {
+			return errors.New("validation failed, 'namespace' is a required parameter of fargate selectors")
+		}
+		if !FargateNamespacePattern.MatchString(selector.Namespace) {
+			return errors.Errorf("validation failed, fargate selector namespace '%v' is invalid", selector.Namespace)
+		}
+		if len(selector.Labels) > MaxFargateSelectorLabels {
+			return errors.Errorf("validation failed, fargate selector of namespace '%v' can have at most %v labels", selector.Namespace, MaxFargateSelectorLabels)
+		}
+		for k, v := range selector.Labels {
+			if !FargateLabelPattern.MatchString(k) {
+				return errors.Errorf("validation failed, fargate selector label key '%v' is invalid", k)
+			}
+			if v != "" && !FargateLabelPattern.MatchString(v) {
+				return errors.Errorf("validation failed, fargate selector label value '%v' of key '%v' is invalid", v, k)
+			}
+		}
+	}
 	return nil
 }
 
@@ -867,3 +2949,19 @@ func (spec *EKSFargateSpec) GetTags() []map[string]string {
 func (spec *EKSFargateSpec) SetTags(tags []map[string]string) {
 	spec.Tags = tags
 }
+
+func (spec *EKSFargateSpec) GetManagedPolicies() []string {
+	return spec.ManagedPolicies
+}
+
+func (spec *EKSFargateSpec) SetManagedPolicies(policies []string) {
+	spec.ManagedPolicies = policies
+}
+
+func (spec *EKSFargateSpec) GetPermissionsBoundary() string {
+	return spec.PermissionsBoundary
+}
+
+func (spec *EKSFargateSpec) SetPermissionsBoundary(arn string) {
+	spec.PermissionsBoundary = arn
+}
@@ -15,9 +15,16 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"fmt"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	awsprovider "github.com/keikoproj/instance-manager/controllers/providers/aws"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type EksUnitTest struct {
@@ -104,3 +111,1254 @@ func MockInstanceGroup(provisioner, strategy string) InstanceGroup {
 
 	return ig
 }
+
+func TestInstanceGroupIsSuspended(t *testing.T) {
+	tests := []struct {
+		name        string
+		suspend     bool
+		annotations map[string]string
+		want        bool
+	}{
+		{name: "not suspended", want: false},
+		{name: "suspended by spec", suspend: true, want: true},
+		{name: "suspended by annotation", annotations: map[string]string{SuspendAnnotationKey: "true"}, want: true},
+		{name: "annotation set to false", annotations: map[string]string{SuspendAnnotationKey: "false"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ig := MockInstanceGroup("eks", "rollingUpdate")
+			ig.Spec.Suspend = tt.suspend
+			ig.SetAnnotations(tt.annotations)
+			if got := ig.IsSuspended(); got != tt.want {
+				t.Errorf("%v: got %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChangeWindowIsOpen(t *testing.T) {
+	// Wednesday
+	now := time.Date(2020, time.June, 3, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		window   ChangeWindow
+		wantOpen bool
+		wantNext time.Time
+	}{
+		{
+			name:     "inside daily window",
+			window:   ChangeWindow{Schedule: "0 10 * * *", Duration: "1h"},
+			wantOpen: true,
+			wantNext: time.Date(2020, time.June, 4, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "after daily window",
+			window:   ChangeWindow{Schedule: "0 9 * * *", Duration: "1h"},
+			wantOpen: false,
+			wantNext: time.Date(2020, time.June, 4, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "window in other time zone",
+			window:   ChangeWindow{Schedule: "0 3 * * *", Duration: "2h", TimeZone: "America/Los_Angeles"},
+			wantOpen: true,
+			wantNext: time.Date(2020, time.June, 4, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "weekend window",
+			window:   ChangeWindow{Schedule: "0 0 * * 6", Duration: "48h"},
+			wantOpen: false,
+			wantNext: time.Date(2020, time.June, 6, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			open, next, err := tt.window.IsOpen(now)
+			if err != nil {
+				t.Fatalf("%v: unexpected error %v", tt.name, err)
+			}
+			if open != tt.wantOpen {
+				t.Errorf("%v: got open %v, want %v", tt.name, open, tt.wantOpen)
+			}
+			if !next.Equal(tt.wantNext) {
+				t.Errorf("%v: got next %v, want %v", tt.name, next, tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestChangeWindowValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		window  ChangeWindow
+		wantErr bool
+	}{
+		{name: "valid window", window: ChangeWindow{Schedule: "0 10 * * 1-5", Duration: "2h", TimeZone: "Europe/London"}, wantErr: false},
+		{name: "invalid schedule", window: ChangeWindow{Schedule: "bad", Duration: "2h"}, wantErr: true},
+		{name: "invalid duration", window: ChangeWindow{Schedule: "0 10 * * *", Duration: "2 hours"}, wantErr: true},
+		{name: "negative duration", window: ChangeWindow{Schedule: "0 10 * * *", Duration: "-1h"}, wantErr: true},
+		{name: "invalid time zone", window: ChangeWindow{Schedule: "0 10 * * *", Duration: "2h", TimeZone: "Mars/Olympus"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.window.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%v: got error %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEKSConfigurationValidateInstanceStorePolicy(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     string
+		wantPolicy string
+		wantErr    bool
+	}{
+		{name: "no policy", policy: "", wantPolicy: "", wantErr: false},
+		{name: "raid0 policy", policy: "RAID0", wantPolicy: InstanceStorePolicyRaid0, wantErr: false},
+		{name: "lower case raid0 policy", policy: "raid0", wantPolicy: InstanceStorePolicyRaid0, wantErr: false},
+		{name: "invalid policy", policy: "RAID5", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &EKSConfiguration{
+				EksClusterName:      "some-cluster",
+				Subnets:             []string{"subnet-1111111"},
+				NodeSecurityGroups:  []string{"sg-1111111"},
+				Image:               "ami-123456789012",
+				InstanceType:        "i3.large",
+				KeyPairName:         "some-key",
+				InstanceStorePolicy: tt.policy,
+			}
+			err := config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%v: got error %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if err == nil && config.GetInstanceStorePolicy() != tt.wantPolicy {
+				t.Errorf("%v: got policy %v, want %v", tt.name, config.GetInstanceStorePolicy(), tt.wantPolicy)
+			}
+		})
+	}
+}
+
+func TestEKSConfigurationValidateSpotPrice(t *testing.T) {
+	tests := []struct {
+		spotPrice  string
+		percentage float64
+		wantErr    bool
+	}{
+		{spotPrice: "", percentage: 0, wantErr: false},
+		{spotPrice: "0.05", percentage: 0, wantErr: false},
+		{spotPrice: "80%", percentage: 80, wantErr: false},
+		{spotPrice: "62.5%", percentage: 62.5, wantErr: false},
+		{spotPrice: "100%", percentage: 100, wantErr: false},
+		{spotPrice: "0%", percentage: 0, wantErr: true},
+		{spotPrice: "120%", percentage: 120, wantErr: true},
+		{spotPrice: "eighty%", percentage: 0, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spotPrice, func(t *testing.T) {
+			config := &EKSConfiguration{
+				EksClusterName:     "some-cluster",
+				Subnets:            []string{"subnet-1111111"},
+				NodeSecurityGroups: []string{"sg-1111111"},
+				Image:              "ami-123456789012",
+				InstanceType:       "m5.large",
+				KeyPairName:        "some-key",
+				SpotPrice:          tt.spotPrice,
+			}
+			if got := config.GetSpotPricePercentage(); got != tt.percentage {
+				t.Errorf("%v: got percentage %v, want %v", tt.spotPrice, got, tt.percentage)
+			}
+			err := config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%v: got error %v, wantErr %v", tt.spotPrice, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEKSConfigurationValidateUnhealthyNodeReplacement(t *testing.T) {
+	tests := []struct {
+		name              string
+		replacement       *UnhealthyNodeReplacementSpec
+		wantErr           bool
+		expectedThreshold time.Duration
+		expectedMax       int
+	}{
+		{name: "defaults", replacement: &UnhealthyNodeReplacementSpec{}, wantErr: false, expectedThreshold: DefaultNotReadyThreshold, expectedMax: 1},
+		{name: "threshold", replacement: &UnhealthyNodeReplacementSpec{NotReadyThreshold: "5m", MaxReplacements: 2}, wantErr: false, expectedThreshold: 5 * time.Minute, expectedMax: 2},
+		{name: "invalid threshold", replacement: &UnhealthyNodeReplacementSpec{NotReadyThreshold: "5 minutes"}, wantErr: true},
+		{name: "negative threshold", replacement: &UnhealthyNodeReplacementSpec{NotReadyThreshold: "-5m"}, wantErr: true},
+		{name: "negative max replacements", replacement: &UnhealthyNodeReplacementSpec{MaxReplacements: -1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &EKSConfiguration{
+				EksClusterName:           "some-cluster",
+				Subnets:                  []string{"subnet-1111111"},
+				NodeSecurityGroups:       []string{"sg-1111111"},
+				Image:                    "ami-123456789012",
+				InstanceType:             "m5.large",
+				KeyPairName:              "some-key",
+				UnhealthyNodeReplacement: tt.replacement,
+			}
+			err := config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%v: got error %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := tt.replacement.GetNotReadyThreshold(); got != tt.expectedThreshold {
+				t.Errorf("%v: got threshold %v, expected %v", tt.name, got, tt.expectedThreshold)
+			}
+			if got := tt.replacement.GetMaxReplacements(); got != tt.expectedMax {
+				t.Errorf("%v: got max replacements %v, expected %v", tt.name, got, tt.expectedMax)
+			}
+		})
+	}
+}
+
+func TestEKSConfigurationValidateBootstrapFailureDetection(t *testing.T) {
+	tests := []struct {
+		name            string
+		detection       *BootstrapFailureDetectionSpec
+		wantErr         bool
+		expectedTimeout time.Duration
+	}{
+		{name: "defaults", detection: &BootstrapFailureDetectionSpec{}, wantErr: false, expectedTimeout: DefaultNodeJoinTimeout},
+		{name: "timeout", detection: &BootstrapFailureDetectionSpec{JoinTimeout: "30m"}, wantErr: false, expectedTimeout: 30 * time.Minute},
+		{name: "invalid timeout", detection: &BootstrapFailureDetectionSpec{JoinTimeout: "30"}, wantErr: true},
+		{name: "negative timeout", detection: &BootstrapFailureDetectionSpec{JoinTimeout: "-30m"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &EKSConfiguration{
+				EksClusterName:            "some-cluster",
+				Subnets:                   []string{"subnet-1111111"},
+				NodeSecurityGroups:        []string{"sg-1111111"},
+				Image:                     "ami-123456789012",
+				InstanceType:              "m5.large",
+				KeyPairName:               "some-key",
+				BootstrapFailureDetection: tt.detection,
+			}
+			err := config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%v: got error %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := tt.detection.GetJoinTimeout(); got != tt.expectedTimeout {
+				t.Errorf("%v: got join timeout %v, expected %v", tt.name, got, tt.expectedTimeout)
+			}
+		})
+	}
+}
+
+func TestEKSConfigurationValidateZoneImbalance(t *testing.T) {
+	tests := []struct {
+		name              string
+		imbalance         *ZoneImbalanceSpec
+		wantErr           bool
+		expectedMaxSkew   int
+		expectedThreshold time.Duration
+	}{
+		{name: "defaults", imbalance: &ZoneImbalanceSpec{}, wantErr: false, expectedMaxSkew: DefaultZoneImbalanceMaxSkew, expectedThreshold: DefaultZoneImbalanceThreshold},
+		{name: "custom", imbalance: &ZoneImbalanceSpec{MaxSkew: 2, Threshold: "1h"}, wantErr: false, expectedMaxSkew: 2, expectedThreshold: time.Hour},
+		{name: "invalid threshold", imbalance: &ZoneImbalanceSpec{Threshold: "30"}, wantErr: true},
+		{name: "negative threshold", imbalance: &ZoneImbalanceSpec{Threshold: "-30m"}, wantErr: true},
+		{name: "negative max skew", imbalance: &ZoneImbalanceSpec{MaxSkew: -1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &EKSConfiguration{
+				EksClusterName:     "some-cluster",
+				Subnets:            []string{"subnet-1111111"},
+				NodeSecurityGroups: []string{"sg-1111111"},
+				Image:              "ami-123456789012",
+				InstanceType:       "m5.large",
+				KeyPairName:        "some-key",
+				ZoneImbalance:      tt.imbalance,
+			}
+			err := config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%v: got error %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := tt.imbalance.GetMaxSkew(); got != tt.expectedMaxSkew {
+				t.Errorf("%v: got max skew %v, expected %v", tt.name, got, tt.expectedMaxSkew)
+			}
+			if got := tt.imbalance.GetThreshold(); got != tt.expectedThreshold {
+				t.Errorf("%v: got threshold %v, expected %v", tt.name, got, tt.expectedThreshold)
+			}
+		})
+	}
+}
+
+func TestEKSConfigurationValidateHealthCheck(t *testing.T) {
+	tests := []struct {
+		name                string
+		healthCheckType     string
+		gracePeriod         *int64
+		wantErr             bool
+		expectedType        string
+		expectedGracePeriod *int64
+	}{
+		{name: "defaults", wantErr: false, expectedType: EC2HealthCheckType},
+		{name: "elb", healthCheckType: "elb", wantErr: false, expectedType: ELBHealthCheckType, expectedGracePeriod: aws.Int64(DefaultHealthCheckGracePeriodSeconds)},
+		{name: "elb grace period", healthCheckType: "ELB", gracePeriod: aws.Int64(60), wantErr: false, expectedType: ELBHealthCheckType, expectedGracePeriod: aws.Int64(60)},
+		{name: "ec2 grace period", gracePeriod: aws.Int64(0), wantErr: false, expectedType: EC2HealthCheckType, expectedGracePeriod: aws.Int64(0)},
+		{name: "invalid type", healthCheckType: "TCP", wantErr: true},
+		{name: "negative grace period", gracePeriod: aws.Int64(-1), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &EKSConfiguration{
+				EksClusterName:         "some-cluster",
+				Subnets:                []string{"subnet-1111111"},
+				NodeSecurityGroups:     []string{"sg-1111111"},
+				Image:                  "ami-123456789012",
+				InstanceType:           "m5.large",
+				KeyPairName:            "some-key",
+				HealthCheckType:        tt.healthCheckType,
+				HealthCheckGracePeriod: tt.gracePeriod,
+			}
+			err := config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%v: got error %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := config.GetHealthCheckType(); got != tt.expectedType {
+				t.Errorf("%v: got health check type %v, expected %v", tt.name, got, tt.expectedType)
+			}
+			if got := config.GetHealthCheckGracePeriod(); !reflect.DeepEqual(got, tt.expectedGracePeriod) {
+				t.Errorf("%v: got grace period %v, expected %v", tt.name, aws.Int64Value(got), aws.Int64Value(tt.expectedGracePeriod))
+			}
+		})
+	}
+}
+
+func TestEKSConfigurationValidateAZRebalance(t *testing.T) {
+	tests := []struct {
+		name             string
+		policy           string
+		suspendProcesses []string
+		wantErr          bool
+		expectedPolicy   string
+	}{
+		{name: "unset", wantErr: false},
+		{name: "disabled", policy: "Disabled", wantErr: false, expectedPolicy: AZRebalanceDisabled},
+		{name: "enabled", policy: "enabled", suspendProcesses: []string{"all"}, wantErr: false, expectedPolicy: AZRebalanceEnabled},
+		{name: "disabled and suspended", policy: "disabled", suspendProcesses: []string{"AZRebalance"}, wantErr: false, expectedPolicy: AZRebalanceDisabled},
+		{name: "enabled and suspended", policy: "enabled", suspendProcesses: []string{"AZRebalance"}, wantErr: true},
+		{name: "invalid policy", policy: "suspended", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &EKSConfiguration{
+				EksClusterName:     "some-cluster",
+				Subnets:            []string{"subnet-1111111"},
+				NodeSecurityGroups: []string{"sg-1111111"},
+				Image:              "ami-123456789012",
+				InstanceType:       "m5.large",
+				KeyPairName:        "some-key",
+				SuspendedProcesses: tt.suspendProcesses,
+				AZRebalance:        tt.policy,
+			}
+			err := config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%v: got error %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := config.GetAZRebalance(); got != tt.expectedPolicy {
+				t.Errorf("%v: got policy %v, expected %v", tt.name, got, tt.expectedPolicy)
+			}
+		})
+	}
+}
+
+func TestEKSConfigurationValidateCommitmentAdvisor(t *testing.T) {
+	tests := []struct {
+		name       string
+		candidates []string
+		wantErr    bool
+	}{
+		{name: "no candidates", candidates: nil, wantErr: false},
+		{name: "candidates", candidates: []string{"m5a.large", "m4.large"}, wantErr: false},
+		{name: "empty candidate", candidates: []string{"m5a.large", ""}, wantErr: true},
+		{name: "duplicate candidate", candidates: []string{"m5a.large", "m5a.large"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &EKSConfiguration{
+				EksClusterName:     "some-cluster",
+				Subnets:            []string{"subnet-1111111"},
+				NodeSecurityGroups: []string{"sg-1111111"},
+				Image:              "ami-123456789012",
+				InstanceType:       "m5.large",
+				KeyPairName:        "some-key",
+				CommitmentAdvisor:  &CommitmentAdvisorSpec{CandidateInstanceTypes: tt.candidates},
+			}
+			err := config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%v: got error %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEKSConfigurationValidateAlarms(t *testing.T) {
+	tests := []struct {
+		name    string
+		alarms  []AlarmSpec
+		wantErr bool
+	}{
+		{name: "defaults", alarms: []AlarmSpec{{Name: "in-service", Metric: "GroupInServiceInstances"}}, wantErr: false},
+		{name: "pending", alarms: []AlarmSpec{{Name: "pending", Metric: "GroupPendingInstances", ComparisonOperator: "GreaterThanThreshold", Threshold: aws.Int64(0), Statistic: "Minimum", EvaluationPeriods: 15}}, wantErr: false},
+		{name: "invalid name", alarms: []AlarmSpec{{Name: "in service", Metric: "GroupInServiceInstances"}}, wantErr: true},
+		{name: "duplicate name", alarms: []AlarmSpec{{Name: "a", Metric: "GroupInServiceInstances"}, {Name: "a", Metric: "GroupPendingInstances"}}, wantErr: true},
+		{name: "invalid metric", alarms: []AlarmSpec{{Name: "cpu", Metric: "CPUUtilization"}}, wantErr: true},
+		{name: "invalid comparison", alarms: []AlarmSpec{{Name: "a", Metric: "GroupInServiceInstances", ComparisonOperator: "LessThanLowerThreshold"}}, wantErr: true},
+		{name: "invalid statistic", alarms: []AlarmSpec{{Name: "a", Metric: "GroupInServiceInstances", Statistic: "p99"}}, wantErr: true},
+		{name: "invalid action", alarms: []AlarmSpec{{Name: "a", Metric: "GroupInServiceInstances", Actions: []string{"alerts"}}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &EKSConfiguration{
+				EksClusterName:     "some-cluster",
+				Subnets:            []string{"subnet-1111111"},
+				NodeSecurityGroups: []string{"sg-1111111"},
+				Image:              "ami-123456789012",
+				InstanceType:       "m5.large",
+				KeyPairName:        "some-key",
+				Alarms:             tt.alarms,
+			}
+			err := config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%v: got error %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if err == nil && tt.name == "defaults" {
+				alarm := config.GetAlarms()[0]
+				if alarm.ComparisonOperator != DefaultAlarmComparisonOperator || alarm.Statistic != DefaultAlarmStatistic || alarm.EvaluationPeriods != DefaultAlarmEvaluationPeriods {
+					t.Errorf("%v: got alarm %+v, want defaults", tt.name, alarm)
+				}
+			}
+		})
+	}
+}
+
+func TestEKSConfigurationValidateNotifications(t *testing.T) {
+	topic := "arn:aws:sns:us-west-2:123456789012:alerts"
+	tests := []struct {
+		name          string
+		notifications []NotificationSpec
+		wantTypes     []string
+		wantErr       bool
+	}{
+		{name: "default types", notifications: []NotificationSpec{{TopicArn: topic}}, wantTypes: awsprovider.DefaultNotificationTypes, wantErr: false},
+		{name: "types", notifications: []NotificationSpec{{TopicArn: topic, Types: []string{"autoscaling:EC2_INSTANCE_LAUNCH_ERROR"}}}, wantTypes: []string{"autoscaling:EC2_INSTANCE_LAUNCH_ERROR"}, wantErr: false},
+		{name: "invalid type", notifications: []NotificationSpec{{TopicArn: topic, Types: []string{"launch"}}}, wantErr: true},
+		{name: "queue arn", notifications: []NotificationSpec{{TopicArn: "arn:aws:sqs:us-west-2:123456789012:alerts"}}, wantErr: true},
+		{name: "duplicate topic", notifications: []NotificationSpec{{TopicArn: topic}, {TopicArn: topic}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &EKSConfiguration{
+				EksClusterName:     "some-cluster",
+				Subnets:            []string{"subnet-1111111"},
+				NodeSecurityGroups: []string{"sg-1111111"},
+				Image:              "ami-123456789012",
+				InstanceType:       "m5.large",
+				KeyPairName:        "some-key",
+				Notifications:      tt.notifications,
+			}
+			err := config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%v: got error %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(config.GetNotifications()[0].Types, tt.wantTypes) {
+				t.Errorf("%v: got types %v, want %v", tt.name, config.GetNotifications()[0].Types, tt.wantTypes)
+			}
+		})
+	}
+}
+
+func TestEKSConfigurationValidateImage(t *testing.T) {
+	tests := []struct {
+		name        string
+		image       string
+		pipelineArn string
+		autoUpgrade bool
+		wantErr     bool
+	}{
+		{name: "image", image: "ami-123456789012", wantErr: false},
+		{name: "no image", image: "", wantErr: true},
+		{name: "no image with auto upgrade", image: "", autoUpgrade: true, wantErr: false},
+		{name: "no image with pipeline", image: "", pipelineArn: "arn:aws:imagebuilder:us-west-2:123456789012:image-pipeline/eks-node", wantErr: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &EKSConfiguration{
+				EksClusterName:     "some-cluster",
+				Subnets:            []string{"subnet-1111111"},
+				NodeSecurityGroups: []string{"sg-1111111"},
+				Image:              tt.image,
+				InstanceType:       "m5.large",
+				KeyPairName:        "some-key",
+				ImagePipelineArn:   tt.pipelineArn,
+				AutoUpgrade:        tt.autoUpgrade,
+			}
+			err := config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%v: got error %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEKSConfigurationValidateImagePipeline(t *testing.T) {
+	tests := []struct {
+		name        string
+		pipelineArn string
+		autoUpgrade bool
+		wantErr     bool
+	}{
+		{name: "no pipeline", pipelineArn: "", wantErr: false},
+		{name: "pipeline", pipelineArn: "arn:aws:imagebuilder:us-west-2:123456789012:image-pipeline/eks-node", wantErr: false},
+		{name: "image arn", pipelineArn: "arn:aws:imagebuilder:us-west-2:123456789012:image/eks-node/1.0.0/1", wantErr: true},
+		{name: "pipeline name", pipelineArn: "eks-node", wantErr: true},
+		{name: "pipeline with auto upgrade", pipelineArn: "arn:aws:imagebuilder:us-west-2:123456789012:image-pipeline/eks-node", autoUpgrade: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &EKSConfiguration{
+				EksClusterName:     "some-cluster",
+				Subnets:            []string{"subnet-1111111"},
+				NodeSecurityGroups: []string{"sg-1111111"},
+				Image:              "ami-123456789012",
+				InstanceType:       "m5.large",
+				KeyPairName:        "some-key",
+				ImagePipelineArn:   tt.pipelineArn,
+				AutoUpgrade:        tt.autoUpgrade,
+			}
+			err := config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%v: got error %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEKSFargateSpecValidateRolePolicies(t *testing.T) {
+	tests := []struct {
+		name     string
+		roleArn  string
+		policies []string
+		boundary string
+		wantErr  bool
+	}{
+		{name: "managed policies", policies: []string{"AmazonS3ReadOnlyAccess"}, boundary: "arn:aws:iam::123456789012:policy/boundary", wantErr: false},
+		{name: "boundary name", boundary: "boundary", wantErr: true},
+		{name: "managed policies with role", roleArn: "arn:aws:iam::123456789012:role/pods", policies: []string{"AmazonS3ReadOnlyAccess"}, wantErr: true},
+		{name: "boundary with role", roleArn: "arn:aws:iam::123456789012:role/pods", boundary: "arn:aws:iam::123456789012:policy/boundary", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ig := MockInstanceGroup("eks-fargate", "managed")
+			ig.Spec.EKSFargateSpec.SetPodExecutionRoleArn(tt.roleArn)
+			ig.Spec.EKSFargateSpec.SetManagedPolicies(tt.policies)
+			ig.Spec.EKSFargateSpec.SetPermissionsBoundary(tt.boundary)
+			err := ig.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%v: got error %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEKSFargateSpecValidateSelectors(t *testing.T) {
+	selector := func(namespace string, labels map[string]string) EKSFargateSelectors {
+		return EKSFargateSelectors{Namespace: namespace, Labels: labels}
+	}
+	tests := []struct {
+		name      string
+		selectors []EKSFargateSelectors
+		wantErr   bool
+	}{
+		{name: "namespace", selectors: []EKSFargateSelectors{selector("default", nil)}, wantErr: false},
+		{name: "wildcard namespace", selectors: []EKSFargateSelectors{selector("team-*", nil), selector("prod-?", nil)}, wantErr: false},
+		{name: "wildcard labels", selectors: []EKSFargateSelectors{selector("*", map[string]string{"app.kubernetes.io/name": "web-*", "tier": "?"})}, wantErr: false},
+		{name: "missing namespace", selectors: []EKSFargateSelectors{selector("", nil)}, wantErr: true},
+		{name: "invalid namespace", selectors: []EKSFargateSelectors{selector("Team_A", nil)}, wantErr: true},
+		{name: "invalid label value", selectors: []EKSFargateSelectors{selector("default", map[string]string{"app": "web app"})}, wantErr: true},
+		{name: "too many labels", selectors: []EKSFargateSelectors{selector("default", map[string]string{"a": "1", "b": "2", "c": "3", "d": "4", "e": "5", "f": "6"})}, wantErr: true},
+		{name: "too many selectors", selectors: []EKSFargateSelectors{selector("a", nil), selector("b", nil), selector("c", nil), selector("d", nil), selector("e", nil), selector("f", nil)}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ig := MockInstanceGroup("eks-fargate", "managed")
+			ig.Spec.EKSFargateSpec.SetSelectors(tt.selectors)
+			err := ig.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%v: got error %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEKSConfigurationValidateBootstrapProvider(t *testing.T) {
+	tests := []struct {
+		name         string
+		provider     string
+		template     string
+		clusterCIDR  string
+		policy       string
+		wantProvider string
+		wantErr      bool
+	}{
+		{name: "default provider", provider: "", wantProvider: EKSBootstrapProvider, wantErr: false},
+		{name: "upper case provider", provider: "Bottlerocket", wantProvider: BottlerocketBootstrapProvider, wantErr: false},
+		{name: "invalid provider", provider: "windows", wantErr: true},
+		{name: "custom template without template", provider: "custom-template", wantErr: true},
+		{name: "custom template", provider: "custom-template", template: "#!/bin/bash", wantProvider: CustomTemplateBootstrapProvider, wantErr: false},
+		{name: "nodeadm without cluster cidr", provider: "nodeadm", wantErr: true},
+		{name: "nodeadm with raid0", provider: "nodeadm", clusterCIDR: "10.100.0.0/16", policy: "RAID0", wantProvider: NodeadmBootstrapProvider, wantErr: false},
+		{name: "bottlerocket with raid0", provider: "bottlerocket", policy: "RAID0", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &EKSConfiguration{
+				EksClusterName:      "some-cluster",
+				Subnets:             []string{"subnet-1111111"},
+				NodeSecurityGroups:  []string{"sg-1111111"},
+				Image:               "ami-123456789012",
+				InstanceType:        "i3.large",
+				KeyPairName:         "some-key",
+				InstanceStorePolicy: tt.policy,
+				BootstrapProvider:   tt.provider,
+				BootstrapTemplate:   tt.template,
+				ClusterCIDR:         tt.clusterCIDR,
+			}
+			err := config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%v: got error %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if err == nil && config.GetBootstrapProvider() != tt.wantProvider {
+				t.Errorf("%v: got provider %v, want %v", tt.name, config.GetBootstrapProvider(), tt.wantProvider)
+			}
+		})
+	}
+}
+
+func TestEKSConfigurationValidateRoles(t *testing.T) {
+	tests := []struct {
+		name        string
+		sharedRole  string
+		role        string
+		profileArn  string
+		profileName string
+		wantErr     bool
+	}{
+		{name: "no shared role", sharedRole: "", wantErr: false},
+		{name: "shared role", sharedRole: "workers", wantErr: false},
+		{name: "shared role with existing role", sharedRole: "workers", role: "some-role", wantErr: true},
+		{name: "invalid shared role", sharedRole: "shared/workers", wantErr: true},
+		{name: "shared role with instance profile arn", sharedRole: "workers", profileArn: "arn:aws:iam::123456789012:instance-profile/some-profile", wantErr: true},
+		{name: "instance profile arn", profileArn: "arn:aws:iam::123456789012:instance-profile/nodes/some-profile", wantErr: false},
+		{name: "instance profile arn with name", profileArn: "arn:aws:iam::123456789012:instance-profile/some-profile", profileName: "some-profile", wantErr: true},
+		{name: "invalid instance profile arn", profileArn: "arn:aws:iam::123456789012:role/some-role", wantErr: true},
+		{name: "govcloud instance profile arn", profileArn: "arn:aws-us-gov:iam::123456789012:instance-profile/some-profile", wantErr: false},
+		{name: "china instance profile arn", profileArn: "arn:aws-cn:iam::123456789012:instance-profile/some-profile", wantErr: false},
+		{name: "instance profile arn of another service", profileArn: "arn:aws:ec2::123456789012:instance-profile/some-profile", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &EKSConfiguration{
+				EksClusterName:              "some-cluster",
+				Subnets:                     []string{"subnet-1111111"},
+				NodeSecurityGroups:          []string{"sg-1111111"},
+				Image:                       "ami-123456789012",
+				InstanceType:                "m5.large",
+				KeyPairName:                 "some-key",
+				SharedRoleName:              tt.sharedRole,
+				ExistingRoleName:            tt.role,
+				ExistingInstanceProfileArn:  tt.profileArn,
+				ExistingInstanceProfileName: tt.profileName,
+			}
+			err := config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%v: got error %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEKSConfigurationGetSecretReferences(t *testing.T) {
+	config := &EKSConfiguration{
+		UserData: []UserDataStage{
+			{
+				Stage: PreBootstrapStage,
+				Data:  `echo {{ secret "registry" "username" }}:{{secret "registry" "password"}}`,
+			},
+			{
+				Stage: PostBootstrapStage,
+				Data:  `echo {{ secret "registry" "username" }} && docker inspect --format '{{.Id}}' some-container`,
+			},
+		},
+	}
+
+	want := []SecretReference{
+		{Name: "registry", Key: "username"},
+		{Name: "registry", Key: "password"},
+	}
+	if got := config.GetSecretReferences(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got references %v, want %v", got, want)
+	}
+}
+
+func TestInstanceGroupStatusConditions(t *testing.T) {
+	status := &InstanceGroupStatus{}
+	status.SetCondition(NewInstanceGroupCondition(NodesReady, corev1.ConditionTrue))
+	degraded := NewInstanceGroupCondition(Degraded, corev1.ConditionTrue)
+	degraded.Reason = "SomeReason"
+	status.SetCondition(degraded)
+	status.SetCondition(NewInstanceGroupCondition(NodesReady, corev1.ConditionFalse))
+
+	if len(status.GetConditions()) != 2 {
+		t.Fatalf("got conditions %v, want 2 conditions", status.GetConditions())
+	}
+	if status.GetNodesReadyCondition() != corev1.ConditionFalse {
+		t.Errorf("got NodesReady %v, want %v", status.GetNodesReadyCondition(), corev1.ConditionFalse)
+	}
+	if c := status.GetCondition(Degraded); c == nil || c.Reason != "SomeReason" {
+		t.Errorf("got Degraded condition %v, want reason SomeReason", c)
+	}
+
+	status.RemoveCondition(Degraded)
+	status.RemoveCondition(Degraded)
+	if status.GetCondition(Degraded) != nil || len(status.GetConditions()) != 1 {
+		t.Errorf("got conditions %v, want only NodesReady", status.GetConditions())
+	}
+}
+
+func TestRollingUpdateStrategyValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		gate       NodeReadinessGate
+		wantStatus corev1.ConditionStatus
+		wantErr    bool
+	}{
+		{name: "default status", gate: NodeReadinessGate{Type: "example.com/NetworkReady"}, wantStatus: corev1.ConditionTrue},
+		{name: "false status", gate: NodeReadinessGate{Type: corev1.NodeNetworkUnavailable, Status: corev1.ConditionFalse}, wantStatus: corev1.ConditionFalse},
+		{name: "missing type", gate: NodeReadinessGate{Status: corev1.ConditionTrue}, wantErr: true},
+		{name: "invalid status", gate: NodeReadinessGate{Type: "example.com/NetworkReady", Status: "Yes"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strategy := &RollingUpdateStrategy{ReadinessGates: []NodeReadinessGate{tt.gate}}
+			err := strategy.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%v: got error %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if err == nil && strategy.GetReadinessGates()[0].Status != tt.wantStatus {
+				t.Errorf("%v: got status %v, want %v", tt.name, strategy.GetReadinessGates()[0].Status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestVerificationHookValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		hook        VerificationHook
+		wantTimeout int64
+		wantErr     bool
+	}{
+		{name: "job", hook: VerificationHook{Job: "kind: Job"}, wantTimeout: DefaultVerificationTimeoutSeconds},
+		{name: "url", hook: VerificationHook{URL: "https://verify.example.com", TimeoutSeconds: 10}, wantTimeout: 10},
+		{name: "job and url", hook: VerificationHook{Job: "kind: Job", URL: "https://verify.example.com"}, wantErr: true},
+		{name: "empty", hook: VerificationHook{}, wantErr: true},
+		{name: "negative timeout", hook: VerificationHook{URL: "https://verify.example.com", TimeoutSeconds: -1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.hook.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%v: got error %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if err == nil && tt.hook.TimeoutSeconds != tt.wantTimeout {
+				t.Errorf("%v: got timeout %v, want %v", tt.name, tt.hook.TimeoutSeconds, tt.wantTimeout)
+			}
+		})
+	}
+}
+
+func TestPreDrainHookValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		hook       PreDrainHook
+		wantStatus corev1.ConditionStatus
+		wantErr    bool
+	}{
+		{name: "annotation", hook: PreDrainHook{Annotation: "example.com/flush-cache"}},
+		{name: "url", hook: PreDrainHook{URL: "https://registry.example.com"}},
+		{name: "condition default status", hook: PreDrainHook{Condition: &NodeReadinessGate{Type: "example.com/Deregistered"}}, wantStatus: corev1.ConditionTrue},
+		{name: "condition missing type", hook: PreDrainHook{Condition: &NodeReadinessGate{Status: corev1.ConditionTrue}}, wantErr: true},
+		{name: "condition invalid status", hook: PreDrainHook{Condition: &NodeReadinessGate{Type: "example.com/Deregistered", Status: "Yes"}}, wantErr: true},
+		{name: "annotation and url", hook: PreDrainHook{Annotation: "example.com/flush-cache", URL: "https://registry.example.com"}, wantErr: true},
+		{name: "empty", hook: PreDrainHook{}, wantErr: true},
+		{name: "negative timeout", hook: PreDrainHook{URL: "https://registry.example.com", TimeoutSeconds: -1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.hook.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%v: got error %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if err == nil && tt.hook.Condition != nil && tt.hook.Condition.Status != tt.wantStatus {
+				t.Errorf("%v: got status %v, want %v", tt.name, tt.hook.Condition.Status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestDrainSpecValidate(t *testing.T) {
+	var (
+		negative int64 = -1
+		skip           = false
+	)
+	tests := []struct {
+		name    string
+		drain   DrainSpec
+		wantErr bool
+	}{
+		{name: "default", drain: DrainSpec{}},
+		{name: "wait policy", drain: DrainSpec{PDBStallPolicy: "wait"}},
+		{name: "invalid policy", drain: DrainSpec{PDBStallPolicy: "retry"}, wantErr: true},
+		{name: "negative timeout", drain: DrainSpec{TimeoutSeconds: -1}, wantErr: true},
+		{name: "negative grace period", drain: DrainSpec{GracePeriodSeconds: &negative}, wantErr: true},
+		{name: "skip daemonsets", drain: DrainSpec{SkipDaemonSets: &skip}},
+		{name: "invalid pre-drain", drain: DrainSpec{PreDrain: &PreDrainHook{}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.drain.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%v: got error %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRollingUpdateStrategyOrderValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy RollingUpdateStrategy
+		wantErr  bool
+	}{
+		{name: "default order", strategy: RollingUpdateStrategy{}},
+		{name: "oldest first", strategy: RollingUpdateStrategy{Order: OldestFirstRotationOrder, CooldownSeconds: 60}},
+		{name: "zone by zone", strategy: RollingUpdateStrategy{Order: "zonebyzone"}},
+		{name: "invalid order", strategy: RollingUpdateStrategy{Order: "Random"}, wantErr: true},
+		{name: "negative cooldown", strategy: RollingUpdateStrategy{CooldownSeconds: -1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.strategy.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%v: got error %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEKSSpecValidateDesiredCapacityPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     EKSSpec
+		expected string
+		wantErr  bool
+	}{
+		{name: "default policy", spec: EKSSpec{}, expected: InitialOnlyDesiredCapacityPolicy},
+		{name: "managed", spec: EKSSpec{DesiredCapacityPolicy: ManagedDesiredCapacityPolicy}, expected: ManagedDesiredCapacityPolicy},
+		{name: "ignore", spec: EKSSpec{DesiredCapacityPolicy: "ignore"}, expected: "ignore"},
+		{name: "invalid policy", spec: EKSSpec{DesiredCapacityPolicy: "Always"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.spec.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%v: got error %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if err == nil && tt.spec.GetDesiredCapacityPolicy() != tt.expected {
+				t.Errorf("%v: got policy %v, expected %v", tt.name, tt.spec.GetDesiredCapacityPolicy(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestEKSSpecValidateReplacementPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     EKSSpec
+		expected string
+		wantErr  bool
+	}{
+		{name: "default policy", spec: EKSSpec{}, expected: RetainReplacementPolicy},
+		{name: "recreate", spec: EKSSpec{ReplacementPolicy: RecreateReplacementPolicy}, expected: RecreateReplacementPolicy},
+		{name: "invalid policy", spec: EKSSpec{ReplacementPolicy: "Replace"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.spec.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%v: got error %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if err == nil && tt.spec.GetReplacementPolicy() != tt.expected {
+				t.Errorf("%v: got policy %v, expected %v", tt.name, tt.spec.GetReplacementPolicy(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestInstanceGroupReconcilePriority(t *testing.T) {
+	tests := []struct {
+		name     string
+		priority string
+		expected string
+		wantErr  bool
+	}{
+		{name: "default priority", priority: "", expected: NormalReconcilePriority},
+		{name: "critical", priority: CriticalReconcilePriority, expected: CriticalReconcilePriority},
+		{name: "invalid priority", priority: "Urgent", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ig := MockInstanceGroup("eks-fargate", "managed")
+			ig.Spec.ReconcilePriority = tt.priority
+			err := ig.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%v: got error %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if err == nil && ig.GetReconcilePriority() != tt.expected {
+				t.Errorf("%v: got priority %v, expected %v", tt.name, ig.GetReconcilePriority(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestInstanceGroupReconcileInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval string
+		expected time.Duration
+		wantErr  bool
+	}{
+		{name: "default interval", interval: "", expected: 0},
+		{name: "valid interval", interval: "30m", expected: 30 * time.Minute},
+		{name: "invalid interval", interval: "often", wantErr: true},
+		{name: "negative interval", interval: "-1m", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ig := MockInstanceGroup("eks-fargate", "managed")
+			ig.Spec.ReconcileInterval = tt.interval
+			err := ig.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%v: got error %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if err == nil && ig.GetReconcileInterval() != tt.expected {
+				t.Errorf("%v: got interval %v, expected %v", tt.name, ig.GetReconcileInterval(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestInstanceGroupValidateReferences(t *testing.T) {
+	tests := []struct {
+		name        string
+		provisioner string
+		strategy    string
+		templateRef string
+		clusterRef  string
+		kubeconfig  *KubeconfigSecretReference
+		wantErr     bool
+	}{
+		{name: "eks-fargate without template", provisioner: "eks-fargate", strategy: "managed"},
+		{name: "eks-fargate with template", provisioner: "eks-fargate", strategy: "managed", templateRef: "workers", wantErr: true},
+		{name: "eks-fargate with cluster configuration", provisioner: "eks-fargate", strategy: "managed", clusterRef: "production", wantErr: true},
+		{name: "eks-fargate with kubeconfig", provisioner: "eks-fargate", strategy: "managed", kubeconfig: &KubeconfigSecretReference{Name: "workload-cluster"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ig := MockInstanceGroup(tt.provisioner, tt.strategy)
+			ig.Spec.TemplateRef = tt.templateRef
+			ig.Spec.ClusterRef = tt.clusterRef
+			ig.Spec.KubeconfigSecretRef = tt.kubeconfig
+			err := ig.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%v: got error %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKubeconfigSecretReferenceKey(t *testing.T) {
+	ref := &KubeconfigSecretReference{Name: "workload-cluster"}
+	if ref.GetKey() != DefaultKubeconfigSecretKey {
+		t.Errorf("got key %v, expected %v", ref.GetKey(), DefaultKubeconfigSecretKey)
+	}
+	ref.Key = "value"
+	if ref.GetKey() != "value" {
+		t.Errorf("got key %v, expected value", ref.GetKey())
+	}
+}
+
+func TestInstanceGroupMachinePoolOwner(t *testing.T) {
+	tests := []struct {
+		owners   []metav1.OwnerReference
+		expected string
+	}{
+		{owners: nil, expected: ""},
+		{owners: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "some-deployment"}}, expected: ""},
+		{owners: []metav1.OwnerReference{{APIVersion: "example.com/v1", Kind: MachinePoolKind, Name: "other-pool"}}, expected: ""},
+		{owners: []metav1.OwnerReference{{APIVersion: "cluster.x-k8s.io/v1beta1", Kind: MachinePoolKind, Name: "some-pool"}}, expected: "some-pool"},
+		{owners: []metav1.OwnerReference{{APIVersion: "exp.cluster.x-k8s.io/v1alpha3", Kind: MachinePoolKind, Name: "some-pool"}}, expected: "some-pool"},
+	}
+
+	for i, tc := range tests {
+		ig := MockInstanceGroup("eks", "rollingUpdate")
+		ig.SetOwnerReferences(tc.owners)
+		var name string
+		if owner := ig.GetMachinePoolOwner(); owner != nil {
+			name = owner.Name
+		}
+		if name != tc.expected {
+			t.Errorf("Test Case %d: got owner '%v', expected '%v'", i, name, tc.expected)
+		}
+	}
+}
+
+func TestInstanceGroupHashConfiguration(t *testing.T) {
+	ig := MockInstanceGroup("eks", "rollingUpdate")
+	hash := ig.HashConfiguration()
+	if hash == "" {
+		t.Fatal("expected a configuration hash")
+	}
+
+	// provider IDs reported by the controller do not change the configuration
+	ig.Spec.ProviderIDList = []string{"aws:///us-west-2a/i-0123456789abcdef0"}
+	if ig.HashConfiguration() != hash {
+		t.Errorf("expected provider IDs to not change the configuration hash")
+	}
+
+	changes := map[string]func(*InstanceGroup){
+		"spec":           func(ig *InstanceGroup) { ig.Spec.EKSFargateSpec.ClusterName = "some-cluster" },
+		"resolved image": func(ig *InstanceGroup) { ig.Status.SetResolvedImage("ami-0123456789abcdef0") },
+		"rotation":       func(ig *InstanceGroup) { ig.Status.SetRotationCounter(1) },
+	}
+	for name, change := range changes {
+		changed := ig.DeepCopy()
+		change(changed)
+		if changed.HashConfiguration() == hash {
+			t.Errorf("%v: expected the configuration hash to change", name)
+		}
+	}
+}
+
+func TestInstanceGroupIsRolledOut(t *testing.T) {
+	tests := []struct {
+		generation         int64
+		observedGeneration int64
+		configurationHash  string
+		rolloutHash        string
+		expected           bool
+	}{
+		{generation: 1, observedGeneration: 0, expected: false},
+		{generation: 2, observedGeneration: 1, configurationHash: "a", rolloutHash: "a", expected: false},
+		{generation: 2, observedGeneration: 2, configurationHash: "b", rolloutHash: "a", expected: false},
+		{generation: 2, observedGeneration: 2, configurationHash: "b", rolloutHash: "b", expected: true},
+	}
+
+	for i, tc := range tests {
+		ig := MockInstanceGroup("eks", "rollingUpdate")
+		ig.SetGeneration(tc.generation)
+		ig.Status.SetObservedGeneration(tc.observedGeneration)
+		ig.Status.SetConfigurationHash(tc.configurationHash)
+		ig.Status.SetRolloutHash(tc.rolloutHash)
+		if got := ig.IsRolledOut(); got != tc.expected {
+			t.Errorf("Test Case %d: got %v, expected %v", i, got, tc.expected)
+		}
+	}
+}
+
+func TestInstanceGroupStatusAddRevision(t *testing.T) {
+	status := &InstanceGroupStatus{}
+	for i := 1; i <= 5; i++ {
+		status.AddRevision(ConfigurationRevision{Revision: i, ConfigurationHash: fmt.Sprintf("hash-%v", i)}, 3)
+	}
+
+	revisions := status.GetRevisions()
+	if len(revisions) != 3 || revisions[0].Revision != 3 || revisions[2].Revision != 5 {
+		t.Fatalf("expected revisions 3 to 5, got %+v", revisions)
+	}
+	if latest := status.GetLatestRevision(); latest == nil || latest.Revision != 5 {
+		t.Errorf("expected latest revision 5, got %+v", latest)
+	}
+	if status.GetRevision(2) != nil {
+		t.Errorf("expected revision 2 to be removed from the history")
+	}
+}
+
+func TestInstanceGroupGetRollbackRevision(t *testing.T) {
+	ig := MockInstanceGroup("eks", "rollingUpdate")
+	ig.Status.SetConfigurationHash("hash-3")
+	for i := 1; i <= 3; i++ {
+		ig.Status.AddRevision(ConfigurationRevision{Revision: i, ConfigurationHash: fmt.Sprintf("hash-%v", i)}, 10)
+	}
+
+	tests := []struct {
+		request   string
+		expected  int
+		shouldErr bool
+	}{
+		{request: "previous", expected: 2},
+		{request: "Previous", expected: 2},
+		{request: "1", expected: 1},
+		{request: "3", expected: 3},
+		{request: "4", shouldErr: true},
+		{request: "latest", shouldErr: true},
+	}
+
+	for i, tc := range tests {
+		revision, err := ig.GetRollbackRevision(tc.request)
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test Case %d: expected error for request %v", i, tc.request)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test Case %d: unexpected error: %v", i, err)
+			continue
+		}
+		if revision.Revision != tc.expected {
+			t.Errorf("Test Case %d: got revision %v, expected %v", i, revision.Revision, tc.expected)
+		}
+	}
+
+	// without a revision with a different configuration there is no previous revision
+	ig.Status.Revisions = []ConfigurationRevision{{Revision: 1, ConfigurationHash: "hash-3"}}
+	if _, err := ig.GetRollbackRevision(PreviousRollbackRequest); err == nil {
+		t.Errorf("expected error without a previous revision")
+	}
+}
+
+func TestConfigurationChanges(t *testing.T) {
+	previous := &EKSConfiguration{
+		Image:        "ami-123456789012",
+		InstanceType: "m5.large",
+		KeyPairName:  "key",
+		Volumes:      []NodeVolume{{Name: "/dev/xvda", Type: "gp2", Size: 30}},
+	}
+	current := previous.DeepCopy()
+	current.Image = "ami-210987654321"
+	current.KeyPairName = ""
+	current.BootstrapArguments = "--max-pods=110"
+	current.Volumes[0].Size = 50
+
+	expected := []string{
+		"spec.eks.configuration.bootstrapArguments: <unset> -> --max-pods=110",
+		"spec.eks.configuration.image: ami-123456789012 -> ami-210987654321",
+		"spec.eks.configuration.keyPairName: key -> <unset>",
+		"spec.eks.configuration.volumes changed",
+	}
+	if changes := ConfigurationChanges(previous, current); !reflect.DeepEqual(changes, expected) {
+		t.Errorf("got changes %v, expected %v", changes, expected)
+	}
+
+	if changes := ConfigurationChanges(previous, previous.DeepCopy()); len(changes) != 0 {
+		t.Errorf("expected no changes, got %v", changes)
+	}
+	if changes := ConfigurationChanges(nil, previous); len(changes) != 4 {
+		t.Errorf("expected all fields to change from an empty configuration, got %v", changes)
+	}
+}
+
+func TestInstanceGroupHandleRotateRequest(t *testing.T) {
+	ig := MockInstanceGroup("eks", "rollingUpdate")
+	status := ig.GetStatus()
+
+	steps := []struct {
+		name     string
+		request  string
+		expected bool
+		counter  int
+	}{
+		{name: "no request", request: "", expected: false, counter: 0},
+		{name: "one-off request", request: "true", expected: true, counter: 1},
+		{name: "handled one-off request", request: "true", expected: false, counter: 1},
+		{name: "annotation removed", request: "", expected: false, counter: 1},
+		{name: "another one-off request", request: "true", expected: true, counter: 2},
+		{name: "timestamp request", request: "2020-10-16T10:00:00Z", expected: true, counter: 3},
+		{name: "handled timestamp request", request: "2020-10-16T10:00:00Z", expected: false, counter: 3},
+		{name: "new timestamp request", request: "2020-10-17T10:00:00Z", expected: true, counter: 4},
+	}
+	for _, step := range steps {
+		ig.SetAnnotations(map[string]string{})
+		if step.request != "" {
+			ig.SetAnnotations(map[string]string{RotateAnnotationKey: step.request})
+		}
+		if got := ig.HandleRotateRequest(); got != step.expected {
+			t.Errorf("%v: got %v, expected %v", step.name, got, step.expected)
+		}
+		if status.GetRotationCounter() != step.counter {
+			t.Errorf("%v: got rotation counter %v, expected %v", step.name, status.GetRotationCounter(), step.counter)
+		}
+		if status.GetRotateRequest() != step.request {
+			t.Errorf("%v: got recorded request %v, expected %v", step.name, status.GetRotateRequest(), step.request)
+		}
+	}
+}
+
+func TestCRDUpdateStrategyRollingUpgrade(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy CRDUpdateStrategy
+		wantErr  bool
+	}{
+		{name: "rolling upgrade", strategy: CRDUpdateStrategy{RollingUpgrade: &RollingUpgradeSpec{NodeIntervalSeconds: 30}}},
+		{name: "rolling upgrade with spec", strategy: CRDUpdateStrategy{Spec: "kind: Dog", RollingUpgrade: &RollingUpgradeSpec{}}, wantErr: true},
+		{name: "invalid strategy type", strategy: CRDUpdateStrategy{RollingUpgrade: &RollingUpgradeSpec{Strategy: &RollingUpgradeStrategySpec{Type: "allAtOnce"}}}, wantErr: true},
+		{name: "invalid mode", strategy: CRDUpdateStrategy{RollingUpgrade: &RollingUpgradeSpec{Strategy: &RollingUpgradeStrategySpec{Mode: "fast"}}}, wantErr: true},
+		{name: "negative drain timeout", strategy: CRDUpdateStrategy{RollingUpgrade: &RollingUpgradeSpec{Strategy: &RollingUpgradeStrategySpec{DrainTimeout: -1}}}, wantErr: true},
+		{name: "negative node interval", strategy: CRDUpdateStrategy{RollingUpgrade: &RollingUpgradeSpec{NodeIntervalSeconds: -1}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.strategy.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%v: got error %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if tt.strategy.GetCRDName() != RollingUpgradeCRDName || tt.strategy.GetStatusJSONPath() != RollingUpgradeStatusJSONPath {
+				t.Errorf("%v: got crdName %v and statusJSONPath %v", tt.name, tt.strategy.GetCRDName(), tt.strategy.GetStatusJSONPath())
+			}
+			if !strings.Contains(tt.strategy.GetSpec(), "kind: RollingUpgrade") || !strings.Contains(tt.strategy.GetSpec(), "nodeIntervalSeconds: 30") {
+				t.Errorf("%v: unexpected spec %v", tt.name, tt.strategy.GetSpec())
+			}
+		})
+	}
+}
@@ -0,0 +1,278 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/keikoproj/instance-manager/controllers/common"
+	awsprovider "github.com/keikoproj/instance-manager/controllers/providers/aws"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// SetupWebhookWithManager registers the instance group admission webhooks with the manager
+func (ig *InstanceGroup) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(ig).
+		Complete()
+}
+
+// +kubebuilder:webhook:verbs=create;update;delete,path=/validate-instancemgr-keikoproj-io-v1alpha1-instancegroup,mutating=false,failurePolicy=fail,groups=instancemgr.keikoproj.io,resources=instancegroups,versions=v1alpha1,name=vinstancegroup.kb.io
+
+var _ webhook.Validator = &InstanceGroup{}
+
+// EnforceUserDataSizeLimit controls whether user data which cannot fit the maximum size is rejected, it is disabled when
+// the controller is configured to upload large user data to a bootstrap bucket
+var EnforceUserDataSizeLimit = true
+
+// DeletionGuard refuses deletion of instance groups whose nodes run protected workloads, it is disabled when nil
+var DeletionGuard *WorkloadDeletionGuard
+
+// InstanceGroupValidator validates instance groups at admission
+type InstanceGroupValidator interface {
+	Validate(ig *InstanceGroup) error
+}
+
+// PolicyValidator rejects instance groups which violate the field policies of the controller configuration, it is
+// disabled when nil
+var PolicyValidator InstanceGroupValidator
+
+// Policy actions of the security policy, violations of instance groups are either rejected or remediated
+const (
+	PolicyActionReject    = "reject"
+	PolicyActionRemediate = "remediate"
+)
+
+// InstanceGroupSecurityPolicy is the cluster-wide security policy which applies to all instance groups, it is enforced
+// by the webhook and the reconciler
+var InstanceGroupSecurityPolicy SecurityPolicy
+
+// SecurityPolicy requires security settings of all instance groups regardless of their spec, violations of the spec
+// are rejected or remediated depending on the action
+type SecurityPolicy struct {
+	// RequireEncryptedVolumes requires all volumes of eks instance groups to be encrypted
+	RequireEncryptedVolumes bool
+	// RequireIMDSv2 requires instances to use session tokens for the instance metadata service, which the instance
+	// group spec has no setting for, so it is always remediated on the instances
+	RequireIMDSv2 bool
+	// Action is reject or remediate, reject is the default
+	Action string
+}
+
+// IsRemediate returns true if violations are remediated instead of rejected
+func (p SecurityPolicy) IsRemediate() bool {
+	return strings.EqualFold(p.Action, PolicyActionRemediate)
+}
+
+// Violations returns the violations of the policy by the spec of an instance group
+func (p SecurityPolicy) Violations(ig *InstanceGroup) []string {
+	violations := make([]string, 0)
+	if !strings.EqualFold(ig.Spec.Provisioner, EKSProvisionerName) || ig.Spec.EKSSpec == nil || ig.GetEKSConfiguration() == nil {
+		return violations
+	}
+	if p.RequireEncryptedVolumes {
+		for _, volume := range ig.GetEKSConfiguration().GetVolumes() {
+			if volume.Encrypted == nil || !*volume.Encrypted {
+				violations = append(violations, fmt.Sprintf("volume '%v' must be encrypted", volume.Name))
+			}
+		}
+	}
+	return violations
+}
+
+// Remediate changes the spec of an instance group to comply with the policy
+func (p SecurityPolicy) Remediate(ig *InstanceGroup) {
+	if !p.RequireEncryptedVolumes || len(p.Violations(ig)) == 0 {
+		return
+	}
+	volumes := ig.GetEKSConfiguration().GetVolumes()
+	for i := range volumes {
+		volumes[i].Encrypted = aws.Bool(true)
+	}
+}
+
+// Validate returns an error listing the violations of the policy by an instance group, unless they are remediated
+func (p SecurityPolicy) Validate(ig *InstanceGroup) error {
+	if p.IsRemediate() {
+		return nil
+	}
+	if violations := p.Violations(ig); len(violations) > 0 {
+		return errors.Errorf("validation failed, instancegroup %v violates the security policy: %v", ig.NamespacedName(), strings.Join(violations, ", "))
+	}
+	return nil
+}
+
+// WorkloadDeletionGuard protects pods from deletion of the instance group they run on, pods are protected if they are in
+// one of the namespaces or match the selector
+type WorkloadDeletionGuard struct {
+	Kubernetes kubernetes.Interface
+	AwsWorker  awsprovider.AwsWorker
+	Namespaces []string
+	Selector   labels.Selector
+}
+
+// ValidateCreate implements webhook.Validator
+func (ig *InstanceGroup) ValidateCreate() error {
+	if err := InstanceGroupSecurityPolicy.Validate(ig); err != nil {
+		return err
+	}
+	if PolicyValidator != nil {
+		if err := PolicyValidator.Validate(ig); err != nil {
+			return err
+		}
+	}
+	return ig.validateUserDataSize()
+}
+
+// ValidateUpdate implements webhook.Validator, updates which do not change the spec are admitted, so that the controller
+// can update the finalizers and annotations of instance groups which violate policies introduced after they were created
+func (ig *InstanceGroup) ValidateUpdate(old runtime.Object) error {
+	if !ig.isSpecUpdate(old) {
+		return nil
+	}
+	if err := InstanceGroupSecurityPolicy.Validate(ig); err != nil {
+		return err
+	}
+	if PolicyValidator != nil {
+		if err := PolicyValidator.Validate(ig); err != nil {
+			return err
+		}
+	}
+	return ig.validateUserDataSize()
+}
+
+// isSpecUpdate returns true if an update changes the spec of an instance group which is not being deleted
+func (ig *InstanceGroup) isSpecUpdate(old runtime.Object) bool {
+	if !ig.GetDeletionTimestamp().IsZero() {
+		return false
+	}
+	previous, ok := old.(*InstanceGroup)
+	return !ok || !reflect.DeepEqual(previous.Spec, ig.Spec)
+}
+
+// ValidateDelete implements webhook.Validator, deletion is refused while deletion protection is enabled
+func (ig *InstanceGroup) ValidateDelete() error {
+	if ig.IsDeletionProtected() {
+		return errors.Errorf("instancegroup %v has deletion protection enabled, remove the annotation '%v' before deleting", ig.NamespacedName(), DeletionProtectionAnnotationKey)
+	}
+	if DeletionGuard != nil && !ig.IsForceDelete() {
+		return DeletionGuard.Validate(ig)
+	}
+	return nil
+}
+
+// Validate returns an error if nodes of the instance group's scaling group run protected pods
+func (g *WorkloadDeletionGuard) Validate(ig *InstanceGroup) error {
+	var (
+		scalingGroupName = ig.GetStatus().GetActiveScalingGroupName()
+		nodeNames        = make(map[string]bool)
+		protected        = make([]string, 0)
+	)
+
+	// instance groups without a scaling group have no nodes to protect
+	if common.StringEmpty(scalingGroupName) {
+		return nil
+	}
+
+	instanceIds, err := g.AwsWorker.GetScalingGroupInstanceIds(scalingGroupName)
+	if err != nil {
+		return errors.Wrapf(err, "failed to describe scaling group %v", scalingGroupName)
+	}
+
+	nodes, err := g.Kubernetes.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return errors.Wrap(err, "failed to list nodes")
+	}
+	for _, node := range nodes.Items {
+		if common.ContainsString(instanceIds, common.GetLastElementBy(node.Spec.ProviderID, "/")) {
+			nodeNames[node.GetName()] = true
+		}
+	}
+
+	if len(nodeNames) == 0 {
+		return nil
+	}
+
+	pods, err := g.Kubernetes.CoreV1().Pods(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		return errors.Wrap(err, "failed to list pods")
+	}
+	for _, pod := range pods.Items {
+		if !nodeNames[pod.Spec.NodeName] || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		if g.IsProtected(pod) {
+			protected = append(protected, pod.GetNamespace()+"/"+pod.GetName())
+		}
+	}
+
+	if len(protected) > 0 {
+		return errors.Errorf("instancegroup %v nodes are running protected pods %v, annotate with '%v: \"true\"' to force deletion", ig.NamespacedName(), protected, ForceDeleteAnnotationKey)
+	}
+	return nil
+}
+
+// IsProtected returns true if a pod is in a protected namespace or matches the protected selector
+func (g *WorkloadDeletionGuard) IsProtected(pod corev1.Pod) bool {
+	if common.ContainsString(g.Namespaces, pod.GetNamespace()) {
+		return true
+	}
+	return g.Selector != nil && !g.Selector.Empty() && g.Selector.Matches(labels.Set(pod.GetLabels()))
+}
+
+// validateUserDataSize returns an error if the user data stages of an eks instance group exceed the maximum user data
+// size even when compressed, the rendered user data is larger, so this only catches payloads which can never fit
+func (ig *InstanceGroup) validateUserDataSize() error {
+	if !EnforceUserDataSizeLimit {
+		return nil
+	}
+	if !strings.EqualFold(ig.Spec.Provisioner, EKSProvisionerName) || ig.Spec.EKSSpec == nil || ig.GetEKSConfiguration() == nil {
+		return nil
+	}
+
+	var payload bytes.Buffer
+	for _, stage := range ig.GetEKSConfiguration().GetUserData() {
+		data, err := common.GetDecodedString(stage.Data)
+		if err != nil {
+			return errors.Wrapf(err, "validation failed, failed to decode userData stage '%v'", stage.Name)
+		}
+		payload.WriteString(data)
+	}
+
+	if payload.Len() <= awsprovider.UserDataMaxSize {
+		return nil
+	}
+
+	compressed, err := common.GzipBytes(payload.Bytes())
+	if err != nil {
+		return errors.Wrap(err, "validation failed, failed to compress userData")
+	}
+	if len(compressed) > awsprovider.UserDataMaxSize {
+		return errors.Errorf("validation failed, userData is %v bytes when compressed, the maximum is %v bytes", len(compressed), awsprovider.UserDataMaxSize)
+	}
+	return nil
+}
@@ -0,0 +1,274 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1alpha1
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/autoscaling/autoscalingiface"
+	awsprovider "github.com/keikoproj/instance-manager/controllers/providers/aws"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+type mockAutoScalingClient struct {
+	autoscalingiface.AutoScalingAPI
+	instanceIds []string
+}
+
+func (a *mockAutoScalingClient) DescribeAutoScalingGroupsWithContext(ctx aws.Context, input *autoscaling.DescribeAutoScalingGroupsInput, opts ...request.Option) (*autoscaling.DescribeAutoScalingGroupsOutput, error) {
+	group := &autoscaling.Group{AutoScalingGroupName: input.AutoScalingGroupNames[0]}
+	for _, id := range a.instanceIds {
+		group.Instances = append(group.Instances, &autoscaling.Instance{InstanceId: aws.String(id)})
+	}
+	return &autoscaling.DescribeAutoScalingGroupsOutput{AutoScalingGroups: []*autoscaling.Group{group}}, nil
+}
+
+func TestInstanceGroupValidateDelete(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		wantErr     bool
+	}{
+		{name: "no annotation", annotations: nil, wantErr: false},
+		{name: "protection enabled", annotations: map[string]string{DeletionProtectionAnnotationKey: "enabled"}, wantErr: true},
+		{name: "protection enabled mixed case", annotations: map[string]string{DeletionProtectionAnnotationKey: "Enabled"}, wantErr: true},
+		{name: "protection disabled", annotations: map[string]string{DeletionProtectionAnnotationKey: "disabled"}, wantErr: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ig := MockInstanceGroup("eks", "rollingUpdate")
+			ig.SetAnnotations(tt.annotations)
+			err := ig.ValidateDelete()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%v: got error %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInstanceGroupValidateDeleteGuard(t *testing.T) {
+	mockPod := func(name, namespace, nodeName string, labels map[string]string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels},
+			Spec:       corev1.PodSpec{NodeName: nodeName},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+	}
+	mockNode := func(name, instanceId string) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       corev1.NodeSpec{ProviderID: "aws:///us-west-2a/" + instanceId},
+		}
+	}
+	selector, _ := labels.Parse("tier=critical")
+
+	tests := []struct {
+		name        string
+		pod         *corev1.Pod
+		annotations map[string]string
+		wantErr     bool
+	}{
+		{name: "unprotected pod", pod: mockPod("web", "default", "node-1", nil), wantErr: false},
+		{name: "protected namespace", pod: mockPod("coredns", "kube-system", "node-1", nil), wantErr: true},
+		{name: "protected label", pod: mockPod("db", "default", "node-1", map[string]string{"tier": "critical"}), wantErr: true},
+		{name: "protected pod on other group", pod: mockPod("coredns", "kube-system", "node-2", nil), wantErr: false},
+		{name: "force delete", pod: mockPod("coredns", "kube-system", "node-1", nil), annotations: map[string]string{ForceDeleteAnnotationKey: "true"}, wantErr: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kube := fake.NewSimpleClientset(mockNode("node-1", "i-1111"), mockNode("node-2", "i-2222"), tt.pod)
+			DeletionGuard = &WorkloadDeletionGuard{
+				Kubernetes: kube,
+				AwsWorker:  awsprovider.AwsWorker{AsgClient: &mockAutoScalingClient{instanceIds: []string{"i-1111"}}},
+				Namespaces: []string{"kube-system"},
+				Selector:   selector,
+			}
+			defer func() { DeletionGuard = nil }()
+
+			ig := MockInstanceGroup("eks", "rollingUpdate")
+			ig.SetAnnotations(tt.annotations)
+			ig.GetStatus().SetActiveScalingGroupName("some-scaling-group")
+			err := ig.ValidateDelete()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%v: got error %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInstanceGroupValidateUserDataSize(t *testing.T) {
+	random := make([]byte, 32768)
+	rand.New(rand.NewSource(1)).Read(random)
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{name: "small user data", data: "echo hello", wantErr: false},
+		{name: "large compressible user data", data: strings.Repeat("echo hello\n", 3000), wantErr: false},
+		{name: "large incompressible user data", data: hex.EncodeToString(random), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ig := MockInstanceGroup("eks", "rollingUpdate")
+			ig.Spec.EKSSpec = &EKSSpec{
+				EKSConfiguration: &EKSConfiguration{
+					UserData: []UserDataStage{
+						{Name: "stage", Stage: PreBootstrapStage, Data: tt.data},
+					},
+				},
+			}
+			err := ig.ValidateCreate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%v: got error %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSecurityPolicy(t *testing.T) {
+	volumes := func(encrypted ...*bool) []NodeVolume {
+		var list []NodeVolume
+		for i, e := range encrypted {
+			list = append(list, NodeVolume{Name: fmt.Sprintf("/dev/xvd%c", 'a'+i), Type: "gp2", Size: 20, Encrypted: e})
+		}
+		return list
+	}
+	tests := []struct {
+		name           string
+		policy         SecurityPolicy
+		volumes        []NodeVolume
+		wantViolations int
+		wantErr        bool
+	}{
+		{name: "no policy", policy: SecurityPolicy{}, volumes: volumes(nil, aws.Bool(false)), wantViolations: 0, wantErr: false},
+		{name: "encrypted volumes", policy: SecurityPolicy{RequireEncryptedVolumes: true}, volumes: volumes(aws.Bool(true), aws.Bool(true)), wantViolations: 0, wantErr: false},
+		{name: "unencrypted volumes rejected", policy: SecurityPolicy{RequireEncryptedVolumes: true}, volumes: volumes(nil, aws.Bool(false), aws.Bool(true)), wantViolations: 2, wantErr: true},
+		{name: "unencrypted volumes remediated", policy: SecurityPolicy{RequireEncryptedVolumes: true, Action: PolicyActionRemediate}, volumes: volumes(nil, aws.Bool(false)), wantViolations: 2, wantErr: false},
+		{name: "imdsv2 has no spec violations", policy: SecurityPolicy{RequireIMDSv2: true}, volumes: volumes(nil), wantViolations: 0, wantErr: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ig := MockInstanceGroup("eks", "rollingUpdate")
+			ig.Spec.EKSSpec = &EKSSpec{
+				EKSConfiguration: &EKSConfiguration{Volumes: tt.volumes},
+			}
+
+			if got := tt.policy.Violations(&ig); len(got) != tt.wantViolations {
+				t.Errorf("%v: got violations %v, want %v", tt.name, got, tt.wantViolations)
+			}
+
+			InstanceGroupSecurityPolicy = tt.policy
+			defer func() { InstanceGroupSecurityPolicy = SecurityPolicy{} }()
+			err := ig.ValidateCreate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%v: got error %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+
+			tt.policy.Remediate(&ig)
+			if tt.policy.RequireEncryptedVolumes && len(tt.policy.Violations(&ig)) != 0 {
+				t.Errorf("%v: got violations after remediation %v", tt.name, tt.policy.Violations(&ig))
+			}
+		})
+	}
+}
+
+func TestInstanceGroupValidateUpdateSecurityPolicy(t *testing.T) {
+	InstanceGroupSecurityPolicy = SecurityPolicy{RequireEncryptedVolumes: true}
+	defer func() { InstanceGroupSecurityPolicy = SecurityPolicy{} }()
+
+	// the instance group was created before the policy required encrypted volumes
+	old := MockInstanceGroup("eks", "rollingUpdate")
+	old.Spec.EKSSpec = &EKSSpec{
+		EKSConfiguration: &EKSConfiguration{Volumes: []NodeVolume{{Name: "/dev/xvda", Type: "gp2", Size: 20}}},
+	}
+	old.SetFinalizers([]string{"finalizer.instancegroups.keikoproj.io"})
+
+	tests := []struct {
+		name    string
+		update  func(ig *InstanceGroup)
+		wantErr bool
+	}{
+		{name: "annotations", update: func(ig *InstanceGroup) {
+			ig.SetAnnotations(map[string]string{"instancemgr.keikoproj.io/rotate": "true"})
+		}, wantErr: false},
+		{name: "spec", update: func(ig *InstanceGroup) {
+			ig.GetEKSConfiguration().Volumes[0].Size = 40
+		}, wantErr: true},
+		{name: "finalizer removal while deleting", update: func(ig *InstanceGroup) {
+			ig.SetDeletionTimestamp(&metav1.Time{Time: time.Now()})
+			ig.SetFinalizers(nil)
+		}, wantErr: false},
+		{name: "spec while deleting", update: func(ig *InstanceGroup) {
+			ig.SetDeletionTimestamp(&metav1.Time{Time: time.Now()})
+			ig.GetEKSConfiguration().Volumes[0].Size = 40
+		}, wantErr: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ig := old.DeepCopy()
+			tt.update(ig)
+			err := ig.ValidateUpdate(&old)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("%v: got error %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+type rejectingValidator struct{}
+
+func (rejectingValidator) Validate(ig *InstanceGroup) error {
+	return errors.Errorf("instancegroup %v violates policies", ig.NamespacedName())
+}
+
+func TestInstanceGroupValidatePolicies(t *testing.T) {
+	PolicyValidator = rejectingValidator{}
+	defer func() { PolicyValidator = nil }()
+
+	old := MockInstanceGroup("eks", "rollingUpdate")
+	if err := old.ValidateCreate(); err == nil {
+		t.Errorf("create: expected policy violation")
+	}
+
+	// instance groups which violate a policy added after they were created can be updated by the controller and deleted
+	ig := old.DeepCopy()
+	ig.SetAnnotations(map[string]string{"instancemgr.keikoproj.io/rotate": "true"})
+	if err := ig.ValidateUpdate(&old); err != nil {
+		t.Errorf("annotations: got error %v", err)
+	}
+	ig.SetDeletionTimestamp(&metav1.Time{Time: time.Now()})
+	if err := ig.ValidateUpdate(&old); err != nil {
+		t.Errorf("deleting: got error %v", err)
+	}
+
+	ig = old.DeepCopy()
+	ig.Spec.Provisioner = "eks-managed"
+	if err := ig.ValidateUpdate(&old); err == nil {
+		t.Errorf("spec: expected policy violation")
+	}
+}
@@ -0,0 +1,155 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// InstanceGroupLabelKey is the label of objects recorded for an instance group, its value is the instance group's name
+	InstanceGroupLabelKey = "instancemgr.keikoproj.io/instancegroup"
+
+	// RevisionOutcomeSucceeded is the outcome of a change which was rolled out to all nodes
+	RevisionOutcomeSucceeded = "Succeeded"
+	// RevisionOutcomeFailed is the outcome of a change whose rotation of nodes failed
+	RevisionOutcomeFailed = "Failed"
+)
+
+// InstanceGroupRevision is the Schema for the instancegrouprevisions API, it records a change applied to an instance
+// group and the outcome of its rollout, revisions are owned by the instance group and form its audit trail
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=instancegrouprevisions,scope=Namespaced,shortName=igr
+// +kubebuilder:printcolumn:name="Instance Group",type="string",JSONPath=".spec.instanceGroup",description="name of the instance group"
+// +kubebuilder:printcolumn:name="Outcome",type="string",JSONPath=".spec.outcome",description="outcome of the rollout of the change"
+// +kubebuilder:printcolumn:name="Actor",type="string",JSONPath=".spec.actor",description="field manager which changed the spec"
+// +kubebuilder:printcolumn:name="Recorded",type="date",JSONPath=".spec.recordedAt",description="time the outcome was recorded"
+type InstanceGroupRevision struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec InstanceGroupRevisionSpec `json:"spec"`
+}
+
+// InstanceGroupRevisionList contains a list of InstanceGroupRevision
+// +kubebuilder:object:root=true
+type InstanceGroupRevisionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []InstanceGroupRevision `json:"items"`
+}
+
+// InstanceGroupRevisionSpec describes a change applied to an instance group
+type InstanceGroupRevisionSpec struct {
+	// InstanceGroup is the name of the instance group the change was applied to
+	InstanceGroup string `json:"instanceGroup"`
+	// Generation is the generation of the instance group's spec which was applied
+	Generation int64 `json:"generation,omitempty"`
+	// ConfigurationHash is the hash of the resolved configuration of the change
+	ConfigurationHash string `json:"configurationHash"`
+	// Changes summarizes the fields of spec.eks.configuration which changed since the previous revision
+	Changes []string `json:"changes,omitempty"`
+	// LaunchConfigurationName is the launch configuration which nodes of the change are launched with
+	LaunchConfigurationName string `json:"launchConfigurationName,omitempty"`
+	// Image is the resolved image of the change
+	Image string `json:"image,omitempty"`
+	// InstanceType is the instance type of the change
+	InstanceType string `json:"instanceType,omitempty"`
+	// Outcome is the outcome of the rollout of the change, Succeeded or Failed
+	Outcome string `json:"outcome"`
+	// Error is the error of a failed rotation of nodes
+	Error string `json:"error,omitempty"`
+	// Actor is the field manager which last changed the spec of the instance group, e.g. kubectl or a GitOps controller
+	Actor string `json:"actor,omitempty"`
+	// Configuration is spec.eks.configuration of the instance group, the changes of the next revision are relative to it
+	Configuration *EKSConfiguration `json:"configuration,omitempty"`
+	// RecordedAt is the time the outcome of the change was recorded
+	RecordedAt metav1.Time `json:"recordedAt,omitempty"`
+}
+
+// ConfigurationChanges summarizes the fields which differ between two configurations, fields with a single value
+// are listed with their previous and current value, other fields are listed by name
+func ConfigurationChanges(previous, current *EKSConfiguration) []string {
+	var (
+		previousFields = configurationFields(previous)
+		currentFields  = configurationFields(current)
+		names          = make([]string, 0)
+		changes        = make([]string, 0)
+	)
+
+	for name := range previousFields {
+		names = append(names, name)
+	}
+	for name := range currentFields {
+		if _, ok := previousFields[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		previousValue, currentValue := previousFields[name], currentFields[name]
+		if reflect.DeepEqual(previousValue, currentValue) {
+			continue
+		}
+		path := fmt.Sprintf("spec.eks.configuration.%v", name)
+		if isScalarField(previousValue) && isScalarField(currentValue) {
+			changes = append(changes, fmt.Sprintf("%v: %v -> %v", path, fieldString(previousValue), fieldString(currentValue)))
+			continue
+		}
+		changes = append(changes, fmt.Sprintf("%v changed", path))
+	}
+	return changes
+}
+
+func configurationFields(configuration *EKSConfiguration) map[string]interface{} {
+	fields := make(map[string]interface{})
+	if configuration == nil {
+		return fields
+	}
+	raw, err := json.Marshal(configuration)
+	if err != nil {
+		return fields
+	}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return fields
+	}
+	return fields
+}
+
+func isScalarField(value interface{}) bool {
+	switch value.(type) {
+	case nil, string, bool, float64:
+		return true
+	}
+	return false
+}
+
+func fieldString(value interface{}) string {
+	if value == nil {
+		return "<unset>"
+	}
+	return fmt.Sprint(value)
+}
+
+func init() {
+	SchemeBuilder.Register(&InstanceGroupRevision{}, &InstanceGroupRevisionList{})
+}
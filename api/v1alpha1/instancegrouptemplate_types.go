@@ -0,0 +1,52 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// InstanceGroupTemplate is the Schema for the instancegrouptemplates API, it holds configuration which is inherited
+// by the instance groups referencing it with spec.templateRef
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=instancegrouptemplates,scope=Cluster,shortName=igt
+type InstanceGroupTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec InstanceGroupTemplateSpec `json:"spec"`
+}
+
+// InstanceGroupTemplateList contains a list of InstanceGroupTemplate
+// +kubebuilder:object:root=true
+type InstanceGroupTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []InstanceGroupTemplate `json:"items"`
+}
+
+// InstanceGroupTemplateSpec defines the configuration inherited by instance groups of the eks provisioner, fields are
+// merged into spec.eks.configuration and the instance group's own values take precedence
+type InstanceGroupTemplateSpec struct {
+	NodeSecurityGroups []string            `json:"securityGroups,omitempty"`
+	Volumes            []NodeVolume        `json:"volumes,omitempty"`
+	Tags               []map[string]string `json:"tags,omitempty"`
+	UserData           []UserDataStage     `json:"userData,omitempty"`
+}
+
+func init() {
+	SchemeBuilder.Register(&InstanceGroupTemplate{}, &InstanceGroupTemplateList{})
+}
@@ -25,19 +25,49 @@ import (
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AlarmSpec) DeepCopyInto(out *AlarmSpec) {
+	*out = *in
+	if in.Threshold != nil {
+		in, out := &in.Threshold, &out.Threshold
+		*out = new(int64)
+		**out = **in
+	}
+	if in.Actions != nil {
+		in, out := &in.Actions, &out.Actions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AlarmSpec.
+func (in *AlarmSpec) DeepCopy() *AlarmSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AlarmSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AwsUpgradeStrategy) DeepCopyInto(out *AwsUpgradeStrategy) {
 	*out = *in
 	if in.CRDType != nil {
 		in, out := &in.CRDType, &out.CRDType
 		*out = new(CRDUpdateStrategy)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.RollingUpdateType != nil {
 		in, out := &in.RollingUpdateType, &out.RollingUpdateType
 		*out = new(RollingUpdateStrategy)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Drain != nil {
+		in, out := &in.Drain, &out.Drain
+		*out = new(DrainSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AwsUpgradeStrategy.
@@ -50,9 +80,203 @@ func (in *AwsUpgradeStrategy) DeepCopy() *AwsUpgradeStrategy {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackoffStatus) DeepCopyInto(out *BackoffStatus) {
+	*out = *in
+	if in.LastFailureTime != nil {
+		in, out := &in.LastFailureTime, &out.LastFailureTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackoffStatus.
+func (in *BackoffStatus) DeepCopy() *BackoffStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BackoffStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BootstrapFailure) DeepCopyInto(out *BootstrapFailure) {
+	*out = *in
+	in.LaunchTime.DeepCopyInto(&out.LaunchTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BootstrapFailure.
+func (in *BootstrapFailure) DeepCopy() *BootstrapFailure {
+	if in == nil {
+		return nil
+	}
+	out := new(BootstrapFailure)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BootstrapFailureDetectionSpec) DeepCopyInto(out *BootstrapFailureDetectionSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BootstrapFailureDetectionSpec.
+func (in *BootstrapFailureDetectionSpec) DeepCopy() *BootstrapFailureDetectionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BootstrapFailureDetectionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterBootstrapSpec) DeepCopyInto(out *ClusterBootstrapSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterBootstrapSpec.
+func (in *ClusterBootstrapSpec) DeepCopy() *ClusterBootstrapSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterBootstrapSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterConfiguration) DeepCopyInto(out *ClusterConfiguration) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterConfiguration.
+func (in *ClusterConfiguration) DeepCopy() *ClusterConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterConfiguration) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterConfigurationList) DeepCopyInto(out *ClusterConfigurationList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClusterConfiguration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterConfigurationList.
+func (in *ClusterConfigurationList) DeepCopy() *ClusterConfigurationList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterConfigurationList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterConfigurationList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterConfigurationSpec) DeepCopyInto(out *ClusterConfigurationSpec) {
+	*out = *in
+	if in.Subnets != nil {
+		in, out := &in.Subnets, &out.Subnets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SecurityGroups != nil {
+		in, out := &in.SecurityGroups, &out.SecurityGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Bootstrap != nil {
+		in, out := &in.Bootstrap, &out.Bootstrap
+		*out = new(ClusterBootstrapSpec)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterConfigurationSpec.
+func (in *ClusterConfigurationSpec) DeepCopy() *ClusterConfigurationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterConfigurationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CommitmentAdvisorSpec) DeepCopyInto(out *CommitmentAdvisorSpec) {
+	*out = *in
+	if in.CandidateInstanceTypes != nil {
+		in, out := &in.CandidateInstanceTypes, &out.CandidateInstanceTypes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CommitmentAdvisorSpec.
+func (in *CommitmentAdvisorSpec) DeepCopy() *CommitmentAdvisorSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CommitmentAdvisorSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigurationRevision) DeepCopyInto(out *ConfigurationRevision) {
+	*out = *in
+	in.RolledOutAt.DeepCopyInto(&out.RolledOutAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigurationRevision.
+func (in *ConfigurationRevision) DeepCopy() *ConfigurationRevision {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigurationRevision)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CRDUpdateStrategy) DeepCopyInto(out *CRDUpdateStrategy) {
 	*out = *in
+	if in.RollingUpgrade != nil {
+		in, out := &in.RollingUpgrade, &out.RollingUpgrade
+		*out = new(RollingUpgradeSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CRDUpdateStrategy.
@@ -65,6 +289,56 @@ func (in *CRDUpdateStrategy) DeepCopy() *CRDUpdateStrategy {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChangeWindow) DeepCopyInto(out *ChangeWindow) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChangeWindow.
+func (in *ChangeWindow) DeepCopy() *ChangeWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(ChangeWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DrainSpec) DeepCopyInto(out *DrainSpec) {
+	*out = *in
+	if in.GracePeriodSeconds != nil {
+		in, out := &in.GracePeriodSeconds, &out.GracePeriodSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.SkipDaemonSets != nil {
+		in, out := &in.SkipDaemonSets, &out.SkipDaemonSets
+		*out = new(bool)
+		**out = **in
+	}
+	if in.DeleteEmptyDirData != nil {
+		in, out := &in.DeleteEmptyDirData, &out.DeleteEmptyDirData
+		*out = new(bool)
+		**out = **in
+	}
+	if in.PreDrain != nil {
+		in, out := &in.PreDrain, &out.PreDrain
+		*out = new(PreDrainHook)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DrainSpec.
+func (in *DrainSpec) DeepCopy() *DrainSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DrainSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EKSConfiguration) DeepCopyInto(out *EKSConfiguration) {
 	*out = *in
@@ -137,6 +411,45 @@ func (in *EKSConfiguration) DeepCopyInto(out *EKSConfiguration) {
 		*out = make([]LifecycleHookSpec, len(*in))
 		copy(*out, *in)
 	}
+	if in.HealthCheckGracePeriod != nil {
+		in, out := &in.HealthCheckGracePeriod, &out.HealthCheckGracePeriod
+		*out = new(int64)
+		**out = **in
+	}
+	if in.Notifications != nil {
+		in, out := &in.Notifications, &out.Notifications
+		*out = make([]NotificationSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Alarms != nil {
+		in, out := &in.Alarms, &out.Alarms
+		*out = make([]AlarmSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.CommitmentAdvisor != nil {
+		in, out := &in.CommitmentAdvisor, &out.CommitmentAdvisor
+		*out = new(CommitmentAdvisorSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.UnhealthyNodeReplacement != nil {
+		in, out := &in.UnhealthyNodeReplacement, &out.UnhealthyNodeReplacement
+		*out = new(UnhealthyNodeReplacementSpec)
+		**out = **in
+	}
+	if in.BootstrapFailureDetection != nil {
+		in, out := &in.BootstrapFailureDetection, &out.BootstrapFailureDetection
+		*out = new(BootstrapFailureDetectionSpec)
+		**out = **in
+	}
+	if in.ZoneImbalance != nil {
+		in, out := &in.ZoneImbalance, &out.ZoneImbalance
+		*out = new(ZoneImbalanceSpec)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EKSConfiguration.
@@ -199,6 +512,11 @@ func (in *EKSFargateSpec) DeepCopyInto(out *EKSFargateSpec) {
 			}
 		}
 	}
+	if in.ManagedPolicies != nil {
+		in, out := &in.ManagedPolicies, &out.ManagedPolicies
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EKSFargateSpec.
@@ -371,53 +689,394 @@ func (in *InstanceGroupList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *InstanceGroupSpec) DeepCopyInto(out *InstanceGroupSpec) {
+func (in *InstanceGroupRevision) DeepCopyInto(out *InstanceGroupRevision) {
 	*out = *in
-	if in.EKSManagedSpec != nil {
-		in, out := &in.EKSManagedSpec, &out.EKSManagedSpec
-		*out = new(EKSManagedSpec)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.EKSFargateSpec != nil {
-		in, out := &in.EKSFargateSpec, &out.EKSFargateSpec
-		*out = new(EKSFargateSpec)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.EKSSpec != nil {
-		in, out := &in.EKSSpec, &out.EKSSpec
-		*out = new(EKSSpec)
-		(*in).DeepCopyInto(*out)
-	}
-	in.AwsUpgradeStrategy.DeepCopyInto(&out.AwsUpgradeStrategy)
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceGroupSpec.
-func (in *InstanceGroupSpec) DeepCopy() *InstanceGroupSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceGroupRevision.
+func (in *InstanceGroupRevision) DeepCopy() *InstanceGroupRevision {
 	if in == nil {
 		return nil
 	}
-	out := new(InstanceGroupSpec)
+	out := new(InstanceGroupRevision)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *InstanceGroupStatus) DeepCopyInto(out *InstanceGroupStatus) {
-	*out = *in
-	if in.Conditions != nil {
-		in, out := &in.Conditions, &out.Conditions
-		*out = make([]InstanceGroupCondition, len(*in))
-		copy(*out, *in)
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *InstanceGroupRevision) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
 	}
+	return nil
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceGroupStatus.
-func (in *InstanceGroupStatus) DeepCopy() *InstanceGroupStatus {
-	if in == nil {
-		return nil
-	}
-	out := new(InstanceGroupStatus)
-	in.DeepCopyInto(out)
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceGroupRevisionList) DeepCopyInto(out *InstanceGroupRevisionList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]InstanceGroupRevision, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceGroupRevisionList.
+func (in *InstanceGroupRevisionList) DeepCopy() *InstanceGroupRevisionList {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceGroupRevisionList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *InstanceGroupRevisionList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceGroupRevisionSpec) DeepCopyInto(out *InstanceGroupRevisionSpec) {
+	*out = *in
+	if in.Changes != nil {
+		in, out := &in.Changes, &out.Changes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Configuration != nil {
+		in, out := &in.Configuration, &out.Configuration
+		*out = new(EKSConfiguration)
+		(*in).DeepCopyInto(*out)
+	}
+	in.RecordedAt.DeepCopyInto(&out.RecordedAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceGroupRevisionSpec.
+func (in *InstanceGroupRevisionSpec) DeepCopy() *InstanceGroupRevisionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceGroupRevisionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceGroupSpec) DeepCopyInto(out *InstanceGroupSpec) {
+	*out = *in
+	if in.EKSManagedSpec != nil {
+		in, out := &in.EKSManagedSpec, &out.EKSManagedSpec
+		*out = new(EKSManagedSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.EKSFargateSpec != nil {
+		in, out := &in.EKSFargateSpec, &out.EKSFargateSpec
+		*out = new(EKSFargateSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.EKSSpec != nil {
+		in, out := &in.EKSSpec, &out.EKSSpec
+		*out = new(EKSSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	in.AwsUpgradeStrategy.DeepCopyInto(&out.AwsUpgradeStrategy)
+	if in.ChangeWindows != nil {
+		in, out := &in.ChangeWindows, &out.ChangeWindows
+		*out = make([]ChangeWindow, len(*in))
+		copy(*out, *in)
+	}
+	if in.KubeconfigSecretRef != nil {
+		in, out := &in.KubeconfigSecretRef, &out.KubeconfigSecretRef
+		*out = new(KubeconfigSecretReference)
+		**out = **in
+	}
+	if in.ProviderIDList != nil {
+		in, out := &in.ProviderIDList, &out.ProviderIDList
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceGroupSpec.
+func (in *InstanceGroupSpec) DeepCopy() *InstanceGroupSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceGroupSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceGroupStatus) DeepCopyInto(out *InstanceGroupStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]InstanceGroupCondition, len(*in))
+		copy(*out, *in)
+	}
+	if in.PendingChanges != nil {
+		in, out := &in.PendingChanges, &out.PendingChanges
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PendingManualReplacement != nil {
+		in, out := &in.PendingManualReplacement, &out.PendingManualReplacement
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NextChangeWindow != nil {
+		in, out := &in.NextChangeWindow, &out.NextChangeWindow
+		*out = (*in).DeepCopy()
+	}
+	if in.Rotation != nil {
+		in, out := &in.Rotation, &out.Rotation
+		*out = new(RotationStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Backoff != nil {
+		in, out := &in.Backoff, &out.Backoff
+		*out = new(BackoffStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Plan != nil {
+		in, out := &in.Plan, &out.Plan
+		*out = make([]PlannedChange, len(*in))
+		copy(*out, *in)
+	}
+	if in.RetiringScalingGroups != nil {
+		in, out := &in.RetiringScalingGroups, &out.RetiringScalingGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Revisions != nil {
+		in, out := &in.Revisions, &out.Revisions
+		*out = make([]ConfigurationRevision, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ZoneTypes != nil {
+		in, out := &in.ZoneTypes, &out.ZoneTypes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Commitments != nil {
+		in, out := &in.Commitments, &out.Commitments
+		*out = make([]InstanceTypeCommitment, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.UnhealthyInstances != nil {
+		in, out := &in.UnhealthyInstances, &out.UnhealthyInstances
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.BootstrapFailures != nil {
+		in, out := &in.BootstrapFailures, &out.BootstrapFailures
+		*out = make([]BootstrapFailure, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LifecycleDistribution != nil {
+		in, out := &in.LifecycleDistribution, &out.LifecycleDistribution
+		*out = new(LifecycleDistribution)
+		**out = **in
+	}
+	if in.ZoneDistribution != nil {
+		in, out := &in.ZoneDistribution, &out.ZoneDistribution
+		*out = make([]ZoneCapacity, len(*in))
+		copy(*out, *in)
+	}
+	if in.ZoneImbalancedSince != nil {
+		in, out := &in.ZoneImbalancedSince, &out.ZoneImbalancedSince
+		*out = (*in).DeepCopy()
+	}
+	if in.ProtectedInstances != nil {
+		in, out := &in.ProtectedInstances, &out.ProtectedInstances
+		*out = make([]ProtectedInstance, len(*in))
+		copy(*out, *in)
+	}
+	if in.ScaleInProtectedInstances != nil {
+		in, out := &in.ScaleInProtectedInstances, &out.ScaleInProtectedInstances
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceGroupStatus.
+func (in *InstanceGroupStatus) DeepCopy() *InstanceGroupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceGroupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceGroupTemplate) DeepCopyInto(out *InstanceGroupTemplate) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceGroupTemplate.
+func (in *InstanceGroupTemplate) DeepCopy() *InstanceGroupTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceGroupTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *InstanceGroupTemplate) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceGroupTemplateList) DeepCopyInto(out *InstanceGroupTemplateList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]InstanceGroupTemplate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceGroupTemplateList.
+func (in *InstanceGroupTemplateList) DeepCopy() *InstanceGroupTemplateList {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceGroupTemplateList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *InstanceGroupTemplateList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceGroupTemplateSpec) DeepCopyInto(out *InstanceGroupTemplateSpec) {
+	*out = *in
+	if in.NodeSecurityGroups != nil {
+		in, out := &in.NodeSecurityGroups, &out.NodeSecurityGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Volumes != nil {
+		in, out := &in.Volumes, &out.Volumes
+		*out = make([]NodeVolume, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make([]map[string]string, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = make(map[string]string, len(*in))
+				for key, val := range *in {
+					(*out)[key] = val
+				}
+			}
+		}
+	}
+	if in.UserData != nil {
+		in, out := &in.UserData, &out.UserData
+		*out = make([]UserDataStage, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceGroupTemplateSpec.
+func (in *InstanceGroupTemplateSpec) DeepCopy() *InstanceGroupTemplateSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceGroupTemplateSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceTypeCommitment) DeepCopyInto(out *InstanceTypeCommitment) {
+	*out = *in
+	if in.SavingsPlans != nil {
+		in, out := &in.SavingsPlans, &out.SavingsPlans
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceTypeCommitment.
+func (in *InstanceTypeCommitment) DeepCopy() *InstanceTypeCommitment {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceTypeCommitment)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubeconfigSecretReference) DeepCopyInto(out *KubeconfigSecretReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubeconfigSecretReference.
+func (in *KubeconfigSecretReference) DeepCopy() *KubeconfigSecretReference {
+	if in == nil {
+		return nil
+	}
+	out := new(KubeconfigSecretReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LifecycleDistribution) DeepCopyInto(out *LifecycleDistribution) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LifecycleDistribution.
+func (in *LifecycleDistribution) DeepCopy() *LifecycleDistribution {
+	if in == nil {
+		return nil
+	}
+	out := new(LifecycleDistribution)
+	in.DeepCopyInto(out)
 	return out
 }
 
@@ -436,6 +1095,21 @@ func (in *LifecycleHookSpec) DeepCopy() *LifecycleHookSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeReadinessGate) DeepCopyInto(out *NodeReadinessGate) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeReadinessGate.
+func (in *NodeReadinessGate) DeepCopy() *NodeReadinessGate {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeReadinessGate)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NodeVolume) DeepCopyInto(out *NodeVolume) {
 	*out = *in
@@ -486,6 +1160,61 @@ func (in *NodeVolumeMountOptions) DeepCopy() *NodeVolumeMountOptions {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NotificationSpec) DeepCopyInto(out *NotificationSpec) {
+	*out = *in
+	if in.Types != nil {
+		in, out := &in.Types, &out.Types
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NotificationSpec.
+func (in *NotificationSpec) DeepCopy() *NotificationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NotificationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlannedChange) DeepCopyInto(out *PlannedChange) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlannedChange.
+func (in *PlannedChange) DeepCopy() *PlannedChange {
+	if in == nil {
+		return nil
+	}
+	out := new(PlannedChange)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PreDrainHook) DeepCopyInto(out *PreDrainHook) {
+	*out = *in
+	if in.Condition != nil {
+		in, out := &in.Condition, &out.Condition
+		*out = new(NodeReadinessGate)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreDrainHook.
+func (in *PreDrainHook) DeepCopy() *PreDrainHook {
+	if in == nil {
+		return nil
+	}
+	out := new(PreDrainHook)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RollingUpdateStrategy) DeepCopyInto(out *RollingUpdateStrategy) {
 	*out = *in
@@ -494,6 +1223,16 @@ func (in *RollingUpdateStrategy) DeepCopyInto(out *RollingUpdateStrategy) {
 		*out = new(intstr.IntOrString)
 		**out = **in
 	}
+	if in.ReadinessGates != nil {
+		in, out := &in.ReadinessGates, &out.ReadinessGates
+		*out = make([]NodeReadinessGate, len(*in))
+		copy(*out, *in)
+	}
+	if in.Verification != nil {
+		in, out := &in.Verification, &out.Verification
+		*out = new(VerificationHook)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RollingUpdateStrategy.
@@ -506,6 +1245,144 @@ func (in *RollingUpdateStrategy) DeepCopy() *RollingUpdateStrategy {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RollingUpgradePostDrain) DeepCopyInto(out *RollingUpgradePostDrain) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RollingUpgradePostDrain.
+func (in *RollingUpgradePostDrain) DeepCopy() *RollingUpgradePostDrain {
+	if in == nil {
+		return nil
+	}
+	out := new(RollingUpgradePostDrain)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RollingUpgradeScript) DeepCopyInto(out *RollingUpgradeScript) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RollingUpgradeScript.
+func (in *RollingUpgradeScript) DeepCopy() *RollingUpgradeScript {
+	if in == nil {
+		return nil
+	}
+	out := new(RollingUpgradeScript)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RollingUpgradeSpec) DeepCopyInto(out *RollingUpgradeSpec) {
+	*out = *in
+	if in.PreDrain != nil {
+		in, out := &in.PreDrain, &out.PreDrain
+		*out = new(RollingUpgradeScript)
+		**out = **in
+	}
+	if in.PostDrain != nil {
+		in, out := &in.PostDrain, &out.PostDrain
+		*out = new(RollingUpgradePostDrain)
+		**out = **in
+	}
+	if in.PostTerminate != nil {
+		in, out := &in.PostTerminate, &out.PostTerminate
+		*out = new(RollingUpgradeScript)
+		**out = **in
+	}
+	if in.Strategy != nil {
+		in, out := &in.Strategy, &out.Strategy
+		*out = new(RollingUpgradeStrategySpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RollingUpgradeSpec.
+func (in *RollingUpgradeSpec) DeepCopy() *RollingUpgradeSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RollingUpgradeSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RollingUpgradeStrategySpec) DeepCopyInto(out *RollingUpgradeStrategySpec) {
+	*out = *in
+	if in.MaxUnavailable != nil {
+		in, out := &in.MaxUnavailable, &out.MaxUnavailable
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RollingUpgradeStrategySpec.
+func (in *RollingUpgradeStrategySpec) DeepCopy() *RollingUpgradeStrategySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RollingUpgradeStrategySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProtectedInstance) DeepCopyInto(out *ProtectedInstance) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProtectedInstance.
+func (in *ProtectedInstance) DeepCopy() *ProtectedInstance {
+	if in == nil {
+		return nil
+	}
+	out := new(ProtectedInstance)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RotationStatus) DeepCopyInto(out *RotationStatus) {
+	*out = *in
+	if in.StartedAt != nil {
+		in, out := &in.StartedAt, &out.StartedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.LastBatchTime != nil {
+		in, out := &in.LastBatchTime, &out.LastBatchTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RotationStatus.
+func (in *RotationStatus) DeepCopy() *RotationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RotationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UnhealthyNodeReplacementSpec) DeepCopyInto(out *UnhealthyNodeReplacementSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UnhealthyNodeReplacementSpec.
+func (in *UnhealthyNodeReplacementSpec) DeepCopy() *UnhealthyNodeReplacementSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(UnhealthyNodeReplacementSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *UserDataStage) DeepCopyInto(out *UserDataStage) {
 	*out = *in
@@ -520,3 +1397,48 @@ func (in *UserDataStage) DeepCopy() *UserDataStage {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VerificationHook) DeepCopyInto(out *VerificationHook) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VerificationHook.
+func (in *VerificationHook) DeepCopy() *VerificationHook {
+	if in == nil {
+		return nil
+	}
+	out := new(VerificationHook)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZoneCapacity) DeepCopyInto(out *ZoneCapacity) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZoneCapacity.
+func (in *ZoneCapacity) DeepCopy() *ZoneCapacity {
+	if in == nil {
+		return nil
+	}
+	out := new(ZoneCapacity)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZoneImbalanceSpec) DeepCopyInto(out *ZoneImbalanceSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZoneImbalanceSpec.
+func (in *ZoneImbalanceSpec) DeepCopy() *ZoneImbalanceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ZoneImbalanceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,129 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// instancegroup-export exports the resolved launch configuration and scaling group of an instance group as a
+// CloudFormation template or Terraform configuration, it annotates the instance group for export and prints the
+// result once the controller has written it to the export ConfigMap
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/keikoproj/instance-manager/api/v1alpha1"
+	"github.com/keikoproj/instance-manager/controllers/common"
+	kubeprovider "github.com/keikoproj/instance-manager/controllers/providers/kubernetes"
+	"github.com/pkg/errors"
+	kerr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const pollInterval = 2 * time.Second
+
+func main() {
+	var (
+		namespace string
+		format    string
+		output    string
+		timeout   time.Duration
+	)
+
+	flag.StringVar(&namespace, "namespace", "default", "The namespace of the instance group")
+	flag.StringVar(&format, "format", v1alpha1.TerraformExportFormat, "The export format, one of cloudformation or terraform")
+	flag.StringVar(&output, "output", "", "The file the export is written to, defaults to stdout")
+	flag.DurationVar(&timeout, "timeout", 2*time.Minute, "The maximum duration to wait for the controller to export the instance group")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %v [flags] <instance group>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	rendered, err := export(namespace, flag.Arg(0), format, timeout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if output == "" {
+		fmt.Println(rendered)
+		return
+	}
+	if err := ioutil.WriteFile(output, []byte(rendered), 0644); err != nil {
+		fmt.Fprintln(os.Stderr, errors.Wrap(err, "failed to write export"))
+		os.Exit(1)
+	}
+}
+
+func export(namespace, name, format string, timeout time.Duration) (string, error) {
+	if !common.ContainsString(v1alpha1.AllowedExportFormats, format) {
+		return "", errors.Errorf("export format '%v' is not supported, must be one of %v", format, v1alpha1.AllowedExportFormats)
+	}
+
+	kube, err := kubeprovider.GetKubernetesClient()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create kubernetes client")
+	}
+	dynamic, err := kubeprovider.GetKubernetesDynamicClient()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create kubernetes client")
+	}
+
+	// the export time is recorded with a precision of seconds
+	requested := time.Now().UTC().Truncate(time.Second)
+
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`, v1alpha1.ExportAnnotationKey, format)
+	gvr := v1alpha1.GroupVersion.WithResource("instancegroups")
+	if _, err := dynamic.Resource(gvr).Namespace(namespace).Patch(name, types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+		return "", errors.Wrapf(err, "failed to annotate instance group %v/%v for export", namespace, name)
+	}
+
+	var (
+		rendered   string
+		exportName = (&v1alpha1.InstanceGroup{ObjectMeta: metav1.ObjectMeta{Name: name}}).GetExportName()
+	)
+	err = wait.PollImmediate(pollInterval, timeout, func() (bool, error) {
+		cm, err := kube.CoreV1().ConfigMaps(namespace).Get(exportName, metav1.GetOptions{})
+		if kerr.IsNotFound(err) {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+
+		exported, err := time.Parse(time.RFC3339, cm.GetAnnotations()[v1alpha1.ExportTimeAnnotationKey])
+		if err != nil || exported.Before(requested) {
+			return false, nil
+		}
+		data, ok := cm.Data[format]
+		if !ok {
+			return false, nil
+		}
+		rendered = data
+		return true, nil
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to wait for export of instance group %v/%v", namespace, name)
+	}
+	return rendered, nil
+}
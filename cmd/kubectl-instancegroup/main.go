@@ -0,0 +1,442 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// kubectl-instancegroup is a kubectl plugin for operating instance groups, it shows their status, follows and
+// triggers node rotations, pauses and resumes changes, prints drift and rolls back configurations, using only the custom resource and the
+// annotations the controller honors, and simulates the AWS calls of a reconcile against a snapshot of AWS state
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/keikoproj/instance-manager/api/v1alpha1"
+	"github.com/keikoproj/instance-manager/controllers"
+	awsprovider "github.com/keikoproj/instance-manager/controllers/providers/aws"
+	kubeprovider "github.com/keikoproj/instance-manager/controllers/providers/kubernetes"
+	"github.com/keikoproj/instance-manager/controllers/simulator"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const usage = `Usage: kubectl instancegroup [-n namespace] <command> [name]
+
+Commands:
+  list             list the instance groups of the namespace
+  status <name>    show the status of an instance group
+  watch <name>     follow the state and node rotation of an instance group until it is ready
+  pause <name>     suspend changes to the cloud resources of an instance group
+  resume <name>    resume changes to the cloud resources of an instance group
+  rotate <name>    replace the launch configuration and rotate all nodes of an instance group
+  drift <name>     print the changes which are pending or planned for an instance group
+  history <name>   list the revisions of configurations rolled out to all nodes of an instance group
+  rollback <name>  roll the configuration of an instance group back to a revision, the previous one by default
+  simulate <name>  print the AWS calls a reconcile of an instance group would make against the AWS state in --aws-state
+
+Flags:
+`
+
+type command struct {
+	run       func(c client.Client, out io.Writer, namespace, name string) error
+	needsName bool
+}
+
+var commands = map[string]command{
+	"list":     {run: list},
+	"status":   {run: status, needsName: true},
+	"watch":    {run: watch, needsName: true},
+	"pause":    {run: pause, needsName: true},
+	"resume":   {run: resume, needsName: true},
+	"rotate":   {run: rotate, needsName: true},
+	"drift":    {run: drift, needsName: true},
+	"history":  {run: history, needsName: true},
+	"rollback": {run: rollback, needsName: true},
+	"simulate": {run: simulate, needsName: true},
+}
+
+var (
+	watchInterval   time.Duration
+	toRevision      string
+	awsState        string
+	awsRegion       string
+	configNamespace string
+)
+
+func main() {
+	var namespace string
+	flag.StringVar(&namespace, "n", "default", "The namespace of the instance groups")
+	flag.StringVar(&namespace, "namespace", "default", "The namespace of the instance groups")
+	flag.DurationVar(&watchInterval, "interval", 5*time.Second, "The interval at which watch polls the instance group")
+	flag.StringVar(&toRevision, "to-revision", v1alpha1.PreviousRollbackRequest, "The revision rollback rolls the instance group back to")
+	flag.StringVar(&awsState, "aws-state", "", "The JSON fixtures of the AWS state simulate reconciles the instance group against")
+	flag.StringVar(&awsRegion, "region", simulator.DefaultRegion, "The region of the AWS state simulate reconciles the instance group against")
+	flag.StringVar(&configNamespace, "config-namespace", "instance-manager", "The namespace of the instance-manager configmap whose defaults simulate applies")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok || (cmd.needsName && flag.NArg() != 2) {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	c, err := newClient()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if err := cmd.run(c, os.Stdout, namespace, flag.Arg(1)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func newClient() (client.Client, error) {
+	config, err := kubeprovider.GetKubernetesConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load kubeconfig")
+	}
+
+	scheme := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	c, err := client.New(config, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create kubernetes client")
+	}
+	return c, nil
+}
+
+func get(c client.Client, namespace, name string) (*v1alpha1.InstanceGroup, error) {
+	instanceGroup := &v1alpha1.InstanceGroup{}
+	if err := c.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: name}, instanceGroup); err != nil {
+		return nil, errors.Wrapf(err, "failed to get instance group %v/%v", namespace, name)
+	}
+	return instanceGroup, nil
+}
+
+func list(c client.Client, out io.Writer, namespace, _ string) error {
+	instanceGroups := &v1alpha1.InstanceGroupList{}
+	if err := c.List(context.Background(), instanceGroups, client.InNamespace(namespace)); err != nil {
+		return errors.Wrapf(err, "failed to list instance groups in %v", namespace)
+	}
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSTATE\tMIN\tMAX\tGROUP NAME\tPROVISIONER\tSTRATEGY\tROTATION")
+	for i := range instanceGroups.Items {
+		ig := &instanceGroups.Items[i]
+		s := ig.GetStatus()
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+			ig.GetName(), ig.GetState(), s.GetCurrentMin(), s.GetCurrentMax(), valueOrNone(s.GetActiveScalingGroupName()),
+			valueOrNone(s.Provisioner), valueOrNone(s.Strategy), rotationProgress(s.GetRotation()))
+	}
+	return w.Flush()
+}
+
+func status(c client.Client, out io.Writer, namespace, name string) error {
+	instanceGroup, err := get(c, namespace, name)
+	if err != nil {
+		return err
+	}
+	s := instanceGroup.GetStatus()
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Name:\t%v/%v\n", instanceGroup.GetNamespace(), instanceGroup.GetName())
+	fmt.Fprintf(w, "State:\t%v\n", instanceGroup.GetState())
+	fmt.Fprintf(w, "Provisioner:\t%v\n", valueOrNone(s.Provisioner))
+	if cluster := instanceGroup.GetClusterRef(); cluster != "" {
+		fmt.Fprintf(w, "Cluster Configuration:\t%v\n", cluster)
+	}
+	if template := instanceGroup.GetTemplateRef(); template != "" {
+		fmt.Fprintf(w, "Template:\t%v\n", template)
+	}
+	fmt.Fprintf(w, "Suspended:\t%v\n", instanceGroup.IsSuspended())
+	fmt.Fprintf(w, "Dry Run:\t%v\n", instanceGroup.IsDryRun())
+	fmt.Fprintf(w, "Scaling Group:\t%v\n", valueOrNone(s.GetActiveScalingGroupName()))
+	if groups := s.GetRetiringScalingGroups(); len(groups) > 0 {
+		fmt.Fprintf(w, "Replaced Scaling Groups:\t%v\n", strings.Join(groups, ", "))
+	}
+	fmt.Fprintf(w, "Launch Configuration:\t%v\n", valueOrNone(s.GetActiveLaunchConfigurationName()))
+	fmt.Fprintf(w, "Size:\tmin %v, max %v\n", s.GetCurrentMin(), s.GetCurrentMax())
+	fmt.Fprintf(w, "Image:\t%v\n", valueOrNone(s.GetResolvedImage()))
+	fmt.Fprintf(w, "Lifecycle:\t%v\n", valueOrNone(s.GetLifecycle()))
+	fmt.Fprintf(w, "Rotation:\t%v\n", rotationProgress(s.GetRotation()))
+	fmt.Fprintf(w, "Rollout:\t%v\n", rolloutProgress(instanceGroup))
+	if latest := s.GetLatestRevision(); latest != nil {
+		fmt.Fprintf(w, "Revision:\t%v\n", latest.Revision)
+	}
+	if backoff := s.GetBackoff(); backoff != nil {
+		fmt.Fprintf(w, "Backoff:\t%v consecutive failures, last %v\n", backoff.ConsecutiveFailures, valueOrNone(backoff.LastFailureClass))
+	}
+	if nodes := s.GetPendingManualReplacement(); len(nodes) > 0 {
+		fmt.Fprintf(w, "Pending Manual Replacement:\t%v\n", strings.Join(nodes, ", "))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if conditions := s.GetConditions(); len(conditions) > 0 {
+		fmt.Fprintln(out, "Conditions:")
+		w = tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "  TYPE\tSTATUS\tREASON\tMESSAGE")
+		for _, condition := range conditions {
+			fmt.Fprintf(w, "  %v\t%v\t%v\t%v\n", condition.Type, condition.Status, valueOrNone(condition.Reason), condition.Message)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func watch(c client.Client, out io.Writer, namespace, name string) error {
+	var last string
+	for {
+		instanceGroup, err := get(c, namespace, name)
+		if err != nil {
+			return err
+		}
+
+		var (
+			state    = instanceGroup.GetState()
+			rotation = instanceGroup.GetStatus().GetRotation()
+			current  = fmt.Sprintf("state: %v, rotation: %v", state, rotationProgress(rotation))
+		)
+		if rotation != nil && rotation.LastError != "" {
+			current = fmt.Sprintf("%v, last error: %v", current, rotation.LastError)
+		}
+		if current != last {
+			fmt.Fprintf(out, "%v  %v\n", time.Now().Format(time.RFC3339), current)
+			last = current
+		}
+
+		if rotation == nil && (state == v1alpha1.ReconcileReady || state == v1alpha1.ReconcileErr) {
+			return nil
+		}
+		time.Sleep(watchInterval)
+	}
+}
+
+func pause(c client.Client, out io.Writer, namespace, name string) error {
+	if err := annotate(c, namespace, name, v1alpha1.SuspendAnnotationKey, "true"); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "instance group %v/%v paused, changes to its cloud resources are suspended\n", namespace, name)
+	return nil
+}
+
+func resume(c client.Client, out io.Writer, namespace, name string) error {
+	instanceGroup, err := get(c, namespace, name)
+	if err != nil {
+		return err
+	}
+	if err := annotate(c, namespace, name, v1alpha1.SuspendAnnotationKey, ""); err != nil {
+		return err
+	}
+	if instanceGroup.Spec.Suspend {
+		fmt.Fprintf(out, "instance group %v/%v is still suspended by spec.suspend\n", namespace, name)
+		return nil
+	}
+	fmt.Fprintf(out, "instance group %v/%v resumed\n", namespace, name)
+	return nil
+}
+
+func rotate(c client.Client, out io.Writer, namespace, name string) error {
+	if err := annotate(c, namespace, name, v1alpha1.RotateAnnotationKey, "true"); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "rotation of instance group %v/%v requested, follow it with: kubectl instancegroup -n %v watch %v\n", namespace, name, namespace, name)
+	return nil
+}
+
+func drift(c client.Client, out io.Writer, namespace, name string) error {
+	instanceGroup, err := get(c, namespace, name)
+	if err != nil {
+		return err
+	}
+	s := instanceGroup.GetStatus()
+
+	if plan := s.GetPlan(); len(plan) > 0 {
+		fmt.Fprintln(out, "Planned changes (dry-run):")
+		for _, change := range plan {
+			fmt.Fprintf(out, "  %v\n", change)
+		}
+		return nil
+	}
+
+	if pending := s.GetPendingChanges(); len(pending) > 0 {
+		fmt.Fprintln(out, "Pending changes (deferred until the next change window):")
+		for _, change := range pending {
+			fmt.Fprintf(out, "  %v\n", change)
+		}
+		return nil
+	}
+
+	fmt.Fprintf(out, "no pending changes recorded, annotate the instance group with %v=true for a detailed plan\n", v1alpha1.DryRunAnnotationKey)
+	return nil
+}
+
+func history(c client.Client, out io.Writer, namespace, name string) error {
+	instanceGroup, err := get(c, namespace, name)
+	if err != nil {
+		return err
+	}
+	s := instanceGroup.GetStatus()
+
+	revisions := s.GetRevisions()
+	if len(revisions) == 0 {
+		fmt.Fprintf(out, "instance group %v/%v has no revisions, a revision is recorded when a configuration is rolled out to all nodes\n", namespace, name)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "REVISION	IMAGE	INSTANCE TYPE	USER DATA	ROLLED OUT	CURRENT")
+	for _, revision := range revisions {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\n",
+			revision.Revision, valueOrNone(revision.Image), valueOrNone(revision.InstanceType), valueOrNone(shortHash(revision.UserDataHash)),
+			revision.RolledOutAt.Format(time.RFC3339), revision.ConfigurationHash == s.GetConfigurationHash())
+	}
+	return w.Flush()
+}
+
+func rollback(c client.Client, out io.Writer, namespace, name string) error {
+	instanceGroup, err := get(c, namespace, name)
+	if err != nil {
+		return err
+	}
+
+	revision, err := instanceGroup.GetRollbackRevision(toRevision)
+	if err != nil {
+		return errors.Wrapf(err, "failed to roll back instance group %v/%v", namespace, name)
+	}
+
+	if err := annotate(c, namespace, name, v1alpha1.RollbackAnnotationKey, fmt.Sprint(revision.Revision)); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "rollback of instance group %v/%v to revision %v requested, follow it with: kubectl instancegroup -n %v watch %v\n", namespace, name, revision.Revision, namespace, name)
+	return nil
+}
+
+// simulate reconciles the instance group against a snapshot of AWS state, no calls are made to AWS and the instance
+// group is not modified
+func simulate(c client.Client, out io.Writer, namespace, name string) error {
+	if awsState == "" {
+		return errors.New("--aws-state is required to simulate a reconcile")
+	}
+	fixtures, err := simulator.LoadFixtures(awsState)
+	if err != nil {
+		return err
+	}
+
+	instanceGroup, err := get(c, namespace, name)
+	if err != nil {
+		return err
+	}
+
+	configMap := &corev1.ConfigMap{}
+	err = c.Get(context.Background(), types.NamespacedName{Namespace: configNamespace, Name: controllers.ConfigMapName}, configMap)
+	if err != nil && !kerrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to get configmap %v/%v", configNamespace, controllers.ConfigMapName)
+	}
+
+	// waits for propagation of IAM resources and AWS waiters are not needed against a snapshot
+	awsprovider.DefaultInstanceProfilePropagationDelay = 0
+	awsprovider.DefaultWaiterDuration = 0
+
+	result, err := simulator.Simulate(instanceGroup, fixtures, simulator.Options{
+		Region:        awsRegion,
+		Configuration: configMap,
+	})
+	if result != nil {
+		fmt.Fprintf(out, "Simulated reconcile of %v/%v, resulting state %v\n", namespace, name, valueOrNone(string(result.State)))
+		if len(result.Mutations) == 0 {
+			fmt.Fprintln(out, "no AWS calls which change AWS state would be made")
+		}
+		for i, mutation := range result.Mutations {
+			fmt.Fprintf(out, "  %v. %v\n", i+1, mutation)
+		}
+	}
+	if err != nil {
+		return errors.Wrapf(err, "simulated reconcile of %v/%v failed", namespace, name)
+	}
+	return nil
+}
+
+// annotate sets an annotation of an instance group with a merge patch, an empty value removes the annotation
+func annotate(c client.Client, namespace, name, key, value string) error {
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`, key, value)
+	if value == "" {
+		patch = fmt.Sprintf(`{"metadata":{"annotations":{%q:null}}}`, key)
+	}
+
+	instanceGroup := &v1alpha1.InstanceGroup{}
+	instanceGroup.SetNamespace(namespace)
+	instanceGroup.SetName(name)
+	if err := c.Patch(context.Background(), instanceGroup, client.ConstantPatch(types.MergePatchType, []byte(patch))); err != nil {
+		return errors.Wrapf(err, "failed to annotate instance group %v/%v", namespace, name)
+	}
+	return nil
+}
+
+func rotationProgress(rotation *v1alpha1.RotationStatus) string {
+	if rotation == nil {
+		return "<none>"
+	}
+	return fmt.Sprintf("%v/%v nodes rotated by %v, batch %v", rotation.RotatedNodes, rotation.TotalNodes, valueOrNone(rotation.Strategy), rotation.CurrentBatch)
+}
+
+func rolloutProgress(instanceGroup *v1alpha1.InstanceGroup) string {
+	status := instanceGroup.GetStatus()
+	switch {
+	case instanceGroup.IsRolledOut():
+		return fmt.Sprintf("generation %v rolled out", status.GetObservedGeneration())
+	case status.GetObservedGeneration() != instanceGroup.GetGeneration():
+		return fmt.Sprintf("generation %v not applied, observed generation %v", instanceGroup.GetGeneration(), status.GetObservedGeneration())
+	default:
+		return fmt.Sprintf("generation %v rolling out", status.GetObservedGeneration())
+	}
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
+
+func valueOrNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+	return s
+}
@@ -0,0 +1,126 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"sync"
+	"time"
+
+	v1alpha1 "github.com/keikoproj/instance-manager/api/v1alpha1"
+	awsprovider "github.com/keikoproj/instance-manager/controllers/providers/aws"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+type BackoffPolicy struct {
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+var DefaultBackoffPolicies = map[awsprovider.ErrorClass]BackoffPolicy{
+	awsprovider.ErrorClassThrottling:        {BaseDelay: time.Second * 30, MaxDelay: time.Minute * 10},
+	awsprovider.ErrorClassPermissionDenied:  {BaseDelay: time.Minute * 1, MaxDelay: time.Minute * 30},
+	awsprovider.ErrorClassDependencyMissing: {BaseDelay: time.Second * 15, MaxDelay: time.Minute * 10},
+	awsprovider.ErrorClassValidation:        {BaseDelay: time.Minute * 1, MaxDelay: time.Minute * 30},
+	awsprovider.ErrorClassTransient:         {BaseDelay: time.Second * 5, MaxDelay: time.Minute * 5},
+	awsprovider.ErrorClassCircuitOpen:       {BaseDelay: time.Minute * 1, MaxDelay: time.Minute * 10},
+}
+
+// RequeueBackoff tracks consecutive reconcile failures per instance group, and computes an
+// exponential requeue delay based on the class of the most recent failure
+type RequeueBackoff struct {
+	sync.Mutex
+	Policies map[awsprovider.ErrorClass]BackoffPolicy
+	failures map[types.NamespacedName]*failureRecord
+}
+
+type failureRecord struct {
+	class awsprovider.ErrorClass
+	count int
+}
+
+func NewRequeueBackoff() *RequeueBackoff {
+	return &RequeueBackoff{
+		Policies: DefaultBackoffPolicies,
+		failures: make(map[types.NamespacedName]*failureRecord),
+	}
+}
+
+// Next records a failure for an instance group and returns the delay before it should be retried, along with
+// the class and number of consecutive failures, the exponent is reset when the class of failure changes
+func (b *RequeueBackoff) Next(key types.NamespacedName, err error) (time.Duration, awsprovider.ErrorClass, int) {
+	b.Lock()
+	defer b.Unlock()
+
+	class := awsprovider.ClassifyError(err)
+	record, ok := b.failures[key]
+	if !ok || record.class != class {
+		record = &failureRecord{class: class}
+		b.failures[key] = record
+	}
+	record.count++
+
+	return b.delay(record), class, record.count
+}
+
+// Restore seeds the failure history of an instance group from the backoff persisted in its status, so that a
+// restarted controller does not retry all failing instance groups at once, it returns the remaining delay of the
+// last failure, history recorded since the controller started takes precedence over the status
+func (b *RequeueBackoff) Restore(key types.NamespacedName, status *v1alpha1.BackoffStatus, now time.Time) time.Duration {
+	b.Lock()
+	defer b.Unlock()
+
+	if status == nil || status.GetLastFailureTime() == nil || status.GetConsecutiveFailures() < 1 {
+		return 0
+	}
+	if _, ok := b.failures[key]; ok {
+		return 0
+	}
+
+	record := &failureRecord{
+		class: awsprovider.ErrorClass(status.GetLastFailureClass()),
+		count: status.GetConsecutiveFailures(),
+	}
+	b.failures[key] = record
+
+	retryAt := status.GetLastFailureTime().Add(b.delay(record))
+	if retryAt.After(now) {
+		return retryAt.Sub(now)
+	}
+	return 0
+}
+
+func (b *RequeueBackoff) delay(record *failureRecord) time.Duration {
+	policy, ok := b.Policies[record.class]
+	if !ok {
+		policy = DefaultBackoffPolicies[awsprovider.ErrorClassTransient]
+	}
+
+	delay := policy.BaseDelay
+	for i := 1; i < record.count; i++ {
+		delay *= 2
+		if delay >= policy.MaxDelay {
+			return policy.MaxDelay
+		}
+	}
+	return delay
+}
+
+// Reset clears the failure history of an instance group after a successful reconcile
+func (b *RequeueBackoff) Reset(key types.NamespacedName) {
+	b.Lock()
+	defer b.Unlock()
+	delete(b.failures, key)
+}
@@ -0,0 +1,115 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	v1alpha1 "github.com/keikoproj/instance-manager/api/v1alpha1"
+	awsprovider "github.com/keikoproj/instance-manager/controllers/providers/aws"
+	"github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestRequeueBackoffNext(t *testing.T) {
+	var (
+		g          = gomega.NewGomegaWithT(t)
+		key        = types.NamespacedName{Namespace: "default", Name: "instance-group-1"}
+		throttled  = awserr.New("Throttling", "rate exceeded", nil)
+		validation = awserr.New("ValidationError", "invalid", nil)
+	)
+
+	tests := []struct {
+		name      string
+		err       error
+		wantDelay time.Duration
+		wantClass awsprovider.ErrorClass
+		wantCount int
+	}{
+		{name: "first throttling failure", err: throttled, wantDelay: 30 * time.Second, wantClass: awsprovider.ErrorClassThrottling, wantCount: 1},
+		{name: "second throttling failure", err: throttled, wantDelay: time.Minute, wantClass: awsprovider.ErrorClassThrottling, wantCount: 2},
+		{name: "third throttling failure", err: errors.Wrap(throttled, "failed"), wantDelay: 2 * time.Minute, wantClass: awsprovider.ErrorClassThrottling, wantCount: 3},
+		{name: "class change resets the exponent", err: validation, wantDelay: time.Minute, wantClass: awsprovider.ErrorClassValidation, wantCount: 1},
+		{name: "unknown errors are transient", err: errors.New("failed"), wantDelay: 5 * time.Second, wantClass: awsprovider.ErrorClassTransient, wantCount: 1},
+	}
+
+	backoff := NewRequeueBackoff()
+	for _, tc := range tests {
+		delay, class, count := backoff.Next(key, tc.err)
+		g.Expect(delay).To(gomega.Equal(tc.wantDelay), tc.name)
+		g.Expect(class).To(gomega.Equal(tc.wantClass), tc.name)
+		g.Expect(count).To(gomega.Equal(tc.wantCount), tc.name)
+	}
+
+	// the delay does not exceed the maximum delay of the class
+	for i := 0; i < 10; i++ {
+		backoff.Next(key, throttled)
+	}
+	delay, _, _ := backoff.Next(key, throttled)
+	g.Expect(delay).To(gomega.Equal(10 * time.Minute))
+
+	// the history is cleared after a successful reconcile
+	backoff.Reset(key)
+	delay, _, count := backoff.Next(key, throttled)
+	g.Expect(delay).To(gomega.Equal(30 * time.Second))
+	g.Expect(count).To(gomega.Equal(1))
+}
+
+func TestRequeueBackoffRestore(t *testing.T) {
+	var (
+		g   = gomega.NewGomegaWithT(t)
+		key = types.NamespacedName{Namespace: "default", Name: "instance-group-1"}
+		now = time.Now()
+	)
+
+	status := &v1alpha1.BackoffStatus{
+		LastFailureTime:     &metav1.Time{Time: now.Add(-time.Minute)},
+		LastFailureClass:    string(awsprovider.ErrorClassThrottling),
+		ConsecutiveFailures: 3,
+	}
+
+	tests := []struct {
+		name   string
+		status *v1alpha1.BackoffStatus
+		want   time.Duration
+	}{
+		{name: "no backoff", status: nil, want: 0},
+		{name: "no failures", status: &v1alpha1.BackoffStatus{}, want: 0},
+		{name: "remaining delay", status: status, want: time.Minute},
+		{name: "expired delay", status: &v1alpha1.BackoffStatus{
+			LastFailureTime:     &metav1.Time{Time: now.Add(-time.Hour)},
+			LastFailureClass:    string(awsprovider.ErrorClassThrottling),
+			ConsecutiveFailures: 3,
+		}, want: 0},
+	}
+
+	for _, tc := range tests {
+		backoff := NewRequeueBackoff()
+		g.Expect(backoff.Restore(key, tc.status, now)).To(gomega.Equal(tc.want), tc.name)
+	}
+
+	// the restored history continues the backoff, history recorded since the controller started takes precedence
+	backoff := NewRequeueBackoff()
+	backoff.Restore(key, status, now)
+	delay, _, count := backoff.Next(key, awserr.New("Throttling", "rate exceeded", nil))
+	g.Expect(delay).To(gomega.Equal(4 * time.Minute))
+	g.Expect(count).To(gomega.Equal(4))
+	g.Expect(backoff.Restore(key, status, now)).To(gomega.BeZero())
+}
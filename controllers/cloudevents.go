@@ -0,0 +1,194 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/go-logr/logr"
+	v1alpha1 "github.com/keikoproj/instance-manager/api/v1alpha1"
+	awsprovider "github.com/keikoproj/instance-manager/controllers/providers/aws"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+const (
+	// CloudEventWaitSeconds is the duration of a single long poll of the cloud event queue
+	CloudEventWaitSeconds = 20
+	// CloudEventErrorInterval is the delay before the cloud event queue is polled again after a failure
+	CloudEventErrorInterval = 10 * time.Second
+
+	// ImageStateChangeDetailType is the detail type of events of Image Builder images changing their state
+	ImageStateChangeDetailType = "EC2 Image Builder Image State Change"
+	// ImageStateAvailable is the state of images which have been built and distributed
+	ImageStateAvailable = "AVAILABLE"
+)
+
+// CloudEvent is the part of an EventBridge event which identifies the scaling group it refers to, events of API
+// calls recorded by CloudTrail carry it in the request parameters, and events of scaling activities in the detail,
+// events of Image Builder images carry the state of the image in the detail
+type CloudEvent struct {
+	Source     string   `json:"source"`
+	DetailType string   `json:"detail-type"`
+	Resources  []string `json:"resources"`
+	Detail     struct {
+		EventName         string `json:"eventName"`
+		AutoScalingGroup  string `json:"AutoScalingGroupName"`
+		RequestParameters struct {
+			AutoScalingGroupName string `json:"autoScalingGroupName"`
+		} `json:"requestParameters"`
+		State struct {
+			Status string `json:"status"`
+		} `json:"state"`
+	} `json:"detail"`
+}
+
+// ScalingGroupName returns the name of the scaling group an event refers to, or an empty string
+func (e *CloudEvent) ScalingGroupName() string {
+	if e.Detail.RequestParameters.AutoScalingGroupName != "" {
+		return e.Detail.RequestParameters.AutoScalingGroupName
+	}
+	return e.Detail.AutoScalingGroup
+}
+
+// IsImageAvailable returns true if the event is of an Image Builder image which has become available
+func (e *CloudEvent) IsImageAvailable() bool {
+	return e.DetailType == ImageStateChangeDetailType && strings.EqualFold(e.Detail.State.Status, ImageStateAvailable)
+}
+
+// CloudEventListener receives EventBridge events of changes to scaling groups from an SQS queue, and enqueues the
+// instance groups which own them, so that changes made outside of the controller are reconciled immediately, events
+// of new Image Builder images enqueue the instance groups which follow an image pipeline
+type CloudEventListener struct {
+	client.Client
+	AwsWorker awsprovider.AwsWorker
+	QueueURL  string
+	Events    chan event.GenericEvent
+	Log       logr.Logger
+}
+
+// Start polls the queue until stop is closed, it implements manager.Runnable and only runs on the leader
+func (l *CloudEventListener) Start(stop <-chan struct{}) error {
+	l.Log.Info("starting cloud event listener", "queue", l.QueueURL)
+	for {
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+
+		if err := l.poll(); err != nil {
+			l.Log.Error(err, "failed to process cloud events", "queue", l.QueueURL)
+			select {
+			case <-stop:
+				return nil
+			case <-time.After(CloudEventErrorInterval):
+			}
+		}
+	}
+}
+
+func (l *CloudEventListener) poll() error {
+	messages, err := l.AwsWorker.ReceiveMessages(l.QueueURL, CloudEventWaitSeconds)
+	if err != nil {
+		return errors.Wrap(err, "failed to receive messages")
+	}
+
+	for _, message := range messages {
+		cloudEvent := &CloudEvent{}
+		if err := json.Unmarshal([]byte(aws.StringValue(message.Body)), cloudEvent); err != nil {
+			l.Log.Info("ignoring malformed cloud event", "messageId", aws.StringValue(message.MessageId), "error", err.Error())
+		} else if err := l.enqueue(cloudEvent); err != nil {
+			// the message is not deleted and will be received again once its visibility timeout expires
+			return err
+		}
+
+		if err := l.AwsWorker.DeleteMessage(l.QueueURL, aws.StringValue(message.ReceiptHandle)); err != nil {
+			return errors.Wrap(err, "failed to delete message")
+		}
+	}
+	return nil
+}
+
+func (l *CloudEventListener) enqueue(cloudEvent *CloudEvent) error {
+	if cloudEvent.IsImageAvailable() {
+		return l.enqueueImagePipelines(cloudEvent)
+	}
+
+	name := cloudEvent.ScalingGroupName()
+	if name == "" {
+		return nil
+	}
+
+	instanceGroups := &v1alpha1.InstanceGroupList{}
+	if err := l.List(context.Background(), instanceGroups); err != nil {
+		return errors.Wrap(err, "failed to list instance groups")
+	}
+
+	for i := range instanceGroups.Items {
+		instanceGroup := &instanceGroups.Items[i]
+		if !strings.EqualFold(instanceGroup.GetStatus().GetActiveScalingGroupName(), name) {
+			continue
+		}
+		l.Log.Info("scaling group changed outside of the controller",
+			"instancegroup", instanceGroup.NamespacedName(),
+			"scalinggroup", name,
+			"source", cloudEvent.Source,
+			"event", cloudEvent.DetailType,
+			"eventName", cloudEvent.Detail.EventName,
+		)
+		l.Events <- event.GenericEvent{
+			Meta:   instanceGroup,
+			Object: instanceGroup,
+		}
+	}
+	return nil
+}
+
+// enqueueImagePipelines enqueues all instance groups which follow an image pipeline, the image ARN of an event does
+// not identify the pipeline which built it, and reconciles only rotate nodes when the latest image of their pipeline
+// has changed
+func (l *CloudEventListener) enqueueImagePipelines(cloudEvent *CloudEvent) error {
+	instanceGroups := &v1alpha1.InstanceGroupList{}
+	if err := l.List(context.Background(), instanceGroups); err != nil {
+		return errors.Wrap(err, "failed to list instance groups")
+	}
+
+	for i := range instanceGroups.Items {
+		instanceGroup := &instanceGroups.Items[i]
+		if instanceGroup.Spec.EKSSpec == nil || instanceGroup.GetEKSConfiguration() == nil {
+			continue
+		}
+		if !instanceGroup.GetEKSConfiguration().HasImagePipeline() {
+			continue
+		}
+		l.Log.Info("image pipeline built a new image",
+			"instancegroup", instanceGroup.NamespacedName(),
+			"pipeline", instanceGroup.GetEKSConfiguration().GetImagePipelineArn(),
+			"images", cloudEvent.Resources,
+		)
+		l.Events <- event.GenericEvent{
+			Meta:   instanceGroup,
+			Object: instanceGroup,
+		}
+	}
+	return nil
+}
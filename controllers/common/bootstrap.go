@@ -16,6 +16,7 @@ limitations under the License.
 package common
 
 import (
+	"reflect"
 	"time"
 
 	awsauth "github.com/keikoproj/aws-auth/pkg/mapper"
@@ -54,10 +55,17 @@ func GetNodeBootstrapRemove(arn string) *awsauth.MapperArguments {
 	}
 }
 
+// RemoveAuthConfigMap removes the mapping of node roles from the aws-auth ConfigMap, the ConfigMap is only written
+// when a role is mapped
 func RemoveAuthConfigMap(kube kubernetes.Interface, arns []string) error {
+	authData, _, err := awsauth.ReadAuthMap(kube)
+	if err != nil {
+		return err
+	}
+
 	authMap := awsauth.New(kube, false)
 	for _, arn := range arns {
-		if arn == "" {
+		if arn == "" || !isRoleMapped(authData.MapRoles, arn, false) {
 			continue
 		}
 		err := authMap.Remove(GetNodeBootstrapRemove(arn))
@@ -68,10 +76,17 @@ func RemoveAuthConfigMap(kube kubernetes.Interface, arns []string) error {
 	return nil
 }
 
+// UpsertAuthConfigMap maps node roles in the aws-auth ConfigMap, the ConfigMap is only written when a role is not
+// mapped yet, so that instance groups which are bootstrapped at every reconcile do not conflict updating it
 func UpsertAuthConfigMap(kube kubernetes.Interface, arns []string) error {
+	authData, _, err := awsauth.ReadAuthMap(kube)
+	if err != nil {
+		return err
+	}
+
 	authMap := awsauth.New(kube, false)
 	for _, arn := range arns {
-		if arn == "" {
+		if arn == "" || isRoleMapped(authData.MapRoles, arn, true) {
 			continue
 		}
 		err := authMap.Upsert(GetNodeBootstrapUpsert(arn))
@@ -81,3 +96,18 @@ func UpsertAuthConfigMap(kube kubernetes.Interface, arns []string) error {
 	}
 	return nil
 }
+
+// isRoleMapped returns true when a role is mapped in aws-auth, with exact set the role must also be mapped to the
+// node username and groups
+func isRoleMapped(roles []*awsauth.RolesAuthMap, arn string, exact bool) bool {
+	expected := GetNodeBootstrapUpsert(arn)
+	for _, role := range roles {
+		if role.RoleARN != arn {
+			continue
+		}
+		if !exact || role.Username == expected.Username && reflect.DeepEqual(role.Groups, expected.Groups) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"github.com/go-logr/logr"
+)
+
+const (
+	LogLevelInfo  = "info"
+	LogLevelDebug = "debug"
+)
+
+var (
+	LogLevels = []string{LogLevelInfo, LogLevelDebug}
+)
+
+// NewLeveledLogger wraps a logger so that debug log lines, which are logged with V(1) or higher, are only written when
+// debug is true, loggers derived from it can enable debug log lines with WithDebug
+func NewLeveledLogger(log logr.Logger, debug bool) logr.Logger {
+	return &leveledLogger{log: log, debug: debug}
+}
+
+// WithDebug returns a logger which writes debug log lines, e.g. for a single instance group, loggers which are not
+// created with NewLeveledLogger are returned as they are
+func WithDebug(log logr.Logger) logr.Logger {
+	if l, ok := log.(*leveledLogger); ok {
+		return &leveledLogger{log: l.log, debug: true}
+	}
+	return log
+}
+
+type leveledLogger struct {
+	log   logr.Logger
+	debug bool
+}
+
+func (l *leveledLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.log.Info(msg, keysAndValues...)
+}
+
+func (l *leveledLogger) Enabled() bool {
+	return l.log.Enabled()
+}
+
+func (l *leveledLogger) Error(err error, msg string, keysAndValues ...interface{}) {
+	l.log.Error(err, msg, keysAndValues...)
+}
+
+func (l *leveledLogger) V(level int) logr.InfoLogger {
+	if level > 0 && !l.debug {
+		return disabledInfoLogger{}
+	}
+	return l.log.V(level)
+}
+
+func (l *leveledLogger) WithValues(keysAndValues ...interface{}) logr.Logger {
+	return &leveledLogger{log: l.log.WithValues(keysAndValues...), debug: l.debug}
+}
+
+func (l *leveledLogger) WithName(name string) logr.Logger {
+	return &leveledLogger{log: l.log.WithName(name), debug: l.debug}
+}
+
+type disabledInfoLogger struct{}
+
+func (disabledInfoLogger) Info(_ string, _ ...interface{}) {}
+
+func (disabledInfoLogger) Enabled() bool {
+	return false
+}
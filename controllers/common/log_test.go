@@ -0,0 +1,74 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/onsi/gomega"
+)
+
+type recordingLogger struct {
+	lines  *[]string
+	values []interface{}
+}
+
+func (l recordingLogger) Info(msg string, _ ...interface{}) {
+	*l.lines = append(*l.lines, msg)
+}
+
+func (l recordingLogger) Enabled() bool {
+	return true
+}
+
+func (l recordingLogger) Error(_ error, msg string, _ ...interface{}) {
+	*l.lines = append(*l.lines, msg)
+}
+
+func (l recordingLogger) V(_ int) logr.InfoLogger {
+	return l
+}
+
+func (l recordingLogger) WithValues(keysAndValues ...interface{}) logr.Logger {
+	return recordingLogger{lines: l.lines, values: append(l.values, keysAndValues...)}
+}
+
+func (l recordingLogger) WithName(_ string) logr.Logger {
+	return l
+}
+
+func TestLeveledLogger(t *testing.T) {
+	var (
+		g     = gomega.NewGomegaWithT(t)
+		lines = make([]string, 0)
+		log   = NewLeveledLogger(recordingLogger{lines: &lines}, false).WithName("controllers")
+	)
+
+	log.Info("info")
+	log.V(1).Info("debug")
+	g.Expect(log.V(1).Enabled()).To(gomega.BeFalse())
+	g.Expect(lines).To(gomega.Equal([]string{"info"}))
+
+	// debug log lines are written for a single derived logger
+	instanceGroupLog := WithDebug(log).WithValues("name", "my-group")
+	instanceGroupLog.V(1).Info("instance group debug")
+	log.V(1).Info("debug")
+	g.Expect(lines).To(gomega.Equal([]string{"info", "instance group debug"}))
+
+	// loggers which are not leveled are returned as they are
+	g.Expect(WithDebug(recordingLogger{lines: &lines})).To(gomega.Equal(recordingLogger{lines: &lines}))
+}
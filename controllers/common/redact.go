@@ -0,0 +1,151 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/go-logr/logr"
+)
+
+const (
+	// RedactedValue replaces the credentials of pre-signed URLs
+	RedactedValue = "REDACTED"
+)
+
+var (
+	// SensitiveKeys are the keys of log and event values which are replaced with their hash, keys are matched
+	// case-insensitively, ignoring dashes and underscores
+	SensitiveKeys = []string{
+		"userdata",
+		"secretvalue",
+		"secretaccesskey",
+		"sessiontoken",
+		"password",
+		"token",
+	}
+
+	rxPresignedCredentials = regexp.MustCompile(`(?i)((?:X-Amz-Signature|X-Amz-Credential|X-Amz-Security-Token|AWSAccessKeyId|Signature)=)[^&\s"']+`)
+)
+
+// HashValue returns the hash which is logged instead of a sensitive value
+func HashValue(value string) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(value)))
+}
+
+// IsSensitiveKey returns true if the values of a log or event key are sensitive
+func IsSensitiveKey(key string) bool {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", "_", "").Replace(key))
+	return ContainsString(SensitiveKeys, normalized)
+}
+
+// RedactString removes the signatures and credentials of pre-signed URLs from a string
+func RedactString(s string) string {
+	return rxPresignedCredentials.ReplaceAllString(s, "${1}"+RedactedValue)
+}
+
+// RedactKeysAndValues returns a copy of structured log or event values, values of sensitive keys are replaced with
+// their hash and pre-signed URLs are redacted from strings and errors
+func RedactKeysAndValues(keysAndValues ...interface{}) []interface{} {
+	redacted := make([]interface{}, len(keysAndValues))
+	copy(redacted, keysAndValues)
+
+	for i := 0; i+1 < len(redacted); i += 2 {
+		key, ok := redacted[i].(string)
+		if !ok {
+			continue
+		}
+		value := redacted[i+1]
+		if IsSensitiveKey(key) && value != nil {
+			if b, ok := value.([]byte); ok {
+				value = string(b)
+			}
+			redacted[i+1] = HashValue(fmt.Sprint(value))
+			continue
+		}
+		switch v := value.(type) {
+		case string:
+			redacted[i+1] = RedactString(v)
+		case error:
+			redacted[i+1] = RedactString(v.Error())
+		}
+	}
+	return redacted
+}
+
+// NewRedactingLogger wraps a logger so that sensitive values are redacted from all log lines
+func NewRedactingLogger(log logr.Logger) logr.Logger {
+	return &redactingLogger{log: log}
+}
+
+type redactingLogger struct {
+	log logr.Logger
+}
+
+func (l *redactingLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.log.Info(msg, RedactKeysAndValues(keysAndValues...)...)
+}
+
+func (l *redactingLogger) Enabled() bool {
+	return l.log.Enabled()
+}
+
+func (l *redactingLogger) Error(err error, msg string, keysAndValues ...interface{}) {
+	if err != nil {
+		err = redactedError{err}
+	}
+	l.log.Error(err, msg, RedactKeysAndValues(keysAndValues...)...)
+}
+
+func (l *redactingLogger) V(level int) logr.InfoLogger {
+	return &redactingInfoLogger{log: l.log.V(level)}
+}
+
+func (l *redactingLogger) WithValues(keysAndValues ...interface{}) logr.Logger {
+	return &redactingLogger{log: l.log.WithValues(RedactKeysAndValues(keysAndValues...)...)}
+}
+
+func (l *redactingLogger) WithName(name string) logr.Logger {
+	return &redactingLogger{log: l.log.WithName(name)}
+}
+
+type redactingInfoLogger struct {
+	log logr.InfoLogger
+}
+
+func (l *redactingInfoLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.log.Info(msg, RedactKeysAndValues(keysAndValues...)...)
+}
+
+func (l *redactingInfoLogger) Enabled() bool {
+	return l.log.Enabled()
+}
+
+// redactedError is an error whose message is redacted, the original error is kept for errors.Cause
+type redactedError struct {
+	err error
+}
+
+func (e redactedError) Error() string {
+	return RedactString(e.err.Error())
+}
+
+func (e redactedError) Cause() error {
+	return e.err
+}
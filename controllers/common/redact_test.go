@@ -0,0 +1,52 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+func TestRedactKeysAndValues(t *testing.T) {
+	var (
+		g         = gomega.NewGomegaWithT(t)
+		userData  = "#!/bin/bash\n/etc/eks/bootstrap.sh my-cluster --token abc"
+		presigned = "https://bucket.s3.amazonaws.com/bootstrap?X-Amz-Algorithm=AWS4-HMAC-SHA256&X-Amz-Credential=AKIAEXAMPLE%2F20201016&X-Amz-Signature=0123abcd"
+		redacted  = "https://bucket.s3.amazonaws.com/bootstrap?X-Amz-Algorithm=AWS4-HMAC-SHA256&X-Amz-Credential=REDACTED&X-Amz-Signature=REDACTED"
+	)
+
+	values := RedactKeysAndValues(
+		"instancegroup", "my-group",
+		"userData", userData,
+		"user-data", []byte(userData),
+		"url", presigned,
+		"error", errors.Errorf("failed to get %v", presigned),
+		"count", 3,
+	)
+
+	g.Expect(values).To(gomega.Equal([]interface{}{
+		"instancegroup", "my-group",
+		"userData", HashValue(userData),
+		"user-data", HashValue(string([]byte(userData))),
+		"url", redacted,
+		"error", "failed to get " + redacted,
+		"count", 3,
+	}))
+	g.Expect(HashValue(userData)).To(gomega.HavePrefix("sha256:"))
+	g.Expect(IsSensitiveKey("secret")).To(gomega.BeFalse())
+}
@@ -16,6 +16,8 @@ limitations under the License.
 package common
 
 import (
+	"bytes"
+	"compress/gzip"
 	"crypto/md5"
 	"encoding/base64"
 	"encoding/hex"
@@ -53,6 +55,22 @@ func GetDecodedString(str string) (string, error) {
 	return str, nil
 }
 
+// GzipBytes compresses data with gzip, the output does not include a timestamp so equal inputs produce equal outputs
+func GzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
 // ContainsString returns true if a given slice 'slice' contains string 's', otherwise return false
 func ContainsString(slice []string, s string) bool {
 	for _, item := range slice {
@@ -17,8 +17,10 @@ package controllers
 
 import (
 	"context"
+	"fmt"
 	"reflect"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -28,13 +30,17 @@ import (
 	kubeprovider "github.com/keikoproj/instance-manager/controllers/providers/kubernetes"
 	"github.com/keikoproj/instance-manager/controllers/provisioners"
 	"github.com/keikoproj/instance-manager/controllers/provisioners/eks"
-	"github.com/keikoproj/instance-manager/controllers/provisioners/eksfargate"
-	"github.com/keikoproj/instance-manager/controllers/provisioners/eksmanaged"
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 )
 
 // InstanceGroupReconciler reconciles an InstanceGroup object
@@ -47,7 +53,31 @@ type InstanceGroupReconciler struct {
 	MaxParallel            int
 	Auth                   *InstanceGroupAuthenticator
 	ConfigMap              *corev1.ConfigMap
+	configMapLock          sync.RWMutex
+	leaderOnce             sync.Once
 	ConfigRetention        int
+	RevisionHistoryLimit   int
+	AuditRevisionLimit     int
+	ReconcileTimeout       time.Duration
+	DryRun                 bool
+	MinReconcileInterval   time.Duration
+	MaxReconcileInterval   time.Duration
+	Backoff                *RequeueBackoff
+	RemoteClusters         *RemoteClusterClients
+	AssumedRoles           *awsprovider.AssumedRoleWorkers
+	Provisioners           *ProvisionerRegistry
+	LifecycleManager       provisioners.LifecycleManagerConfiguration
+	BootstrapBucket        provisioners.BootstrapBucketConfiguration
+	ResourceNames          provisioners.ResourceNameConfiguration
+	SecurityPolicy         v1alpha1.SecurityPolicy
+
+	// ClusterAPIProvider reconciles instance groups owned by Cluster API MachinePools as their infrastructure, the
+	// MachinePool's replicas size the scaling group and the instances are reported back in spec.providerIDList
+	ClusterAPIProvider    bool
+	MachinePoolAPIVersion string
+
+	// CloudEvents enqueues instance groups whose cloud resources were changed outside of the controller
+	CloudEvents chan event.GenericEvent
 }
 
 type InstanceGroupAuthenticator struct {
@@ -57,6 +87,20 @@ type InstanceGroupAuthenticator struct {
 
 const (
 	FinalizerStr = "finalizer.instancegroups.keikoproj.io"
+
+	// instance groups which follow the cluster version are requeued at this interval to detect control plane upgrades
+	AutoUpgradeRequeueInterval = 10 * time.Minute
+
+	// instance groups with a spot price which is a percentage of the on-demand price are requeued at this interval to
+	// follow changes of the on-demand price
+	SpotPriceRequeueInterval = time.Hour
+
+	// instance groups are requeued at this interval when IMDSv2 is required, so that instances which were launched
+	// since the last reconcile are required to use it
+	IMDSv2RequeueInterval = 5 * time.Minute
+
+	// instance groups are degraded with this reason while their reconciles fail fast on an open AWS API circuit
+	CircuitOpenReason = "AWSCircuitOpen"
 )
 
 func (r *InstanceGroupReconciler) Finalize(instanceGroup *v1alpha1.InstanceGroup) {
@@ -89,17 +133,31 @@ func (r *InstanceGroupReconciler) SetFinalizer(instanceGroup *v1alpha1.InstanceG
 	}
 }
 
-// +kubebuilder:rbac:groups=core,resources=nodes,verbs=list;patch;watch
+// +kubebuilder:rbac:groups=core,resources=nodes,verbs=delete;list;patch;watch
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=list;delete
+// +kubebuilder:rbac:groups=core,resources=pods/eviction,verbs=create
 // +kubebuilder:rbac:groups=core,resources=events,verbs=get;list;watch;create
 // +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;create;update;patch;watch
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get
+// +kubebuilder:rbac:groups=core,namespace=instance-manager,resources=secrets,verbs=create;update
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;create;delete
+// +kubebuilder:rbac:groups=admissionregistration.k8s.io,resources=validatingwebhookconfigurations;mutatingwebhookconfigurations,verbs=get;list;update
 // +kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=instancemgr.keikoproj.io,resources=instancegroups,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=instancemgr.keikoproj.io,resources=instancegroups/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=instancemgr.keikoproj.io,resources=instancegrouptemplates,verbs=get;list;watch
+// +kubebuilder:rbac:groups=instancemgr.keikoproj.io,resources=instancegrouprevisions,verbs=get;list;watch;create;patch;delete
+// +kubebuilder:rbac:groups=instancemgr.keikoproj.io,resources=clusterconfigurations,verbs=get;list;watch
+// +kubebuilder:rbac:groups=upgrademgr.keikoproj.io,resources=rollingupgrades,verbs=get;list;create;delete
+// +kubebuilder:rbac:groups=cluster.x-k8s.io;exp.cluster.x-k8s.io,resources=machinepools,verbs=get;list;watch
 
 func (r *InstanceGroupReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	_ = context.Background()
 	_ = r.Log.WithValues("instancegroup", req.NamespacedName)
 
+	// reconciles only run on the leader, the first one resets caches which may predate the previous leader's changes
+	r.leaderOnce.Do(r.onStartedLeading)
+
 	instanceGroup := &v1alpha1.InstanceGroup{}
 	err := r.Get(context.Background(), req.NamespacedName, instanceGroup)
 	if err != nil {
@@ -114,18 +172,56 @@ func (r *InstanceGroupReconciler) Reconcile(req ctrl.Request) (ctrl.Result, erro
 	// set/unset finalizer
 	r.SetFinalizer(instanceGroup)
 
+	// cloud resources are not deleted while deletion protection is enabled, the finalizer is kept
+	// until the annotation is removed
+	if !instanceGroup.ObjectMeta.DeletionTimestamp.IsZero() && instanceGroup.IsDeletionProtected() {
+		r.Log.Info("instancegroup has deletion protection enabled, will not delete resources",
+			"instancegroup", req.NamespacedName,
+			"annotation", v1alpha1.DeletionProtectionAnnotationKey,
+		)
+		return ctrl.Result{}, nil
+	}
+
+	// the configuration is rolled back with a patch of the spec, the instance group is reconciled again with it
+	if r.handleRollbackRequest(instanceGroup) {
+		return ctrl.Result{}, nil
+	}
+
+	// after a controller restart, instance groups which were failing wait for the remainder of their backoff
+	if remaining := r.Backoff.Restore(req.NamespacedName, instanceGroup.GetStatus().GetBackoff(), time.Now()); remaining > 0 {
+		r.Log.Info("resuming requeue backoff from status", "instancegroup", req.NamespacedName, "requeueAfter", remaining)
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+
+	// bound all cloud provider calls made during this reconcile
+	deadlineCtx, cancel := r.newReconcileContext()
+	defer cancel()
+
+	// the configmap is replaced when it changes, the reconcile uses the configmap it started with
+	configMap := r.GetConfigMap()
+
 	input := provisioners.ProvisionerInput{
-		AwsWorker:       r.Auth.Aws,
-		Kubernetes:      r.Auth.Kubernetes,
-		Configuration:   r.ConfigMap,
-		InstanceGroup:   instanceGroup,
-		Log:             r.Log,
-		ConfigRetention: r.ConfigRetention,
+		AwsWorker:        r.Auth.Aws.WithContext(deadlineCtx),
+		Kubernetes:       r.Auth.Kubernetes,
+		Configuration:    configMap,
+		InstanceGroup:    instanceGroup,
+		Log:              r.Log,
+		ConfigRetention:  r.ConfigRetention,
+		LifecycleManager: r.LifecycleManager,
+		BootstrapBucket:  r.BootstrapBucket,
+		ResourceNames:    r.ResourceNames,
+		SecurityPolicy:   r.SecurityPolicy,
+	}
+
+	if input.InstanceGroup, err = r.applyReferences(instanceGroup); err != nil {
+		instanceGroup.SetState(v1alpha1.ReconcileErr)
+		r.UpdateStatus(instanceGroup)
+		return ctrl.Result{}, err
 	}
 
-	if !reflect.DeepEqual(r.ConfigMap, &corev1.ConfigMap{}) {
+	if !reflect.DeepEqual(configMap, &corev1.ConfigMap{}) {
 		var defaultConfig *provisioners.ProvisionerConfiguration
-		if defaultConfig, err = provisioners.NewProvisionerConfiguration(r.ConfigMap, instanceGroup); err != nil {
+		if defaultConfig, err = provisioners.NewProvisionerConfiguration(configMap, input.InstanceGroup); err != nil {
 			return ctrl.Result{}, err
 		}
 
@@ -135,24 +231,62 @@ func (r *InstanceGroupReconciler) Reconcile(req ctrl.Request) (ctrl.Result, erro
 		}
 
 		input.InstanceGroup = defaultConfig.InstanceGroup
+
+		// field policies of the boundaries are evaluated on the instance group with its defaults, instance groups which
+		// violate a policy added after they were created can still be deleted
+		if instanceGroup.GetDeletionTimestamp().IsZero() {
+			if err = defaultConfig.ValidatePolicies(); err != nil {
+				instanceGroup.SetState(v1alpha1.ReconcileErr)
+				r.UpdateStatus(instanceGroup)
+				return ctrl.Result{}, err
+			}
+		}
+
+		// AWS calls for instance groups of a namespace with a role are made with the role's credentials
+		if role, ok := defaultConfig.GetNamespaceRole(instanceGroup.GetNamespace()); ok {
+			var worker awsprovider.AwsWorker
+			if worker, err = r.AssumedRoles.Get(instanceGroup.GetNamespace(), role); err != nil {
+				instanceGroup.SetState(v1alpha1.ReconcileErr)
+				r.UpdateStatus(instanceGroup)
+				return ctrl.Result{}, err
+			}
+			input.AwsWorker = worker.WithContext(deadlineCtx)
+		}
 	}
 
-	provisionerKind := strings.ToLower(input.InstanceGroup.Spec.Provisioner)
+	if r.isMachinePool(input.InstanceGroup) {
+		if input.InstanceGroup, err = r.applyMachinePoolReplicas(input.InstanceGroup); err != nil {
+			instanceGroup.SetState(v1alpha1.ReconcileErr)
+			r.UpdateStatus(instanceGroup)
+			return ctrl.Result{}, err
+		}
+	}
+
+	if input.InstanceGroup.GetKubeconfigSecretRef() != nil {
+		if input.ClusterKubernetes, err = r.RemoteClusters.Get(r.Auth.Kubernetes.Kubernetes, input.InstanceGroup); err != nil {
+			instanceGroup.SetState(v1alpha1.ReconcileErr)
+			r.UpdateStatus(instanceGroup)
+			return ctrl.Result{}, err
+		}
+	}
 
-	if !common.ContainsEqualFold(v1alpha1.Provisioners, provisionerKind) {
-		return ctrl.Result{}, errors.Errorf("provisioner '%v' does not exist", provisionerKind)
+	if input.InstanceGroup, err = r.applySecurityPolicy(input.InstanceGroup); err != nil {
+		instanceGroup.SetState(v1alpha1.ReconcileErr)
+		r.UpdateStatus(instanceGroup)
+		return ctrl.Result{}, err
 	}
 
-	r.Log.Info("reconcile event started", "instancegroup", req.NamespacedName, "provisioner", provisionerKind)
-	var ctx CloudDeployer
-	switch {
-	case strings.EqualFold(provisionerKind, eks.ProvisionerName):
-		ctx = eks.New(input)
-	case strings.EqualFold(provisionerKind, eksmanaged.ProvisionerName):
-		ctx = eksmanaged.New(input)
-	case strings.EqualFold(provisionerKind, eksfargate.ProvisionerName):
-		ctx = eksfargate.New(input)
+	provisionerKind := strings.ToLower(input.InstanceGroup.Spec.Provisioner)
+
+	// log lines of the provisioner carry the keys of the instance group and honor its log level
+	log := r.instanceGroupLogger(input.InstanceGroup)
+	input.Log = log
+
+	ctx, err := r.Provisioners.New(input)
+	if err != nil {
+		return ctrl.Result{}, err
 	}
+	log.Info("reconcile event started", "instancegroup", req.NamespacedName, "provisioner", provisionerKind)
 
 	if err = input.InstanceGroup.Validate(); err != nil {
 		ctx.SetState(v1alpha1.ReconcileErr)
@@ -160,26 +294,371 @@ func (r *InstanceGroupReconciler) Reconcile(req ctrl.Request) (ctrl.Result, erro
 		return ctrl.Result{}, errors.Wrapf(err, "provisioner %v reconcile failed", provisionerKind)
 	}
 
-	if err = HandleReconcileRequest(ctx); err != nil {
+	if input.InstanceGroup.IsSuspended() {
+		var pending v1alpha1.ReconcileState
+		if pending, err = HandleSuspendedRequest(ctx); err != nil {
+			ctx.SetState(v1alpha1.ReconcileErr)
+			SetCircuitOpenCondition(input.InstanceGroup, err)
+			return r.requeueWithBackoff(input.InstanceGroup, errors.Wrapf(err, "provisioner %v discovery failed", provisionerKind))
+		}
+		r.resetBackoff(input.InstanceGroup)
+		SetCircuitOpenCondition(input.InstanceGroup, nil)
+		log.Info("instancegroup is suspended, skipping changes", "instancegroup", req.NamespacedName, "pendingState", pending)
+		r.UpdateStatus(input.InstanceGroup)
+		r.exportInstanceGroup(instanceGroup, ctx)
+		return ctrl.Result{RequeueAfter: r.reconcileInterval(input.InstanceGroup, 0)}, nil
+	}
+
+	if r.DryRun || input.InstanceGroup.IsDryRun() {
+		var plan []v1alpha1.PlannedChange
+		if plan, err = HandlePlanRequest(ctx); err != nil {
+			ctx.SetState(v1alpha1.ReconcileErr)
+			SetCircuitOpenCondition(input.InstanceGroup, err)
+			return r.requeueWithBackoff(input.InstanceGroup, errors.Wrapf(err, "provisioner %v discovery failed", provisionerKind))
+		}
+		r.resetBackoff(input.InstanceGroup)
+		SetCircuitOpenCondition(input.InstanceGroup, nil)
+		r.publishPlan(input.InstanceGroup, plan)
+		r.UpdateStatus(input.InstanceGroup)
+		r.exportInstanceGroup(instanceGroup, ctx)
+		return ctrl.Result{RequeueAfter: r.reconcileInterval(input.InstanceGroup, 0)}, nil
+	}
+	input.InstanceGroup.GetStatus().SetPlan(nil)
+
+	if input.InstanceGroup.HandleRotateRequest() {
+		log.Info("rotation requested", "instancegroup", req.NamespacedName, "request", input.InstanceGroup.GetRotateRequest(), "rotationCounter", input.InstanceGroup.GetStatus().GetRotationCounter())
+		r.eventPublisher(instanceGroup).Publish(kubeprovider.RotationRequestedEvent, "instancegroup", instanceGroup.GetName(), "request", input.InstanceGroup.GetRotateRequest())
+	}
+
+	var (
+		now              = time.Now()
+		status           = input.InstanceGroup.GetStatus()
+		windowOpen, next = input.InstanceGroup.IsChangeWindowOpen(now)
+		pending          []string
+	)
+
+	if windowOpen {
+		err = HandleReconcileRequest(ctx)
+	} else {
+		pending, err = HandleDeferredRequest(ctx)
+	}
+
+	if err != nil {
 		ctx.SetState(v1alpha1.ReconcileErr)
+		SetCircuitOpenCondition(input.InstanceGroup, err)
+		if rotation := status.GetRotation(); rotation != nil && rotation.GetLastError() != "" {
+			r.recordInstanceGroupRevision(instanceGroup, input.InstanceGroup, v1alpha1.RevisionOutcomeFailed, rotation.GetLastError())
+		}
+		return r.requeueWithBackoff(input.InstanceGroup, errors.Wrapf(err, "provisioner %v reconcile failed", provisionerKind))
+	}
+	r.resetBackoff(input.InstanceGroup)
+	SetCircuitOpenCondition(input.InstanceGroup, nil)
+
+	var providerIDs []string
+	if r.isMachinePool(input.InstanceGroup) {
+		providerIDs = r.setMachinePoolStatus(input.InstanceGroup, ctx)
+	}
+
+	status.SetPendingChanges(pending)
+	if len(pending) > 0 {
+		status.SetNextChangeWindow(&metav1.Time{Time: next})
+		log.Info("changes deferred until next change window", "instancegroup", req.NamespacedName, "pendingChanges", pending, "nextChangeWindow", next)
 		r.UpdateStatus(input.InstanceGroup)
-		return ctrl.Result{}, errors.Wrapf(err, "provisioner %v reconcile failed", provisionerKind)
+		r.removeRotateRequest(instanceGroup, status.GetRotateRequest())
+		r.updateProviderIDList(instanceGroup, providerIDs)
+		return ctrl.Result{RequeueAfter: next.Sub(now)}, nil
+	}
+	status.SetNextChangeWindow(nil)
+
+	// the spec of this generation is applied, its configuration is rolled out once the instance group is ready
+	status.SetObservedGeneration(input.InstanceGroup.GetGeneration())
+	status.SetConfigurationHash(input.InstanceGroup.HashConfiguration())
+	if ctx.GetState() == v1alpha1.ReconcileReady {
+		if status.GetRolloutHash() != status.GetConfigurationHash() {
+			r.recordInstanceGroupRevision(instanceGroup, input.InstanceGroup, v1alpha1.RevisionOutcomeSucceeded, "")
+		}
+		status.SetRolloutHash(status.GetConfigurationHash())
+		r.recordRevision(instanceGroup, input.InstanceGroup)
 	}
 
 	if provisioners.IsRetryable(input.InstanceGroup) {
-		r.Log.Info("reconcile event ended with requeue", "instancegroup", req.NamespacedName, "provisioner", provisionerKind)
+		log.Info("reconcile event ended with requeue", "instancegroup", req.NamespacedName, "provisioner", provisionerKind)
 		r.UpdateStatus(input.InstanceGroup)
+		r.updateProviderIDList(instanceGroup, providerIDs)
 		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
 	}
 
 	r.UpdateStatus(input.InstanceGroup)
 	r.Finalize(instanceGroup)
-	return ctrl.Result{}, nil
+	r.exportInstanceGroup(instanceGroup, ctx)
+	r.removeRotateRequest(instanceGroup, status.GetRotateRequest())
+	r.updateProviderIDList(instanceGroup, providerIDs)
+
+	if !input.InstanceGroup.ObjectMeta.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	var interval time.Duration
+	if strings.EqualFold(provisionerKind, eks.ProvisionerName) {
+		configuration := input.InstanceGroup.GetEKSConfiguration()
+		if configuration.IsAutoUpgrade() || configuration.HasImagePipeline() {
+			interval = AutoUpgradeRequeueInterval
+		} else if input.InstanceGroup.GetStatus().GetResolvedSpotPrice() != "" {
+			interval = SpotPriceRequeueInterval
+		}
+		if r.SecurityPolicy.RequireIMDSv2 && (interval == 0 || interval > IMDSv2RequeueInterval) {
+			interval = IMDSv2RequeueInterval
+		}
+	}
+	return ctrl.Result{RequeueAfter: r.reconcileInterval(input.InstanceGroup, interval)}, nil
 }
 
+// applyReferences returns a copy of the instance group with the settings of its ClusterConfiguration and the
+// configuration of its InstanceGroupTemplate applied, or the instance group itself when it references neither
+func (r *InstanceGroupReconciler) applyReferences(instanceGroup *v1alpha1.InstanceGroup) (*v1alpha1.InstanceGroup, error) {
+	var (
+		clusterRef  = instanceGroup.GetClusterRef()
+		templateRef = instanceGroup.GetTemplateRef()
+	)
+
+	if clusterRef == "" && templateRef == "" {
+		return instanceGroup, nil
+	}
+	resolved := instanceGroup.DeepCopy()
+
+	if clusterRef != "" {
+		cluster := &v1alpha1.ClusterConfiguration{}
+		if err := r.Get(context.Background(), types.NamespacedName{Name: clusterRef}, cluster); err != nil {
+			return nil, errors.Wrapf(err, "failed to get cluster configuration %v", clusterRef)
+		}
+		provisioners.ApplyClusterConfiguration(resolved, cluster)
+	}
+
+	if templateRef != "" {
+		template := &v1alpha1.InstanceGroupTemplate{}
+		if err := r.Get(context.Background(), types.NamespacedName{Name: templateRef}, template); err != nil {
+			return nil, errors.Wrapf(err, "failed to get instance group template %v", templateRef)
+		}
+		if err := provisioners.ApplyTemplate(resolved, template); err != nil {
+			return nil, errors.Wrapf(err, "failed to apply instance group template %v", templateRef)
+		}
+	}
+	return resolved, nil
+}
+
+// reconcileInterval returns the interval at which a reconciled instance group is requeued, the instance group's
+// reconcile interval overrides the default and is kept within the controller's minimum and maximum interval, an
+// interval of 0 leaves the instance group to the manager's sync period
+// applySecurityPolicy returns the instance group with the violations of the security policy remediated, or an error if
+// they are rejected, violations are remediated on a copy so that the spec of the instance group is not changed, the
+// policy is not enforced on instance groups which are being deleted so that groups which violate it can be deleted
+func (r *InstanceGroupReconciler) applySecurityPolicy(instanceGroup *v1alpha1.InstanceGroup) (*v1alpha1.InstanceGroup, error) {
+	if !instanceGroup.GetDeletionTimestamp().IsZero() {
+		return instanceGroup, nil
+	}
+
+	violations := r.SecurityPolicy.Violations(instanceGroup)
+	if len(violations) == 0 {
+		return instanceGroup, nil
+	}
+	if err := r.SecurityPolicy.Validate(instanceGroup); err != nil {
+		return instanceGroup, err
+	}
+
+	r.Log.Info("remediating security policy violations", "instancegroup", instanceGroup.NamespacedName(), "violations", violations)
+	remediated := instanceGroup.DeepCopy()
+	r.SecurityPolicy.Remediate(remediated)
+	return remediated, nil
+}
+
+func (r *InstanceGroupReconciler) reconcileInterval(instanceGroup *v1alpha1.InstanceGroup, defaultInterval time.Duration) time.Duration {
+	interval := instanceGroup.GetReconcileInterval()
+	if interval == 0 {
+		return defaultInterval
+	}
+	if r.MinReconcileInterval > 0 && interval < r.MinReconcileInterval {
+		interval = r.MinReconcileInterval
+	}
+	if r.MaxReconcileInterval > 0 && interval > r.MaxReconcileInterval {
+		interval = r.MaxReconcileInterval
+	}
+	return interval
+}
+
+// requeueWithBackoff records a failed reconcile in the instance group's status, so the backoff survives a
+// controller restart, and requeues it after the backoff delay
+func (r *InstanceGroupReconciler) requeueWithBackoff(instanceGroup *v1alpha1.InstanceGroup, err error) (ctrl.Result, error) {
+	key := types.NamespacedName{Namespace: instanceGroup.GetNamespace(), Name: instanceGroup.GetName()}
+	delay, class, count := r.Backoff.Next(key, err)
+	code, requestID := awsprovider.ErrorDetails(err)
+	instanceGroup.GetStatus().SetBackoff(&v1alpha1.BackoffStatus{
+		LastFailureTime:      &metav1.Time{Time: time.Now()},
+		LastFailureClass:     string(class),
+		ConsecutiveFailures:  count,
+		LastFailureCode:      code,
+		LastFailureRequestID: requestID,
+		LastFailureMessage:   err.Error(),
+	})
+	SetReconcileFailedCondition(instanceGroup, err)
+	r.UpdateStatus(instanceGroup)
+	r.Log.Error(err, "reconcile failed", "instancegroup", key, "errorClass", class, "errorCode", code, "requestId", requestID, "requeueAfter", delay)
+	return ctrl.Result{RequeueAfter: delay}, nil
+}
+
+func (r *InstanceGroupReconciler) resetBackoff(instanceGroup *v1alpha1.InstanceGroup) {
+	r.Backoff.Reset(types.NamespacedName{Namespace: instanceGroup.GetNamespace(), Name: instanceGroup.GetName()})
+	instanceGroup.GetStatus().SetBackoff(nil)
+	instanceGroup.GetStatus().RemoveCondition(v1alpha1.ReconcileFailed)
+}
+
+// SetReconcileFailedCondition sets the ReconcileFailed condition of an instance group with the class of the error as
+// its reason, so that alerts can be routed by the class of failure
+func SetReconcileFailedCondition(instanceGroup *v1alpha1.InstanceGroup, err error) {
+	condition := v1alpha1.NewInstanceGroupCondition(v1alpha1.ReconcileFailed, corev1.ConditionTrue)
+	condition.Reason = string(awsprovider.ClassifyError(err))
+	condition.Message = err.Error()
+	instanceGroup.GetStatus().SetCondition(condition)
+}
+
+// publishPlan records the changes planned for an instance group in dry-run in its status, and publishes an event
+// when the plan differs from the previous one
+func (r *InstanceGroupReconciler) publishPlan(instanceGroup *v1alpha1.InstanceGroup, plan []v1alpha1.PlannedChange) {
+	status := instanceGroup.GetStatus()
+	if reflect.DeepEqual(status.GetPlan(), plan) || len(status.GetPlan())+len(plan) == 0 {
+		return
+	}
+	status.SetPlan(plan)
+
+	changes := make([]string, 0)
+	for _, change := range plan {
+		changes = append(changes, change.String())
+	}
+	r.Log.Info("instancegroup is in dry-run, planned changes", "instancegroup", instanceGroup.NamespacedName(), "plan", changes)
+
+	r.eventPublisher(instanceGroup).Publish(kubeprovider.ChangesPlannedEvent, "instancegroup", instanceGroup.GetName(), "plan", strings.Join(changes, "; "))
+}
+
+// instanceGroupLogger returns the logger of an instance group's reconcile, its log lines carry the cluster,
+// namespace, name, scaling group and launch configuration of the instance group, and include debug log lines when
+// the instance group is annotated with the debug log level
+func (r *InstanceGroupReconciler) instanceGroupLogger(instanceGroup *v1alpha1.InstanceGroup) logr.Logger {
+	log := r.Log
+	if instanceGroup.IsDebugLogging() {
+		log = common.WithDebug(log)
+	}
+	status := instanceGroup.GetStatus()
+	return log.WithValues(
+		"cluster", instanceGroup.GetClusterName(),
+		"namespace", instanceGroup.GetNamespace(),
+		"name", instanceGroup.GetName(),
+		"asg", status.GetActiveScalingGroupName(),
+		"launchConfiguration", status.GetActiveLaunchConfigurationName(),
+	)
+}
+
+func (r *InstanceGroupReconciler) eventPublisher(instanceGroup *v1alpha1.InstanceGroup) *kubeprovider.EventPublisher {
+	return &kubeprovider.EventPublisher{
+		Client:          r.Auth.Kubernetes.Kubernetes,
+		Namespace:       instanceGroup.GetNamespace(),
+		Name:            instanceGroup.GetName(),
+		UID:             instanceGroup.GetUID(),
+		ResourceVersion: instanceGroup.GetResourceVersion(),
+	}
+}
+
+// removeRotateRequest removes a one-off rotate annotation once the rotate request has been handled and recorded in
+// the status, timestamp requests are kept so they are only handled again when the timestamp changes
+func (r *InstanceGroupReconciler) removeRotateRequest(instanceGroup *v1alpha1.InstanceGroup, handled string) {
+	request := instanceGroup.GetRotateRequest()
+	if !strings.EqualFold(request, "true") || handled != request {
+		return
+	}
+
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:null}}}`, v1alpha1.RotateAnnotationKey)
+	if err := r.Patch(context.Background(), instanceGroup, client.ConstantPatch(types.MergePatchType, []byte(patch))); err != nil {
+		r.Log.Error(err, "failed to remove rotate annotation", "instancegroup", instanceGroup.NamespacedName())
+	}
+}
+
+// exportInstanceGroup writes the resolved cloud resources of an instance group annotated for export to a ConfigMap,
+// and removes the annotation once the export is written, it runs after the status update so the annotation is
+// removed with a patch of the latest object
+func (r *InstanceGroupReconciler) exportInstanceGroup(instanceGroup *v1alpha1.InstanceGroup, d CloudDeployer) {
+	format := instanceGroup.GetExportFormat()
+	if format == "" || !instanceGroup.ObjectMeta.DeletionTimestamp.IsZero() {
+		return
+	}
+
+	exporter, ok := d.(Exporter)
+	if !ok {
+		r.Log.Info("provisioner does not support export", "instancegroup", instanceGroup.NamespacedName(), "provisioner", instanceGroup.Spec.Provisioner)
+		return
+	}
+
+	rendered, err := exporter.Export(format)
+	if err != nil {
+		r.Log.Error(err, "failed to export instancegroup", "instancegroup", instanceGroup.NamespacedName(), "format", format)
+		return
+	}
+
+	if err := kubeprovider.WriteExport(r.Auth.Kubernetes.KubeDynamic, instanceGroup, format, rendered); err != nil {
+		r.Log.Error(err, "failed to export instancegroup", "instancegroup", instanceGroup.NamespacedName(), "format", format)
+		return
+	}
+	r.Log.Info("exported instancegroup", "instancegroup", instanceGroup.NamespacedName(), "format", format, "configmap", instanceGroup.GetExportName())
+
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:null}}}`, v1alpha1.ExportAnnotationKey)
+	if err := r.Patch(context.Background(), instanceGroup, client.ConstantPatch(types.MergePatchType, []byte(patch))); err != nil {
+		r.Log.Error(err, "failed to remove export annotation", "instancegroup", instanceGroup.NamespacedName())
+	}
+}
+
+// SetCircuitOpenCondition marks an instance group as degraded when a reconcile failed fast on an open AWS API
+// circuit, and removes the condition once a reconcile completes without it
+func SetCircuitOpenCondition(instanceGroup *v1alpha1.InstanceGroup, err error) {
+	status := instanceGroup.GetStatus()
+	if awsprovider.IsCircuitOpenError(err) {
+		condition := v1alpha1.NewInstanceGroupCondition(v1alpha1.Degraded, corev1.ConditionTrue)
+		condition.Reason = CircuitOpenReason
+		condition.Message = errors.Cause(err).Error()
+		status.SetCondition(condition)
+		return
+	}
+	if condition := status.GetCondition(v1alpha1.Degraded); condition != nil && condition.Reason == CircuitOpenReason {
+		status.RemoveCondition(v1alpha1.Degraded)
+	}
+}
+
+func (r *InstanceGroupReconciler) newReconcileContext() (context.Context, context.CancelFunc) {
+	if r.ReconcileTimeout > 0 {
+		return context.WithTimeout(context.Background(), r.ReconcileTimeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// UpdateStatus applies the status of an instance group with server-side apply, the status is owned by the
+// controller's field manager so it is applied without a resource version and does not conflict with other writers
+// of the instance group, apply patches which fail with a conflict are retried
 func (r *InstanceGroupReconciler) UpdateStatus(ig *v1alpha1.InstanceGroup) {
 	r.Log.Info("updating resource status", "instancegroup", ig.NamespacedName())
-	if err := r.Status().Update(context.Background(), ig); err != nil {
+
+	status, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&ig.Status)
+	if err != nil {
+		r.Log.Info("failed to update status", "error", err, "instancegroup", ig.NamespacedName())
+		return
+	}
+
+	patch := &unstructured.Unstructured{Object: map[string]interface{}{"status": status}}
+	patch.SetGroupVersionKind(v1alpha1.GroupVersion.WithKind(kubeprovider.InvolvedObjectKind))
+	patch.SetNamespace(ig.GetNamespace())
+	patch.SetName(ig.GetName())
+
+	err = retry.OnError(retry.DefaultBackoff, kubeprovider.IsRetryableApplyError, func() error {
+		return r.Status().Patch(context.Background(), patch, client.Apply, client.FieldOwner(kubeprovider.FieldManager), client.ForceOwnership)
+	})
+	if err != nil {
 		r.Log.Info("failed to update status", "error", err, "instancegroup", ig.NamespacedName())
+		return
 	}
+	ig.SetResourceVersion(patch.GetResourceVersion())
 }
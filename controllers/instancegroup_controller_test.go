@@ -0,0 +1,77 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	v1alpha1 "github.com/keikoproj/instance-manager/api/v1alpha1"
+	"github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func mockEKSInstanceGroup(volumes ...v1alpha1.NodeVolume) *v1alpha1.InstanceGroup {
+	return &v1alpha1.InstanceGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "instance-group-1", Namespace: "default"},
+		Spec: v1alpha1.InstanceGroupSpec{
+			Provisioner: v1alpha1.EKSProvisionerName,
+			EKSSpec: &v1alpha1.EKSSpec{
+				EKSConfiguration: &v1alpha1.EKSConfiguration{Volumes: volumes},
+			},
+		},
+	}
+}
+
+func TestApplySecurityPolicy(t *testing.T) {
+	var (
+		g           = gomega.NewGomegaWithT(t)
+		unencrypted = v1alpha1.NodeVolume{Name: "/dev/xvda", Type: "gp2", Size: 20}
+		encrypted   = v1alpha1.NodeVolume{Name: "/dev/xvda", Type: "gp2", Size: 20, Encrypted: aws.Bool(true)}
+	)
+
+	r := &InstanceGroupReconciler{
+		Log:            ctrl.Log.WithName("test"),
+		SecurityPolicy: v1alpha1.SecurityPolicy{RequireEncryptedVolumes: true},
+	}
+
+	// compliant instance groups are not copied
+	ig := mockEKSInstanceGroup(encrypted)
+	applied, err := r.applySecurityPolicy(ig)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(applied).To(gomega.BeIdenticalTo(ig))
+
+	// violations are rejected
+	ig = mockEKSInstanceGroup(unencrypted)
+	_, err = r.applySecurityPolicy(ig)
+	g.Expect(err).To(gomega.HaveOccurred())
+
+	// instance groups which violate the policy can be deleted
+	ig.SetDeletionTimestamp(&metav1.Time{Time: time.Now()})
+	applied, err = r.applySecurityPolicy(ig)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(applied).To(gomega.BeIdenticalTo(ig))
+
+	// violations are remediated on a copy
+	r.SecurityPolicy.Action = v1alpha1.PolicyActionRemediate
+	ig = mockEKSInstanceGroup(unencrypted)
+	applied, err = r.applySecurityPolicy(ig)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(applied.GetEKSConfiguration().GetVolumes()[0].Encrypted).To(gomega.Equal(aws.Bool(true)))
+	g.Expect(ig.GetEKSConfiguration().GetVolumes()[0].Encrypted).To(gomega.BeNil())
+}
@@ -18,7 +18,34 @@ type CloudDeployer interface {
 	IsReady() bool                    // Returns true if state is Ready
 }
 
-func HandleReconcileRequest(d CloudDeployer) error {
+// DriftDetector is implemented by provisioners which can describe pending changes to cloud resources without applying them
+type DriftDetector interface {
+	PendingChanges() []string // Returns the changes which would be made by the discovered state
+}
+
+// Planner is implemented by provisioners which can describe the changes a reconcile would make to cloud resources in detail
+type Planner interface {
+	Plan() []v1alpha.PlannedChange // Returns the changes which would be made by the discovered state
+}
+
+// Exporter is implemented by provisioners which can render the resolved cloud resources of an instance group in
+// another infrastructure as code format
+type Exporter interface {
+	Export(format string) (string, error) // Returns the cloud resources rendered in the given format
+}
+
+// MachinePoolProvider is implemented by provisioners which can report the instances of an instance group to the
+// Cluster API MachinePool it is the infrastructure of
+type MachinePoolProvider interface {
+	ProviderIDs() []string // Returns the provider IDs of the instances of the instance group
+}
+
+var (
+	// DeferrableStates are operations which are deferred outside of change windows
+	DeferrableStates = []v1alpha.ReconcileState{v1alpha.ReconcileInitCreate, v1alpha.ReconcileInitUpdate, v1alpha.ReconcileInitUpgrade}
+)
+
+func discover(d CloudDeployer) error {
 	// Cloud Discovery
 	err := d.CloudDiscovery()
 	if err != nil {
@@ -27,6 +54,53 @@ func HandleReconcileRequest(d CloudDeployer) error {
 
 	// State Discovery
 	d.StateDiscovery()
+	return nil
+}
+
+func HandleReconcileRequest(d CloudDeployer) error {
+	if err := discover(d); err != nil {
+		return err
+	}
+	return handleState(d)
+}
+
+// HandleDeferredRequest runs discovery for an instance group outside of its change windows, operations which create or modify
+// cloud resources are not performed and the changes which are pending are returned, deletion is not deferred
+func HandleDeferredRequest(d CloudDeployer) ([]string, error) {
+	if err := discover(d); err != nil {
+		return nil, err
+	}
+
+	if !isDeferrable(d.GetState()) {
+		return nil, handleState(d)
+	}
+
+	pending := []string{string(d.GetState())}
+	if detector, ok := d.(DriftDetector); ok {
+		pending = detector.PendingChanges()
+	}
+
+	if len(pending) == 0 {
+		// nothing has drifted, nodes can be bootstrapped
+		d.SetState(v1alpha.ReconcileModified)
+		return nil, handleState(d)
+	}
+
+	d.SetState(v1alpha.ReconcileDeferred)
+	return pending, nil
+}
+
+func isDeferrable(state v1alpha.ReconcileState) bool {
+	for _, s := range DeferrableStates {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+func handleState(d CloudDeployer) error {
+	var err error
 
 	// CRUD Delete
 	if d.GetState() == v1alpha.ReconcileInitDelete {
@@ -86,3 +160,34 @@ func HandleReconcileRequest(d CloudDeployer) error {
 	}
 	return nil
 }
+
+// HandlePlanRequest runs discovery for an instance group in dry-run without performing any operation, the changes
+// which would have been made are returned and the state is set to Planned
+func HandlePlanRequest(d CloudDeployer) ([]v1alpha.PlannedChange, error) {
+	if err := discover(d); err != nil {
+		return nil, err
+	}
+
+	plan := make([]v1alpha.PlannedChange, 0)
+	if planner, ok := d.(Planner); ok {
+		plan = planner.Plan()
+	} else if state := d.GetState(); isDeferrable(state) || state == v1alpha.ReconcileInitDelete {
+		// provisioners which cannot plan in detail only describe the operation
+		plan = append(plan, v1alpha.PlannedChange{Resource: "InstanceGroup", Action: string(state)})
+	}
+
+	d.SetState(v1alpha.ReconcilePlanned)
+	return plan, nil
+}
+
+// HandleSuspendedRequest runs discovery for an instance group which is suspended without performing any
+// operation, the state which would have been acted upon is returned and the state is set to Suspended
+func HandleSuspendedRequest(d CloudDeployer) (v1alpha.ReconcileState, error) {
+	if err := discover(d); err != nil {
+		return d.GetState(), err
+	}
+
+	pending := d.GetState()
+	d.SetState(v1alpha.ReconcileSuspended)
+	return pending, nil
+}
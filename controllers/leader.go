@@ -0,0 +1,46 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// onStartedLeading resets the in-memory state of a replica before its first reconcile as the leader, a replica which
+// was a standby serves webhooks and may have cached AWS responses and the configmap while the previous leader was
+// changing them, a replica which loses leadership exits so this runs once per process
+func (r *InstanceGroupReconciler) onStartedLeading() {
+	r.Auth.Aws.FlushCaches()
+	if r.AssumedRoles != nil {
+		r.AssumedRoles.FlushCaches()
+	}
+
+	cm := &corev1.ConfigMap{}
+	err := r.Get(context.Background(), types.NamespacedName{Namespace: r.ConfigNamespace, Name: ConfigMapName}, cm)
+	switch {
+	case kerrors.IsNotFound(err):
+		r.SetConfigMap(&corev1.ConfigMap{})
+	case err != nil:
+		r.Log.Error(err, "failed to reload instance-manager configmap, using the configmap loaded at startup")
+	default:
+		r.SetConfigMap(cm)
+	}
+	r.Log.Info("started leading, flushed caches and reloaded configmap")
+}
@@ -0,0 +1,143 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	v1alpha1 "github.com/keikoproj/instance-manager/api/v1alpha1"
+	"github.com/keikoproj/instance-manager/controllers/provisioners/eks"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+)
+
+const (
+	// DefaultMachinePoolAPIVersion is the API version of the Cluster API MachinePools which are watched
+	DefaultMachinePoolAPIVersion = "cluster.x-k8s.io/v1beta1"
+
+	// InstanceGroupKind is the kind MachinePools refer to instance groups as their infrastructure with
+	InstanceGroupKind = "InstanceGroup"
+)
+
+// isMachinePool returns true when the instance group is the infrastructure of a Cluster API MachinePool and the
+// controller runs as a Cluster API infrastructure provider
+func (r *InstanceGroupReconciler) isMachinePool(instanceGroup *v1alpha1.InstanceGroup) bool {
+	return r.ClusterAPIProvider && instanceGroup.GetMachinePoolOwner() != nil
+}
+
+// applyMachinePoolReplicas returns a copy of the instance group sized to the replicas of its MachinePool, the
+// MachinePool owns the desired capacity of the scaling group so its min and max size are both set to the replicas
+func (r *InstanceGroupReconciler) applyMachinePoolReplicas(instanceGroup *v1alpha1.InstanceGroup) (*v1alpha1.InstanceGroup, error) {
+	owner := instanceGroup.GetMachinePoolOwner()
+	if !strings.EqualFold(instanceGroup.Spec.Provisioner, eks.ProvisionerName) {
+		return nil, errors.Errorf("machinepool %v is only supported with the %v provisioner", owner.Name, eks.ProvisionerName)
+	}
+
+	machinePool := &unstructured.Unstructured{}
+	machinePool.SetGroupVersionKind(schema.FromAPIVersionAndKind(owner.APIVersion, owner.Kind))
+	key := types.NamespacedName{Namespace: instanceGroup.GetNamespace(), Name: owner.Name}
+	if err := r.Get(context.Background(), key, machinePool); err != nil {
+		return nil, errors.Wrapf(err, "failed to get machinepool %v", key)
+	}
+
+	replicas, ok, err := unstructured.NestedInt64(machinePool.Object, "spec", "replicas")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get replicas of machinepool %v", key)
+	}
+
+	resolved := instanceGroup.DeepCopy()
+	if ok {
+		spec := resolved.GetEKSSpec()
+		spec.MinSize = replicas
+		spec.MaxSize = replicas
+		spec.SetDesiredCapacityPolicy(v1alpha1.ManagedDesiredCapacityPolicy)
+	}
+	return resolved, nil
+}
+
+// setMachinePoolStatus reports the instances of an instance group and whether its scaling group is provisioned in
+// its status, the provider IDs of the instances are returned to be set in spec.providerIDList
+func (r *InstanceGroupReconciler) setMachinePoolStatus(instanceGroup *v1alpha1.InstanceGroup, d CloudDeployer) []string {
+	provider, ok := d.(MachinePoolProvider)
+	if !ok {
+		r.Log.Info("provisioner does not support machinepools", "instancegroup", instanceGroup.NamespacedName(), "provisioner", instanceGroup.Spec.Provisioner)
+		return nil
+	}
+
+	var (
+		status      = instanceGroup.GetStatus()
+		providerIDs = provider.ProviderIDs()
+	)
+	status.SetReplicas(int32(len(providerIDs)))
+	status.SetReady(status.GetActiveScalingGroupName() != "" && instanceGroup.ObjectMeta.DeletionTimestamp.IsZero())
+	return providerIDs
+}
+
+// updateProviderIDList sets the provider IDs of an instance group's instances in spec.providerIDList, where the
+// Cluster API contract expects them, it runs after the status update so the spec is patched on the latest object
+func (r *InstanceGroupReconciler) updateProviderIDList(instanceGroup *v1alpha1.InstanceGroup, providerIDs []string) {
+	if providerIDs == nil || reflect.DeepEqual(instanceGroup.GetProviderIDList(), providerIDs) {
+		return
+	}
+	if len(providerIDs)+len(instanceGroup.GetProviderIDList()) == 0 {
+		return
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"providerIDList": providerIDs,
+		},
+	})
+	if err != nil {
+		r.Log.Error(err, "failed to marshal provider IDs", "instancegroup", instanceGroup.NamespacedName())
+		return
+	}
+
+	if err := r.Patch(context.Background(), instanceGroup, client.ConstantPatch(types.MergePatchType, patch)); err != nil {
+		r.Log.Error(err, "failed to update provider IDs", "instancegroup", instanceGroup.NamespacedName())
+	}
+}
+
+// machinePoolReconciler enqueues the instance group a MachinePool refers to as its infrastructure when it changes
+func (r *InstanceGroupReconciler) machinePoolReconciler(obj handler.MapObject) []ctrl.Request {
+	machinePool, ok := obj.Object.(*unstructured.Unstructured)
+	if !ok {
+		return nil
+	}
+
+	ref, ok, err := unstructured.NestedStringMap(machinePool.Object, "spec", "template", "spec", "infrastructureRef")
+	if err != nil || !ok || ref["kind"] != InstanceGroupKind {
+		return nil
+	}
+
+	namespacedName := types.NamespacedName{
+		Namespace: ref["namespace"],
+		Name:      ref["name"],
+	}
+	if namespacedName.Namespace == "" {
+		namespacedName.Namespace = obj.Meta.GetNamespace()
+	}
+	r.Log.Info("machinepool changed", "instancegroup", namespacedName, "machinepool", obj.Meta.GetName())
+	return []ctrl.Request{{NamespacedName: namespacedName}}
+}
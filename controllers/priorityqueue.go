@@ -0,0 +1,188 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	v1alpha1 "github.com/keikoproj/instance-manager/api/v1alpha1"
+	"github.com/pkg/errors"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+)
+
+var ReconcilePriorityWeights = map[string]int{
+	v1alpha1.CriticalReconcilePriority: 3,
+	v1alpha1.HighReconcilePriority:     2,
+	v1alpha1.NormalReconcilePriority:   1,
+	v1alpha1.LowReconcilePriority:      0,
+}
+
+// PriorityFunc returns the priority of a queued item, items with a higher priority are processed first
+type PriorityFunc func(item interface{}) int
+
+// PriorityQueue is a rate limited work queue which hands out the item with the highest priority first, items of
+// the same priority are handed out in the order they were added, like the default work queue an item is never
+// processed concurrently and items added while processing are requeued once processing is done
+type PriorityQueue struct {
+	cond        *sync.Cond
+	priority    PriorityFunc
+	rateLimiter workqueue.RateLimiter
+
+	queue        []*queuedItem
+	dirty        map[interface{}]*queuedItem
+	processing   map[interface{}]struct{}
+	shuttingDown bool
+}
+
+type queuedItem struct {
+	item     interface{}
+	priority int
+}
+
+var _ workqueue.RateLimitingInterface = &PriorityQueue{}
+
+func NewPriorityQueue(priority PriorityFunc, rateLimiter workqueue.RateLimiter) *PriorityQueue {
+	return &PriorityQueue{
+		cond:        sync.NewCond(&sync.Mutex{}),
+		priority:    priority,
+		rateLimiter: rateLimiter,
+		dirty:       make(map[interface{}]*queuedItem),
+		processing:  make(map[interface{}]struct{}),
+	}
+}
+
+// Add queues an item, the priority of an item which is already queued is updated
+func (q *PriorityQueue) Add(item interface{}) {
+	priority := q.priority(item)
+
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	if q.shuttingDown {
+		return
+	}
+	if queued, ok := q.dirty[item]; ok {
+		queued.priority = priority
+		return
+	}
+
+	queued := &queuedItem{item: item, priority: priority}
+	q.dirty[item] = queued
+	if _, ok := q.processing[item]; ok {
+		return
+	}
+
+	q.queue = append(q.queue, queued)
+	q.cond.Signal()
+}
+
+func (q *PriorityQueue) Len() int {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	return len(q.queue)
+}
+
+// Get blocks until an item can be processed and returns the queued item with the highest priority
+func (q *PriorityQueue) Get() (interface{}, bool) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	for len(q.queue) == 0 && !q.shuttingDown {
+		q.cond.Wait()
+	}
+	if len(q.queue) == 0 {
+		return nil, true
+	}
+
+	next := 0
+	for i, queued := range q.queue {
+		if queued.priority > q.queue[next].priority {
+			next = i
+		}
+	}
+	queued := q.queue[next]
+	q.queue = append(q.queue[:next], q.queue[next+1:]...)
+
+	q.processing[queued.item] = struct{}{}
+	delete(q.dirty, queued.item)
+	return queued.item, false
+}
+
+// Done marks an item as processed, it is queued again if it was added while processing
+func (q *PriorityQueue) Done(item interface{}) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+
+	delete(q.processing, item)
+	if queued, ok := q.dirty[item]; ok {
+		q.queue = append(q.queue, queued)
+		q.cond.Signal()
+	}
+}
+
+func (q *PriorityQueue) ShutDown() {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	q.shuttingDown = true
+	q.cond.Broadcast()
+}
+
+func (q *PriorityQueue) ShuttingDown() bool {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	return q.shuttingDown
+}
+
+func (q *PriorityQueue) AddAfter(item interface{}, duration time.Duration) {
+	if q.ShuttingDown() {
+		return
+	}
+	if duration <= 0 {
+		q.Add(item)
+		return
+	}
+	time.AfterFunc(duration, func() {
+		q.Add(item)
+	})
+}
+
+func (q *PriorityQueue) AddRateLimited(item interface{}) {
+	q.AddAfter(item, q.rateLimiter.When(item))
+}
+
+func (q *PriorityQueue) Forget(item interface{}) {
+	q.rateLimiter.Forget(item)
+}
+
+func (q *PriorityQueue) NumRequeues(item interface{}) int {
+	return q.rateLimiter.NumRequeues(item)
+}
+
+// SetControllerQueue replaces the work queue a controller creates when it starts, controller-runtime does not
+// expose an option for the queue so its MakeQueue field is set directly, it must be called before the manager starts
+func SetControllerQueue(c controller.Controller, makeQueue func() workqueue.RateLimitingInterface) error {
+	v := reflect.ValueOf(c)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return errors.Errorf("controller of type %T does not support a custom queue", c)
+	}
+	field := v.Elem().FieldByName("MakeQueue")
+	if !field.IsValid() || !field.CanSet() || field.Type() != reflect.TypeOf(makeQueue) {
+		return errors.Errorf("controller of type %T does not support a custom queue", c)
+	}
+	field.Set(reflect.ValueOf(makeQueue))
+	return nil
+}
@@ -0,0 +1,107 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+)
+
+func TestPriorityQueue(t *testing.T) {
+	var (
+		g          = gomega.NewGomegaWithT(t)
+		priorities = map[string]int{"critical": 3, "high": 2}
+		q          = NewPriorityQueue(func(item interface{}) int {
+			return priorities[item.(string)]
+		}, workqueue.DefaultControllerRateLimiter())
+	)
+
+	get := func() interface{} {
+		item, shutdown := q.Get()
+		g.Expect(shutdown).To(gomega.BeFalse())
+		return item
+	}
+
+	// items with a higher priority are handed out first, items of the same priority in the order they were added
+	q.Add("normal-1")
+	q.Add("high")
+	q.Add("normal-2")
+	q.Add("critical")
+	q.Add("high")
+	g.Expect(q.Len()).To(gomega.Equal(4))
+	g.Expect(get()).To(gomega.Equal("critical"))
+	g.Expect(get()).To(gomega.Equal("high"))
+	g.Expect(get()).To(gomega.Equal("normal-1"))
+	g.Expect(get()).To(gomega.Equal("normal-2"))
+	g.Expect(q.Len()).To(gomega.Equal(0))
+
+	for _, item := range []string{"critical", "high", "normal-1", "normal-2"} {
+		q.Done(item)
+	}
+	g.Expect(q.Len()).To(gomega.Equal(0))
+
+	// the priority of a queued item is updated when it is added again
+	q.Add("normal-1")
+	q.Add("normal-2")
+	priorities["normal-2"] = 3
+	q.Add("normal-2")
+	g.Expect(get()).To(gomega.Equal("normal-2"))
+	g.Expect(get()).To(gomega.Equal("normal-1"))
+
+	// an item added while processing is queued again once it is done
+	q.Add("normal-2")
+	g.Expect(q.Len()).To(gomega.Equal(0))
+	q.Done("normal-2")
+	g.Expect(q.Len()).To(gomega.Equal(1))
+	g.Expect(get()).To(gomega.Equal("normal-2"))
+	q.Done("normal-2")
+	q.Done("normal-1")
+	g.Expect(q.Len()).To(gomega.Equal(0))
+
+	// items are not added after shut down, and getting returns immediately
+	q.ShutDown()
+	q.Add("high")
+	g.Expect(q.ShuttingDown()).To(gomega.BeTrue())
+	item, shutdown := q.Get()
+	g.Expect(item).To(gomega.BeNil())
+	g.Expect(shutdown).To(gomega.BeTrue())
+}
+
+type mockController struct {
+	controller.Controller
+	MakeQueue func() workqueue.RateLimitingInterface
+}
+
+func TestSetControllerQueue(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	makeQueue := func() workqueue.RateLimitingInterface {
+		return NewPriorityQueue(func(interface{}) int { return 0 }, workqueue.DefaultControllerRateLimiter())
+	}
+
+	c := &mockController{}
+	g.Expect(SetControllerQueue(c, makeQueue)).To(gomega.Succeed())
+	g.Expect(c.MakeQueue).NotTo(gomega.BeNil())
+	g.Expect(c.MakeQueue()).To(gomega.BeAssignableToTypeOf(&PriorityQueue{}))
+
+	// controllers without a queue field are not supported
+	var unsupported controller.Controller
+	g.Expect(SetControllerQueue(unsupported, makeQueue)).NotTo(gomega.Succeed())
+	g.Expect(SetControllerQueue(struct{ controller.Controller }{}, makeQueue)).NotTo(gomega.Succeed())
+}
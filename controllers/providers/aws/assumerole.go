@@ -0,0 +1,170 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/keikoproj/aws-sdk-go-cache/cache"
+	"github.com/pkg/errors"
+)
+
+const (
+	// NamespaceSessionTagKey is the session tag which attributes the AWS calls of an assumed role to a namespace
+	NamespaceSessionTagKey = "instance-manager/namespace"
+
+	// ControllerRoleSessionName is the session name of the role assumed for all AWS calls of the controller
+	ControllerRoleSessionName = "instance-manager"
+
+	// RoleSessionNamePrefix is the prefix of the session names of assumed roles, followed by the namespace
+	RoleSessionNamePrefix = "instance-manager-"
+
+	// MaxRoleSessionNameLength is the maximum length of a role session name
+	MaxRoleSessionNameLength = 64
+)
+
+// NamespaceRole is an IAM role the controller assumes for the reconciles of instance groups in a namespace
+type NamespaceRole struct {
+	// RoleArn is the ARN of the role
+	RoleArn string `json:"roleArn"`
+	// ExternalID is passed to the role's trust policy when the role is assumed
+	ExternalID string `json:"externalId,omitempty"`
+	// SessionTags are added to the session tags of the role, the namespace is always tagged
+	SessionTags map[string]string `json:"sessionTags,omitempty"`
+}
+
+// Validate returns an error if the role cannot be assumed
+func (r NamespaceRole) Validate() error {
+	if r.RoleArn == "" {
+		return errors.New("roleArn is required")
+	}
+	if !IsIAMResourceArn(r.RoleArn, "role") {
+		return errors.Errorf("roleArn '%v' is not an IAM role ARN", r.RoleArn)
+	}
+	if _, ok := r.SessionTags[NamespaceSessionTagKey]; ok {
+		return errors.Errorf("session tag '%v' is reserved", NamespaceSessionTagKey)
+	}
+	return nil
+}
+
+// AssumedRoleWorkers creates the workers of the IAM roles assumed for namespaces, workers are cached by namespace and
+// created again when the namespace's role changes, each worker has its own credentials, rate limits and response
+// cache so that responses are not shared across roles, the circuit breaker is shared since it tracks the
+// availability of AWS
+type AssumedRoleWorkers struct {
+	sync.Mutex
+	Config          ClientConfig
+	RoleConfig      AssumeRoleConfig
+	ClusterCacheTTL time.Duration
+	Refresher       *CredentialsRefresher
+	workers         map[string]assumedRoleWorker
+}
+
+type assumedRoleWorker struct {
+	hash   string
+	worker AwsWorker
+}
+
+func NewAssumedRoleWorkers(config ClientConfig, roleConfig AssumeRoleConfig, clusterCacheTTL time.Duration, refresher *CredentialsRefresher) *AssumedRoleWorkers {
+	return &AssumedRoleWorkers{
+		Config:          config,
+		RoleConfig:      roleConfig,
+		ClusterCacheTTL: clusterCacheTTL,
+		Refresher:       refresher,
+		workers:         make(map[string]assumedRoleWorker),
+	}
+}
+
+// Get returns the worker of a namespace's role, its credentials are requested from STS with the credentials of the
+// controller once and refreshed by the refresher before they expire, rather than on every reconcile
+func (w *AssumedRoleWorkers) Get(namespace string, role NamespaceRole) (AwsWorker, error) {
+	if err := role.Validate(); err != nil {
+		return AwsWorker{}, errors.Wrapf(err, "invalid role of namespace %v", namespace)
+	}
+
+	raw, err := json.Marshal(role)
+	if err != nil {
+		return AwsWorker{}, errors.Wrapf(err, "failed to marshal role of namespace %v", namespace)
+	}
+	hash := fmt.Sprintf("%x", sha256.Sum256(raw))
+
+	w.Lock()
+	defer w.Unlock()
+
+	if cached, ok := w.workers[namespace]; ok && cached.hash == hash {
+		return cached.worker, nil
+	}
+
+	creds, err := NewAssumeRoleCredentials(w.Config, w.RoleConfig, role.RoleArn,
+		WithExternalID(role.ExternalID), WithRoleSessionName(RoleSessionName(namespace)), func(p *stscreds.AssumeRoleProvider) {
+			p.Tags = SessionTags(namespace, role.SessionTags)
+		})
+	if err != nil {
+		return AwsWorker{}, errors.Wrapf(err, "failed to create credentials of role %v", role.RoleArn)
+	}
+	if w.Refresher != nil {
+		w.Refresher.Add(namespace, role.RoleArn, creds)
+	}
+
+	config := w.Config
+	config.Credentials = creds
+
+	worker := config.NewWorker(cache.NewConfig(CacheDefaultTTL, CacheMaxItems, CacheItemsToPrune))
+	worker.ClusterCache = NewClusterCache(w.ClusterCacheTTL)
+
+	w.workers[namespace] = assumedRoleWorker{hash: hash, worker: worker}
+	return worker, nil
+}
+
+// FlushCaches removes the cached responses and clusters of the workers of all namespaces
+func (w *AssumedRoleWorkers) FlushCaches() {
+	w.Lock()
+	defer w.Unlock()
+	for _, cached := range w.workers {
+		cached.worker.FlushCaches()
+	}
+}
+
+// RoleSessionName returns the session name of the role assumed for a namespace
+func RoleSessionName(namespace string) string {
+	name := fmt.Sprintf("%v%v", RoleSessionNamePrefix, namespace)
+	if len(name) > MaxRoleSessionNameLength {
+		name = name[:MaxRoleSessionNameLength]
+	}
+	return name
+}
+
+// SessionTags returns the session tags of the role assumed for a namespace, sorted by key
+func SessionTags(namespace string, tags map[string]string) []*sts.Tag {
+	sessionTags := []*sts.Tag{
+		{Key: aws.String(NamespaceSessionTagKey), Value: aws.String(namespace)},
+	}
+	for key, value := range tags {
+		sessionTags = append(sessionTags, &sts.Tag{Key: aws.String(key), Value: aws.String(value)})
+	}
+	sort.Slice(sessionTags, func(i, j int) bool {
+		return aws.StringValue(sessionTags[i].Key) < aws.StringValue(sessionTags[j].Key)
+	})
+	return sessionTags
+}
@@ -16,23 +16,41 @@ limitations under the License.
 package aws
 
 import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
 	"os"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/ec2metadata"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/autoscaling"
 	"github.com/aws/aws-sdk-go/service/autoscaling/autoscalingiface"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
 	"github.com/aws/aws-sdk-go/service/eks"
 	"github.com/aws/aws-sdk-go/service/eks/eksiface"
 	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/imagebuilder/imagebuilderiface"
+	"github.com/aws/aws-sdk-go/service/outposts/outpostsiface"
+	"github.com/aws/aws-sdk-go/service/pricing/pricingiface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/savingsplans/savingsplansiface"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+	"github.com/aws/aws-sdk-go/service/sts"
 	"github.com/keikoproj/aws-sdk-go-cache/cache"
 	"github.com/keikoproj/instance-manager/controllers/common"
 	"github.com/pkg/errors"
@@ -52,11 +70,25 @@ const (
 	GetInstanceProfileTTL           time.Duration = 60 * time.Second
 	DescribeNodegroupTTL            time.Duration = 60 * time.Second
 	DescribeLifecycleHooksTTL       time.Duration = 180 * time.Second
+	DescribeTagsTTL                 time.Duration = 60 * time.Second
 	DescribeClusterTTL              time.Duration = 180 * time.Second
 	DescribeSecurityGroupsTTL       time.Duration = 180 * time.Second
 	DescribeSubnetsTTL              time.Duration = 180 * time.Second
+	DescribeAvailabilityZonesTTL    time.Duration = 3600 * time.Second
+	InstanceTypeOfferingsTTL        time.Duration = 3600 * time.Second
+	OutpostInstanceTypesTTL         time.Duration = 3600 * time.Second
+	GetParameterTTL                 time.Duration = 300 * time.Second
+	GetProductsTTL                  time.Duration = 21600 * time.Second
+	DescribeReservedInstancesTTL    time.Duration = 3600 * time.Second
+	DescribeSavingsPlansTTL         time.Duration = 3600 * time.Second
 	CacheMaxItems                   int64         = 5000
 	CacheItemsToPrune               uint32        = 500
+
+	// MaxInstanceProtectionBatchSize is the maximum number of instances in a single SetInstanceProtection call
+	MaxInstanceProtectionBatchSize = 50
+
+	// MaxScalingGroupNamesBatchSize is the maximum number of names in a single DescribeAutoScalingGroups call
+	MaxScalingGroupNamesBatchSize = 50
 )
 
 type AwsWorker struct {
@@ -64,7 +96,64 @@ type AwsWorker struct {
 	EksClient  eksiface.EKSAPI
 	IamClient  iamiface.IAMAPI
 	Ec2Client  ec2iface.EC2API
+	S3Client   s3iface.S3API
+	SsmClient  ssmiface.SSMAPI
+	SqsClient  sqsiface.SQSAPI
 	Parameters map[string]interface{}
+	// Partition is the partition of the worker's region, ARNs of AWS managed policies are constructed in it
+	Partition string
+	ctx       context.Context
+
+	// ClusterCache is shared by all copies of the worker, clusters are described on every call when it is nil
+	ClusterCache *ClusterCache
+	// ResponseCache caches the responses of the worker's clients, it is shared by all copies of the worker
+	ResponseCache *cache.Config
+
+	// OutpostsClient lists the instance types which are installed on outposts
+	OutpostsClient outpostsiface.OutpostsAPI
+	// ImageBuilderClient lists the images built by Image Builder pipelines
+	ImageBuilderClient imagebuilderiface.ImagebuilderAPI
+	// CloudWatchClient manages the alarms of instance groups
+	CloudWatchClient cloudwatchiface.CloudWatchAPI
+	// PricingClient gets the on-demand prices of instance types
+	PricingClient pricingiface.PricingAPI
+	// SavingsPlansClient lists the savings plans which instance types are covered by
+	SavingsPlansClient savingsplansiface.SavingsPlansAPI
+	// Region is the region of the worker, images of pipelines are resolved to the AMIs distributed to it
+	Region string
+}
+
+// WithContext returns a copy of the worker which makes all API calls with ctx, so that they are
+// cancelled when ctx is done or its deadline expires
+func (w AwsWorker) WithContext(ctx context.Context) AwsWorker {
+	w.ctx = ctx
+	return w
+}
+
+// FlushCaches removes all cached responses and clusters of the worker
+func (w AwsWorker) FlushCaches() {
+	if w.ResponseCache != nil {
+		// all caches are named after their service, which have the empty prefix
+		w.ResponseCache.FlushCache("")
+	}
+	if w.ClusterCache != nil {
+		w.ClusterCache.Flush()
+	}
+}
+
+// GetPartition returns the partition of the worker's region
+func (w *AwsWorker) GetPartition() string {
+	if w.Partition == "" {
+		return DefaultPartition
+	}
+	return w.Partition
+}
+
+func (w *AwsWorker) context() context.Context {
+	if w.ctx == nil {
+		return context.Background()
+	}
+	return w.ctx
 }
 
 var (
@@ -100,20 +189,32 @@ var (
 		"GroupTotalCapacity",
 	}
 
+	// DefaultNotificationTypes are the notifications of launches and terminations of instances, and their failures,
+	// which are sent to SNS topics when no notification types are configured
+	DefaultNotificationTypes = []string{
+		"autoscaling:EC2_INSTANCE_LAUNCH",
+		"autoscaling:EC2_INSTANCE_LAUNCH_ERROR",
+		"autoscaling:EC2_INSTANCE_TERMINATE",
+		"autoscaling:EC2_INSTANCE_TERMINATE_ERROR",
+	}
+	AllowedNotificationTypes = append([]string{"autoscaling:TEST_NOTIFICATION"}, DefaultNotificationTypes...)
+
 	AllowedVolumeTypes               = []string{"gp2", "io1", "sc1", "st1"}
 	LifecycleHookTransitionLaunch    = "autoscaling:EC2_INSTANCE_LAUNCHING"
 	LifecycleHookTransitionTerminate = "autoscaling:EC2_INSTANCE_TERMINATING"
 )
 
 const (
-	IAMPolicyPrefix                         = "arn:aws:iam::aws:policy"
-	IAMARNPrefix                            = "arn:aws:iam::"
-	ARNPrefix                               = "arn:aws:"
 	LaunchConfigurationNotFoundErrorMessage = "Launch configuration name not found"
+	// ImageNotFoundErrorCode is the error code of images which do not exist or were deregistered
+	ImageNotFoundErrorCode = "InvalidAMIID.NotFound"
+
+	// UserDataMaxSize is the maximum size of launch configuration user data before it is base64 encoded
+	UserDataMaxSize = 16384
 )
 
 func (w *AwsWorker) CreateLifecycleHook(input *autoscaling.PutLifecycleHookInput) error {
-	_, err := w.AsgClient.PutLifecycleHook(input)
+	_, err := w.AsgClient.PutLifecycleHookWithContext(w.context(), input)
 	if err != nil {
 		return err
 	}
@@ -121,7 +222,7 @@ func (w *AwsWorker) CreateLifecycleHook(input *autoscaling.PutLifecycleHookInput
 }
 
 func (w *AwsWorker) DeleteLifecycleHook(asgName, hookName string) error {
-	_, err := w.AsgClient.DeleteLifecycleHook(&autoscaling.DeleteLifecycleHookInput{
+	_, err := w.AsgClient.DeleteLifecycleHookWithContext(w.context(), &autoscaling.DeleteLifecycleHookInput{
 		AutoScalingGroupName: aws.String(asgName),
 		LifecycleHookName:    aws.String(hookName),
 	})
@@ -132,7 +233,7 @@ func (w *AwsWorker) DeleteLifecycleHook(asgName, hookName string) error {
 }
 
 func (w *AwsWorker) DescribeLifecycleHooks(asgName string) ([]*autoscaling.LifecycleHook, error) {
-	out, err := w.AsgClient.DescribeLifecycleHooks(&autoscaling.DescribeLifecycleHooksInput{
+	out, err := w.AsgClient.DescribeLifecycleHooksWithContext(w.context(), &autoscaling.DescribeLifecycleHooksInput{
 		AutoScalingGroupName: aws.String(asgName),
 	})
 	if err != nil {
@@ -158,7 +259,7 @@ func (w *AwsWorker) InstanceProfileExist(name string) (*iam.InstanceProfile, boo
 		}
 	)
 
-	out, err := w.IamClient.GetInstanceProfile(input)
+	out, err := w.IamClient.GetInstanceProfileWithContext(w.context(), input)
 	if err != nil {
 		return instanceProfile, false
 	}
@@ -193,8 +294,16 @@ func (w *AwsWorker) GetBasicBlockDevice(name, volType, snapshot string, volSize,
 	return device
 }
 
+// GetEphemeralBlockDevice returns the mapping of an instance store volume, e.g. ephemeral0
+func (w *AwsWorker) GetEphemeralBlockDevice(name string, index int) *autoscaling.BlockDeviceMapping {
+	return &autoscaling.BlockDeviceMapping{
+		DeviceName:  aws.String(name),
+		VirtualName: aws.String(fmt.Sprintf("ephemeral%v", index)),
+	}
+}
+
 func (w *AwsWorker) CreateLaunchConfig(input *autoscaling.CreateLaunchConfigurationInput) error {
-	_, err := w.AsgClient.CreateLaunchConfiguration(input)
+	_, err := w.AsgClient.CreateLaunchConfigurationWithContext(w.context(), input)
 	if err != nil {
 		return err
 	}
@@ -205,7 +314,7 @@ func (w *AwsWorker) DeleteLaunchConfig(name string) error {
 	input := &autoscaling.DeleteLaunchConfigurationInput{
 		LaunchConfigurationName: aws.String(name),
 	}
-	_, err := w.AsgClient.DeleteLaunchConfiguration(input)
+	_, err := w.AsgClient.DeleteLaunchConfigurationWithContext(w.context(), input)
 	if err != nil {
 		return err
 	}
@@ -213,7 +322,7 @@ func (w *AwsWorker) DeleteLaunchConfig(name string) error {
 }
 
 func (w *AwsWorker) CreateScalingGroup(input *autoscaling.CreateAutoScalingGroupInput) error {
-	_, err := w.AsgClient.CreateAutoScalingGroup(input)
+	_, err := w.AsgClient.CreateAutoScalingGroupWithContext(w.context(), input)
 	if err != nil {
 		return err
 	}
@@ -222,7 +331,7 @@ func (w *AwsWorker) CreateScalingGroup(input *autoscaling.CreateAutoScalingGroup
 
 func (w *AwsWorker) UpdateScalingGroupTags(add []*autoscaling.Tag, remove []*autoscaling.Tag) error {
 	if len(add) > 0 {
-		_, err := w.AsgClient.CreateOrUpdateTags(&autoscaling.CreateOrUpdateTagsInput{
+		_, err := w.AsgClient.CreateOrUpdateTagsWithContext(w.context(), &autoscaling.CreateOrUpdateTagsInput{
 			Tags: add,
 		})
 		if err != nil {
@@ -231,7 +340,7 @@ func (w *AwsWorker) UpdateScalingGroupTags(add []*autoscaling.Tag, remove []*aut
 	}
 
 	if len(remove) > 0 {
-		_, err := w.AsgClient.DeleteTags(&autoscaling.DeleteTagsInput{
+		_, err := w.AsgClient.DeleteTagsWithContext(w.context(), &autoscaling.DeleteTagsInput{
 			Tags: remove,
 		})
 		if err != nil {
@@ -242,7 +351,7 @@ func (w *AwsWorker) UpdateScalingGroupTags(add []*autoscaling.Tag, remove []*aut
 }
 
 func (w *AwsWorker) UpdateScalingGroup(input *autoscaling.UpdateAutoScalingGroupInput) error {
-	_, err := w.AsgClient.UpdateAutoScalingGroup(input)
+	_, err := w.AsgClient.UpdateAutoScalingGroupWithContext(w.context(), input)
 	if err != nil {
 		return err
 	}
@@ -254,7 +363,7 @@ func (w *AwsWorker) DeleteScalingGroup(name string) error {
 		AutoScalingGroupName: aws.String(name),
 		ForceDelete:          aws.Bool(true),
 	}
-	_, err := w.AsgClient.DeleteAutoScalingGroup(input)
+	_, err := w.AsgClient.DeleteAutoScalingGroupWithContext(w.context(), input)
 	if err != nil {
 		return err
 	}
@@ -266,7 +375,7 @@ func (w *AwsWorker) SetSuspendProcesses(name string, processesToSuspend []string
 		AutoScalingGroupName: aws.String(name),
 		ScalingProcesses:     aws.StringSlice(processesToSuspend),
 	}
-	_, err := w.AsgClient.SuspendProcesses(input)
+	_, err := w.AsgClient.SuspendProcessesWithContext(w.context(), input)
 	if err != nil {
 		return err
 	}
@@ -278,7 +387,7 @@ func (w *AwsWorker) SetResumeProcesses(name string, processesToResume []string)
 		AutoScalingGroupName: aws.String(name),
 		ScalingProcesses:     aws.StringSlice(processesToResume),
 	}
-	_, err := w.AsgClient.ResumeProcesses(input)
+	_, err := w.AsgClient.ResumeProcessesWithContext(w.context(), input)
 	if err != nil {
 		return err
 	}
@@ -312,7 +421,7 @@ func (w *AwsWorker) WithRetries(f func() bool) error {
 
 func (w *AwsWorker) TerminateScalingInstances(instanceIds []string) error {
 	for _, instance := range instanceIds {
-		_, err := w.AsgClient.TerminateInstanceInAutoScalingGroup(&autoscaling.TerminateInstanceInAutoScalingGroupInput{
+		_, err := w.AsgClient.TerminateInstanceInAutoScalingGroupWithContext(w.context(), &autoscaling.TerminateInstanceInAutoScalingGroupInput{
 			InstanceId:                     aws.String(instance),
 			ShouldDecrementDesiredCapacity: aws.Bool(false),
 		})
@@ -323,9 +432,43 @@ func (w *AwsWorker) TerminateScalingInstances(instanceIds []string) error {
 	return nil
 }
 
+// TerminateScalingInstancesWithDecrement terminates instances and decrements the desired capacity of their scaling
+// group, so that the instances are not replaced
+func (w *AwsWorker) TerminateScalingInstancesWithDecrement(instanceIds []string) error {
+	for _, instance := range instanceIds {
+		_, err := w.AsgClient.TerminateInstanceInAutoScalingGroupWithContext(w.context(), &autoscaling.TerminateInstanceInAutoScalingGroupInput{
+			InstanceId:                     aws.String(instance),
+			ShouldDecrementDesiredCapacity: aws.Bool(true),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetScalingInstanceProtection sets or removes scale-in protection from instances of a scaling group
+func (w *AwsWorker) SetScalingInstanceProtection(asgName string, instanceIds []string, protected bool) error {
+	for start := 0; start < len(instanceIds); start += MaxInstanceProtectionBatchSize {
+		end := start + MaxInstanceProtectionBatchSize
+		if end > len(instanceIds) {
+			end = len(instanceIds)
+		}
+		_, err := w.AsgClient.SetInstanceProtectionWithContext(w.context(), &autoscaling.SetInstanceProtectionInput{
+			AutoScalingGroupName: aws.String(asgName),
+			InstanceIds:          aws.StringSlice(instanceIds[start:end]),
+			ProtectedFromScaleIn: aws.Bool(protected),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (w *AwsWorker) DeleteScalingGroupRole(name string, managedPolicies []string) error {
 	for _, policy := range managedPolicies {
-		_, err := w.IamClient.DetachRolePolicy(&iam.DetachRolePolicyInput{
+		_, err := w.IamClient.DetachRolePolicyWithContext(w.context(), &iam.DetachRolePolicyInput{
 			RoleName:  aws.String(name),
 			PolicyArn: aws.String(policy),
 		})
@@ -334,7 +477,7 @@ func (w *AwsWorker) DeleteScalingGroupRole(name string, managedPolicies []string
 		}
 	}
 
-	_, err := w.IamClient.RemoveRoleFromInstanceProfile(&iam.RemoveRoleFromInstanceProfileInput{
+	_, err := w.IamClient.RemoveRoleFromInstanceProfileWithContext(w.context(), &iam.RemoveRoleFromInstanceProfileInput{
 		InstanceProfileName: aws.String(name),
 		RoleName:            aws.String(name),
 	})
@@ -346,7 +489,7 @@ func (w *AwsWorker) DeleteScalingGroupRole(name string, managedPolicies []string
 		}
 	}
 
-	_, err = w.IamClient.DeleteInstanceProfile(&iam.DeleteInstanceProfileInput{
+	_, err = w.IamClient.DeleteInstanceProfileWithContext(w.context(), &iam.DeleteInstanceProfileInput{
 		InstanceProfileName: aws.String(name),
 	})
 	if err != nil {
@@ -359,7 +502,7 @@ func (w *AwsWorker) DeleteScalingGroupRole(name string, managedPolicies []string
 
 	// must wait until all policies are detached
 	err = w.WithRetries(func() bool {
-		_, err := w.IamClient.DeleteRole(&iam.DeleteRoleInput{
+		_, err := w.IamClient.DeleteRoleWithContext(w.context(), &iam.DeleteRoleInput{
 			RoleName: aws.String(name),
 		})
 		if err != nil {
@@ -381,7 +524,7 @@ func (w *AwsWorker) DeleteScalingGroupRole(name string, managedPolicies []string
 
 func (w *AwsWorker) AttachManagedPolicies(name string, managedPolicies []string) error {
 	for _, policy := range managedPolicies {
-		_, err := w.IamClient.AttachRolePolicy(&iam.AttachRolePolicyInput{
+		_, err := w.IamClient.AttachRolePolicyWithContext(w.context(), &iam.AttachRolePolicyInput{
 			RoleName:  aws.String(name),
 			PolicyArn: aws.String(policy),
 		})
@@ -394,7 +537,7 @@ func (w *AwsWorker) AttachManagedPolicies(name string, managedPolicies []string)
 
 func (w *AwsWorker) DetachManagedPolicies(name string, managedPolicies []string) error {
 	for _, policy := range managedPolicies {
-		_, err := w.IamClient.DetachRolePolicy(&iam.DetachRolePolicyInput{
+		_, err := w.IamClient.DetachRolePolicyWithContext(w.context(), &iam.DetachRolePolicyInput{
 			RoleName:  aws.String(name),
 			PolicyArn: aws.String(policy),
 		})
@@ -407,7 +550,7 @@ func (w *AwsWorker) DetachManagedPolicies(name string, managedPolicies []string)
 
 func (w *AwsWorker) ListRolePolicies(name string) ([]*iam.AttachedPolicy, error) {
 	policies := []*iam.AttachedPolicy{}
-	err := w.IamClient.ListAttachedRolePoliciesPages(
+	err := w.IamClient.ListAttachedRolePoliciesPagesWithContext(w.context(),
 		&iam.ListAttachedRolePoliciesInput{
 			RoleName: aws.String(name),
 		},
@@ -425,21 +568,21 @@ func (w *AwsWorker) ListRolePolicies(name string) ([]*iam.AttachedPolicy, error)
 
 func (w *AwsWorker) CreateScalingGroupRole(name string) (*iam.Role, *iam.InstanceProfile, error) {
 	var (
-		assumeRolePolicyDocument = `{
+		assumeRolePolicyDocument = fmt.Sprintf(`{
 			"Version": "2012-10-17",
 			"Statement": [{
 				"Effect": "Allow",
 				"Principal": {
-					"Service": "ec2.amazonaws.com"
+					"Service": "%v"
 				},
 				"Action": "sts:AssumeRole"
 			}]
-		}`
+		}`, ServicePrincipal(w.GetPartition(), ec2.EndpointsID))
 		createdRole    = &iam.Role{}
 		createdProfile = &iam.InstanceProfile{}
 	)
 	if role, ok := w.RoleExist(name); !ok {
-		out, err := w.IamClient.CreateRole(&iam.CreateRoleInput{
+		out, err := w.IamClient.CreateRoleWithContext(w.context(), &iam.CreateRoleInput{
 			RoleName:                 aws.String(name),
 			AssumeRolePolicyDocument: aws.String(assumeRolePolicyDocument),
 		})
@@ -452,7 +595,7 @@ func (w *AwsWorker) CreateScalingGroupRole(name string) (*iam.Role, *iam.Instanc
 	}
 
 	if instanceProfile, ok := w.InstanceProfileExist(name); !ok {
-		out, err := w.IamClient.CreateInstanceProfile(&iam.CreateInstanceProfileInput{
+		out, err := w.IamClient.CreateInstanceProfileWithContext(w.context(), &iam.CreateInstanceProfileInput{
 			InstanceProfileName: aws.String(name),
 		})
 		if err != nil {
@@ -461,7 +604,7 @@ func (w *AwsWorker) CreateScalingGroupRole(name string) (*iam.Role, *iam.Instanc
 		createdProfile = out.InstanceProfile
 		time.Sleep(DefaultInstanceProfilePropagationDelay)
 
-		_, err = w.IamClient.AddRoleToInstanceProfile(&iam.AddRoleToInstanceProfileInput{
+		_, err = w.IamClient.AddRoleToInstanceProfileWithContext(w.context(), &iam.AddRoleToInstanceProfileInput{
 			InstanceProfileName: aws.String(name),
 			RoleName:            aws.String(name),
 		})
@@ -486,7 +629,7 @@ func (w *AwsWorker) IsNodeGroupExist() bool {
 		ClusterName:   aws.String(w.Parameters["ClusterName"].(string)),
 		NodegroupName: aws.String(w.Parameters["NodegroupName"].(string)),
 	}
-	_, err := w.EksClient.DescribeNodegroup(input)
+	_, err := w.EksClient.DescribeNodegroupWithContext(w.context(), input)
 	if err != nil {
 		if awsErr, ok := err.(awserr.Error); ok {
 			if awsErr.Code() == eks.ErrCodeResourceNotFoundException {
@@ -500,16 +643,27 @@ func (w *AwsWorker) IsNodeGroupExist() bool {
 	return true
 }
 
+// DescribeEKSCluster returns a cluster from the cluster cache, or describes it if it is not cached
 func (w *AwsWorker) DescribeEKSCluster(clusterName string) (*eks.Cluster, error) {
+	if w.ClusterCache != nil {
+		if cluster, ok := w.ClusterCache.Get(clusterName); ok {
+			return cluster, nil
+		}
+	}
+
 	cluster := &eks.Cluster{}
 	input := &eks.DescribeClusterInput{
 		Name: aws.String(clusterName),
 	}
 
-	output, err := w.EksClient.DescribeCluster(input)
+	output, err := w.EksClient.DescribeClusterWithContext(w.context(), input)
 	if err != nil {
 		return cluster, err
 	}
+
+	if w.ClusterCache != nil {
+		w.ClusterCache.Set(clusterName, output.Cluster)
+	}
 	return output.Cluster, nil
 }
 
@@ -519,7 +673,7 @@ func (w *AwsWorker) GetSelfNodeGroup() (error, *eks.Nodegroup) {
 		ClusterName:   aws.String(w.Parameters["ClusterName"].(string)),
 		NodegroupName: aws.String(w.Parameters["NodegroupName"].(string)),
 	}
-	output, err := w.EksClient.DescribeNodegroup(input)
+	output, err := w.EksClient.DescribeNodegroupWithContext(w.context(), input)
 	if err != nil {
 		return err, &eks.Nodegroup{}
 	}
@@ -531,7 +685,7 @@ func (w *AwsWorker) DeleteManagedNodeGroup() error {
 		ClusterName:   aws.String(w.Parameters["ClusterName"].(string)),
 		NodegroupName: aws.String(w.Parameters["NodegroupName"].(string)),
 	}
-	_, err := w.EksClient.DeleteNodegroup(input)
+	_, err := w.EksClient.DeleteNodegroupWithContext(w.context(), input)
 	if err != nil {
 		return err
 	}
@@ -581,7 +735,7 @@ func (w *AwsWorker) UpdateManagedNodeGroup(currentDesired int64, labelsPayload *
 		},
 		Labels: labelsPayload,
 	}
-	_, err := w.EksClient.UpdateNodegroupConfig(input)
+	_, err := w.EksClient.UpdateNodegroupConfigWithContext(w.context(), input)
 	if err != nil {
 		return err
 	}
@@ -612,7 +766,7 @@ func (w *AwsWorker) CreateManagedNodeGroup() error {
 		Version: aws.String(w.Parameters["Version"].(string)),
 	}
 
-	_, err := w.EksClient.CreateNodegroup(input)
+	_, err := w.EksClient.CreateNodegroupWithContext(w.context(), input)
 	if err != nil {
 		return err
 	}
@@ -642,7 +796,7 @@ func (w *AwsWorker) SubnetByName(name, vpc string) (*ec2.Subnet, error) {
 	subnets := []*ec2.Subnet{}
 	filteredSubnets := []*ec2.Subnet{}
 
-	err := w.Ec2Client.DescribeSubnetsPages(
+	err := w.Ec2Client.DescribeSubnetsPagesWithContext(w.context(),
 		&ec2.DescribeSubnetsInput{
 			Filters: []*ec2.Filter{
 				{
@@ -681,7 +835,7 @@ func (w *AwsWorker) SubnetByName(name, vpc string) (*ec2.Subnet, error) {
 func (w *AwsWorker) SecurityGroupByName(name, vpc string) (*ec2.SecurityGroup, error) {
 	groups := []*ec2.SecurityGroup{}
 	filteredGroups := []*ec2.SecurityGroup{}
-	err := w.Ec2Client.DescribeSecurityGroupsPages(
+	err := w.Ec2Client.DescribeSecurityGroupsPagesWithContext(w.context(),
 		&ec2.DescribeSecurityGroupsInput{
 			Filters: []*ec2.Filter{
 				{
@@ -716,9 +870,40 @@ func (w *AwsWorker) SecurityGroupByName(name, vpc string) (*ec2.SecurityGroup, e
 	return filteredGroups[0], nil
 }
 
+// DescribeInstanceType returns the details of an instance type, or nil if it does not exist
+func (w *AwsWorker) DescribeInstanceType(instanceType string) (*ec2.InstanceTypeInfo, error) {
+	output, err := w.Ec2Client.DescribeInstanceTypesWithContext(w.context(), &ec2.DescribeInstanceTypesInput{
+		InstanceTypes: aws.StringSlice([]string{instanceType}),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(output.InstanceTypes) == 0 {
+		return nil, nil
+	}
+	return output.InstanceTypes[0], nil
+}
+
+// DescribeImage returns the details of an image, or nil if it does not exist or was deregistered
+func (w *AwsWorker) DescribeImage(imageId string) (*ec2.Image, error) {
+	output, err := w.Ec2Client.DescribeImagesWithContext(w.context(), &ec2.DescribeImagesInput{
+		ImageIds: aws.StringSlice([]string{imageId}),
+	})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == ImageNotFoundErrorCode {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if len(output.Images) == 0 {
+		return nil, nil
+	}
+	return output.Images[0], nil
+}
+
 func (w *AwsWorker) DescribeAutoscalingGroups() ([]*autoscaling.Group, error) {
 	scalingGroups := []*autoscaling.Group{}
-	err := w.AsgClient.DescribeAutoScalingGroupsPages(&autoscaling.DescribeAutoScalingGroupsInput{}, func(page *autoscaling.DescribeAutoScalingGroupsOutput, lastPage bool) bool {
+	err := w.AsgClient.DescribeAutoScalingGroupsPagesWithContext(w.context(), &autoscaling.DescribeAutoScalingGroupsInput{}, func(page *autoscaling.DescribeAutoScalingGroupsOutput, lastPage bool) bool {
 		scalingGroups = append(scalingGroups, page.AutoScalingGroups...)
 		return page.NextToken != nil
 	})
@@ -728,9 +913,236 @@ func (w *AwsWorker) DescribeAutoscalingGroups() ([]*autoscaling.Group, error) {
 	return scalingGroups, nil
 }
 
+// DescribeAutoscalingGroupsByTag returns the scaling groups which have a tag with the given key and value, the groups
+// are selected with a server side filter of the tagging API rather than by describing all groups in the account
+func (w *AwsWorker) DescribeAutoscalingGroupsByTag(key, value string) ([]*autoscaling.Group, error) {
+	var (
+		scalingGroups = []*autoscaling.Group{}
+		names         = make([]string, 0)
+	)
+
+	err := w.AsgClient.DescribeTagsPagesWithContext(w.context(), &autoscaling.DescribeTagsInput{
+		Filters: []*autoscaling.Filter{
+			{
+				Name:   aws.String("key"),
+				Values: aws.StringSlice([]string{key}),
+			},
+			{
+				Name:   aws.String("value"),
+				Values: aws.StringSlice([]string{value}),
+			},
+		},
+	}, func(page *autoscaling.DescribeTagsOutput, lastPage bool) bool {
+		for _, tag := range page.Tags {
+			if aws.StringValue(tag.ResourceType) == "auto-scaling-group" {
+				names = append(names, aws.StringValue(tag.ResourceId))
+			}
+		}
+		return page.NextToken != nil
+	})
+	if err != nil {
+		return scalingGroups, err
+	}
+
+	for start := 0; start < len(names); start += MaxScalingGroupNamesBatchSize {
+		end := start + MaxScalingGroupNamesBatchSize
+		if end > len(names) {
+			end = len(names)
+		}
+		err := w.AsgClient.DescribeAutoScalingGroupsPagesWithContext(w.context(), &autoscaling.DescribeAutoScalingGroupsInput{
+			AutoScalingGroupNames: aws.StringSlice(names[start:end]),
+		}, func(page *autoscaling.DescribeAutoScalingGroupsOutput, lastPage bool) bool {
+			scalingGroups = append(scalingGroups, page.AutoScalingGroups...)
+			return page.NextToken != nil
+		})
+		if err != nil {
+			return scalingGroups, err
+		}
+	}
+	return scalingGroups, nil
+}
+
+// GetScalingGroupInstanceIds returns the ids of the instances of a scaling group
+func (w *AwsWorker) GetScalingGroupInstanceIds(name string) ([]string, error) {
+	instanceIds := make([]string, 0)
+	out, err := w.AsgClient.DescribeAutoScalingGroupsWithContext(w.context(), &autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: aws.StringSlice([]string{name}),
+	})
+	if err != nil {
+		return instanceIds, err
+	}
+	for _, group := range out.AutoScalingGroups {
+		for _, instance := range group.Instances {
+			instanceIds = append(instanceIds, aws.StringValue(instance.InstanceId))
+		}
+	}
+	return instanceIds, nil
+}
+
+// GetLiveInstanceIds returns the ids of the instances which exist and are not shutting down or terminated
+func (w *AwsWorker) GetLiveInstanceIds(instanceIds []string) ([]string, error) {
+	ids := make([]string, 0)
+	if len(instanceIds) == 0 {
+		return ids, nil
+	}
+	err := w.Ec2Client.DescribeInstancesPagesWithContext(w.context(), &ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("instance-id"),
+				Values: aws.StringSlice(instanceIds),
+			},
+			{
+				Name: aws.String("instance-state-name"),
+				Values: aws.StringSlice([]string{
+					ec2.InstanceStateNamePending,
+					ec2.InstanceStateNameRunning,
+					ec2.InstanceStateNameStopping,
+					ec2.InstanceStateNameStopped,
+				}),
+			},
+		},
+	}, func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				ids = append(ids, aws.StringValue(instance.InstanceId))
+			}
+		}
+		return page.NextToken != nil
+	})
+	if err != nil {
+		return ids, err
+	}
+	return ids, nil
+}
+
+// GetInstanceLifecycles returns whether instances are spot or on-demand instances, keyed by instance id
+func (w *AwsWorker) GetInstanceLifecycles(instanceIds []string) (map[string]string, error) {
+	lifecycles := make(map[string]string)
+	if len(instanceIds) == 0 {
+		return lifecycles, nil
+	}
+	err := w.Ec2Client.DescribeInstancesPagesWithContext(w.context(), &ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("instance-id"),
+				Values: aws.StringSlice(instanceIds),
+			},
+		},
+	}, func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				lifecycle := aws.StringValue(instance.InstanceLifecycle)
+				if lifecycle == "" {
+					lifecycle = "on-demand"
+				}
+				lifecycles[aws.StringValue(instance.InstanceId)] = lifecycle
+			}
+		}
+		return page.NextToken != nil
+	})
+	if err != nil {
+		return lifecycles, err
+	}
+	return lifecycles, nil
+}
+
+// GetInstanceLaunchTimes returns the launch times of the pending and running instances, keyed by instance id
+func (w *AwsWorker) GetInstanceLaunchTimes(instanceIds []string) (map[string]time.Time, error) {
+	launchTimes := make(map[string]time.Time)
+	if len(instanceIds) == 0 {
+		return launchTimes, nil
+	}
+	err := w.Ec2Client.DescribeInstancesPagesWithContext(w.context(), &ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("instance-id"),
+				Values: aws.StringSlice(instanceIds),
+			},
+			{
+				Name:   aws.String("instance-state-name"),
+				Values: aws.StringSlice([]string{ec2.InstanceStateNamePending, ec2.InstanceStateNameRunning}),
+			},
+		},
+	}, func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				launchTimes[aws.StringValue(instance.InstanceId)] = aws.TimeValue(instance.LaunchTime)
+			}
+		}
+		return page.NextToken != nil
+	})
+	if err != nil {
+		return launchTimes, err
+	}
+	return launchTimes, nil
+}
+
+// GetConsoleOutputTail returns the last lines of the console output of an instance
+func (w *AwsWorker) GetConsoleOutputTail(instanceId string, lines int) (string, error) {
+	out, err := w.Ec2Client.GetConsoleOutputWithContext(w.context(), &ec2.GetConsoleOutputInput{
+		InstanceId: aws.String(instanceId),
+	})
+	if err != nil {
+		return "", err
+	}
+	output, err := base64.StdEncoding.DecodeString(aws.StringValue(out.Output))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to decode console output")
+	}
+	tail := strings.Split(strings.TrimRight(string(output), "\n"), "\n")
+	if len(tail) > lines {
+		tail = tail[len(tail)-lines:]
+	}
+	return strings.Join(tail, "\n"), nil
+}
+
+// GetInstancesAllowingIMDSv1 returns the ids of the pending and running instances whose instance metadata service does
+// not require session tokens
+func (w *AwsWorker) GetInstancesAllowingIMDSv1(instanceIds []string) ([]string, error) {
+	ids := make([]string, 0)
+	if len(instanceIds) == 0 {
+		return ids, nil
+	}
+	err := w.Ec2Client.DescribeInstancesPagesWithContext(w.context(), &ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("instance-id"),
+				Values: aws.StringSlice(instanceIds),
+			},
+			{
+				Name:   aws.String("instance-state-name"),
+				Values: aws.StringSlice([]string{ec2.InstanceStateNamePending, ec2.InstanceStateNameRunning}),
+			},
+		},
+	}, func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				if instance.MetadataOptions != nil && aws.StringValue(instance.MetadataOptions.HttpTokens) != ec2.HttpTokensStateRequired {
+					ids = append(ids, aws.StringValue(instance.InstanceId))
+				}
+			}
+		}
+		return page.NextToken != nil
+	})
+	if err != nil {
+		return ids, err
+	}
+	return ids, nil
+}
+
+// RequireIMDSv2 requires session tokens for the instance metadata service of an instance
+func (w *AwsWorker) RequireIMDSv2(instanceId string) error {
+	_, err := w.Ec2Client.ModifyInstanceMetadataOptionsWithContext(w.context(), &ec2.ModifyInstanceMetadataOptionsInput{
+		InstanceId:   aws.String(instanceId),
+		HttpTokens:   aws.String(ec2.HttpTokensStateRequired),
+		HttpEndpoint: aws.String(ec2.InstanceMetadataEndpointStateEnabled),
+	})
+	return err
+}
+
 func (w *AwsWorker) DescribeAutoscalingLaunchConfigs() ([]*autoscaling.LaunchConfiguration, error) {
 	launchConfigurations := []*autoscaling.LaunchConfiguration{}
-	err := w.AsgClient.DescribeLaunchConfigurationsPages(&autoscaling.DescribeLaunchConfigurationsInput{}, func(page *autoscaling.DescribeLaunchConfigurationsOutput, lastPage bool) bool {
+	err := w.AsgClient.DescribeLaunchConfigurationsPagesWithContext(w.context(), &autoscaling.DescribeLaunchConfigurationsInput{}, func(page *autoscaling.DescribeLaunchConfigurationsOutput, lastPage bool) bool {
 		launchConfigurations = append(launchConfigurations, page.LaunchConfigurations...)
 		return page.NextToken != nil
 	})
@@ -744,7 +1156,7 @@ func (w *AwsWorker) EnableMetrics(asgName string, metrics []string) error {
 	if common.SliceEmpty(metrics) {
 		return nil
 	}
-	_, err := w.AsgClient.EnableMetricsCollection(&autoscaling.EnableMetricsCollectionInput{
+	_, err := w.AsgClient.EnableMetricsCollectionWithContext(w.context(), &autoscaling.EnableMetricsCollectionInput{
 		AutoScalingGroupName: aws.String(asgName),
 		Granularity:          aws.String("1Minute"),
 		Metrics:              aws.StringSlice(metrics),
@@ -759,7 +1171,7 @@ func (w *AwsWorker) DisableMetrics(asgName string, metrics []string) error {
 	if common.SliceEmpty(metrics) {
 		return nil
 	}
-	_, err := w.AsgClient.DisableMetricsCollection(&autoscaling.DisableMetricsCollectionInput{
+	_, err := w.AsgClient.DisableMetricsCollectionWithContext(w.context(), &autoscaling.DisableMetricsCollectionInput{
 		AutoScalingGroupName: aws.String(asgName),
 		Metrics:              aws.StringSlice(metrics),
 	})
@@ -769,6 +1181,41 @@ func (w *AwsWorker) DisableMetrics(asgName string, metrics []string) error {
 	return nil
 }
 
+// DescribeNotificationConfigurations returns the SNS topics a scaling group sends notifications to
+func (w *AwsWorker) DescribeNotificationConfigurations(asgName string) ([]*autoscaling.NotificationConfiguration, error) {
+	configurations := []*autoscaling.NotificationConfiguration{}
+	err := w.AsgClient.DescribeNotificationConfigurationsPagesWithContext(w.context(), &autoscaling.DescribeNotificationConfigurationsInput{
+		AutoScalingGroupNames: aws.StringSlice([]string{asgName}),
+	}, func(page *autoscaling.DescribeNotificationConfigurationsOutput, lastPage bool) bool {
+		configurations = append(configurations, page.NotificationConfigurations...)
+		return page.NextToken != nil
+	})
+	if err != nil {
+		return configurations, err
+	}
+	return configurations, nil
+}
+
+// PutNotificationConfiguration sends the given types of notifications of a scaling group to an SNS topic, replacing
+// the types previously sent to the topic
+func (w *AwsWorker) PutNotificationConfiguration(asgName, topicArn string, types []string) error {
+	_, err := w.AsgClient.PutNotificationConfigurationWithContext(w.context(), &autoscaling.PutNotificationConfigurationInput{
+		AutoScalingGroupName: aws.String(asgName),
+		TopicARN:             aws.String(topicArn),
+		NotificationTypes:    aws.StringSlice(types),
+	})
+	return err
+}
+
+// DeleteNotificationConfiguration stops sending notifications of a scaling group to an SNS topic
+func (w *AwsWorker) DeleteNotificationConfiguration(asgName, topicArn string) error {
+	_, err := w.AsgClient.DeleteNotificationConfigurationWithContext(w.context(), &autoscaling.DeleteNotificationConfigurationInput{
+		AutoScalingGroupName: aws.String(asgName),
+		TopicARN:             aws.String(topicArn),
+	})
+	return err
+}
+
 func GetScalingGroupTagsByName(name string, client autoscalingiface.AutoScalingAPI) ([]*autoscaling.TagDescription, error) {
 	tags := []*autoscaling.TagDescription{}
 	input := &autoscaling.DescribeAutoScalingGroupsInput{}
@@ -785,6 +1232,24 @@ func GetScalingGroupTagsByName(name string, client autoscalingiface.AutoScalingA
 	return tags, nil
 }
 
+// GetScalingGroupTagsByInstanceID returns the tags of the scaling group which an instance belongs to
+func GetScalingGroupTagsByInstanceID(instanceID string, client autoscalingiface.AutoScalingAPI) ([]*autoscaling.TagDescription, error) {
+	tags := []*autoscaling.TagDescription{}
+	input := &autoscaling.DescribeAutoScalingGroupsInput{}
+	out, err := client.DescribeAutoScalingGroups(input)
+	if err != nil {
+		return tags, err
+	}
+	for _, asg := range out.AutoScalingGroups {
+		for _, instance := range asg.Instances {
+			if strings.EqualFold(instanceID, aws.StringValue(instance.InstanceId)) {
+				return asg.Tags, nil
+			}
+		}
+	}
+	return tags, nil
+}
+
 func GetTagValueByKey(tags []*autoscaling.TagDescription, key string) string {
 	for _, tag := range tags {
 		k := aws.StringValue(tag.Key)
@@ -796,6 +1261,91 @@ func GetTagValueByKey(tags []*autoscaling.TagDescription, key string) string {
 	return ""
 }
 
+// ClientConfig configures the AWS clients of a worker, clients of the same configuration share its circuit breaker
+type ClientConfig struct {
+	Region     string
+	MaxRetries int
+	RateLimits RateLimits
+	Breaker    *CircuitBreaker
+	// Credentials are the credentials of the clients, the default credential chain is used when nil
+	Credentials *credentials.Credentials
+	// Endpoints overrides the endpoints of services, keyed by the service's endpoint ID, e.g. ec2 or autoscaling
+	Endpoints map[string]string
+	// EndpointURL is the endpoint of all services which do not have an endpoint in Endpoints
+	EndpointURL string
+	// Partition is the partition ARNs are constructed in, it is derived from the region when empty
+	Partition string
+	// UseFIPSEndpoints resolves the FIPS endpoints of services which do not have an endpoint override
+	UseFIPSEndpoints bool
+	// UseDualStackEndpoints resolves the dual-stack (IPv4 and IPv6) endpoints of services which do not have an
+	// endpoint override
+	UseDualStackEndpoints bool
+	// STSRegionalEndpoints calls STS at the endpoint of the region instead of the global endpoint
+	STSRegionalEndpoints bool
+}
+
+// GetEndpoint returns the endpoint a service's client is configured with, or an empty string for the default
+// endpoint of the region
+func (c ClientConfig) GetEndpoint(service string) string {
+	if endpoint, ok := c.Endpoints[service]; ok {
+		return endpoint
+	}
+	return c.EndpointURL
+}
+
+// GetPartition returns the partition of the clients' region, unless it is overridden
+func (c ClientConfig) GetPartition() string {
+	if c.Partition != "" {
+		return c.Partition
+	}
+	return PartitionForRegion(c.Region)
+}
+
+func (c ClientConfig) awsConfig(service string) *aws.Config {
+	config := aws.NewConfig().WithRegion(c.Region).WithCredentialsChainVerboseErrors(true)
+	if c.Credentials != nil {
+		config = config.WithCredentials(c.Credentials)
+	}
+	if endpoint := c.GetEndpoint(service); endpoint != "" {
+		config = config.WithEndpoint(endpoint)
+		// custom S3 endpoints such as localstack do not serve virtual hosted buckets
+		if service == s3.EndpointsID {
+			config = config.WithS3ForcePathStyle(true)
+		}
+	} else if c.UseFIPSEndpoints || c.UseDualStackEndpoints {
+		config = config.WithEndpointResolver(endpointVariantResolver{
+			fips:      c.UseFIPSEndpoints,
+			dualStack: c.UseDualStackEndpoints,
+		})
+	}
+	if c.STSRegionalEndpoints && service == sts.EndpointsID {
+		config = config.WithSTSRegionalEndpoint(endpoints.RegionalSTSEndpoint)
+	}
+	return request.WithRetryer(config, NewRetryLogger(c.MaxRetries))
+}
+
+// NewWorker returns a worker with the clients of all services used by reconciles, the responses of cached clients
+// are cached in cacheCfg
+func (c ClientConfig) NewWorker(cacheCfg *cache.Config) AwsWorker {
+	return AwsWorker{
+		Ec2Client: c.GetAwsEc2Client(cacheCfg),
+		IamClient: c.GetAwsIamClient(cacheCfg),
+		AsgClient: c.GetAwsAsgClient(cacheCfg),
+		EksClient: c.GetAwsEksClient(cacheCfg),
+		S3Client:  c.GetAwsS3Client(),
+		SsmClient: c.GetAwsSsmClient(cacheCfg),
+		Partition: c.GetPartition(),
+
+		OutpostsClient:     c.GetAwsOutpostsClient(cacheCfg),
+		ImageBuilderClient: c.GetAwsImageBuilderClient(),
+		CloudWatchClient:   c.GetAwsCloudWatchClient(),
+		PricingClient:      c.GetAwsPricingClient(cacheCfg),
+		SavingsPlansClient: c.GetAwsSavingsPlansClient(cacheCfg),
+		Region:             c.Region,
+		ResponseCache:      cacheCfg,
+	}
+}
+
 func GetRegion() (string, error) {
 	if os.Getenv("AWS_REGION") != "" {
 		return os.Getenv("AWS_REGION"), nil
@@ -815,18 +1365,20 @@ func GetRegion() (string, error) {
 }
 
 // GetAwsAsgClient returns an ASG client
-func GetAwsAsgClient(region string, cacheCfg *cache.Config, maxRetries int) autoscalingiface.AutoScalingAPI {
-	config := aws.NewConfig().WithRegion(region).WithCredentialsChainVerboseErrors(true)
-	config = request.WithRetryer(config, NewRetryLogger(maxRetries))
+func (c ClientConfig) GetAwsAsgClient(cacheCfg *cache.Config) autoscalingiface.AutoScalingAPI {
+	config := c.awsConfig(autoscaling.EndpointsID)
 	sess, err := session.NewSession(config)
 	if err != nil {
 		panic(err)
 	}
 
 	cache.AddCaching(sess, cacheCfg)
+	NewRateLimiter(c.RateLimits).AddRateLimiting(sess)
+	c.Breaker.AddCircuitBreaking(sess)
 	cacheCfg.SetCacheTTL("autoscaling", "DescribeAutoScalingGroups", DescribeAutoScalingGroupsTTL)
 	cacheCfg.SetCacheTTL("autoscaling", "DescribeLaunchConfigurations", DescribeLaunchConfigurationsTTL)
 	cacheCfg.SetCacheTTL("autoscaling", "DescribeLifecycleHooks", DescribeLifecycleHooksTTL)
+	cacheCfg.SetCacheTTL("autoscaling", "DescribeTags", DescribeTagsTTL)
 	sess.Handlers.Complete.PushFront(func(r *request.Request) {
 		ctx := r.HTTPRequest.Context()
 		log.V(1).Info("AWS API call",
@@ -839,17 +1391,21 @@ func GetAwsAsgClient(region string, cacheCfg *cache.Config, maxRetries int) auto
 }
 
 // GetAwsEc2Client returns an EC2 client
-func GetAwsEc2Client(region string, cacheCfg *cache.Config, maxRetries int) ec2iface.EC2API {
-	config := aws.NewConfig().WithRegion(region).WithCredentialsChainVerboseErrors(true)
-	config = request.WithRetryer(config, NewRetryLogger(maxRetries))
+func (c ClientConfig) GetAwsEc2Client(cacheCfg *cache.Config) ec2iface.EC2API {
+	config := c.awsConfig(ec2.EndpointsID)
 	sess, err := session.NewSession(config)
 	if err != nil {
 		panic(err)
 	}
 
 	cache.AddCaching(sess, cacheCfg)
+	NewRateLimiter(c.RateLimits).AddRateLimiting(sess)
+	c.Breaker.AddCircuitBreaking(sess)
 	cacheCfg.SetCacheTTL("ec2", "DescribeSecurityGroups", DescribeSecurityGroupsTTL)
 	cacheCfg.SetCacheTTL("ec2", "DescribeSubnets", DescribeSubnetsTTL)
+	cacheCfg.SetCacheTTL("ec2", "DescribeAvailabilityZones", DescribeAvailabilityZonesTTL)
+	cacheCfg.SetCacheTTL("ec2", "DescribeInstanceTypeOfferings", InstanceTypeOfferingsTTL)
+	cacheCfg.SetCacheTTL("ec2", "DescribeReservedInstances", DescribeReservedInstancesTTL)
 	sess.Handlers.Complete.PushFront(func(r *request.Request) {
 		ctx := r.HTTPRequest.Context()
 		log.V(1).Info("AWS API call",
@@ -862,15 +1418,15 @@ func GetAwsEc2Client(region string, cacheCfg *cache.Config, maxRetries int) ec2i
 }
 
 // GetAwsEksClient returns an EKS client
-func GetAwsEksClient(region string, cacheCfg *cache.Config, maxRetries int) eksiface.EKSAPI {
-	config := aws.NewConfig().WithRegion(region).WithCredentialsChainVerboseErrors(true)
-	config = request.WithRetryer(config, NewRetryLogger(maxRetries))
+func (c ClientConfig) GetAwsEksClient(cacheCfg *cache.Config) eksiface.EKSAPI {
+	config := c.awsConfig(eks.EndpointsID)
 	sess, err := session.NewSession(config)
 	if err != nil {
 		panic(err)
 	}
 	cache.AddCaching(sess, cacheCfg)
-	cacheCfg.SetCacheTTL("eks", "DescribeCluster", DescribeClusterTTL)
+	NewRateLimiter(c.RateLimits).AddRateLimiting(sess)
+	c.Breaker.AddCircuitBreaking(sess)
 	cacheCfg.SetCacheTTL("eks", "DescribeNodegroup", DescribeNodegroupTTL)
 	sess.Handlers.Complete.PushFront(func(r *request.Request) {
 		ctx := r.HTTPRequest.Context()
@@ -883,12 +1439,129 @@ func GetAwsEksClient(region string, cacheCfg *cache.Config, maxRetries int) eksi
 	return eks.New(sess, config)
 }
 
+// GetAwsSsmClient returns an SSM client
+func (c ClientConfig) GetAwsSsmClient(cacheCfg *cache.Config) ssmiface.SSMAPI {
+	config := c.awsConfig(ssm.EndpointsID)
+	sess, err := session.NewSession(config)
+	if err != nil {
+		panic(err)
+	}
+	cache.AddCaching(sess, cacheCfg)
+	NewRateLimiter(c.RateLimits).AddRateLimiting(sess)
+	c.Breaker.AddCircuitBreaking(sess)
+	cacheCfg.SetCacheTTL("ssm", "GetParameter", GetParameterTTL)
+	sess.Handlers.Complete.PushFront(func(r *request.Request) {
+		ctx := r.HTTPRequest.Context()
+		log.V(1).Info("AWS API call",
+			"cacheHit", cache.IsCacheHit(ctx),
+			"service", r.ClientInfo.ServiceName,
+			"operation", r.Operation.Name,
+		)
+	})
+	return ssm.New(sess, config)
+}
+
+// GetParameter returns the value of an SSM parameter
+func (w *AwsWorker) GetParameter(name string) (string, error) {
+	out, err := w.SsmClient.GetParameterWithContext(w.context(), &ssm.GetParameterInput{
+		Name: aws.String(name),
+	})
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(out.Parameter.Value), nil
+}
+
+// GetAwsS3Client returns an S3 client
+func (c ClientConfig) GetAwsS3Client() s3iface.S3API {
+	config := c.awsConfig(s3.EndpointsID)
+	sess, err := session.NewSession(config)
+	if err != nil {
+		panic(err)
+	}
+	NewRateLimiter(c.RateLimits).AddRateLimiting(sess)
+	c.Breaker.AddCircuitBreaking(sess)
+	sess.Handlers.Complete.PushFront(func(r *request.Request) {
+		log.V(1).Info("AWS API call",
+			"service", r.ClientInfo.ServiceName,
+			"operation", r.Operation.Name,
+		)
+	})
+	return s3.New(sess, config)
+}
+
+// GetAwsSqsClient returns an SQS client
+func (c ClientConfig) GetAwsSqsClient() sqsiface.SQSAPI {
+	config := c.awsConfig(sqs.EndpointsID)
+	sess, err := session.NewSession(config)
+	if err != nil {
+		panic(err)
+	}
+	NewRateLimiter(c.RateLimits).AddRateLimiting(sess)
+	c.Breaker.AddCircuitBreaking(sess)
+	sess.Handlers.Complete.PushFront(func(r *request.Request) {
+		log.V(1).Info("AWS API call",
+			"service", r.ClientInfo.ServiceName,
+			"operation", r.Operation.Name,
+		)
+	})
+	return sqs.New(sess, config)
+}
+
+// ReceiveMessages long polls a queue for up to waitSeconds and returns the received messages
+func (w *AwsWorker) ReceiveMessages(queueURL string, waitSeconds int64) ([]*sqs.Message, error) {
+	out, err := w.SqsClient.ReceiveMessageWithContext(w.context(), &sqs.ReceiveMessageInput{
+		QueueUrl:            aws.String(queueURL),
+		MaxNumberOfMessages: aws.Int64(10),
+		WaitTimeSeconds:     aws.Int64(waitSeconds),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.Messages, nil
+}
+
+// DeleteMessage removes a processed message from a queue
+func (w *AwsWorker) DeleteMessage(queueURL, receiptHandle string) error {
+	_, err := w.SqsClient.DeleteMessageWithContext(w.context(), &sqs.DeleteMessageInput{
+		QueueUrl:      aws.String(queueURL),
+		ReceiptHandle: aws.String(receiptHandle),
+	})
+	return err
+}
+
+// ObjectExists returns true if an object with the given key exists in the bucket
+func (w *AwsWorker) ObjectExists(bucket, key string) (bool, error) {
+	_, err := w.S3Client.HeadObjectWithContext(w.context(), &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.RequestFailure); ok && aerr.StatusCode() == 404 {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// PutObject uploads an object to the bucket, encrypted at rest
+func (w *AwsWorker) PutObject(bucket, key string, body []byte) error {
+	_, err := w.S3Client.PutObjectWithContext(w.context(), &s3.PutObjectInput{
+		Bucket:               aws.String(bucket),
+		Key:                  aws.String(key),
+		Body:                 bytes.NewReader(body),
+		ServerSideEncryption: aws.String(s3.ServerSideEncryptionAes256),
+	})
+	return err
+}
+
 func (w *AwsWorker) DeriveEksVpcID(clusterName string) (string, error) {
-	out, err := w.EksClient.DescribeCluster(&eks.DescribeClusterInput{Name: aws.String(clusterName)})
+	cluster, err := w.DescribeEKSCluster(clusterName)
 	if err != nil {
 		return "", err
 	}
-	return aws.StringValue(out.Cluster.ResourcesVpcConfig.VpcId), nil
+	return aws.StringValue(cluster.ResourcesVpcConfig.VpcId), nil
 }
 
 type CloudResourceReconcileState struct {
@@ -908,14 +1581,15 @@ var UnrecoverableError = CloudResourceReconcileState{UnrecoverableError: true}
 var UnrecoverableDeleteError = CloudResourceReconcileState{UnrecoverableDeleteError: true}
 
 // GetAwsIAMClient returns an IAM client
-func GetAwsIamClient(region string, cacheCfg *cache.Config, maxRetries int) iamiface.IAMAPI {
-	config := aws.NewConfig().WithRegion(region).WithCredentialsChainVerboseErrors(true)
-	config = request.WithRetryer(config, NewRetryLogger(maxRetries))
+func (c ClientConfig) GetAwsIamClient(cacheCfg *cache.Config) iamiface.IAMAPI {
+	config := c.awsConfig(iam.EndpointsID)
 	sess, err := session.NewSession(config)
 	if err != nil {
 		panic(err)
 	}
 	cache.AddCaching(sess, cacheCfg)
+	NewRateLimiter(c.RateLimits).AddRateLimiting(sess)
+	c.Breaker.AddCircuitBreaking(sess)
 	cacheCfg.SetCacheTTL("iam", "GetInstanceProfile", GetInstanceProfileTTL)
 	cacheCfg.SetCacheTTL("iam", "GetRole", GetRoleTTL)
 	cacheCfg.SetCacheTTL("iam", "ListAttachedRolePolicies", ListAttachedRolePoliciesTTL)
@@ -997,15 +1671,17 @@ func IsProfileInConditionState(key string, condition string) bool {
 	}
 }
 
-const defaultPolicyArn = "arn:aws:iam::aws:policy/AmazonEKSFargatePodExecutionRolePolicy"
+// FargatePodExecutionRolePolicyName is the managed policy which is attached to the pod execution roles created by the
+// eks-fargate provisioner
+const FargatePodExecutionRolePolicyName = "AmazonEKSFargatePodExecutionRolePolicy"
 
 func (w *AwsWorker) DetachDefaultPolicyFromDefaultRole() error {
 	var roleName = w.Parameters["DefaultRoleName"].(string)
 	rolePolicy := &iam.DetachRolePolicyInput{
-		PolicyArn: aws.String(defaultPolicyArn),
+		PolicyArn: aws.String(ManagedPolicyArn(w.GetPartition(), FargatePodExecutionRolePolicyName)),
 		RoleName:  aws.String(roleName),
 	}
-	_, err := w.IamClient.DetachRolePolicy(rolePolicy)
+	_, err := w.IamClient.DetachRolePolicyWithContext(w.context(), rolePolicy)
 	return err
 }
 
@@ -1014,7 +1690,7 @@ func (w *AwsWorker) DeleteDefaultFargateRole() error {
 	role := &iam.DeleteRoleInput{
 		RoleName: aws.String(roleName),
 	}
-	_, err := w.IamClient.DeleteRole(role)
+	_, err := w.IamClient.DeleteRoleWithContext(w.context(), role)
 	return err
 }
 
@@ -1026,7 +1702,7 @@ func (w *AwsWorker) GetRole(roleName string) (*iam.Role, error) {
 	role := &iam.GetRoleInput{
 		RoleName: aws.String(roleName),
 	}
-	resp, err := w.IamClient.GetRole(role)
+	resp, err := w.IamClient.GetRoleWithContext(w.context(), role)
 	if err != nil {
 		return nil, err
 	}
@@ -1041,17 +1717,36 @@ func (w *AwsWorker) CreateDefaultFargateRole() error {
 		Path:                     aws.String("/"),
 		RoleName:                 aws.String(roleName),
 	}
-	_, err := w.IamClient.CreateRole(role)
+	if boundary, ok := w.Parameters["PermissionsBoundary"].(string); ok && boundary != "" {
+		role.PermissionsBoundary = aws.String(boundary)
+	}
+	_, err := w.IamClient.CreateRoleWithContext(w.context(), role)
+	return err
+}
+
+// UpdateRolePermissionsBoundary sets the permissions boundary of a role, or removes it when the boundary is empty
+func (w *AwsWorker) UpdateRolePermissionsBoundary(name, boundary string) error {
+	if boundary == "" {
+		_, err := w.IamClient.DeleteRolePermissionsBoundaryWithContext(w.context(), &iam.DeleteRolePermissionsBoundaryInput{
+			RoleName: aws.String(name),
+		})
+		return err
+	}
+
+	_, err := w.IamClient.PutRolePermissionsBoundaryWithContext(w.context(), &iam.PutRolePermissionsBoundaryInput{
+		RoleName:            aws.String(name),
+		PermissionsBoundary: aws.String(boundary),
+	})
 	return err
 }
 
 func (w *AwsWorker) AttachDefaultPolicyToDefaultRole() error {
 	var roleName = w.Parameters["DefaultRoleName"].(string)
 	rolePolicy := &iam.AttachRolePolicyInput{
-		PolicyArn: aws.String(defaultPolicyArn),
+		PolicyArn: aws.String(ManagedPolicyArn(w.GetPartition(), FargatePodExecutionRolePolicyName)),
 		RoleName:  aws.String(roleName),
 	}
-	_, err := w.IamClient.AttachRolePolicy(rolePolicy)
+	_, err := w.IamClient.AttachRolePolicyWithContext(w.context(), rolePolicy)
 	if err == nil {
 		time.Sleep(DefaultInstanceProfilePropagationDelay)
 	}
@@ -1077,7 +1772,7 @@ func (w *AwsWorker) CreateFargateProfile(arn string) error {
 		Tags:                tags,
 	}
 
-	_, err := w.EksClient.CreateFargateProfile(fargateInput)
+	_, err := w.EksClient.CreateFargateProfileWithContext(w.context(), fargateInput)
 	return err
 }
 
@@ -1086,16 +1781,41 @@ func (w *AwsWorker) DeleteFargateProfile() error {
 		ClusterName:        aws.String(w.Parameters["ClusterName"].(string)),
 		FargateProfileName: aws.String(w.Parameters["ProfileName"].(string)),
 	}
-	_, err := w.EksClient.DeleteFargateProfile(deleteInput)
+	_, err := w.EksClient.DeleteFargateProfileWithContext(w.context(), deleteInput)
 	return err
 }
 
+// UpdateFargateProfileTags adds and removes tags of a fargate profile, the tags are the only attribute of a profile
+// which can be changed without recreating it
+func (w *AwsWorker) UpdateFargateProfileTags(arn string, add map[string]*string, remove []string) error {
+	if len(add) > 0 {
+		_, err := w.EksClient.TagResourceWithContext(w.context(), &eks.TagResourceInput{
+			ResourceArn: aws.String(arn),
+			Tags:        add,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(remove) > 0 {
+		_, err := w.EksClient.UntagResourceWithContext(w.context(), &eks.UntagResourceInput{
+			ResourceArn: aws.String(arn),
+			TagKeys:     aws.StringSlice(remove),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (w *AwsWorker) DescribeFargateProfile() (*eks.FargateProfile, error) {
 	describeInput := &eks.DescribeFargateProfileInput{
 		ClusterName:        aws.String(w.Parameters["ClusterName"].(string)),
 		FargateProfileName: aws.String(w.Parameters["ProfileName"].(string)),
 	}
-	output, err := w.EksClient.DescribeFargateProfile(describeInput)
+	output, err := w.EksClient.DescribeFargateProfileWithContext(w.context(), describeInput)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,197 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/keikoproj/aws-sdk-go-cache/cache"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	// CircuitOpenErrorCode is the error code of calls which fail fast while the circuit of their API is open
+	CircuitOpenErrorCode = "CircuitOpen"
+)
+
+var (
+	DefaultCircuitBreakerConfig = CircuitBreakerConfig{
+		FailureThreshold: 10,
+		Cooldown:         time.Minute * 2,
+	}
+
+	// CircuitOpenMetric is 1 while the circuit of an AWS API is open
+	CircuitOpenMetric = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "instance_manager_aws_circuit_open",
+		Help: "Whether calls to an AWS API are failing fast after repeated failures",
+	}, []string{"service", "operation"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(CircuitOpenMetric)
+}
+
+// CircuitBreakerConfig configures when the circuit of an AWS API opens and for how long
+type CircuitBreakerConfig struct {
+	FailureThreshold int
+	Cooldown         time.Duration
+}
+
+// CircuitBreaker tracks consecutive failures of AWS API calls across all services and instance groups, once an
+// API fails repeatedly its circuit is opened and calls to it fail fast until the cooldown has passed
+type CircuitBreaker struct {
+	sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	circuits  map[string]*circuit
+}
+
+type circuit struct {
+	service   string
+	operation string
+	failures  int
+	openUntil time.Time
+}
+
+// NewCircuitBreaker returns a circuit breaker for the given configuration, a non-positive threshold disables it
+func NewCircuitBreaker(config CircuitBreakerConfig) *CircuitBreaker {
+	if config.FailureThreshold <= 0 {
+		return nil
+	}
+	return &CircuitBreaker{
+		threshold: config.FailureThreshold,
+		cooldown:  config.Cooldown,
+		circuits:  make(map[string]*circuit),
+	}
+}
+
+// IsCircuitOpenError returns true if an error was returned by a call which failed fast on an open circuit
+func IsCircuitOpenError(err error) bool {
+	return ClassifyError(err) == ErrorClassCircuitOpen
+}
+
+// IsDependencyFailure returns true if a completed call failed in a way that indicates the API itself is
+// unavailable, rather than a problem with the request, such as throttling, server or connection errors
+func IsDependencyFailure(r *request.Request) bool {
+	if r.Error == nil {
+		return false
+	}
+	if aerr, ok := r.Error.(awserr.Error); ok {
+		switch aerr.Code() {
+		case CircuitOpenErrorCode, request.CanceledErrorCode:
+			return false
+		}
+	}
+	if r.IsErrorThrottle() || r.IsErrorRetryable() {
+		return true
+	}
+	return r.HTTPResponse != nil && r.HTTPResponse.StatusCode >= 500
+}
+
+func (b *CircuitBreaker) circuitFor(r *request.Request) *circuit {
+	var (
+		service   = r.ClientInfo.ServiceName
+		operation string
+	)
+	if r.Operation != nil {
+		operation = r.Operation.Name
+	}
+
+	key := fmt.Sprintf("%v/%v", service, operation)
+	c, ok := b.circuits[key]
+	if !ok {
+		c = &circuit{service: service, operation: operation}
+		b.circuits[key] = c
+	}
+	return c
+}
+
+// Allow returns an error if the circuit of the request's API is open, once the cooldown has passed calls are
+// allowed again and a single further failure reopens the circuit
+func (b *CircuitBreaker) Allow(r *request.Request) error {
+	b.Lock()
+	defer b.Unlock()
+
+	c := b.circuitFor(r)
+	if remaining := time.Until(c.openUntil); remaining > 0 {
+		return awserr.New(CircuitOpenErrorCode,
+			fmt.Sprintf("%v %v failed %d consecutive times, failing fast for %v",
+				c.service, c.operation, c.failures, remaining.Round(time.Second)), nil)
+	}
+	return nil
+}
+
+// Record updates the circuit of the request's API with the outcome of a completed call
+func (b *CircuitBreaker) Record(r *request.Request) {
+	b.Lock()
+	defer b.Unlock()
+
+	c := b.circuitFor(r)
+	if !IsDependencyFailure(r) {
+		if aerr, ok := r.Error.(awserr.Error); ok && aerr.Code() == CircuitOpenErrorCode {
+			return
+		}
+		if c.failures >= b.threshold {
+			log.Info("closing AWS API circuit", "service", c.service, "operation", c.operation)
+			CircuitOpenMetric.WithLabelValues(c.service, c.operation).Set(0)
+		}
+		c.failures = 0
+		return
+	}
+
+	c.failures++
+	if c.failures >= b.threshold {
+		log.Info("opening AWS API circuit after repeated failures",
+			"service", c.service,
+			"operation", c.operation,
+			"failures", c.failures,
+			"cooldown", b.cooldown,
+			"error", r.Error,
+		)
+		c.openUntil = time.Now().Add(b.cooldown)
+		CircuitOpenMetric.WithLabelValues(c.service, c.operation).Set(1)
+	}
+}
+
+// AddCircuitBreaking adds handlers to a session which fail calls fast while the circuit of their API is open,
+// and record the outcome of every completed call, responses served from cache are always allowed
+func (b *CircuitBreaker) AddCircuitBreaking(sess *session.Session) {
+	if b == nil {
+		return
+	}
+
+	sess.Handlers.Sign.PushFront(func(r *request.Request) {
+		if cache.IsCacheHit(r.HTTPRequest.Context()) {
+			return
+		}
+		if err := b.Allow(r); err != nil {
+			r.Error = err
+		}
+	})
+
+	sess.Handlers.Complete.PushBack(func(r *request.Request) {
+		if cache.IsCacheHit(r.HTTPRequest.Context()) {
+			return
+		}
+		b.Record(r)
+	})
+}
@@ -0,0 +1,185 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/keikoproj/aws-sdk-go-cache/cache"
+	"github.com/onsi/gomega"
+)
+
+type mockOutcome struct {
+	operation  string
+	err        error
+	statusCode int
+}
+
+var (
+	serverError   = mockOutcome{operation: "DescribeAutoScalingGroups", err: awserr.New("InternalFailure", "internal failure", nil), statusCode: 500}
+	throttleError = mockOutcome{operation: "DescribeAutoScalingGroups", err: awserr.New("Throttling", "rate exceeded", nil), statusCode: 400}
+	clientError   = mockOutcome{operation: "DescribeAutoScalingGroups", err: awserr.New("ValidationError", "invalid", nil), statusCode: 400}
+	canceledError = mockOutcome{operation: "DescribeAutoScalingGroups", err: awserr.New(request.CanceledErrorCode, "canceled", nil)}
+	circuitOpen   = mockOutcome{operation: "DescribeAutoScalingGroups", err: awserr.New(CircuitOpenErrorCode, "failing fast", nil)}
+	success       = mockOutcome{operation: "DescribeAutoScalingGroups", statusCode: 200}
+)
+
+func mockCircuitRequest(outcome mockOutcome) *request.Request {
+	r := &request.Request{
+		ClientInfo: metadata.ClientInfo{ServiceName: "autoscaling"},
+		Operation:  &request.Operation{Name: outcome.operation},
+		Error:      outcome.err,
+	}
+	if outcome.statusCode != 0 {
+		r.HTTPResponse = &http.Response{StatusCode: outcome.statusCode}
+	}
+	return r
+}
+
+func TestCircuitBreakerAllowRecord(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	tests := []struct {
+		name      string
+		outcomes  []mockOutcome
+		operation string
+		wantOpen  bool
+	}{
+		{name: "no calls", wantOpen: false},
+		{name: "failures below threshold", outcomes: []mockOutcome{serverError, serverError}, wantOpen: false},
+		{name: "failures reach threshold", outcomes: []mockOutcome{serverError, serverError, serverError}, wantOpen: true},
+		{name: "throttling counts as failure", outcomes: []mockOutcome{throttleError, throttleError, throttleError}, wantOpen: true},
+		{name: "success resets failures", outcomes: []mockOutcome{serverError, serverError, success, serverError, serverError}, wantOpen: false},
+		{name: "client errors reset failures", outcomes: []mockOutcome{serverError, serverError, clientError, serverError}, wantOpen: false},
+		{name: "canceled calls reset failures", outcomes: []mockOutcome{serverError, serverError, canceledError, serverError}, wantOpen: false},
+		{name: "circuit open responses are not recorded", outcomes: []mockOutcome{serverError, serverError, circuitOpen, serverError}, wantOpen: true},
+		{name: "circuits are per operation", outcomes: []mockOutcome{serverError, serverError, serverError}, operation: "DescribeLaunchConfigurations", wantOpen: false},
+	}
+
+	for _, tc := range tests {
+		breaker := NewCircuitBreaker(CircuitBreakerConfig{FailureThreshold: 3, Cooldown: time.Minute})
+		for _, outcome := range tc.outcomes {
+			breaker.Record(mockCircuitRequest(outcome))
+		}
+		operation := tc.operation
+		if operation == "" {
+			operation = "DescribeAutoScalingGroups"
+		}
+		err := breaker.Allow(mockCircuitRequest(mockOutcome{operation: operation}))
+		g.Expect(IsCircuitOpenError(err)).To(gomega.Equal(tc.wantOpen), tc.name)
+		g.Expect(err != nil).To(gomega.Equal(tc.wantOpen), tc.name)
+	}
+
+	// a non-positive threshold disables the circuit breaker
+	g.Expect(NewCircuitBreaker(CircuitBreakerConfig{})).To(gomega.BeNil())
+}
+
+func TestCircuitBreakerCooldown(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		breaker = NewCircuitBreaker(CircuitBreakerConfig{FailureThreshold: 3, Cooldown: time.Minute})
+		r       = mockCircuitRequest(mockOutcome{operation: "DescribeAutoScalingGroups"})
+	)
+
+	expireCooldown := func() {
+		breaker.circuitFor(r).openUntil = time.Now().Add(-time.Second)
+	}
+
+	for i := 0; i < 3; i++ {
+		breaker.Record(mockCircuitRequest(serverError))
+	}
+	g.Expect(breaker.Allow(r)).NotTo(gomega.Succeed())
+
+	// calls are allowed once the cooldown expired, a single failure reopens the circuit
+	expireCooldown()
+	g.Expect(breaker.Allow(r)).To(gomega.Succeed())
+	breaker.Record(mockCircuitRequest(serverError))
+	g.Expect(breaker.Allow(r)).NotTo(gomega.Succeed())
+
+	// a success after the cooldown closes the circuit, it reopens after the threshold again
+	expireCooldown()
+	breaker.Record(mockCircuitRequest(success))
+	breaker.Record(mockCircuitRequest(serverError))
+	g.Expect(breaker.Allow(r)).To(gomega.Succeed())
+	breaker.Record(mockCircuitRequest(serverError))
+	breaker.Record(mockCircuitRequest(serverError))
+	g.Expect(breaker.Allow(r)).NotTo(gomega.Succeed())
+}
+
+func TestCircuitBreakerCachedResponses(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		failing bool
+		calls   int
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		if failing {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`<ErrorResponse><Error><Code>InternalFailure</Code><Message>internal failure</Message></Error><RequestId>1</RequestId></ErrorResponse>`))
+			return
+		}
+		w.Write([]byte(`<DescribeAutoScalingGroupsResponse><DescribeAutoScalingGroupsResult><AutoScalingGroups/></DescribeAutoScalingGroupsResult><ResponseMetadata><RequestId>1</RequestId></ResponseMetadata></DescribeAutoScalingGroupsResponse>`))
+	}))
+	defer server.Close()
+
+	sess, err := session.NewSession(&aws.Config{
+		Endpoint:    aws.String(server.URL),
+		Region:      aws.String("us-west-2"),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+		MaxRetries:  aws.Int(0),
+	})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	cacheCfg := cache.NewConfig(time.Minute, 1<<10, 64)
+	cache.AddCaching(sess, cacheCfg)
+	breaker := NewCircuitBreaker(CircuitBreakerConfig{FailureThreshold: 2, Cooldown: time.Minute})
+	breaker.AddCircuitBreaking(sess)
+	client := autoscaling.New(sess)
+
+	cached := &autoscaling.DescribeAutoScalingGroupsInput{AutoScalingGroupNames: aws.StringSlice([]string{"asg-1"})}
+	uncached := &autoscaling.DescribeAutoScalingGroupsInput{AutoScalingGroupNames: aws.StringSlice([]string{"asg-2"})}
+
+	_, err = client.DescribeAutoScalingGroups(cached)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	// the circuit opens after repeated failures, calls fail fast without reaching the API
+	failing = true
+	for i := 0; i < 2; i++ {
+		_, err = client.DescribeAutoScalingGroups(uncached)
+		g.Expect(err).To(gomega.HaveOccurred())
+	}
+	_, err = client.DescribeAutoScalingGroups(uncached)
+	g.Expect(IsCircuitOpenError(err)).To(gomega.BeTrue())
+	g.Expect(calls).To(gomega.Equal(3))
+
+	// cached responses are served while the circuit is open, and do not close it
+	_, err = client.DescribeAutoScalingGroups(cached)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	_, err = client.DescribeAutoScalingGroups(uncached)
+	g.Expect(IsCircuitOpenError(err)).To(gomega.BeTrue())
+	g.Expect(calls).To(gomega.Equal(3))
+}
@@ -0,0 +1,98 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
+)
+
+const (
+	// AutoScalingMetricNamespace is the namespace of the group metrics of scaling groups
+	AutoScalingMetricNamespace = "AWS/AutoScaling"
+	// AlarmPeriod is the period in seconds of alarms on group metrics, which are collected at 1 minute granularity
+	AlarmPeriod = 60
+)
+
+var (
+	AllowedAlarmComparisonOperators = []string{
+		cloudwatch.ComparisonOperatorGreaterThanOrEqualToThreshold,
+		cloudwatch.ComparisonOperatorGreaterThanThreshold,
+		cloudwatch.ComparisonOperatorLessThanThreshold,
+		cloudwatch.ComparisonOperatorLessThanOrEqualToThreshold,
+	}
+	AllowedAlarmStatistics = []string{
+		cloudwatch.StatisticAverage,
+		cloudwatch.StatisticMinimum,
+		cloudwatch.StatisticMaximum,
+		cloudwatch.StatisticSum,
+		cloudwatch.StatisticSampleCount,
+	}
+)
+
+// DescribeAlarms returns the metric alarms whose names start with prefix
+func (w *AwsWorker) DescribeAlarms(prefix string) ([]*cloudwatch.MetricAlarm, error) {
+	alarms := []*cloudwatch.MetricAlarm{}
+	err := w.CloudWatchClient.DescribeAlarmsPagesWithContext(w.context(), &cloudwatch.DescribeAlarmsInput{
+		AlarmNamePrefix: aws.String(prefix),
+		AlarmTypes:      aws.StringSlice([]string{cloudwatch.AlarmTypeMetricAlarm}),
+	}, func(page *cloudwatch.DescribeAlarmsOutput, lastPage bool) bool {
+		alarms = append(alarms, page.MetricAlarms...)
+		return page.NextToken != nil
+	})
+	if err != nil {
+		return alarms, err
+	}
+	return alarms, nil
+}
+
+// PutMetricAlarm creates an alarm, or replaces the configuration of an existing alarm with the same name
+func (w *AwsWorker) PutMetricAlarm(input *cloudwatch.PutMetricAlarmInput) error {
+	_, err := w.CloudWatchClient.PutMetricAlarmWithContext(w.context(), input)
+	return err
+}
+
+// DeleteAlarms deletes alarms by name
+func (w *AwsWorker) DeleteAlarms(names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	_, err := w.CloudWatchClient.DeleteAlarmsWithContext(w.context(), &cloudwatch.DeleteAlarmsInput{
+		AlarmNames: aws.StringSlice(names),
+	})
+	return err
+}
+
+// GetAwsCloudWatchClient returns a CloudWatch client
+func (c ClientConfig) GetAwsCloudWatchClient() cloudwatchiface.CloudWatchAPI {
+	config := c.awsConfig(cloudwatch.EndpointsID)
+	sess, err := session.NewSession(config)
+	if err != nil {
+		panic(err)
+	}
+	NewRateLimiter(c.RateLimits).AddRateLimiting(sess)
+	c.Breaker.AddCircuitBreaking(sess)
+	sess.Handlers.Complete.PushFront(func(r *request.Request) {
+		log.V(1).Info("AWS API call",
+			"service", r.ClientInfo.ServiceName,
+			"operation", r.Operation.Name,
+		)
+	})
+	return cloudwatch.New(sess, config)
+}
@@ -0,0 +1,84 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/eks"
+)
+
+// ClusterCache holds described EKS clusters by name, it is shared by the reconciles of all instance groups so that
+// a cluster is described once per TTL rather than once per instance group, cached clusters must not be modified
+type ClusterCache struct {
+	sync.Mutex
+	TTL      time.Duration
+	clusters map[string]*cachedCluster
+}
+
+type cachedCluster struct {
+	cluster   *eks.Cluster
+	expiresAt time.Time
+}
+
+// NewClusterCache returns a cluster cache with the given TTL, a non-positive TTL disables caching
+func NewClusterCache(ttl time.Duration) *ClusterCache {
+	return &ClusterCache{
+		TTL:      ttl,
+		clusters: make(map[string]*cachedCluster),
+	}
+}
+
+// Get returns a cached cluster if it has not expired
+func (c *ClusterCache) Get(name string) (*eks.Cluster, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	cached, ok := c.clusters[name]
+	if !ok || time.Now().After(cached.expiresAt) {
+		return nil, false
+	}
+	return cached.cluster, true
+}
+
+// Set caches a described cluster until the TTL expires
+func (c *ClusterCache) Set(name string, cluster *eks.Cluster) {
+	if c.TTL <= 0 || cluster == nil {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	c.clusters[name] = &cachedCluster{
+		cluster:   cluster,
+		expiresAt: time.Now().Add(c.TTL),
+	}
+}
+
+// Invalidate removes a cluster from the cache, it is described again on the next lookup
+func (c *ClusterCache) Invalidate(name string) {
+	c.Lock()
+	defer c.Unlock()
+	delete(c.clusters, name)
+}
+
+// Flush removes all clusters from the cache
+func (c *ClusterCache) Flush() {
+	c.Lock()
+	defer c.Unlock()
+	c.clusters = make(map[string]*cachedCluster)
+}
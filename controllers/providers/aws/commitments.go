@@ -0,0 +1,176 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/savingsplans"
+	"github.com/aws/aws-sdk-go/service/savingsplans/savingsplansiface"
+	"github.com/keikoproj/aws-sdk-go-cache/cache"
+)
+
+// DescribeActiveReservedInstances returns the active reserved instances in the worker's region
+func (w *AwsWorker) DescribeActiveReservedInstances() ([]*ec2.ReservedInstances, error) {
+	out, err := w.Ec2Client.DescribeReservedInstancesWithContext(w.context(), &ec2.DescribeReservedInstancesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("state"),
+				Values: aws.StringSlice([]string{ec2.ReservedInstanceStateActive}),
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.ReservedInstances, nil
+}
+
+// DescribeActiveSavingsPlans returns the active savings plans of the account
+func (w *AwsWorker) DescribeActiveSavingsPlans() ([]*savingsplans.SavingsPlan, error) {
+	var (
+		plans     = make([]*savingsplans.SavingsPlan, 0)
+		nextToken *string
+	)
+	for {
+		out, err := w.SavingsPlansClient.DescribeSavingsPlansWithContext(w.context(), &savingsplans.DescribeSavingsPlansInput{
+			States:    aws.StringSlice([]string{savingsplans.SavingsPlanStateActive}),
+			NextToken: nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, out.SavingsPlans...)
+		if aws.StringValue(out.NextToken) == "" {
+			return plans, nil
+		}
+		nextToken = out.NextToken
+	}
+}
+
+// ReservedInstancesCovering returns the number of instances of an instance type which reserved instances cover,
+// regional Linux reserved instances of the same family are size flexible and are counted by their normalization
+// factor
+func ReservedInstancesCovering(reservations []*ec2.ReservedInstances, instanceType string) int64 {
+	var units float64
+	for _, ri := range reservations {
+		var (
+			riType   = aws.StringValue(ri.InstanceType)
+			count    = float64(aws.Int64Value(ri.InstanceCount))
+			flexible = aws.StringValue(ri.Scope) == ec2.ScopeRegion &&
+				aws.StringValue(ri.InstanceTenancy) == ec2.TenancyDefault &&
+				strings.HasPrefix(aws.StringValue(ri.ProductDescription), ec2.RIProductDescriptionLinuxUnix)
+		)
+		if riType == instanceType {
+			units += count
+			continue
+		}
+		if !flexible || InstanceFamily(riType) != InstanceFamily(instanceType) {
+			continue
+		}
+		riFactor, ok := NormalizationFactor(riType)
+		if !ok {
+			continue
+		}
+		factor, ok := NormalizationFactor(instanceType)
+		if !ok {
+			continue
+		}
+		units += count * riFactor / factor
+	}
+	return int64(units)
+}
+
+// SavingsPlansCovering returns the savings plans which apply to an instance type in a region, compute savings plans
+// apply to all instance types and EC2 instance savings plans to the instance family in their region
+func SavingsPlansCovering(plans []*savingsplans.SavingsPlan, instanceType, region string) []*savingsplans.SavingsPlan {
+	covering := make([]*savingsplans.SavingsPlan, 0)
+	for _, plan := range plans {
+		switch aws.StringValue(plan.SavingsPlanType) {
+		case savingsplans.SavingsPlanTypeCompute:
+			covering = append(covering, plan)
+		case savingsplans.SavingsPlanTypeEc2instance:
+			if aws.StringValue(plan.Region) == region && aws.StringValue(plan.Ec2InstanceFamily) == InstanceFamily(instanceType) {
+				covering = append(covering, plan)
+			}
+		}
+	}
+	return covering
+}
+
+// InstanceFamily returns the family of an instance type, e.g. m5 for m5.large
+func InstanceFamily(instanceType string) string {
+	return strings.SplitN(instanceType, ".", 2)[0]
+}
+
+// NormalizationFactor returns the normalization factor of the size of an instance type which size flexible reserved
+// instances are applied by, sizes such as metal which have no factor return false
+func NormalizationFactor(instanceType string) (float64, bool) {
+	parts := strings.SplitN(instanceType, ".", 2)
+	if len(parts) != 2 {
+		return 0, false
+	}
+	size := parts[1]
+	switch size {
+	case "nano":
+		return 0.25, true
+	case "micro":
+		return 0.5, true
+	case "small":
+		return 1, true
+	case "medium":
+		return 2, true
+	case "large":
+		return 4, true
+	case "xlarge":
+		return 8, true
+	}
+	if !strings.HasSuffix(size, "xlarge") {
+		return 0, false
+	}
+	multiplier, err := strconv.ParseFloat(strings.TrimSuffix(size, "xlarge"), 64)
+	if err != nil {
+		return 0, false
+	}
+	return 8 * multiplier, true
+}
+
+// GetAwsSavingsPlansClient returns a Savings Plans client
+func (c ClientConfig) GetAwsSavingsPlansClient(cacheCfg *cache.Config) savingsplansiface.SavingsPlansAPI {
+	config := c.awsConfig(savingsplans.EndpointsID)
+	sess, err := session.NewSession(config)
+	if err != nil {
+		panic(err)
+	}
+	cache.AddCaching(sess, cacheCfg)
+	NewRateLimiter(c.RateLimits).AddRateLimiting(sess)
+	c.Breaker.AddCircuitBreaking(sess)
+	cacheCfg.SetCacheTTL(savingsplans.ServiceName, "DescribeSavingsPlans", DescribeSavingsPlansTTL)
+	sess.Handlers.Complete.PushFront(func(r *request.Request) {
+		ctx := r.HTTPRequest.Context()
+		log.V(1).Info("AWS API call",
+			"cacheHit", cache.IsCacheHit(ctx),
+			"service", r.ClientInfo.ServiceName,
+			"operation", r.Operation.Name,
+		)
+	})
+	return savingsplans.New(sess, config)
+}
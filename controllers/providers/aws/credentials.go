@@ -0,0 +1,185 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	// DefaultAssumeRoleDuration is the duration of the credentials of assumed roles, it is the maximum duration of
+	// roles assumed with the credentials of another role
+	DefaultAssumeRoleDuration = time.Hour
+	// DefaultCredentialsRefreshWindow is how long before they expire the credentials of assumed roles are refreshed
+	DefaultCredentialsRefreshWindow = 5 * time.Minute
+	// DefaultCredentialsRefreshInterval is the interval at which the credentials of assumed roles are checked
+	DefaultCredentialsRefreshInterval = time.Minute
+)
+
+var (
+	// AssumedRoleCredentialsExpiryMetric is the unix time at which the credentials of an assumed role expire, the
+	// namespace is empty for the role of the controller
+	AssumedRoleCredentialsExpiryMetric = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "instance_manager_aws_assumed_role_credentials_expiry_timestamp_seconds",
+		Help: "The unix time at which the credentials of an assumed IAM role expire",
+	}, []string{"namespace", "role"})
+	// AssumedRoleRefreshFailuresMetric is the number of failed refreshes of the credentials of an assumed role
+	AssumedRoleRefreshFailuresMetric = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "instance_manager_aws_assumed_role_refresh_failures_total",
+		Help: "The number of failed refreshes of the credentials of an assumed IAM role",
+	}, []string{"namespace", "role"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(AssumedRoleCredentialsExpiryMetric, AssumedRoleRefreshFailuresMetric)
+}
+
+// AssumeRoleConfig configures how the credentials of assumed roles are requested and refreshed
+type AssumeRoleConfig struct {
+	// Duration is the duration of the credentials of assumed roles
+	Duration time.Duration
+	// RefreshWindow is how long before they expire the credentials of assumed roles are refreshed
+	RefreshWindow time.Duration
+}
+
+// NewAssumeRoleCredentials returns the credentials of an IAM role assumed with the credentials of a client config,
+// the role is assumed when the credentials are first used and again once they are within the refresh window
+func NewAssumeRoleCredentials(config ClientConfig, roleConfig AssumeRoleConfig, roleArn string, options ...func(*stscreds.AssumeRoleProvider)) (*credentials.Credentials, error) {
+	if !IsIAMResourceArn(roleArn, "role") {
+		return nil, errors.Errorf("'%v' is not an IAM role ARN", roleArn)
+	}
+
+	sess, err := session.NewSession(config.awsConfig(sts.EndpointsID))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create session")
+	}
+
+	return stscreds.NewCredentials(sess, roleArn, append([]func(*stscreds.AssumeRoleProvider){
+		func(p *stscreds.AssumeRoleProvider) {
+			p.Duration = roleConfig.Duration
+			p.ExpiryWindow = roleConfig.RefreshWindow
+		},
+	}, options...)...), nil
+}
+
+// WithExternalID passes an external ID to the trust policy of an assumed role
+func WithExternalID(externalID string) func(*stscreds.AssumeRoleProvider) {
+	return func(p *stscreds.AssumeRoleProvider) {
+		if externalID != "" {
+			p.ExternalID = aws.String(externalID)
+		}
+	}
+}
+
+// WithRoleSessionName sets the session name of an assumed role
+func WithRoleSessionName(name string) func(*stscreds.AssumeRoleProvider) {
+	return func(p *stscreds.AssumeRoleProvider) {
+		p.RoleSessionName = name
+	}
+}
+
+// CredentialsRefresher refreshes the credentials of assumed roles in the background before they expire, so that
+// reconciles do not wait on STS, and exports when the credentials expire
+type CredentialsRefresher struct {
+	sync.Mutex
+	Interval      time.Duration
+	RefreshWindow time.Duration
+	credentials   map[string]refreshedCredentials
+}
+
+type refreshedCredentials struct {
+	namespace   string
+	role        string
+	credentials *credentials.Credentials
+}
+
+func NewCredentialsRefresher(refreshWindow time.Duration) *CredentialsRefresher {
+	return &CredentialsRefresher{
+		Interval:      DefaultCredentialsRefreshInterval,
+		RefreshWindow: refreshWindow,
+		credentials:   make(map[string]refreshedCredentials),
+	}
+}
+
+// Add refreshes the credentials of a namespace's role, replacing the credentials of a previous role of the namespace
+func (r *CredentialsRefresher) Add(namespace, role string, creds *credentials.Credentials) {
+	r.Lock()
+	defer r.Unlock()
+
+	if previous, ok := r.credentials[namespace]; ok && previous.role != role {
+		AssumedRoleCredentialsExpiryMetric.DeleteLabelValues(previous.namespace, previous.role)
+		AssumedRoleRefreshFailuresMetric.DeleteLabelValues(previous.namespace, previous.role)
+	}
+	r.credentials[namespace] = refreshedCredentials{namespace: namespace, role: role, credentials: creds}
+}
+
+// Refresh assumes the roles whose credentials were not requested yet or are within the refresh window
+func (r *CredentialsRefresher) Refresh() {
+	r.Lock()
+	refreshed := make([]refreshedCredentials, 0, len(r.credentials))
+	for _, c := range r.credentials {
+		refreshed = append(refreshed, c)
+	}
+	r.Unlock()
+
+	for _, c := range refreshed {
+		if c.credentials.IsExpired() {
+			if _, err := c.credentials.Get(); err != nil {
+				AssumedRoleRefreshFailuresMetric.WithLabelValues(c.namespace, c.role).Inc()
+				log.Error(err, "failed to refresh assumed role credentials", "namespace", c.namespace, "role", c.role)
+				continue
+			}
+			log.V(1).Info("refreshed assumed role credentials", "namespace", c.namespace, "role", c.role)
+		}
+
+		// the expiry of the credentials is moved forward by the refresh window
+		if expiry, err := c.credentials.ExpiresAt(); err == nil {
+			AssumedRoleCredentialsExpiryMetric.WithLabelValues(c.namespace, c.role).Set(float64(expiry.Add(r.RefreshWindow).Unix()))
+		}
+	}
+}
+
+// Start implements a controller-runtime runnable, it refreshes credentials at the interval until stop is closed
+func (r *CredentialsRefresher) Start(stop <-chan struct{}) error {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	r.Refresh()
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+			r.Refresh()
+		}
+	}
+}
+
+// NeedLeaderElection returns false, credentials are refreshed on standby replicas as well so that a replica which
+// becomes the leader does not wait on STS
+func (r *CredentialsRefresher) NeedLeaderElection() bool {
+	return false
+}
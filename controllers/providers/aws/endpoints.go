@@ -0,0 +1,118 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/pkg/errors"
+)
+
+var (
+	// DualStackDNSSuffixes are the DNS suffixes of the dual-stack endpoints of partitions
+	DualStackDNSSuffixes = map[string]string{
+		endpoints.AwsPartitionID:      "api.aws",
+		endpoints.AwsUsGovPartitionID: "api.aws",
+		endpoints.AwsCnPartitionID:    "api.amazonwebservices.com.cn",
+	}
+
+	// FIPSHostPrefixes are the host prefixes of the FIPS endpoints of services which do not follow the
+	// <service>-fips convention
+	FIPSHostPrefixes = map[string]string{
+		eks.EndpointsID: "fips.eks",
+	}
+)
+
+// endpointVariantResolver resolves the FIPS or dual-stack variant of the endpoints of the SDK's default resolver,
+// the SDK only models the FIPS endpoints of some services as pseudo regions and the dual-stack endpoints of S3
+type endpointVariantResolver struct {
+	fips      bool
+	dualStack bool
+}
+
+func (r endpointVariantResolver) EndpointFor(service, region string, opts ...func(*endpoints.Options)) (endpoints.ResolvedEndpoint, error) {
+	// dual-stack endpoints of S3 are modeled by the SDK
+	if r.dualStack && service == s3.EndpointsID {
+		opts = append(opts, endpoints.UseDualStackOption)
+	}
+
+	resolved, err := endpoints.DefaultResolver().EndpointFor(service, region, opts...)
+	if err != nil {
+		return resolved, err
+	}
+
+	u, err := url.Parse(resolved.URL)
+	if err != nil {
+		return resolved, errors.Wrapf(err, "failed to parse endpoint %v", resolved.URL)
+	}
+
+	if r.fips {
+		if u.Host, err = fipsHost(service, resolved.PartitionID, u.Host); err != nil {
+			return resolved, err
+		}
+	}
+	if r.dualStack && service != s3.EndpointsID {
+		u.Host = dualStackHost(resolved.PartitionID, u.Host)
+	}
+
+	resolved.URL = u.String()
+	return resolved, nil
+}
+
+// fipsHost returns the host of the FIPS endpoint of a service, the regional endpoints of GovCloud are FIPS endpoints
+// except those of S3
+func fipsHost(service, partition, host string) (string, error) {
+	switch partition {
+	case endpoints.AwsCnPartitionID:
+		return "", errors.Errorf("FIPS endpoints are not available in partition %v", partition)
+	case endpoints.AwsUsGovPartitionID:
+		if service != s3.EndpointsID {
+			return host, nil
+		}
+	}
+
+	labels := strings.SplitN(host, ".", 2)
+	if len(labels) != 2 {
+		return "", errors.Errorf("endpoint host %v of service %v has no FIPS variant", host, service)
+	}
+	if prefix, ok := FIPSHostPrefixes[service]; ok && labels[0] == service {
+		return fmt.Sprintf("%v.%v", prefix, labels[1]), nil
+	}
+	return fmt.Sprintf("%v-fips.%v", labels[0], labels[1]), nil
+}
+
+// dualStackHost returns the host of the dual-stack endpoint of a service, the endpoints of global services such as
+// IAM are in the global pseudo region
+func dualStackHost(partition, host string) string {
+	var (
+		suffix          = DNSSuffix(partition)
+		dualStackSuffix = DualStackDNSSuffixes[partition]
+	)
+	if dualStackSuffix == "" || !strings.HasSuffix(host, "."+suffix) {
+		return host
+	}
+
+	name := strings.TrimSuffix(host, "."+suffix)
+	if !strings.Contains(name, ".") {
+		name = fmt.Sprintf("%v.global", name)
+	}
+	return fmt.Sprintf("%v.%v", name, dualStackSuffix)
+}
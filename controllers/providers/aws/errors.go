@@ -0,0 +1,111 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/keikoproj/instance-manager/controllers/common"
+	"github.com/pkg/errors"
+)
+
+type ErrorClass string
+
+const (
+	ErrorClassThrottling        ErrorClass = "Throttling"
+	ErrorClassPermissionDenied  ErrorClass = "PermissionDenied"
+	ErrorClassDependencyMissing ErrorClass = "DependencyMissing"
+	ErrorClassValidation        ErrorClass = "Validation"
+	ErrorClassTransient         ErrorClass = "Transient"
+	ErrorClassCircuitOpen       ErrorClass = "CircuitOpen"
+)
+
+var (
+	PermissionDeniedErrorCodes = []string{
+		"AccessDenied",
+		"AccessDeniedException",
+		"UnauthorizedOperation",
+		"UnrecognizedClientException",
+		"InvalidClientTokenId",
+		"ExpiredToken",
+	}
+
+	DependencyMissingErrorCodes = []string{
+		"NoSuchEntity",
+		"ResourceNotFoundException",
+		"InvalidGroup.NotFound",
+		"InvalidSubnetID.NotFound",
+		"InvalidAMIID.NotFound",
+		"InvalidAMIID.Malformed",
+		"InvalidKeyPair.NotFound",
+	}
+
+	ValidationErrorCodes = []string{
+		"ValidationError",
+		"ValidationException",
+		"InvalidParameter",
+		"InvalidParameterValue",
+		"InvalidParameterCombination",
+		"InvalidParameterException",
+		"InvalidInput",
+		"MalformedPolicyDocument",
+	}
+)
+
+// ClassifyError returns the class of an error returned from an AWS API call, errors which are not
+// recognized are considered transient
+func ClassifyError(err error) ErrorClass {
+	if err == nil {
+		return ErrorClassTransient
+	}
+
+	aerr, ok := errors.Cause(err).(awserr.Error)
+	if !ok {
+		return ErrorClassTransient
+	}
+
+	code := aerr.Code()
+	switch {
+	case code == CircuitOpenErrorCode:
+		return ErrorClassCircuitOpen
+	case request.IsErrorThrottle(aerr):
+		return ErrorClassThrottling
+	case common.ContainsString(PermissionDeniedErrorCodes, code):
+		return ErrorClassPermissionDenied
+	case common.ContainsString(DependencyMissingErrorCodes, code):
+		return ErrorClassDependencyMissing
+	case code == "ValidationError" && strings.Contains(strings.ToLower(aerr.Message()), "not found"):
+		return ErrorClassDependencyMissing
+	case common.ContainsString(ValidationErrorCodes, code):
+		return ErrorClassValidation
+	}
+	return ErrorClassTransient
+}
+
+// ErrorDetails returns the error code and request ID of an error returned from an AWS API call, they are empty for
+// errors which did not come from AWS
+func ErrorDetails(err error) (string, string) {
+	var code, requestID string
+	if aerr, ok := errors.Cause(err).(awserr.Error); ok {
+		code = aerr.Code()
+	}
+	if rerr, ok := errors.Cause(err).(awserr.RequestFailure); ok {
+		requestID = rerr.RequestID()
+	}
+	return code, requestID
+}
@@ -0,0 +1,134 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/autoscaling/autoscalingiface"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	// DefaultHealthCheckInterval is the minimum interval between AWS health checks, probes in between return the
+	// result of the last check
+	DefaultHealthCheckInterval = 30 * time.Second
+	// DefaultHealthCheckTimeout is the maximum duration of the AWS API calls of a health check
+	DefaultHealthCheckTimeout = 10 * time.Second
+)
+
+var (
+	// CredentialsExpiryMetric is the unix time at which the controller's AWS credentials expire, it is not set for
+	// credentials which do not expire
+	CredentialsExpiryMetric = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "instance_manager_aws_credentials_expiry_timestamp_seconds",
+		Help: "The unix time at which the AWS credentials of the controller expire",
+	})
+	// HealthCheckFailuresMetric is the number of failed AWS health checks
+	HealthCheckFailuresMetric = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "instance_manager_aws_health_check_failures_total",
+		Help: "The number of failed AWS credential and connectivity checks",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(CredentialsExpiryMetric, HealthCheckFailuresMetric)
+}
+
+// HealthChecker checks that the controller's AWS credentials are valid and that the AWS endpoints are reachable
+type HealthChecker struct {
+	StsClient   stsiface.STSAPI
+	AsgClient   autoscalingiface.AutoScalingAPI
+	Credentials *credentials.Credentials
+	Interval    time.Duration
+	Timeout     time.Duration
+
+	lock      sync.Mutex
+	lastCheck time.Time
+	lastErr   error
+}
+
+// NewHealthChecker returns a health checker which uses its own uncached clients, so that checks always reach AWS
+func NewHealthChecker(config ClientConfig) *HealthChecker {
+	stsSess, err := session.NewSession(config.awsConfig(sts.EndpointsID))
+	if err != nil {
+		panic(err)
+	}
+	asgSess, err := session.NewSession(config.awsConfig(autoscaling.EndpointsID))
+	if err != nil {
+		panic(err)
+	}
+
+	return &HealthChecker{
+		StsClient:   sts.New(stsSess),
+		AsgClient:   autoscaling.New(asgSess),
+		Credentials: stsSess.Config.Credentials,
+		Interval:    DefaultHealthCheckInterval,
+		Timeout:     DefaultHealthCheckTimeout,
+	}
+}
+
+// Check implements a controller-runtime health checker, it fails when the credentials are invalid or expired, or AWS
+// cannot be reached
+func (h *HealthChecker) Check(req *http.Request) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if !h.lastCheck.IsZero() && time.Since(h.lastCheck) < h.Interval {
+		return h.lastErr
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), h.Timeout)
+	defer cancel()
+
+	h.lastErr = h.check(ctx)
+	h.lastCheck = time.Now()
+	if h.lastErr != nil {
+		HealthCheckFailuresMetric.Inc()
+		log.Error(h.lastErr, "aws health check failed")
+	}
+	return h.lastErr
+}
+
+func (h *HealthChecker) check(ctx context.Context) error {
+	if _, err := h.StsClient.GetCallerIdentityWithContext(ctx, &sts.GetCallerIdentityInput{}); err != nil {
+		return errors.Wrap(err, "failed to validate aws credentials")
+	}
+
+	if h.Credentials != nil {
+		if expiry, err := h.Credentials.ExpiresAt(); err == nil {
+			CredentialsExpiryMetric.Set(float64(expiry.Unix()))
+			if time.Now().After(expiry) {
+				return errors.Errorf("aws credentials expired at %v", expiry)
+			}
+		}
+	}
+
+	if _, err := h.AsgClient.DescribeAccountLimitsWithContext(ctx, &autoscaling.DescribeAccountLimitsInput{}); err != nil {
+		return errors.Wrap(err, "failed to reach autoscaling endpoint")
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/imagebuilder"
+	"github.com/aws/aws-sdk-go/service/imagebuilder/imagebuilderiface"
+	"github.com/pkg/errors"
+)
+
+// PipelineImage is an image built by an Image Builder pipeline, and the AMI it distributed to the worker's region
+type PipelineImage struct {
+	Arn         string
+	Version     string
+	Image       string
+	DateCreated string
+}
+
+// IsImagePipelineArn returns true if s is the ARN of an Image Builder pipeline
+func IsImagePipelineArn(s string) bool {
+	resource, err := arn.Parse(s)
+	if err != nil {
+		return false
+	}
+	return resource.Service == imagebuilder.EndpointsID && strings.HasPrefix(resource.Resource, "image-pipeline/")
+}
+
+// GetLatestPipelineImage returns the most recently created image of a pipeline which is available and was
+// distributed to the worker's region, or nil if the pipeline has not built such an image
+func (w *AwsWorker) GetLatestPipelineImage(pipelineArn string) (*PipelineImage, error) {
+	var latest *PipelineImage
+	err := w.ImageBuilderClient.ListImagePipelineImagesPagesWithContext(w.context(), &imagebuilder.ListImagePipelineImagesInput{
+		ImagePipelineArn: aws.String(pipelineArn),
+	}, func(page *imagebuilder.ListImagePipelineImagesOutput, lastPage bool) bool {
+		for _, summary := range page.ImageSummaryList {
+			if summary.State == nil || aws.StringValue(summary.State.Status) != imagebuilder.ImageStatusAvailable {
+				continue
+			}
+			image := w.regionalAmi(summary.OutputResources)
+			if image == "" {
+				continue
+			}
+			// creation dates are ISO 8601 timestamps in UTC and sort lexically
+			created := aws.StringValue(summary.DateCreated)
+			if latest != nil && latest.DateCreated >= created {
+				continue
+			}
+			latest = &PipelineImage{
+				Arn:         aws.StringValue(summary.Arn),
+				Version:     aws.StringValue(summary.Version),
+				Image:       image,
+				DateCreated: created,
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list images of pipeline %v", pipelineArn)
+	}
+	return latest, nil
+}
+
+// regionalAmi returns the AMI of an image's output resources in the worker's region, images distributed to a
+// single region are used when the region of the worker is unknown
+func (w *AwsWorker) regionalAmi(resources *imagebuilder.OutputResources) string {
+	if resources == nil {
+		return ""
+	}
+	if w.Region == "" {
+		if len(resources.Amis) == 1 {
+			return aws.StringValue(resources.Amis[0].Image)
+		}
+		return ""
+	}
+	for _, ami := range resources.Amis {
+		if aws.StringValue(ami.Region) == w.Region {
+			return aws.StringValue(ami.Image)
+		}
+	}
+	return ""
+}
+
+// GetAwsImageBuilderClient returns an Image Builder client, images of pipelines are not cached so that new images
+// are picked up by the reconcile following an image state change event
+func (c ClientConfig) GetAwsImageBuilderClient() imagebuilderiface.ImagebuilderAPI {
+	config := c.awsConfig(imagebuilder.EndpointsID)
+	sess, err := session.NewSession(config)
+	if err != nil {
+		panic(err)
+	}
+	NewRateLimiter(c.RateLimits).AddRateLimiting(sess)
+	c.Breaker.AddCircuitBreaking(sess)
+	sess.Handlers.Complete.PushFront(func(r *request.Request) {
+		log.V(1).Info("AWS API call",
+			"service", r.ClientInfo.ServiceName,
+			"operation", r.Operation.Name,
+		)
+	})
+	return imagebuilder.New(sess, config)
+}
@@ -0,0 +1,125 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/keikoproj/instance-manager/controllers/common"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultPartition is the partition of regions which are unknown to the SDK
+	DefaultPartition = endpoints.AwsPartitionID
+
+	// DefaultDNSSuffix is the DNS suffix of service principals of partitions which are unknown to the SDK
+	DefaultDNSSuffix = "amazonaws.com"
+)
+
+var (
+	// EndpointServices are the endpoint IDs of the services whose endpoints can be overridden
+	EndpointServices = []string{
+		autoscaling.EndpointsID,
+		ec2.EndpointsID,
+		eks.EndpointsID,
+		iam.EndpointsID,
+		s3.EndpointsID,
+		sqs.EndpointsID,
+		ssm.EndpointsID,
+		sts.EndpointsID,
+	}
+)
+
+// PartitionForRegion returns the partition of a region, e.g. aws-cn or aws-us-gov
+func PartitionForRegion(region string) string {
+	if partition, ok := endpoints.PartitionForRegion(endpoints.DefaultPartitions(), region); ok {
+		return partition.ID()
+	}
+	return DefaultPartition
+}
+
+// DNSSuffix returns the DNS suffix of the endpoints of a partition, e.g. amazonaws.com.cn in aws-cn
+func DNSSuffix(partition string) string {
+	for _, p := range endpoints.DefaultPartitions() {
+		if p.ID() == partition {
+			return p.DNSSuffix()
+		}
+	}
+	return DefaultDNSSuffix
+}
+
+// ServicePrincipal returns the principal of an AWS service in a partition, e.g. ec2.amazonaws.com.cn in aws-cn
+func ServicePrincipal(partition, service string) string {
+	return fmt.Sprintf("%v.%v", service, DNSSuffix(partition))
+}
+
+// ManagedPolicyArn returns the ARN of an AWS managed policy in a partition
+func ManagedPolicyArn(partition, name string) string {
+	return arn.ARN{
+		Partition: partition,
+		Service:   iam.EndpointsID,
+		AccountID: "aws",
+		Resource:  fmt.Sprintf("policy/%v", name),
+	}.String()
+}
+
+// IsIAMArn returns true if a string is the ARN of an IAM resource in any partition
+func IsIAMArn(s string) bool {
+	parsed, err := arn.Parse(s)
+	return err == nil && parsed.Service == iam.EndpointsID
+}
+
+// IsIAMResourceArn returns true if a string is the ARN of an IAM resource of a type in any partition, e.g. role or
+// instance-profile
+func IsIAMResourceArn(s, resourceType string) bool {
+	parsed, err := arn.Parse(s)
+	return err == nil && parsed.Service == iam.EndpointsID && strings.HasPrefix(parsed.Resource, resourceType+"/")
+}
+
+// ParseEndpoints parses a comma separated list of service=url endpoint overrides, services are endpoint IDs such as
+// ec2, autoscaling, iam, eks or sts
+func ParseEndpoints(s string) (map[string]string, error) {
+	overrides := make(map[string]string)
+	if strings.TrimSpace(s) == "" {
+		return overrides, nil
+	}
+
+	for _, pair := range strings.Split(s, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, errors.Errorf("endpoint '%v' is not in the form service=url", pair)
+		}
+
+		service := strings.ToLower(parts[0])
+		if !common.ContainsString(EndpointServices, service) {
+			return nil, errors.Errorf("endpoint of service '%v' cannot be overridden, services are %v", service, strings.Join(EndpointServices, ", "))
+		}
+		overrides[service] = parts[1]
+	}
+	return overrides, nil
+}
@@ -0,0 +1,194 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/outposts"
+	"github.com/aws/aws-sdk-go/service/outposts/outpostsiface"
+	"github.com/keikoproj/aws-sdk-go-cache/cache"
+)
+
+const (
+	ZoneTypeAvailabilityZone = "availability-zone"
+	ZoneTypeLocalZone        = "local-zone"
+	ZoneTypeWavelengthZone   = "wavelength-zone"
+	ZoneTypeOutpost          = "outpost"
+)
+
+// SubnetPlacement is the location of a subnet, subnets of local zones, wavelength zones and outposts only offer a
+// subset of the instance types of the region
+type SubnetPlacement struct {
+	SubnetID   string
+	Zone       string
+	ZoneType   string
+	OutpostArn string
+}
+
+// IsRegional returns true if the subnet is in an availability zone of the region
+func (p SubnetPlacement) IsRegional() bool {
+	return p.ZoneType == ZoneTypeAvailabilityZone
+}
+
+// ZoneType returns the type of a zone, local and wavelength zones have a network border group other than the region
+func ZoneType(zone *ec2.AvailabilityZone) string {
+	var (
+		name        = aws.StringValue(zone.ZoneName)
+		borderGroup = aws.StringValue(zone.NetworkBorderGroup)
+	)
+	switch {
+	case strings.Contains(name, "-wlz-"):
+		return ZoneTypeWavelengthZone
+	case borderGroup != "" && borderGroup != aws.StringValue(zone.RegionName):
+		return ZoneTypeLocalZone
+	default:
+		return ZoneTypeAvailabilityZone
+	}
+}
+
+// DescribeSubnetPlacements returns the placement of subnets, the zone type of subnets on an outpost is outpost
+func (w *AwsWorker) DescribeSubnetPlacements(subnetIds []string) ([]SubnetPlacement, error) {
+	var (
+		placements = make([]SubnetPlacement, 0)
+		subnets    = make([]*ec2.Subnet, 0)
+		zones      = make(map[string]*ec2.AvailabilityZone)
+		zoneNames  = make([]string, 0)
+	)
+
+	if len(subnetIds) == 0 {
+		return placements, nil
+	}
+
+	err := w.Ec2Client.DescribeSubnetsPagesWithContext(w.context(), &ec2.DescribeSubnetsInput{
+		SubnetIds: aws.StringSlice(subnetIds),
+	}, func(page *ec2.DescribeSubnetsOutput, lastPage bool) bool {
+		subnets = append(subnets, page.Subnets...)
+		return page.NextToken != nil
+	})
+	if err != nil {
+		return placements, err
+	}
+
+	for _, subnet := range subnets {
+		zoneNames = append(zoneNames, aws.StringValue(subnet.AvailabilityZone))
+	}
+	if len(zoneNames) == 0 {
+		return placements, nil
+	}
+
+	out, err := w.Ec2Client.DescribeAvailabilityZonesWithContext(w.context(), &ec2.DescribeAvailabilityZonesInput{
+		AllAvailabilityZones: aws.Bool(true),
+		ZoneNames:            aws.StringSlice(zoneNames),
+	})
+	if err != nil {
+		return placements, err
+	}
+	for _, zone := range out.AvailabilityZones {
+		zones[aws.StringValue(zone.ZoneName)] = zone
+	}
+
+	for _, subnet := range subnets {
+		placement := SubnetPlacement{
+			SubnetID:   aws.StringValue(subnet.SubnetId),
+			Zone:       aws.StringValue(subnet.AvailabilityZone),
+			ZoneType:   ZoneTypeAvailabilityZone,
+			OutpostArn: aws.StringValue(subnet.OutpostArn),
+		}
+		if zone, ok := zones[placement.Zone]; ok {
+			placement.ZoneType = ZoneType(zone)
+		}
+		if placement.OutpostArn != "" {
+			placement.ZoneType = ZoneTypeOutpost
+		}
+		placements = append(placements, placement)
+	}
+	return placements, nil
+}
+
+// IsInstanceTypeOffered returns true if an instance type is offered in a placement, instance types of outposts are
+// the ones which are installed on the outpost
+func (w *AwsWorker) IsInstanceTypeOffered(placement SubnetPlacement, instanceType string) (bool, error) {
+	if placement.OutpostArn != "" {
+		return w.isOutpostInstanceType(placement.OutpostArn, instanceType)
+	}
+
+	out, err := w.Ec2Client.DescribeInstanceTypeOfferingsWithContext(w.context(), &ec2.DescribeInstanceTypeOfferingsInput{
+		LocationType: aws.String(ec2.LocationTypeAvailabilityZone),
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("location"),
+				Values: aws.StringSlice([]string{placement.Zone}),
+			},
+			{
+				Name:   aws.String("instance-type"),
+				Values: aws.StringSlice([]string{instanceType}),
+			},
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+	return len(out.InstanceTypeOfferings) > 0, nil
+}
+
+func (w *AwsWorker) isOutpostInstanceType(outpostArn, instanceType string) (bool, error) {
+	var nextToken *string
+	for {
+		out, err := w.OutpostsClient.GetOutpostInstanceTypesWithContext(w.context(), &outposts.GetOutpostInstanceTypesInput{
+			OutpostId: aws.String(outpostArn),
+			NextToken: nextToken,
+		})
+		if err != nil {
+			return false, err
+		}
+		for _, item := range out.InstanceTypes {
+			if aws.StringValue(item.InstanceType) == instanceType {
+				return true, nil
+			}
+		}
+		if out.NextToken == nil {
+			return false, nil
+		}
+		nextToken = out.NextToken
+	}
+}
+
+// GetAwsOutpostsClient returns an Outposts client
+func (c ClientConfig) GetAwsOutpostsClient(cacheCfg *cache.Config) outpostsiface.OutpostsAPI {
+	config := c.awsConfig(outposts.EndpointsID)
+	sess, err := session.NewSession(config)
+	if err != nil {
+		panic(err)
+	}
+	cache.AddCaching(sess, cacheCfg)
+	NewRateLimiter(c.RateLimits).AddRateLimiting(sess)
+	c.Breaker.AddCircuitBreaking(sess)
+	cacheCfg.SetCacheTTL("outposts", "GetOutpostInstanceTypes", OutpostInstanceTypesTTL)
+	sess.Handlers.Complete.PushFront(func(r *request.Request) {
+		ctx := r.HTTPRequest.Context()
+		log.V(1).Info("AWS API call",
+			"cacheHit", cache.IsCacheHit(ctx),
+			"service", r.ClientInfo.ServiceName,
+			"operation", r.Operation.Name,
+		)
+	})
+	return outposts.New(sess, config)
+}
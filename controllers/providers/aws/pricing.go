@@ -0,0 +1,136 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/pricing"
+	"github.com/aws/aws-sdk-go/service/pricing/pricingiface"
+	"github.com/keikoproj/aws-sdk-go-cache/cache"
+	"github.com/pkg/errors"
+)
+
+const (
+	// PricingServiceCode is the service code of the prices of EC2 instances
+	PricingServiceCode = "AmazonEC2"
+)
+
+// pricingEndpoints are the region of the Pricing API endpoint and the currency of prices in each partition, the
+// endpoint serves the prices of all regions of the partition
+var pricingEndpoints = map[string]struct {
+	region   string
+	currency string
+}{
+	endpoints.AwsPartitionID:   {region: endpoints.UsEast1RegionID, currency: "USD"},
+	endpoints.AwsCnPartitionID: {region: endpoints.CnNorthwest1RegionID, currency: "CNY"},
+}
+
+// GetOnDemandPrice returns the hourly on-demand price of a Linux instance type with shared tenancy in the worker's
+// region, in the currency of the partition's prices
+func (w *AwsWorker) GetOnDemandPrice(instanceType string) (float64, error) {
+	endpoint, ok := pricingEndpoints[w.GetPartition()]
+	if !ok {
+		return 0, errors.Errorf("prices are not available in partition %v", w.GetPartition())
+	}
+
+	filters := map[string]string{
+		"instanceType":    instanceType,
+		"regionCode":      w.Region,
+		"operatingSystem": "Linux",
+		"tenancy":         "Shared",
+		"preInstalledSw":  "NA",
+		"capacitystatus":  "Used",
+	}
+	input := &pricing.GetProductsInput{
+		ServiceCode: aws.String(PricingServiceCode),
+	}
+	for field, value := range filters {
+		input.Filters = append(input.Filters, &pricing.Filter{
+			Type:  aws.String(pricing.FilterTypeTermMatch),
+			Field: aws.String(field),
+			Value: aws.String(value),
+		})
+	}
+
+	var price float64
+	err := w.PricingClient.GetProductsPagesWithContext(w.context(), input, func(page *pricing.GetProductsOutput, lastPage bool) bool {
+		for _, product := range page.PriceList {
+			if p, ok := onDemandPrice(product, endpoint.currency); ok {
+				price = p
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return 0, err
+	}
+	if price == 0 {
+		return 0, errors.Errorf("no on-demand price found for instance type %v in region %v", instanceType, w.Region)
+	}
+	return price, nil
+}
+
+// onDemandPrice returns the price per unit of the first on-demand price dimension of a product with a non-zero price
+func onDemandPrice(product aws.JSONValue, currency string) (float64, bool) {
+	terms, _ := product["terms"].(map[string]interface{})
+	offers, _ := terms["OnDemand"].(map[string]interface{})
+	for _, offer := range offers {
+		o, _ := offer.(map[string]interface{})
+		dimensions, _ := o["priceDimensions"].(map[string]interface{})
+		for _, dimension := range dimensions {
+			d, _ := dimension.(map[string]interface{})
+			pricePerUnit, _ := d["pricePerUnit"].(map[string]interface{})
+			value, _ := pricePerUnit[currency].(string)
+			price, err := strconv.ParseFloat(value, 64)
+			if err == nil && price > 0 {
+				return price, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// GetAwsPricingClient returns a Pricing client, its endpoint is in the partition's pricing region regardless of the
+// region of the controller, and prices are cached as they change rarely
+func (c ClientConfig) GetAwsPricingClient(cacheCfg *cache.Config) pricingiface.PricingAPI {
+	config := c.awsConfig(pricing.EndpointsID)
+	if endpoint, ok := pricingEndpoints[c.GetPartition()]; ok {
+		config = config.WithRegion(endpoint.region)
+	}
+	sess, err := session.NewSession(config)
+	if err != nil {
+		panic(err)
+	}
+	cache.AddCaching(sess, cacheCfg)
+	NewRateLimiter(c.RateLimits).AddRateLimiting(sess)
+	c.Breaker.AddCircuitBreaking(sess)
+	cacheCfg.SetCacheTTL(pricing.ServiceName, "GetProducts", GetProductsTTL)
+	sess.Handlers.Complete.PushFront(func(r *request.Request) {
+		ctx := r.HTTPRequest.Context()
+		log.V(1).Info("AWS API call",
+			"cacheHit", cache.IsCacheHit(ctx),
+			"service", r.ClientInfo.ServiceName,
+			"operation", r.Operation.Name,
+		)
+	})
+	return pricing.New(sess, config)
+}
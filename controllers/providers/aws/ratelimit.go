@@ -0,0 +1,159 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/keikoproj/aws-sdk-go-cache/cache"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// rate limits are reduced by this factor when a call is throttled, and recovered
+	// by a fraction of the configured limit on every successful call
+	throttleDecreaseFactor = 0.5
+	recoveryIncreaseFactor = 0.05
+	minimumLimitFactor     = 0.1
+)
+
+var (
+	DefaultRateLimits = RateLimits{
+		ReadQPS:     20,
+		ReadBurst:   40,
+		MutateQPS:   5,
+		MutateBurst: 10,
+	}
+
+	ReadOperationPrefixes = []string{"Describe", "List", "Get", "Head"}
+)
+
+// RateLimits configures the client-side token buckets for read and mutating AWS API calls
+type RateLimits struct {
+	ReadQPS     float64
+	ReadBurst   int
+	MutateQPS   float64
+	MutateBurst int
+}
+
+// RateLimiter is a client-side limiter for AWS API calls of a single service, keeping separate
+// budgets for read and mutating calls, and adapting the rate down when calls are throttled
+type RateLimiter struct {
+	read   *adaptiveLimiter
+	mutate *adaptiveLimiter
+}
+
+type adaptiveLimiter struct {
+	sync.Mutex
+	limiter *rate.Limiter
+	max     rate.Limit
+}
+
+func newAdaptiveLimiter(qps float64, burst int) *adaptiveLimiter {
+	if qps <= 0 {
+		return &adaptiveLimiter{
+			limiter: rate.NewLimiter(rate.Inf, burst),
+			max:     rate.Inf,
+		}
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &adaptiveLimiter{
+		limiter: rate.NewLimiter(rate.Limit(qps), burst),
+		max:     rate.Limit(qps),
+	}
+}
+
+func (l *adaptiveLimiter) decrease() {
+	l.Lock()
+	defer l.Unlock()
+	if l.max == rate.Inf {
+		return
+	}
+	limit := l.limiter.Limit() * throttleDecreaseFactor
+	if floor := l.max * minimumLimitFactor; limit < floor {
+		limit = floor
+	}
+	l.limiter.SetLimit(limit)
+}
+
+func (l *adaptiveLimiter) increase() {
+	l.Lock()
+	defer l.Unlock()
+	if l.max == rate.Inf {
+		return
+	}
+	limit := l.limiter.Limit() + l.max*recoveryIncreaseFactor
+	if limit > l.max {
+		limit = l.max
+	}
+	l.limiter.SetLimit(limit)
+}
+
+// NewRateLimiter returns a rate limiter for the given limits, a non-positive QPS disables limiting
+func NewRateLimiter(limits RateLimits) *RateLimiter {
+	return &RateLimiter{
+		read:   newAdaptiveLimiter(limits.ReadQPS, limits.ReadBurst),
+		mutate: newAdaptiveLimiter(limits.MutateQPS, limits.MutateBurst),
+	}
+}
+
+// IsReadOperation returns true if an API operation does not mutate resources
+func IsReadOperation(name string) bool {
+	for _, prefix := range ReadOperationPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *RateLimiter) limiterFor(r *request.Request) *adaptiveLimiter {
+	if r.Operation != nil && IsReadOperation(r.Operation.Name) {
+		return l.read
+	}
+	return l.mutate
+}
+
+// AddRateLimiting adds handlers to a session which wait for a token before every attempt of a call, and adjust
+// the rate based on the outcome, responses served from cache do not consume tokens
+func (l *RateLimiter) AddRateLimiting(sess *session.Session) {
+	sess.Handlers.Sign.PushFront(func(r *request.Request) {
+		if r.Error != nil || cache.IsCacheHit(r.HTTPRequest.Context()) {
+			return
+		}
+		if err := l.limiterFor(r).limiter.Wait(r.Context()); err != nil {
+			r.Error = awserr.New(request.CanceledErrorCode, "request context canceled while rate limited", err)
+		}
+	})
+
+	sess.Handlers.Retry.PushBack(func(r *request.Request) {
+		if r.IsErrorThrottle() {
+			l.limiterFor(r).decrease()
+		}
+	})
+
+	sess.Handlers.Complete.PushBack(func(r *request.Request) {
+		if r.Error == nil && !cache.IsCacheHit(r.HTTPRequest.Context()) {
+			l.limiterFor(r).increase()
+		}
+	})
+}
@@ -0,0 +1,67 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"github.com/pkg/errors"
+	kerr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// FieldManager is the manager of the fields instance-manager applies to objects with server-side apply
+	FieldManager = "instance-manager"
+)
+
+// ApplyPatchOptions returns the options of server-side apply patches, fields owned by other managers are taken over
+// since the fields which are applied are owned by instance-manager
+func ApplyPatchOptions() metav1.PatchOptions {
+	force := true
+	return metav1.PatchOptions{
+		FieldManager: FieldManager,
+		Force:        &force,
+	}
+}
+
+// IsRetryableApplyError returns true for errors of apply patches which can succeed when retried
+func IsRetryableApplyError(err error) bool {
+	return kerr.IsConflict(err) || kerr.IsServerTimeout(err) || kerr.IsTooManyRequests(err)
+}
+
+// ApplyUnstructured applies an object with server-side apply, the object should only have the fields managed by
+// instance-manager, apply patches which conflict are retried with backoff
+func ApplyUnstructured(kube dynamic.Interface, gvr schema.GroupVersionResource, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	data, err := obj.MarshalJSON()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to marshal %v %v", gvr.Resource, obj.GetName())
+	}
+
+	var applied *unstructured.Unstructured
+	err = retry.OnError(retry.DefaultBackoff, IsRetryableApplyError, func() error {
+		var err error
+		applied, err = kube.Resource(gvr).Namespace(obj.GetNamespace()).Patch(obj.GetName(), types.ApplyPatchType, data, ApplyPatchOptions())
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to apply %v %v", gvr.Resource, obj.GetName())
+	}
+	return applied, nil
+}
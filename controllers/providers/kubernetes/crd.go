@@ -204,15 +204,9 @@ func GetActiveResources(kube dynamic.Interface, instanceGroup *v1alpha1.Instance
 	return activeResources, nil
 }
 
+// SubmitCustomResource applies the custom resource of an upgrade with server-side apply, a resource which was already
+// submitted keeps the fields set by other managers, such as its status
 func SubmitCustomResource(kube dynamic.Interface, customResource *unstructured.Unstructured, CRDName string) error {
-	var (
-		customResourceNamespace = customResource.GetNamespace()
-		GVR                     = GetGVR(customResource, CRDName)
-	)
-
-	_, err := kube.Resource(GVR).Namespace(customResourceNamespace).Create(customResource, metav1.CreateOptions{})
-	if !kerr.IsAlreadyExists(err) {
-		return err
-	}
-	return nil
+	_, err := ApplyUnstructured(kube, GetGVR(customResource, CRDName), customResource)
+	return err
 }
@@ -0,0 +1,368 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/keikoproj/instance-manager/api/v1alpha1"
+	"github.com/keikoproj/instance-manager/controllers/common"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	MirrorPodAnnotationKey           = "kubernetes.io/config.mirror"
+	DaemonSetKind                    = "DaemonSet"
+	DrainStartedAnnotationKey        = "instancemgr.keikoproj.io/drain-started"
+	PreDrainStartedAnnotationKey     = "instancemgr.keikoproj.io/pre-drain-started"
+	PreDrainRequestedAnnotationValue = "requested"
+	PreDrainRequestTimeout           = 30 * time.Second
+)
+
+// PreDrainRequest is the body of the request sent to a pre-drain hook URL
+type PreDrainRequest struct {
+	Node       string `json:"node"`
+	InstanceID string `json:"instanceId"`
+}
+
+// CordonNode marks a node as unschedulable
+func CordonNode(kube kubernetes.Interface, node corev1.Node) error {
+	if node.Spec.Unschedulable {
+		return nil
+	}
+	patch := []byte(`{"spec":{"unschedulable":true}}`)
+	_, err := kube.CoreV1().Nodes().Patch(node.GetName(), types.StrategicMergePatchType, patch)
+	return err
+}
+
+// IsEvictablePod returns false for pods which do not need to be evicted when draining a node, such as
+// mirror pods, daemonset pods, and pods which have already completed
+func IsEvictablePod(pod corev1.Pod) bool {
+	if _, ok := pod.GetAnnotations()[MirrorPodAnnotationKey]; ok {
+		return false
+	}
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return false
+	}
+	if IsDaemonSetPod(pod) {
+		return false
+	}
+	return true
+}
+
+func IsDaemonSetPod(pod corev1.Pod) bool {
+	controller := metav1.GetControllerOf(&pod)
+	return controller != nil && controller.Kind == DaemonSetKind
+}
+
+func HasEmptyDirVolume(pod corev1.Pod) bool {
+	for _, volume := range pod.Spec.Volumes {
+		if volume.EmptyDir != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateDrainPods returns an error if a node cannot be drained according to the drain spec, like kubectl drain
+// refuses to drain nodes running DaemonSet pods or pods with local storage unless told to ignore them
+func ValidateDrainPods(pods []corev1.Pod, drain *v1alpha1.DrainSpec) error {
+	var daemonSetPods, emptyDirPods []string
+	for _, pod := range pods {
+		if _, ok := pod.GetAnnotations()[MirrorPodAnnotationKey]; ok {
+			continue
+		}
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		name := fmt.Sprintf("%v/%v", pod.GetNamespace(), pod.GetName())
+		if IsDaemonSetPod(pod) {
+			daemonSetPods = append(daemonSetPods, name)
+			continue
+		}
+		if HasEmptyDirVolume(pod) {
+			emptyDirPods = append(emptyDirPods, name)
+		}
+	}
+
+	if len(daemonSetPods) > 0 && !drain.IsSkipDaemonSets() {
+		return errors.Errorf("cannot drain DaemonSet pods %v, set 'skipDaemonSets' to ignore them", daemonSetPods)
+	}
+	if len(emptyDirPods) > 0 && !drain.IsDeleteEmptyDirData() {
+		return errors.Errorf("cannot drain pods with emptyDir volumes %v, set 'deleteEmptyDirData' to evict them", emptyDirPods)
+	}
+	return nil
+}
+
+// DrainNode evicts all evictable pods from a node, evictions which are refused due to a PodDisruptionBudget
+// are retried on the next call. When force is set, pods are deleted instead of evicted. Returns true
+// when no evictable pods remain on the node.
+func DrainNode(kube kubernetes.Interface, nodeName string, drain *v1alpha1.DrainSpec, force bool) (bool, error) {
+	pods, err := kube.CoreV1().Pods(metav1.NamespaceAll).List(metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", nodeName).String(),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if err := ValidateDrainPods(pods.Items, drain); err != nil {
+		return false, err
+	}
+
+	deleteOptions := &metav1.DeleteOptions{
+		GracePeriodSeconds: drain.GetGracePeriodSeconds(),
+	}
+
+	drained := true
+	for _, pod := range pods.Items {
+		if !IsEvictablePod(pod) {
+			continue
+		}
+		drained = false
+
+		if pod.GetDeletionTimestamp() != nil {
+			continue
+		}
+
+		var (
+			name      = pod.GetName()
+			namespace = pod.GetNamespace()
+		)
+
+		if force {
+			err = kube.CoreV1().Pods(namespace).Delete(name, deleteOptions)
+		} else {
+			err = kube.CoreV1().Pods(namespace).Evict(&policyv1beta1.Eviction{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespace,
+				},
+				DeleteOptions: deleteOptions,
+			})
+		}
+
+		if err != nil {
+			if kerrors.IsNotFound(err) {
+				continue
+			}
+			if kerrors.IsTooManyRequests(err) {
+				log.Info("eviction refused by disruption budget", "node", nodeName, "pod", name, "namespace", namespace)
+				continue
+			}
+			return false, err
+		}
+		log.Info("evicted pod", "node", nodeName, "pod", name, "namespace", namespace, "force", force)
+	}
+	return drained, nil
+}
+
+// DrainInstances runs the pre-drain hook, cordons and drains the nodes of the given instances, and returns true once
+// they are drained. If a node is not drained within the drain timeout, an error is returned unless force is set or
+// the PDB stall policy is to wait. Pods deleted by force are waited for until they are gone, or until the drain
+// timeout expires again.
+func DrainInstances(kube kubernetes.Interface, nodes *corev1.NodeList, instanceIds []string, drain *v1alpha1.DrainSpec) (bool, error) {
+	var (
+		timeout = time.Duration(drain.GetTimeoutSeconds()) * time.Second
+		drained = true
+	)
+
+	if nodes == nil {
+		return true, nil
+	}
+
+	for _, node := range nodes.Items {
+		var (
+			nodeName   = node.GetName()
+			instanceId = common.GetLastElementBy(node.Spec.ProviderID, "/")
+		)
+
+		if !common.ContainsString(instanceIds, instanceId) {
+			continue
+		}
+
+		ok, err := PrepareNodeDrain(kube, node, drain.GetPreDrain())
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to cordon node %v", nodeName)
+		}
+		if !ok {
+			drained = false
+			continue
+		}
+
+		var timedOut, forceTimedOut bool
+		if started, err := time.Parse(time.RFC3339, node.GetAnnotations()[DrainStartedAnnotationKey]); err == nil {
+			timedOut = time.Since(started) > timeout
+			forceTimedOut = time.Since(started) > 2*timeout
+		}
+		force := timedOut && drain.IsForce()
+
+		ok, err = DrainNode(kube, nodeName, drain, force)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to drain node %v", nodeName)
+		}
+		if ok || (force && forceTimedOut) {
+			continue
+		}
+		if force {
+			log.Info("waiting for pods deleted by force to terminate", "node", nodeName)
+			drained = false
+			continue
+		}
+		if timedOut && !drain.IsWaitOnPDBStall() {
+			return false, errors.Errorf("node %v was not drained within %v", nodeName, timeout)
+		}
+		drained = false
+	}
+	return drained, nil
+}
+
+// PrepareNodeDrain runs the pre-drain hook of a node, and cordons the node once the hook has completed. Returns true
+// when the node can be drained.
+func PrepareNodeDrain(kube kubernetes.Interface, node corev1.Node, hook *v1alpha1.PreDrainHook) (bool, error) {
+	if _, ok := node.GetAnnotations()[DrainStartedAnnotationKey]; ok {
+		return true, CordonNode(kube, node)
+	}
+
+	if hook != nil {
+		ok, err := ProcessPreDrainHook(kube, node, hook)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+
+	patch := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{
+				DrainStartedAnnotationKey: time.Now().UTC().Format(time.RFC3339),
+			},
+		},
+		"spec": map[string]interface{}{
+			"unschedulable": true,
+		},
+	}
+	if err := PatchNode(kube, node.GetName(), patch); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ProcessPreDrainHook starts the pre-drain hook of a node and returns true once it has completed or timed out
+func ProcessPreDrainHook(kube kubernetes.Interface, node corev1.Node, hook *v1alpha1.PreDrainHook) (bool, error) {
+	var (
+		nodeName    = node.GetName()
+		annotations = node.GetAnnotations()
+		timeout     = time.Duration(hook.GetTimeoutSeconds()) * time.Second
+	)
+
+	started, err := time.Parse(time.RFC3339, annotations[PreDrainStartedAnnotationKey])
+	if err != nil {
+		started = time.Now().UTC()
+		hookAnnotations := map[string]string{
+			PreDrainStartedAnnotationKey: started.Format(time.RFC3339),
+		}
+		if !common.StringEmpty(hook.Annotation) {
+			hookAnnotations[hook.Annotation] = PreDrainRequestedAnnotationValue
+		}
+		patch := map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"annotations": hookAnnotations,
+			},
+		}
+		if err := PatchNode(kube, nodeName, patch); err != nil {
+			return false, err
+		}
+		log.Info("started pre-drain hook", "node", nodeName)
+
+		// the node annotations are observed on the next reconcile
+		if !common.StringEmpty(hook.Annotation) {
+			return false, nil
+		}
+	}
+
+	var completed bool
+	switch {
+	case !common.StringEmpty(hook.URL):
+		if err := CallPreDrainURL(hook.URL, node); err != nil {
+			log.Info("pre-drain hook request failed, will retry", "node", nodeName, "reason", err.Error())
+		} else {
+			completed = true
+		}
+	case !common.StringEmpty(hook.Annotation):
+		_, ok := annotations[hook.Annotation]
+		completed = !ok
+	case hook.Condition != nil:
+		completed = IsNodeReadinessGatesSatisfied(node, []v1alpha1.NodeReadinessGate{*hook.Condition})
+	}
+
+	if completed {
+		log.Info("pre-drain hook completed", "node", nodeName)
+		return true, nil
+	}
+
+	if time.Since(started) > timeout {
+		log.Info("pre-drain hook timed out, draining node", "node", nodeName, "timeout", timeout)
+		return true, nil
+	}
+
+	log.Info("waiting for pre-drain hook", "node", nodeName)
+	return false, nil
+}
+
+func CallPreDrainURL(url string, node corev1.Node) error {
+	body, err := json.Marshal(PreDrainRequest{
+		Node:       node.GetName(),
+		InstanceID: common.GetLastElementBy(node.Spec.ProviderID, "/"),
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal pre-drain request")
+	}
+
+	client := &http.Client{Timeout: PreDrainRequestTimeout}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return errors.Errorf("pre-drain url %v returned status %v", url, resp.StatusCode)
+	}
+	return nil
+}
+
+// PatchNode applies a strategic merge patch to a node, nodes which no longer exist are ignored
+func PatchNode(kube kubernetes.Interface, name string, patch map[string]interface{}) error {
+	body, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+	_, err = kube.CoreV1().Nodes().Patch(name, types.StrategicMergePatchType, body)
+	if kerrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
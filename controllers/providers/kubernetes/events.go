@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"github.com/keikoproj/instance-manager/api/v1alpha1"
+	"github.com/keikoproj/instance-manager/controllers/common"
 	"k8s.io/apimachinery/pkg/types"
 
 	v1 "k8s.io/api/core/v1"
@@ -50,6 +51,21 @@ var (
 	NodesReadyEvent                 EventKind = "InstanceGroupNodesReady"
 	NodesNotReadyEvent              EventKind = "InstanceGroupNodesNotReady"
 	InstanceGroupUpgradeFailedEvent EventKind = "InstanceGroupUpgradeFailed"
+	ImageResolvedEvent              EventKind = "InstanceGroupImageResolved"
+	ChangesRevertedEvent            EventKind = "InstanceGroupChangesReverted"
+	ChangesPlannedEvent             EventKind = "InstanceGroupChangesPlanned"
+	RotationRequestedEvent          EventKind = "InstanceGroupRotationRequested"
+	ScalingGroupRetiredEvent        EventKind = "InstanceGroupScalingGroupRetired"
+	RevisionRecordedEvent           EventKind = "InstanceGroupRevisionRecorded"
+	RolledBackEvent                 EventKind = "InstanceGroupRolledBack"
+	RollbackFailedEvent             EventKind = "InstanceGroupRollbackFailed"
+	FargateProfileStatusEvent       EventKind = "InstanceGroupFargateProfileStatusChanged"
+	PipelineImageResolvedEvent      EventKind = "InstanceGroupPipelineImageResolved"
+	UncoveredInstanceTypeEvent      EventKind = "InstanceGroupInstanceTypeUncovered"
+	StaleNodeRemovedEvent           EventKind = "InstanceGroupStaleNodeRemoved"
+	UnhealthyNodesReplacedEvent     EventKind = "InstanceGroupUnhealthyNodesReplaced"
+	BootstrapFailedEvent            EventKind = "InstanceGroupBootstrapFailed"
+	ZonesImbalancedEvent            EventKind = "InstanceGroupZonesImbalanced"
 
 	EventLevels = map[EventKind]string{
 		InstanceGroupCreatedEvent:       EventLevelNormal,
@@ -57,6 +73,21 @@ var (
 		NodesNotReadyEvent:              EventLevelWarning,
 		NodesReadyEvent:                 EventLevelNormal,
 		InstanceGroupUpgradeFailedEvent: EventLevelWarning,
+		ImageResolvedEvent:              EventLevelNormal,
+		ChangesRevertedEvent:            EventLevelWarning,
+		ChangesPlannedEvent:             EventLevelNormal,
+		RotationRequestedEvent:          EventLevelNormal,
+		ScalingGroupRetiredEvent:        EventLevelNormal,
+		RevisionRecordedEvent:           EventLevelNormal,
+		RolledBackEvent:                 EventLevelNormal,
+		RollbackFailedEvent:             EventLevelWarning,
+		FargateProfileStatusEvent:       EventLevelNormal,
+		PipelineImageResolvedEvent:      EventLevelNormal,
+		UncoveredInstanceTypeEvent:      EventLevelNormal,
+		StaleNodeRemovedEvent:           EventLevelNormal,
+		UnhealthyNodesReplacedEvent:     EventLevelWarning,
+		BootstrapFailedEvent:            EventLevelWarning,
+		ZonesImbalancedEvent:            EventLevelWarning,
 	}
 
 	EventMessages = map[EventKind]string{
@@ -65,6 +96,21 @@ var (
 		InstanceGroupUpgradeFailedEvent: "instance group has failed upgrading",
 		NodesNotReadyEvent:              "instance group nodes are not ready",
 		NodesReadyEvent:                 "instance group nodes are ready",
+		ImageResolvedEvent:              "instance group image has been resolved for the cluster version",
+		ChangesRevertedEvent:            "changes made to the scaling group outside of the controller have been reverted",
+		ChangesPlannedEvent:             "instance group is in dry-run, changes to cloud resources have been planned without being made",
+		RotationRequestedEvent:          "a rotation of all instance group nodes has been requested",
+		ScalingGroupRetiredEvent:        "nodes have been migrated from a replaced scaling group, and it has been deleted",
+		RevisionRecordedEvent:           "a configuration has been rolled out to all nodes and recorded in the revision history",
+		RolledBackEvent:                 "instance group configuration has been rolled back to a revision of its history",
+		RollbackFailedEvent:             "instance group configuration could not be rolled back",
+		FargateProfileStatusEvent:       "the status of the fargate profile has changed",
+		PipelineImageResolvedEvent:      "instance group image has been resolved from the latest image of the image pipeline",
+		UncoveredInstanceTypeEvent:      "instance group instance type is not covered by reserved instances or savings plans, but a candidate instance type is",
+		StaleNodeRemovedEvent:           "a node whose instance no longer exists has been removed",
+		UnhealthyNodesReplacedEvent:     "instances whose nodes were not ready have been terminated and are replaced by the scaling group",
+		BootstrapFailedEvent:            "instances have been running for longer than the join timeout without their nodes joining the cluster",
+		ZonesImbalancedEvent:            "instances have been imbalanced across zones for longer than the threshold, resume the AZRebalance process or change the subnets to rebalance them",
 	}
 )
 
@@ -81,6 +127,8 @@ func (e *EventPublisher) Publish(kind EventKind, keysAndValues ...interface{}) {
 	messageFields := make(map[string]string)
 	messageFields["msg"] = getEventMessage(kind)
 
+	// sensitive values such as user data are redacted from event messages
+	keysAndValues = common.RedactKeysAndValues(keysAndValues...)
 	for i := 0; i < len(keysAndValues); i += 2 {
 		key := keysAndValues[i].(string)
 		value := keysAndValues[i+1].(string)
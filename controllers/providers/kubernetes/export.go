@@ -0,0 +1,111 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"time"
+
+	"github.com/keikoproj/instance-manager/api/v1alpha1"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+)
+
+// WriteExport stores the exported cloud resources of an instance group in a ConfigMap owned by the instance group,
+// keyed by the export format, the time of the export is recorded in an annotation, the ConfigMap is applied with
+// server-side apply and keeps the exports of other formats
+func WriteExport(kube dynamic.Interface, instanceGroup *v1alpha1.InstanceGroup, format, rendered string) error {
+	var (
+		name = instanceGroup.GetExportName()
+		now  = time.Now().UTC().Format(time.RFC3339)
+		data = map[string]string{format: rendered}
+	)
+
+	exports, err := getConfigMapData(kube, instanceGroup.GetNamespace(), name)
+	if err != nil {
+		return errors.Wrap(err, "failed to get export configmap")
+	}
+	for key, value := range exports {
+		if _, ok := data[key]; !ok {
+			data[key] = value
+		}
+	}
+
+	annotations := map[string]string{
+		v1alpha1.ExportTimeAnnotationKey: now,
+	}
+	if err := applyOwnedConfigMap(kube, instanceGroup, name, annotations, data); err != nil {
+		return errors.Wrap(err, "failed to apply export configmap")
+	}
+	return nil
+}
+
+// getConfigMapData returns the data of a ConfigMap, or nil when the ConfigMap does not exist
+func getConfigMapData(kube dynamic.Interface, namespace, name string) (map[string]string, error) {
+	gvr := corev1.SchemeGroupVersion.WithResource("configmaps")
+	existing, err := kube.Resource(gvr).Namespace(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		if kerr.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	data, _, _ := unstructured.NestedStringMap(existing.Object, "data")
+	return data, nil
+}
+
+// applyOwnedConfigMap applies a ConfigMap owned by an instance group with server-side apply, keys of the data which
+// were previously applied and are no longer present are removed
+func applyOwnedConfigMap(kube dynamic.Interface, instanceGroup *v1alpha1.InstanceGroup, name string, annotations, data map[string]string) error {
+	gvr := corev1.SchemeGroupVersion.WithResource("configmaps")
+
+	metaAnnotations := map[string]string{
+		OwnershipAnnotationKey: OwnershipAnnotationValue,
+	}
+	for key, value := range annotations {
+		metaAnnotations[key] = value
+	}
+
+	cm := &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "ConfigMap",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   instanceGroup.GetNamespace(),
+			Annotations: metaAnnotations,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(instanceGroup, v1alpha1.GroupVersion.WithKind(InvolvedObjectKind)),
+			},
+		},
+		Data: data,
+	}
+
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(cm)
+	if err != nil {
+		return errors.Wrapf(err, "failed to convert configmap %v to unstructured", name)
+	}
+	// the creation timestamp is not a field instance-manager applies
+	unstructured.RemoveNestedField(obj, "metadata", "creationTimestamp")
+
+	_, err = ApplyUnstructured(kube, gvr, &unstructured.Unstructured{Object: obj})
+	return err
+}
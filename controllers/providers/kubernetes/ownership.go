@@ -0,0 +1,89 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	NodeGroupLabelKey     = "node.instancemgr.keikoproj.io/group"
+	NodeNamespaceLabelKey = "node.instancemgr.keikoproj.io/namespace"
+	NodeLifecycleLabelKey = "node.instancemgr.keikoproj.io/lifecycle"
+
+	// NodeLaunchConfigurationAnnotationKey is the launch configuration the instance of a node was launched with, it
+	// is an annotation since launch configuration names can exceed the length of label values
+	NodeLaunchConfigurationAnnotationKey = "node.instancemgr.keikoproj.io/launch-configuration"
+
+	NodeLifecycleSpot     = "spot"
+	NodeLifecycleOnDemand = "on-demand"
+)
+
+// NodeOwnership is the instance group which owns a node, and how the node's instance was launched
+type NodeOwnership struct {
+	Group               string
+	Namespace           string
+	Lifecycle           string
+	LaunchConfiguration string
+}
+
+// Labels returns the ownership labels of a node, the lifecycle is omitted when it is unknown
+func (o NodeOwnership) Labels() map[string]string {
+	labels := map[string]string{
+		NodeGroupLabelKey:     o.Group,
+		NodeNamespaceLabelKey: o.Namespace,
+	}
+	if o.Lifecycle != "" {
+		labels[NodeLifecycleLabelKey] = o.Lifecycle
+	}
+	return labels
+}
+
+// Annotations returns the ownership annotations of a node
+func (o NodeOwnership) Annotations() map[string]string {
+	annotations := map[string]string{}
+	if o.LaunchConfiguration != "" {
+		annotations[NodeLaunchConfigurationAnnotationKey] = o.LaunchConfiguration
+	}
+	return annotations
+}
+
+// HasNodeOwnership returns true if a node already has the ownership labels and annotations
+func HasNodeOwnership(node corev1.Node, o NodeOwnership) bool {
+	for k, v := range o.Labels() {
+		if node.GetLabels()[k] != v {
+			return false
+		}
+	}
+	for k, v := range o.Annotations() {
+		if node.GetAnnotations()[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// SetNodeOwnership labels and annotates a node with the instance group which owns it
+func SetNodeOwnership(kube kubernetes.Interface, node corev1.Node, o NodeOwnership) error {
+	patch := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels":      o.Labels(),
+			"annotations": o.Annotations(),
+		},
+	}
+	return PatchNode(kube, node.GetName(), patch)
+}
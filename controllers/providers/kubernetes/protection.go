@@ -0,0 +1,31 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	ScaleInProtectionAnnotationKey = "instancemgr.keikoproj.io/scale-in-protection"
+)
+
+// IsScaleInProtected returns true if a node is annotated to protect its instance from scale-in
+func IsScaleInProtected(node corev1.Node) bool {
+	return strings.EqualFold(node.GetAnnotations()[ScaleInProtectionAnnotationKey], "true")
+}
@@ -0,0 +1,205 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/keikoproj/instance-manager/api/v1alpha1"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+)
+
+var (
+	// InstanceGroupRevisionResource is the resource of InstanceGroupRevisions
+	InstanceGroupRevisionResource = v1alpha1.GroupVersion.WithResource("instancegrouprevisions")
+)
+
+// WriteRevision stores the configuration of a revision in the revisions ConfigMap of an instance group, keyed by the
+// revision number, configurations of revisions which are no longer in the instance group's revision history are
+// removed from the ConfigMap
+func WriteRevision(kube dynamic.Interface, instanceGroup *v1alpha1.InstanceGroup, revision int, configuration *v1alpha1.EKSConfiguration) error {
+	name := instanceGroup.GetRevisionsName()
+
+	raw, err := json.Marshal(configuration)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal configuration of revision %v", revision)
+	}
+
+	existing, err := getConfigMapData(kube, instanceGroup.GetNamespace(), name)
+	if err != nil {
+		return errors.Wrap(err, "failed to get revisions configmap")
+	}
+
+	data := map[string]string{strconv.Itoa(revision): string(raw)}
+	for _, retained := range instanceGroup.GetStatus().GetRevisions() {
+		key := strconv.Itoa(retained.Revision)
+		if value, ok := existing[key]; ok && retained.Revision != revision {
+			data[key] = value
+		}
+	}
+
+	if err := applyOwnedConfigMap(kube, instanceGroup, name, nil, data); err != nil {
+		return errors.Wrap(err, "failed to apply revisions configmap")
+	}
+	return nil
+}
+
+// GetRevisionConfiguration returns the configuration of a revision from the revisions ConfigMap of an instance group
+func GetRevisionConfiguration(kube dynamic.Interface, instanceGroup *v1alpha1.InstanceGroup, revision int) (*v1alpha1.EKSConfiguration, error) {
+	name := instanceGroup.GetRevisionsName()
+
+	data, err := getConfigMapData(kube, instanceGroup.GetNamespace(), name)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get revisions configmap")
+	}
+
+	raw, ok := data[strconv.Itoa(revision)]
+	if !ok {
+		return nil, errors.Errorf("configuration of revision %v is not in configmap %v", revision, name)
+	}
+
+	configuration := &v1alpha1.EKSConfiguration{}
+	if err := json.Unmarshal([]byte(raw), configuration); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal configuration of revision %v", revision)
+	}
+	return configuration, nil
+}
+
+// WriteInstanceGroupRevision records a change applied to an instance group as an InstanceGroupRevision owned by the
+// instance group, its changes are summarized relative to the configuration of the previous revision, a revision
+// which is already recorded with the same outcome is not written again, and the oldest revisions beyond the limit
+// are deleted
+func WriteInstanceGroupRevision(kube dynamic.Interface, instanceGroup *v1alpha1.InstanceGroup, revision *v1alpha1.InstanceGroupRevision, limit int) error {
+	revisions, err := ListInstanceGroupRevisions(kube, instanceGroup)
+	if err != nil {
+		return errors.Wrap(err, "failed to list instance group revisions")
+	}
+
+	var previous *v1alpha1.InstanceGroupRevision
+	for i := range revisions {
+		existing := &revisions[i]
+		if existing.GetName() == revision.GetName() {
+			if existing.Spec.Outcome == revision.Spec.Outcome {
+				return nil
+			}
+			continue
+		}
+		previous = existing
+	}
+	if previous != nil {
+		revision.Spec.Changes = v1alpha1.ConfigurationChanges(previous.Spec.Configuration, revision.Spec.Configuration)
+	}
+
+	revision.TypeMeta = metav1.TypeMeta{
+		APIVersion: v1alpha1.GroupVersion.String(),
+		Kind:       "InstanceGroupRevision",
+	}
+	revision.SetNamespace(instanceGroup.GetNamespace())
+	revision.SetLabels(map[string]string{
+		v1alpha1.InstanceGroupLabelKey: instanceGroup.GetName(),
+	})
+	revision.SetOwnerReferences([]metav1.OwnerReference{
+		*metav1.NewControllerRef(instanceGroup, v1alpha1.GroupVersion.WithKind(InvolvedObjectKind)),
+	})
+
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(revision)
+	if err != nil {
+		return errors.Wrapf(err, "failed to convert instance group revision %v to unstructured", revision.GetName())
+	}
+	// the creation timestamp is not a field instance-manager applies
+	unstructured.RemoveNestedField(obj, "metadata", "creationTimestamp")
+
+	if _, err := ApplyUnstructured(kube, InstanceGroupRevisionResource, &unstructured.Unstructured{Object: obj}); err != nil {
+		return err
+	}
+
+	retained := []v1alpha1.InstanceGroupRevision{*revision}
+	for _, existing := range revisions {
+		if existing.GetName() != revision.GetName() {
+			retained = append(retained, existing)
+		}
+	}
+	sortRevisions(retained)
+
+	for i := 0; i < len(retained)-limit; i++ {
+		name := retained[i].GetName()
+		err := kube.Resource(InstanceGroupRevisionResource).Namespace(instanceGroup.GetNamespace()).Delete(name, &metav1.DeleteOptions{})
+		if err != nil {
+			return errors.Wrapf(err, "failed to delete instance group revision %v", name)
+		}
+	}
+	return nil
+}
+
+// ListInstanceGroupRevisions returns the InstanceGroupRevisions of an instance group, oldest first
+func ListInstanceGroupRevisions(kube dynamic.Interface, instanceGroup *v1alpha1.InstanceGroup) ([]v1alpha1.InstanceGroupRevision, error) {
+	list, err := kube.Resource(InstanceGroupRevisionResource).Namespace(instanceGroup.GetNamespace()).List(metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%v=%v", v1alpha1.InstanceGroupLabelKey, instanceGroup.GetName()),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := make([]v1alpha1.InstanceGroupRevision, 0)
+	for _, item := range list.Items {
+		revision := v1alpha1.InstanceGroupRevision{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, &revision); err != nil {
+			return nil, errors.Wrapf(err, "failed to convert instance group revision %v", item.GetName())
+		}
+		revisions = append(revisions, revision)
+	}
+	sortRevisions(revisions)
+	return revisions, nil
+}
+
+func sortRevisions(revisions []v1alpha1.InstanceGroupRevision) {
+	sort.SliceStable(revisions, func(i, j int) bool {
+		return revisions[i].Spec.RecordedAt.Before(&revisions[j].Spec.RecordedAt)
+	})
+}
+
+// GetSpecManager returns the field manager which most recently changed the spec of an object, fields applied by
+// instance-manager are not considered
+func GetSpecManager(obj metav1.Object) string {
+	var (
+		manager string
+		latest  *metav1.Time
+	)
+	for _, entry := range obj.GetManagedFields() {
+		if entry.Manager == FieldManager || entry.FieldsV1 == nil {
+			continue
+		}
+		if !bytes.Contains(entry.FieldsV1.Raw, []byte(`"f:spec"`)) {
+			continue
+		}
+		if latest == nil || (entry.Time != nil && latest.Before(entry.Time)) {
+			manager = entry.Manager
+			latest = entry.Time
+			if latest == nil {
+				latest = &metav1.Time{}
+			}
+		}
+	}
+	return manager
+}
@@ -16,8 +16,12 @@ limitations under the License.
 package kubernetes
 
 import (
+	"time"
+
+	"github.com/keikoproj/instance-manager/api/v1alpha1"
 	awsprovider "github.com/keikoproj/instance-manager/controllers/providers/aws"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
 
@@ -31,12 +35,21 @@ var (
 
 type RollingUpdateRequest struct {
 	AwsWorker        awsprovider.AwsWorker
+	Kubernetes       kubernetes.Interface
+	Drain            *v1alpha1.DrainSpec
 	ClusterNodes     *corev1.NodeList
 	ScalingGroupName string
 	MaxUnavailable   int
 	DesiredCapacity  int
 	AllInstances     []string
 	UpdateTargets    []string
+	ReadinessGates   []v1alpha1.NodeReadinessGate
+	Cooldown         time.Duration
+	LastBatchTime    time.Time
+	// ExcludedTargets are the nodes of instances which need update but are excluded from rotation
+	ExcludedTargets []string
+	// Terminated is set when a batch of targets was terminated
+	Terminated bool
 }
 
 func ProcessRollingUpgradeStrategy(req *RollingUpdateRequest) (bool, error) {
@@ -61,16 +74,23 @@ func ProcessRollingUpgradeStrategy(req *RollingUpdateRequest) (bool, error) {
 		req.MaxUnavailable = req.DesiredCapacity
 	}
 
-	ok, err := IsMinNodesReady(req.ClusterNodes, req.AllInstances, req.MaxUnavailable)
+	ok, err := IsMinNodesReady(req.ClusterNodes, req.AllInstances, req.MaxUnavailable, req.ReadinessGates)
 	if err != nil {
 		return false, err
 	}
 
 	if !ok {
-		log.Info("desired nodes are not ready", "scalinggroup", req.ScalingGroupName)
+		log.Info("desired nodes are not ready", "scalinggroup", req.ScalingGroupName, "readinessGates", req.ReadinessGates)
 		return false, nil
 	}
 
+	if req.Cooldown > 0 && !req.LastBatchTime.IsZero() {
+		if remaining := req.Cooldown - time.Since(req.LastBatchTime); remaining > 0 {
+			log.Info("waiting for cooldown between batches", "scalinggroup", req.ScalingGroupName, "remaining", remaining.Round(time.Second))
+			return false, nil
+		}
+	}
+
 	var terminateTargets []string
 	if req.MaxUnavailable <= len(req.UpdateTargets) {
 		terminateTargets = req.UpdateTargets[:req.MaxUnavailable]
@@ -78,11 +98,23 @@ func ProcessRollingUpgradeStrategy(req *RollingUpdateRequest) (bool, error) {
 		terminateTargets = req.UpdateTargets
 	}
 
+	if req.Drain != nil && req.Drain.IsEnabled() {
+		drained, err := DrainInstances(req.Kubernetes, req.ClusterNodes, terminateTargets, req.Drain)
+		if err != nil {
+			return false, err
+		}
+		if !drained {
+			log.Info("waiting for targets to drain", "scalinggroup", req.ScalingGroupName, "targets", terminateTargets)
+			return false, nil
+		}
+	}
+
 	log.Info("terminating targets", "scalinggroup", req.ScalingGroupName, "targets", terminateTargets)
 	if err := req.AwsWorker.TerminateScalingInstances(terminateTargets); err != nil {
 		// terminate failures are retryable
 		log.Info("failed to terminate targets", "reason", err.Error(), "scalinggroup", req.ScalingGroupName, "targets", terminateTargets)
 		return false, nil
 	}
+	req.Terminated = true
 	return false, nil
 }
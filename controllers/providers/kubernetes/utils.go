@@ -26,6 +26,7 @@ import (
 	"github.com/ghodss/yaml"
 	"github.com/keikoproj/instance-manager/api/v1alpha1"
 	"github.com/keikoproj/instance-manager/controllers/common"
+	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -57,7 +58,7 @@ func IsDesiredNodesReady(nodes *corev1.NodeList, instanceIds []string, desiredCo
 		return false, nil
 	}
 
-	readyInstances := GetReadyNodesByInstance(instanceIds, nodes)
+	readyInstances := GetReadyNodesByInstance(instanceIds, nodes, nil)
 
 	// if discovered nodes match provided instance ids, condition is ready
 	if common.StringSliceEquals(readyInstances, instanceIds) {
@@ -67,27 +68,28 @@ func IsDesiredNodesReady(nodes *corev1.NodeList, instanceIds []string, desiredCo
 	return false, nil
 }
 
-func IsMinNodesReady(nodes *corev1.NodeList, instanceIds []string, minCount int) (bool, error) {
+// IsMinNodesReady returns true if all instances have ready nodes which satisfy the readiness gates
+func IsMinNodesReady(nodes *corev1.NodeList, instanceIds []string, minCount int, gates []v1alpha1.NodeReadinessGate) (bool, error) {
 	// if count of instances in scaling group is not over min, requeue
 	if len(instanceIds) < minCount {
 		return false, nil
 	}
 
-	readyInstances := GetReadyNodesByInstance(instanceIds, nodes)
+	readyInstances := GetReadyNodesByInstance(instanceIds, nodes, gates)
 
-	// if discovered nodes match provided instance ids, condition is ready
-	if common.StringSliceContains(readyInstances, instanceIds) {
+	// every instance must have a ready node, otherwise the previous batch has not completed
+	if common.StringSliceContains(instanceIds, readyInstances) {
 		return true, nil
 	}
 
 	return false, nil
 }
 
-func GetReadyNodesByInstance(instanceIds []string, nodes *corev1.NodeList) []string {
+func GetReadyNodesByInstance(instanceIds []string, nodes *corev1.NodeList, gates []v1alpha1.NodeReadinessGate) []string {
 	readyInstances := make([]string, 0)
 	for _, id := range instanceIds {
 		for _, node := range nodes.Items {
-			if IsNodeReady(node) && common.GetLastElementBy(node.Spec.ProviderID, "/") == id {
+			if IsNodeReady(node) && IsNodeReadinessGatesSatisfied(node, gates) && common.GetLastElementBy(node.Spec.ProviderID, "/") == id {
 				readyInstances = append(readyInstances, id)
 			}
 		}
@@ -104,6 +106,24 @@ func IsNodeReady(n corev1.Node) bool {
 	return false
 }
 
+// IsNodeReadinessGatesSatisfied returns true if the node has every gate's condition with the gate's status, a missing
+// condition does not satisfy a gate
+func IsNodeReadinessGatesSatisfied(n corev1.Node, gates []v1alpha1.NodeReadinessGate) bool {
+	for _, gate := range gates {
+		var satisfied bool
+		for _, condition := range n.Status.Conditions {
+			if condition.Type == gate.Type && condition.Status == gate.Status {
+				satisfied = true
+				break
+			}
+		}
+		if !satisfied {
+			return false
+		}
+	}
+	return true
+}
+
 func AddAnnotation(u *unstructured.Unstructured, key, value string) {
 	annotations := u.GetAnnotations()
 	if annotations == nil {
@@ -180,6 +200,26 @@ func GetKubernetesDynamicClient() (dynamic.Interface, error) {
 	return client, nil
 }
 
+// NewKubernetesClientSet returns the clients of the cluster a kubeconfig refers to
+func NewKubernetesClientSet(kubeconfig []byte) (KubernetesClientSet, error) {
+	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return KubernetesClientSet{}, errors.Wrap(err, "failed to load kubeconfig")
+	}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return KubernetesClientSet{}, errors.Wrap(err, "failed to create kubernetes client")
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return KubernetesClientSet{}, errors.Wrap(err, "failed to create kubernetes dynamic client")
+	}
+	return KubernetesClientSet{
+		Kubernetes:  client,
+		KubeDynamic: dynamicClient,
+	}, nil
+}
+
 func GetKubernetesConfig() (*rest.Config, error) {
 	var config *rest.Config
 	config, err := rest.InClusterConfig()
@@ -0,0 +1,166 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/keikoproj/instance-manager/api/v1alpha1"
+	"github.com/keikoproj/instance-manager/controllers/common"
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	VerificationBatchAnnotationKey = "instancemgr.keikoproj.io/rotation-batch"
+)
+
+// VerificationRequest is the body of the request sent to a verification hook URL
+type VerificationRequest struct {
+	InstanceGroup string `json:"instanceGroup"`
+	Namespace     string `json:"namespace"`
+	ScalingGroup  string `json:"scalingGroup"`
+	Batch         int    `json:"batch"`
+}
+
+// ProcessVerificationHook runs the rolling update verification hook for a rotation batch, it returns true once the
+// verification has succeeded and an error if it failed
+func ProcessVerificationHook(kube kubernetes.Interface, instanceGroup *v1alpha1.InstanceGroup, batch int) (bool, error) {
+	var (
+		hook = instanceGroup.GetUpgradeStrategy().GetRollingUpdateType().GetVerification()
+	)
+
+	if hook == nil {
+		return true, nil
+	}
+
+	if !common.StringEmpty(hook.URL) {
+		return true, CallVerificationURL(hook, instanceGroup, batch)
+	}
+	return ProcessVerificationJob(kube, hook, instanceGroup, batch)
+}
+
+func ProcessVerificationJob(kube kubernetes.Interface, hook *v1alpha1.VerificationHook, instanceGroup *v1alpha1.InstanceGroup, batch int) (bool, error) {
+	var (
+		status    = instanceGroup.GetStatus()
+		namespace = instanceGroup.GetNamespace()
+		lcName    = status.GetActiveLaunchConfigurationName()
+		launchID  = common.GetLastElementBy(lcName, "-")
+	)
+
+	renderParams := struct {
+		InstanceGroup *v1alpha1.InstanceGroup
+		Batch         int
+	}{
+		InstanceGroup: instanceGroup,
+		Batch:         batch,
+	}
+
+	templatedJob, err := RenderCustomResource(hook.Job, renderParams)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to render verification job templating")
+	}
+
+	job := &batchv1.Job{}
+	if err := yaml.Unmarshal([]byte(templatedJob), job); err != nil {
+		return false, errors.Wrap(err, "failed to parse verification job yaml")
+	}
+
+	job.SetNamespace(namespace)
+	job.SetName(GetVerificationJobName(instanceGroup.GetName(), launchID, batch))
+	annotations := job.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[OwnershipAnnotationKey] = OwnershipAnnotationValue
+	annotations[ScopeAnnotationKey] = status.GetActiveScalingGroupName()
+	annotations[VerificationBatchAnnotationKey] = fmt.Sprint(batch)
+	job.SetAnnotations(annotations)
+
+	existingJob, err := kube.BatchV1().Jobs(namespace).Get(job.GetName(), metav1.GetOptions{})
+	if kerr.IsNotFound(err) {
+		log.Info("submitting verification job", "instancegroup", instanceGroup.GetName(), "job", job.GetName(), "batch", batch)
+		if _, err := kube.BatchV1().Jobs(namespace).Create(job); err != nil && !kerr.IsAlreadyExists(err) {
+			return false, errors.Wrap(err, "failed to submit verification job")
+		}
+		return false, nil
+	} else if err != nil {
+		return false, errors.Wrap(err, "failed to get verification job")
+	}
+
+	for _, condition := range existingJob.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			log.Info("verification job succeeded", "instancegroup", instanceGroup.GetName(), "job", job.GetName(), "batch", batch)
+			return true, nil
+		case batchv1.JobFailed:
+			return false, errors.Errorf("verification job %v failed: %v", job.GetName(), condition.Message)
+		}
+	}
+
+	log.Info("verification job still running", "instancegroup", instanceGroup.GetName(), "job", job.GetName(), "batch", batch)
+	return false, nil
+}
+
+func CallVerificationURL(hook *v1alpha1.VerificationHook, instanceGroup *v1alpha1.InstanceGroup, batch int) error {
+	body, err := json.Marshal(VerificationRequest{
+		InstanceGroup: instanceGroup.GetName(),
+		Namespace:     instanceGroup.GetNamespace(),
+		ScalingGroup:  instanceGroup.GetStatus().GetActiveScalingGroupName(),
+		Batch:         batch,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal verification request")
+	}
+
+	timeout := hook.TimeoutSeconds
+	if timeout == 0 {
+		timeout = v1alpha1.DefaultVerificationTimeoutSeconds
+	}
+	client := &http.Client{Timeout: time.Duration(timeout) * time.Second}
+
+	log.Info("calling verification url", "instancegroup", instanceGroup.GetName(), "url", hook.URL, "batch", batch)
+	resp, err := client.Post(hook.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "failed to call verification url")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return errors.Errorf("verification url %v returned status %v", hook.URL, resp.StatusCode)
+	}
+	return nil
+}
+
+func GetVerificationJobName(name, launchID string, batch int) string {
+	jobName := fmt.Sprintf("%v-verify-%v-%v", name, launchID, batch)
+	if len(jobName) > 63 {
+		jobName = fmt.Sprintf("instancemgr-verify-%v-%v", launchID, batch)
+	}
+	return jobName
+}
@@ -0,0 +1,401 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	admissionv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	kerr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	WebhookCACertKey         = "ca.crt"
+	WebhookCAKeyKey          = "ca.key"
+	WebhookPreviousCACertKey = "ca-previous.crt"
+	WebhookCertKey           = "tls.crt"
+	WebhookKeyKey            = "tls.key"
+
+	DefaultWebhookCAValidity         = 10 * 365 * 24 * time.Hour
+	DefaultWebhookCertValidity       = 365 * 24 * time.Hour
+	DefaultWebhookCertRefreshWindow  = 30 * 24 * time.Hour
+	DefaultWebhookCertCheckInterval  = time.Hour
+	webhookCertificateKeySize        = 2048
+	webhookCertificateOrganization   = "instance-manager"
+	webhookCertificateCACommonName   = "instance-manager-webhook-ca"
+	webhookCertificateFilePermission = 0600
+)
+
+// WebhookCertificates generates a self-signed CA and a serving certificate of the webhook service, and rotates the
+// serving certificate before it expires, the certificates are kept in a Secret shared by all replicas, written to
+// the certificate directory of the webhook server, and the CA is patched into the CA bundle of the webhook
+// configurations whose service is the webhook service
+type WebhookCertificates struct {
+	Kubernetes  kubernetes.Interface
+	Namespace   string
+	SecretName  string
+	ServiceName string
+	CertDir     string
+
+	CAValidity    time.Duration
+	CertValidity  time.Duration
+	RefreshWindow time.Duration
+	CheckInterval time.Duration
+}
+
+// Ensure generates or rotates the certificates when needed, writes them to the certificate directory, and patches the
+// CA bundle of the webhook configurations
+func (w *WebhookCertificates) Ensure() error {
+	secrets := w.Kubernetes.CoreV1().Secrets(w.Namespace)
+
+	secret, err := secrets.Get(w.SecretName, metav1.GetOptions{})
+	if err != nil {
+		if !kerr.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to get webhook certificate secret %v/%v", w.Namespace, w.SecretName)
+		}
+		secret = nil
+	}
+
+	var existing map[string][]byte
+	if secret != nil {
+		existing = secret.Data
+	}
+
+	data, changed, err := w.refresh(existing, time.Now())
+	if err != nil {
+		return errors.Wrap(err, "failed to generate webhook certificates")
+	}
+
+	switch {
+	case secret == nil:
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      w.SecretName,
+				Namespace: w.Namespace,
+				Annotations: map[string]string{
+					OwnershipAnnotationKey: OwnershipAnnotationValue,
+				},
+			},
+			Type: corev1.SecretTypeTLS,
+			Data: data,
+		}
+		if _, err := secrets.Create(secret); err != nil {
+			// another replica created the secret first, its certificates are used on the next check
+			return errors.Wrapf(err, "failed to create webhook certificate secret %v/%v", w.Namespace, w.SecretName)
+		}
+		log.Info("generated webhook certificates", "secret", w.SecretName, "namespace", w.Namespace)
+	case changed:
+		secret.Data = data
+		// the update fails with a conflict when another replica rotated the certificates first
+		if _, err := secrets.Update(secret); err != nil {
+			return errors.Wrapf(err, "failed to update webhook certificate secret %v/%v", w.Namespace, w.SecretName)
+		}
+		log.Info("rotated webhook certificates", "secret", w.SecretName, "namespace", w.Namespace)
+	}
+
+	if err := w.writeCertificates(data); err != nil {
+		return err
+	}
+	return w.patchCABundles(caBundle(data))
+}
+
+// Start checks the certificates at every interval until the stop channel is closed
+func (w *WebhookCertificates) Start(stop <-chan struct{}) error {
+	interval := w.CheckInterval
+	if interval <= 0 {
+		interval = DefaultWebhookCertCheckInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+			if err := w.Ensure(); err != nil {
+				log.Error(err, "failed to ensure webhook certificates")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection returns false, every replica serves webhooks and needs the certificates
+func (w *WebhookCertificates) NeedLeaderElection() bool {
+	return false
+}
+
+// DNSNames returns the names of the webhook service which the serving certificate is valid for
+func (w *WebhookCertificates) DNSNames() []string {
+	return []string{
+		w.ServiceName,
+		fmt.Sprintf("%v.%v", w.ServiceName, w.Namespace),
+		fmt.Sprintf("%v.%v.svc", w.ServiceName, w.Namespace),
+		fmt.Sprintf("%v.%v.svc.cluster.local", w.ServiceName, w.Namespace),
+	}
+}
+
+// refresh returns the certificates with a new CA when the CA is missing or expires within the refresh window, and a
+// new serving certificate when it is missing, expires within the refresh window, is not signed by the CA or is not
+// valid for the webhook service. A rotated CA is kept as the previous CA until it expires
+func (w *WebhookCertificates) refresh(existing map[string][]byte, now time.Time) (map[string][]byte, bool, error) {
+	var (
+		refreshWindow = durationOrDefault(w.RefreshWindow, DefaultWebhookCertRefreshWindow)
+		data          = map[string][]byte{}
+		changed       bool
+	)
+	for key, value := range existing {
+		data[key] = value
+	}
+
+	if previous, err := parseCertificatePEM(data[WebhookPreviousCACertKey]); err == nil && now.After(previous.NotAfter) {
+		delete(data, WebhookPreviousCACertKey)
+		changed = true
+	}
+
+	ca, caKey, err := parseCertificate(data[WebhookCACertKey], data[WebhookCAKeyKey])
+	if err != nil || now.Add(refreshWindow).After(ca.NotAfter) {
+		// serving certificates signed by the rotated CA remain trusted until it expires
+		if err == nil && now.Before(ca.NotAfter) {
+			data[WebhookPreviousCACertKey] = data[WebhookCACertKey]
+		} else {
+			delete(data, WebhookPreviousCACertKey)
+		}
+		ca, caKey, data[WebhookCACertKey], data[WebhookCAKeyKey], err = w.generateCA(now)
+		if err != nil {
+			return nil, false, err
+		}
+		changed = true
+	}
+
+	cert, _, err := parseCertificate(data[WebhookCertKey], data[WebhookKeyKey])
+	if changed || err != nil || now.Add(refreshWindow).After(cert.NotAfter) || cert.CheckSignatureFrom(ca) != nil || cert.VerifyHostname(w.DNSNames()[2]) != nil {
+		data[WebhookCertKey], data[WebhookKeyKey], err = w.generateServingCertificate(ca, caKey, now)
+		if err != nil {
+			return nil, false, err
+		}
+		changed = true
+	}
+
+	return data, changed, nil
+}
+
+func (w *WebhookCertificates) generateCA(now time.Time) (*x509.Certificate, *rsa.PrivateKey, []byte, []byte, error) {
+	key, err := rsa.GenerateKey(rand.Reader, webhookCertificateKeySize)
+	if err != nil {
+		return nil, nil, nil, nil, errors.Wrap(err, "failed to generate CA key")
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, nil, nil, errors.Wrap(err, "failed to generate CA serial number")
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName:   webhookCertificateCACommonName,
+			Organization: []string{webhookCertificateOrganization},
+		},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(durationOrDefault(w.CAValidity, DefaultWebhookCAValidity)),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	raw, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, nil, nil, errors.Wrap(err, "failed to create CA certificate")
+	}
+	ca, err := x509.ParseCertificate(raw)
+	if err != nil {
+		return nil, nil, nil, nil, errors.Wrap(err, "failed to parse CA certificate")
+	}
+	return ca, key, encodeCertificate(raw), encodeKey(key), nil
+}
+
+func (w *WebhookCertificates) generateServingCertificate(ca *x509.Certificate, caKey *rsa.PrivateKey, now time.Time) ([]byte, []byte, error) {
+	key, err := rsa.GenerateKey(rand.Reader, webhookCertificateKeySize)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to generate serving key")
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to generate serving certificate serial number")
+	}
+
+	names := w.DNSNames()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName:   names[2],
+			Organization: []string{webhookCertificateOrganization},
+		},
+		DNSNames:    names,
+		NotBefore:   now.Add(-time.Hour),
+		NotAfter:    now.Add(durationOrDefault(w.CertValidity, DefaultWebhookCertValidity)),
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	raw, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to create serving certificate")
+	}
+	return encodeCertificate(raw), encodeKey(key), nil
+}
+
+// writeCertificates writes the serving certificate and key to the certificate directory, files are only replaced
+// when their content changes since the webhook server reloads them when they are written
+func (w *WebhookCertificates) writeCertificates(data map[string][]byte) error {
+	if err := os.MkdirAll(w.CertDir, 0700); err != nil {
+		return errors.Wrapf(err, "failed to create webhook certificate directory %v", w.CertDir)
+	}
+	for _, key := range []string{WebhookKeyKey, WebhookCertKey} {
+		path := filepath.Join(w.CertDir, key)
+		if current, err := ioutil.ReadFile(path); err == nil && bytes.Equal(current, data[key]) {
+			continue
+		}
+		if err := ioutil.WriteFile(path, data[key], webhookCertificateFilePermission); err != nil {
+			return errors.Wrapf(err, "failed to write webhook certificate %v", path)
+		}
+	}
+	return nil
+}
+
+// patchCABundles sets the CA bundle of the webhooks of validating and mutating webhook configurations whose service
+// is the webhook service
+func (w *WebhookCertificates) patchCABundles(caBundle []byte) error {
+	admission := w.Kubernetes.AdmissionregistrationV1beta1()
+
+	validating, err := admission.ValidatingWebhookConfigurations().List(metav1.ListOptions{})
+	if err != nil {
+		return errors.Wrap(err, "failed to list validating webhook configurations")
+	}
+	for i := range validating.Items {
+		configuration := &validating.Items[i]
+		patched := false
+		for j := range configuration.Webhooks {
+			clientConfig := &configuration.Webhooks[j].ClientConfig
+			if w.isWebhookService(clientConfig.Service) && !bytes.Equal(clientConfig.CABundle, caBundle) {
+				clientConfig.CABundle = caBundle
+				patched = true
+			}
+		}
+		if !patched {
+			continue
+		}
+		if _, err := admission.ValidatingWebhookConfigurations().Update(configuration); err != nil {
+			return errors.Wrapf(err, "failed to update CA bundle of validating webhook configuration %v", configuration.GetName())
+		}
+		log.Info("updated webhook CA bundle", "validatingwebhookconfiguration", configuration.GetName())
+	}
+
+	mutating, err := admission.MutatingWebhookConfigurations().List(metav1.ListOptions{})
+	if err != nil {
+		return errors.Wrap(err, "failed to list mutating webhook configurations")
+	}
+	for i := range mutating.Items {
+		configuration := &mutating.Items[i]
+		patched := false
+		for j := range configuration.Webhooks {
+			clientConfig := &configuration.Webhooks[j].ClientConfig
+			if w.isWebhookService(clientConfig.Service) && !bytes.Equal(clientConfig.CABundle, caBundle) {
+				clientConfig.CABundle = caBundle
+				patched = true
+			}
+		}
+		if !patched {
+			continue
+		}
+		if _, err := admission.MutatingWebhookConfigurations().Update(configuration); err != nil {
+			return errors.Wrapf(err, "failed to update CA bundle of mutating webhook configuration %v", configuration.GetName())
+		}
+		log.Info("updated webhook CA bundle", "mutatingwebhookconfiguration", configuration.GetName())
+	}
+	return nil
+}
+
+func (w *WebhookCertificates) isWebhookService(service *admissionv1beta1.ServiceReference) bool {
+	return service != nil && service.Name == w.ServiceName && service.Namespace == w.Namespace
+}
+
+// caBundle returns the CA followed by the previous CA during a CA rotation
+func caBundle(data map[string][]byte) []byte {
+	bundle := append([]byte{}, data[WebhookCACertKey]...)
+	return append(bundle, data[WebhookPreviousCACertKey]...)
+}
+
+func parseCertificatePEM(certPEM []byte) (*x509.Certificate, error) {
+	certBlock, _ := pem.Decode(certPEM)
+	if certBlock == nil {
+		return nil, errors.New("certificate is not PEM encoded")
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse certificate")
+	}
+	return cert, nil
+}
+
+func parseCertificate(certPEM, keyPEM []byte) (*x509.Certificate, *rsa.PrivateKey, error) {
+	cert, err := parseCertificatePEM(certPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyBlock, _ := pem.Decode(keyPEM)
+	if keyBlock == nil {
+		return nil, nil, errors.New("key is not PEM encoded")
+	}
+	key, err := x509.ParsePKCS1PrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to parse key")
+	}
+	return cert, key, nil
+}
+
+func encodeCertificate(raw []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: raw})
+}
+
+func encodeKey(key *rsa.PrivateKey) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+}
+
+func durationOrDefault(d, defaultDuration time.Duration) time.Duration {
+	if d <= 0 {
+		return defaultDuration
+	}
+	return d
+}
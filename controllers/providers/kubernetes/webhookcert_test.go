@@ -0,0 +1,170 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"crypto/x509"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	admissionv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func mockWebhookCertificates() *WebhookCertificates {
+	return &WebhookCertificates{
+		Namespace:     "instance-manager",
+		SecretName:    "instance-manager-webhook-cert",
+		ServiceName:   "instance-manager-webhook-service",
+		CAValidity:    365 * 24 * time.Hour,
+		CertValidity:  90 * 24 * time.Hour,
+		RefreshWindow: 30 * 24 * time.Hour,
+	}
+}
+
+func verifyServingCertificate(g *gomega.GomegaWithT, data map[string][]byte, dnsName string, now time.Time) {
+	cert, _, err := parseCertificate(data[WebhookCertKey], data[WebhookKeyKey])
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	roots := x509.NewCertPool()
+	g.Expect(roots.AppendCertsFromPEM(caBundle(data))).To(gomega.BeTrue())
+	_, err = cert.Verify(x509.VerifyOptions{DNSName: dnsName, Roots: roots, CurrentTime: now})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+}
+
+func TestWebhookCertificatesRefresh(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		w       = mockWebhookCertificates()
+		now     = time.Now()
+		dnsName = "instance-manager-webhook-service.instance-manager.svc"
+	)
+
+	// certificates are generated when the secret is empty
+	data, changed, err := w.refresh(nil, now)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(changed).To(gomega.BeTrue())
+	g.Expect(data).To(gomega.HaveKey(WebhookCACertKey))
+	g.Expect(data).NotTo(gomega.HaveKey(WebhookPreviousCACertKey))
+	verifyServingCertificate(g, data, dnsName, now)
+
+	// valid certificates are kept
+	refreshed, changed, err := w.refresh(data, now.Add(24*time.Hour))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(changed).To(gomega.BeFalse())
+	g.Expect(refreshed).To(gomega.Equal(data))
+
+	// the serving certificate is rotated within the refresh window of its expiry, the CA is kept
+	later := now.Add(61 * 24 * time.Hour)
+	refreshed, changed, err = w.refresh(data, later)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(changed).To(gomega.BeTrue())
+	g.Expect(refreshed[WebhookCACertKey]).To(gomega.Equal(data[WebhookCACertKey]))
+	g.Expect(refreshed[WebhookCertKey]).NotTo(gomega.Equal(data[WebhookCertKey]))
+	verifyServingCertificate(g, refreshed, dnsName, later)
+
+	// the serving certificate is rotated when it is not valid for the webhook service
+	w.ServiceName = "other-webhook-service"
+	refreshed, changed, err = w.refresh(data, now)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(changed).To(gomega.BeTrue())
+	g.Expect(refreshed[WebhookCACertKey]).To(gomega.Equal(data[WebhookCACertKey]))
+	verifyServingCertificate(g, refreshed, "other-webhook-service.instance-manager.svc", now)
+	w.ServiceName = "instance-manager-webhook-service"
+}
+
+func TestWebhookCertificatesRefreshCARotation(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		w       = mockWebhookCertificates()
+		now     = time.Now()
+		dnsName = "instance-manager-webhook-service.instance-manager.svc"
+	)
+
+	data, _, err := w.refresh(nil, now)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	// the serving certificate was last rotated shortly before the CA expires
+	data, _, err = w.refresh(data, now.Add(330*24*time.Hour))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	// the CA is rotated within the refresh window of its expiry, the previous CA is kept in the bundle so that
+	// certificates signed by it remain trusted
+	rotation := now.Add(340 * 24 * time.Hour)
+	rotated, changed, err := w.refresh(data, rotation)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(changed).To(gomega.BeTrue())
+	g.Expect(rotated[WebhookCACertKey]).NotTo(gomega.Equal(data[WebhookCACertKey]))
+	g.Expect(rotated[WebhookPreviousCACertKey]).To(gomega.Equal(data[WebhookCACertKey]))
+	verifyServingCertificate(g, rotated, dnsName, rotation)
+	verifyServingCertificate(g, map[string][]byte{
+		WebhookCACertKey:         rotated[WebhookCACertKey],
+		WebhookPreviousCACertKey: rotated[WebhookPreviousCACertKey],
+		WebhookCertKey:           data[WebhookCertKey],
+		WebhookKeyKey:            data[WebhookKeyKey],
+	}, dnsName, rotation)
+
+	// the previous CA is removed once it expires
+	expired := now.Add(366 * 24 * time.Hour)
+	refreshed, changed, err := w.refresh(rotated, expired)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(changed).To(gomega.BeTrue())
+	g.Expect(refreshed).NotTo(gomega.HaveKey(WebhookPreviousCACertKey))
+	g.Expect(refreshed[WebhookCACertKey]).To(gomega.Equal(rotated[WebhookCACertKey]))
+}
+
+func TestWebhookCertificatesEnsure(t *testing.T) {
+	var (
+		g    = gomega.NewGomegaWithT(t)
+		w    = mockWebhookCertificates()
+		kube = fake.NewSimpleClientset()
+	)
+
+	dir, err := ioutil.TempDir("", "webhook-certs")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	defer os.RemoveAll(dir)
+	w.Kubernetes = kube
+	w.CertDir = dir
+
+	_, err = kube.AdmissionregistrationV1beta1().ValidatingWebhookConfigurations().Create(&admissionv1beta1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "instance-manager"},
+		Webhooks: []admissionv1beta1.ValidatingWebhook{
+			{
+				Name: "instancegroups.instancemgr.keikoproj.io",
+				ClientConfig: admissionv1beta1.WebhookClientConfig{
+					Service: &admissionv1beta1.ServiceReference{Name: w.ServiceName, Namespace: w.Namespace},
+				},
+			},
+		},
+	})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	err = w.Ensure()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	secret, err := kube.CoreV1().Secrets(w.Namespace).Get(w.SecretName, metav1.GetOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	configuration, err := kube.AdmissionregistrationV1beta1().ValidatingWebhookConfigurations().Get("instance-manager", metav1.GetOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(configuration.Webhooks[0].ClientConfig.CABundle).To(gomega.Equal(caBundle(secret.Data)))
+
+	cert, err := ioutil.ReadFile(filepath.Join(dir, WebhookCertKey))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(cert).To(gomega.Equal(secret.Data[WebhookCertKey]))
+}
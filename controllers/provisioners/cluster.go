@@ -0,0 +1,62 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioners
+
+import (
+	"github.com/keikoproj/instance-manager/api/v1alpha1"
+	"github.com/keikoproj/instance-manager/controllers/common"
+)
+
+// ApplyClusterConfiguration sets the cluster-level settings of a ClusterConfiguration on an instance group, the
+// cluster name, subnets and bootstrap settings are used when the instance group does not set them, and the security
+// groups are added to those of the instance group
+func ApplyClusterConfiguration(instanceGroup *v1alpha1.InstanceGroup, cluster *v1alpha1.ClusterConfiguration) {
+	if instanceGroup.Spec.EKSSpec == nil || instanceGroup.GetEKSConfiguration() == nil {
+		return
+	}
+
+	var (
+		configuration = instanceGroup.GetEKSConfiguration()
+		spec          = cluster.Spec
+	)
+
+	if configuration.GetClusterName() == "" {
+		configuration.SetClusterName(spec.ClusterName)
+	}
+
+	if common.SliceEmpty(configuration.Subnets) {
+		configuration.Subnets = append([]string{}, spec.Subnets...)
+	}
+
+	securityGroups := append([]string{}, spec.SecurityGroups...)
+	for _, group := range configuration.NodeSecurityGroups {
+		if !common.ContainsString(securityGroups, group) {
+			securityGroups = append(securityGroups, group)
+		}
+	}
+	if len(securityGroups) > 0 {
+		configuration.NodeSecurityGroups = securityGroups
+	}
+
+	if bootstrap := spec.Bootstrap; bootstrap != nil {
+		if configuration.GetAPIServerEndpoint() == "" {
+			configuration.SetAPIServerEndpoint(bootstrap.APIServerEndpoint)
+		}
+		if configuration.GetCertificateAuthority() == "" {
+			configuration.SetCertificateAuthority(bootstrap.CertificateAuthority)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioners
+
+import (
+	"testing"
+
+	"github.com/keikoproj/instance-manager/api/v1alpha1"
+	"github.com/onsi/gomega"
+)
+
+func TestApplyClusterConfiguration(t *testing.T) {
+	var (
+		g = gomega.NewGomegaWithT(t)
+	)
+
+	cluster := &v1alpha1.ClusterConfiguration{
+		Spec: v1alpha1.ClusterConfigurationSpec{
+			ClusterName:    "some-cluster",
+			Subnets:        []string{"subnet-1234", "subnet-2345"},
+			SecurityGroups: []string{"sg-123456789012"},
+			Bootstrap: &v1alpha1.ClusterBootstrapSpec{
+				APIServerEndpoint:    "https://ABCDEF.gr7.us-west-2.eks.amazonaws.com",
+				CertificateAuthority: "LS0tLS1CRUdJTg==",
+			},
+		},
+	}
+
+	// cluster settings are used when the instance group does not set them
+	cr := MockResource()
+	cr.Spec.EKSSpec.EKSConfiguration.NodeSecurityGroups = []string{"sg-000000000000", "sg-123456789012"}
+	ApplyClusterConfiguration(cr, cluster)
+
+	config := cr.GetEKSConfiguration()
+	g.Expect(config.GetClusterName()).To(gomega.Equal("some-cluster"))
+	g.Expect(config.GetSubnets()).To(gomega.Equal([]string{"subnet-1234", "subnet-2345"}))
+	g.Expect(config.GetSecurityGroups()).To(gomega.Equal([]string{"sg-123456789012", "sg-000000000000"}))
+	g.Expect(config.GetAPIServerEndpoint()).To(gomega.Equal("https://ABCDEF.gr7.us-west-2.eks.amazonaws.com"))
+	g.Expect(config.GetCertificateAuthority()).To(gomega.Equal("LS0tLS1CRUdJTg=="))
+
+	// settings of the instance group take precedence
+	cr = MockResource()
+	cr.Spec.EKSSpec.EKSConfiguration.EksClusterName = "other-cluster"
+	cr.Spec.EKSSpec.EKSConfiguration.Subnets = []string{"subnet-3456"}
+	ApplyClusterConfiguration(cr, cluster)
+
+	config = cr.GetEKSConfiguration()
+	g.Expect(config.GetClusterName()).To(gomega.Equal("other-cluster"))
+	g.Expect(config.GetSubnets()).To(gomega.Equal([]string{"subnet-3456"}))
+	g.Expect(config.GetSecurityGroups()).To(gomega.Equal([]string{"sg-123456789012"}))
+
+	// the cluster configuration is not changed
+	g.Expect(cluster.Spec.Subnets).To(gomega.Equal([]string{"subnet-1234", "subnet-2345"}))
+
+	// instance groups of other provisioners are ignored
+	ApplyClusterConfiguration(&v1alpha1.InstanceGroup{}, cluster)
+}
@@ -17,6 +17,7 @@ package provisioners
 
 import (
 	"fmt"
+	"reflect"
 	"strings"
 
 	"github.com/ghodss/yaml"
@@ -26,6 +27,7 @@ import (
 
 	"github.com/keikoproj/instance-manager/api/v1alpha1"
 	"github.com/keikoproj/instance-manager/controllers/common"
+	awsprovider "github.com/keikoproj/instance-manager/controllers/providers/aws"
 	corev1 "k8s.io/api/core/v1"
 )
 
@@ -47,6 +49,8 @@ type ProvisionerConfiguration struct {
 	Boundaries    ResourceFieldBoundary
 	Defaults      map[string]interface{}
 	InstanceGroup *v1alpha1.InstanceGroup
+	// NamespaceRoles are the IAM roles assumed for the reconciles of instance groups in a namespace, keyed by namespace
+	NamespaceRoles map[string]awsprovider.NamespaceRole
 }
 
 func NewProvisionerConfiguration(config *corev1.ConfigMap, instanceGroup *v1alpha1.InstanceGroup) (*ProvisionerConfiguration, error) {
@@ -68,12 +72,14 @@ type SharedBoundaries struct {
 type ResourceFieldBoundary struct {
 	Restricted []string         `yaml:"restricted,omitempty"`
 	Shared     SharedBoundaries `yaml:"shared,omitempty"`
+	Policies   []FieldPolicy    `yaml:"policies,omitempty"`
 }
 
 func (c *ProvisionerConfiguration) Unmarshal(cm *corev1.ConfigMap) error {
 	var (
-		boundariesPath = common.FieldPath("data.boundaries")
-		defaultsPath   = common.FieldPath("data.defaults")
+		boundariesPath     = common.FieldPath("data.boundaries")
+		defaultsPath       = common.FieldPath("data.defaults")
+		namespaceRolesPath = common.FieldPath("data.namespaceRoles")
 	)
 
 	config, err := runtime.DefaultUnstructuredConverter.ToUnstructured(cm)
@@ -87,6 +93,11 @@ func (c *ProvisionerConfiguration) Unmarshal(cm *corev1.ConfigMap) error {
 		if err != nil {
 			return errors.Wrap(err, "failed to unmarshal boundaries")
 		}
+		for _, policy := range boundaryConfig.Policies {
+			if err := policy.Validate(); err != nil {
+				return errors.Wrap(err, "invalid boundaries policy")
+			}
+		}
 		c.Boundaries = *boundaryConfig
 	}
 
@@ -103,9 +114,77 @@ func (c *ProvisionerConfiguration) Unmarshal(cm *corev1.ConfigMap) error {
 		}
 	}
 
+	if namespaceRoles, ok, _ := unstructured.NestedString(config, namespaceRolesPath...); ok {
+		roles := map[string]awsprovider.NamespaceRole{}
+		err := yaml.Unmarshal([]byte(namespaceRoles), &roles)
+		if err != nil {
+			return errors.Wrap(err, "failed to unmarshal namespace roles")
+		}
+		c.NamespaceRoles = roles
+	}
+
 	return nil
 }
 
+// GetNamespaceRole returns the IAM role assumed for the reconciles of instance groups in a namespace
+func (c *ProvisionerConfiguration) GetNamespaceRole(namespace string) (awsprovider.NamespaceRole, bool) {
+	role, ok := c.NamespaceRoles[namespace]
+	return role, ok
+}
+
+// EffectiveConfigurationChanged returns true if the configuration which an instance group is reconciled with differs
+// between two versions of the controller configmap, either because the defaults and boundaries change its spec, or
+// because the outcome of its policies or its namespace role change
+func EffectiveConfigurationChanged(previous, current *corev1.ConfigMap, instanceGroup *v1alpha1.InstanceGroup) bool {
+	previousConfig, err := effectiveConfiguration(previous, instanceGroup)
+	if err != nil {
+		return true
+	}
+	currentConfig, err := effectiveConfiguration(current, instanceGroup)
+	if err != nil {
+		// instance groups are reconciled so that the configuration error is reported
+		return true
+	}
+
+	if !reflect.DeepEqual(previousConfig.InstanceGroup.Spec, currentConfig.InstanceGroup.Spec) {
+		return true
+	}
+
+	if errorString(previousConfig.ValidatePolicies()) != errorString(currentConfig.ValidatePolicies()) {
+		return true
+	}
+
+	previousRole, _ := previousConfig.GetNamespaceRole(instanceGroup.GetNamespace())
+	currentRole, _ := currentConfig.GetNamespaceRole(instanceGroup.GetNamespace())
+	return !reflect.DeepEqual(previousRole, currentRole)
+}
+
+// effectiveConfiguration returns the configuration of an instance group with the defaults of a configmap, like at
+// reconcile time, an empty configmap leaves the instance group unchanged
+func effectiveConfiguration(cm *corev1.ConfigMap, instanceGroup *v1alpha1.InstanceGroup) (*ProvisionerConfiguration, error) {
+	if cm == nil {
+		cm = &corev1.ConfigMap{}
+	}
+	config, err := NewProvisionerConfiguration(cm, instanceGroup)
+	if err != nil {
+		return nil, err
+	}
+	if reflect.DeepEqual(cm, &corev1.ConfigMap{}) {
+		return config, nil
+	}
+	if err := config.SetDefaults(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
 func (c *ProvisionerConfiguration) SetDefaults() error {
 	unstructuredInstanceGroup, err := runtime.DefaultUnstructuredConverter.ToUnstructured(c.InstanceGroup)
 	if err != nil {
@@ -16,6 +16,7 @@ limitations under the License.
 package provisioners
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/ghodss/yaml"
@@ -535,6 +536,94 @@ spec:
 	g.Expect(c.Defaults).To(gomega.Equal(expectedDefaults))
 }
 
+func TestUnmarshalNamespaceRoles(t *testing.T) {
+	var (
+		g = gomega.NewGomegaWithT(t)
+	)
+
+	mockNamespaceRoles := `
+team-a:
+  roleArn: arn:aws:iam::111122223333:role/team-a
+  externalId: team-a
+  sessionTags:
+    team: a
+team-b:
+  roleArn: arn:aws:iam::111122223333:role/team-b`
+
+	cm := MockConfigMap(MockConfigData("namespaceRoles", mockNamespaceRoles))
+	c, err := NewProvisionerConfiguration(cm, &v1alpha1.InstanceGroup{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	role, ok := c.GetNamespaceRole("team-a")
+	g.Expect(ok).To(gomega.BeTrue())
+	g.Expect(role.RoleArn).To(gomega.Equal("arn:aws:iam::111122223333:role/team-a"))
+	g.Expect(role.ExternalID).To(gomega.Equal("team-a"))
+	g.Expect(role.SessionTags).To(gomega.Equal(map[string]string{"team": "a"}))
+
+	role, ok = c.GetNamespaceRole("team-b")
+	g.Expect(ok).To(gomega.BeTrue())
+	g.Expect(role.Validate()).To(gomega.Succeed())
+
+	_, ok = c.GetNamespaceRole("default")
+	g.Expect(ok).To(gomega.BeFalse())
+
+	cm = MockConfigMap(MockConfigData("namespaceRoles", "team-a: [invalid"))
+	_, err = NewProvisionerConfiguration(cm, &v1alpha1.InstanceGroup{})
+	g.Expect(err).To(gomega.HaveOccurred())
+}
+
+func TestEffectiveConfigurationChanged(t *testing.T) {
+	var (
+		g  = gomega.NewGomegaWithT(t)
+		cr = MockResource()
+	)
+
+	// the instance type is denied by the policies of the namespace
+	cr.SetNamespace("team-a")
+	cr.Spec.EKSSpec.EKSConfiguration.InstanceType = "p3.2xlarge"
+
+	mockDefaults := `
+spec:
+  eks:
+    configuration:
+      image: %v`
+
+	mockBoundaries := `
+restricted:
+- spec.eks.configuration.image`
+
+	var (
+		empty          = MockConfigMap(MockConfigData())
+		defaults       = MockConfigMap(MockConfigData("boundaries", mockBoundaries, "defaults", fmt.Sprintf(mockDefaults, "ami-123456789012")))
+		changedDefault = MockConfigMap(MockConfigData("boundaries", mockBoundaries, "defaults", fmt.Sprintf(mockDefaults, "ami-210987654321")))
+		otherNamespace = MockConfigMap(MockConfigData("boundaries", mockBoundaries, "defaults", fmt.Sprintf(mockDefaults, "ami-123456789012"), "namespaceRoles", "team-b:\n  roleArn: arn:aws:iam::111122223333:role/team-b"))
+		namespaceRole  = MockConfigMap(MockConfigData("boundaries", mockBoundaries, "defaults", fmt.Sprintf(mockDefaults, "ami-123456789012"), "namespaceRoles", "team-a:\n  roleArn: arn:aws:iam::111122223333:role/team-a"))
+		policy         = MockConfigMap(MockConfigData("boundaries", mockPolicyBoundaries))
+	)
+
+	tests := []struct {
+		previous *corev1.ConfigMap
+		current  *corev1.ConfigMap
+		expected bool
+	}{
+		{previous: empty, current: empty, expected: false},
+		{previous: nil, current: empty, expected: false},
+		{previous: empty, current: defaults, expected: true},
+		{previous: defaults, current: defaults, expected: false},
+		{previous: defaults, current: changedDefault, expected: true},
+		{previous: defaults, current: otherNamespace, expected: false},
+		{previous: defaults, current: namespaceRole, expected: true},
+		{previous: empty, current: policy, expected: true},
+		{previous: policy, current: empty, expected: true},
+		{previous: empty, current: MockConfigMap(MockConfigData("defaults", "spec: [invalid")), expected: true},
+	}
+
+	for i, tc := range tests {
+		t.Logf("Test #%v - %+v", i, tc)
+		g.Expect(EffectiveConfigurationChanged(tc.previous, tc.current, cr)).To(gomega.Equal(tc.expected))
+	}
+}
+
 func TestIsRetryable(t *testing.T) {
 	var (
 		g  = gomega.NewGomegaWithT(t)
@@ -0,0 +1,189 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eks
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/keikoproj/instance-manager/api/v1alpha1"
+	"github.com/keikoproj/instance-manager/controllers/common"
+	awsprovider "github.com/keikoproj/instance-manager/controllers/providers/aws"
+	"github.com/pkg/errors"
+)
+
+// AlarmNamePrefix returns the prefix of the names of the alarms of the instance group
+func (ctx *EksInstanceGroupContext) AlarmNamePrefix() string {
+	return fmt.Sprintf("%v-alarm-", ctx.ResourcePrefix)
+}
+
+// GetAlarmMetrics returns the group metrics which alarms are configured on, they are collected even when they are
+// not configured in metricsCollection
+func (ctx *EksInstanceGroupContext) GetAlarmMetrics() []string {
+	var (
+		instanceGroup = ctx.GetInstanceGroup()
+		configuration = instanceGroup.GetEKSConfiguration()
+		metrics       = make([]string, 0)
+	)
+
+	for _, alarm := range configuration.GetAlarms() {
+		if !common.ContainsString(metrics, alarm.Metric) {
+			metrics = append(metrics, alarm.Metric)
+		}
+	}
+	return metrics
+}
+
+// GetAlarmInput returns the configuration of an alarm on a metric of a scaling group, the threshold defaults to the
+// min size of the instance group
+func (ctx *EksInstanceGroupContext) GetAlarmInput(alarm v1alpha1.AlarmSpec, asgName string) *cloudwatch.PutMetricAlarmInput {
+	var (
+		instanceGroup = ctx.GetInstanceGroup()
+		spec          = instanceGroup.GetEKSSpec()
+		threshold     = spec.GetMinSize()
+	)
+
+	if alarm.Threshold != nil {
+		threshold = *alarm.Threshold
+	}
+
+	return &cloudwatch.PutMetricAlarmInput{
+		AlarmName:          aws.String(ctx.AlarmNamePrefix() + alarm.Name),
+		AlarmDescription:   aws.String(fmt.Sprintf("%v of instance group %v", alarm.Name, instanceGroup.NamespacedName())),
+		Namespace:          aws.String(awsprovider.AutoScalingMetricNamespace),
+		MetricName:         aws.String(alarm.Metric),
+		Statistic:          aws.String(alarm.Statistic),
+		ComparisonOperator: aws.String(alarm.ComparisonOperator),
+		Threshold:          aws.Float64(float64(threshold)),
+		Period:             aws.Int64(awsprovider.AlarmPeriod),
+		EvaluationPeriods:  aws.Int64(alarm.EvaluationPeriods),
+		AlarmActions:       aws.StringSlice(alarm.Actions),
+		Dimensions: []*cloudwatch.Dimension{
+			{
+				Name:  aws.String("AutoScalingGroupName"),
+				Value: aws.String(asgName),
+			},
+		},
+	}
+}
+
+// IsAlarmDrifted returns true if an existing alarm is configured differently than its desired configuration
+func IsAlarmDrifted(alarm *cloudwatch.MetricAlarm, input *cloudwatch.PutMetricAlarmInput) bool {
+	if aws.StringValue(alarm.MetricName) != aws.StringValue(input.MetricName) ||
+		aws.StringValue(alarm.Namespace) != aws.StringValue(input.Namespace) ||
+		aws.StringValue(alarm.Statistic) != aws.StringValue(input.Statistic) ||
+		aws.StringValue(alarm.ComparisonOperator) != aws.StringValue(input.ComparisonOperator) ||
+		aws.Float64Value(alarm.Threshold) != aws.Float64Value(input.Threshold) ||
+		aws.Int64Value(alarm.Period) != aws.Int64Value(input.Period) ||
+		aws.Int64Value(alarm.EvaluationPeriods) != aws.Int64Value(input.EvaluationPeriods) {
+		return true
+	}
+	if !common.StringSliceEquals(aws.StringValueSlice(alarm.AlarmActions), aws.StringValueSlice(input.AlarmActions)) {
+		return true
+	}
+	if len(alarm.Dimensions) != len(input.Dimensions) {
+		return true
+	}
+	for i, d := range alarm.Dimensions {
+		if aws.StringValue(d.Name) != aws.StringValue(input.Dimensions[i].Name) || aws.StringValue(d.Value) != aws.StringValue(input.Dimensions[i].Value) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetAlarmChanges returns the alarms which do not exist or are drifted, and the names of the alarms which are no
+// longer configured
+func (ctx *EksInstanceGroupContext) GetAlarmChanges(asgName string) ([]*cloudwatch.PutMetricAlarmInput, []string) {
+	var (
+		instanceGroup = ctx.GetInstanceGroup()
+		configuration = instanceGroup.GetEKSConfiguration()
+		state         = ctx.GetDiscoveredState()
+		existing      = make(map[string]*cloudwatch.MetricAlarm)
+		desired       = make([]string, 0)
+		put           = make([]*cloudwatch.PutMetricAlarmInput, 0)
+		remove        = make([]string, 0)
+	)
+
+	for _, alarm := range state.Alarms {
+		existing[aws.StringValue(alarm.AlarmName)] = alarm
+	}
+
+	for _, alarm := range configuration.GetAlarms() {
+		input := ctx.GetAlarmInput(alarm, asgName)
+		name := aws.StringValue(input.AlarmName)
+		desired = append(desired, name)
+		if e, ok := existing[name]; !ok || IsAlarmDrifted(e, input) {
+			put = append(put, input)
+		}
+	}
+
+	for name := range existing {
+		if !common.ContainsString(desired, name) {
+			remove = append(remove, name)
+		}
+	}
+	sort.Strings(remove)
+	return put, remove
+}
+
+// UpdateAlarms creates or updates the configured alarms of the scaling group, and deletes the alarms which are no
+// longer configured
+func (ctx *EksInstanceGroupContext) UpdateAlarms(asgName string) error {
+	var (
+		instanceGroup = ctx.GetInstanceGroup()
+		put, remove   = ctx.GetAlarmChanges(asgName)
+	)
+
+	if len(remove) > 0 {
+		if err := ctx.AwsWorker.DeleteAlarms(remove); err != nil {
+			return errors.Wrapf(err, "failed to delete alarms %v", remove)
+		}
+		ctx.Log.Info("deleted alarms", "instancegroup", instanceGroup.GetName(), "alarms", remove)
+	}
+
+	for _, input := range put {
+		if err := ctx.AwsWorker.PutMetricAlarm(input); err != nil {
+			return errors.Wrapf(err, "failed to put alarm %v", aws.StringValue(input.AlarmName))
+		}
+		ctx.Log.Info("updated alarm", "instancegroup", instanceGroup.GetName(), "alarm", aws.StringValue(input.AlarmName))
+	}
+	return nil
+}
+
+// DeleteAlarms deletes all alarms of the instance group
+func (ctx *EksInstanceGroupContext) DeleteAlarms() error {
+	var (
+		instanceGroup = ctx.GetInstanceGroup()
+		state         = ctx.GetDiscoveredState()
+		names         = make([]string, 0)
+	)
+
+	for _, alarm := range state.Alarms {
+		names = append(names, aws.StringValue(alarm.AlarmName))
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	if err := ctx.AwsWorker.DeleteAlarms(names); err != nil {
+		return errors.Wrapf(err, "failed to delete alarms %v", names)
+	}
+	ctx.Log.Info("deleted alarms", "instancegroup", instanceGroup.GetName(), "alarms", names)
+	return nil
+}
@@ -0,0 +1,132 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eks
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/keikoproj/instance-manager/api/v1alpha1"
+	"github.com/onsi/gomega"
+)
+
+func TestUpdateAlarms(t *testing.T) {
+	var (
+		g             = gomega.NewGomegaWithT(t)
+		k             = MockKubernetesClientSet()
+		ig            = MockInstanceGroup()
+		configuration = ig.GetEKSConfiguration()
+		asgMock       = NewAutoScalingMocker()
+		iamMock       = NewIamMocker()
+		eksMock       = NewEksMocker()
+		ec2Mock       = NewEc2Mocker()
+		cwMock        = &MockCloudWatchClient{}
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	w.CloudWatchClient = cwMock
+	ctx := MockContext(ig, k, w)
+	ctx.ResourcePrefix = "my-cluster-instance-group-test-instance-group-1"
+	ig.GetEKSSpec().MinSize = 3
+
+	inService := v1alpha1.AlarmSpec{
+		Name:               "in-service",
+		Metric:             "GroupInServiceInstances",
+		ComparisonOperator: "LessThanThreshold",
+		Statistic:          "Average",
+		EvaluationPeriods:  5,
+		Actions:            []string{"arn:aws:sns:us-west-2:123456789012:alerts"},
+	}
+	pending := v1alpha1.AlarmSpec{
+		Name:               "pending",
+		Metric:             "GroupPendingInstances",
+		ComparisonOperator: "GreaterThanThreshold",
+		Threshold:          aws.Int64(0),
+		Statistic:          "Minimum",
+		EvaluationPeriods:  15,
+	}
+
+	existing := func(alarm v1alpha1.AlarmSpec, asgName string) *cloudwatch.MetricAlarm {
+		input := ctx.GetAlarmInput(alarm, asgName)
+		return &cloudwatch.MetricAlarm{
+			AlarmName:          input.AlarmName,
+			Namespace:          input.Namespace,
+			MetricName:         input.MetricName,
+			Statistic:          input.Statistic,
+			ComparisonOperator: input.ComparisonOperator,
+			Threshold:          input.Threshold,
+			Period:             input.Period,
+			EvaluationPeriods:  input.EvaluationPeriods,
+			AlarmActions:       input.AlarmActions,
+			Dimensions:         input.Dimensions,
+		}
+	}
+	stale := &cloudwatch.MetricAlarm{AlarmName: aws.String(ctx.AlarmNamePrefix() + "stale")}
+
+	// the threshold defaults to the min size
+	g.Expect(aws.Float64Value(ctx.GetAlarmInput(inService, "my-asg").Threshold)).To(gomega.Equal(float64(3)))
+	g.Expect(aws.StringValue(ctx.GetAlarmInput(inService, "my-asg").AlarmName)).To(gomega.Equal("my-cluster-instance-group-test-instance-group-1-alarm-in-service"))
+
+	tests := []struct {
+		existing        []*cloudwatch.MetricAlarm
+		desired         []v1alpha1.AlarmSpec
+		expectedPut     []string
+		expectedDeleted []string
+	}{
+		{expectedPut: []string{}, expectedDeleted: []string{}},
+		{desired: []v1alpha1.AlarmSpec{inService, pending}, expectedPut: []string{"in-service", "pending"}, expectedDeleted: []string{}},
+		{existing: []*cloudwatch.MetricAlarm{existing(inService, "my-asg"), existing(pending, "my-asg")}, desired: []v1alpha1.AlarmSpec{inService, pending}, expectedPut: []string{}, expectedDeleted: []string{}},
+		{existing: []*cloudwatch.MetricAlarm{existing(inService, "my-old-asg"), existing(pending, "my-asg")}, desired: []v1alpha1.AlarmSpec{inService, pending}, expectedPut: []string{"in-service"}, expectedDeleted: []string{}},
+		{existing: []*cloudwatch.MetricAlarm{existing(inService, "my-asg"), stale}, desired: []v1alpha1.AlarmSpec{inService}, expectedPut: []string{}, expectedDeleted: []string{"stale"}},
+	}
+
+	for i, tc := range tests {
+		t.Logf("Test #%v - %+v", i, tc)
+		cwMock.PutAlarms = nil
+		cwMock.DeletedAlarms = []string{}
+		ctx.SetDiscoveredState(&DiscoveredState{Alarms: tc.existing})
+		configuration.SetAlarms(tc.desired)
+
+		err := ctx.UpdateAlarms("my-asg")
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+
+		put := make([]string, 0)
+		for _, input := range cwMock.PutAlarms {
+			put = append(put, aws.StringValue(input.AlarmName)[len(ctx.AlarmNamePrefix()):])
+		}
+		deleted := make([]string, 0)
+		for _, name := range cwMock.DeletedAlarms {
+			deleted = append(deleted, name[len(ctx.AlarmNamePrefix()):])
+		}
+		g.Expect(put).To(gomega.Equal(tc.expectedPut))
+		g.Expect(deleted).To(gomega.Equal(tc.expectedDeleted))
+	}
+
+	// the metrics of alarms are collected
+	ctx.SetDiscoveredState(&DiscoveredState{ScalingGroup: MockScalingGroup("my-asg")})
+	configuration.SetMetricsCollection([]string{"GroupMinSize"})
+	configuration.SetAlarms([]v1alpha1.AlarmSpec{inService})
+	enable, ok := ctx.GetEnabledMetrics()
+	g.Expect(ok).To(gomega.BeTrue())
+	g.Expect(enable).To(gomega.ConsistOf("GroupMinSize", "GroupInServiceInstances"))
+
+	// all alarms are deleted with the instance group
+	cwMock.DeletedAlarms = []string{}
+	ctx.SetDiscoveredState(&DiscoveredState{Alarms: []*cloudwatch.MetricAlarm{existing(inService, "my-asg"), stale}})
+	g.Expect(ctx.DeleteAlarms()).To(gomega.Succeed())
+	g.Expect(cwMock.DeletedAlarms).To(gomega.HaveLen(2))
+}
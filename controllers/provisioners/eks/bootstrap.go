@@ -0,0 +1,251 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eks
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/keikoproj/instance-manager/api/v1alpha1"
+	"github.com/pkg/errors"
+)
+
+// BootstrapProvider renders the user data which bootstraps the nodes of an instance group, providers are selected by
+// the bootstrapProvider of the instance group so that other operating systems can be bootstrapped without changes to
+// the provisioner
+type BootstrapProvider interface {
+	// UserData returns the user data of nodes, before it is compressed or offloaded to the bootstrap bucket
+	UserData(instanceGroup *v1alpha1.InstanceGroup, data EKSUserData) ([]byte, error)
+}
+
+var bootstrapProviders = map[string]BootstrapProvider{
+	v1alpha1.EKSBootstrapProvider:            templateBootstrapProvider{template: EKSBootstrapTemplate},
+	v1alpha1.BottlerocketBootstrapProvider:   templateBootstrapProvider{template: BottlerocketBootstrapTemplate, requiresCluster: true},
+	v1alpha1.NodeadmBootstrapProvider:        templateBootstrapProvider{template: NodeadmBootstrapTemplate, requiresCluster: true},
+	v1alpha1.CustomTemplateBootstrapProvider: customTemplateBootstrapProvider{},
+}
+
+// RegisterBootstrapProvider adds a bootstrap provider, it must be called before the controller is started
+func RegisterBootstrapProvider(name string, provider BootstrapProvider) {
+	bootstrapProviders[strings.ToLower(name)] = provider
+	v1alpha1.RegisterBootstrapProvider(name)
+}
+
+// GetBootstrapProvider returns a bootstrap provider by name
+func GetBootstrapProvider(name string) (BootstrapProvider, bool) {
+	provider, ok := bootstrapProviders[strings.ToLower(name)]
+	return provider, ok
+}
+
+const (
+	// EKSBootstrapTemplate runs the bootstrap script of the EKS optimized Amazon Linux 2 images
+	EKSBootstrapTemplate = `#!/bin/bash
+{{- if .SecretsHash}}
+# secrets-hash: {{ .SecretsHash }}
+{{- end}}
+{{- if .RotationCounter}}
+# rotation: {{ .RotationCounter }}
+{{- end}}
+{{range $pre := .PreBootstrap}}{{$pre}}{{end}}
+{{- if eq .InstanceStorePolicy "RAID0"}}
+devices=$(ls /dev/disk/by-id/nvme-Amazon_EC2_NVMe_Instance_Storage_* 2>/dev/null | xargs -r -n1 readlink -f | sort -u)
+if [ -n "$devices" ]; then
+  command -v mdadm || yum install -y mdadm
+  mdadm --create --force --verbose /dev/md0 --level=0 --raid-devices=$(echo $devices | wc -w) $devices
+  mkfs.xfs /dev/md0
+  mkdir -p /mnt/instance-store
+  mount /dev/md0 /mnt/instance-store
+  echo "UUID=$(blkid -s UUID -o value /dev/md0)    /mnt/instance-store    xfs    defaults,nofail    0    2" >> /etc/fstab
+  systemctl stop docker
+  for dir in kubelet docker containerd; do
+    mkdir -p /mnt/instance-store/$dir /var/lib/$dir
+    mount --bind /mnt/instance-store/$dir /var/lib/$dir
+    echo "/mnt/instance-store/$dir    /var/lib/$dir    none    bind    0    0" >> /etc/fstab
+  done
+fi
+{{- end}}
+{{- range .MountOptions}}
+mkfs.{{ .FileSystem | ToLower }} {{ .Device }}
+mkdir {{ .Mount }}
+mount {{ .Device }} {{ .Mount }}
+mount
+{{- if .Persistance}}
+echo "{{ .Device}}    {{ .Mount }}    {{ .FileSystem | ToLower }}    defaults    0    2" >> /etc/fstab
+{{- end}}
+{{- end}}
+set -o xtrace
+/etc/eks/bootstrap.sh {{ .ClusterName }} {{ .Arguments }}
+set +o xtrace
+{{range $post := .PostBootstrap}}{{$post}}{{end}}`
+
+	// BottlerocketBootstrapTemplate renders the settings of Bottlerocket images, userData stages are appended as
+	// additional settings
+	BottlerocketBootstrapTemplate = `{{- if .SecretsHash}}
+# secrets-hash: {{ .SecretsHash }}
+{{- end}}
+{{- if .RotationCounter}}
+# rotation: {{ .RotationCounter }}
+{{- end}}
+[settings.kubernetes]
+cluster-name = "{{ .ClusterName }}"
+api-server = "{{ .APIServerEndpoint }}"
+cluster-certificate = "{{ .CertificateAuthority }}"
+
+[settings.kubernetes.node-labels]
+{{- range .NodeLabels}}
+"{{ Key . }}" = "{{ Value . }}"
+{{- end}}
+
+[settings.kubernetes.node-taints]
+{{- range .NodeTaints}}
+"{{ Key . }}" = "{{ Value . }}"
+{{- end}}
+{{range $pre := .PreBootstrap}}{{$pre}}{{end}}
+{{- range $post := .PostBootstrap}}{{$post}}{{end}}`
+
+	// NodeadmBootstrapTemplate renders the NodeConfig of Amazon Linux 2023 images, userData stages and mounts are run
+	// as a script before the kubelet is started
+	NodeadmBootstrapTemplate = `MIME-Version: 1.0
+Content-Type: multipart/mixed; boundary="BOUNDARY"
+
+--BOUNDARY
+Content-Type: application/node.eks.aws
+
+---
+{{- if .SecretsHash}}
+# secrets-hash: {{ .SecretsHash }}
+{{- end}}
+{{- if .RotationCounter}}
+# rotation: {{ .RotationCounter }}
+{{- end}}
+apiVersion: node.eks.aws/v1alpha1
+kind: NodeConfig
+spec:
+  cluster:
+    name: {{ .ClusterName }}
+    apiServerEndpoint: {{ .APIServerEndpoint }}
+    certificateAuthority: {{ .CertificateAuthority }}
+    cidr: {{ .ClusterCIDR }}
+{{- if eq .InstanceStorePolicy "RAID0"}}
+  instance:
+    localStorage:
+      strategy: RAID0
+{{- end}}
+  kubelet:
+    flags:
+    - --node-labels={{ Join .NodeLabels "," }}
+{{- if .NodeTaints}}
+    - --register-with-taints={{ Join .NodeTaints "," }}
+{{- end}}
+{{- range Fields .KubeletExtraArgs}}
+    - {{ . }}
+{{- end}}
+{{- if or .PreBootstrap .PostBootstrap .MountOptions}}
+
+--BOUNDARY
+Content-Type: text/x-shellscript; charset="us-ascii"
+
+#!/bin/bash
+{{range $pre := .PreBootstrap}}{{$pre}}{{end}}
+{{- range .MountOptions}}
+mkfs.{{ .FileSystem | ToLower }} {{ .Device }}
+mkdir {{ .Mount }}
+mount {{ .Device }} {{ .Mount }}
+{{- if .Persistance}}
+echo "{{ .Device}}    {{ .Mount }}    {{ .FileSystem | ToLower }}    defaults    0    2" >> /etc/fstab
+{{- end}}
+{{- end}}
+{{range $post := .PostBootstrap}}{{$post}}{{end}}
+{{- end}}
+
+--BOUNDARY--
+`
+)
+
+// bootstrapFuncs are the functions of bootstrap templates
+var bootstrapFuncs = template.FuncMap{
+	"ToLower": strings.ToLower,
+	"Join":    strings.Join,
+	"Fields":  strings.Fields,
+	// Key and Value split labels and taints of the form key=value
+	"Key": func(s string) string {
+		return strings.SplitN(s, "=", 2)[0]
+	},
+	"Value": func(s string) string {
+		if parts := strings.SplitN(s, "=", 2); len(parts) == 2 {
+			return parts[1]
+		}
+		return ""
+	},
+}
+
+// RenderBootstrapTemplate renders a bootstrap template with the user data of an instance group
+func RenderBootstrapTemplate(text string, data EKSUserData) ([]byte, error) {
+	tmpl, err := template.New("userData").Funcs(bootstrapFuncs).Parse(text)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse userData template")
+	}
+
+	out := &bytes.Buffer{}
+	if err := tmpl.Execute(out, data); err != nil {
+		return nil, errors.Wrap(err, "failed to render userData template")
+	}
+	return out.Bytes(), nil
+}
+
+// templateBootstrapProvider renders one of the built-in templates, templates of images which do not describe the
+// cluster at boot require its API server endpoint and certificate authority
+type templateBootstrapProvider struct {
+	template        string
+	requiresCluster bool
+}
+
+func (p templateBootstrapProvider) UserData(instanceGroup *v1alpha1.InstanceGroup, data EKSUserData) ([]byte, error) {
+	if p.requiresCluster && (data.APIServerEndpoint == "" || data.CertificateAuthority == "") {
+		return nil, errors.Errorf("bootstrap provider %v requires the API server endpoint and certificate authority of the cluster",
+			instanceGroup.GetEKSConfiguration().GetBootstrapProvider())
+	}
+	return RenderBootstrapTemplate(p.template, data)
+}
+
+// customTemplateBootstrapProvider renders the bootstrapTemplate of an instance group
+type customTemplateBootstrapProvider struct{}
+
+func (p customTemplateBootstrapProvider) UserData(instanceGroup *v1alpha1.InstanceGroup, data EKSUserData) ([]byte, error) {
+	return RenderBootstrapTemplate(instanceGroup.GetEKSConfiguration().GetBootstrapTemplate(), data)
+}
+
+// GetClusterBootstrapData returns the API server endpoint and certificate authority nodes are bootstrapped with, the
+// values of the configuration take precedence over those of the discovered cluster
+func (ctx *EksInstanceGroupContext) GetClusterBootstrapData() (string, string) {
+	var (
+		configuration = ctx.GetInstanceGroup().GetEKSConfiguration()
+		endpoint      = configuration.GetAPIServerEndpoint()
+		ca            = configuration.GetCertificateAuthority()
+	)
+
+	if state := ctx.GetDiscoveredState(); state != nil && state.Cluster != nil {
+		if endpoint == "" {
+			endpoint = aws.StringValue(state.Cluster.Endpoint)
+		}
+		if ca == "" && state.Cluster.CertificateAuthority != nil {
+			ca = aws.StringValue(state.Cluster.CertificateAuthority.Data)
+		}
+	}
+	return endpoint, ca
+}
@@ -0,0 +1,127 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eks
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/keikoproj/instance-manager/api/v1alpha1"
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestBootstrapProviders(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+	ctx.SetDiscoveredState(&DiscoveredState{})
+	configuration := ig.GetEKSConfiguration()
+	configuration.Labels = map[string]string{"team": "platform"}
+	configuration.Taints = []corev1.Taint{{Key: "dedicated", Value: "platform", Effect: corev1.TaintEffectNoSchedule}}
+
+	render := func() string {
+		userData, _ := base64.StdEncoding.DecodeString(ctx.GetBasicUserData("some-cluster", ctx.GetBootstrapArgs(), UserDataPayload{PreBootstrap: []string{"echo hello"}}, nil))
+		return string(userData)
+	}
+
+	// the bootstrap script of the EKS optimized image is the default
+	userData := render()
+	g.Expect(userData).To(gomega.HavePrefix("#!/bin/bash"))
+	g.Expect(userData).To(gomega.ContainSubstring("/etc/eks/bootstrap.sh some-cluster"))
+
+	// bottlerocket does not describe the cluster at boot and cannot be rendered without its endpoint
+	configuration.SetBootstrapProvider(v1alpha1.BottlerocketBootstrapProvider)
+	g.Expect(render()).To(gomega.BeEmpty())
+
+	ctx.GetDiscoveredState().SetCluster(&eks.Cluster{
+		Version:              aws.String("1.18"),
+		Endpoint:             aws.String("https://cluster.eks.amazonaws.com"),
+		CertificateAuthority: &eks.Certificate{Data: aws.String("Y2EtZGF0YQ==")},
+	})
+	userData = render()
+	g.Expect(userData).To(gomega.ContainSubstring(`cluster-name = "some-cluster"`))
+	g.Expect(userData).To(gomega.ContainSubstring(`api-server = "https://cluster.eks.amazonaws.com"`))
+	g.Expect(userData).To(gomega.ContainSubstring(`cluster-certificate = "Y2EtZGF0YQ=="`))
+	g.Expect(userData).To(gomega.ContainSubstring(`"team" = "platform"`))
+	g.Expect(userData).To(gomega.ContainSubstring(`"dedicated" = "platform:NoSchedule"`))
+
+	// the endpoint of the configuration takes precedence over the discovered cluster
+	configuration.SetAPIServerEndpoint("https://private.eks.amazonaws.com")
+	g.Expect(render()).To(gomega.ContainSubstring(`api-server = "https://private.eks.amazonaws.com"`))
+
+	// nodeadm renders a NodeConfig and runs userData stages as a script
+	configuration.SetBootstrapProvider(v1alpha1.NodeadmBootstrapProvider)
+	configuration.ClusterCIDR = "10.100.0.0/16"
+	configuration.BootstrapArguments = "--max-pods=58"
+	configuration.SetInstanceStorePolicy(v1alpha1.InstanceStorePolicyRaid0)
+	userData = render()
+	g.Expect(userData).To(gomega.ContainSubstring("Content-Type: application/node.eks.aws"))
+	g.Expect(userData).To(gomega.ContainSubstring("cidr: 10.100.0.0/16"))
+	g.Expect(userData).To(gomega.ContainSubstring("strategy: RAID0"))
+	g.Expect(userData).To(gomega.ContainSubstring("- --register-with-taints=dedicated=platform:NoSchedule"))
+	g.Expect(userData).To(gomega.ContainSubstring("- --max-pods=58"))
+	g.Expect(userData).To(gomega.ContainSubstring("Content-Type: text/x-shellscript"))
+	g.Expect(userData).To(gomega.ContainSubstring("echo hello"))
+
+	// custom templates are rendered with the same data
+	configuration.SetBootstrapProvider(v1alpha1.CustomTemplateBootstrapProvider)
+	configuration.BootstrapTemplate = "#!/bin/bash\n/opt/bootstrap {{ .ClusterName }} {{ Join .NodeLabels \",\" }}"
+	g.Expect(render()).To(gomega.HavePrefix("#!/bin/bash\n/opt/bootstrap some-cluster "))
+	g.Expect(render()).To(gomega.ContainSubstring("team=platform"))
+
+	configuration.BootstrapTemplate = "{{ .Unknown }}"
+	g.Expect(render()).To(gomega.BeEmpty())
+}
+
+type mockBootstrapProvider struct{}
+
+func (p mockBootstrapProvider) UserData(instanceGroup *v1alpha1.InstanceGroup, data EKSUserData) ([]byte, error) {
+	return []byte("mock " + data.ClusterName), nil
+}
+
+func TestRegisterBootstrapProvider(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	RegisterBootstrapProvider("Mock", mockBootstrapProvider{})
+	g.Expect(v1alpha1.BootstrapProviders).To(gomega.ContainElement("Mock"))
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+	ig.GetEKSConfiguration().SetBootstrapProvider("mock")
+
+	userData, _ := base64.StdEncoding.DecodeString(ctx.GetBasicUserData("some-cluster", "", UserDataPayload{}, nil))
+	g.Expect(string(userData)).To(gomega.Equal("mock some-cluster"))
+}
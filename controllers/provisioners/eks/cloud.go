@@ -17,34 +17,52 @@ package eks
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/keikoproj/instance-manager/api/v1alpha1"
+	"github.com/keikoproj/instance-manager/controllers/common"
+	awsprovider "github.com/keikoproj/instance-manager/controllers/providers/aws"
 	kubeprovider "github.com/keikoproj/instance-manager/controllers/providers/kubernetes"
+	"github.com/keikoproj/instance-manager/controllers/provisioners"
 	"github.com/keikoproj/instance-manager/controllers/provisioners/eks/scaling"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/eks"
 	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
 )
 
 type DiscoveredState struct {
-	Provisioned          bool
-	NodesReady           bool
-	ClusterNodes         *corev1.NodeList
-	OwnedScalingGroups   []*autoscaling.Group
-	ScalingGroup         *autoscaling.Group
-	LifecycleHooks       []*autoscaling.LifecycleHook
-	ScalingConfiguration scaling.Configuration
-	IAMRole              *iam.Role
-	AttachedPolicies     []*iam.AttachedPolicy
-	InstanceProfile      *iam.InstanceProfile
-	Publisher            kubeprovider.EventPublisher
-	Cluster              *eks.Cluster
-	VPCId                string
+	Provisioned           bool
+	NodesReady            bool
+	ClusterNodes          *corev1.NodeList
+	OwnedScalingGroups    []*autoscaling.Group
+	ScalingGroup          *autoscaling.Group
+	RetiringScalingGroups []*autoscaling.Group
+	LifecycleHooks        []*autoscaling.LifecycleHook
+	Notifications         []*autoscaling.NotificationConfiguration
+	Alarms                []*cloudwatch.MetricAlarm
+	ScalingConfiguration  scaling.Configuration
+	IAMRole               *iam.Role
+	AttachedPolicies      []*iam.AttachedPolicy
+	InstanceProfile       *iam.InstanceProfile
+	Publisher             kubeprovider.EventPublisher
+	Cluster               *eks.Cluster
+	VPCId                 string
+	InstanceTypeInfo      *ec2.InstanceTypeInfo
+	Image                 *ec2.Image
+	Secrets               map[string]*corev1.Secret
+	SubnetPlacements      []awsprovider.SubnetPlacement
+	UnofferedSubnets      []string
 }
 
 func (ctx *EksInstanceGroupContext) CloudDiscovery() error {
@@ -54,8 +72,13 @@ func (ctx *EksInstanceGroupContext) CloudDiscovery() error {
 		configuration = instanceGroup.GetEKSConfiguration()
 		status        = instanceGroup.GetStatus()
 		clusterName   = configuration.GetClusterName()
+		deleting      = !instanceGroup.GetDeletionTimestamp().IsZero()
 	)
 
+	if err := ctx.ValidateResourceNames(); err != nil {
+		return errors.Wrap(err, "failed to validate resource names")
+	}
+
 	state.Publisher = kubeprovider.EventPublisher{
 		Client:          ctx.KubernetesClient.Kubernetes,
 		Namespace:       instanceGroup.GetNamespace(),
@@ -66,54 +89,106 @@ func (ctx *EksInstanceGroupContext) CloudDiscovery() error {
 
 	state.ScalingConfiguration = &scaling.LaunchConfiguration{
 		AwsWorker: ctx.AwsWorker,
+		Log:       ctx.Log,
 	}
 
-	nodes, err := ctx.KubernetesClient.Kubernetes.CoreV1().Nodes().List(metav1.ListOptions{})
+	// the lookups below are independent of each other and run concurrently
+	var scalingGroups []*autoscaling.Group
+	err := discoverConcurrently(
+		func() error {
+			nodes, err := ctx.ClusterKubernetesClient.Kubernetes.CoreV1().Nodes().List(metav1.ListOptions{})
+			if err != nil {
+				return errors.Wrap(err, "failed to list cluster nodes")
+			}
+			state.SetClusterNodes(nodes)
+			return nil
+		},
+		func() error {
+			if err := ctx.DiscoverSecrets(); err != nil {
+				return errors.Wrap(err, "failed to discover userData secrets")
+			}
+			return nil
+		},
+		ctx.DiscoverRole,
+		func() error {
+			groups, err := ctx.AwsWorker.DescribeAutoscalingGroupsByTag(provisioners.TagClusterName, clusterName)
+			if err != nil {
+				return errors.Wrap(err, "failed to describe autoscaling groups")
+			}
+			scalingGroups = groups
+			return nil
+		},
+		func() error {
+			cluster, err := ctx.AwsWorker.DescribeEKSCluster(clusterName)
+			if err != nil {
+				return errors.Wrap(err, "failed to describe cluster")
+			}
+			state.SetCluster(cluster)
+			state.SetVPCId(aws.StringValue(cluster.ResourcesVpcConfig.VpcId))
+			return nil
+		},
+		func() error {
+			// instance groups which are being deleted do not launch instances, their instance type is not described
+			if deleting {
+				return nil
+			}
+			instanceTypeInfo, err := ctx.AwsWorker.DescribeInstanceType(configuration.InstanceType)
+			if err != nil {
+				return errors.Wrap(err, "failed to describe instance type")
+			}
+			state.SetInstanceTypeInfo(instanceTypeInfo)
+			status.SetAcceleratorCount(GetAcceleratorCount(instanceTypeInfo))
+			return nil
+		},
+	)
 	if err != nil {
-		return errors.Wrap(err, "failed to list cluster nodes")
+		return err
 	}
-	state.SetClusterNodes(nodes)
 
-	var roleName, instanceProfileName string
-	if configuration.HasExistingRole() {
-		roleName = configuration.GetRoleName()
-		instanceProfileName = configuration.GetInstanceProfileName()
-	} else {
-		roleName = fmt.Sprintf("%v-%v-%v", clusterName, instanceGroup.GetNamespace(), instanceGroup.GetName())
-		instanceProfileName = fmt.Sprintf("%v-%v-%v", clusterName, instanceGroup.GetNamespace(), instanceGroup.GetName())
+	// subnets are resolved by name in the cluster's VPC, the placement is not discovered while the instance group is
+	// being deleted so that instance groups whose subnets were deleted can still be deleted
+	if !deleting {
+		if err := ctx.DiscoverPlacement(); err != nil {
+			return errors.Wrap(err, "failed to discover subnet placement")
+		}
 	}
 
-	// cache the instancegroup IAM role if it exists
-	if val, ok := ctx.AwsWorker.RoleExist(roleName); ok {
-		state.SetRole(val)
-		status.SetNodesArn(aws.StringValue(val.Arn))
-
-		if !configuration.HasExistingRole() {
-			policies, err := ctx.AwsWorker.ListRolePolicies(roleName)
-			if err != nil {
-				return errors.Wrap(err, "failed to list attached role policies")
-			}
-			state.SetAttachedPolicies(policies)
+	// the image follows the cluster version, a new image is detected as drift and rotates the nodes
+	if configuration.IsAutoUpgrade() {
+		resolved, err := ctx.ResolveImage()
+		if err != nil {
+			return errors.Wrap(err, "failed to resolve image")
 		}
+		configuration.Image = resolved
 	}
 
-	if val, ok := ctx.AwsWorker.InstanceProfileExist(instanceProfileName); ok {
-		state.SetInstanceProfile(val)
+	// the image follows the image pipeline, a newly built image is detected as drift and rotates the nodes
+	if configuration.HasImagePipeline() {
+		resolved, err := ctx.ResolvePipelineImage()
+		if err != nil {
+			return errors.Wrap(err, "failed to resolve pipeline image")
+		}
+		configuration.Image = resolved
 	}
 
-	scalingGroups, err := ctx.AwsWorker.DescribeAutoscalingGroups()
-	if err != nil {
-		return errors.Wrap(err, "failed to describe autoscaling groups")
+	// a spot price which is a percentage follows the on-demand price of the instance type, a changed price is
+	// detected as drift of the launch configuration
+	if err := ctx.ResolveSpotPrice(); err != nil {
+		return errors.Wrap(err, "failed to resolve spot price")
 	}
 
-	cluster, err := ctx.AwsWorker.DescribeEKSCluster(clusterName)
-	if err != nil {
-		return errors.Wrap(err, "failed to describe cluster")
+	// the commitment advisor only reports coverage, failing to describe commitments does not fail the reconcile
+	if err := ctx.AdviseCommitments(); err != nil {
+		ctx.Log.Error(err, "failed to advise commitments", "instancegroup", instanceGroup.GetName())
 	}
-	state.SetCluster(cluster)
 
-	vpcId := aws.StringValue(cluster.ResourcesVpcConfig.VpcId)
-	state.SetVPCId(vpcId)
+	// the image is not validated while the instance group is being deleted, so that instance groups whose image was
+	// deregistered can still be deleted
+	if !deleting {
+		if err := ctx.DiscoverImage(); err != nil {
+			return err
+		}
+	}
 
 	// find all owned scaling groups
 	ownedScalingGroups := ctx.findOwnedScalingGroups(scalingGroups)
@@ -121,9 +196,22 @@ func (ctx *EksInstanceGroupContext) CloudDiscovery() error {
 	// cache the scaling group we are reconciling for if it exists
 	targetScalingGroup := ctx.findTargetScalingGroup(ownedScalingGroups)
 
+	// with the recreate replacement policy, a scaling group which is not named by the resource name is replaced, and
+	// retired once its nodes are migrated to the scaling group which is
+	if instanceGroup.GetEKSSpec().IsRecreateReplacementPolicy() {
+		var retiring []*autoscaling.Group
+		targetScalingGroup, retiring = ctx.findReplacedScalingGroups(ownedScalingGroups)
+		state.SetRetiringScalingGroups(retiring)
+	}
+	status.SetRetiringScalingGroups(state.GetRetiringScalingGroupNames())
+
 	// if there is no scaling group found, it's deprovisioned
 	if targetScalingGroup == nil {
 		state.SetProvisioned(false)
+		status.SetLifecycleDistribution(nil)
+		ctx.deleteLifecycleMetrics()
+		ctx.DiscoverZoneDistribution(time.Now())
+		ctx.DiscoverProtectedInstances()
 		return nil
 	}
 
@@ -135,28 +223,30 @@ func (ctx *EksInstanceGroupContext) CloudDiscovery() error {
 	if err != nil {
 		return errors.Wrap(err, "failed to describe lifecycle hooks")
 	}
+	state.Notifications, err = ctx.AwsWorker.DescribeNotificationConfigurations(asgName)
+	if err != nil {
+		return errors.Wrap(err, "failed to describe notification configurations")
+	}
+	state.Alarms, err = ctx.AwsWorker.DescribeAlarms(ctx.AlarmNamePrefix())
+	if err != nil {
+		return errors.Wrap(err, "failed to describe alarms")
+	}
 	// update status with scaling group info
 	status.SetActiveScalingGroupName(asgName)
 	status.SetCurrentMin(int(aws.Int64Value(targetScalingGroup.MinSize)))
 	status.SetCurrentMax(int(aws.Int64Value(targetScalingGroup.MaxSize)))
 
-	state.ScalingConfiguration, err = scaling.NewLaunchConfiguration(instanceGroup.NamespacedName(), ctx.AwsWorker, &scaling.DiscoverConfigurationInput{
+	launchConfiguration, err := scaling.NewLaunchConfiguration(instanceGroup.NamespacedName(), ctx.AwsWorker, &scaling.DiscoverConfigurationInput{
 		ScalingGroup: targetScalingGroup,
 	})
+	launchConfiguration.Log = ctx.Log
+	state.ScalingConfiguration = launchConfiguration
 	if err != nil {
 		return errors.Wrap(err, "failed to discover launch configurations")
 	}
 	configName := state.ScalingConfiguration.Name()
 	status.SetActiveLaunchConfigurationName(configName)
 
-	// delete old launch configurations
-	state.ScalingConfiguration.Delete(&scaling.DeleteConfigurationInput{
-		Name:           configName,
-		Prefix:         ctx.ResourcePrefix,
-		DeleteAll:      false,
-		RetainVersions: ctx.ConfigRetention,
-	})
-
 	if status.GetNodesReadyCondition() == corev1.ConditionTrue {
 		state.SetNodesReady(true)
 	} else {
@@ -174,6 +264,274 @@ func (ctx *EksInstanceGroupContext) CloudDiscovery() error {
 		status.SetLifecycle(v1alpha1.LifecycleStateSpot)
 	}
 
+	// the split only reports the instances' lifecycle, failing to describe it does not fail the reconcile
+	if err := ctx.DiscoverLifecycleDistribution(); err != nil {
+		ctx.Log.Error(err, "failed to discover lifecycle distribution", "instancegroup", instanceGroup.GetName())
+	}
+
+	// instances which stay imbalanced across zones are flagged, e.g. after launches failed with insufficient capacity
+	ctx.DiscoverZoneDistribution(time.Now())
+
+	// instances protected from scale-in are reported, so that scale-downs which skip them can be explained
+	ctx.DiscoverProtectedInstances()
+
+	ctx.Log.V(1).Info("discovered cloud resources",
+		"scalingGroup", asgName,
+		"launchConfiguration", configName,
+		"instances", len(targetScalingGroup.Instances),
+		"retiringScalingGroups", len(state.GetRetiringScalingGroups()),
+		"lifecycle", status.GetLifecycle(),
+		"nodesReady", state.IsNodesReady(),
+	)
+	return nil
+}
+
+// DiscoverImage discovers the image of the instance group, the instance group is degraded while its image does not
+// exist or violates the version skew policy of the cluster
+func (ctx *EksInstanceGroupContext) DiscoverImage() error {
+	var (
+		state         = ctx.GetDiscoveredState()
+		instanceGroup = ctx.GetInstanceGroup()
+		configuration = instanceGroup.GetEKSConfiguration()
+		status        = instanceGroup.GetStatus()
+	)
+
+	image, err := ctx.AwsWorker.DescribeImage(configuration.Image)
+	if err != nil {
+		return errors.Wrap(err, "failed to describe image")
+	}
+	state.SetImage(image)
+	if image != nil {
+		status.SetRootDeviceName(aws.StringValue(image.RootDeviceName))
+	}
+
+	// fleets running deprecated images or unsupported cluster versions are flagged, but still reconciled
+	ctx.CheckDeprecation(time.Now())
+
+	// images which were deregistered cannot launch instances, and nodes which violate the version skew policy would
+	// not join the cluster, the instance group is degraded until the image or cluster version is changed
+	var (
+		reason  string
+		message string
+	)
+	if image == nil {
+		reason = ImageNotFoundReason
+		message = fmt.Sprintf("image %v was not found", configuration.Image)
+	} else if err := ctx.ValidateKubeletVersion(); err != nil {
+		reason = KubeletVersionSkewReason
+		message = err.Error()
+	}
+
+	if reason != "" {
+		ctx.Log.Info("image is not usable", "instancegroup", instanceGroup.GetName(), "reason", message)
+		condition := v1alpha1.NewInstanceGroupCondition(v1alpha1.Degraded, corev1.ConditionTrue)
+		condition.Reason = reason
+		condition.Message = message
+		status.SetCondition(condition)
+	} else if condition := status.GetCondition(v1alpha1.Degraded); condition != nil && common.ContainsString(ImageDegradedReasons, condition.Reason) {
+		status.RemoveCondition(v1alpha1.Degraded)
+	}
+	return nil
+}
+
+// DiscoverPlacement discovers the zones and outposts of the subnets, and the subnets of local zones, wavelength zones
+// and outposts which do not offer the instance type
+func (ctx *EksInstanceGroupContext) DiscoverPlacement() error {
+	var (
+		state         = ctx.GetDiscoveredState()
+		instanceGroup = ctx.GetInstanceGroup()
+		configuration = instanceGroup.GetEKSConfiguration()
+		status        = instanceGroup.GetStatus()
+		zoneTypes     = make([]string, 0)
+		unoffered     = make([]string, 0)
+	)
+
+	placements, err := ctx.AwsWorker.DescribeSubnetPlacements(ctx.ResolveSubnets())
+	if err != nil {
+		return errors.Wrap(err, "failed to describe subnets")
+	}
+	state.SetSubnetPlacements(placements)
+
+	for _, placement := range placements {
+		if !common.ContainsString(zoneTypes, placement.ZoneType) {
+			zoneTypes = append(zoneTypes, placement.ZoneType)
+		}
+		if placement.IsRegional() {
+			continue
+		}
+		offered, err := ctx.AwsWorker.IsInstanceTypeOffered(placement, configuration.InstanceType)
+		if err != nil {
+			return errors.Wrapf(err, "failed to get instance types offered in %v", placement.SubnetID)
+		}
+		if !offered {
+			unoffered = append(unoffered, placement.SubnetID)
+		}
+	}
+	state.SetUnofferedSubnets(unoffered)
+
+	sort.Strings(zoneTypes)
+	if len(zoneTypes) == 0 {
+		zoneTypes = nil
+	}
+	status.SetZoneTypes(zoneTypes)
+	return nil
+}
+
+// DiscoverRole discovers the IAM role, attached policies and instance profile of the nodes
+func (ctx *EksInstanceGroupContext) DiscoverRole() error {
+	var (
+		state         = ctx.GetDiscoveredState()
+		instanceGroup = ctx.GetInstanceGroup()
+		configuration = instanceGroup.GetEKSConfiguration()
+		status        = instanceGroup.GetStatus()
+	)
+
+	if configuration.HasExistingInstanceProfile() {
+		if err := ctx.DiscoverInstanceProfile(); err != nil {
+			return errors.Wrap(err, "failed to discover instance profile")
+		}
+		return nil
+	}
+
+	var roleName, instanceProfileName string
+	if configuration.HasExistingRole() {
+		roleName = configuration.GetRoleName()
+		instanceProfileName = configuration.GetInstanceProfileName()
+	} else {
+		roleName = ctx.GetManagedRoleName()
+		instanceProfileName = ctx.GetManagedRoleName()
+	}
+
+	// cache the instancegroup IAM role if it exists
+	if val, ok := ctx.AwsWorker.RoleExist(roleName); ok {
+		state.SetRole(val)
+		status.SetNodesArn(aws.StringValue(val.Arn))
+
+		if !configuration.HasExistingRole() {
+			policies, err := ctx.AwsWorker.ListRolePolicies(roleName)
+			if err != nil {
+				return errors.Wrap(err, "failed to list attached role policies")
+			}
+			state.SetAttachedPolicies(policies)
+		}
+	}
+
+	if val, ok := ctx.AwsWorker.InstanceProfileExist(instanceProfileName); ok {
+		state.SetInstanceProfile(val)
+	}
+	return nil
+}
+
+// discoverConcurrently runs independent discovery lookups concurrently and waits for all of them, the errors of
+// failed lookups are aggregated and the first of them is the cause, so that it is classified like a single failure
+func discoverConcurrently(lookups ...func() error) error {
+	var (
+		group errgroup.Group
+		lock  sync.Mutex
+		errs  []error
+	)
+
+	for _, lookup := range lookups {
+		lookup := lookup
+		group.Go(func() error {
+			err := lookup()
+			if err != nil {
+				lock.Lock()
+				errs = append(errs, err)
+				lock.Unlock()
+			}
+			return err
+		})
+	}
+
+	if err := group.Wait(); err == nil {
+		return nil
+	}
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return &discoveryError{errs: errs}
+}
+
+type discoveryError struct {
+	errs []error
+}
+
+func (e *discoveryError) Error() string {
+	messages := make([]string, 0, len(e.errs))
+	for _, err := range e.errs {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e *discoveryError) Cause() error {
+	return e.errs[0]
+}
+
+// DiscoverInstanceProfile resolves an existing instance profile by its ARN, and the role of the profile, if roleName is
+// also set the profile must contain that role
+func (ctx *EksInstanceGroupContext) DiscoverInstanceProfile() error {
+	var (
+		state         = ctx.GetDiscoveredState()
+		instanceGroup = ctx.GetInstanceGroup()
+		configuration = instanceGroup.GetEKSConfiguration()
+		status        = instanceGroup.GetStatus()
+		profileArn    = configuration.GetInstanceProfileArn()
+		// instance profile names are unique regardless of their path
+		profileName = common.GetLastElementBy(profileArn, "/")
+	)
+
+	profile, ok := ctx.AwsWorker.InstanceProfileExist(profileName)
+	if !ok {
+		return errors.Errorf("instance profile '%v' does not exist", profileArn)
+	}
+
+	if !strings.EqualFold(aws.StringValue(profile.Arn), profileArn) {
+		return errors.Errorf("instance profile '%v' does not match ARN '%v'", profileName, profileArn)
+	}
+
+	var role *iam.Role
+	for _, r := range profile.Roles {
+		if common.StringEmpty(configuration.GetRoleName()) || strings.EqualFold(aws.StringValue(r.RoleName), configuration.GetRoleName()) {
+			role = r
+			break
+		}
+	}
+
+	if role == nil {
+		return errors.Errorf("instance profile '%v' does not contain a matching role", profileArn)
+	}
+
+	state.SetInstanceProfile(profile)
+	state.SetRole(role)
+	status.SetNodesArn(aws.StringValue(role.Arn))
+	return nil
+}
+
+// ValidateResourceNames validates the names of the cloud resources of the instance group against AWS length limits
+func (ctx *EksInstanceGroupContext) ValidateResourceNames() error {
+	var (
+		configuration = ctx.GetInstanceGroup().GetEKSConfiguration()
+		// launch configurations are named by the prefix and a timestamp suffix
+		configNameLength = len(ctx.ResourcePrefix) + len(common.GetTimeString()) + 1
+	)
+
+	if ctx.ResourceNameErr != nil {
+		return ctx.ResourceNameErr
+	}
+
+	if len(ctx.ResourcePrefix) > provisioners.MaxScalingGroupNameLength {
+		return errors.Errorf("scaling group name '%v' exceeds %v characters", ctx.ResourcePrefix, provisioners.MaxScalingGroupNameLength)
+	}
+
+	if configNameLength > provisioners.MaxLaunchConfigurationNameLength {
+		return errors.Errorf("launch configuration name prefix '%v' exceeds %v characters", ctx.ResourcePrefix, provisioners.MaxLaunchConfigurationNameLength)
+	}
+
+	if roleName := ctx.GetManagedRoleName(); !configuration.HasExistingRole() && len(roleName) > provisioners.MaxRoleNameLength {
+		return errors.Errorf("role name '%v' exceeds %v characters, use a shorter resource name template or an existing role", roleName, provisioners.MaxRoleNameLength)
+	}
 	return nil
 }
 
@@ -208,12 +566,68 @@ func (d *DiscoveredState) GetClusterVersion() string {
 	return aws.StringValue(d.Cluster.Version)
 }
 
+func (d *DiscoveredState) SetSubnetPlacements(placements []awsprovider.SubnetPlacement) {
+	d.SubnetPlacements = placements
+}
+
+func (d *DiscoveredState) GetSubnetPlacements() []awsprovider.SubnetPlacement {
+	return d.SubnetPlacements
+}
+
+func (d *DiscoveredState) SetUnofferedSubnets(subnets []string) {
+	d.UnofferedSubnets = subnets
+}
+
+func (d *DiscoveredState) GetUnofferedSubnets() []string {
+	return d.UnofferedSubnets
+}
+
+func (d *DiscoveredState) SetInstanceTypeInfo(info *ec2.InstanceTypeInfo) {
+	d.InstanceTypeInfo = info
+}
+
+func (d *DiscoveredState) GetInstanceTypeInfo() *ec2.InstanceTypeInfo {
+	return d.InstanceTypeInfo
+}
+
+func (d *DiscoveredState) SetImage(image *ec2.Image) {
+	d.Image = image
+}
+
+func (d *DiscoveredState) GetImage() *ec2.Image {
+	return d.Image
+}
+
+func (d *DiscoveredState) SetSecrets(secrets map[string]*corev1.Secret) {
+	d.Secrets = secrets
+}
+
+func (d *DiscoveredState) GetSecrets() map[string]*corev1.Secret {
+	return d.Secrets
+}
+
 func (d *DiscoveredState) SetOwnedScalingGroups(groups []*autoscaling.Group) {
 	d.OwnedScalingGroups = groups
 }
 func (d *DiscoveredState) GetOwnedScalingGroups() []*autoscaling.Group {
 	return d.OwnedScalingGroups
 }
+func (d *DiscoveredState) SetRetiringScalingGroups(groups []*autoscaling.Group) {
+	d.RetiringScalingGroups = groups
+}
+func (d *DiscoveredState) GetRetiringScalingGroups() []*autoscaling.Group {
+	return d.RetiringScalingGroups
+}
+func (d *DiscoveredState) HasRetiringScalingGroups() bool {
+	return len(d.RetiringScalingGroups) > 0
+}
+func (d *DiscoveredState) GetRetiringScalingGroupNames() []string {
+	var names []string
+	for _, group := range d.RetiringScalingGroups {
+		names = append(names, aws.StringValue(group.AutoScalingGroupName))
+	}
+	return names
+}
 func (d *DiscoveredState) GetScalingConfiguration() scaling.Configuration {
 	return d.ScalingConfiguration
 }
@@ -17,15 +17,26 @@ package eks
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/eks"
 	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/keikoproj/instance-manager/api/v1alpha1"
+	awsprovider "github.com/keikoproj/instance-manager/controllers/providers/aws"
+	kubeprovider "github.com/keikoproj/instance-manager/controllers/providers/kubernetes"
 	"github.com/keikoproj/instance-manager/controllers/provisioners"
 	"github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	ctrl "sigs.k8s.io/controller-runtime"
 )
 
 func TestCloudDiscoveryPositive(t *testing.T) {
@@ -110,6 +121,125 @@ func TestCloudDiscoveryPositive(t *testing.T) {
 	g.Expect(status.GetCurrentMax()).To(gomega.Equal(6))
 }
 
+func TestDiscoverPlacement(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	w.OutpostsClient = &MockOutpostsClient{InstanceTypes: []string{"m5.large", "c5.large"}}
+	ctx := MockContext(ig, k, w)
+	state := ctx.GetDiscoveredState()
+	status := ig.GetStatus()
+	configuration := ig.GetEKSConfiguration()
+	configuration.InstanceType = "m5.large"
+	configuration.SetSubnets([]string{"subnet-1", "subnet-2", "subnet-3"})
+
+	outpostArn := "arn:aws:outposts:us-west-2:123456789012:outpost/op-1234567890"
+	ec2Mock.Subnets = []*ec2.Subnet{
+		{SubnetId: aws.String("subnet-1"), AvailabilityZone: aws.String("us-west-2a")},
+		{SubnetId: aws.String("subnet-2"), AvailabilityZone: aws.String("us-west-2-lax-1a")},
+		{SubnetId: aws.String("subnet-3"), AvailabilityZone: aws.String("us-west-2b"), OutpostArn: aws.String(outpostArn)},
+	}
+	ec2Mock.AvailabilityZones = []*ec2.AvailabilityZone{
+		{ZoneName: aws.String("us-west-2a"), RegionName: aws.String("us-west-2"), NetworkBorderGroup: aws.String("us-west-2")},
+		{ZoneName: aws.String("us-west-2b"), RegionName: aws.String("us-west-2"), NetworkBorderGroup: aws.String("us-west-2")},
+		{ZoneName: aws.String("us-west-2-lax-1a"), RegionName: aws.String("us-west-2"), NetworkBorderGroup: aws.String("us-west-2-lax-1")},
+	}
+	ec2Mock.InstanceTypeOfferings = []*ec2.InstanceTypeOffering{
+		{InstanceType: aws.String("m5.large"), Location: aws.String("us-west-2a")},
+	}
+
+	err := ctx.DiscoverPlacement()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(status.GetZoneTypes()).To(gomega.Equal([]string{"availability-zone", "local-zone", "outpost"}))
+	g.Expect(state.GetSubnetPlacements()).To(gomega.HaveLen(3))
+	g.Expect(state.GetUnofferedSubnets()).To(gomega.Equal([]string{"subnet-2"}))
+
+	// the local zone offers the instance type
+	ec2Mock.InstanceTypeOfferings = append(ec2Mock.InstanceTypeOfferings, &ec2.InstanceTypeOffering{
+		InstanceType: aws.String("m5.large"), Location: aws.String("us-west-2-lax-1a"),
+	})
+	err = ctx.DiscoverPlacement()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(state.GetUnofferedSubnets()).To(gomega.BeEmpty())
+}
+
+func TestCloudDiscoveryAggregatedErrors(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+
+	iamMock.Role = &iam.Role{
+		RoleName: aws.String("some-role"),
+		Arn:      aws.String("some-arn"),
+	}
+	asgMock.DescribeAutoScalingGroupsErr = errors.New("asg error")
+	eksMock.DescribeClusterErr = errors.New("eks error")
+
+	err := ctx.CloudDiscovery()
+	g.Expect(err).To(gomega.HaveOccurred())
+	g.Expect(err.Error()).To(gomega.ContainSubstring("failed to describe autoscaling groups: asg error"))
+	g.Expect(err.Error()).To(gomega.ContainSubstring("failed to describe cluster: eks error"))
+	g.Expect(errors.Cause(err).Error()).To(gomega.MatchRegexp("^(asg|eks) error$"))
+}
+
+func TestCloudDiscoveryRemoteCluster(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	remote := kubeprovider.KubernetesClientSet{
+		Kubernetes: fake.NewSimpleClientset(&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "remote-node"},
+		}),
+	}
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := New(provisioners.ProvisionerInput{
+		AwsWorker:         w,
+		Kubernetes:        k,
+		ClusterKubernetes: remote,
+		InstanceGroup:     ig,
+		Log:               ctrl.Log.WithName("unit-test").WithName("InstanceGroup"),
+	})
+	iamMock.Role = &iam.Role{
+		RoleName: aws.String("some-role"),
+		Arn:      aws.String("some-arn"),
+	}
+
+	// nodes are discovered in the cluster of the instance group, events are published to the controller's cluster
+	err := ctx.CloudDiscovery()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(ctx.GetDiscoveredState().GetClusterNodes().Items).To(gomega.HaveLen(1))
+	g.Expect(ctx.GetDiscoveredState().Publisher.Client).To(gomega.Equal(k.Kubernetes))
+
+	// without a remote cluster the nodes of the controller's cluster are discovered
+	ctx = MockContext(ig, k, w)
+	g.Expect(ctx.ClusterKubernetesClient).To(gomega.Equal(k))
+}
+
 func TestCloudDiscoveryExistingRole(t *testing.T) {
 	var (
 		g       = gomega.NewGomegaWithT(t)
@@ -144,6 +274,63 @@ func TestCloudDiscoveryExistingRole(t *testing.T) {
 	g.Expect(state.GetInstanceProfile()).To(gomega.Equal(iamMock.InstanceProfile))
 }
 
+func TestCloudDiscoveryInstanceProfileArn(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+	configuration := ig.GetEKSConfiguration()
+	state := ctx.GetDiscoveredState()
+
+	role := &iam.Role{
+		RoleName: aws.String("some-role"),
+		Arn:      aws.String("arn:aws:iam::123456789012:role/nodes/some-role"),
+	}
+	iamMock.InstanceProfile = &iam.InstanceProfile{
+		InstanceProfileName: aws.String("some-profile"),
+		Arn:                 aws.String("arn:aws:iam::123456789012:instance-profile/nodes/some-profile"),
+		Roles:               []*iam.Role{role},
+	}
+
+	configuration.ExistingInstanceProfileArn = "arn:aws:iam::123456789012:instance-profile/nodes/some-profile"
+
+	err := ctx.CloudDiscovery()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(state.GetRole()).To(gomega.Equal(role))
+	g.Expect(state.GetInstanceProfile()).To(gomega.Equal(iamMock.InstanceProfile))
+	g.Expect(ig.GetStatus().GetNodesArn()).To(gomega.Equal(aws.StringValue(role.Arn)))
+
+	// the profile and role are not managed
+	err = ctx.CreateManagedRole()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	err = ctx.DeleteManagedRole()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(iamMock.DeleteRoleCallCount).To(gomega.Equal(0))
+
+	// the role must be in the profile
+	configuration.SetRoleName("other-role")
+	err = ctx.CloudDiscovery()
+	g.Expect(err).To(gomega.HaveOccurred())
+
+	// the profile must match the ARN
+	configuration.SetRoleName("")
+	configuration.ExistingInstanceProfileArn = "arn:aws:iam::210987654321:instance-profile/some-profile"
+	err = ctx.CloudDiscovery()
+	g.Expect(err).To(gomega.HaveOccurred())
+
+	iamMock.GetInstanceProfileErr = errors.New("some-error")
+	err = ctx.CloudDiscovery()
+	g.Expect(err).To(gomega.HaveOccurred())
+}
+
 func TestCloudDiscoverySpotPrice(t *testing.T) {
 	var (
 		g       = gomega.NewGomegaWithT(t)
@@ -283,7 +470,191 @@ func TestLaunchConfigDeletion(t *testing.T) {
 		},
 	}
 
+	// discovery also runs for dry-run and suspended reconciles, old launch configurations are only deleted on update
 	err := ctx.CloudDiscovery()
 	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(asgMock.DeleteLaunchConfigurationCallCount).To(gomega.Equal(0))
+
+	err = ctx.Update()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
 	g.Expect(asgMock.DeleteLaunchConfigurationCallCount).To(gomega.Equal(2))
 }
+
+func TestCloudDiscoveryDegradedCondition(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		status  = ig.GetStatus()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+
+	iamMock.Role = &iam.Role{
+		RoleName: aws.String("some-role"),
+		Arn:      aws.String("some-arn"),
+	}
+	iamMock.InstanceProfile = &iam.InstanceProfile{
+		InstanceProfileName: aws.String("some-profile"),
+	}
+
+	ec2Mock.Images = []*ec2.Image{{ImageId: aws.String(ig.GetEKSConfiguration().Image)}}
+
+	// a degraded condition with another reason is not removed when the kubelet version is compatible
+	condition := v1alpha1.NewInstanceGroupCondition(v1alpha1.Degraded, corev1.ConditionTrue)
+	condition.Reason = "AWSCircuitOpen"
+	status.SetCondition(condition)
+	err := ctx.CloudDiscovery()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(status.GetCondition(v1alpha1.Degraded)).NotTo(gomega.BeNil())
+
+	condition.Reason = KubeletVersionSkewReason
+	status.SetCondition(condition)
+	err = ctx.CloudDiscovery()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(status.GetCondition(v1alpha1.Degraded)).To(gomega.BeNil())
+}
+
+func TestCloudDiscoveryImageNotFound(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		status  = ig.GetStatus()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+
+	iamMock.Role = &iam.Role{
+		RoleName: aws.String("some-role"),
+		Arn:      aws.String("some-arn"),
+	}
+	iamMock.InstanceProfile = &iam.InstanceProfile{
+		InstanceProfileName: aws.String("some-profile"),
+	}
+
+	// a deregistered image degrades the instance group
+	ec2Mock.DescribeImagesErr = awserr.New(awsprovider.ImageNotFoundErrorCode, "image not found", nil)
+	err := ctx.CloudDiscovery()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(ctx.GetDiscoveredState().GetImage()).To(gomega.BeNil())
+	condition := status.GetCondition(v1alpha1.Degraded)
+	g.Expect(condition).NotTo(gomega.BeNil())
+	g.Expect(condition.Reason).To(gomega.Equal(ImageNotFoundReason))
+
+	// the condition is removed once the image exists
+	ec2Mock.DescribeImagesErr = nil
+	ec2Mock.Images = []*ec2.Image{{ImageId: aws.String(ig.GetEKSConfiguration().Image)}}
+	err = ctx.CloudDiscovery()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(status.GetCondition(v1alpha1.Degraded)).To(gomega.BeNil())
+
+	// other errors fail discovery, unless the instance group is being deleted
+	ec2Mock.DescribeImagesErr = errors.New("some error")
+	err = ctx.CloudDiscovery()
+	g.Expect(err).To(gomega.HaveOccurred())
+
+	ig.SetDeletionTimestamp(&metav1.Time{Time: time.Now()})
+	err = ctx.CloudDiscovery()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+}
+
+func TestCloudDiscoveryDeleting(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+	ig.GetEKSConfiguration().SetSubnets([]string{"subnet-1", "subnet-2"})
+
+	iamMock.Role = &iam.Role{
+		RoleName: aws.String("some-role"),
+		Arn:      aws.String("some-arn"),
+	}
+	iamMock.InstanceProfile = &iam.InstanceProfile{
+		InstanceProfileName: aws.String("some-profile"),
+	}
+
+	// lookups which are only needed to launch instances fail discovery, unless the instance group is being deleted
+	ec2Mock.DescribeInstanceTypesErr = errors.New("some error")
+	err := ctx.CloudDiscovery()
+	g.Expect(err).To(gomega.HaveOccurred())
+	ec2Mock.DescribeInstanceTypesErr = nil
+	ec2Mock.DescribeSubnetsErr = awserr.New("InvalidSubnetID.NotFound", "subnet not found", nil)
+	err = ctx.CloudDiscovery()
+	g.Expect(err).To(gomega.HaveOccurred())
+
+	ec2Mock.DescribeInstanceTypesErr = errors.New("some error")
+	ig.SetDeletionTimestamp(&metav1.Time{Time: time.Now()})
+	err = ctx.CloudDiscovery()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(ctx.GetDiscoveredState().GetInstanceTypeInfo()).To(gomega.BeNil())
+	g.Expect(ctx.GetDiscoveredState().GetSubnetPlacements()).To(gomega.BeEmpty())
+}
+
+func TestCloudDiscoveryResourceNames(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+
+	tests := []struct {
+		template     string
+		name         string
+		existingRole string
+		expected     string
+		wantErr      bool
+	}{
+		{template: "", name: "instance-group-1", expected: "some-cluster-instance-manager-instance-group-1"},
+		{template: "nodes-{{.Name}}-{{.Hash}}", name: "instance-group-1", expected: "nodes-instance-group-1-"},
+		{template: "", name: strings.Repeat("a", 64), wantErr: true},
+		{template: "", name: strings.Repeat("a", 64), existingRole: "some-role", expected: "some-cluster-instance-manager-" + strings.Repeat("a", 64)},
+	}
+
+	for i, tc := range tests {
+		t.Logf("Test #%v - %+v", i, tc)
+		ig := MockInstanceGroup()
+		ig.SetName(tc.name)
+		ig.GetEKSConfiguration().SetClusterName("some-cluster")
+		ig.GetEKSConfiguration().SetRoleName(tc.existingRole)
+
+		ctx := New(provisioners.ProvisionerInput{
+			AwsWorker:     w,
+			Kubernetes:    k,
+			InstanceGroup: ig,
+			Log:           ctrl.Log.WithName("unit-test").WithName("InstanceGroup"),
+			ResourceNames: provisioners.ResourceNameConfiguration{Template: tc.template},
+		})
+
+		err := ctx.ValidateResourceNames()
+		if tc.wantErr {
+			g.Expect(err).To(gomega.HaveOccurred())
+			continue
+		}
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(ctx.ResourcePrefix).To(gomega.HavePrefix(tc.expected))
+	}
+}
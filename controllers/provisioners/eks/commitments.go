@@ -0,0 +1,106 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eks
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/savingsplans"
+	"github.com/keikoproj/instance-manager/api/v1alpha1"
+	"github.com/keikoproj/instance-manager/controllers/common"
+	awsprovider "github.com/keikoproj/instance-manager/controllers/providers/aws"
+	kubeprovider "github.com/keikoproj/instance-manager/controllers/providers/kubernetes"
+	"github.com/pkg/errors"
+)
+
+// AdviseCommitments records the coverage of the instance type and the candidate instance types of the commitment
+// advisor by reserved instances and savings plans, and the preferred instance type, in the status
+func (ctx *EksInstanceGroupContext) AdviseCommitments() error {
+	var (
+		instanceGroup = ctx.GetInstanceGroup()
+		configuration = instanceGroup.GetEKSConfiguration()
+		status        = instanceGroup.GetStatus()
+		state         = ctx.GetDiscoveredState()
+		instanceType  = configuration.InstanceType
+	)
+
+	if !configuration.HasCommitmentAdvisor() {
+		status.SetCommitments(nil)
+		status.SetPreferredInstanceType("")
+		return nil
+	}
+
+	reservations, err := ctx.AwsWorker.DescribeActiveReservedInstances()
+	if err != nil {
+		return errors.Wrap(err, "failed to describe reserved instances")
+	}
+	plans, err := ctx.AwsWorker.DescribeActiveSavingsPlans()
+	if err != nil {
+		return errors.Wrap(err, "failed to describe savings plans")
+	}
+
+	instanceTypes := []string{instanceType}
+	for _, candidate := range configuration.GetCommitmentCandidates() {
+		if !common.ContainsString(instanceTypes, candidate) {
+			instanceTypes = append(instanceTypes, candidate)
+		}
+	}
+
+	commitments := GetInstanceTypeCommitments(instanceTypes, reservations, plans, ctx.AwsWorker.Region)
+	preferred := PreferredInstanceType(commitments)
+	if preferred != instanceType && preferred != status.GetPreferredInstanceType() {
+		ctx.Log.Info("instance type is not covered by commitments", "instancegroup", instanceGroup.GetName(), "instanceType", instanceType, "preferredInstanceType", preferred)
+		state.Publisher.Publish(kubeprovider.UncoveredInstanceTypeEvent, "instancegroup", instanceGroup.GetName(), "instanceType", instanceType, "preferredInstanceType", preferred)
+	}
+	status.SetCommitments(commitments)
+	status.SetPreferredInstanceType(preferred)
+	return nil
+}
+
+// GetInstanceTypeCommitments returns the coverage of instance types by reserved instances and savings plans, an
+// instance type is covered by reserved instances or EC2 instance savings plans of its family
+func GetInstanceTypeCommitments(instanceTypes []string, reservations []*ec2.ReservedInstances, plans []*savingsplans.SavingsPlan, region string) []v1alpha1.InstanceTypeCommitment {
+	commitments := make([]v1alpha1.InstanceTypeCommitment, 0)
+	for _, instanceType := range instanceTypes {
+		commitment := v1alpha1.InstanceTypeCommitment{
+			InstanceType:      instanceType,
+			ReservedInstances: awsprovider.ReservedInstancesCovering(reservations, instanceType),
+		}
+		commitment.Covered = commitment.ReservedInstances > 0
+		for _, plan := range awsprovider.SavingsPlansCovering(plans, instanceType, region) {
+			commitment.SavingsPlans = append(commitment.SavingsPlans, aws.StringValue(plan.SavingsPlanId))
+			if aws.StringValue(plan.SavingsPlanType) == savingsplans.SavingsPlanTypeEc2instance {
+				commitment.Covered = true
+			}
+		}
+		commitments = append(commitments, commitment)
+	}
+	return commitments
+}
+
+// PreferredInstanceType returns the first covered instance type of the commitments, or the first instance type if
+// none are covered
+func PreferredInstanceType(commitments []v1alpha1.InstanceTypeCommitment) string {
+	if len(commitments) == 0 {
+		return ""
+	}
+	for _, commitment := range commitments {
+		if commitment.Covered {
+			return commitment.InstanceType
+		}
+	}
+	return commitments[0].InstanceType
+}
@@ -0,0 +1,107 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eks
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/savingsplans"
+	"github.com/keikoproj/instance-manager/api/v1alpha1"
+	"github.com/onsi/gomega"
+)
+
+func TestGetInstanceTypeCommitments(t *testing.T) {
+	var (
+		g            = gomega.NewGomegaWithT(t)
+		reservations = []*ec2.ReservedInstances{
+			MockReservedInstances("m5.xlarge", ec2.ScopeRegion, 2),
+			MockReservedInstances("c5.large", ec2.ScopeAvailabilityZone, 3),
+			MockReservedInstances("c5.2xlarge", ec2.ScopeAvailabilityZone, 1),
+		}
+		plans = []*savingsplans.SavingsPlan{
+			MockSavingsPlan("sp-compute", savingsplans.SavingsPlanTypeCompute, "", ""),
+			MockSavingsPlan("sp-r5", savingsplans.SavingsPlanTypeEc2instance, "r5", "us-west-2"),
+			MockSavingsPlan("sp-r5a", savingsplans.SavingsPlanTypeEc2instance, "r5a", "us-east-1"),
+		}
+	)
+
+	commitments := GetInstanceTypeCommitments([]string{"t3.large", "m5.large", "c5.xlarge", "c5.large", "r5.large", "r5a.large"}, reservations, plans, "us-west-2")
+	g.Expect(commitments).To(gomega.Equal([]v1alpha1.InstanceTypeCommitment{
+		// compute savings plans apply to all instance types but do not cover them
+		{InstanceType: "t3.large", SavingsPlans: []string{"sp-compute"}},
+		// regional reserved instances are size flexible within the family
+		{InstanceType: "m5.large", ReservedInstances: 4, SavingsPlans: []string{"sp-compute"}, Covered: true},
+		// zonal reserved instances only cover their instance type
+		{InstanceType: "c5.xlarge", SavingsPlans: []string{"sp-compute"}},
+		{InstanceType: "c5.large", ReservedInstances: 3, SavingsPlans: []string{"sp-compute"}, Covered: true},
+		// EC2 instance savings plans cover the family in their region
+		{InstanceType: "r5.large", SavingsPlans: []string{"sp-compute", "sp-r5"}, Covered: true},
+		{InstanceType: "r5a.large", SavingsPlans: []string{"sp-compute"}},
+	}))
+
+	g.Expect(PreferredInstanceType(commitments)).To(gomega.Equal("m5.large"))
+	g.Expect(PreferredInstanceType(commitments[2:3])).To(gomega.Equal("c5.xlarge"))
+	g.Expect(PreferredInstanceType(nil)).To(gomega.BeEmpty())
+}
+
+func TestAdviseCommitments(t *testing.T) {
+	var (
+		g             = gomega.NewGomegaWithT(t)
+		k             = MockKubernetesClientSet()
+		ig            = MockInstanceGroup()
+		configuration = ig.GetEKSConfiguration()
+		status        = ig.GetStatus()
+		asgMock       = NewAutoScalingMocker()
+		iamMock       = NewIamMocker()
+		eksMock       = NewEksMocker()
+		ec2Mock       = NewEc2Mocker()
+		spMock        = &MockSavingsPlansClient{}
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	w.SavingsPlansClient = spMock
+	w.Region = "us-west-2"
+	ctx := MockContext(ig, k, w)
+	ctx.GetDiscoveredState().Publisher.Client = k.Kubernetes
+	configuration.InstanceType = "m5.large"
+
+	// without an advisor nothing is reported
+	err := ctx.AdviseCommitments()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(status.GetCommitments()).To(gomega.BeEmpty())
+	g.Expect(status.GetPreferredInstanceType()).To(gomega.BeEmpty())
+
+	// a covered candidate is preferred over an uncovered instance type
+	configuration.CommitmentAdvisor = &v1alpha1.CommitmentAdvisorSpec{CandidateInstanceTypes: []string{"m5a.large", "m5.large", "m4.large"}}
+	ec2Mock.ReservedInstances = []*ec2.ReservedInstances{MockReservedInstances("m4.large", ec2.ScopeRegion, 5)}
+	err = ctx.AdviseCommitments()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(status.GetCommitments()).To(gomega.HaveLen(3))
+	g.Expect(status.GetPreferredInstanceType()).To(gomega.Equal("m4.large"))
+
+	// the instance type is preferred once it is covered
+	spMock.SavingsPlans = []*savingsplans.SavingsPlan{MockSavingsPlan("sp-m5", savingsplans.SavingsPlanTypeEc2instance, "m5", "us-west-2")}
+	err = ctx.AdviseCommitments()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(status.GetPreferredInstanceType()).To(gomega.Equal("m5.large"))
+
+	// removing the advisor clears the status
+	configuration.CommitmentAdvisor = nil
+	err = ctx.AdviseCommitments()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(status.GetCommitments()).To(gomega.BeNil())
+}
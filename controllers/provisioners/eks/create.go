@@ -46,6 +46,14 @@ func (ctx *EksInstanceGroupContext) Create() error {
 		spotPrice       = configuration.GetSpotPrice()
 	)
 
+	if err := ctx.ValidateImage(); err != nil {
+		return errors.Wrap(err, "failed to validate image")
+	}
+
+	if err := ctx.ValidatePlacement(); err != nil {
+		return errors.Wrap(err, "failed to validate placement")
+	}
+
 	instanceGroup.SetState(v1alpha1.ReconcileModifying)
 
 	// no need to create a role if one is already provided
@@ -56,6 +64,9 @@ func (ctx *EksInstanceGroupContext) Create() error {
 	instanceProfile := state.GetInstanceProfile()
 
 	if !scalingConfig.Provisioned() {
+		if err := ctx.UploadBootstrapObject(); err != nil {
+			return errors.Wrap(err, "failed to upload bootstrap object")
+		}
 		configName = fmt.Sprintf("%v-%v", ctx.ResourcePrefix, common.GetTimeString())
 		if err := scalingConfig.Create(&scaling.CreateConfigurationInput{
 			Name:                  configName,
@@ -64,9 +75,11 @@ func (ctx *EksInstanceGroupContext) Create() error {
 			InstanceType:          configuration.InstanceType,
 			KeyName:               configuration.KeyPairName,
 			SecurityGroups:        sgs,
-			Volumes:               configuration.Volumes,
+			Volumes:               ctx.GetVolumes(),
 			UserData:              userData,
 			SpotPrice:             spotPrice,
+			InstanceStoreVolumes:  ctx.GetInstanceStoreVolumes(),
+			SensitiveUserData:     len(configuration.GetSecretReferences()) > 0,
 		}); err != nil {
 			return errors.Wrap(err, "failed to create scaling configuration")
 		}
@@ -89,6 +102,7 @@ func (ctx *EksInstanceGroupContext) CreateScalingGroup(lcName string) error {
 		instanceGroup = ctx.GetInstanceGroup()
 		status        = instanceGroup.GetStatus()
 		spec          = instanceGroup.GetEKSSpec()
+		configuration = instanceGroup.GetEKSConfiguration()
 		state         = ctx.GetDiscoveredState()
 		asgName       = ctx.ResourcePrefix
 		tags          = ctx.GetAddedTags(asgName)
@@ -98,15 +112,24 @@ func (ctx *EksInstanceGroupContext) CreateScalingGroup(lcName string) error {
 		return nil
 	}
 
-	err := ctx.AwsWorker.CreateScalingGroup(&autoscaling.CreateAutoScalingGroupInput{
-		AutoScalingGroupName:    aws.String(asgName),
-		DesiredCapacity:         aws.Int64(spec.GetMinSize()),
-		LaunchConfigurationName: aws.String(lcName),
-		MinSize:                 aws.Int64(spec.GetMinSize()),
-		MaxSize:                 aws.Int64(spec.GetMaxSize()),
-		VPCZoneIdentifier:       aws.String(common.ConcatenateList(ctx.ResolveSubnets(), ",")),
-		Tags:                    tags,
-	})
+	input := &autoscaling.CreateAutoScalingGroupInput{
+		AutoScalingGroupName:             aws.String(asgName),
+		LaunchConfigurationName:          aws.String(lcName),
+		MinSize:                          aws.Int64(spec.GetMinSize()),
+		MaxSize:                          aws.Int64(spec.GetMaxSize()),
+		VPCZoneIdentifier:                aws.String(common.ConcatenateList(ctx.ResolveSubnets(), ",")),
+		Tags:                             tags,
+		NewInstancesProtectedFromScaleIn: aws.Bool(configuration.IsNewInstancesProtectedFromScaleIn()),
+		HealthCheckType:                  aws.String(configuration.GetHealthCheckType()),
+		HealthCheckGracePeriod:           configuration.GetHealthCheckGracePeriod(),
+	}
+
+	// when desired capacity is ignored it is left for the scaling group to default
+	if !spec.IsDesiredCapacityIgnored() {
+		input.DesiredCapacity = aws.Int64(spec.GetMinSize())
+	}
+
+	err := ctx.AwsWorker.CreateScalingGroup(input)
 	if err != nil {
 		return err
 	}
@@ -126,6 +149,14 @@ func (ctx *EksInstanceGroupContext) CreateScalingGroup(lcName string) error {
 		return err
 	}
 
+	if err := ctx.UpdateNotifications(asgName); err != nil {
+		return err
+	}
+
+	if err := ctx.UpdateAlarms(asgName); err != nil {
+		return err
+	}
+
 	state.Publisher.Publish(kubeprovider.InstanceGroupCreatedEvent, "instancegroup", instanceGroup.GetName(), "scalinggroup", asgName)
 	return nil
 }
@@ -135,7 +166,7 @@ func (ctx *EksInstanceGroupContext) CreateManagedRole() error {
 		instanceGroup = ctx.GetInstanceGroup()
 		state         = ctx.GetDiscoveredState()
 		configuration = instanceGroup.GetEKSConfiguration()
-		roleName      = ctx.ResourcePrefix
+		roleName      = ctx.GetManagedRoleName()
 	)
 
 	if configuration.HasExistingRole() {
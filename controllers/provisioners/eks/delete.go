@@ -16,13 +16,18 @@ limitations under the License.
 package eks
 
 import (
+	"strings"
+	"time"
+
 	"github.com/keikoproj/instance-manager/controllers/provisioners/eks/scaling"
 
 	"github.com/keikoproj/instance-manager/api/v1alpha1"
+	"github.com/keikoproj/instance-manager/controllers/common"
 	kubeprovider "github.com/keikoproj/instance-manager/controllers/providers/kubernetes"
 	"github.com/pkg/errors"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
 )
 
 func (ctx *EksInstanceGroupContext) Delete() error {
@@ -34,9 +39,30 @@ func (ctx *EksInstanceGroupContext) Delete() error {
 		scalingConfig = state.GetScalingConfiguration()
 	)
 
+	// drain the nodes before the scaling group is deleted
+	drained, err := ctx.DrainScalingGroupNodes()
+	if err != nil {
+		return errors.Wrap(err, "failed to drain nodes")
+	}
+	if !drained {
+		ctx.Log.Info("waiting for nodes to drain", "instancegroup", instanceGroup.GetName())
+		return nil
+	}
+
 	instanceGroup.SetState(v1alpha1.ReconcileDeleting)
+	for _, group := range state.GetRetiringScalingGroups() {
+		if err := ctx.DeleteRetiringScalingGroup(group); err != nil {
+			return errors.Wrap(err, "failed to delete replaced scaling group")
+		}
+	}
+
+	// alarms are discovered with the scaling group, they are deleted first so they are not left behind
+	if err := ctx.DeleteAlarms(); err != nil {
+		return errors.Wrap(err, "failed to delete alarms")
+	}
+
 	// delete scaling group
-	err := ctx.DeleteScalingGroup()
+	err = ctx.DeleteScalingGroup()
 	if err != nil {
 		return errors.Wrap(err, "failed to delete scaling group")
 	}
@@ -60,6 +86,7 @@ func (ctx *EksInstanceGroupContext) Delete() error {
 		return errors.Wrap(err, "failed to delete scaling group role")
 	}
 
+	ctx.DeleteDeprecationMetrics()
 	return nil
 }
 
@@ -84,6 +111,94 @@ func (ctx *EksInstanceGroupContext) DeleteScalingGroup() error {
 	return nil
 }
 
+// DrainScalingGroupNodes cordons and drains the nodes of the scaling group and the scaling groups it replaces, and
+// returns true once they are drained. If the drain timeout has expired since the instance group was deleted, an error
+// is returned unless force is set or the PDB stall policy is to wait. Pods deleted by force are waited for until they
+// are gone, or until the drain timeout expires again.
+func (ctx *EksInstanceGroupContext) DrainScalingGroupNodes() (bool, error) {
+	var (
+		instanceGroup = ctx.GetInstanceGroup()
+		drainSpec     = instanceGroup.GetUpgradeStrategy().GetDrain()
+		state         = ctx.GetDiscoveredState()
+		timeout       = time.Duration(drainSpec.GetTimeoutSeconds()) * time.Second
+	)
+
+	if !state.HasScalingGroup() && !state.HasRetiringScalingGroups() {
+		return true, nil
+	}
+
+	var timedOut, forceTimedOut bool
+	if deletionTimestamp := instanceGroup.GetDeletionTimestamp(); deletionTimestamp != nil {
+		timedOut = time.Since(deletionTimestamp.Time) > timeout
+		forceTimedOut = time.Since(deletionTimestamp.Time) > 2*timeout
+	}
+	force := timedOut && drainSpec.IsForce()
+
+	instanceIds := make([]string, 0)
+	for _, group := range append([]*autoscaling.Group{state.GetScalingGroup()}, state.GetRetiringScalingGroups()...) {
+		for _, instance := range group.Instances {
+			instanceIds = append(instanceIds, aws.StringValue(instance.InstanceId))
+		}
+	}
+
+	drained, err := ctx.DrainNodes(instanceIds, force)
+	if err != nil {
+		return false, err
+	}
+
+	if drained || (force && forceTimedOut) {
+		return true, nil
+	}
+	if force {
+		ctx.Log.Info("waiting for pods deleted by force to terminate", "instancegroup", instanceGroup.GetName())
+		return false, nil
+	}
+
+	if timedOut && !drainSpec.IsWaitOnPDBStall() {
+		return false, errors.Errorf("nodes were not drained within %v", timeout)
+	}
+	return false, nil
+}
+
+// DrainNodes cordons and drains the nodes of instances, and returns true once they are drained
+func (ctx *EksInstanceGroupContext) DrainNodes(instanceIds []string, force bool) (bool, error) {
+	var (
+		drainSpec = ctx.GetInstanceGroup().GetUpgradeStrategy().GetDrain()
+		nodes     = ctx.GetDiscoveredState().GetClusterNodes()
+		kube      = ctx.ClusterKubernetesClient.Kubernetes
+	)
+
+	if !drainSpec.IsEnabled() || nodes == nil {
+		return true, nil
+	}
+
+	drained := true
+	for _, node := range nodes.Items {
+		instanceId := common.GetLastElementBy(node.Spec.ProviderID, "/")
+		if !common.ContainsString(instanceIds, instanceId) {
+			continue
+		}
+
+		ok, err := kubeprovider.PrepareNodeDrain(kube, node, drainSpec.GetPreDrain())
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to cordon node %v", node.GetName())
+		}
+		if !ok {
+			drained = false
+			continue
+		}
+
+		ok, err = kubeprovider.DrainNode(kube, node.GetName(), drainSpec, force)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to drain node %v", node.GetName())
+		}
+		if !ok {
+			drained = false
+		}
+	}
+	return drained, nil
+}
+
 func (ctx *EksInstanceGroupContext) DeleteManagedRole() error {
 	var (
 		instanceGroup      = ctx.GetInstanceGroup()
@@ -98,6 +213,18 @@ func (ctx *EksInstanceGroupContext) DeleteManagedRole() error {
 		return nil
 	}
 
+	// a shared role is only deleted when the last instance group using it is deleted
+	if configuration.HasSharedRole() {
+		references, err := ctx.GetRoleReferences(aws.StringValue(role.Arn))
+		if err != nil {
+			return errors.Wrap(err, "failed to list role references")
+		}
+		if len(references) > 1 {
+			ctx.Log.Info("skipping deletion of shared role, is used by another instancegroup", "instancegroup", instanceGroup.GetName(), "iamrole", roleName, "references", strings.Join(references, ","))
+			return nil
+		}
+	}
+
 	managedPolicies := ctx.GetManagedPoliciesList(additionalPolicies)
 
 	err := ctx.AwsWorker.DeleteScalingGroupRole(roleName, managedPolicies)
@@ -17,6 +17,7 @@ package eks
 
 import (
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
@@ -28,7 +29,13 @@ import (
 	"github.com/keikoproj/instance-manager/controllers/provisioners/eks/scaling"
 	"github.com/onsi/gomega"
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 )
 
 func TestDeletePositive(t *testing.T) {
@@ -236,3 +243,268 @@ func TestRemoveAuthRoleNegative(t *testing.T) {
 	g.Expect(err).NotTo(gomega.HaveOccurred())
 	g.Expect(len(auth.MapRoles)).To(gomega.Equal(0))
 }
+
+func TestDeleteSharedRole(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		ig2     = MockInstanceGroup()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ig.GetEKSConfiguration().SetClusterName("some-cluster")
+	ig.GetEKSConfiguration().SharedRoleName = "workers"
+	ctx := MockContext(ig, k, w)
+	g.Expect(ctx.GetManagedRoleName()).To(gomega.Equal("some-cluster-workers"))
+
+	// two instancegroups with the same shared role
+	ig.Status.NodesArn = "shared-role"
+	ig2.Name = "instance-group-2"
+	ig2.Status.NodesArn = "shared-role"
+
+	igObj, err := kubeprovider.GetUnstructuredInstanceGroup(ig)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	ig2Obj, err := kubeprovider.GetUnstructuredInstanceGroup(ig2)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	_, err = ctx.KubernetesClient.KubeDynamic.Resource(v1alpha1.GroupVersionResource).Namespace(ig.Namespace).Create(igObj, metav1.CreateOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	_, err = ctx.KubernetesClient.KubeDynamic.Resource(v1alpha1.GroupVersionResource).Namespace(ig2.Namespace).Create(ig2Obj, metav1.CreateOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	ctx.SetDiscoveredState(&DiscoveredState{
+		Publisher: kubeprovider.EventPublisher{
+			Client: k.Kubernetes,
+		},
+		IAMRole: &iam.Role{
+			Arn:      aws.String("shared-role"),
+			RoleName: aws.String("some-cluster-workers"),
+		},
+		ScalingConfiguration: &scaling.LaunchConfiguration{
+			AwsWorker: w,
+		},
+	})
+
+	// the role is still referenced by another instancegroup
+	err = ctx.DeleteManagedRole()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(iamMock.DeleteRoleCallCount).To(gomega.Equal(0))
+
+	// the last instancegroup using the role deletes it
+	err = ctx.KubernetesClient.KubeDynamic.Resource(v1alpha1.GroupVersionResource).Namespace(ig2.Namespace).Delete(ig2.Name, &metav1.DeleteOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	err = ctx.DeleteManagedRole()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(iamMock.DeleteRoleCallCount).To(gomega.Equal(1))
+}
+
+func TestDeleteDrainNodes(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+
+	node := MockNode("i-000000000", corev1.ConditionTrue)
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod-1",
+			Namespace: "default",
+		},
+		Spec: corev1.PodSpec{
+			NodeName: node.GetName(),
+		},
+	}
+	k.Kubernetes.CoreV1().Nodes().Create(node)
+	k.Kubernetes.CoreV1().Pods("default").Create(pod)
+
+	// the fake clientset does not support evictions
+	k.Kubernetes.(*fake.Clientset).PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return action.GetSubresource() == "eviction", nil, nil
+	})
+
+	scalingGroup := MockScalingGroup("asg-1")
+	scalingGroup.Instances = MockScalingInstances(1, 0)
+
+	ctx.SetDiscoveredState(&DiscoveredState{
+		Publisher: kubeprovider.EventPublisher{
+			Client: k.Kubernetes,
+		},
+		ScalingGroup: scalingGroup,
+		ScalingConfiguration: &scaling.LaunchConfiguration{
+			AwsWorker: w,
+		},
+		ClusterNodes: &corev1.NodeList{Items: []corev1.Node{*node}},
+		IAMRole:      &iam.Role{},
+	})
+
+	// pods are still running, wait for drain
+	deletionTime := metav1.NewTime(time.Now())
+	ig.SetDeletionTimestamp(&deletionTime)
+	ig.SetState(v1alpha1.ReconcileInitDelete)
+	err := ctx.Delete()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(ctx.GetState()).To(gomega.Equal(v1alpha1.ReconcileInitDelete))
+
+	cordoned, err := k.Kubernetes.CoreV1().Nodes().Get(node.GetName(), metav1.GetOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(cordoned.Spec.Unschedulable).To(gomega.BeTrue())
+
+	// drain timeout expired without force
+	deletionTime = metav1.NewTime(time.Now().Add(-time.Hour))
+	ig.SetDeletionTimestamp(&deletionTime)
+	err = ctx.Delete()
+	g.Expect(err).To(gomega.HaveOccurred())
+
+	// drain timeout expired with force, deleted pods are waited for until they are gone
+	deletionTime = metav1.NewTime(time.Now().Add(-6 * time.Minute))
+	ig.SetDeletionTimestamp(&deletionTime)
+	ig.GetUpgradeStrategy().SetDrain(&v1alpha1.DrainSpec{Force: true})
+	err = ctx.Delete()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(ctx.GetState()).To(gomega.Equal(v1alpha1.ReconcileInitDelete))
+
+	_, err = k.Kubernetes.CoreV1().Pods("default").Get(pod.GetName(), metav1.GetOptions{})
+	g.Expect(err).To(gomega.HaveOccurred())
+
+	err = ctx.Delete()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(ctx.GetState()).To(gomega.Equal(v1alpha1.ReconcileDeleting))
+
+	// pods which do not terminate are not waited for once the drain timeout expired again
+	terminating := pod.DeepCopy()
+	terminating.SetDeletionTimestamp(&deletionTime)
+	k.Kubernetes.CoreV1().Pods("default").Create(terminating)
+	ig.SetState(v1alpha1.ReconcileInitDelete)
+	err = ctx.Delete()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(ctx.GetState()).To(gomega.Equal(v1alpha1.ReconcileInitDelete))
+
+	deletionTime = metav1.NewTime(time.Now().Add(-time.Hour))
+	ig.SetDeletionTimestamp(&deletionTime)
+	err = ctx.Delete()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(ctx.GetState()).To(gomega.Equal(v1alpha1.ReconcileDeleting))
+	k.Kubernetes.CoreV1().Pods("default").Delete(pod.GetName(), &metav1.DeleteOptions{})
+
+	// drain disabled
+	ig.GetUpgradeStrategy().SetDrain(&v1alpha1.DrainSpec{Disabled: true})
+	k.Kubernetes.CoreV1().Pods("default").Create(pod)
+	ig.SetState(v1alpha1.ReconcileInitDelete)
+	err = ctx.Delete()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(ctx.GetState()).To(gomega.Equal(v1alpha1.ReconcileDeleting))
+}
+
+func TestDeleteDrainBehavior(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+
+	node := MockNode("i-000000000", corev1.ConditionTrue)
+	daemonSetPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "daemonset-pod",
+			Namespace: "default",
+			OwnerReferences: []metav1.OwnerReference{
+				{Kind: kubeprovider.DaemonSetKind, Name: "daemonset", Controller: aws.Bool(true)},
+			},
+		},
+		Spec: corev1.PodSpec{
+			NodeName: node.GetName(),
+		},
+	}
+	emptyDirPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "emptydir-pod",
+			Namespace: "default",
+		},
+		Spec: corev1.PodSpec{
+			NodeName: node.GetName(),
+			Volumes: []corev1.Volume{
+				{Name: "cache", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+			},
+		},
+	}
+	k.Kubernetes.CoreV1().Nodes().Create(node)
+	k.Kubernetes.CoreV1().Pods("default").Create(daemonSetPod)
+	k.Kubernetes.CoreV1().Pods("default").Create(emptyDirPod)
+
+	// evictions are refused by a disruption budget
+	var evictions []*policyv1beta1.Eviction
+	k.Kubernetes.(*fake.Clientset).PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		evictions = append(evictions, action.(k8stesting.CreateAction).GetObject().(*policyv1beta1.Eviction))
+		return true, nil, kerrors.NewTooManyRequests("disruption budget", 0)
+	})
+
+	scalingGroup := MockScalingGroup("asg-1")
+	scalingGroup.Instances = MockScalingInstances(1, 0)
+
+	ctx.SetDiscoveredState(&DiscoveredState{
+		Publisher: kubeprovider.EventPublisher{
+			Client: k.Kubernetes,
+		},
+		ScalingGroup: scalingGroup,
+		ScalingConfiguration: &scaling.LaunchConfiguration{
+			AwsWorker: w,
+		},
+		ClusterNodes: &corev1.NodeList{Items: []corev1.Node{*node}},
+		IAMRole:      &iam.Role{},
+	})
+
+	deletionTime := metav1.NewTime(time.Now())
+	ig.SetDeletionTimestamp(&deletionTime)
+
+	// daemonset pods are not skipped
+	ig.GetUpgradeStrategy().SetDrain(&v1alpha1.DrainSpec{SkipDaemonSets: aws.Bool(false)})
+	g.Expect(ctx.Delete()).NotTo(gomega.Succeed())
+
+	// emptyDir pods are not deleted
+	ig.GetUpgradeStrategy().SetDrain(&v1alpha1.DrainSpec{DeleteEmptyDirData: aws.Bool(false)})
+	g.Expect(ctx.Delete()).NotTo(gomega.Succeed())
+	g.Expect(evictions).To(gomega.BeEmpty())
+
+	// grace period is passed to evictions, the daemonset pod is skipped
+	ig.GetUpgradeStrategy().SetDrain(&v1alpha1.DrainSpec{GracePeriodSeconds: aws.Int64(10)})
+	ig.SetState(v1alpha1.ReconcileInitDelete)
+	g.Expect(ctx.Delete()).To(gomega.Succeed())
+	g.Expect(ctx.GetState()).To(gomega.Equal(v1alpha1.ReconcileInitDelete))
+	g.Expect(evictions).To(gomega.HaveLen(1))
+	g.Expect(evictions[0].GetName()).To(gomega.Equal(emptyDirPod.GetName()))
+	g.Expect(aws.Int64Value(evictions[0].DeleteOptions.GracePeriodSeconds)).To(gomega.Equal(int64(10)))
+
+	// drain timeout expired with the default stall policy
+	deletionTime = metav1.NewTime(time.Now().Add(-time.Hour))
+	ig.SetDeletionTimestamp(&deletionTime)
+	g.Expect(ctx.Delete()).NotTo(gomega.Succeed())
+
+	// drain timeout expired with the wait stall policy
+	ig.GetUpgradeStrategy().SetDrain(&v1alpha1.DrainSpec{PDBStallPolicy: v1alpha1.WaitPDBStallPolicy})
+	g.Expect(ctx.Delete()).To(gomega.Succeed())
+	g.Expect(ctx.GetState()).To(gomega.Equal(v1alpha1.ReconcileInitDelete))
+}
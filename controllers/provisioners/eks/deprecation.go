@@ -0,0 +1,184 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eks
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/keikoproj/instance-manager/api/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	// images can declare the time they are deprecated with this tag, in RFC 3339 format
+	ImageDeprecationTimeTagKey = "instancemgr.keikoproj.io/deprecation-time"
+	// a warning is raised this long before an image is deprecated or the cluster version reaches its end of support
+	DeprecationWarningPeriod = 30 * 24 * time.Hour
+
+	ImageDeprecatedReason             = "ImageDeprecated"
+	ImageDeprecationNearReason        = "ImageDeprecationNear"
+	ClusterVersionUnsupportedReason   = "ClusterVersionEndOfSupport"
+	ClusterVersionSupportEndingReason = "ClusterVersionEndOfSupportNear"
+
+	DeprecationKindImage          = "image"
+	DeprecationKindClusterVersion = "cluster-version"
+)
+
+var (
+	// EKSVersionEndOfSupport is the date on which the standard support of each EKS version ends
+	EKSVersionEndOfSupport = map[string]string{
+		"1.10": "2019-07-22",
+		"1.11": "2019-11-04",
+		"1.12": "2020-05-11",
+		"1.13": "2020-06-30",
+		"1.14": "2020-12-08",
+		"1.15": "2021-05-03",
+		"1.16": "2021-09-27",
+		"1.17": "2021-11-02",
+		"1.18": "2022-03-31",
+		"1.19": "2022-08-01",
+		"1.20": "2022-11-01",
+		"1.21": "2023-02-15",
+		"1.22": "2023-06-04",
+		"1.23": "2023-10-11",
+		"1.24": "2024-01-31",
+		"1.25": "2024-05-01",
+		"1.26": "2024-06-11",
+		"1.27": "2024-07-24",
+		"1.28": "2024-11-26",
+		"1.29": "2025-03-23",
+		"1.30": "2025-07-23",
+		"1.31": "2025-11-26",
+	}
+
+	// DeprecationTimeMetric is the unix time at which the image of an instance group is deprecated, or the version of
+	// its cluster reaches its end of support
+	DeprecationTimeMetric = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "instance_manager_deprecation_timestamp_seconds",
+		Help: "The unix time at which the image of an instance group is deprecated or its cluster version reaches its end of support",
+	}, []string{"namespace", "instancegroup", "kind"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(DeprecationTimeMetric)
+}
+
+// GetImageDeprecationTime returns the time an image is deprecated from its tag, or nil if it is unknown
+func GetImageDeprecationTime(image *ec2.Image) *time.Time {
+	if image == nil {
+		return nil
+	}
+	for _, tag := range image.Tags {
+		if aws.StringValue(tag.Key) != ImageDeprecationTimeTagKey {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, aws.StringValue(tag.Value))
+		if err != nil {
+			return nil
+		}
+		return &t
+	}
+	return nil
+}
+
+// GetClusterVersionEndOfSupport returns the time the standard support of an EKS version ends, or nil if it is unknown
+func GetClusterVersionEndOfSupport(version string) *time.Time {
+	date, ok := EKSVersionEndOfSupport[version]
+	if !ok {
+		return nil
+	}
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+// CheckDeprecation sets a Deprecated condition when the image is deprecated or the cluster version reaches its end of
+// support within DeprecationWarningPeriod, or has already, and exports both times as metrics
+func (ctx *EksInstanceGroupContext) CheckDeprecation(now time.Time) {
+	var (
+		instanceGroup = ctx.GetInstanceGroup()
+		status        = instanceGroup.GetStatus()
+		state         = ctx.GetDiscoveredState()
+		image         = state.GetImage()
+		version       = state.GetClusterVersion()
+		reasons       = make([]string, 0)
+		messages      = make([]string, 0)
+	)
+
+	if t := GetImageDeprecationTime(image); t != nil {
+		ctx.setDeprecationMetric(DeprecationKindImage, t)
+		imageId := aws.StringValue(image.ImageId)
+		if !now.Before(*t) {
+			reasons = append(reasons, ImageDeprecatedReason)
+			messages = append(messages, fmt.Sprintf("image %v was deprecated on %v", imageId, t.Format(time.RFC3339)))
+		} else if t.Sub(now) <= DeprecationWarningPeriod {
+			reasons = append(reasons, ImageDeprecationNearReason)
+			messages = append(messages, fmt.Sprintf("image %v will be deprecated on %v", imageId, t.Format(time.RFC3339)))
+		}
+	} else {
+		ctx.deleteDeprecationMetric(DeprecationKindImage)
+	}
+
+	if t := GetClusterVersionEndOfSupport(version); t != nil {
+		ctx.setDeprecationMetric(DeprecationKindClusterVersion, t)
+		if !now.Before(*t) {
+			reasons = append(reasons, ClusterVersionUnsupportedReason)
+			messages = append(messages, fmt.Sprintf("cluster version %v reached its end of support on %v", version, t.Format("2006-01-02")))
+		} else if t.Sub(now) <= DeprecationWarningPeriod {
+			reasons = append(reasons, ClusterVersionSupportEndingReason)
+			messages = append(messages, fmt.Sprintf("cluster version %v reaches its end of support on %v", version, t.Format("2006-01-02")))
+		}
+	} else {
+		ctx.deleteDeprecationMetric(DeprecationKindClusterVersion)
+	}
+
+	if len(reasons) == 0 {
+		status.RemoveCondition(v1alpha1.Deprecated)
+		return
+	}
+
+	if existing := status.GetCondition(v1alpha1.Deprecated); existing == nil || existing.Reason != reasons[0] {
+		ctx.Log.Info("image or cluster version is deprecated", "instancegroup", instanceGroup.GetName(), "message", strings.Join(messages, ", "))
+	}
+	condition := v1alpha1.NewInstanceGroupCondition(v1alpha1.Deprecated, corev1.ConditionTrue)
+	condition.Reason = reasons[0]
+	condition.Message = strings.Join(messages, ", ")
+	status.SetCondition(condition)
+}
+
+// DeleteDeprecationMetrics removes the deprecation metrics of a deleted instance group
+func (ctx *EksInstanceGroupContext) DeleteDeprecationMetrics() {
+	ctx.deleteDeprecationMetric(DeprecationKindImage)
+	ctx.deleteDeprecationMetric(DeprecationKindClusterVersion)
+}
+
+func (ctx *EksInstanceGroupContext) setDeprecationMetric(kind string, t *time.Time) {
+	instanceGroup := ctx.GetInstanceGroup()
+	DeprecationTimeMetric.WithLabelValues(instanceGroup.GetNamespace(), instanceGroup.GetName(), kind).Set(float64(t.Unix()))
+}
+
+func (ctx *EksInstanceGroupContext) deleteDeprecationMetric(kind string) {
+	instanceGroup := ctx.GetInstanceGroup()
+	DeprecationTimeMetric.DeleteLabelValues(instanceGroup.GetNamespace(), instanceGroup.GetName(), kind)
+}
@@ -0,0 +1,94 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eks
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/keikoproj/instance-manager/api/v1alpha1"
+	"github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCheckDeprecation(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+		now     = time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+	state := ctx.GetDiscoveredState()
+	status := ig.GetStatus()
+
+	image := func(deprecationTime string) *ec2.Image {
+		image := &ec2.Image{ImageId: aws.String("ami-123456789012")}
+		if deprecationTime != "" {
+			image.Tags = []*ec2.Tag{{Key: aws.String(ImageDeprecationTimeTagKey), Value: aws.String(deprecationTime)}}
+		}
+		return image
+	}
+
+	tests := []struct {
+		clusterVersion  string
+		deprecationTime string
+		expectedReason  string
+	}{
+		{clusterVersion: "1.21", deprecationTime: "", expectedReason: ""},
+		{clusterVersion: "1.99", deprecationTime: "2023-01-01T00:00:00Z", expectedReason: ""},
+		{clusterVersion: "1.99", deprecationTime: "2022-03-15T00:00:00Z", expectedReason: ImageDeprecationNearReason},
+		{clusterVersion: "1.99", deprecationTime: "2022-02-01T00:00:00Z", expectedReason: ImageDeprecatedReason},
+		{clusterVersion: "1.18", deprecationTime: "", expectedReason: ClusterVersionSupportEndingReason},
+		{clusterVersion: "1.17", deprecationTime: "", expectedReason: ClusterVersionUnsupportedReason},
+		{clusterVersion: "1.17", deprecationTime: "2022-02-01T00:00:00Z", expectedReason: ImageDeprecatedReason},
+		{clusterVersion: "1.99", deprecationTime: "not-a-time", expectedReason: ""},
+	}
+
+	for i, tc := range tests {
+		t.Logf("Test #%v - %+v", i, tc)
+		state.SetCluster(&eks.Cluster{Version: aws.String(tc.clusterVersion)})
+		state.SetImage(image(tc.deprecationTime))
+		ctx.CheckDeprecation(now)
+
+		condition := status.GetCondition(v1alpha1.Deprecated)
+		if tc.expectedReason == "" {
+			g.Expect(condition).To(gomega.BeNil())
+			continue
+		}
+		g.Expect(condition).NotTo(gomega.BeNil())
+		g.Expect(condition.Reason).To(gomega.Equal(tc.expectedReason))
+	}
+
+	// the end of support of known versions is exported as a metric, and removed when the instance group is deleted
+	state.SetCluster(&eks.Cluster{Version: aws.String("1.18")})
+	state.SetImage(image(""))
+	ctx.CheckDeprecation(now)
+	endOfSupport := DeprecationTimeMetric.WithLabelValues(ig.GetNamespace(), ig.GetName(), DeprecationKindClusterVersion)
+	g.Expect(testutil.ToFloat64(endOfSupport)).To(gomega.Equal(float64(time.Date(2022, 3, 31, 0, 0, 0, 0, time.UTC).Unix())))
+
+	ctx.DeleteDeprecationMetrics()
+	g.Expect(DeprecationTimeMetric.DeleteLabelValues(ig.GetNamespace(), ig.GetName(), DeprecationKindClusterVersion)).To(gomega.BeFalse())
+}
@@ -16,7 +16,7 @@ limitations under the License.
 package eks
 
 import (
-	"fmt"
+	"regexp"
 	"sync"
 
 	"github.com/go-logr/logr"
@@ -31,6 +31,50 @@ const (
 	ProvisionerName                     = "eks"
 	defaultLaunchConfigurationRetention = 2
 	OverrideDefaultLabelsAnnotationKey  = "instancemgr.keikoproj.io/default-labels"
+
+	PendingChangeCreate              = "Create"
+	PendingChangeLaunchConfiguration = "LaunchConfiguration"
+	PendingChangeScalingGroup        = "ScalingGroup"
+	PendingChangeTags                = "Tags"
+	PendingChangeRotation            = "Rotation"
+	PendingChangeScaleInProtection   = "ScaleInProtection"
+	PendingChangeMigration           = "Migration"
+
+	NvidiaGPUKey       = "nvidia.com/gpu"
+	NvidiaManufacturer = "NVIDIA"
+	NeuronKey          = "aws.amazon.com/neuron"
+	NeuronManufacturer = "AWS"
+
+	// custom images can declare the kubelet version they include with this tag, e.g. 1.18
+	ImageKubernetesVersionTagKey = "instancemgr.keikoproj.io/kubernetes-version"
+	// the number of minor versions a kubelet may be older than the cluster
+	MaxKubeletVersionSkew    = 2
+	KubeletVersionSkewReason = "KubeletVersionSkew"
+	// the image does not exist, e.g. since it was deregistered
+	ImageNotFoundReason = "ImageNotFound"
+
+	// SSM parameter of the recommended EKS optimized image for a cluster version and image variant
+	EKSOptimizedImageParameterFmt = "/aws/service/eks/optimized-ami/%v/%v/recommended/image_id"
+	EKSOptimizedImageVariant      = "amazon-linux-2"
+	EKSOptimizedGPUImageVariant   = "amazon-linux-2-gpu"
+	EKSOptimizedArm64ImageVariant = "amazon-linux-2-arm64"
+
+	// rendered user data larger than this is gzip compressed
+	UserDataCompressionThreshold = 12288
+
+	// user data which downloads the bootstrap payload from S3, verifies its hash and executes it
+	BootstrapStubFmt = `#!/bin/bash
+set -o errexit
+token=$(curl -s -X PUT http://169.254.169.254/latest/api/token -H "X-aws-ec2-metadata-token-ttl-seconds: 60")
+region=$(curl -s -H "X-aws-ec2-metadata-token: $token" http://169.254.169.254/latest/meta-data/placement/availability-zone | sed 's/[a-z]$//')
+aws s3 cp --region $region s3://%[1]v/%[2]v /tmp/bootstrap.sh
+echo "%[3]v  /tmp/bootstrap.sh" | sha256sum -c -
+/bin/bash /tmp/bootstrap.sh
+`
+
+	ClusterAutoscalerResourceTagFmt = "k8s.io/cluster-autoscaler/node-template/resources/%v"
+	ClusterAutoscalerLabelTagFmt    = "k8s.io/cluster-autoscaler/node-template/label/%v"
+	ClusterAutoscalerTaintTagFmt    = "k8s.io/cluster-autoscaler/node-template/taint/%v"
 )
 
 var (
@@ -38,6 +82,22 @@ var (
 	RoleOldLabelFmt     = "node-role.kubernetes.io/%s=\"\""
 	InstanceMgrLabelFmt = "instancemgr.keikoproj.io/%s=%s"
 
+	// name prefixes of the EKS optimized images which do not include GPU drivers
+	StandardEKSImagePrefixes = []string{"amazon-eks-node-", "amazon-eks-arm64-node-"}
+
+	// device names which are commonly used for root volumes, if the first volume uses one of these names but does not
+	// match the image's root device name, it is treated as the root volume
+	RootDeviceNames = []string{"/dev/xvda", "/dev/xvda1", "/dev/sda", "/dev/sda1"}
+
+	// Inferentia and Trainium instance families, these use AWS Neuron devices
+	NeuronInstanceFamilies = []string{"inf1", "inf2", "trn1"}
+
+	// matches the kubelet version in the names of EKS optimized and Bottlerocket images
+	ImageKubernetesVersionPattern = regexp.MustCompile(`^(?:amazon-eks(?:-gpu|-arm64)?-node|bottlerocket-aws-k8s)-(\d+\.\d+)-`)
+
+	// reasons of the degraded condition which are set when discovering the image
+	ImageDegradedReasons = []string{ImageNotFoundReason, KubeletVersionSkewReason}
+
 	DefaultManagedPolicies = []string{"AmazonEKSWorkerNodePolicy", "AmazonEKS_CNI_Policy", "AmazonEC2ContainerRegistryReadOnly"}
 )
 
@@ -51,15 +111,34 @@ func New(p provisioners.ProvisionerInput) *EksInstanceGroupContext {
 		configHash    = kubeprovider.ConfigmapHash(p.Configuration)
 	)
 
+	// the template is validated on startup, a render error is returned by cloud discovery
+	resourcePrefix, resourceNameErr := p.ResourceNames.Render(provisioners.ResourceNameInput{
+		ClusterName: configuration.GetClusterName(),
+		Namespace:   instanceGroup.GetNamespace(),
+		Name:        instanceGroup.GetName(),
+	})
+
+	// nodes join the cluster the controller runs in unless the instance group refers to another cluster
+	clusterClient := p.ClusterKubernetes
+	if clusterClient.Kubernetes == nil {
+		clusterClient = p.Kubernetes
+	}
+
 	ctx := &EksInstanceGroupContext{
-		InstanceGroup:    instanceGroup,
-		KubernetesClient: p.Kubernetes,
-		AwsWorker:        p.AwsWorker,
-		Log:              p.Log.WithName("eks"),
-		ResourcePrefix:   fmt.Sprintf("%v-%v-%v", configuration.GetClusterName(), instanceGroup.GetNamespace(), instanceGroup.GetName()),
-		ConfigRetention:  p.ConfigRetention,
+		InstanceGroup:           instanceGroup,
+		KubernetesClient:        p.Kubernetes,
+		ClusterKubernetesClient: clusterClient,
+		AwsWorker:               p.AwsWorker,
+		Log:                     p.Log.WithName("eks"),
+		ResourcePrefix:          resourcePrefix,
+		ResourceNameErr:         resourceNameErr,
+		ConfigRetention:         p.ConfigRetention,
+		BootstrapBucket:         p.BootstrapBucket,
+		SecurityPolicy:          p.SecurityPolicy,
 	}
 
+	ctx.SetLifecycleManagerDefaults(p.LifecycleManager)
+
 	instanceGroup.SetState(v1alpha1.ReconcileInit)
 	status.SetConfigHash(configHash)
 	status.SetProvisioner(ProvisionerName)
@@ -72,12 +151,26 @@ type EksInstanceGroupContext struct {
 	sync.Mutex
 	InstanceGroup    *v1alpha1.InstanceGroup
 	KubernetesClient kubeprovider.KubernetesClientSet
-	AwsWorker        awsprovider.AwsWorker
-	DiscoveredState  *DiscoveredState
-	Log              logr.Logger
-	Configuration    *provisioners.ProvisionerConfiguration
-	ConfigRetention  int
-	ResourcePrefix   string
+	// ClusterKubernetesClient is the client set of the cluster the nodes join, used for nodes, aws-auth, draining,
+	// verification jobs and upgrade custom resources
+	ClusterKubernetesClient kubeprovider.KubernetesClientSet
+	AwsWorker               awsprovider.AwsWorker
+	DiscoveredState         *DiscoveredState
+	Log                     logr.Logger
+	Configuration           *provisioners.ProvisionerConfiguration
+	ConfigRetention         int
+	ResourcePrefix          string
+	ResourceNameErr         error
+	BootstrapBucket         provisioners.BootstrapBucketConfiguration
+	BootstrapObject         *BootstrapObject
+	SecurityPolicy          v1alpha1.SecurityPolicy
+}
+
+// BootstrapObject is user data which is uploaded to the bootstrap bucket and downloaded by instances at boot
+type BootstrapObject struct {
+	Key  string
+	Hash string
+	Data []byte
 }
 
 type UserDataPayload struct {
@@ -93,11 +186,23 @@ type MountOpts struct {
 }
 
 type EKSUserData struct {
-	ClusterName   string
-	Arguments     string
-	PreBootstrap  []string
-	PostBootstrap []string
-	MountOptions  []MountOpts
+	ClusterName         string
+	Arguments           string
+	PreBootstrap        []string
+	PostBootstrap       []string
+	MountOptions        []MountOpts
+	InstanceStorePolicy string
+	SecretsHash         string
+	RotationCounter     int
+	// APIServerEndpoint, CertificateAuthority and ClusterCIDR are the cluster data of images which do not describe
+	// the cluster at boot
+	APIServerEndpoint    string
+	CertificateAuthority string
+	ClusterCIDR          string
+	// NodeLabels and NodeTaints are the labels and taints of nodes, of the form key=value and key=value:Effect
+	NodeLabels       []string
+	NodeTaints       []string
+	KubeletExtraArgs string
 }
 
 func (ctx *EksInstanceGroupContext) GetInstanceGroup() *v1alpha1.InstanceGroup {
@@ -16,30 +16,54 @@ limitations under the License.
 package eks
 
 import (
+	"bytes"
+	"encoding/base64"
 	"flag"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/autoscaling"
 	"github.com/aws/aws-sdk-go/service/autoscaling/autoscalingiface"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
 	"github.com/aws/aws-sdk-go/service/eks"
 	"github.com/aws/aws-sdk-go/service/eks/eksiface"
 	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/imagebuilder"
+	"github.com/aws/aws-sdk-go/service/imagebuilder/imagebuilderiface"
+	"github.com/aws/aws-sdk-go/service/outposts"
+	"github.com/aws/aws-sdk-go/service/outposts/outpostsiface"
+	"github.com/aws/aws-sdk-go/service/pricing"
+	"github.com/aws/aws-sdk-go/service/pricing/pricingiface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/savingsplans"
+	"github.com/aws/aws-sdk-go/service/savingsplans/savingsplansiface"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
 	"github.com/keikoproj/instance-manager/api/v1alpha1"
+	"github.com/keikoproj/instance-manager/controllers/common"
 	awsprovider "github.com/keikoproj/instance-manager/controllers/providers/aws"
 	kubeprovider "github.com/keikoproj/instance-manager/controllers/providers/kubernetes"
 	"github.com/keikoproj/instance-manager/controllers/provisioners"
 	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	dynamic "k8s.io/client-go/dynamic/fake"
 	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
 
@@ -75,19 +99,76 @@ func NewEc2Mocker() *MockEc2Client {
 	return &MockEc2Client{}
 }
 
+func NewSsmMocker() *MockSsmClient {
+	return &MockSsmClient{
+		Parameters: make(map[string]string),
+	}
+}
+
+func NewS3Mocker() *MockS3Client {
+	return &MockS3Client{
+		Objects: make(map[string][]byte),
+	}
+}
+
 func MockAwsWorker(asgClient *MockAutoScalingClient, iamClient *MockIamClient, eksClient *MockEksClient, ec2Client *MockEc2Client) awsprovider.AwsWorker {
 	return awsprovider.AwsWorker{
 		Ec2Client: ec2Client,
 		AsgClient: asgClient,
 		IamClient: iamClient,
 		EksClient: eksClient,
+
+		CloudWatchClient: &MockCloudWatchClient{},
 	}
 }
 
 func MockKubernetesClientSet() kubeprovider.KubernetesClientSet {
+	var (
+		scheme        = runtime.NewScheme()
+		tracker       = k8stesting.NewObjectTracker(scheme, serializer.NewCodecFactory(scheme).UniversalDecoder())
+		dynamicClient = dynamic.NewSimpleDynamicClient(scheme)
+	)
+
+	// the fake dynamic client does not support server-side apply, applied objects are created or replaced
+	dynamicClient.PrependReactor("*", "*", k8stesting.ObjectReaction(tracker))
+	dynamicClient.PrependReactor("patch", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch := action.(k8stesting.PatchAction)
+		if patch.GetPatchType() != types.ApplyPatchType {
+			return false, nil, nil
+		}
+
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(patch.GetPatch()); err != nil {
+			return true, nil, err
+		}
+		existing, err := tracker.Get(patch.GetResource(), patch.GetNamespace(), patch.GetName())
+		if kerrors.IsNotFound(err) {
+			return true, obj, tracker.Create(patch.GetResource(), obj, patch.GetNamespace())
+		} else if err != nil {
+			return true, nil, err
+		}
+
+		// fields which are not applied, such as the status, are kept
+		merged := existing.(*unstructured.Unstructured).DeepCopy()
+		mergeFields(merged.Object, obj.Object)
+		return true, merged, tracker.Update(patch.GetResource(), merged, patch.GetNamespace())
+	})
+
 	return kubeprovider.KubernetesClientSet{
 		Kubernetes:  fake.NewSimpleClientset(),
-		KubeDynamic: dynamic.NewSimpleDynamicClient(runtime.NewScheme()),
+		KubeDynamic: dynamicClient,
+	}
+}
+
+func mergeFields(dst, src map[string]interface{}) {
+	for key, value := range src {
+		srcMap, srcOk := value.(map[string]interface{})
+		dstMap, dstOk := dst[key].(map[string]interface{})
+		if srcOk && dstOk {
+			mergeFields(dstMap, srcMap)
+			continue
+		}
+		dst[key] = value
 	}
 }
 
@@ -179,7 +260,7 @@ func MockCustomResourceDefinition() *unstructured.Unstructured {
 func MockAttachedPolicies(policies ...string) []*iam.AttachedPolicy {
 	mock := []*iam.AttachedPolicy{}
 	for _, p := range policies {
-		arn := fmt.Sprintf("%v/%v", awsprovider.IAMPolicyPrefix, p)
+		arn := awsprovider.ManagedPolicyArn(awsprovider.DefaultPartition, p)
 		policy := &iam.AttachedPolicy{
 			PolicyName: aws.String(p),
 			PolicyArn:  aws.String(arn),
@@ -344,58 +425,164 @@ type MockAutoScalingClient struct {
 	PutLifecycleHookErr                    error
 	DeleteLifecycleHookErr                 error
 	DeleteLaunchConfigurationCallCount     int
+	DeleteAutoScalingGroupCallCount        int
 	PutLifecycleHookCallCount              int
 	DeleteLifecycleHookCallCount           int
+	SetInstanceProtectionCallCount         int
+	TerminateInstanceCallCount             int
 	LaunchConfiguration                    *autoscaling.LaunchConfiguration
 	LaunchConfigurations                   []*autoscaling.LaunchConfiguration
 	AutoScalingGroup                       *autoscaling.Group
 	AutoScalingGroups                      []*autoscaling.Group
 	LifecycleHooks                         []*autoscaling.LifecycleHook
+	Notifications                          []*autoscaling.NotificationConfiguration
+	PutNotificationInputs                  []*autoscaling.PutNotificationConfigurationInput
+	DeletedNotificationTopics              []string
+	CreateAutoScalingGroupInput            *autoscaling.CreateAutoScalingGroupInput
+	UpdateAutoScalingGroupInput            *autoscaling.UpdateAutoScalingGroupInput
+	SuspendProcessesInput                  *autoscaling.ScalingProcessQuery
+	ResumeProcessesInput                   *autoscaling.ScalingProcessQuery
 }
 
 func (a *MockAutoScalingClient) EnableMetricsCollection(input *autoscaling.EnableMetricsCollectionInput) (*autoscaling.EnableMetricsCollectionOutput, error) {
 	return &autoscaling.EnableMetricsCollectionOutput{}, a.EnableMetricsCollectionErr
 }
 
+func (a *MockAutoScalingClient) EnableMetricsCollectionWithContext(ctx aws.Context, input *autoscaling.EnableMetricsCollectionInput, opts ...request.Option) (*autoscaling.EnableMetricsCollectionOutput, error) {
+	return a.EnableMetricsCollection(input)
+}
+
 func (a *MockAutoScalingClient) DisableMetricsCollection(input *autoscaling.DisableMetricsCollectionInput) (*autoscaling.DisableMetricsCollectionOutput, error) {
 	return &autoscaling.DisableMetricsCollectionOutput{}, a.DisableMetricsCollectionErr
 }
 
+func (a *MockAutoScalingClient) DisableMetricsCollectionWithContext(ctx aws.Context, input *autoscaling.DisableMetricsCollectionInput, opts ...request.Option) (*autoscaling.DisableMetricsCollectionOutput, error) {
+	return a.DisableMetricsCollection(input)
+}
+
 func (a *MockAutoScalingClient) TerminateInstanceInAutoScalingGroup(input *autoscaling.TerminateInstanceInAutoScalingGroupInput) (*autoscaling.TerminateInstanceInAutoScalingGroupOutput, error) {
+	a.TerminateInstanceCallCount++
 	return &autoscaling.TerminateInstanceInAutoScalingGroupOutput{}, a.TerminateInstanceInAutoScalingGroupErr
 }
 
+func (a *MockAutoScalingClient) TerminateInstanceInAutoScalingGroupWithContext(ctx aws.Context, input *autoscaling.TerminateInstanceInAutoScalingGroupInput, opts ...request.Option) (*autoscaling.TerminateInstanceInAutoScalingGroupOutput, error) {
+	return a.TerminateInstanceInAutoScalingGroup(input)
+}
+
+func (a *MockAutoScalingClient) SetInstanceProtection(input *autoscaling.SetInstanceProtectionInput) (*autoscaling.SetInstanceProtectionOutput, error) {
+	a.SetInstanceProtectionCallCount++
+	return &autoscaling.SetInstanceProtectionOutput{}, nil
+}
+
+func (a *MockAutoScalingClient) SetInstanceProtectionWithContext(ctx aws.Context, input *autoscaling.SetInstanceProtectionInput, opts ...request.Option) (*autoscaling.SetInstanceProtectionOutput, error) {
+	return a.SetInstanceProtection(input)
+}
+
 func (a *MockAutoScalingClient) CreateOrUpdateTags(input *autoscaling.CreateOrUpdateTagsInput) (*autoscaling.CreateOrUpdateTagsOutput, error) {
 	return &autoscaling.CreateOrUpdateTagsOutput{}, nil
 }
 
+func (a *MockAutoScalingClient) CreateOrUpdateTagsWithContext(ctx aws.Context, input *autoscaling.CreateOrUpdateTagsInput, opts ...request.Option) (*autoscaling.CreateOrUpdateTagsOutput, error) {
+	return a.CreateOrUpdateTags(input)
+}
+
 func (a *MockAutoScalingClient) DeleteTags(input *autoscaling.DeleteTagsInput) (*autoscaling.DeleteTagsOutput, error) {
 	return &autoscaling.DeleteTagsOutput{}, nil
 }
 
+func (a *MockAutoScalingClient) DeleteTagsWithContext(ctx aws.Context, input *autoscaling.DeleteTagsInput, opts ...request.Option) (*autoscaling.DeleteTagsOutput, error) {
+	return a.DeleteTags(input)
+}
+
 func (a *MockAutoScalingClient) CreateLaunchConfiguration(input *autoscaling.CreateLaunchConfigurationInput) (*autoscaling.CreateLaunchConfigurationOutput, error) {
 	return &autoscaling.CreateLaunchConfigurationOutput{}, a.CreateLaunchConfigurationErr
 }
 
+func (a *MockAutoScalingClient) CreateLaunchConfigurationWithContext(ctx aws.Context, input *autoscaling.CreateLaunchConfigurationInput, opts ...request.Option) (*autoscaling.CreateLaunchConfigurationOutput, error) {
+	return a.CreateLaunchConfiguration(input)
+}
+
 func (a *MockAutoScalingClient) DescribeLaunchConfigurations(input *autoscaling.DescribeLaunchConfigurationsInput) (*autoscaling.DescribeLaunchConfigurationsOutput, error) {
 	return &autoscaling.DescribeLaunchConfigurationsOutput{LaunchConfigurations: a.LaunchConfigurations}, a.DescribeLaunchConfigurationsErr
 }
 
+func (a *MockAutoScalingClient) DescribeLaunchConfigurationsWithContext(ctx aws.Context, input *autoscaling.DescribeLaunchConfigurationsInput, opts ...request.Option) (*autoscaling.DescribeLaunchConfigurationsOutput, error) {
+	return a.DescribeLaunchConfigurations(input)
+}
+
 func (a *MockAutoScalingClient) DeleteLaunchConfiguration(input *autoscaling.DeleteLaunchConfigurationInput) (*autoscaling.DeleteLaunchConfigurationOutput, error) {
 	a.DeleteLaunchConfigurationCallCount++
 	return &autoscaling.DeleteLaunchConfigurationOutput{}, a.DeleteLaunchConfigurationErr
 }
 
+func (a *MockAutoScalingClient) DeleteLaunchConfigurationWithContext(ctx aws.Context, input *autoscaling.DeleteLaunchConfigurationInput, opts ...request.Option) (*autoscaling.DeleteLaunchConfigurationOutput, error) {
+	return a.DeleteLaunchConfiguration(input)
+}
+
 func (a *MockAutoScalingClient) CreateAutoScalingGroup(input *autoscaling.CreateAutoScalingGroupInput) (*autoscaling.CreateAutoScalingGroupOutput, error) {
+	a.CreateAutoScalingGroupInput = input
 	return &autoscaling.CreateAutoScalingGroupOutput{}, a.CreateAutoScalingGroupErr
 }
 
+func (a *MockAutoScalingClient) CreateAutoScalingGroupWithContext(ctx aws.Context, input *autoscaling.CreateAutoScalingGroupInput, opts ...request.Option) (*autoscaling.CreateAutoScalingGroupOutput, error) {
+	return a.CreateAutoScalingGroup(input)
+}
+
 func (a *MockAutoScalingClient) DeleteAutoScalingGroup(input *autoscaling.DeleteAutoScalingGroupInput) (*autoscaling.DeleteAutoScalingGroupOutput, error) {
+	a.DeleteAutoScalingGroupCallCount++
 	return &autoscaling.DeleteAutoScalingGroupOutput{}, a.DeleteAutoScalingGroupErr
 }
 
+func (a *MockAutoScalingClient) DeleteAutoScalingGroupWithContext(ctx aws.Context, input *autoscaling.DeleteAutoScalingGroupInput, opts ...request.Option) (*autoscaling.DeleteAutoScalingGroupOutput, error) {
+	return a.DeleteAutoScalingGroup(input)
+}
+
 func (a *MockAutoScalingClient) DescribeAutoScalingGroups(input *autoscaling.DescribeAutoScalingGroupsInput) (*autoscaling.DescribeAutoScalingGroupsOutput, error) {
-	return &autoscaling.DescribeAutoScalingGroupsOutput{AutoScalingGroups: a.AutoScalingGroups}, a.DescribeAutoScalingGroupsErr
+	if len(input.AutoScalingGroupNames) == 0 {
+		return &autoscaling.DescribeAutoScalingGroupsOutput{AutoScalingGroups: a.AutoScalingGroups}, a.DescribeAutoScalingGroupsErr
+	}
+	groups := make([]*autoscaling.Group, 0)
+	for _, group := range a.AutoScalingGroups {
+		if common.ContainsString(aws.StringValueSlice(input.AutoScalingGroupNames), aws.StringValue(group.AutoScalingGroupName)) {
+			groups = append(groups, group)
+		}
+	}
+	return &autoscaling.DescribeAutoScalingGroupsOutput{AutoScalingGroups: groups}, a.DescribeAutoScalingGroupsErr
+}
+
+func (a *MockAutoScalingClient) DescribeTagsPagesWithContext(ctx aws.Context, input *autoscaling.DescribeTagsInput, callback func(*autoscaling.DescribeTagsOutput, bool) bool, opts ...request.Option) error {
+	if a.DescribeAutoScalingGroupsErr != nil {
+		return a.DescribeAutoScalingGroupsErr
+	}
+	tags := make([]*autoscaling.TagDescription, 0)
+	for _, group := range a.AutoScalingGroups {
+		for _, tag := range group.Tags {
+			match := true
+			for _, filter := range input.Filters {
+				values := aws.StringValueSlice(filter.Values)
+				switch aws.StringValue(filter.Name) {
+				case "key":
+					match = match && common.ContainsString(values, aws.StringValue(tag.Key))
+				case "value":
+					match = match && common.ContainsString(values, aws.StringValue(tag.Value))
+				}
+			}
+			if match {
+				tags = append(tags, &autoscaling.TagDescription{
+					Key:          tag.Key,
+					Value:        tag.Value,
+					ResourceId:   group.AutoScalingGroupName,
+					ResourceType: aws.String("auto-scaling-group"),
+				})
+			}
+		}
+	}
+	callback(&autoscaling.DescribeTagsOutput{Tags: tags}, true)
+	return nil
+}
+
+func (a *MockAutoScalingClient) DescribeAutoScalingGroupsWithContext(ctx aws.Context, input *autoscaling.DescribeAutoScalingGroupsInput, opts ...request.Option) (*autoscaling.DescribeAutoScalingGroupsOutput, error) {
+	return a.DescribeAutoScalingGroups(input)
 }
 
 func (a *MockAutoScalingClient) DescribeAutoScalingGroupsPages(input *autoscaling.DescribeAutoScalingGroupsInput, callback func(*autoscaling.DescribeAutoScalingGroupsOutput, bool) bool) error {
@@ -407,6 +594,10 @@ func (a *MockAutoScalingClient) DescribeAutoScalingGroupsPages(input *autoscalin
 	return nil
 }
 
+func (a *MockAutoScalingClient) DescribeAutoScalingGroupsPagesWithContext(ctx aws.Context, input *autoscaling.DescribeAutoScalingGroupsInput, callback func(*autoscaling.DescribeAutoScalingGroupsOutput, bool) bool, opts ...request.Option) error {
+	return a.DescribeAutoScalingGroupsPages(input, callback)
+}
+
 func (a *MockAutoScalingClient) DescribeLaunchConfigurationsPages(input *autoscaling.DescribeLaunchConfigurationsInput, callback func(*autoscaling.DescribeLaunchConfigurationsOutput, bool) bool) error {
 	page, err := a.DescribeLaunchConfigurations(input)
 	if err != nil {
@@ -416,38 +607,143 @@ func (a *MockAutoScalingClient) DescribeLaunchConfigurationsPages(input *autosca
 	return nil
 }
 
+func (a *MockAutoScalingClient) DescribeLaunchConfigurationsPagesWithContext(ctx aws.Context, input *autoscaling.DescribeLaunchConfigurationsInput, callback func(*autoscaling.DescribeLaunchConfigurationsOutput, bool) bool, opts ...request.Option) error {
+	return a.DescribeLaunchConfigurationsPages(input, callback)
+}
+
 func (a *MockAutoScalingClient) UpdateAutoScalingGroup(input *autoscaling.UpdateAutoScalingGroupInput) (*autoscaling.UpdateAutoScalingGroupOutput, error) {
+	a.UpdateAutoScalingGroupInput = input
 	return &autoscaling.UpdateAutoScalingGroupOutput{}, a.UpdateAutoScalingGroupErr
 }
 
+func (a *MockAutoScalingClient) UpdateAutoScalingGroupWithContext(ctx aws.Context, input *autoscaling.UpdateAutoScalingGroupInput, opts ...request.Option) (*autoscaling.UpdateAutoScalingGroupOutput, error) {
+	return a.UpdateAutoScalingGroup(input)
+}
+
 func (a *MockAutoScalingClient) SuspendProcesses(input *autoscaling.ScalingProcessQuery) (*autoscaling.SuspendProcessesOutput, error) {
+	a.SuspendProcessesInput = input
 	return &autoscaling.SuspendProcessesOutput{}, a.UpdateSuspendProcessesErr
 }
 
+func (a *MockAutoScalingClient) SuspendProcessesWithContext(ctx aws.Context, input *autoscaling.ScalingProcessQuery, opts ...request.Option) (*autoscaling.SuspendProcessesOutput, error) {
+	return a.SuspendProcesses(input)
+}
+
 func (a *MockAutoScalingClient) ResumeProcesses(input *autoscaling.ScalingProcessQuery) (*autoscaling.ResumeProcessesOutput, error) {
+	a.ResumeProcessesInput = input
 	return &autoscaling.ResumeProcessesOutput{}, a.UpdateSuspendProcessesErr
 }
 
+func (a *MockAutoScalingClient) ResumeProcessesWithContext(ctx aws.Context, input *autoscaling.ScalingProcessQuery, opts ...request.Option) (*autoscaling.ResumeProcessesOutput, error) {
+	return a.ResumeProcesses(input)
+}
+
 func (a *MockAutoScalingClient) DescribeLifecycleHooks(input *autoscaling.DescribeLifecycleHooksInput) (*autoscaling.DescribeLifecycleHooksOutput, error) {
 	return &autoscaling.DescribeLifecycleHooksOutput{LifecycleHooks: a.LifecycleHooks}, a.DescribeLifecycleHooksErr
 }
 
+func (a *MockAutoScalingClient) DescribeLifecycleHooksWithContext(ctx aws.Context, input *autoscaling.DescribeLifecycleHooksInput, opts ...request.Option) (*autoscaling.DescribeLifecycleHooksOutput, error) {
+	return a.DescribeLifecycleHooks(input)
+}
+
 func (a *MockAutoScalingClient) DeleteLifecycleHook(input *autoscaling.DeleteLifecycleHookInput) (*autoscaling.DeleteLifecycleHookOutput, error) {
 	a.DeleteLifecycleHookCallCount++
 	return &autoscaling.DeleteLifecycleHookOutput{}, a.DeleteLifecycleHookErr
 }
 
+func (a *MockAutoScalingClient) DeleteLifecycleHookWithContext(ctx aws.Context, input *autoscaling.DeleteLifecycleHookInput, opts ...request.Option) (*autoscaling.DeleteLifecycleHookOutput, error) {
+	return a.DeleteLifecycleHook(input)
+}
+
 func (a *MockAutoScalingClient) PutLifecycleHook(input *autoscaling.PutLifecycleHookInput) (*autoscaling.PutLifecycleHookOutput, error) {
 	a.PutLifecycleHookCallCount++
 	return &autoscaling.PutLifecycleHookOutput{}, a.PutLifecycleHookErr
 }
 
+func (a *MockAutoScalingClient) PutLifecycleHookWithContext(ctx aws.Context, input *autoscaling.PutLifecycleHookInput, opts ...request.Option) (*autoscaling.PutLifecycleHookOutput, error) {
+	return a.PutLifecycleHook(input)
+}
+
+func (a *MockAutoScalingClient) DescribeNotificationConfigurationsPagesWithContext(ctx aws.Context, input *autoscaling.DescribeNotificationConfigurationsInput, callback func(*autoscaling.DescribeNotificationConfigurationsOutput, bool) bool, opts ...request.Option) error {
+	callback(&autoscaling.DescribeNotificationConfigurationsOutput{NotificationConfigurations: a.Notifications}, true)
+	return nil
+}
+
+func (a *MockAutoScalingClient) PutNotificationConfigurationWithContext(ctx aws.Context, input *autoscaling.PutNotificationConfigurationInput, opts ...request.Option) (*autoscaling.PutNotificationConfigurationOutput, error) {
+	a.PutNotificationInputs = append(a.PutNotificationInputs, input)
+	return &autoscaling.PutNotificationConfigurationOutput{}, nil
+}
+
+func (a *MockAutoScalingClient) DeleteNotificationConfigurationWithContext(ctx aws.Context, input *autoscaling.DeleteNotificationConfigurationInput, opts ...request.Option) (*autoscaling.DeleteNotificationConfigurationOutput, error) {
+	a.DeletedNotificationTopics = append(a.DeletedNotificationTopics, aws.StringValue(input.TopicARN))
+	return &autoscaling.DeleteNotificationConfigurationOutput{}, nil
+}
+
+type MockCloudWatchClient struct {
+	cloudwatchiface.CloudWatchAPI
+	Alarms        []*cloudwatch.MetricAlarm
+	PutAlarms     []*cloudwatch.PutMetricAlarmInput
+	DeletedAlarms []string
+}
+
+func (c *MockCloudWatchClient) DescribeAlarmsPagesWithContext(ctx aws.Context, input *cloudwatch.DescribeAlarmsInput, callback func(*cloudwatch.DescribeAlarmsOutput, bool) bool, opts ...request.Option) error {
+	alarms := make([]*cloudwatch.MetricAlarm, 0)
+	for _, alarm := range c.Alarms {
+		if strings.HasPrefix(aws.StringValue(alarm.AlarmName), aws.StringValue(input.AlarmNamePrefix)) {
+			alarms = append(alarms, alarm)
+		}
+	}
+	callback(&cloudwatch.DescribeAlarmsOutput{MetricAlarms: alarms}, true)
+	return nil
+}
+
+func (c *MockCloudWatchClient) PutMetricAlarmWithContext(ctx aws.Context, input *cloudwatch.PutMetricAlarmInput, opts ...request.Option) (*cloudwatch.PutMetricAlarmOutput, error) {
+	c.PutAlarms = append(c.PutAlarms, input)
+	return &cloudwatch.PutMetricAlarmOutput{}, nil
+}
+
+func (c *MockCloudWatchClient) DeleteAlarmsWithContext(ctx aws.Context, input *cloudwatch.DeleteAlarmsInput, opts ...request.Option) (*cloudwatch.DeleteAlarmsOutput, error) {
+	c.DeletedAlarms = append(c.DeletedAlarms, aws.StringValueSlice(input.AlarmNames)...)
+	return &cloudwatch.DeleteAlarmsOutput{}, nil
+}
+
 type MockEc2Client struct {
 	ec2iface.EC2API
 	DescribeSubnetsErr        error
 	DescribeSecurityGroupsErr error
+	DescribeImagesErr         error
+	DescribeInstanceTypesErr  error
 	Subnets                   []*ec2.Subnet
 	SecurityGroups            []*ec2.SecurityGroup
+	InstanceTypes             []*ec2.InstanceTypeInfo
+	Images                    []*ec2.Image
+	AvailabilityZones         []*ec2.AvailabilityZone
+	InstanceTypeOfferings     []*ec2.InstanceTypeOffering
+	ReservedInstances         []*ec2.ReservedInstances
+	Instances                 []*ec2.Instance
+	RequiredIMDSv2Instances   []string
+	ConsoleOutput             string
+}
+
+func (c *MockEc2Client) GetConsoleOutputWithContext(ctx aws.Context, input *ec2.GetConsoleOutputInput, opts ...request.Option) (*ec2.GetConsoleOutputOutput, error) {
+	return &ec2.GetConsoleOutputOutput{
+		InstanceId: input.InstanceId,
+		Output:     aws.String(base64.StdEncoding.EncodeToString([]byte(c.ConsoleOutput))),
+	}, nil
+}
+
+func (c *MockEc2Client) DescribeInstancesPagesWithContext(ctx aws.Context, input *ec2.DescribeInstancesInput, callback func(*ec2.DescribeInstancesOutput, bool) bool, opts ...request.Option) error {
+	callback(&ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{{Instances: c.Instances}}}, true)
+	return nil
+}
+
+func (c *MockEc2Client) ModifyInstanceMetadataOptionsWithContext(ctx aws.Context, input *ec2.ModifyInstanceMetadataOptionsInput, opts ...request.Option) (*ec2.ModifyInstanceMetadataOptionsOutput, error) {
+	c.RequiredIMDSv2Instances = append(c.RequiredIMDSv2Instances, aws.StringValue(input.InstanceId))
+	return &ec2.ModifyInstanceMetadataOptionsOutput{}, nil
+}
+
+func (c *MockEc2Client) DescribeReservedInstancesWithContext(ctx aws.Context, input *ec2.DescribeReservedInstancesInput, opts ...request.Option) (*ec2.DescribeReservedInstancesOutput, error) {
+	return &ec2.DescribeReservedInstancesOutput{ReservedInstances: c.ReservedInstances}, nil
 }
 
 func (c *MockEc2Client) DescribeSecurityGroupsPages(input *ec2.DescribeSecurityGroupsInput, callback func(*ec2.DescribeSecurityGroupsOutput, bool) bool) error {
@@ -459,6 +755,10 @@ func (c *MockEc2Client) DescribeSecurityGroupsPages(input *ec2.DescribeSecurityG
 	return nil
 }
 
+func (c *MockEc2Client) DescribeSecurityGroupsPagesWithContext(ctx aws.Context, input *ec2.DescribeSecurityGroupsInput, callback func(*ec2.DescribeSecurityGroupsOutput, bool) bool, opts ...request.Option) error {
+	return c.DescribeSecurityGroupsPages(input, callback)
+}
+
 func (c *MockEc2Client) DescribeSubnetsPages(input *ec2.DescribeSubnetsInput, callback func(*ec2.DescribeSubnetsOutput, bool) bool) error {
 	page, err := c.DescribeSubnets(input)
 	if err != nil {
@@ -468,14 +768,171 @@ func (c *MockEc2Client) DescribeSubnetsPages(input *ec2.DescribeSubnetsInput, ca
 	return nil
 }
 
+func (c *MockEc2Client) DescribeSubnetsPagesWithContext(ctx aws.Context, input *ec2.DescribeSubnetsInput, callback func(*ec2.DescribeSubnetsOutput, bool) bool, opts ...request.Option) error {
+	return c.DescribeSubnetsPages(input, callback)
+}
+
 func (c *MockEc2Client) DescribeSecurityGroups(input *ec2.DescribeSecurityGroupsInput) (*ec2.DescribeSecurityGroupsOutput, error) {
 	return &ec2.DescribeSecurityGroupsOutput{SecurityGroups: c.SecurityGroups}, c.DescribeSecurityGroupsErr
 }
 
+func (c *MockEc2Client) DescribeSecurityGroupsWithContext(ctx aws.Context, input *ec2.DescribeSecurityGroupsInput, opts ...request.Option) (*ec2.DescribeSecurityGroupsOutput, error) {
+	return c.DescribeSecurityGroups(input)
+}
+
 func (c *MockEc2Client) DescribeSubnets(input *ec2.DescribeSubnetsInput) (*ec2.DescribeSubnetsOutput, error) {
 	return &ec2.DescribeSubnetsOutput{Subnets: c.Subnets}, c.DescribeSubnetsErr
 }
 
+func (c *MockEc2Client) DescribeSubnetsWithContext(ctx aws.Context, input *ec2.DescribeSubnetsInput, opts ...request.Option) (*ec2.DescribeSubnetsOutput, error) {
+	return c.DescribeSubnets(input)
+}
+
+func (c *MockEc2Client) DescribeInstanceTypes(input *ec2.DescribeInstanceTypesInput) (*ec2.DescribeInstanceTypesOutput, error) {
+	return &ec2.DescribeInstanceTypesOutput{InstanceTypes: c.InstanceTypes}, c.DescribeInstanceTypesErr
+}
+
+func (c *MockEc2Client) DescribeInstanceTypesWithContext(ctx aws.Context, input *ec2.DescribeInstanceTypesInput, opts ...request.Option) (*ec2.DescribeInstanceTypesOutput, error) {
+	return c.DescribeInstanceTypes(input)
+}
+
+func (c *MockEc2Client) DescribeAvailabilityZonesWithContext(ctx aws.Context, input *ec2.DescribeAvailabilityZonesInput, opts ...request.Option) (*ec2.DescribeAvailabilityZonesOutput, error) {
+	return &ec2.DescribeAvailabilityZonesOutput{AvailabilityZones: c.AvailabilityZones}, nil
+}
+
+func (c *MockEc2Client) DescribeInstanceTypeOfferingsWithContext(ctx aws.Context, input *ec2.DescribeInstanceTypeOfferingsInput, opts ...request.Option) (*ec2.DescribeInstanceTypeOfferingsOutput, error) {
+	var (
+		offerings = make([]*ec2.InstanceTypeOffering, 0)
+		location  = aws.StringValue(input.Filters[0].Values[0])
+	)
+	for _, offering := range c.InstanceTypeOfferings {
+		if aws.StringValue(offering.Location) == location {
+			offerings = append(offerings, offering)
+		}
+	}
+	return &ec2.DescribeInstanceTypeOfferingsOutput{InstanceTypeOfferings: offerings}, nil
+}
+
+type MockOutpostsClient struct {
+	outpostsiface.OutpostsAPI
+	InstanceTypes []string
+}
+
+func (c *MockOutpostsClient) GetOutpostInstanceTypesWithContext(ctx aws.Context, input *outposts.GetOutpostInstanceTypesInput, opts ...request.Option) (*outposts.GetOutpostInstanceTypesOutput, error) {
+	output := &outposts.GetOutpostInstanceTypesOutput{}
+	for _, instanceType := range c.InstanceTypes {
+		output.InstanceTypes = append(output.InstanceTypes, &outposts.InstanceTypeItem{InstanceType: aws.String(instanceType)})
+	}
+	return output, nil
+}
+
+type MockImageBuilderClient struct {
+	imagebuilderiface.ImagebuilderAPI
+	Images []*imagebuilder.ImageSummary
+}
+
+func (c *MockImageBuilderClient) ListImagePipelineImagesPagesWithContext(ctx aws.Context, input *imagebuilder.ListImagePipelineImagesInput, callback func(*imagebuilder.ListImagePipelineImagesOutput, bool) bool, opts ...request.Option) error {
+	callback(&imagebuilder.ListImagePipelineImagesOutput{ImageSummaryList: c.Images}, true)
+	return nil
+}
+
+func MockPipelineImage(version, status, created string, amis map[string]string) *imagebuilder.ImageSummary {
+	resources := &imagebuilder.OutputResources{}
+	for region, image := range amis {
+		resources.Amis = append(resources.Amis, &imagebuilder.Ami{Region: aws.String(region), Image: aws.String(image)})
+	}
+	return &imagebuilder.ImageSummary{
+		Arn:             aws.String(fmt.Sprintf("arn:aws:imagebuilder:us-west-2:123456789012:image/eks-node/%v", version)),
+		Version:         aws.String(version),
+		State:           &imagebuilder.ImageState{Status: aws.String(status)},
+		DateCreated:     aws.String(created),
+		OutputResources: resources,
+	}
+}
+
+type MockPricingClient struct {
+	pricingiface.PricingAPI
+	Prices    map[string]string
+	GetErr    error
+	CallCount int
+}
+
+func (c *MockPricingClient) GetProductsPagesWithContext(ctx aws.Context, input *pricing.GetProductsInput, callback func(*pricing.GetProductsOutput, bool) bool, opts ...request.Option) error {
+	c.CallCount++
+	if c.GetErr != nil {
+		return c.GetErr
+	}
+	var products []aws.JSONValue
+	for _, filter := range input.Filters {
+		if aws.StringValue(filter.Field) != "instanceType" {
+			continue
+		}
+		if price, ok := c.Prices[aws.StringValue(filter.Value)]; ok {
+			products = append(products, MockOnDemandProduct(price))
+		}
+	}
+	callback(&pricing.GetProductsOutput{PriceList: products}, true)
+	return nil
+}
+
+type MockSavingsPlansClient struct {
+	savingsplansiface.SavingsPlansAPI
+	SavingsPlans []*savingsplans.SavingsPlan
+}
+
+func (c *MockSavingsPlansClient) DescribeSavingsPlansWithContext(ctx aws.Context, input *savingsplans.DescribeSavingsPlansInput, opts ...request.Option) (*savingsplans.DescribeSavingsPlansOutput, error) {
+	return &savingsplans.DescribeSavingsPlansOutput{SavingsPlans: c.SavingsPlans}, nil
+}
+
+func MockReservedInstances(instanceType, scope string, count int64) *ec2.ReservedInstances {
+	return &ec2.ReservedInstances{
+		InstanceType:       aws.String(instanceType),
+		InstanceCount:      aws.Int64(count),
+		Scope:              aws.String(scope),
+		InstanceTenancy:    aws.String(ec2.TenancyDefault),
+		ProductDescription: aws.String(ec2.RIProductDescriptionLinuxUnix),
+		State:              aws.String(ec2.ReservedInstanceStateActive),
+	}
+}
+
+func MockSavingsPlan(id, planType, family, region string) *savingsplans.SavingsPlan {
+	plan := &savingsplans.SavingsPlan{
+		SavingsPlanId:   aws.String(id),
+		SavingsPlanType: aws.String(planType),
+		State:           aws.String(savingsplans.SavingsPlanStateActive),
+	}
+	if family != "" {
+		plan.Ec2InstanceFamily = aws.String(family)
+		plan.Region = aws.String(region)
+	}
+	return plan
+}
+
+func MockOnDemandProduct(price string) aws.JSONValue {
+	return aws.JSONValue{
+		"terms": map[string]interface{}{
+			"OnDemand": map[string]interface{}{
+				"SKU.JRTCKXETXF": map[string]interface{}{
+					"priceDimensions": map[string]interface{}{
+						"SKU.JRTCKXETXF.6YS6EN2CT7": map[string]interface{}{
+							"unit":         "Hrs",
+							"pricePerUnit": map[string]interface{}{"USD": price},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (c *MockEc2Client) DescribeImages(input *ec2.DescribeImagesInput) (*ec2.DescribeImagesOutput, error) {
+	return &ec2.DescribeImagesOutput{Images: c.Images}, c.DescribeImagesErr
+}
+
+func (c *MockEc2Client) DescribeImagesWithContext(ctx aws.Context, input *ec2.DescribeImagesInput, opts ...request.Option) (*ec2.DescribeImagesOutput, error) {
+	return c.DescribeImages(input)
+}
+
 type MockEksClient struct {
 	eksiface.EKSAPI
 	DescribeClusterErr error
@@ -486,11 +943,57 @@ func (e *MockEksClient) DescribeCluster(input *eks.DescribeClusterInput) (*eks.D
 	return &eks.DescribeClusterOutput{Cluster: e.EksCluster}, e.DescribeClusterErr
 }
 
+func (e *MockEksClient) DescribeClusterWithContext(ctx aws.Context, input *eks.DescribeClusterInput, opts ...request.Option) (*eks.DescribeClusterOutput, error) {
+	return e.DescribeCluster(input)
+}
+
+type MockSsmClient struct {
+	ssmiface.SSMAPI
+	GetParameterCallCount int
+	Parameters            map[string]string
+}
+
+func (s *MockSsmClient) GetParameterWithContext(ctx aws.Context, input *ssm.GetParameterInput, opts ...request.Option) (*ssm.GetParameterOutput, error) {
+	s.GetParameterCallCount++
+	value, ok := s.Parameters[aws.StringValue(input.Name)]
+	if !ok {
+		return nil, awserr.New(ssm.ErrCodeParameterNotFound, "parameter not found", nil)
+	}
+	return &ssm.GetParameterOutput{Parameter: &ssm.Parameter{Value: aws.String(value)}}, nil
+}
+
+type MockS3Client struct {
+	s3iface.S3API
+	PutObjectErr       error
+	PutObjectCallCount int
+	Objects            map[string][]byte
+}
+
+func (s *MockS3Client) HeadObjectWithContext(ctx aws.Context, input *s3.HeadObjectInput, opts ...request.Option) (*s3.HeadObjectOutput, error) {
+	key := fmt.Sprintf("%v/%v", aws.StringValue(input.Bucket), aws.StringValue(input.Key))
+	if _, ok := s.Objects[key]; !ok {
+		return nil, awserr.NewRequestFailure(awserr.New("NotFound", "Not Found", nil), 404, "")
+	}
+	return &s3.HeadObjectOutput{}, nil
+}
+
+func (s *MockS3Client) PutObjectWithContext(ctx aws.Context, input *s3.PutObjectInput, opts ...request.Option) (*s3.PutObjectOutput, error) {
+	s.PutObjectCallCount++
+	if s.PutObjectErr != nil {
+		return nil, s.PutObjectErr
+	}
+	body := &bytes.Buffer{}
+	body.ReadFrom(input.Body)
+	s.Objects[fmt.Sprintf("%v/%v", aws.StringValue(input.Bucket), aws.StringValue(input.Key))] = body.Bytes()
+	return &s3.PutObjectOutput{}, nil
+}
+
 type MockIamClient struct {
 	iamiface.IAMAPI
 	CreateRoleErr                     error
 	GetRoleErr                        error
 	DeleteRoleErr                     error
+	DeleteRoleCallCount               int
 	CreateInstanceProfileErr          error
 	GetInstanceProfileErr             error
 	DeleteInstanceProfileErr          error
@@ -514,6 +1017,10 @@ func (i *MockIamClient) ListAttachedRolePolicies(input *iam.ListAttachedRolePoli
 	return &iam.ListAttachedRolePoliciesOutput{}, i.ListAttachedRolePoliciesErr
 }
 
+func (i *MockIamClient) ListAttachedRolePoliciesWithContext(ctx aws.Context, input *iam.ListAttachedRolePoliciesInput, opts ...request.Option) (*iam.ListAttachedRolePoliciesOutput, error) {
+	return i.ListAttachedRolePolicies(input)
+}
+
 func (i *MockIamClient) ListAttachedRolePoliciesPages(input *iam.ListAttachedRolePoliciesInput, callback func(*iam.ListAttachedRolePoliciesOutput, bool) bool) error {
 	page, err := i.ListAttachedRolePolicies(input)
 	if err != nil {
@@ -523,6 +1030,10 @@ func (i *MockIamClient) ListAttachedRolePoliciesPages(input *iam.ListAttachedRol
 	return nil
 }
 
+func (i *MockIamClient) ListAttachedRolePoliciesPagesWithContext(ctx aws.Context, input *iam.ListAttachedRolePoliciesInput, callback func(*iam.ListAttachedRolePoliciesOutput, bool) bool, opts ...request.Option) error {
+	return i.ListAttachedRolePoliciesPages(input, callback)
+}
+
 func (i *MockIamClient) CreateRole(input *iam.CreateRoleInput) (*iam.CreateRoleOutput, error) {
 	if i.Role != nil {
 		return &iam.CreateRoleOutput{Role: i.Role}, i.CreateRoleErr
@@ -530,14 +1041,27 @@ func (i *MockIamClient) CreateRole(input *iam.CreateRoleInput) (*iam.CreateRoleO
 	return &iam.CreateRoleOutput{}, i.CreateRoleErr
 }
 
+func (i *MockIamClient) CreateRoleWithContext(ctx aws.Context, input *iam.CreateRoleInput, opts ...request.Option) (*iam.CreateRoleOutput, error) {
+	return i.CreateRole(input)
+}
+
 func (i *MockIamClient) GetRole(input *iam.GetRoleInput) (*iam.GetRoleOutput, error) {
 	return &iam.GetRoleOutput{Role: i.Role}, i.GetRoleErr
 }
 
+func (i *MockIamClient) GetRoleWithContext(ctx aws.Context, input *iam.GetRoleInput, opts ...request.Option) (*iam.GetRoleOutput, error) {
+	return i.GetRole(input)
+}
+
 func (i *MockIamClient) DeleteRole(input *iam.DeleteRoleInput) (*iam.DeleteRoleOutput, error) {
+	i.DeleteRoleCallCount++
 	return &iam.DeleteRoleOutput{}, i.DeleteRoleErr
 }
 
+func (i *MockIamClient) DeleteRoleWithContext(ctx aws.Context, input *iam.DeleteRoleInput, opts ...request.Option) (*iam.DeleteRoleOutput, error) {
+	return i.DeleteRole(input)
+}
+
 func (i *MockIamClient) CreateInstanceProfile(input *iam.CreateInstanceProfileInput) (*iam.CreateInstanceProfileOutput, error) {
 	if i.InstanceProfile != nil {
 		return &iam.CreateInstanceProfileOutput{InstanceProfile: i.InstanceProfile}, i.CreateInstanceProfileErr
@@ -545,32 +1069,64 @@ func (i *MockIamClient) CreateInstanceProfile(input *iam.CreateInstanceProfileIn
 	return &iam.CreateInstanceProfileOutput{}, i.CreateInstanceProfileErr
 }
 
+func (i *MockIamClient) CreateInstanceProfileWithContext(ctx aws.Context, input *iam.CreateInstanceProfileInput, opts ...request.Option) (*iam.CreateInstanceProfileOutput, error) {
+	return i.CreateInstanceProfile(input)
+}
+
 func (i *MockIamClient) DeleteInstanceProfile(input *iam.DeleteInstanceProfileInput) (*iam.DeleteInstanceProfileOutput, error) {
 	return &iam.DeleteInstanceProfileOutput{}, i.DeleteInstanceProfileErr
 }
 
+func (i *MockIamClient) DeleteInstanceProfileWithContext(ctx aws.Context, input *iam.DeleteInstanceProfileInput, opts ...request.Option) (*iam.DeleteInstanceProfileOutput, error) {
+	return i.DeleteInstanceProfile(input)
+}
+
 func (i *MockIamClient) AddRoleToInstanceProfile(input *iam.AddRoleToInstanceProfileInput) (*iam.AddRoleToInstanceProfileOutput, error) {
 	return &iam.AddRoleToInstanceProfileOutput{}, i.AddRoleToInstanceProfileErr
 }
 
+func (i *MockIamClient) AddRoleToInstanceProfileWithContext(ctx aws.Context, input *iam.AddRoleToInstanceProfileInput, opts ...request.Option) (*iam.AddRoleToInstanceProfileOutput, error) {
+	return i.AddRoleToInstanceProfile(input)
+}
+
 func (i *MockIamClient) RemoveRoleFromInstanceProfile(input *iam.RemoveRoleFromInstanceProfileInput) (*iam.RemoveRoleFromInstanceProfileOutput, error) {
 	return &iam.RemoveRoleFromInstanceProfileOutput{}, i.RemoveRoleFromInstanceProfileErr
 }
 
+func (i *MockIamClient) RemoveRoleFromInstanceProfileWithContext(ctx aws.Context, input *iam.RemoveRoleFromInstanceProfileInput, opts ...request.Option) (*iam.RemoveRoleFromInstanceProfileOutput, error) {
+	return i.RemoveRoleFromInstanceProfile(input)
+}
+
 func (i *MockIamClient) AttachRolePolicy(input *iam.AttachRolePolicyInput) (*iam.AttachRolePolicyOutput, error) {
 	i.AttachRolePolicyCallCount++
 	return &iam.AttachRolePolicyOutput{}, i.AttachRolePolicyErr
 }
 
+func (i *MockIamClient) AttachRolePolicyWithContext(ctx aws.Context, input *iam.AttachRolePolicyInput, opts ...request.Option) (*iam.AttachRolePolicyOutput, error) {
+	return i.AttachRolePolicy(input)
+}
+
 func (i *MockIamClient) DetachRolePolicy(input *iam.DetachRolePolicyInput) (*iam.DetachRolePolicyOutput, error) {
 	i.DetachRolePolicyCallCount++
 	return &iam.DetachRolePolicyOutput{}, i.DetachRolePolicyErr
 }
 
+func (i *MockIamClient) DetachRolePolicyWithContext(ctx aws.Context, input *iam.DetachRolePolicyInput, opts ...request.Option) (*iam.DetachRolePolicyOutput, error) {
+	return i.DetachRolePolicy(input)
+}
+
 func (i *MockIamClient) GetInstanceProfile(input *iam.GetInstanceProfileInput) (*iam.GetInstanceProfileOutput, error) {
 	return &iam.GetInstanceProfileOutput{InstanceProfile: i.InstanceProfile}, i.GetInstanceProfileErr
 }
 
+func (i *MockIamClient) GetInstanceProfileWithContext(ctx aws.Context, input *iam.GetInstanceProfileInput, opts ...request.Option) (*iam.GetInstanceProfileOutput, error) {
+	return i.GetInstanceProfile(input)
+}
+
 func (i *MockIamClient) WaitUntilInstanceProfileExists(input *iam.GetInstanceProfileInput) error {
 	return i.WaitUntilInstanceProfileExistsErr
 }
+
+func (i *MockIamClient) WaitUntilInstanceProfileExistsWithContext(ctx aws.Context, input *iam.GetInstanceProfileInput, opts ...request.WaiterOption) error {
+	return i.WaitUntilInstanceProfileExists(input)
+}
@@ -0,0 +1,349 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eks
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/keikoproj/instance-manager/api/v1alpha1"
+	"github.com/keikoproj/instance-manager/controllers/common"
+	"github.com/keikoproj/instance-manager/controllers/provisioners/eks/scaling"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ExportOmittedUserData replaces user data which includes secret values in exported configuration
+	ExportOmittedUserData = "user data is omitted since it includes secret values"
+)
+
+var (
+	terraformNameRegex = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+	terraformTemplate = template.Must(template.New("terraform").Funcs(template.FuncMap{
+		"quote": hclString,
+		"list":  hclList,
+	}).Parse(`# instance group {{ .Namespace }}/{{ .Name }} exported by instance-manager
+resource "aws_launch_configuration" "{{ .ResourceName }}" {
+  name_prefix          = {{ quote .NamePrefix }}
+  image_id             = {{ quote .Config.ImageId }}
+  instance_type        = {{ quote .Config.InstanceType }}
+  iam_instance_profile = {{ quote .Config.IamInstanceProfileArn }}
+  security_groups      = {{ list .Config.SecurityGroups }}
+{{- if .Config.KeyName }}
+  key_name             = {{ quote .Config.KeyName }}
+{{- end }}
+{{- if .Config.SpotPrice }}
+  spot_price           = {{ quote .Config.SpotPrice }}
+{{- end }}
+{{- if .Config.SensitiveUserData }}
+  # {{ .OmittedUserData }}
+{{- else }}
+  user_data_base64     = {{ quote .Config.UserData }}
+{{- end }}
+{{- range .RootDevices }}
+
+  root_block_device {
+    volume_type           = {{ quote .Ebs.VolumeType }}
+{{- if .Ebs.VolumeSize }}
+    volume_size           = {{ .Ebs.VolumeSize }}
+{{- end }}
+{{- if .Ebs.Iops }}
+    iops                  = {{ .Ebs.Iops }}
+{{- end }}
+{{- if .Ebs.Encrypted }}
+    encrypted             = {{ .Ebs.Encrypted }}
+{{- end }}
+    delete_on_termination = {{ .Ebs.DeleteOnTermination }}
+  }
+{{- end }}
+{{- range .EbsDevices }}
+
+  ebs_block_device {
+    device_name           = {{ quote .DeviceName }}
+    volume_type           = {{ quote .Ebs.VolumeType }}
+{{- if .Ebs.VolumeSize }}
+    volume_size           = {{ .Ebs.VolumeSize }}
+{{- end }}
+{{- if .Ebs.Iops }}
+    iops                  = {{ .Ebs.Iops }}
+{{- end }}
+{{- if .Ebs.SnapshotId }}
+    snapshot_id           = {{ quote .Ebs.SnapshotId }}
+{{- end }}
+{{- if .Ebs.Encrypted }}
+    encrypted             = {{ .Ebs.Encrypted }}
+{{- end }}
+    delete_on_termination = {{ .Ebs.DeleteOnTermination }}
+  }
+{{- end }}
+{{- range .EphemeralDevices }}
+
+  ephemeral_block_device {
+    device_name  = {{ quote .DeviceName }}
+    virtual_name = {{ quote .VirtualName }}
+  }
+{{- end }}
+
+  lifecycle {
+    create_before_destroy = true
+  }
+}
+
+resource "aws_autoscaling_group" "{{ .ResourceName }}" {
+  name                  = {{ quote .ScalingGroupName }}
+  launch_configuration  = aws_launch_configuration.{{ .ResourceName }}.name
+  min_size              = {{ .MinSize }}
+  max_size              = {{ .MaxSize }}
+{{- if .DesiredCapacity }}
+  desired_capacity      = {{ .DesiredCapacity }}
+{{- end }}
+  vpc_zone_identifier   = {{ list .Subnets }}
+  protect_from_scale_in = {{ .ProtectedFromScaleIn }}
+  health_check_type     = {{ quote .HealthCheckType }}
+{{- if .HealthCheckGracePeriod }}
+  health_check_grace_period = {{ .HealthCheckGracePeriod }}
+{{- end }}
+{{- range .Tags }}
+
+  tag {
+    key                 = {{ quote .Key }}
+    value               = {{ quote .Value }}
+    propagate_at_launch = {{ .PropagateAtLaunch }}
+  }
+{{- end }}
+}
+`))
+)
+
+// exportInput is the fully resolved configuration of the launch configuration and scaling group of an instance group
+type exportInput struct {
+	Name                 string
+	Namespace            string
+	ResourceName         string
+	NamePrefix           string
+	ScalingGroupName     string
+	Config               *scaling.CreateConfigurationInput
+	RootDevices          []*autoscaling.BlockDeviceMapping
+	EbsDevices           []*autoscaling.BlockDeviceMapping
+	EphemeralDevices     []*autoscaling.BlockDeviceMapping
+	MinSize              int64
+	MaxSize              int64
+	DesiredCapacity      *int64
+	Subnets              []string
+	ProtectedFromScaleIn bool
+	HealthCheckType      string
+	// HealthCheckGracePeriod is nil when the grace period is left to the scaling group
+	HealthCheckGracePeriod *int64
+	Tags                   []*autoscaling.Tag
+	OmittedUserData        string
+}
+
+// Export renders the resolved launch configuration and scaling group of the instance group as a CloudFormation
+// template or Terraform configuration, user data which includes secret values is omitted
+func (ctx *EksInstanceGroupContext) Export(format string) (string, error) {
+	var (
+		instanceGroup = ctx.GetInstanceGroup()
+		spec          = instanceGroup.GetEKSSpec()
+		configuration = instanceGroup.GetEKSConfiguration()
+		state         = ctx.GetDiscoveredState()
+		status        = instanceGroup.GetStatus()
+		asgName       = ctx.ResourcePrefix
+	)
+
+	if state.GetInstanceProfile() == nil {
+		return "", errors.New("instance profile has not been discovered")
+	}
+
+	if state.HasScalingGroup() {
+		asgName = aws.StringValue(state.GetScalingGroup().AutoScalingGroupName)
+	}
+
+	config := ctx.GetDesiredConfiguration()
+	input := &exportInput{
+		Name:                   instanceGroup.GetName(),
+		Namespace:              instanceGroup.GetNamespace(),
+		ResourceName:           terraformNameRegex.ReplaceAllString(asgName, "_"),
+		NamePrefix:             fmt.Sprintf("%v-", ctx.ResourcePrefix),
+		ScalingGroupName:       asgName,
+		Config:                 config,
+		MinSize:                spec.GetMinSize(),
+		MaxSize:                spec.GetMaxSize(),
+		Subnets:                ctx.ResolveSubnets(),
+		ProtectedFromScaleIn:   configuration.IsNewInstancesProtectedFromScaleIn(),
+		HealthCheckType:        configuration.GetHealthCheckType(),
+		HealthCheckGracePeriod: configuration.GetHealthCheckGracePeriod(),
+		Tags:                   ctx.GetAddedTags(asgName),
+		OmittedUserData:        ExportOmittedUserData,
+	}
+
+	if ctx.IsDesiredCapacityManaged() {
+		input.DesiredCapacity = aws.Int64(spec.GetMinSize())
+	}
+
+	devices := (&scaling.LaunchConfiguration{AwsWorker: ctx.AwsWorker}).BlockDeviceMappings(config)
+	for _, device := range devices {
+		var (
+			name   = aws.StringValue(device.DeviceName)
+			isRoot = name == status.GetRootDeviceName() || (status.GetRootDeviceName() == "" && common.ContainsString(RootDeviceNames, name))
+		)
+		switch {
+		case device.Ebs == nil:
+			input.EphemeralDevices = append(input.EphemeralDevices, device)
+		case isRoot:
+			input.RootDevices = append(input.RootDevices, device)
+		default:
+			input.EbsDevices = append(input.EbsDevices, device)
+		}
+	}
+
+	switch strings.ToLower(format) {
+	case v1alpha1.CloudFormationExportFormat:
+		return renderCloudFormation(input, devices)
+	case v1alpha1.TerraformExportFormat:
+		buf := &bytes.Buffer{}
+		if err := terraformTemplate.Execute(buf, input); err != nil {
+			return "", errors.Wrap(err, "failed to render terraform configuration")
+		}
+		return buf.String(), nil
+	}
+	return "", errors.Errorf("export format '%v' is not supported, must be one of %v", format, v1alpha1.AllowedExportFormats)
+}
+
+func renderCloudFormation(input *exportInput, devices []*autoscaling.BlockDeviceMapping) (string, error) {
+	var (
+		config       = input.Config
+		description  = fmt.Sprintf("instance group %v/%v exported by instance-manager", input.Namespace, input.Name)
+		blockDevices = make([]map[string]interface{}, 0)
+		tags         = make([]map[string]interface{}, 0)
+	)
+
+	for _, device := range devices {
+		mapping := map[string]interface{}{
+			"DeviceName": aws.StringValue(device.DeviceName),
+		}
+		if device.VirtualName != nil {
+			mapping["VirtualName"] = aws.StringValue(device.VirtualName)
+		}
+		if ebs := device.Ebs; ebs != nil {
+			properties := map[string]interface{}{
+				"VolumeType":          aws.StringValue(ebs.VolumeType),
+				"DeleteOnTermination": aws.BoolValue(ebs.DeleteOnTermination),
+			}
+			if ebs.VolumeSize != nil {
+				properties["VolumeSize"] = aws.Int64Value(ebs.VolumeSize)
+			}
+			if ebs.Iops != nil {
+				properties["Iops"] = aws.Int64Value(ebs.Iops)
+			}
+			if ebs.Encrypted != nil {
+				properties["Encrypted"] = aws.BoolValue(ebs.Encrypted)
+			}
+			if ebs.SnapshotId != nil {
+				properties["SnapshotId"] = aws.StringValue(ebs.SnapshotId)
+			}
+			mapping["Ebs"] = properties
+		}
+		blockDevices = append(blockDevices, mapping)
+	}
+
+	for _, tag := range input.Tags {
+		tags = append(tags, map[string]interface{}{
+			"Key":               aws.StringValue(tag.Key),
+			"Value":             aws.StringValue(tag.Value),
+			"PropagateAtLaunch": aws.BoolValue(tag.PropagateAtLaunch),
+		})
+	}
+
+	launchConfiguration := map[string]interface{}{
+		"IamInstanceProfile":  config.IamInstanceProfileArn,
+		"ImageId":             config.ImageId,
+		"InstanceType":        config.InstanceType,
+		"SecurityGroups":      config.SecurityGroups,
+		"BlockDeviceMappings": blockDevices,
+	}
+	if config.KeyName != "" {
+		launchConfiguration["KeyName"] = config.KeyName
+	}
+	if config.SpotPrice != "" {
+		launchConfiguration["SpotPrice"] = config.SpotPrice
+	}
+	if config.SensitiveUserData {
+		description = fmt.Sprintf("%v, %v", description, input.OmittedUserData)
+	} else {
+		launchConfiguration["UserData"] = config.UserData
+	}
+
+	scalingGroup := map[string]interface{}{
+		"AutoScalingGroupName":             input.ScalingGroupName,
+		"LaunchConfigurationName":          map[string]string{"Ref": "LaunchConfiguration"},
+		"MinSize":                          strconv.FormatInt(input.MinSize, 10),
+		"MaxSize":                          strconv.FormatInt(input.MaxSize, 10),
+		"VPCZoneIdentifier":                input.Subnets,
+		"NewInstancesProtectedFromScaleIn": input.ProtectedFromScaleIn,
+		"HealthCheckType":                  input.HealthCheckType,
+		"Tags":                             tags,
+	}
+	if input.DesiredCapacity != nil {
+		scalingGroup["DesiredCapacity"] = strconv.FormatInt(aws.Int64Value(input.DesiredCapacity), 10)
+	}
+	if input.HealthCheckGracePeriod != nil {
+		scalingGroup["HealthCheckGracePeriod"] = aws.Int64Value(input.HealthCheckGracePeriod)
+	}
+
+	cfn := map[string]interface{}{
+		"AWSTemplateFormatVersion": "2010-09-09",
+		"Description":              description,
+		"Resources": map[string]interface{}{
+			"LaunchConfiguration": map[string]interface{}{
+				"Type":       "AWS::AutoScaling::LaunchConfiguration",
+				"Properties": launchConfiguration,
+			},
+			"AutoScalingGroup": map[string]interface{}{
+				"Type":       "AWS::AutoScaling::AutoScalingGroup",
+				"Properties": scalingGroup,
+			},
+		},
+	}
+
+	out, err := json.MarshalIndent(cfn, "", "  ")
+	if err != nil {
+		return "", errors.Wrap(err, "failed to render cloudformation template")
+	}
+	return string(out), nil
+}
+
+// hclString quotes a string for HCL, template sequences are escaped so that values are never interpolated
+func hclString(s string) string {
+	quoted := strconv.Quote(s)
+	quoted = strings.Replace(quoted, "${", "$${", -1)
+	return strings.Replace(quoted, "%{", "%%{", -1)
+}
+
+func hclList(values []string) string {
+	quoted := make([]string, 0)
+	for _, v := range values {
+		quoted = append(quoted, hclString(v))
+	}
+	return fmt.Sprintf("[%v]", strings.Join(quoted, ", "))
+}
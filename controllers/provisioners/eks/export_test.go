@@ -0,0 +1,110 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eks
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/keikoproj/instance-manager/api/v1alpha1"
+	kubeprovider "github.com/keikoproj/instance-manager/controllers/providers/kubernetes"
+	"github.com/keikoproj/instance-manager/controllers/provisioners/eks/scaling"
+	"github.com/onsi/gomega"
+)
+
+func TestExport(t *testing.T) {
+	var (
+		g             = gomega.NewGomegaWithT(t)
+		k             = MockKubernetesClientSet()
+		ig            = MockInstanceGroup()
+		configuration = ig.GetEKSConfiguration()
+		asgMock       = NewAutoScalingMocker()
+		iamMock       = NewIamMocker()
+		eksMock       = NewEksMocker()
+		ec2Mock       = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+	ig.GetEKSSpec().MinSize = int64(3)
+	ig.GetEKSSpec().MaxSize = int64(6)
+	configuration.Image = "ami-123456789012"
+	configuration.InstanceType = "m5.large"
+	configuration.SetSubnets([]string{"subnet-1", "subnet-2"})
+	configuration.Volumes = []v1alpha1.NodeVolume{
+		{Name: "/dev/xvda", Type: "gp2", Size: 50},
+		{Name: "/dev/xvdb", Type: "io1", Size: 100, Iops: 1000},
+	}
+
+	ctx.SetDiscoveredState(&DiscoveredState{
+		Publisher: kubeprovider.EventPublisher{
+			Client: k.Kubernetes,
+		},
+		ScalingGroup: MockScalingGroup("asg-1"),
+		ScalingConfiguration: &scaling.LaunchConfiguration{
+			AwsWorker: w,
+		},
+		InstanceProfile: &iam.InstanceProfile{
+			Arn: aws.String("arn:aws:iam::123456789012:instance-profile/some-profile"),
+		},
+		Cluster: &eks.Cluster{
+			Version: aws.String("1.15"),
+		},
+	})
+
+	// cloudformation templates are valid json
+	rendered, err := ctx.Export(v1alpha1.CloudFormationExportFormat)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	template := map[string]interface{}{}
+	g.Expect(json.Unmarshal([]byte(rendered), &template)).To(gomega.Succeed())
+	resources := template["Resources"].(map[string]interface{})
+	g.Expect(resources).To(gomega.HaveKey("LaunchConfiguration"))
+	g.Expect(resources).To(gomega.HaveKey("AutoScalingGroup"))
+	g.Expect(rendered).To(gomega.ContainSubstring(`"ImageId": "ami-123456789012"`))
+	g.Expect(rendered).To(gomega.ContainSubstring(`"MinSize": "3"`))
+	g.Expect(rendered).To(gomega.ContainSubstring(`"Iops": 1000`))
+	g.Expect(rendered).To(gomega.ContainSubstring(`"UserData"`))
+
+	rendered, err = ctx.Export(v1alpha1.TerraformExportFormat)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(rendered).To(gomega.ContainSubstring(`resource "aws_launch_configuration" "asg-1" {`))
+	g.Expect(rendered).To(gomega.ContainSubstring(`launch_configuration  = aws_launch_configuration.asg-1.name`))
+	g.Expect(rendered).To(gomega.ContainSubstring(`vpc_zone_identifier   = ["subnet-1", "subnet-2"]`))
+	g.Expect(rendered).To(gomega.ContainSubstring("root_block_device {\n    volume_type           = \"gp2\"\n    volume_size           = 50\n"))
+	g.Expect(rendered).To(gomega.ContainSubstring("device_name           = \"/dev/xvdb\""))
+	g.Expect(rendered).To(gomega.ContainSubstring("iops                  = 1000"))
+
+	// user data with secret values is not exported
+	configuration.UserData = []v1alpha1.UserDataStage{
+		{Stage: v1alpha1.PreBootstrapStage, Data: `echo {{ secret "registry" "password" }}`},
+	}
+	rendered, err = ctx.Export(v1alpha1.TerraformExportFormat)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(rendered).NotTo(gomega.ContainSubstring("user_data_base64"))
+	g.Expect(rendered).To(gomega.ContainSubstring(ExportOmittedUserData))
+
+	_, err = ctx.Export("pulumi")
+	g.Expect(err).To(gomega.HaveOccurred())
+}
+
+func TestHCLString(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	g.Expect(hclString(`echo "${HOME}" %{ if }`)).To(gomega.Equal(`"echo \"$${HOME}\" %%{ if }"`))
+	g.Expect(hclList([]string{"sg-1", "sg-2"})).To(gomega.Equal(`["sg-1", "sg-2"]`))
+}
@@ -16,17 +16,19 @@ limitations under the License.
 package eks
 
 import (
-	"bytes"
+	"crypto/sha256"
 	"encoding/base64"
 	"fmt"
+	"path"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
-	"text/template"
 
 	"github.com/Masterminds/semver"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/keikoproj/instance-manager/api/v1alpha1"
 	"github.com/keikoproj/instance-manager/controllers/common"
 	awsprovider "github.com/keikoproj/instance-manager/controllers/providers/aws"
@@ -97,40 +99,102 @@ func (ctx *EksInstanceGroupContext) ResolveSecurityGroups() []string {
 }
 
 func (ctx *EksInstanceGroupContext) GetBasicUserData(clusterName, args string, payload UserDataPayload, mounts []MountOpts) string {
-
-	var UserDataTemplate = `#!/bin/bash
-{{range $pre := .PreBootstrap}}{{$pre}}{{end}}
-{{- range .MountOptions}}
-mkfs.{{ .FileSystem | ToLower }} {{ .Device }}
-mkdir {{ .Mount }}
-mount {{ .Device }} {{ .Mount }}
-mount
-{{- if .Persistance}}
-echo "{{ .Device}}    {{ .Mount }}    {{ .FileSystem | ToLower }}    defaults    0    2" >> /etc/fstab
-{{- end}}
-{{- end}}
-set -o xtrace
-/etc/eks/bootstrap.sh {{ .ClusterName }} {{ .Arguments }}
-set +o xtrace
-{{range $post := .PostBootstrap}}{{$post}}{{end}}`
+	var (
+		instanceGroup = ctx.GetInstanceGroup()
+		configuration = instanceGroup.GetEKSConfiguration()
+		endpoint, ca  = ctx.GetClusterBootstrapData()
+	)
 
 	data := EKSUserData{
-		ClusterName:   clusterName,
-		Arguments:     args,
-		PreBootstrap:  payload.PreBootstrap,
-		PostBootstrap: payload.PostBootstrap,
-		MountOptions:  mounts,
-	}
-	out := &bytes.Buffer{}
-	tmpl := template.New("userData").Funcs(template.FuncMap{
-		"ToLower": strings.ToLower,
-	})
-	var err error
-	if tmpl, err = tmpl.Parse(UserDataTemplate); err != nil {
-		ctx.Log.Error(err, "failed to parse userData template")
+		ClusterName:          clusterName,
+		Arguments:            args,
+		PreBootstrap:         payload.PreBootstrap,
+		PostBootstrap:        payload.PostBootstrap,
+		MountOptions:         mounts,
+		InstanceStorePolicy:  configuration.GetInstanceStorePolicy(),
+		SecretsHash:          ctx.GetSecretsHash(),
+		RotationCounter:      instanceGroup.GetStatus().GetRotationCounter(),
+		APIServerEndpoint:    endpoint,
+		CertificateAuthority: ca,
+		ClusterCIDR:          configuration.GetClusterCIDR(),
+		NodeLabels:           ctx.GetLabelList(),
+		NodeTaints:           ctx.GetTaintList(),
+		KubeletExtraArgs:     configuration.GetBootstrapArguments(),
+	}
+
+	provider, ok := GetBootstrapProvider(configuration.GetBootstrapProvider())
+	if !ok {
+		ctx.Log.Error(errors.Errorf("bootstrap provider '%v' does not exist", configuration.GetBootstrapProvider()), "failed to render userData")
+		return ""
+	}
+	rendered, err := provider.UserData(instanceGroup, data)
+	if err != nil {
+		ctx.Log.Error(err, "failed to render userData", "instancegroup", instanceGroup.GetName(), "bootstrapProvider", configuration.GetBootstrapProvider())
+	}
+
+	// cloud-init detects and decompresses gzip user data, so it is compressed when approaching the size limit
+	userData := rendered
+	if len(userData) > UserDataCompressionThreshold {
+		compressed, err := common.GzipBytes(userData)
+		if err != nil {
+			ctx.Log.Error(err, "failed to compress userData")
+		} else {
+			userData = compressed
+		}
 	}
-	tmpl.Execute(out, data)
-	return base64.StdEncoding.EncodeToString(out.Bytes())
+	if len(userData) > awsprovider.UserDataMaxSize && ctx.BootstrapBucket.Enabled() {
+		userData = ctx.GetBootstrapStub(rendered)
+	}
+	if len(userData) > awsprovider.UserDataMaxSize {
+		ctx.Log.Info("userData exceeds the maximum size", "instancegroup", ctx.GetInstanceGroup().GetName(), "size", len(userData), "maxSize", awsprovider.UserDataMaxSize)
+	}
+	return base64.StdEncoding.EncodeToString(userData)
+}
+
+// GetBootstrapStub records the payload as the bootstrap object to upload, and returns user data which downloads and
+// executes it, the object is keyed by its hash so any change to the payload changes the stub and is detected as drift
+func (ctx *EksInstanceGroupContext) GetBootstrapStub(payload []byte) []byte {
+	var (
+		instanceGroup = ctx.GetInstanceGroup()
+		clusterName   = instanceGroup.GetEKSConfiguration().GetClusterName()
+		bucket        = ctx.BootstrapBucket
+		hash          = fmt.Sprintf("%x", sha256.Sum256(payload))
+		key           = path.Join(bucket.Prefix, clusterName, instanceGroup.GetNamespace(), instanceGroup.GetName(), hash)
+	)
+
+	ctx.BootstrapObject = &BootstrapObject{
+		Key:  key,
+		Hash: hash,
+		Data: payload,
+	}
+	return []byte(fmt.Sprintf(BootstrapStubFmt, bucket.Name, key, hash))
+}
+
+// UploadBootstrapObject uploads the bootstrap object to the bootstrap bucket if user data was offloaded and the object
+// does not already exist
+func (ctx *EksInstanceGroupContext) UploadBootstrapObject() error {
+	var (
+		object = ctx.BootstrapObject
+		bucket = ctx.BootstrapBucket.Name
+	)
+
+	if object == nil {
+		return nil
+	}
+
+	exists, err := ctx.AwsWorker.ObjectExists(bucket, object.Key)
+	if err != nil {
+		return errors.Wrapf(err, "failed to check bootstrap object s3://%v/%v", bucket, object.Key)
+	}
+	if exists {
+		return nil
+	}
+
+	ctx.Log.Info("uploading bootstrap object", "instancegroup", ctx.GetInstanceGroup().GetName(), "bucket", bucket, "key", object.Key, "size", len(object.Data))
+	if err := ctx.AwsWorker.PutObject(bucket, object.Key, object.Data); err != nil {
+		return errors.Wrapf(err, "failed to upload bootstrap object s3://%v/%v", bucket, object.Key)
+	}
+	return nil
 }
 
 func (ctx *EksInstanceGroupContext) GetUserDataStages() UserDataPayload {
@@ -148,22 +212,143 @@ func (ctx *EksInstanceGroupContext) GetUserDataStages() UserDataPayload {
 		case strings.EqualFold(stage.Stage, v1alpha1.PreBootstrapStage):
 			data, err := common.GetDecodedString(stage.Data)
 			if err != nil {
-				ctx.Log.Error(err, "failed to decode base64 stage data", "stage", stage.Stage, "data", stage.Data)
+				ctx.Log.Error(err, "failed to decode base64 stage data", "stage", stage.Stage, "dataHash", common.HashValue(stage.Data))
 			}
-			payload.PreBootstrap = append(payload.PreBootstrap, data)
+			payload.PreBootstrap = append(payload.PreBootstrap, ctx.ResolveSecretReferences(data))
 		case strings.EqualFold(stage.Stage, v1alpha1.PostBootstrapStage):
 			data, err := common.GetDecodedString(stage.Data)
 			if err != nil {
-				ctx.Log.Error(err, "failed to decode base64 stage data", "stage", stage.Stage, "data", stage.Data)
+				ctx.Log.Error(err, "failed to decode base64 stage data", "stage", stage.Stage, "dataHash", common.HashValue(stage.Data))
 			}
-			payload.PostBootstrap = append(payload.PostBootstrap, data)
+			payload.PostBootstrap = append(payload.PostBootstrap, ctx.ResolveSecretReferences(data))
 		default:
-			ctx.Log.Info("invalid userdata stage will not be rendered", "stage", stage.Stage, "data", stage.Data)
+			ctx.Log.Info("invalid userdata stage will not be rendered", "stage", stage.Stage, "dataHash", common.HashValue(stage.Data))
 		}
 	}
 	return payload
 }
 
+// DiscoverSecrets gets the secrets referenced in the userData stages, an error is returned if a secret or one of the
+// referenced keys does not exist so that nodes are not bootstrapped with missing values
+func (ctx *EksInstanceGroupContext) DiscoverSecrets() error {
+	var (
+		instanceGroup = ctx.GetInstanceGroup()
+		configuration = instanceGroup.GetEKSConfiguration()
+		state         = ctx.GetDiscoveredState()
+		namespace     = instanceGroup.GetNamespace()
+		secrets       = make(map[string]*corev1.Secret)
+	)
+
+	for _, reference := range configuration.GetSecretReferences() {
+		secret, ok := secrets[reference.Name]
+		if !ok {
+			var err error
+			secret, err = ctx.KubernetesClient.Kubernetes.CoreV1().Secrets(namespace).Get(reference.Name, metav1.GetOptions{})
+			if err != nil {
+				return errors.Wrapf(err, "failed to get secret %v/%v", namespace, reference.Name)
+			}
+			secrets[reference.Name] = secret
+		}
+		if _, ok := secret.Data[reference.Key]; !ok {
+			return errors.Errorf("secret %v/%v does not have key '%v'", namespace, reference.Name, reference.Key)
+		}
+	}
+
+	state.SetSecrets(secrets)
+	return nil
+}
+
+// ResolveSecretReferences replaces the secret references in data with the values of the discovered secrets, values
+// are never logged
+func (ctx *EksInstanceGroupContext) ResolveSecretReferences(data string) string {
+	secrets := ctx.GetDiscoveredState().GetSecrets()
+	return v1alpha1.SecretReferencePattern.ReplaceAllStringFunc(data, func(reference string) string {
+		match := v1alpha1.SecretReferencePattern.FindStringSubmatch(reference)
+		secret, ok := secrets[match[1]]
+		if !ok {
+			ctx.Log.Info("secret reference was not discovered and will not be resolved", "instancegroup", ctx.GetInstanceGroup().GetName(), "secret", match[1], "key", match[2])
+			return reference
+		}
+		return string(secret.Data[match[2]])
+	})
+}
+
+// GetSecretsHash returns a hash of the resource versions of the discovered secrets, it is rendered into the user data
+// so that nodes are rotated when a referenced secret changes
+func (ctx *EksInstanceGroupContext) GetSecretsHash() string {
+	var (
+		secrets  = ctx.GetDiscoveredState().GetSecrets()
+		versions = make([]string, 0)
+	)
+
+	if len(secrets) == 0 {
+		return ""
+	}
+	for name, secret := range secrets {
+		versions = append(versions, fmt.Sprintf("%v=%v", name, secret.GetResourceVersion()))
+	}
+	sort.Strings(versions)
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(strings.Join(versions, ","))))
+}
+
+// GetVolumes returns the volumes of the instance group, when the first volume looks like a root volume but does not
+// match the root device name of the image, it is renamed to the image's root device name. Otherwise it would be
+// added as a second device, and the root volume would use the image's defaults
+func (ctx *EksInstanceGroupContext) GetVolumes() []v1alpha1.NodeVolume {
+	var (
+		instanceGroup = ctx.GetInstanceGroup()
+		configuration = instanceGroup.GetEKSConfiguration()
+		volumes       = configuration.GetVolumes()
+		image         = ctx.GetDiscoveredState().GetImage()
+	)
+
+	if image == nil || len(volumes) == 0 {
+		return volumes
+	}
+
+	rootDeviceName := aws.StringValue(image.RootDeviceName)
+	if common.StringEmpty(rootDeviceName) {
+		return volumes
+	}
+
+	for _, v := range volumes {
+		if v.Name == rootDeviceName {
+			return volumes
+		}
+	}
+
+	if !common.ContainsString(RootDeviceNames, volumes[0].Name) {
+		return volumes
+	}
+
+	resolved := make([]v1alpha1.NodeVolume, len(volumes))
+	copy(resolved, volumes)
+	resolved[0].Name = rootDeviceName
+	return resolved
+}
+
+// GetInstanceStoreVolumes returns the number of instance store volumes which should be mapped to instances,
+// instance store volumes are only mapped when an instance store policy is configured
+func (ctx *EksInstanceGroupContext) GetInstanceStoreVolumes() int {
+	var (
+		count            int
+		instanceGroup    = ctx.GetInstanceGroup()
+		configuration    = instanceGroup.GetEKSConfiguration()
+		instanceTypeInfo = ctx.GetDiscoveredState().GetInstanceTypeInfo()
+	)
+
+	if common.StringEmpty(configuration.GetInstanceStorePolicy()) {
+		return count
+	}
+	if instanceTypeInfo == nil || instanceTypeInfo.InstanceStorageInfo == nil {
+		return count
+	}
+	for _, disk := range instanceTypeInfo.InstanceStorageInfo.Disks {
+		count += int(aws.Int64Value(disk.Count))
+	}
+	return count
+}
+
 func (ctx *EksInstanceGroupContext) GetMountOpts() []MountOpts {
 	var (
 		mountOpts     = make([]MountOpts, 0)
@@ -218,6 +403,54 @@ func (ctx *EksInstanceGroupContext) GetAddedTags(asgName string) []*autoscaling.
 	for _, tagSlice := range configuration.GetTags() {
 		tags = append(tags, ctx.AwsWorker.NewTag(tagSlice["key"], tagSlice["value"], asgName))
 	}
+
+	// node template tags
+	for _, tagSlice := range ctx.GetNodeTemplateTags() {
+		tags = append(tags, ctx.AwsWorker.NewTag(tagSlice["key"], tagSlice["value"], asgName))
+	}
+	return tags
+}
+
+// GetNodeTemplateTags returns the cluster-autoscaler node template tags describing the accelerators of the
+// instance group's nodes, these allow cluster-autoscaler to scale up from zero for pods requesting accelerators
+func (ctx *EksInstanceGroupContext) GetNodeTemplateTags() []map[string]string {
+	var (
+		tags             []map[string]string
+		instanceTypeInfo = ctx.GetDiscoveredState().GetInstanceTypeInfo()
+		resourceName     = GetAcceleratorResourceName(instanceTypeInfo)
+		count            = GetAcceleratorCount(instanceTypeInfo)
+	)
+
+	if resourceName == "" {
+		return tags
+	}
+
+	if count > 0 {
+		tags = append(tags, map[string]string{
+			"key":   fmt.Sprintf(ClusterAutoscalerResourceTagFmt, resourceName),
+			"value": strconv.Itoa(count),
+		})
+	}
+
+	for _, label := range ctx.GetLabelList() {
+		kv := strings.SplitN(label, "=", 2)
+		if len(kv) == 2 && kv[0] == resourceName {
+			tags = append(tags, map[string]string{
+				"key":   fmt.Sprintf(ClusterAutoscalerLabelTagFmt, resourceName),
+				"value": kv[1],
+			})
+		}
+	}
+
+	for _, taint := range ctx.GetTaintList() {
+		kv := strings.SplitN(taint, "=", 2)
+		if len(kv) == 2 && kv[0] == resourceName {
+			tags = append(tags, map[string]string{
+				"key":   fmt.Sprintf(ClusterAutoscalerTaintTagFmt, resourceName),
+				"value": kv[1],
+			})
+		}
+	}
 	return tags
 }
 
@@ -249,21 +482,22 @@ func (ctx *EksInstanceGroupContext) UpdateScalingProcesses(asgName string) error
 	var (
 		instanceGroup         = ctx.GetInstanceGroup()
 		configuration         = instanceGroup.GetEKSConfiguration()
+		status                = instanceGroup.GetStatus()
 		state                 = ctx.GetDiscoveredState()
 		scalingGroup          = state.GetScalingGroup()
-		specSuspendProcesses  = configuration.GetSuspendProcesses()
+		specSuspendProcesses  = ctx.GetSuspendProcesses()
 		groupSuspendProcesses []string
 	)
 
-	// handle 'all' metrics provided
-	if common.ContainsEqualFold(specSuspendProcesses, "all") {
-		specSuspendProcesses = awsprovider.DefaultSuspendProcesses
-	}
-
 	for _, element := range scalingGroup.SuspendedProcesses {
 		groupSuspendProcesses = append(groupSuspendProcesses, *element.ProcessName)
 	}
 
+	if ctx.AZRebalanceDrifted() {
+		ctx.Log.Info("reverting change of the AZRebalance process made outside of the controller", "instancegroup", instanceGroup.GetName(), "scalinggroup", asgName, "policy", configuration.GetAZRebalance())
+		state.Publisher.Publish(kubeprovider.ChangesRevertedEvent, "instancegroup", instanceGroup.GetName(), "scalinggroup", asgName, "changes", "azRebalance")
+	}
+
 	if suspend := common.Difference(specSuspendProcesses, groupSuspendProcesses); len(suspend) > 0 {
 		if err := ctx.AwsWorker.SetSuspendProcesses(asgName, suspend); err != nil {
 			return err
@@ -278,9 +512,61 @@ func (ctx *EksInstanceGroupContext) UpdateScalingProcesses(asgName string) error
 		ctx.Log.Info("resumed scaling processes", "instancegroup", instanceGroup.GetName(), "scalinggroup", asgName, "processes", resume)
 	}
 
+	status.SetAZRebalance(configuration.GetAZRebalance())
 	return nil
 }
 
+// GetSuspendProcesses returns the processes of the scaling group which should be suspended, the AZRebalance process
+// follows the azRebalance policy when it is set
+func (ctx *EksInstanceGroupContext) GetSuspendProcesses() []string {
+	var (
+		configuration = ctx.GetInstanceGroup().GetEKSConfiguration()
+		processes     = make([]string, 0)
+	)
+
+	specSuspendProcesses := configuration.GetSuspendProcesses()
+	// handle 'all' metrics provided
+	if common.ContainsEqualFold(specSuspendProcesses, "all") {
+		specSuspendProcesses = awsprovider.DefaultSuspendProcesses
+	}
+
+	for _, process := range specSuspendProcesses {
+		if configuration.IsAZRebalanceEnabled() && strings.EqualFold(process, v1alpha1.AZRebalanceProcess) {
+			continue
+		}
+		processes = append(processes, process)
+	}
+	if configuration.IsAZRebalanceDisabled() && !common.ContainsEqualFold(processes, v1alpha1.AZRebalanceProcess) {
+		processes = append(processes, v1alpha1.AZRebalanceProcess)
+	}
+	return processes
+}
+
+// AZRebalanceDrifted returns true if the AZRebalance process of the scaling group was suspended or resumed outside of
+// the controller since the azRebalance policy was applied
+func (ctx *EksInstanceGroupContext) AZRebalanceDrifted() bool {
+	var (
+		instanceGroup = ctx.GetInstanceGroup()
+		configuration = instanceGroup.GetEKSConfiguration()
+		status        = instanceGroup.GetStatus()
+		scalingGroup  = ctx.GetDiscoveredState().GetScalingGroup()
+		policy        = configuration.GetAZRebalance()
+	)
+
+	// a policy which was not applied yet is a change of the spec
+	if common.StringEmpty(policy) || !strings.EqualFold(status.GetAZRebalance(), policy) {
+		return false
+	}
+
+	var suspended bool
+	for _, process := range scalingGroup.SuspendedProcesses {
+		if strings.EqualFold(aws.StringValue(process.ProcessName), v1alpha1.AZRebalanceProcess) {
+			suspended = true
+		}
+	}
+	return suspended != configuration.IsAZRebalanceDisabled()
+}
+
 func (ctx *EksInstanceGroupContext) GetTaintList() []string {
 	var (
 		taintList     []string
@@ -289,11 +575,21 @@ func (ctx *EksInstanceGroupContext) GetTaintList() []string {
 		taints        = configuration.GetTaints()
 	)
 
+	var hasAcceleratorTaint bool
+	resourceName := GetAcceleratorResourceName(ctx.DiscoveredState.GetInstanceTypeInfo())
 	if len(taints) > 0 {
 		for _, t := range taints {
+			if resourceName != "" && t.Key == resourceName {
+				hasAcceleratorTaint = true
+			}
 			taintList = append(taintList, fmt.Sprintf("%v=%v:%v", t.Key, t.Value, t.Effect))
 		}
 	}
+
+	// keep pods which do not request accelerators off of accelerated nodes unless a taint was already configured
+	if resourceName != "" && !hasAcceleratorTaint {
+		taintList = append(taintList, fmt.Sprintf("%v=true:%v", resourceName, corev1.TaintEffectNoSchedule))
+	}
 	sort.Strings(taintList)
 	return taintList
 }
@@ -342,6 +638,12 @@ func (ctx *EksInstanceGroupContext) GetLabelList() []string {
 		}
 	}
 
+	if resourceName := GetAcceleratorResourceName(ctx.DiscoveredState.GetInstanceTypeInfo()); resourceName != "" {
+		if _, ok := customLabels[resourceName]; !ok {
+			labelList = append(labelList, fmt.Sprintf("%v=true", resourceName))
+		}
+	}
+
 	if configuration.GetSpotPrice() == "" {
 		labelList = append(labelList, fmt.Sprintf(InstanceMgrLabelFmt, "lifecycle", v1alpha1.LifecycleStateNormal))
 	} else {
@@ -352,6 +654,296 @@ func (ctx *EksInstanceGroupContext) GetLabelList() []string {
 	return labelList
 }
 
+// GetAcceleratorCount returns the number of GPUs or inference accelerators attached to each instance of an instance type
+func GetAcceleratorCount(info *ec2.InstanceTypeInfo) int {
+	var count int
+	if info == nil {
+		return count
+	}
+	if info.GpuInfo != nil {
+		for _, gpu := range info.GpuInfo.Gpus {
+			count += int(aws.Int64Value(gpu.Count))
+		}
+	}
+	if info.InferenceAcceleratorInfo != nil {
+		for _, accelerator := range info.InferenceAcceleratorInfo.Accelerators {
+			count += int(aws.Int64Value(accelerator.Count))
+		}
+	}
+	return count
+}
+
+// GetAcceleratorResourceName returns the name of the extended resource advertised by the device plugin of the
+// accelerators attached to an instance type, or an empty string if it does not have supported accelerators
+func GetAcceleratorResourceName(info *ec2.InstanceTypeInfo) string {
+	switch {
+	case IsNvidiaGPUInstanceType(info):
+		return NvidiaGPUKey
+	case IsNeuronInstanceType(info):
+		return NeuronKey
+	}
+	return ""
+}
+
+// IsNeuronInstanceType returns true if an instance type has AWS Inferentia or Trainium devices attached
+func IsNeuronInstanceType(info *ec2.InstanceTypeInfo) bool {
+	if info == nil {
+		return false
+	}
+	family := strings.Split(aws.StringValue(info.InstanceType), ".")[0]
+	if common.ContainsString(NeuronInstanceFamilies, family) {
+		return true
+	}
+	if info.InferenceAcceleratorInfo != nil {
+		for _, accelerator := range info.InferenceAcceleratorInfo.Accelerators {
+			if strings.EqualFold(aws.StringValue(accelerator.Manufacturer), NeuronManufacturer) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsNvidiaGPUInstanceType returns true if an instance type has NVIDIA GPUs attached
+func IsNvidiaGPUInstanceType(info *ec2.InstanceTypeInfo) bool {
+	if info == nil || info.GpuInfo == nil {
+		return false
+	}
+	for _, gpu := range info.GpuInfo.Gpus {
+		if strings.EqualFold(aws.StringValue(gpu.Manufacturer), NvidiaManufacturer) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidatePlacement returns an error if the subnets are on more than one outpost, if subnets of an outpost are mixed
+// with subnets which are not, or if the instance type is not offered in a subnet of a local zone, wavelength zone or
+// outpost
+func (ctx *EksInstanceGroupContext) ValidatePlacement() error {
+	var (
+		state         = ctx.GetDiscoveredState()
+		configuration = ctx.GetInstanceGroup().GetEKSConfiguration()
+		outposts      = make([]string, 0)
+		placements    = state.GetSubnetPlacements()
+	)
+
+	for _, placement := range placements {
+		if placement.OutpostArn != "" && !common.ContainsString(outposts, placement.OutpostArn) {
+			outposts = append(outposts, placement.OutpostArn)
+		}
+	}
+
+	if len(outposts) > 1 {
+		return errors.Errorf("subnets are on more than one outpost: %v", strings.Join(outposts, ","))
+	}
+
+	if len(outposts) == 1 {
+		for _, placement := range placements {
+			if placement.OutpostArn == "" {
+				return errors.Errorf("subnet %v is not on outpost %v, subnets of an outpost cannot be mixed with other subnets", placement.SubnetID, outposts[0])
+			}
+		}
+	}
+
+	if unoffered := state.GetUnofferedSubnets(); len(unoffered) > 0 {
+		return errors.Errorf("instance type %v is not offered in subnets %v", configuration.InstanceType, strings.Join(unoffered, ","))
+	}
+	return nil
+}
+
+// ValidateImage returns an error if the discovered image is not able to run the discovered instance type,
+// e.g. an arm64 image on an x86_64 instance type, or a standard EKS optimized image which does not include the
+// drivers required by GPU or Inferentia instances. Such instances are launched but never join the cluster
+func (ctx *EksInstanceGroupContext) ValidateImage() error {
+	var (
+		state            = ctx.GetDiscoveredState()
+		image            = state.GetImage()
+		instanceTypeInfo = state.GetInstanceTypeInfo()
+	)
+
+	if err := ctx.ValidateKubeletVersion(); err != nil {
+		return err
+	}
+
+	if image == nil || instanceTypeInfo == nil {
+		return nil
+	}
+
+	var (
+		imageId      = aws.StringValue(image.ImageId)
+		imageName    = aws.StringValue(image.Name)
+		architecture = aws.StringValue(image.Architecture)
+		instanceType = aws.StringValue(instanceTypeInfo.InstanceType)
+	)
+
+	if processor := instanceTypeInfo.ProcessorInfo; processor != nil && architecture != "" {
+		supported := aws.StringValueSlice(processor.SupportedArchitectures)
+		if !common.ContainsString(supported, architecture) {
+			return errors.Errorf("instance type %v supports %v architectures, image %v is %v", instanceType, strings.Join(supported, ","), imageId, architecture)
+		}
+	}
+
+	// the EKS optimized accelerated image includes both the NVIDIA and the Neuron drivers
+	if GetAcceleratorResourceName(instanceTypeInfo) != "" && IsStandardEKSImage(imageName) {
+		return errors.Errorf("instance type %v requires an accelerated image, %v (%v) does not include accelerator drivers", instanceType, imageId, imageName)
+	}
+	return nil
+}
+
+// ValidateKubeletVersion returns an error if the kubelet version of the image violates the version skew policy of the
+// cluster, the kubelet may not be newer than the cluster or more than MaxKubeletVersionSkew minor versions older.
+// Images whose kubelet version is unknown are not validated
+func (ctx *EksInstanceGroupContext) ValidateKubeletVersion() error {
+	var (
+		state          = ctx.GetDiscoveredState()
+		image          = state.GetImage()
+		clusterVersion = state.GetClusterVersion()
+		kubeletVersion = GetImageKubernetesVersion(image)
+	)
+
+	if clusterVersion == "" || kubeletVersion == "" {
+		return nil
+	}
+
+	cluster, err := semver.NewVersion(clusterVersion)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse cluster version %v", clusterVersion)
+	}
+	kubelet, err := semver.NewVersion(kubeletVersion)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse kubelet version %v of image %v", kubeletVersion, aws.StringValue(image.ImageId))
+	}
+
+	imageId := aws.StringValue(image.ImageId)
+	if kubelet.Major() != cluster.Major() || kubelet.Minor() > cluster.Minor() {
+		return errors.Errorf("image %v kubelet version %v is newer than cluster version %v", imageId, kubeletVersion, clusterVersion)
+	}
+	if cluster.Minor()-kubelet.Minor() > MaxKubeletVersionSkew {
+		return errors.Errorf("image %v kubelet version %v is more than %v minor versions older than cluster version %v", imageId, kubeletVersion, MaxKubeletVersionSkew, clusterVersion)
+	}
+	return nil
+}
+
+// GetImageKubernetesVersion returns the kubelet version of an image from its tag, or from its name if it is an EKS
+// optimized or Bottlerocket image, an empty string is returned if it is unknown
+func GetImageKubernetesVersion(image *ec2.Image) string {
+	if image == nil {
+		return ""
+	}
+	for _, tag := range image.Tags {
+		if aws.StringValue(tag.Key) == ImageKubernetesVersionTagKey {
+			return aws.StringValue(tag.Value)
+		}
+	}
+	if match := ImageKubernetesVersionPattern.FindStringSubmatch(aws.StringValue(image.Name)); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// GetImageParameter returns the SSM parameter of the recommended EKS optimized image for the cluster version, the
+// accelerated image is used for GPU and Inferentia instance types and the arm64 image for arm64 instance types
+func (ctx *EksInstanceGroupContext) GetImageParameter() string {
+	var (
+		state            = ctx.GetDiscoveredState()
+		instanceTypeInfo = state.GetInstanceTypeInfo()
+		variant          = EKSOptimizedImageVariant
+	)
+
+	if GetAcceleratorResourceName(instanceTypeInfo) != "" {
+		variant = EKSOptimizedGPUImageVariant
+	} else if instanceTypeInfo != nil && instanceTypeInfo.ProcessorInfo != nil {
+		supported := aws.StringValueSlice(instanceTypeInfo.ProcessorInfo.SupportedArchitectures)
+		if common.ContainsString(supported, ec2.ArchitectureTypeArm64) && !common.ContainsString(supported, ec2.ArchitectureTypeX8664) {
+			variant = EKSOptimizedArm64ImageVariant
+		}
+	}
+	return fmt.Sprintf(EKSOptimizedImageParameterFmt, state.GetClusterVersion(), variant)
+}
+
+// ResolveImage returns the recommended EKS optimized image for the cluster version, the image is resolved once and
+// recorded in the status, it is resolved again only when the cluster version or the image variant changes
+func (ctx *EksInstanceGroupContext) ResolveImage() (string, error) {
+	var (
+		instanceGroup = ctx.GetInstanceGroup()
+		status        = instanceGroup.GetStatus()
+		state         = ctx.GetDiscoveredState()
+		parameter     = ctx.GetImageParameter()
+		previousImage = status.GetResolvedImage()
+	)
+
+	if state.GetClusterVersion() == "" {
+		return "", errors.New("cluster version is unknown")
+	}
+
+	if status.GetImageParameter() == parameter && previousImage != "" {
+		return previousImage, nil
+	}
+
+	image, err := ctx.AwsWorker.GetParameter(parameter)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get parameter %v", parameter)
+	}
+
+	ctx.Log.Info("resolved image", "instancegroup", instanceGroup.GetName(), "parameter", parameter, "previousImage", previousImage, "image", image)
+	if image != previousImage {
+		state.Publisher.Publish(kubeprovider.ImageResolvedEvent, "instancegroup", instanceGroup.GetName(), "clusterVersion", state.GetClusterVersion(), "image", image)
+	}
+	status.SetImageParameter(parameter)
+	status.SetResolvedImage(image)
+	return image, nil
+}
+
+// ResolvePipelineImage returns the latest available image of the image pipeline, the configured image is used until
+// the pipeline has built an image which was distributed to the region
+func (ctx *EksInstanceGroupContext) ResolvePipelineImage() (string, error) {
+	var (
+		instanceGroup = ctx.GetInstanceGroup()
+		configuration = instanceGroup.GetEKSConfiguration()
+		status        = instanceGroup.GetStatus()
+		state         = ctx.GetDiscoveredState()
+		pipelineArn   = configuration.GetImagePipelineArn()
+		previousImage = status.GetResolvedImage()
+	)
+
+	latest, err := ctx.AwsWorker.GetLatestPipelineImage(pipelineArn)
+	if err != nil {
+		return "", err
+	}
+
+	if latest == nil {
+		// the image may be omitted when the pipeline is set, there is no image to fall back to until one is built
+		if configuration.Image == "" {
+			return "", errors.Errorf("image pipeline %v has no available images, and no image is configured", pipelineArn)
+		}
+		ctx.Log.Info("image pipeline has no available images, using configured image", "instancegroup", instanceGroup.GetName(), "pipeline", pipelineArn, "image", configuration.Image)
+		status.SetImageParameter("")
+		status.SetImageVersion("")
+		status.SetResolvedImage("")
+		return configuration.Image, nil
+	}
+
+	if latest.Image != previousImage {
+		ctx.Log.Info("resolved pipeline image", "instancegroup", instanceGroup.GetName(), "pipeline", pipelineArn, "version", latest.Version, "previousImage", previousImage, "image", latest.Image)
+		state.Publisher.Publish(kubeprovider.PipelineImageResolvedEvent, "instancegroup", instanceGroup.GetName(), "pipeline", pipelineArn, "version", latest.Version, "image", latest.Image)
+	}
+	status.SetImageParameter("")
+	status.SetImageVersion(latest.Version)
+	status.SetResolvedImage(latest.Image)
+	return latest.Image, nil
+}
+
+// IsStandardEKSImage returns true if an image name matches one of the EKS optimized images without GPU drivers
+func IsStandardEKSImage(name string) bool {
+	for _, prefix := range StandardEKSImagePrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 func (ctx *EksInstanceGroupContext) GetBootstrapArgs() string {
 	var (
 		instanceGroup = ctx.GetInstanceGroup()
@@ -361,7 +953,143 @@ func (ctx *EksInstanceGroupContext) GetBootstrapArgs() string {
 
 	labelsFlag := fmt.Sprintf("--node-labels=%v", strings.Join(ctx.GetLabelList(), ","))
 	taintsFlag := fmt.Sprintf("--register-with-taints=%v", strings.Join(ctx.GetTaintList(), ","))
-	return fmt.Sprintf("--kubelet-extra-args '%v %v %v'", labelsFlag, taintsFlag, bootstrapArgs)
+	args := fmt.Sprintf("--kubelet-extra-args '%v %v %v'", labelsFlag, taintsFlag, bootstrapArgs)
+
+	// nodes describe the cluster at boot unless its endpoint and certificate authority are provided
+	if endpoint := configuration.GetAPIServerEndpoint(); endpoint != "" {
+		args = fmt.Sprintf("%v --apiserver-endpoint %v", args, endpoint)
+	}
+	if ca := configuration.GetCertificateAuthority(); ca != "" {
+		args = fmt.Sprintf("%v --b64-cluster-ca %v", args, ca)
+	}
+	return args
+}
+
+// UpdateNodeOwnership labels and annotates the nodes of the scaling group's instances with the instance group which
+// owns them, the lifecycle of the instances is only described for nodes which are missing their ownership
+func (ctx *EksInstanceGroupContext) UpdateNodeOwnership() error {
+	var (
+		instanceGroup = ctx.GetInstanceGroup()
+		state         = ctx.GetDiscoveredState()
+		scalingGroup  = state.GetScalingGroup()
+		nodes         = state.GetClusterNodes()
+		instances     = make(map[string]*autoscaling.Instance)
+		pending       = make([]corev1.Node, 0)
+		pendingIds    = make([]string, 0)
+	)
+
+	if scalingGroup == nil || nodes == nil {
+		return nil
+	}
+
+	for _, instance := range scalingGroup.Instances {
+		instances[aws.StringValue(instance.InstanceId)] = instance
+	}
+
+	ownership := func(instanceID, lifecycle string) kubeprovider.NodeOwnership {
+		return kubeprovider.NodeOwnership{
+			Group:               instanceGroup.GetName(),
+			Namespace:           instanceGroup.GetNamespace(),
+			Lifecycle:           lifecycle,
+			LaunchConfiguration: aws.StringValue(instances[instanceID].LaunchConfigurationName),
+		}
+	}
+
+	for _, node := range nodes.Items {
+		instanceID := common.GetLastElementBy(node.Spec.ProviderID, "/")
+		if _, ok := instances[instanceID]; !ok {
+			continue
+		}
+		lifecycle := node.GetLabels()[kubeprovider.NodeLifecycleLabelKey]
+		if lifecycle != "" && kubeprovider.HasNodeOwnership(node, ownership(instanceID, lifecycle)) {
+			continue
+		}
+		pending = append(pending, node)
+		pendingIds = append(pendingIds, instanceID)
+	}
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	lifecycles, err := ctx.AwsWorker.GetInstanceLifecycles(pendingIds)
+	if err != nil {
+		return errors.Wrap(err, "failed to describe instance lifecycles")
+	}
+
+	for i, node := range pending {
+		o := ownership(pendingIds[i], lifecycles[pendingIds[i]])
+		if err := kubeprovider.SetNodeOwnership(ctx.ClusterKubernetesClient.Kubernetes, node, o); err != nil {
+			return errors.Wrapf(err, "failed to label node %v", node.GetName())
+		}
+		ctx.Log.Info("labeled node with ownership", "instancegroup", instanceGroup.GetName(), "node", node.GetName(), "labels", o.Labels())
+	}
+	return nil
+}
+
+// RequireIMDSv2 requires session tokens for the instance metadata service of the instances of the scaling group
+// which allow IMDSv1, launch configurations cannot configure the metadata options of the instances they launch
+func (ctx *EksInstanceGroupContext) RequireIMDSv2() error {
+	var (
+		instanceGroup = ctx.GetInstanceGroup()
+		state         = ctx.GetDiscoveredState()
+		scalingGroup  = state.GetScalingGroup()
+		instanceIds   = make([]string, 0)
+	)
+
+	if scalingGroup == nil {
+		return nil
+	}
+	for _, instance := range scalingGroup.Instances {
+		instanceIds = append(instanceIds, aws.StringValue(instance.InstanceId))
+	}
+
+	allowing, err := ctx.AwsWorker.GetInstancesAllowingIMDSv1(instanceIds)
+	if err != nil {
+		return errors.Wrap(err, "failed to describe instance metadata options")
+	}
+	for _, instanceId := range allowing {
+		ctx.Log.Info("requiring IMDSv2", "instancegroup", instanceGroup.GetName(), "instance", instanceId)
+		if err := ctx.AwsWorker.RequireIMDSv2(instanceId); err != nil {
+			return errors.Wrapf(err, "failed to require IMDSv2 on instance %v", instanceId)
+		}
+	}
+	return nil
+}
+
+// ResolveSpotPrice sets a spot price which is a percentage to that percentage of the on-demand price of the instance
+// type, the last resolved price is used while the on-demand price cannot be retrieved
+func (ctx *EksInstanceGroupContext) ResolveSpotPrice() error {
+	var (
+		instanceGroup = ctx.GetInstanceGroup()
+		configuration = instanceGroup.GetEKSConfiguration()
+		status        = instanceGroup.GetStatus()
+		instanceType  = configuration.InstanceType
+		previousPrice = status.GetResolvedSpotPrice()
+	)
+
+	if !configuration.IsSpotPricePercentage() {
+		status.SetResolvedSpotPrice("")
+		return nil
+	}
+
+	onDemandPrice, err := ctx.AwsWorker.GetOnDemandPrice(instanceType)
+	if err != nil {
+		if previousPrice == "" {
+			return errors.Wrapf(err, "failed to get on-demand price of instance type %v", instanceType)
+		}
+		ctx.Log.Error(err, "failed to get on-demand price, using last resolved spot price", "instancegroup", instanceGroup.GetName(), "instanceType", instanceType, "spotPrice", previousPrice)
+		configuration.SetSpotPrice(previousPrice)
+		return nil
+	}
+
+	price := strconv.FormatFloat(onDemandPrice*configuration.GetSpotPricePercentage()/100, 'f', 4, 64)
+	if price != previousPrice {
+		ctx.Log.Info("resolved spot price", "instancegroup", instanceGroup.GetName(), "instanceType", instanceType, "percentage", configuration.GetSpotPrice(), "onDemandPrice", onDemandPrice, "previousPrice", previousPrice, "spotPrice", price)
+	}
+	status.SetResolvedSpotPrice(price)
+	configuration.SetSpotPrice(price)
+	return nil
 }
 
 func (ctx *EksInstanceGroupContext) discoverSpotPrice() error {
@@ -380,7 +1108,7 @@ func (ctx *EksInstanceGroupContext) discoverSpotPrice() error {
 	}
 
 	// get latest spot recommendations from events
-	recommendation, err := kubeprovider.GetSpotRecommendation(ctx.KubernetesClient.Kubernetes, scalingGroupName)
+	recommendation, err := kubeprovider.GetSpotRecommendation(ctx.ClusterKubernetesClient.Kubernetes, scalingGroupName)
 	if err != nil {
 		configuration.SetSpotPrice("")
 		return err
@@ -463,6 +1191,44 @@ func (ctx *EksInstanceGroupContext) findTargetScalingGroup(groups []*autoscaling
 	return nil
 }
 
+// findReplacedScalingGroups returns the scaling group of the instance group which is named by the resource name, if it
+// exists, and the other scaling groups of the instance group which it replaces
+func (ctx *EksInstanceGroupContext) findReplacedScalingGroups(groups []*autoscaling.Group) (*autoscaling.Group, []*autoscaling.Group) {
+	var (
+		instanceGroup = ctx.GetInstanceGroup()
+		target        *autoscaling.Group
+		replaced      = make([]*autoscaling.Group, 0)
+	)
+
+	for _, group := range groups {
+		var nameMatch, namespaceMatch bool
+		for _, tag := range group.Tags {
+			var (
+				key   = aws.StringValue(tag.Key)
+				value = aws.StringValue(tag.Value)
+			)
+			if key == provisioners.TagInstanceGroupName && value == instanceGroup.GetName() {
+				nameMatch = true
+			}
+			if key == provisioners.TagInstanceGroupNamespace && value == instanceGroup.GetNamespace() {
+				namespaceMatch = true
+			}
+		}
+		// scaling groups which are being deleted are no longer migrated
+		if !nameMatch || !namespaceMatch || aws.StringValue(group.Status) == ScalingGroupDeletionStatus {
+			continue
+		}
+
+		if aws.StringValue(group.AutoScalingGroupName) == ctx.ResourcePrefix {
+			target = group
+			continue
+		}
+		replaced = append(replaced, group)
+	}
+
+	return target, replaced
+}
+
 func (ctx *EksInstanceGroupContext) UpdateNodeReadyCondition() bool {
 	var (
 		state         = ctx.GetDiscoveredState()
@@ -490,7 +1256,6 @@ func (ctx *EksInstanceGroupContext) UpdateNodeReadyCondition() bool {
 
 	instances := strings.Join(instanceIds, ",")
 
-	var conditions []v1alpha1.InstanceGroupCondition
 	ok, err := kubeprovider.IsDesiredNodesReady(nodes, instanceIds, desiredCount)
 	if err != nil {
 		ctx.Log.Error(err, "could not update node conditions", "instancegroup", instanceGroup.GetName())
@@ -502,8 +1267,7 @@ func (ctx *EksInstanceGroupContext) UpdateNodeReadyCondition() bool {
 		}
 		ctx.Log.Info("desired nodes are ready", "instancegroup", instanceGroup.GetName(), "instances", instances)
 		state.SetNodesReady(true)
-		conditions = append(conditions, v1alpha1.NewInstanceGroupCondition(v1alpha1.NodesReady, corev1.ConditionTrue))
-		status.SetConditions(conditions)
+		status.SetCondition(v1alpha1.NewInstanceGroupCondition(v1alpha1.NodesReady, corev1.ConditionTrue))
 		return true
 	}
 
@@ -512,8 +1276,7 @@ func (ctx *EksInstanceGroupContext) UpdateNodeReadyCondition() bool {
 	}
 	ctx.Log.Info("desired nodes are not ready", "instancegroup", instanceGroup.GetName(), "instances", instances)
 	state.SetNodesReady(false)
-	conditions = append(conditions, v1alpha1.NewInstanceGroupCondition(v1alpha1.NodesReady, corev1.ConditionFalse))
-	status.SetConditions(conditions)
+	status.SetCondition(v1alpha1.NewInstanceGroupCondition(v1alpha1.NodesReady, corev1.ConditionFalse))
 	return false
 }
 
@@ -531,9 +1294,16 @@ func (ctx *EksInstanceGroupContext) GetEnabledMetrics() ([]string, bool) {
 
 	// handle 'all' metrics provided
 	if common.ContainsEqualFold(metrics, "all") {
-		desiredMetrics = awsprovider.DefaultAutoscalingMetrics
+		desiredMetrics = append([]string{}, awsprovider.DefaultAutoscalingMetrics...)
 	} else {
-		desiredMetrics = metrics
+		desiredMetrics = append([]string{}, metrics...)
+	}
+
+	// metrics which alarms are configured on are collected
+	for _, m := range ctx.GetAlarmMetrics() {
+		if !common.ContainsString(desiredMetrics, m) {
+			desiredMetrics = append(desiredMetrics, m)
+		}
 	}
 
 	// get all already enabled metrics
@@ -569,9 +1339,16 @@ func (ctx *EksInstanceGroupContext) GetDisabledMetrics() ([]string, bool) {
 
 	// handle 'all' metrics provided
 	if common.ContainsEqualFold(metrics, "all") {
-		desiredMetrics = awsprovider.DefaultAutoscalingMetrics
+		desiredMetrics = append([]string{}, awsprovider.DefaultAutoscalingMetrics...)
 	} else {
-		desiredMetrics = metrics
+		desiredMetrics = append([]string{}, metrics...)
+	}
+
+	// metrics which alarms are configured on are collected
+	for _, m := range ctx.GetAlarmMetrics() {
+		if !common.ContainsString(desiredMetrics, m) {
+			desiredMetrics = append(desiredMetrics, m)
+		}
 	}
 
 	// find metrics that need to be disabled
@@ -682,6 +1459,27 @@ func (ctx *EksInstanceGroupContext) GetAddedHooks() ([]v1alpha1.LifecycleHookSpe
 	return addHooks, true
 }
 
+// SetLifecycleManagerDefaults sets the notification target and role of lifecycle-manager hooks which do not specify them
+func (ctx *EksInstanceGroupContext) SetLifecycleManagerDefaults(defaults provisioners.LifecycleManagerConfiguration) {
+	var (
+		instanceGroup = ctx.GetInstanceGroup()
+		configuration = instanceGroup.GetEKSConfiguration()
+		hooks         = configuration.GetLifecycleHooks()
+	)
+
+	for i, hook := range hooks {
+		if !hook.LifecycleManager {
+			continue
+		}
+		if common.StringEmpty(hook.NotificationArn) {
+			hooks[i].NotificationArn = defaults.NotificationArn
+		}
+		if common.StringEmpty(hook.RoleArn) {
+			hooks[i].RoleArn = defaults.RoleArn
+		}
+	}
+}
+
 func (ctx *EksInstanceGroupContext) UpdateLifecycleHooks(asgName string) error {
 	var (
 		instanceGroup = ctx.GetInstanceGroup()
@@ -727,47 +1525,130 @@ func (ctx *EksInstanceGroupContext) UpdateLifecycleHooks(asgName string) error {
 	return nil
 }
 
+// GetNotificationChanges returns the notifications which are sent to a topic with other types than configured, or not
+// at all, and the topics which notifications are sent to but which are no longer configured
+func (ctx *EksInstanceGroupContext) GetNotificationChanges() ([]v1alpha1.NotificationSpec, []string) {
+	var (
+		instanceGroup = ctx.GetInstanceGroup()
+		state         = ctx.GetDiscoveredState()
+		configuration = instanceGroup.GetEKSConfiguration()
+		desired       = configuration.GetNotifications()
+		existing      = make(map[string][]string)
+		put           = make([]v1alpha1.NotificationSpec, 0)
+		remove        = make([]string, 0)
+	)
+
+	// a scaling group has a notification configuration per topic and type
+	for _, n := range state.Notifications {
+		topic := aws.StringValue(n.TopicARN)
+		existing[topic] = append(existing[topic], aws.StringValue(n.NotificationType))
+	}
+
+	desiredTopics := make([]string, 0)
+	for _, n := range desired {
+		desiredTopics = append(desiredTopics, n.TopicArn)
+		types, ok := existing[n.TopicArn]
+		if !ok || !common.StringSliceEquals(types, append([]string{}, n.Types...)) {
+			put = append(put, n)
+		}
+	}
+
+	for topic := range existing {
+		if !common.ContainsString(desiredTopics, topic) {
+			remove = append(remove, topic)
+		}
+	}
+	sort.Strings(remove)
+	return put, remove
+}
+
+// UpdateNotifications sends the configured notifications of the scaling group to their SNS topics, and stops sending
+// notifications to topics which are no longer configured
+func (ctx *EksInstanceGroupContext) UpdateNotifications(asgName string) error {
+	var (
+		instanceGroup = ctx.GetInstanceGroup()
+		put, remove   = ctx.GetNotificationChanges()
+	)
+
+	for _, topic := range remove {
+		if err := ctx.AwsWorker.DeleteNotificationConfiguration(asgName, topic); err != nil {
+			return errors.Wrapf(err, "failed to delete notification configuration of topic %v", topic)
+		}
+		ctx.Log.Info("deleted notification configuration", "instancegroup", instanceGroup.GetName(), "topic", topic)
+	}
+
+	for _, n := range put {
+		if err := ctx.AwsWorker.PutNotificationConfiguration(asgName, n.TopicArn, n.Types); err != nil {
+			return errors.Wrapf(err, "failed to put notification configuration of topic %v", n.TopicArn)
+		}
+		ctx.Log.Info("updated notification configuration", "instancegroup", instanceGroup.GetName(), "topic", n.TopicArn, "types", n.Types)
+	}
+	return nil
+}
+
 func (ctx *EksInstanceGroupContext) GetManagedPoliciesList(additionalPolicies []string) []string {
-	managedPolicies := make([]string, 0)
+	var (
+		managedPolicies = make([]string, 0)
+		partition       = ctx.AwsWorker.GetPartition()
+	)
 	for _, name := range additionalPolicies {
 		switch {
-		case strings.HasPrefix(name, awsprovider.IAMPolicyPrefix):
-			managedPolicies = append(managedPolicies, name)
-		case strings.HasPrefix(name, awsprovider.IAMARNPrefix):
+		case awsprovider.IsIAMArn(name):
 			managedPolicies = append(managedPolicies, name)
 		default:
-			managedPolicies = append(managedPolicies, fmt.Sprintf("%s/%s", awsprovider.IAMPolicyPrefix, name))
+			managedPolicies = append(managedPolicies, awsprovider.ManagedPolicyArn(partition, name))
 		}
 	}
 
 	for _, name := range DefaultManagedPolicies {
-		managedPolicies = append(managedPolicies, fmt.Sprintf("%s/%s", awsprovider.IAMPolicyPrefix, name))
+		managedPolicies = append(managedPolicies, awsprovider.ManagedPolicyArn(partition, name))
 	}
 
 	return managedPolicies
 }
 
-func (ctx *EksInstanceGroupContext) RemoveAuthRole(arn string) error {
-	ctx.Lock()
-	defer ctx.Unlock()
-
-	var instanceGroup = ctx.GetInstanceGroup()
+// GetRoleReferences returns the names of the instance groups whose nodes use a role, including this instance group
+func (ctx *EksInstanceGroupContext) GetRoleReferences(arn string) ([]string, error) {
 	var list = &unstructured.UnstructuredList{}
-	var sharedGroups = make([]string, 0)
+	var references = make([]string, 0)
 
 	list, err := ctx.KubernetesClient.KubeDynamic.Resource(v1alpha1.GroupVersionResource).List(metav1.ListOptions{})
 	if err != nil {
-		return err
+		return references, err
 	}
 
 	// find objects which share the same nodesInstanceRoleArn
 	for _, obj := range list.Items {
 		if val, ok, _ := unstructured.NestedString(obj.Object, "status", "nodesInstanceRoleArn"); ok {
 			if strings.EqualFold(arn, val) {
-				sharedGroups = append(sharedGroups, obj.GetName())
+				references = append(references, obj.GetName())
 			}
 		}
 	}
+	return references, nil
+}
+
+// GetManagedRoleName returns the name of the role and instance profile which are created by the controller, a shared
+// role is named by the cluster and the shared role name
+func (ctx *EksInstanceGroupContext) GetManagedRoleName() string {
+	var configuration = ctx.GetInstanceGroup().GetEKSConfiguration()
+
+	if configuration.HasSharedRole() {
+		return fmt.Sprintf("%v-%v", configuration.GetClusterName(), configuration.GetSharedRoleName())
+	}
+	return ctx.ResourcePrefix
+}
+
+func (ctx *EksInstanceGroupContext) RemoveAuthRole(arn string) error {
+	ctx.Lock()
+	defer ctx.Unlock()
+
+	var instanceGroup = ctx.GetInstanceGroup()
+
+	sharedGroups, err := ctx.GetRoleReferences(arn)
+	if err != nil {
+		return err
+	}
 
 	// If there are other instance groups using the same role we should not remove it from aws-auth
 	if len(sharedGroups) > 1 {
@@ -780,5 +1661,5 @@ func (ctx *EksInstanceGroupContext) RemoveAuthRole(arn string) error {
 		return nil
 	}
 
-	return common.RemoveAuthConfigMap(ctx.KubernetesClient.Kubernetes, []string{arn})
+	return common.RemoveAuthConfigMap(ctx.ClusterKubernetesClient.Kubernetes, []string{arn})
 }
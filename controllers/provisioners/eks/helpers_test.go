@@ -16,19 +16,26 @@ limitations under the License.
 package eks
 
 import (
+	"encoding/base64"
 	"fmt"
+	"math/rand"
 	"sort"
+	"strings"
 	"testing"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/autoscaling"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/aws/aws-sdk-go/service/imagebuilder"
 	"github.com/keikoproj/instance-manager/api/v1alpha1"
 	awsprovider "github.com/keikoproj/instance-manager/controllers/providers/aws"
 	kubeprovider "github.com/keikoproj/instance-manager/controllers/providers/kubernetes"
+	"github.com/keikoproj/instance-manager/controllers/provisioners"
 	"github.com/onsi/gomega"
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestResolveSecurityGroups(t *testing.T) {
@@ -529,3 +536,1099 @@ func TestUpdateLifecycleHooks(t *testing.T) {
 		g.Expect(len(tc.expectedAdded)).To(gomega.Equal(asgMock.PutLifecycleHookCallCount))
 	}
 }
+
+func TestUpdateNotifications(t *testing.T) {
+	var (
+		g             = gomega.NewGomegaWithT(t)
+		k             = MockKubernetesClientSet()
+		ig            = MockInstanceGroup()
+		configuration = ig.GetEKSConfiguration()
+		asgMock       = NewAutoScalingMocker()
+		iamMock       = NewIamMocker()
+		eksMock       = NewEksMocker()
+		ec2Mock       = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+
+	var (
+		alerts   = "arn:aws:sns:us-west-2:123456789012:alerts"
+		audit    = "arn:aws:sns:us-west-2:123456789012:audit"
+		launch   = "autoscaling:EC2_INSTANCE_LAUNCH"
+		failures = []string{"autoscaling:EC2_INSTANCE_TERMINATE_ERROR", "autoscaling:EC2_INSTANCE_LAUNCH_ERROR"}
+	)
+
+	notification := func(topic, notificationType string) *autoscaling.NotificationConfiguration {
+		return &autoscaling.NotificationConfiguration{
+			AutoScalingGroupName: aws.String("my-asg"),
+			TopicARN:             aws.String(topic),
+			NotificationType:     aws.String(notificationType),
+		}
+	}
+
+	tests := []struct {
+		existing        []*autoscaling.NotificationConfiguration
+		desired         []v1alpha1.NotificationSpec
+		expectedPut     []string
+		expectedDeleted []string
+	}{
+		{expectedPut: []string{}, expectedDeleted: []string{}},
+		{desired: []v1alpha1.NotificationSpec{{TopicArn: alerts, Types: failures}}, expectedPut: []string{alerts}, expectedDeleted: []string{}},
+		{existing: []*autoscaling.NotificationConfiguration{notification(alerts, failures[1]), notification(alerts, failures[0])}, desired: []v1alpha1.NotificationSpec{{TopicArn: alerts, Types: failures}}, expectedPut: []string{}, expectedDeleted: []string{}},
+		{existing: []*autoscaling.NotificationConfiguration{notification(alerts, failures[0])}, desired: []v1alpha1.NotificationSpec{{TopicArn: alerts, Types: failures}}, expectedPut: []string{alerts}, expectedDeleted: []string{}},
+		{existing: []*autoscaling.NotificationConfiguration{notification(alerts, failures[0]), notification(audit, launch)}, desired: []v1alpha1.NotificationSpec{{TopicArn: alerts, Types: []string{failures[0]}}}, expectedPut: []string{}, expectedDeleted: []string{audit}},
+		{existing: []*autoscaling.NotificationConfiguration{notification(audit, launch)}, expectedPut: []string{}, expectedDeleted: []string{audit}},
+	}
+
+	for i, tc := range tests {
+		t.Logf("Test #%v - %+v", i, tc)
+		asgMock.PutNotificationInputs = nil
+		asgMock.DeletedNotificationTopics = []string{}
+
+		ctx.SetDiscoveredState(&DiscoveredState{
+			Publisher: kubeprovider.EventPublisher{
+				Client: k.Kubernetes,
+			},
+			Notifications: tc.existing,
+		})
+		configuration.SetNotifications(tc.desired)
+
+		err := ctx.UpdateNotifications("my-asg")
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+
+		put := make([]string, 0)
+		for _, input := range asgMock.PutNotificationInputs {
+			put = append(put, aws.StringValue(input.TopicARN))
+			g.Expect(aws.StringValue(input.AutoScalingGroupName)).To(gomega.Equal("my-asg"))
+		}
+		g.Expect(put).To(gomega.Equal(tc.expectedPut))
+		g.Expect(asgMock.DeletedNotificationTopics).To(gomega.Equal(tc.expectedDeleted))
+	}
+
+	// the configured types are not reordered
+	g.Expect(failures).To(gomega.Equal([]string{"autoscaling:EC2_INSTANCE_TERMINATE_ERROR", "autoscaling:EC2_INSTANCE_LAUNCH_ERROR"}))
+}
+
+func TestUpdateScalingProcessesAZRebalance(t *testing.T) {
+	var (
+		g             = gomega.NewGomegaWithT(t)
+		k             = MockKubernetesClientSet()
+		ig            = MockInstanceGroup()
+		configuration = ig.GetEKSConfiguration()
+		status        = ig.GetStatus()
+		asgMock       = NewAutoScalingMocker()
+		iamMock       = NewIamMocker()
+		eksMock       = NewEksMocker()
+		ec2Mock       = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+
+	processes := func(names ...string) []*autoscaling.SuspendedProcess {
+		suspended := make([]*autoscaling.SuspendedProcess, 0)
+		for _, name := range names {
+			suspended = append(suspended, &autoscaling.SuspendedProcess{ProcessName: aws.String(name)})
+		}
+		return suspended
+	}
+
+	tests := []struct {
+		policy          string
+		appliedPolicy   string
+		specProcesses   []string
+		groupProcesses  []*autoscaling.SuspendedProcess
+		expectedSuspend []string
+		expectedResume  []string
+		expectedDrift   bool
+	}{
+		// without a policy the process follows suspendProcesses
+		{specProcesses: []string{"AZRebalance"}, expectedSuspend: []string{"AZRebalance"}},
+		{groupProcesses: processes("AZRebalance"), expectedResume: []string{"AZRebalance"}},
+		// the policy overrides suspendProcesses
+		{policy: v1alpha1.AZRebalanceDisabled, specProcesses: []string{"Launch"}, expectedSuspend: []string{"Launch", "AZRebalance"}},
+		{policy: v1alpha1.AZRebalanceEnabled, specProcesses: []string{"all"}, groupProcesses: processes(awsprovider.DefaultSuspendProcesses...), expectedResume: []string{"AZRebalance"}},
+		// a policy which is applied for the first time is not drift
+		{policy: v1alpha1.AZRebalanceEnabled, appliedPolicy: v1alpha1.AZRebalanceDisabled, groupProcesses: processes("AZRebalance"), expectedResume: []string{"AZRebalance"}},
+		// toggling the process outside of the controller is drift
+		{policy: v1alpha1.AZRebalanceDisabled, appliedPolicy: v1alpha1.AZRebalanceDisabled, expectedSuspend: []string{"AZRebalance"}, expectedDrift: true},
+		{policy: v1alpha1.AZRebalanceEnabled, appliedPolicy: v1alpha1.AZRebalanceEnabled, groupProcesses: processes("AZRebalance"), expectedResume: []string{"AZRebalance"}, expectedDrift: true},
+		{policy: v1alpha1.AZRebalanceDisabled, appliedPolicy: v1alpha1.AZRebalanceDisabled, groupProcesses: processes("AZRebalance")},
+	}
+
+	for i, tc := range tests {
+		t.Logf("Test #%v - %+v", i, tc)
+		asgMock.SuspendProcessesInput = nil
+		asgMock.ResumeProcessesInput = nil
+
+		scalingGroup := MockScalingGroup("my-asg")
+		scalingGroup.SuspendedProcesses = tc.groupProcesses
+		ctx.SetDiscoveredState(&DiscoveredState{
+			Publisher: kubeprovider.EventPublisher{
+				Client: k.Kubernetes,
+			},
+			ScalingGroup: scalingGroup,
+		})
+		configuration.AZRebalance = tc.policy
+		configuration.SetSuspendProcesses(tc.specProcesses)
+		status.SetAZRebalance(tc.appliedPolicy)
+
+		g.Expect(ctx.AZRebalanceDrifted()).To(gomega.Equal(tc.expectedDrift))
+		err := ctx.UpdateScalingProcesses("my-asg")
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(status.GetAZRebalance()).To(gomega.Equal(tc.policy))
+
+		var suspended, resumed []string
+		if asgMock.SuspendProcessesInput != nil {
+			suspended = aws.StringValueSlice(asgMock.SuspendProcessesInput.ScalingProcesses)
+		}
+		if asgMock.ResumeProcessesInput != nil {
+			resumed = aws.StringValueSlice(asgMock.ResumeProcessesInput.ScalingProcesses)
+		}
+		g.Expect(suspended).To(gomega.Equal(tc.expectedSuspend))
+		g.Expect(resumed).To(gomega.Equal(tc.expectedResume))
+	}
+}
+
+func TestLifecycleManagerHooks(t *testing.T) {
+	var (
+		g             = gomega.NewGomegaWithT(t)
+		k             = MockKubernetesClientSet()
+		ig            = MockInstanceGroup()
+		configuration = ig.GetEKSConfiguration()
+		asgMock       = NewAutoScalingMocker()
+		iamMock       = NewIamMocker()
+		eksMock       = NewEksMocker()
+		ec2Mock       = NewEc2Mocker()
+		defaults      = provisioners.LifecycleManagerConfiguration{
+			NotificationArn: "arn:aws:sqs:us-west-2:123456789012:lifecycle-manager-queue",
+			RoleArn:         "arn:aws:iam::123456789012:role/lifecycle-manager-role",
+		}
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+
+	configuration.SetLifecycleHooks([]v1alpha1.LifecycleHookSpec{
+		{
+			Name:             "drain-hook",
+			Lifecycle:        awsprovider.LifecycleHookTransitionTerminate,
+			LifecycleManager: true,
+		},
+		{
+			Name:             "custom-hook",
+			Lifecycle:        awsprovider.LifecycleHookTransitionTerminate,
+			NotificationArn:  "arn:aws:sns:us-west-2:123456789012:custom-topic",
+			RoleArn:          "arn:aws:iam::123456789012:role/custom-role",
+			LifecycleManager: true,
+		},
+		{
+			Name:      "other-hook",
+			Lifecycle: awsprovider.LifecycleHookTransitionLaunch,
+		},
+	})
+
+	ctx.SetLifecycleManagerDefaults(defaults)
+	hooks := configuration.GetLifecycleHooks()
+	g.Expect(hooks[0].NotificationArn).To(gomega.Equal(defaults.NotificationArn))
+	g.Expect(hooks[0].RoleArn).To(gomega.Equal(defaults.RoleArn))
+	g.Expect(hooks[1].NotificationArn).To(gomega.Equal("arn:aws:sns:us-west-2:123456789012:custom-topic"))
+	g.Expect(hooks[1].RoleArn).To(gomega.Equal("arn:aws:iam::123456789012:role/custom-role"))
+	g.Expect(hooks[2].NotificationArn).To(gomega.BeEmpty())
+
+	// existing hooks are not recreated
+	ctx.SetDiscoveredState(&DiscoveredState{
+		Publisher: kubeprovider.EventPublisher{
+			Client: k.Kubernetes,
+		},
+		LifecycleHooks: []*autoscaling.LifecycleHook{
+			{
+				LifecycleHookName:     aws.String("drain-hook"),
+				LifecycleTransition:   aws.String(awsprovider.LifecycleHookTransitionTerminate),
+				NotificationTargetARN: aws.String(defaults.NotificationArn),
+				RoleARN:               aws.String(defaults.RoleArn),
+			},
+		},
+	})
+	added, ok := ctx.GetAddedHooks()
+	g.Expect(ok).To(gomega.BeTrue())
+	g.Expect(added).To(gomega.HaveLen(2))
+	_, ok = ctx.GetRemovedHooks()
+	g.Expect(ok).To(gomega.BeFalse())
+}
+
+func TestGPUInstanceType(t *testing.T) {
+	var (
+		g             = gomega.NewGomegaWithT(t)
+		k             = MockKubernetesClientSet()
+		ig            = MockInstanceGroup()
+		configuration = ig.GetEKSConfiguration()
+		asgMock       = NewAutoScalingMocker()
+		iamMock       = NewIamMocker()
+		eksMock       = NewEksMocker()
+		ec2Mock       = NewEc2Mocker()
+		gpuType       = &ec2.InstanceTypeInfo{
+			InstanceType: aws.String("p3.8xlarge"),
+			GpuInfo: &ec2.GpuInfo{
+				Gpus: []*ec2.GpuDeviceInfo{
+					{Name: aws.String("V100"), Manufacturer: aws.String("NVIDIA"), Count: aws.Int64(4)},
+				},
+			},
+		}
+		standardImage = &ec2.Image{ImageId: aws.String("ami-123456789012"), Name: aws.String("amazon-eks-node-1.16-v20200618")}
+		gpuImage      = &ec2.Image{ImageId: aws.String("ami-210987654321"), Name: aws.String("amazon-eks-gpu-node-1.16-v20200618")}
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+
+	state := &DiscoveredState{
+		Publisher: kubeprovider.EventPublisher{
+			Client: k.Kubernetes,
+		},
+		Cluster: &eks.Cluster{
+			Version: aws.String("1.16"),
+		},
+	}
+	ctx.SetDiscoveredState(state)
+
+	// non-GPU instance types are unchanged
+	state.SetInstanceTypeInfo(&ec2.InstanceTypeInfo{InstanceType: aws.String("m5.large")})
+	state.SetImage(standardImage)
+	g.Expect(GetAcceleratorCount(state.GetInstanceTypeInfo())).To(gomega.Equal(0))
+	g.Expect(ctx.GetLabelList()).NotTo(gomega.ContainElement("nvidia.com/gpu=true"))
+	g.Expect(ctx.GetTaintList()).To(gomega.BeEmpty())
+	g.Expect(ctx.ValidateImage()).To(gomega.Succeed())
+
+	// GPU instance types get the nvidia labels and taints, and require a GPU image
+	state.SetInstanceTypeInfo(gpuType)
+	g.Expect(GetAcceleratorCount(gpuType)).To(gomega.Equal(4))
+	g.Expect(ctx.GetLabelList()).To(gomega.ContainElement("nvidia.com/gpu=true"))
+	g.Expect(ctx.GetTaintList()).To(gomega.Equal([]string{"nvidia.com/gpu=true:NoSchedule"}))
+	g.Expect(ctx.ValidateImage()).NotTo(gomega.Succeed())
+
+	state.SetImage(gpuImage)
+	g.Expect(ctx.ValidateImage()).To(gomega.Succeed())
+
+	// configured taints take precedence
+	configuration.Taints = []corev1.Taint{{Key: "nvidia.com/gpu", Value: "dedicated", Effect: corev1.TaintEffectNoExecute}}
+	g.Expect(ctx.GetTaintList()).To(gomega.Equal([]string{"nvidia.com/gpu=dedicated:NoExecute"}))
+}
+
+func TestValidatePlacement(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+
+	var (
+		outpost1  = "arn:aws:outposts:us-west-2:123456789012:outpost/op-1"
+		outpost2  = "arn:aws:outposts:us-west-2:123456789012:outpost/op-2"
+		regional  = awsprovider.SubnetPlacement{SubnetID: "subnet-1", Zone: "us-west-2a", ZoneType: awsprovider.ZoneTypeAvailabilityZone}
+		localZone = awsprovider.SubnetPlacement{SubnetID: "subnet-2", Zone: "us-west-2-lax-1a", ZoneType: awsprovider.ZoneTypeLocalZone}
+		onOutpost = awsprovider.SubnetPlacement{SubnetID: "subnet-3", Zone: "us-west-2a", ZoneType: awsprovider.ZoneTypeOutpost, OutpostArn: outpost1}
+		onOther   = awsprovider.SubnetPlacement{SubnetID: "subnet-4", Zone: "us-west-2b", ZoneType: awsprovider.ZoneTypeOutpost, OutpostArn: outpost2}
+	)
+
+	tests := []struct {
+		placements []awsprovider.SubnetPlacement
+		unoffered  []string
+		expectErr  bool
+	}{
+		{placements: []awsprovider.SubnetPlacement{regional, localZone}, expectErr: false},
+		{placements: []awsprovider.SubnetPlacement{onOutpost}, expectErr: false},
+		{placements: []awsprovider.SubnetPlacement{regional, localZone}, unoffered: []string{"subnet-2"}, expectErr: true},
+		{placements: []awsprovider.SubnetPlacement{onOutpost, regional}, expectErr: true},
+		{placements: []awsprovider.SubnetPlacement{onOutpost, onOther}, expectErr: true},
+	}
+
+	for i, tc := range tests {
+		t.Logf("Test #%v - %+v", i, tc)
+		ctx.SetDiscoveredState(&DiscoveredState{
+			SubnetPlacements: tc.placements,
+			UnofferedSubnets: tc.unoffered,
+		})
+		err := ctx.ValidatePlacement()
+		if tc.expectErr {
+			g.Expect(err).To(gomega.HaveOccurred())
+		} else {
+			g.Expect(err).NotTo(gomega.HaveOccurred())
+		}
+	}
+}
+
+func TestValidateImageArchitecture(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+
+	x86Type := &ec2.InstanceTypeInfo{
+		InstanceType:  aws.String("m5.large"),
+		ProcessorInfo: &ec2.ProcessorInfo{SupportedArchitectures: aws.StringSlice([]string{"i386", "x86_64"})},
+	}
+	armType := &ec2.InstanceTypeInfo{
+		InstanceType:  aws.String("m6g.large"),
+		ProcessorInfo: &ec2.ProcessorInfo{SupportedArchitectures: aws.StringSlice([]string{"arm64"})},
+	}
+	x86Image := &ec2.Image{ImageId: aws.String("ami-123456789012"), Name: aws.String("amazon-eks-node-1.16-v20200618"), Architecture: aws.String("x86_64")}
+	armImage := &ec2.Image{ImageId: aws.String("ami-210987654321"), Name: aws.String("amazon-eks-arm64-node-1.16-v20200618"), Architecture: aws.String("arm64")}
+
+	tests := []struct {
+		instanceType *ec2.InstanceTypeInfo
+		image        *ec2.Image
+		expectErr    bool
+	}{
+		{instanceType: x86Type, image: x86Image, expectErr: false},
+		{instanceType: armType, image: armImage, expectErr: false},
+		{instanceType: x86Type, image: armImage, expectErr: true},
+		{instanceType: armType, image: x86Image, expectErr: true},
+		// undiscovered instance types or images are not validated
+		{instanceType: nil, image: armImage, expectErr: false},
+		{instanceType: armType, image: nil, expectErr: false},
+	}
+
+	for i, tc := range tests {
+		t.Logf("Test #%v - %+v", i, tc)
+		ctx.SetDiscoveredState(&DiscoveredState{
+			InstanceTypeInfo: tc.instanceType,
+			Image:            tc.image,
+		})
+		err := ctx.ValidateImage()
+		if tc.expectErr {
+			g.Expect(err).To(gomega.HaveOccurred())
+		} else {
+			g.Expect(err).NotTo(gomega.HaveOccurred())
+		}
+	}
+}
+
+func TestNeuronInstanceType(t *testing.T) {
+	var (
+		g          = gomega.NewGomegaWithT(t)
+		k          = MockKubernetesClientSet()
+		ig         = MockInstanceGroup()
+		asgMock    = NewAutoScalingMocker()
+		iamMock    = NewIamMocker()
+		eksMock    = NewEksMocker()
+		ec2Mock    = NewEc2Mocker()
+		neuronType = &ec2.InstanceTypeInfo{
+			InstanceType: aws.String("inf1.6xlarge"),
+			InferenceAcceleratorInfo: &ec2.InferenceAcceleratorInfo{
+				Accelerators: []*ec2.InferenceDeviceInfo{
+					{Name: aws.String("Inferentia"), Manufacturer: aws.String("AWS"), Count: aws.Int64(4)},
+				},
+			},
+		}
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+
+	state := &DiscoveredState{
+		Publisher: kubeprovider.EventPublisher{
+			Client: k.Kubernetes,
+		},
+		Cluster: &eks.Cluster{
+			Version: aws.String("1.16"),
+		},
+	}
+	ctx.SetDiscoveredState(state)
+
+	// instance types without accelerators have no node template tags
+	state.SetInstanceTypeInfo(&ec2.InstanceTypeInfo{InstanceType: aws.String("m5.large")})
+	g.Expect(ctx.GetNodeTemplateTags()).To(gomega.BeEmpty())
+
+	// trn1 is recognized by its family
+	g.Expect(IsNeuronInstanceType(&ec2.InstanceTypeInfo{InstanceType: aws.String("trn1.32xlarge")})).To(gomega.BeTrue())
+
+	state.SetInstanceTypeInfo(neuronType)
+	g.Expect(IsNeuronInstanceType(neuronType)).To(gomega.BeTrue())
+	g.Expect(GetAcceleratorResourceName(neuronType)).To(gomega.Equal(NeuronKey))
+	g.Expect(GetAcceleratorCount(neuronType)).To(gomega.Equal(4))
+	g.Expect(ctx.GetLabelList()).To(gomega.ContainElement("aws.amazon.com/neuron=true"))
+	g.Expect(ctx.GetTaintList()).To(gomega.Equal([]string{"aws.amazon.com/neuron=true:NoSchedule"}))
+	g.Expect(ctx.GetNodeTemplateTags()).To(gomega.ConsistOf(
+		map[string]string{"key": "k8s.io/cluster-autoscaler/node-template/resources/aws.amazon.com/neuron", "value": "4"},
+		map[string]string{"key": "k8s.io/cluster-autoscaler/node-template/label/aws.amazon.com/neuron", "value": "true"},
+		map[string]string{"key": "k8s.io/cluster-autoscaler/node-template/taint/aws.amazon.com/neuron", "value": "true:NoSchedule"},
+	))
+
+	// the standard image does not include the neuron driver
+	state.SetImage(&ec2.Image{ImageId: aws.String("ami-123456789012"), Name: aws.String("amazon-eks-node-1.16-v20200618")})
+	g.Expect(ctx.ValidateImage()).NotTo(gomega.Succeed())
+	state.SetImage(&ec2.Image{ImageId: aws.String("ami-210987654321"), Name: aws.String("amazon-eks-gpu-node-1.16-v20200618")})
+	g.Expect(ctx.ValidateImage()).To(gomega.Succeed())
+}
+
+func TestInstanceStorePolicy(t *testing.T) {
+	var (
+		g             = gomega.NewGomegaWithT(t)
+		k             = MockKubernetesClientSet()
+		ig            = MockInstanceGroup()
+		configuration = ig.GetEKSConfiguration()
+		asgMock       = NewAutoScalingMocker()
+		iamMock       = NewIamMocker()
+		eksMock       = NewEksMocker()
+		ec2Mock       = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+
+	state := &DiscoveredState{
+		InstanceTypeInfo: &ec2.InstanceTypeInfo{
+			InstanceType: aws.String("i3.4xlarge"),
+			InstanceStorageInfo: &ec2.InstanceStorageInfo{
+				Disks: []*ec2.DiskInfo{
+					{Count: aws.Int64(2), SizeInGB: aws.Int64(1900), Type: aws.String("ssd")},
+				},
+			},
+		},
+	}
+	ctx.SetDiscoveredState(state)
+
+	// instance store volumes are not used unless a policy is set
+	g.Expect(ctx.GetInstanceStoreVolumes()).To(gomega.Equal(0))
+	userData, _ := base64.StdEncoding.DecodeString(ctx.GetBasicUserData("some-cluster", "", UserDataPayload{}, nil))
+	g.Expect(string(userData)).NotTo(gomega.ContainSubstring("mdadm"))
+
+	configuration.SetInstanceStorePolicy(v1alpha1.InstanceStorePolicyRaid0)
+	g.Expect(ctx.GetInstanceStoreVolumes()).To(gomega.Equal(2))
+	userData, _ = base64.StdEncoding.DecodeString(ctx.GetBasicUserData("some-cluster", "", UserDataPayload{}, nil))
+	g.Expect(string(userData)).To(gomega.ContainSubstring("mdadm --create --force --verbose /dev/md0 --level=0"))
+	g.Expect(string(userData)).To(gomega.ContainSubstring("mount --bind /mnt/instance-store/$dir /var/lib/$dir"))
+}
+
+func TestGetVolumesRootDevice(t *testing.T) {
+	var (
+		g             = gomega.NewGomegaWithT(t)
+		k             = MockKubernetesClientSet()
+		ig            = MockInstanceGroup()
+		configuration = ig.GetEKSConfiguration()
+		asgMock       = NewAutoScalingMocker()
+		iamMock       = NewIamMocker()
+		eksMock       = NewEksMocker()
+		ec2Mock       = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+
+	rootVolume := v1alpha1.NodeVolume{Name: "/dev/xvda", Type: "gp2", Size: 50}
+	dataVolume := v1alpha1.NodeVolume{Name: "/dev/xvdb", Type: "gp2", Size: 100}
+
+	tests := []struct {
+		rootDeviceName string
+		volumes        []v1alpha1.NodeVolume
+		expectedNames  []string
+	}{
+		// root device matches a volume
+		{rootDeviceName: "/dev/xvda", volumes: []v1alpha1.NodeVolume{rootVolume, dataVolume}, expectedNames: []string{"/dev/xvda", "/dev/xvdb"}},
+		// root volume is renamed to the image's root device
+		{rootDeviceName: "/dev/sda1", volumes: []v1alpha1.NodeVolume{rootVolume, dataVolume}, expectedNames: []string{"/dev/sda1", "/dev/xvdb"}},
+		// data volumes are not renamed
+		{rootDeviceName: "/dev/sda1", volumes: []v1alpha1.NodeVolume{dataVolume}, expectedNames: []string{"/dev/xvdb"}},
+		// undiscovered image
+		{rootDeviceName: "", volumes: []v1alpha1.NodeVolume{rootVolume}, expectedNames: []string{"/dev/xvda"}},
+	}
+
+	for i, tc := range tests {
+		t.Logf("Test #%v - %+v", i, tc)
+		configuration.Volumes = tc.volumes
+		state := &DiscoveredState{}
+		if tc.rootDeviceName != "" {
+			state.SetImage(&ec2.Image{RootDeviceName: aws.String(tc.rootDeviceName)})
+		}
+		ctx.SetDiscoveredState(state)
+
+		var names []string
+		for _, v := range ctx.GetVolumes() {
+			names = append(names, v.Name)
+		}
+		g.Expect(names).To(gomega.Equal(tc.expectedNames))
+		// the spec is not modified
+		g.Expect(configuration.Volumes[0].Name).To(gomega.Equal(tc.volumes[0].Name))
+	}
+}
+
+func TestUserDataCompression(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+
+	// small user data is not compressed
+	userData, _ := base64.StdEncoding.DecodeString(ctx.GetBasicUserData("some-cluster", "", UserDataPayload{PreBootstrap: []string{"echo hello"}}, nil))
+	g.Expect(string(userData)).To(gomega.HavePrefix("#!/bin/bash"))
+
+	// large user data is gzip compressed, and renders the same every time
+	payload := UserDataPayload{PreBootstrap: []string{strings.Repeat("echo hello\n", 2000)}}
+	encoded := ctx.GetBasicUserData("some-cluster", "", payload, nil)
+	userData, _ = base64.StdEncoding.DecodeString(encoded)
+	g.Expect(userData[:2]).To(gomega.Equal([]byte{0x1f, 0x8b}))
+	g.Expect(len(userData)).To(gomega.BeNumerically("<", UserDataCompressionThreshold))
+	g.Expect(ctx.GetBasicUserData("some-cluster", "", payload, nil)).To(gomega.Equal(encoded))
+}
+
+func TestBootstrapBucket(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+		s3Mock  = NewS3Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	w.S3Client = s3Mock
+	ctx := MockContext(ig, k, w)
+
+	// random data does not compress below the maximum size
+	random := make([]byte, 30000)
+	rand.New(rand.NewSource(1)).Read(random)
+	payload := UserDataPayload{PreBootstrap: []string{base64.StdEncoding.EncodeToString(random)}}
+
+	// without a bootstrap bucket the user data is not offloaded
+	userData, _ := base64.StdEncoding.DecodeString(ctx.GetBasicUserData("some-cluster", "", payload, nil))
+	g.Expect(len(userData)).To(gomega.BeNumerically(">", awsprovider.UserDataMaxSize))
+	g.Expect(ctx.BootstrapObject).To(gomega.BeNil())
+	g.Expect(ctx.UploadBootstrapObject()).To(gomega.Succeed())
+	g.Expect(s3Mock.PutObjectCallCount).To(gomega.Equal(0))
+
+	// with a bootstrap bucket the user data is replaced with a stub which downloads the payload
+	ctx.BootstrapBucket = provisioners.BootstrapBucketConfiguration{Name: "some-bucket", Prefix: "instance-manager"}
+	userData, _ = base64.StdEncoding.DecodeString(ctx.GetBasicUserData("some-cluster", "", payload, nil))
+	object := ctx.BootstrapObject
+	g.Expect(object).NotTo(gomega.BeNil())
+	g.Expect(object.Key).To(gomega.Equal(fmt.Sprintf("instance-manager/%v/%v/%v/%v", ig.GetEKSConfiguration().GetClusterName(), ig.GetNamespace(), ig.GetName(), object.Hash)))
+	g.Expect(string(object.Data)).To(gomega.HavePrefix("#!/bin/bash"))
+	g.Expect(len(userData)).To(gomega.BeNumerically("<", awsprovider.UserDataMaxSize))
+	g.Expect(string(userData)).To(gomega.ContainSubstring(fmt.Sprintf("s3://some-bucket/%v", object.Key)))
+	g.Expect(string(userData)).To(gomega.ContainSubstring(object.Hash))
+
+	// the object is uploaded once
+	g.Expect(ctx.UploadBootstrapObject()).To(gomega.Succeed())
+	g.Expect(ctx.UploadBootstrapObject()).To(gomega.Succeed())
+	g.Expect(s3Mock.PutObjectCallCount).To(gomega.Equal(1))
+	g.Expect(s3Mock.Objects["some-bucket/"+object.Key]).To(gomega.Equal(object.Data))
+
+	// a different payload changes the stub
+	payload.PostBootstrap = []string{"echo done"}
+	changed, _ := base64.StdEncoding.DecodeString(ctx.GetBasicUserData("some-cluster", "", payload, nil))
+	g.Expect(changed).NotTo(gomega.Equal(userData))
+
+	// upload failures are returned
+	s3Mock.PutObjectErr = errors.New("some-error")
+	g.Expect(ctx.UploadBootstrapObject()).NotTo(gomega.Succeed())
+}
+
+func TestSecretReferences(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+	configuration := ig.GetEKSConfiguration()
+	configuration.UserData = []v1alpha1.UserDataStage{
+		{
+			Stage: v1alpha1.PreBootstrapStage,
+			Data:  `docker login -u {{ secret "registry" "username" }} -p {{ secret "registry" "password" }}`,
+		},
+	}
+
+	// a missing secret fails discovery
+	g.Expect(ctx.DiscoverSecrets()).NotTo(gomega.Succeed())
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "registry",
+			Namespace:       ig.GetNamespace(),
+			ResourceVersion: "1",
+		},
+		Data: map[string][]byte{
+			"username": []byte("some-user"),
+		},
+	}
+	secret, _ = k.Kubernetes.CoreV1().Secrets(ig.GetNamespace()).Create(secret)
+
+	// a missing key fails discovery
+	g.Expect(ctx.DiscoverSecrets()).NotTo(gomega.Succeed())
+
+	secret.Data["password"] = []byte("some-password")
+	secret, _ = k.Kubernetes.CoreV1().Secrets(ig.GetNamespace()).Update(secret)
+	g.Expect(ctx.DiscoverSecrets()).To(gomega.Succeed())
+
+	// references are resolved at render time and the resource version is rendered as a hash
+	payload := ctx.GetUserDataStages()
+	g.Expect(payload.PreBootstrap).To(gomega.Equal([]string{"docker login -u some-user -p some-password"}))
+	hash := ctx.GetSecretsHash()
+	g.Expect(hash).NotTo(gomega.BeEmpty())
+	userData, _ := base64.StdEncoding.DecodeString(ctx.GetBasicUserData("some-cluster", "", payload, nil))
+	g.Expect(string(userData)).To(gomega.HavePrefix(fmt.Sprintf("#!/bin/bash\n# secrets-hash: %v\n", hash)))
+
+	// a new resource version changes the hash
+	secret.ResourceVersion = "2"
+	k.Kubernetes.CoreV1().Secrets(ig.GetNamespace()).Update(secret)
+	g.Expect(ctx.DiscoverSecrets()).To(gomega.Succeed())
+	g.Expect(ctx.GetSecretsHash()).NotTo(gomega.Equal(hash))
+
+	// without references there is no hash
+	configuration.UserData = nil
+	g.Expect(ctx.DiscoverSecrets()).To(gomega.Succeed())
+	g.Expect(ctx.GetSecretsHash()).To(gomega.BeEmpty())
+	userData, _ = base64.StdEncoding.DecodeString(ctx.GetBasicUserData("some-cluster", "", UserDataPayload{}, nil))
+	g.Expect(string(userData)).NotTo(gomega.ContainSubstring("secrets-hash"))
+}
+
+func TestRotationCounter(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+
+	// without a rotate request the counter is not rendered
+	before := ctx.GetBasicUserData("some-cluster", "", UserDataPayload{}, nil)
+	userData, _ := base64.StdEncoding.DecodeString(before)
+	g.Expect(string(userData)).NotTo(gomega.ContainSubstring("# rotation:"))
+
+	// a rotate request changes the user data, which is detected as drift of the launch configuration
+	ig.SetAnnotations(map[string]string{v1alpha1.RotateAnnotationKey: "true"})
+	g.Expect(ig.HandleRotateRequest()).To(gomega.BeTrue())
+	after := ctx.GetBasicUserData("some-cluster", "", UserDataPayload{}, nil)
+	userData, _ = base64.StdEncoding.DecodeString(after)
+	g.Expect(string(userData)).To(gomega.HavePrefix("#!/bin/bash\n# rotation: 1\n"))
+	g.Expect(after).NotTo(gomega.Equal(before))
+}
+
+func TestBootstrapArgsClusterOverrides(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+	ctx.SetDiscoveredState(&DiscoveredState{
+		Publisher: kubeprovider.EventPublisher{
+			Client: k.Kubernetes,
+		},
+	})
+
+	args := ctx.GetBootstrapArgs()
+	g.Expect(args).NotTo(gomega.ContainSubstring("--apiserver-endpoint"))
+	g.Expect(args).NotTo(gomega.ContainSubstring("--b64-cluster-ca"))
+
+	configuration := ig.GetEKSConfiguration()
+	configuration.SetAPIServerEndpoint("https://ABCDEF.gr7.us-west-2.eks.amazonaws.com")
+	configuration.SetCertificateAuthority("LS0tLS1CRUdJTg==")
+	args = ctx.GetBootstrapArgs()
+	g.Expect(args).To(gomega.HaveSuffix(" --apiserver-endpoint https://ABCDEF.gr7.us-west-2.eks.amazonaws.com --b64-cluster-ca LS0tLS1CRUdJTg=="))
+}
+
+func TestValidateKubeletVersion(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+
+	tests := []struct {
+		clusterVersion string
+		imageName      string
+		imageTags      []*ec2.Tag
+		kubeletVersion string
+		shouldFail     bool
+	}{
+		{clusterVersion: "1.18", imageName: "amazon-eks-node-1.18-v20201211", kubeletVersion: "1.18", shouldFail: false},
+		{clusterVersion: "1.18", imageName: "amazon-eks-gpu-node-1.16-v20201211", kubeletVersion: "1.16", shouldFail: false},
+		{clusterVersion: "1.18", imageName: "amazon-eks-arm64-node-1.15-v20201211", kubeletVersion: "1.15", shouldFail: true},
+		{clusterVersion: "1.17", imageName: "bottlerocket-aws-k8s-1.18-x86_64-v1.0.5-a5a8a2a8", kubeletVersion: "1.18", shouldFail: true},
+		{clusterVersion: "1.18", imageName: "custom-image", imageTags: []*ec2.Tag{{Key: aws.String(ImageKubernetesVersionTagKey), Value: aws.String("1.19")}}, kubeletVersion: "1.19", shouldFail: true},
+		{clusterVersion: "1.18", imageName: "custom-image", kubeletVersion: "", shouldFail: false},
+		{clusterVersion: "", imageName: "amazon-eks-node-1.18-v20201211", kubeletVersion: "1.18", shouldFail: false},
+	}
+
+	for i, tc := range tests {
+		t.Logf("Test #%v - %+v", i, tc)
+		image := &ec2.Image{
+			ImageId: aws.String("ami-123456789012"),
+			Name:    aws.String(tc.imageName),
+			Tags:    tc.imageTags,
+		}
+		ctx.GetDiscoveredState().SetImage(image)
+		ctx.GetDiscoveredState().SetCluster(&eks.Cluster{Version: aws.String(tc.clusterVersion)})
+		g.Expect(GetImageKubernetesVersion(image)).To(gomega.Equal(tc.kubeletVersion))
+		if tc.shouldFail {
+			g.Expect(ctx.ValidateKubeletVersion()).NotTo(gomega.Succeed())
+			g.Expect(ctx.ValidateImage()).NotTo(gomega.Succeed())
+		} else {
+			g.Expect(ctx.ValidateKubeletVersion()).To(gomega.Succeed())
+		}
+	}
+}
+
+func TestResolvePipelineImage(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+		ibMock  = &MockImageBuilderClient{}
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	w.ImageBuilderClient = ibMock
+	w.Region = "us-west-2"
+	ctx := MockContext(ig, k, w)
+	ctx.GetDiscoveredState().Publisher.Client = k.Kubernetes
+	status := ig.GetStatus()
+	configuration := ig.GetEKSConfiguration()
+	configuration.SetImagePipelineArn("arn:aws:imagebuilder:us-west-2:123456789012:image-pipeline/eks-node")
+
+	// the image may be omitted, the pipeline must have built an image then
+	_, err := ctx.ResolvePipelineImage()
+	g.Expect(err).To(gomega.HaveOccurred())
+
+	// the configured image is used until the pipeline has built an image
+	configuration.Image = "ami-configured"
+	image, err := ctx.ResolvePipelineImage()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(image).To(gomega.Equal("ami-configured"))
+	g.Expect(status.GetImageVersion()).To(gomega.BeEmpty())
+
+	// the latest available image distributed to the region is used
+	ibMock.Images = []*imagebuilder.ImageSummary{
+		MockPipelineImage("1.0.0/1", imagebuilder.ImageStatusAvailable, "2020-06-01T10:00:00.000Z", map[string]string{"us-west-2": "ami-100", "us-east-1": "ami-100-east"}),
+		MockPipelineImage("1.0.0/2", imagebuilder.ImageStatusAvailable, "2020-06-02T10:00:00.000Z", map[string]string{"us-west-2": "ami-101"}),
+		MockPipelineImage("1.0.0/3", imagebuilder.ImageStatusBuilding, "2020-06-03T10:00:00.000Z", nil),
+		MockPipelineImage("1.0.0/4", imagebuilder.ImageStatusAvailable, "2020-06-04T10:00:00.000Z", map[string]string{"us-east-1": "ami-103-east"}),
+	}
+	image, err = ctx.ResolvePipelineImage()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(image).To(gomega.Equal("ami-101"))
+	g.Expect(status.GetResolvedImage()).To(gomega.Equal("ami-101"))
+	g.Expect(status.GetImageVersion()).To(gomega.Equal("1.0.0/2"))
+
+	// a newly built image is resolved on the next discovery
+	ibMock.Images = append(ibMock.Images, MockPipelineImage("1.0.1/1", imagebuilder.ImageStatusAvailable, "2020-06-05T10:00:00.000Z", map[string]string{"us-west-2": "ami-110"}))
+	image, err = ctx.ResolvePipelineImage()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(image).To(gomega.Equal("ami-110"))
+	g.Expect(status.GetImageVersion()).To(gomega.Equal("1.0.1/1"))
+}
+
+func TestRequireIMDSv2(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+
+	// nothing is required before the scaling group is provisioned
+	err := ctx.RequireIMDSv2()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(ec2Mock.RequiredIMDSv2Instances).To(gomega.BeEmpty())
+
+	mockInstance := func(id, httpTokens string) *ec2.Instance {
+		return &ec2.Instance{
+			InstanceId:      aws.String(id),
+			MetadataOptions: &ec2.InstanceMetadataOptionsResponse{HttpTokens: aws.String(httpTokens)},
+		}
+	}
+	scalingGroup := MockScalingGroup("asg-1")
+	scalingGroup.Instances = []*autoscaling.Instance{
+		{InstanceId: aws.String("i-1")},
+		{InstanceId: aws.String("i-2")},
+	}
+	ctx.GetDiscoveredState().SetScalingGroup(scalingGroup)
+	ec2Mock.Instances = []*ec2.Instance{
+		mockInstance("i-1", ec2.HttpTokensStateOptional),
+		mockInstance("i-2", ec2.HttpTokensStateRequired),
+	}
+
+	// only instances which allow IMDSv1 are changed
+	err = ctx.RequireIMDSv2()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(ec2Mock.RequiredIMDSv2Instances).To(gomega.Equal([]string{"i-1"}))
+}
+
+func TestUpdateNodeOwnership(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+
+	scalingGroup := MockScalingGroup("asg-1")
+	scalingGroup.Instances = MockScalingInstances(2, 0)
+	ec2Mock.Instances = []*ec2.Instance{
+		{InstanceId: aws.String("i-000000000"), InstanceLifecycle: aws.String("spot")},
+		{InstanceId: aws.String("i-000000001")},
+	}
+
+	// nodes of other scaling groups are not labeled
+	for _, id := range []string{"i-000000000", "i-000000001", "i-200000000"} {
+		_, err := k.Kubernetes.CoreV1().Nodes().Create(MockNode(id, corev1.ConditionTrue))
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+	}
+	nodes, err := k.Kubernetes.CoreV1().Nodes().List(metav1.ListOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	ctx.GetDiscoveredState().SetScalingGroup(scalingGroup)
+	ctx.GetDiscoveredState().SetClusterNodes(nodes)
+
+	err = ctx.UpdateNodeOwnership()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	expectedLifecycles := map[string]string{
+		"node-i-000000000": kubeprovider.NodeLifecycleSpot,
+		"node-i-000000001": kubeprovider.NodeLifecycleOnDemand,
+		"node-i-200000000": "",
+	}
+	for name, lifecycle := range expectedLifecycles {
+		node, err := k.Kubernetes.CoreV1().Nodes().Get(name, metav1.GetOptions{})
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(node.GetLabels()[kubeprovider.NodeLifecycleLabelKey]).To(gomega.Equal(lifecycle))
+		if lifecycle == "" {
+			g.Expect(node.GetLabels()).NotTo(gomega.HaveKey(kubeprovider.NodeGroupLabelKey))
+			continue
+		}
+		g.Expect(node.GetLabels()[kubeprovider.NodeGroupLabelKey]).To(gomega.Equal(ig.GetName()))
+		g.Expect(node.GetLabels()[kubeprovider.NodeNamespaceLabelKey]).To(gomega.Equal(ig.GetNamespace()))
+		g.Expect(node.GetAnnotations()[kubeprovider.NodeLaunchConfigurationAnnotationKey]).To(gomega.Equal("some-launch-config"))
+	}
+}
+
+func TestResolveSpotPrice(t *testing.T) {
+	var (
+		g           = gomega.NewGomegaWithT(t)
+		k           = MockKubernetesClientSet()
+		ig          = MockInstanceGroup()
+		asgMock     = NewAutoScalingMocker()
+		iamMock     = NewIamMocker()
+		eksMock     = NewEksMocker()
+		ec2Mock     = NewEc2Mocker()
+		pricingMock = &MockPricingClient{Prices: map[string]string{"m5.large": "0.0960000000"}}
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	w.PricingClient = pricingMock
+	w.Region = "us-west-2"
+	ctx := MockContext(ig, k, w)
+	status := ig.GetStatus()
+	configuration := ig.GetEKSConfiguration()
+	configuration.InstanceType = "m5.large"
+
+	// a spot price in dollars is not resolved
+	configuration.SetSpotPrice("0.05")
+	err := ctx.ResolveSpotPrice()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(configuration.GetSpotPrice()).To(gomega.Equal("0.05"))
+	g.Expect(status.GetResolvedSpotPrice()).To(gomega.BeEmpty())
+	g.Expect(pricingMock.CallCount).To(gomega.Equal(0))
+
+	// a percentage is resolved against the on-demand price
+	configuration.SetSpotPrice("80%")
+	err = ctx.ResolveSpotPrice()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(configuration.GetSpotPrice()).To(gomega.Equal("0.0768"))
+	g.Expect(status.GetResolvedSpotPrice()).To(gomega.Equal("0.0768"))
+
+	// the last resolved price is used while the on-demand price cannot be retrieved
+	pricingMock.GetErr = errors.New("throttled")
+	configuration.SetSpotPrice("80%")
+	err = ctx.ResolveSpotPrice()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(configuration.GetSpotPrice()).To(gomega.Equal("0.0768"))
+
+	// without a resolved price, resolution fails
+	status.SetResolvedSpotPrice("")
+	configuration.SetSpotPrice("80%")
+	err = ctx.ResolveSpotPrice()
+	g.Expect(err).To(gomega.HaveOccurred())
+
+	// instance types without an on-demand price fail resolution
+	pricingMock.GetErr = nil
+	configuration.InstanceType = "m5.xlarge"
+	err = ctx.ResolveSpotPrice()
+	g.Expect(err).To(gomega.HaveOccurred())
+}
+
+func TestResolveImage(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+		ssmMock = NewSsmMocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	w.SsmClient = ssmMock
+	ctx := MockContext(ig, k, w)
+	state := ctx.GetDiscoveredState()
+	state.Publisher.Client = k.Kubernetes
+	status := ig.GetStatus()
+
+	ssmMock.Parameters = map[string]string{
+		"/aws/service/eks/optimized-ami/1.17/amazon-linux-2/recommended/image_id":       "ami-117",
+		"/aws/service/eks/optimized-ami/1.18/amazon-linux-2/recommended/image_id":       "ami-118",
+		"/aws/service/eks/optimized-ami/1.18/amazon-linux-2-gpu/recommended/image_id":   "ami-118-gpu",
+		"/aws/service/eks/optimized-ami/1.18/amazon-linux-2-arm64/recommended/image_id": "ami-118-arm64",
+	}
+
+	// the cluster version is required
+	_, err := ctx.ResolveImage()
+	g.Expect(err).To(gomega.HaveOccurred())
+
+	state.SetCluster(&eks.Cluster{Version: aws.String("1.17")})
+	state.SetInstanceTypeInfo(&ec2.InstanceTypeInfo{
+		InstanceType:  aws.String("m5.large"),
+		ProcessorInfo: &ec2.ProcessorInfo{SupportedArchitectures: aws.StringSlice([]string{"i386", "x86_64"})},
+	})
+	image, err := ctx.ResolveImage()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(image).To(gomega.Equal("ami-117"))
+	g.Expect(status.GetResolvedImage()).To(gomega.Equal("ami-117"))
+
+	// the image is not resolved again until the cluster version changes
+	ssmMock.Parameters["/aws/service/eks/optimized-ami/1.17/amazon-linux-2/recommended/image_id"] = "ami-117-new"
+	image, _ = ctx.ResolveImage()
+	g.Expect(image).To(gomega.Equal("ami-117"))
+	g.Expect(ssmMock.GetParameterCallCount).To(gomega.Equal(1))
+
+	state.SetCluster(&eks.Cluster{Version: aws.String("1.18")})
+	image, _ = ctx.ResolveImage()
+	g.Expect(image).To(gomega.Equal("ami-118"))
+	g.Expect(status.GetImageParameter()).To(gomega.Equal("/aws/service/eks/optimized-ami/1.18/amazon-linux-2/recommended/image_id"))
+
+	// accelerated and arm64 instance types use their image variants
+	state.SetInstanceTypeInfo(&ec2.InstanceTypeInfo{
+		InstanceType: aws.String("p3.2xlarge"),
+		GpuInfo: &ec2.GpuInfo{
+			Gpus: []*ec2.GpuDeviceInfo{
+				{Name: aws.String("V100"), Manufacturer: aws.String("NVIDIA"), Count: aws.Int64(1)},
+			},
+		},
+	})
+	image, _ = ctx.ResolveImage()
+	g.Expect(image).To(gomega.Equal("ami-118-gpu"))
+
+	state.SetInstanceTypeInfo(&ec2.InstanceTypeInfo{
+		InstanceType:  aws.String("m6g.large"),
+		ProcessorInfo: &ec2.ProcessorInfo{SupportedArchitectures: aws.StringSlice([]string{"arm64"})},
+	})
+	image, _ = ctx.ResolveImage()
+	g.Expect(image).To(gomega.Equal("ami-118-arm64"))
+
+	// missing parameters fail
+	state.SetCluster(&eks.Cluster{Version: aws.String("1.19")})
+	_, err = ctx.ResolveImage()
+	g.Expect(err).To(gomega.HaveOccurred())
+	g.Expect(status.GetResolvedImage()).To(gomega.Equal("ami-118-arm64"))
+}
+
+func TestGetManagedPoliciesListPartition(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	w.Partition = "aws-us-gov"
+	ctx := MockContext(ig, k, w)
+
+	policies := ctx.GetManagedPoliciesList([]string{
+		"policy-1",
+		"arn:aws-us-gov:iam::aws:policy/policy-2",
+		"arn:aws-us-gov:iam::123456789012:policy/policy-3",
+	})
+	g.Expect(policies).To(gomega.ConsistOf(
+		"arn:aws-us-gov:iam::aws:policy/policy-1",
+		"arn:aws-us-gov:iam::aws:policy/policy-2",
+		"arn:aws-us-gov:iam::123456789012:policy/policy-3",
+		"arn:aws-us-gov:iam::aws:policy/AmazonEKSWorkerNodePolicy",
+		"arn:aws-us-gov:iam::aws:policy/AmazonEKS_CNI_Policy",
+		"arn:aws-us-gov:iam::aws:policy/AmazonEC2ContainerRegistryReadOnly",
+	))
+}
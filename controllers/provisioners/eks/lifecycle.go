@@ -0,0 +1,127 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eks
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/keikoproj/instance-manager/api/v1alpha1"
+	"github.com/keikoproj/instance-manager/controllers/common"
+	kubeprovider "github.com/keikoproj/instance-manager/controllers/providers/kubernetes"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	// InstanceLifecycleMetric is the number of spot and on-demand instances of the scaling group of an instance group
+	InstanceLifecycleMetric = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "instance_manager_instances",
+		Help: "The number of instances of the scaling group of an instance group by lifecycle, spot or on-demand",
+	}, []string{"namespace", "instancegroup", "lifecycle"})
+	// SpotPercentageMetric is the percentage of the instances of the scaling group of an instance group which are spot
+	// instances
+	SpotPercentageMetric = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "instance_manager_spot_instances_percent",
+		Help: "The percentage of the instances of the scaling group of an instance group which are spot instances",
+	}, []string{"namespace", "instancegroup"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(InstanceLifecycleMetric, SpotPercentageMetric)
+}
+
+// DiscoverLifecycleDistribution sets the split of the scaling group's instances between spot and on-demand instances
+// in the status and metrics, the lifecycle of an instance is taken from the lifecycle label of its node, and is only
+// described for instances whose nodes are not labeled yet
+func (ctx *EksInstanceGroupContext) DiscoverLifecycleDistribution() error {
+	var (
+		instanceGroup = ctx.GetInstanceGroup()
+		status        = instanceGroup.GetStatus()
+		state         = ctx.GetDiscoveredState()
+		scalingGroup  = state.GetScalingGroup()
+		nodes         = state.GetClusterNodes()
+		lifecycles    = make(map[string]string)
+		instanceIds   = make([]string, 0)
+		unknown       = make([]string, 0)
+	)
+
+	if !state.IsProvisioned() {
+		status.SetLifecycleDistribution(nil)
+		ctx.deleteLifecycleMetrics()
+		return nil
+	}
+
+	for _, instance := range scalingGroup.Instances {
+		// instances which are terminating no longer count towards the distribution
+		if strings.HasPrefix(aws.StringValue(instance.LifecycleState), "Terminat") {
+			continue
+		}
+		instanceIds = append(instanceIds, aws.StringValue(instance.InstanceId))
+	}
+
+	if nodes != nil {
+		for _, node := range nodes.Items {
+			instanceID := common.GetLastElementBy(node.Spec.ProviderID, "/")
+			if lifecycle := node.GetLabels()[kubeprovider.NodeLifecycleLabelKey]; lifecycle != "" {
+				lifecycles[instanceID] = lifecycle
+			}
+		}
+	}
+
+	for _, instanceID := range instanceIds {
+		if _, ok := lifecycles[instanceID]; !ok {
+			unknown = append(unknown, instanceID)
+		}
+	}
+
+	if len(unknown) > 0 {
+		described, err := ctx.AwsWorker.GetInstanceLifecycles(unknown)
+		if err != nil {
+			return errors.Wrap(err, "failed to describe instance lifecycles")
+		}
+		for instanceID, lifecycle := range described {
+			lifecycles[instanceID] = lifecycle
+		}
+	}
+
+	var spot, onDemand int
+	for _, instanceID := range instanceIds {
+		switch lifecycles[instanceID] {
+		case kubeprovider.NodeLifecycleSpot:
+			spot++
+		case kubeprovider.NodeLifecycleOnDemand:
+			onDemand++
+		}
+	}
+
+	distribution := v1alpha1.NewLifecycleDistribution(spot, onDemand)
+	status.SetLifecycleDistribution(distribution)
+
+	namespace, name := instanceGroup.GetNamespace(), instanceGroup.GetName()
+	InstanceLifecycleMetric.WithLabelValues(namespace, name, kubeprovider.NodeLifecycleSpot).Set(float64(spot))
+	InstanceLifecycleMetric.WithLabelValues(namespace, name, kubeprovider.NodeLifecycleOnDemand).Set(float64(onDemand))
+	SpotPercentageMetric.WithLabelValues(namespace, name).Set(float64(distribution.SpotPercentage))
+	return nil
+}
+
+func (ctx *EksInstanceGroupContext) deleteLifecycleMetrics() {
+	namespace, name := ctx.GetInstanceGroup().GetNamespace(), ctx.GetInstanceGroup().GetName()
+	InstanceLifecycleMetric.DeleteLabelValues(namespace, name, kubeprovider.NodeLifecycleSpot)
+	InstanceLifecycleMetric.DeleteLabelValues(namespace, name, kubeprovider.NodeLifecycleOnDemand)
+	SpotPercentageMetric.DeleteLabelValues(namespace, name)
+}
@@ -0,0 +1,90 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eks
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/keikoproj/instance-manager/api/v1alpha1"
+	kubeprovider "github.com/keikoproj/instance-manager/controllers/providers/kubernetes"
+	"github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDiscoverLifecycleDistribution(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+
+	scalingGroup := MockScalingGroup("asg-1")
+	scalingGroup.Instances = MockScalingInstances(4, 0)
+	// terminating instances are not counted
+	scalingGroup.Instances[3].LifecycleState = aws.String(autoscaling.LifecycleStateTerminating)
+
+	// the lifecycle of labeled nodes is not described
+	node := MockNode("i-000000000", corev1.ConditionTrue)
+	node.SetLabels(map[string]string{kubeprovider.NodeLifecycleLabelKey: kubeprovider.NodeLifecycleSpot})
+	_, err := k.Kubernetes.CoreV1().Nodes().Create(node)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	nodes, err := k.Kubernetes.CoreV1().Nodes().List(metav1.ListOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	ec2Mock.Instances = []*ec2.Instance{
+		{InstanceId: aws.String("i-000000001"), InstanceLifecycle: aws.String("spot")},
+		{InstanceId: aws.String("i-000000002")},
+	}
+	ctx.GetDiscoveredState().SetProvisioned(true)
+	ctx.GetDiscoveredState().SetScalingGroup(scalingGroup)
+	ctx.GetDiscoveredState().SetClusterNodes(nodes)
+
+	err = ctx.DiscoverLifecycleDistribution()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(ig.GetStatus().GetLifecycleDistribution()).To(gomega.Equal(&v1alpha1.LifecycleDistribution{
+		SpotInstances:     2,
+		OnDemandInstances: 1,
+		SpotPercentage:    66,
+	}))
+	g.Expect(testutil.ToFloat64(SpotPercentageMetric.WithLabelValues(ig.GetNamespace(), ig.GetName()))).To(gomega.Equal(float64(66)))
+	g.Expect(testutil.ToFloat64(InstanceLifecycleMetric.WithLabelValues(ig.GetNamespace(), ig.GetName(), kubeprovider.NodeLifecycleOnDemand))).To(gomega.Equal(float64(1)))
+
+	// the distribution is removed when the scaling group is deleted
+	ctx.GetDiscoveredState().SetProvisioned(false)
+	err = ctx.DiscoverLifecycleDistribution()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(ig.GetStatus().GetLifecycleDistribution()).To(gomega.BeNil())
+}
+
+func TestNewLifecycleDistribution(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	g.Expect(v1alpha1.NewLifecycleDistribution(0, 0).SpotPercentage).To(gomega.Equal(0))
+	g.Expect(v1alpha1.NewLifecycleDistribution(1, 3).SpotPercentage).To(gomega.Equal(25))
+	g.Expect(v1alpha1.NewLifecycleDistribution(3, 0).SpotPercentage).To(gomega.Equal(100))
+}
@@ -0,0 +1,50 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eks
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+)
+
+// ProviderIDs returns the provider IDs of the instances of the instance group's scaling groups which are not
+// terminating, the instances of scaling groups which are being retired are included until they are terminated
+func (ctx *EksInstanceGroupContext) ProviderIDs() []string {
+	var (
+		state       = ctx.GetDiscoveredState()
+		providerIDs = make([]string, 0)
+	)
+
+	if state == nil || !state.IsProvisioned() {
+		return providerIDs
+	}
+
+	groups := append([]*autoscaling.Group{state.GetScalingGroup()}, state.GetRetiringScalingGroups()...)
+	for _, group := range groups {
+		for _, instance := range group.Instances {
+			if strings.HasPrefix(aws.StringValue(instance.LifecycleState), autoscaling.LifecycleStateTerminating) {
+				continue
+			}
+			providerIDs = append(providerIDs, fmt.Sprintf("aws:///%v/%v", aws.StringValue(instance.AvailabilityZone), aws.StringValue(instance.InstanceId)))
+		}
+	}
+	sort.Strings(providerIDs)
+	return providerIDs
+}
@@ -0,0 +1,73 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eks
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/onsi/gomega"
+)
+
+func TestProviderIDs(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+
+	// an instance group without a scaling group has no instances
+	ctx.SetDiscoveredState(&DiscoveredState{Provisioned: false})
+	g.Expect(ctx.ProviderIDs()).To(gomega.BeEmpty())
+
+	newInstance := func(id, zone, state string) *autoscaling.Instance {
+		return &autoscaling.Instance{
+			InstanceId:       aws.String(id),
+			AvailabilityZone: aws.String(zone),
+			LifecycleState:   aws.String(state),
+		}
+	}
+
+	active := MockScalingGroup("some-scaling-group")
+	active.Instances = []*autoscaling.Instance{
+		newInstance("i-000000002", "us-west-2b", autoscaling.LifecycleStateInService),
+		newInstance("i-000000001", "us-west-2a", autoscaling.LifecycleStatePending),
+		newInstance("i-000000003", "us-west-2a", autoscaling.LifecycleStateTerminatingWait),
+	}
+	retiring := MockScalingGroup("retiring-scaling-group")
+	retiring.Instances = []*autoscaling.Instance{
+		newInstance("i-100000001", "us-west-2c", autoscaling.LifecycleStateInService),
+	}
+
+	state := &DiscoveredState{Provisioned: true}
+	state.SetScalingGroup(active)
+	state.SetRetiringScalingGroups([]*autoscaling.Group{retiring})
+	ctx.SetDiscoveredState(state)
+
+	g.Expect(ctx.ProviderIDs()).To(gomega.Equal([]string{
+		"aws:///us-west-2a/i-000000001",
+		"aws:///us-west-2b/i-000000002",
+		"aws:///us-west-2c/i-100000001",
+	}))
+}
@@ -0,0 +1,361 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eks
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/keikoproj/instance-manager/api/v1alpha1"
+	"github.com/keikoproj/instance-manager/controllers/common"
+	kubeprovider "github.com/keikoproj/instance-manager/controllers/providers/kubernetes"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	// UnhealthyNodeReplacementsMetric counts the instances which were replaced since their nodes were not ready
+	UnhealthyNodeReplacementsMetric = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "instance_manager_unhealthy_node_replacements_total",
+		Help: "The number of instances of an instance group which were replaced since their nodes were not ready",
+	}, []string{"namespace", "instancegroup"})
+	// BootstrapFailuresMetric counts the instances whose nodes did not join the cluster within the join timeout
+	BootstrapFailuresMetric = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "instance_manager_bootstrap_failures_total",
+		Help: "The number of instances of an instance group whose nodes did not join the cluster within the join timeout",
+	}, []string{"namespace", "instancegroup"})
+)
+
+// BootstrapFailureConsoleOutputLines is the number of lines of the console output of failed instances in the status
+const BootstrapFailureConsoleOutputLines = 20
+
+func init() {
+	metrics.Registry.MustRegister(UnhealthyNodeReplacementsMetric, BootstrapFailuresMetric)
+}
+
+// RemoveStaleNodes deletes the nodes of the instance group whose instances are no longer in its scaling groups and no
+// longer exist, such as terminated spot instances, only nodes which are labeled with the instance group's ownership
+// and are not ready are considered
+func (ctx *EksInstanceGroupContext) RemoveStaleNodes() error {
+	var (
+		instanceGroup = ctx.GetInstanceGroup()
+		state         = ctx.GetDiscoveredState()
+		scalingGroup  = state.GetScalingGroup()
+		nodes         = state.GetClusterNodes()
+		instanceIds   = make(map[string]bool)
+		candidates    = make(map[string]corev1.Node)
+		candidateIds  = make([]string, 0)
+	)
+
+	if scalingGroup == nil || nodes == nil {
+		return nil
+	}
+
+	// instances of replaced scaling groups which are still migrated are not stale
+	groups := []*autoscaling.Group{scalingGroup}
+	groups = append(groups, state.GetRetiringScalingGroups()...)
+	for _, group := range groups {
+		for _, instance := range group.Instances {
+			instanceIds[aws.StringValue(instance.InstanceId)] = true
+		}
+	}
+
+	for _, node := range nodes.Items {
+		var (
+			labels     = node.GetLabels()
+			instanceID = common.GetLastElementBy(node.Spec.ProviderID, "/")
+		)
+		if labels[kubeprovider.NodeGroupLabelKey] != instanceGroup.GetName() || labels[kubeprovider.NodeNamespaceLabelKey] != instanceGroup.GetNamespace() {
+			continue
+		}
+		if common.StringEmpty(instanceID) || instanceIds[instanceID] || kubeprovider.IsNodeReady(node) {
+			continue
+		}
+		candidates[instanceID] = node
+		candidateIds = append(candidateIds, instanceID)
+	}
+
+	if len(candidateIds) == 0 {
+		return nil
+	}
+
+	live, err := ctx.AwsWorker.GetLiveInstanceIds(candidateIds)
+	if err != nil {
+		return errors.Wrap(err, "failed to describe instances of stale nodes")
+	}
+
+	for _, instanceID := range candidateIds {
+		if common.ContainsString(live, instanceID) {
+			continue
+		}
+		node := candidates[instanceID]
+		err := ctx.ClusterKubernetesClient.Kubernetes.CoreV1().Nodes().Delete(node.GetName(), &metav1.DeleteOptions{})
+		if err != nil && !kerrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to delete stale node %v", node.GetName())
+		}
+		ctx.Log.Info("removed stale node", "instancegroup", instanceGroup.GetName(), "node", node.GetName(), "instance", instanceID)
+		state.Publisher.Publish(kubeprovider.StaleNodeRemovedEvent, "instancegroup", instanceGroup.GetName(), "node", node.GetName(), "instance", instanceID)
+	}
+	return nil
+}
+
+// ReplaceUnhealthyNodes replaces the instances of nodes which are not ready for longer than the threshold, and which
+// the scaling group considers healthy and in service. Nodes are drained before their instances are terminated, unless
+// they are still not drained after the drain timeout
+func (ctx *EksInstanceGroupContext) ReplaceUnhealthyNodes() error {
+	var (
+		instanceGroup = ctx.GetInstanceGroup()
+		configuration = instanceGroup.GetEKSConfiguration()
+		status        = instanceGroup.GetStatus()
+		state         = ctx.GetDiscoveredState()
+		scalingGroup  = state.GetScalingGroup()
+		nodes         = state.GetClusterNodes()
+		replacement   = configuration.GetUnhealthyNodeReplacement()
+		instances     = make(map[string]*autoscaling.Instance)
+		expired       = make(map[string]bool)
+		candidates    = make([]string, 0)
+	)
+
+	if replacement == nil || scalingGroup == nil || nodes == nil {
+		status.SetUnhealthyInstances(nil)
+		return nil
+	}
+
+	var (
+		threshold    = replacement.GetNotReadyThreshold()
+		drainTimeout = time.Duration(instanceGroup.GetUpgradeStrategy().GetDrain().GetTimeoutSeconds()) * time.Second
+	)
+
+	for _, instance := range scalingGroup.Instances {
+		instances[aws.StringValue(instance.InstanceId)] = instance
+	}
+
+	for _, node := range nodes.Items {
+		instanceID := common.GetLastElementBy(node.Spec.ProviderID, "/")
+		instance, ok := instances[instanceID]
+		if !ok {
+			continue
+		}
+		since, notReady := notReadySince(node)
+		if !notReady || time.Since(since) < threshold {
+			continue
+		}
+		// instances which the scaling group already replaces are left to it
+		if aws.StringValue(instance.LifecycleState) != autoscaling.LifecycleStateInService || aws.StringValue(instance.HealthStatus) != "Healthy" {
+			continue
+		}
+		candidates = append(candidates, instanceID)
+		// the drain timeout is measured from the start of the drain, which is recorded on the node once it is cordoned
+		if started, err := time.Parse(time.RFC3339, node.GetAnnotations()[kubeprovider.DrainStartedAnnotationKey]); err == nil {
+			expired[instanceID] = time.Since(started) > drainTimeout
+		}
+	}
+
+	// instances which are already being replaced are replaced first
+	replacing := status.GetUnhealthyInstances()
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return common.ContainsString(replacing, candidates[i]) && !common.ContainsString(replacing, candidates[j])
+	})
+
+	if len(candidates) > 0 {
+		live, err := ctx.AwsWorker.GetLiveInstanceIds(candidates)
+		if err != nil {
+			return errors.Wrap(err, "failed to describe instances of unhealthy nodes")
+		}
+		healthy := make([]string, 0)
+		for _, instanceID := range candidates {
+			if common.ContainsString(live, instanceID) {
+				healthy = append(healthy, instanceID)
+			}
+		}
+		candidates = healthy
+	}
+
+	if max := replacement.GetMaxReplacements(); len(candidates) > max {
+		candidates = candidates[:max]
+	}
+
+	if len(candidates) == 0 {
+		status.SetUnhealthyInstances(nil)
+		return nil
+	}
+	status.SetUnhealthyInstances(candidates)
+
+	drained, err := ctx.DrainNodes(candidates, false)
+	if err != nil {
+		return errors.Wrap(err, "failed to drain unhealthy nodes")
+	}
+
+	terminate := make([]string, 0)
+	for _, instanceID := range candidates {
+		if drained || expired[instanceID] {
+			terminate = append(terminate, instanceID)
+		}
+	}
+	if len(terminate) == 0 {
+		ctx.Log.Info("waiting for unhealthy nodes to drain", "instancegroup", instanceGroup.GetName(), "instances", candidates)
+		return nil
+	}
+
+	if err := ctx.AwsWorker.TerminateScalingInstances(terminate); err != nil {
+		return errors.Wrap(err, "failed to terminate instances of unhealthy nodes")
+	}
+
+	status.IncrementUnhealthyNodeReplacements(len(terminate))
+	UnhealthyNodeReplacementsMetric.WithLabelValues(instanceGroup.GetNamespace(), instanceGroup.GetName()).Add(float64(len(terminate)))
+	ctx.Log.Info("replaced instances of unhealthy nodes", "instancegroup", instanceGroup.GetName(), "instances", terminate)
+	state.Publisher.Publish(kubeprovider.UnhealthyNodesReplacedEvent, "instancegroup", instanceGroup.GetName(), "instances", strings.Join(terminate, ","))
+
+	remaining := make([]string, 0)
+	for _, instanceID := range candidates {
+		if !common.ContainsString(terminate, instanceID) {
+			remaining = append(remaining, instanceID)
+		}
+	}
+	if len(remaining) == 0 {
+		remaining = nil
+	}
+	status.SetUnhealthyInstances(remaining)
+	return nil
+}
+
+// DetectBootstrapFailures reports the in service instances of the scaling group which were launched longer than the
+// join timeout ago, but whose nodes did not join the cluster. Failed instances are terminated when enabled, and the
+// scaling group launches their replacements
+func (ctx *EksInstanceGroupContext) DetectBootstrapFailures() error {
+	var (
+		instanceGroup = ctx.GetInstanceGroup()
+		configuration = instanceGroup.GetEKSConfiguration()
+		status        = instanceGroup.GetStatus()
+		state         = ctx.GetDiscoveredState()
+		scalingGroup  = state.GetScalingGroup()
+		nodes         = state.GetClusterNodes()
+		detection     = configuration.GetBootstrapFailureDetection()
+		joined        = make(map[string]bool)
+		previous      = make(map[string]v1alpha1.BootstrapFailure)
+		candidates    = make([]string, 0)
+	)
+
+	if detection == nil || scalingGroup == nil || nodes == nil {
+		status.SetBootstrapFailures(nil)
+		return nil
+	}
+
+	for _, node := range nodes.Items {
+		joined[common.GetLastElementBy(node.Spec.ProviderID, "/")] = true
+	}
+
+	for _, instance := range scalingGroup.Instances {
+		instanceID := aws.StringValue(instance.InstanceId)
+		// instances are in service once their launch lifecycle hooks completed
+		if aws.StringValue(instance.LifecycleState) != autoscaling.LifecycleStateInService || joined[instanceID] {
+			continue
+		}
+		candidates = append(candidates, instanceID)
+	}
+
+	if len(candidates) == 0 {
+		status.SetBootstrapFailures(nil)
+		return nil
+	}
+
+	launchTimes, err := ctx.AwsWorker.GetInstanceLaunchTimes(candidates)
+	if err != nil {
+		return errors.Wrap(err, "failed to describe instances whose nodes did not join")
+	}
+
+	for _, failure := range status.GetBootstrapFailures() {
+		previous[failure.InstanceID] = failure
+	}
+
+	var (
+		timeout  = detection.GetJoinTimeout()
+		failures = make([]v1alpha1.BootstrapFailure, 0)
+		detected = make([]string, 0)
+	)
+	for _, instanceID := range candidates {
+		launchTime, ok := launchTimes[instanceID]
+		if !ok || time.Since(launchTime) < timeout {
+			continue
+		}
+
+		failure, ok := previous[instanceID]
+		if !ok {
+			failure = v1alpha1.BootstrapFailure{
+				InstanceID: instanceID,
+				LaunchTime: metav1.NewTime(launchTime),
+			}
+			detected = append(detected, instanceID)
+		}
+
+		// the console output is only retrieved once, it is not updated after the instance failed
+		if detection.IsConsoleOutputEnabled() && common.StringEmpty(failure.ConsoleOutput) {
+			output, err := ctx.AwsWorker.GetConsoleOutputTail(instanceID, BootstrapFailureConsoleOutputLines)
+			if err != nil {
+				ctx.Log.Info("failed to get console output", "error", err, "instancegroup", instanceGroup.GetName(), "instance", instanceID)
+			}
+			failure.ConsoleOutput = output
+		}
+		failures = append(failures, failure)
+	}
+
+	if len(failures) == 0 {
+		status.SetBootstrapFailures(nil)
+		return nil
+	}
+	status.SetBootstrapFailures(failures)
+
+	if len(detected) > 0 {
+		BootstrapFailuresMetric.WithLabelValues(instanceGroup.GetNamespace(), instanceGroup.GetName()).Add(float64(len(detected)))
+		ctx.Log.Info("detected instances whose nodes did not join", "instancegroup", instanceGroup.GetName(), "instances", detected, "timeout", timeout)
+		state.Publisher.Publish(kubeprovider.BootstrapFailedEvent, "instancegroup", instanceGroup.GetName(), "instances", strings.Join(detected, ","), "timeout", timeout.String())
+	}
+
+	if !detection.IsTerminateFailedInstances() {
+		return nil
+	}
+
+	terminate := make([]string, 0)
+	for _, failure := range failures {
+		terminate = append(terminate, failure.InstanceID)
+	}
+	if err := ctx.AwsWorker.TerminateScalingInstances(terminate); err != nil {
+		return errors.Wrap(err, "failed to terminate instances whose nodes did not join")
+	}
+	ctx.Log.Info("terminated instances whose nodes did not join", "instancegroup", instanceGroup.GetName(), "instances", terminate)
+	return nil
+}
+
+// notReadySince returns the time a node became not ready, and false if it is ready
+func notReadySince(node corev1.Node) (time.Time, bool) {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type != corev1.NodeReady {
+			continue
+		}
+		if condition.Status == corev1.ConditionTrue {
+			return time.Time{}, false
+		}
+		return condition.LastTransitionTime.Time, true
+	}
+	return node.GetCreationTimestamp().Time, true
+}
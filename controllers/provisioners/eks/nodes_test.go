@@ -0,0 +1,314 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eks
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/keikoproj/instance-manager/api/v1alpha1"
+	kubeprovider "github.com/keikoproj/instance-manager/controllers/providers/kubernetes"
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRemoveStaleNodes(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+
+	scalingGroup := MockScalingGroup("asg-1")
+	scalingGroup.Instances = MockScalingInstances(1, 0)
+
+	// i-300000000 was terminated, i-300000001 is running but was detached from the scaling group
+	ec2Mock.Instances = []*ec2.Instance{
+		{InstanceId: aws.String("i-000000000")},
+		{InstanceId: aws.String("i-300000001")},
+	}
+
+	owned := kubeprovider.NodeOwnership{Group: ig.GetName(), Namespace: ig.GetNamespace()}
+	mockNode := func(id string, status corev1.ConditionStatus, o kubeprovider.NodeOwnership) {
+		node := MockNode(id, status)
+		node.SetLabels(o.Labels())
+		_, err := k.Kubernetes.CoreV1().Nodes().Create(node)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+	}
+	mockNode("i-000000000", corev1.ConditionFalse, owned)
+	mockNode("i-300000000", corev1.ConditionFalse, owned)
+	mockNode("i-300000001", corev1.ConditionFalse, owned)
+	mockNode("i-300000002", corev1.ConditionTrue, owned)
+	mockNode("i-300000003", corev1.ConditionFalse, kubeprovider.NodeOwnership{Group: "other", Namespace: ig.GetNamespace()})
+
+	nodes, err := k.Kubernetes.CoreV1().Nodes().List(metav1.ListOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	ctx.SetDiscoveredState(&DiscoveredState{
+		Publisher: kubeprovider.EventPublisher{
+			Client: k.Kubernetes,
+		},
+		ScalingGroup: scalingGroup,
+		ClusterNodes: nodes,
+	})
+
+	err = ctx.RemoveStaleNodes()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	nodes, err = k.Kubernetes.CoreV1().Nodes().List(metav1.ListOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	names := make([]string, 0)
+	for _, node := range nodes.Items {
+		names = append(names, node.GetName())
+	}
+	g.Expect(names).To(gomega.ConsistOf("node-i-000000000", "node-i-300000001", "node-i-300000002", "node-i-300000003"))
+}
+
+func TestReplaceUnhealthyNodes(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+
+	mockInstance := func(id, lifecycleState string) *autoscaling.Instance {
+		return &autoscaling.Instance{
+			InstanceId:     aws.String(id),
+			LifecycleState: aws.String(lifecycleState),
+			HealthStatus:   aws.String("Healthy"),
+		}
+	}
+	scalingGroup := MockScalingGroup("asg-1")
+	scalingGroup.Instances = []*autoscaling.Instance{
+		mockInstance("i-1", autoscaling.LifecycleStateInService),
+		mockInstance("i-2", autoscaling.LifecycleStateInService),
+		mockInstance("i-3", autoscaling.LifecycleStateInService),
+		mockInstance("i-4", autoscaling.LifecycleStateTerminating),
+		mockInstance("i-5", autoscaling.LifecycleStateInService),
+	}
+	ec2Mock.Instances = []*ec2.Instance{
+		{InstanceId: aws.String("i-1")},
+		{InstanceId: aws.String("i-2")},
+	}
+
+	mockNode := func(id string, status corev1.ConditionStatus, since time.Duration) {
+		node := MockNode(id, status)
+		node.Status.Conditions[0].LastTransitionTime = metav1.NewTime(time.Now().Add(-since))
+		_, err := k.Kubernetes.CoreV1().Nodes().Create(node)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+	}
+	// i-1 and i-2 are unhealthy, i-3 is below the threshold, i-4 is terminated by the scaling group and i-5 is ready
+	mockNode("i-1", corev1.ConditionFalse, 30*time.Minute)
+	mockNode("i-2", corev1.ConditionUnknown, 20*time.Minute)
+	mockNode("i-3", corev1.ConditionFalse, 5*time.Minute)
+	mockNode("i-4", corev1.ConditionFalse, 30*time.Minute)
+	mockNode("i-5", corev1.ConditionTrue, 30*time.Minute)
+
+	nodes, err := k.Kubernetes.CoreV1().Nodes().List(metav1.ListOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	ctx.SetDiscoveredState(&DiscoveredState{
+		Publisher: kubeprovider.EventPublisher{
+			Client: k.Kubernetes,
+		},
+		ScalingGroup: scalingGroup,
+		ClusterNodes: nodes,
+	})
+
+	// nothing is replaced without a replacement policy
+	err = ctx.ReplaceUnhealthyNodes()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(asgMock.TerminateInstanceCallCount).To(gomega.Equal(0))
+
+	// one instance is replaced at a time
+	ig.GetEKSConfiguration().UnhealthyNodeReplacement = &v1alpha1.UnhealthyNodeReplacementSpec{
+		NotReadyThreshold: "10m",
+	}
+	err = ctx.ReplaceUnhealthyNodes()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(asgMock.TerminateInstanceCallCount).To(gomega.Equal(1))
+	g.Expect(ig.GetStatus().GetUnhealthyNodeReplacements()).To(gomega.Equal(1))
+	g.Expect(ig.GetStatus().GetUnhealthyInstances()).To(gomega.BeNil())
+
+	ig.GetEKSConfiguration().UnhealthyNodeReplacement.MaxReplacements = 3
+	err = ctx.ReplaceUnhealthyNodes()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(asgMock.TerminateInstanceCallCount).To(gomega.Equal(3))
+	g.Expect(ig.GetStatus().GetUnhealthyNodeReplacements()).To(gomega.Equal(3))
+}
+
+func TestReplaceUnhealthyNodesDrainTimeout(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+	ig.GetEKSConfiguration().UnhealthyNodeReplacement = &v1alpha1.UnhealthyNodeReplacementSpec{
+		NotReadyThreshold: "10m",
+	}
+
+	scalingGroup := MockScalingGroup("asg-1")
+	scalingGroup.Instances = []*autoscaling.Instance{
+		{InstanceId: aws.String("i-1"), LifecycleState: aws.String(autoscaling.LifecycleStateInService), HealthStatus: aws.String("Healthy")},
+	}
+	ec2Mock.Instances = []*ec2.Instance{
+		{InstanceId: aws.String("i-1")},
+	}
+
+	// the node has been not ready for longer than the threshold and the drain timeout, but its pod is still terminating
+	node := MockNode("i-1", corev1.ConditionFalse)
+	node.Status.Conditions[0].LastTransitionTime = metav1.NewTime(time.Now().Add(-time.Hour))
+	_, err := k.Kubernetes.CoreV1().Nodes().Create(node)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "pod-1",
+			Namespace:         "default",
+			DeletionTimestamp: &metav1.Time{Time: time.Now()},
+			OwnerReferences:   []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "replicaset-1"}},
+		},
+		Spec: corev1.PodSpec{NodeName: node.GetName()},
+	}
+	_, err = k.Kubernetes.CoreV1().Pods("default").Create(pod)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	discover := func() {
+		nodes, err := k.Kubernetes.CoreV1().Nodes().List(metav1.ListOptions{})
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		ctx.SetDiscoveredState(&DiscoveredState{
+			Publisher: kubeprovider.EventPublisher{
+				Client: k.Kubernetes,
+			},
+			ScalingGroup: scalingGroup,
+			ClusterNodes: nodes,
+		})
+	}
+
+	// the drain has just started, the instance is not terminated
+	discover()
+	err = ctx.ReplaceUnhealthyNodes()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(asgMock.TerminateInstanceCallCount).To(gomega.Equal(0))
+	g.Expect(ig.GetStatus().GetUnhealthyInstances()).To(gomega.ConsistOf("i-1"))
+
+	discover()
+	err = ctx.ReplaceUnhealthyNodes()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(asgMock.TerminateInstanceCallCount).To(gomega.Equal(0))
+
+	// the instance is terminated once the drain did not complete within the drain timeout
+	err = kubeprovider.PatchNode(k.Kubernetes, node.GetName(), map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{
+				kubeprovider.DrainStartedAnnotationKey: time.Now().Add(-time.Hour).UTC().Format(time.RFC3339),
+			},
+		},
+	})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	discover()
+	err = ctx.ReplaceUnhealthyNodes()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(asgMock.TerminateInstanceCallCount).To(gomega.Equal(1))
+	g.Expect(ig.GetStatus().GetUnhealthyInstances()).To(gomega.BeNil())
+}
+
+func TestDetectBootstrapFailures(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+
+	scalingGroup := MockScalingGroup("asg-1")
+	scalingGroup.Instances = []*autoscaling.Instance{
+		{InstanceId: aws.String("i-1"), LifecycleState: aws.String(autoscaling.LifecycleStateInService)},
+		{InstanceId: aws.String("i-2"), LifecycleState: aws.String(autoscaling.LifecycleStateInService)},
+		{InstanceId: aws.String("i-3"), LifecycleState: aws.String(autoscaling.LifecycleStateInService)},
+		{InstanceId: aws.String("i-4"), LifecycleState: aws.String(autoscaling.LifecycleStatePendingWait)},
+	}
+	// i-1 joined, i-2 did not join within the timeout, i-3 was launched recently and i-4 is not in service
+	ec2Mock.Instances = []*ec2.Instance{
+		{InstanceId: aws.String("i-1"), LaunchTime: aws.Time(time.Now().Add(-time.Hour))},
+		{InstanceId: aws.String("i-2"), LaunchTime: aws.Time(time.Now().Add(-time.Hour))},
+		{InstanceId: aws.String("i-3"), LaunchTime: aws.Time(time.Now().Add(-time.Minute))},
+		{InstanceId: aws.String("i-4"), LaunchTime: aws.Time(time.Now().Add(-time.Hour))},
+	}
+	ec2Mock.ConsoleOutput = "cloud-init starting\nbootstrap.sh: failed to reach the API server\n"
+
+	_, err := k.Kubernetes.CoreV1().Nodes().Create(MockNode("i-1", corev1.ConditionTrue))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	nodes, err := k.Kubernetes.CoreV1().Nodes().List(metav1.ListOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	ctx.SetDiscoveredState(&DiscoveredState{
+		Publisher: kubeprovider.EventPublisher{
+			Client: k.Kubernetes,
+		},
+		ScalingGroup: scalingGroup,
+		ClusterNodes: nodes,
+	})
+
+	// nothing is detected without bootstrap failure detection
+	err = ctx.DetectBootstrapFailures()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(ig.GetStatus().GetBootstrapFailures()).To(gomega.BeEmpty())
+
+	ig.GetEKSConfiguration().BootstrapFailureDetection = &v1alpha1.BootstrapFailureDetectionSpec{
+		ConsoleOutput: true,
+	}
+	err = ctx.DetectBootstrapFailures()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	failures := ig.GetStatus().GetBootstrapFailures()
+	g.Expect(failures).To(gomega.HaveLen(1))
+	g.Expect(failures[0].InstanceID).To(gomega.Equal("i-2"))
+	g.Expect(failures[0].ConsoleOutput).To(gomega.Equal("cloud-init starting\nbootstrap.sh: failed to reach the API server"))
+	g.Expect(asgMock.TerminateInstanceCallCount).To(gomega.Equal(0))
+
+	ig.GetEKSConfiguration().BootstrapFailureDetection.TerminateFailedInstances = true
+	err = ctx.DetectBootstrapFailures()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(ig.GetStatus().GetBootstrapFailures()).To(gomega.HaveLen(1))
+	g.Expect(asgMock.TerminateInstanceCallCount).To(gomega.Equal(1))
+}
@@ -0,0 +1,212 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eks
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/keikoproj/instance-manager/api/v1alpha1"
+)
+
+const (
+	PlanActionCreate  = "Create"
+	PlanActionUpdate  = "Update"
+	PlanActionDelete  = "Delete"
+	PlanActionRotate  = "Rotate"
+	PlanActionDrain   = "Drain"
+	PlanActionMigrate = "Migrate"
+
+	PlanResourceNodes = "Nodes"
+)
+
+// Plan returns the changes to cloud resources which a reconcile of the discovered state would make, without
+// making them
+func (ctx *EksInstanceGroupContext) Plan() []v1alpha1.PlannedChange {
+	var (
+		instanceGroup = ctx.GetInstanceGroup()
+		configuration = instanceGroup.GetEKSConfiguration()
+		spec          = instanceGroup.GetEKSSpec()
+		state         = ctx.GetDiscoveredState()
+		scalingGroup  = state.GetScalingGroup()
+		scalingConfig = state.GetScalingConfiguration()
+		plan          = make([]v1alpha1.PlannedChange, 0)
+	)
+
+	switch instanceGroup.GetState() {
+	case v1alpha1.ReconcileInitCreate:
+		plan = append(plan,
+			v1alpha1.PlannedChange{
+				Resource: PendingChangeLaunchConfiguration,
+				Action:   PlanActionCreate,
+				Detail:   fmt.Sprintf("image: %v, instanceType: %v", configuration.Image, configuration.InstanceType),
+			},
+			v1alpha1.PlannedChange{
+				Resource: PendingChangeScalingGroup,
+				Action:   PlanActionCreate,
+				Detail:   fmt.Sprintf("minSize: %v, maxSize: %v", spec.GetMinSize(), spec.GetMaxSize()),
+			},
+		)
+		return append(plan, ctx.migrationChanges()...)
+	case v1alpha1.ReconcileInitDelete:
+		if len(scalingGroup.Instances) > 0 {
+			plan = append(plan, v1alpha1.PlannedChange{
+				Resource: PlanResourceNodes,
+				Action:   PlanActionDrain,
+				Detail:   fmt.Sprintf("%v nodes", len(scalingGroup.Instances)),
+			})
+		}
+		for _, name := range state.GetRetiringScalingGroupNames() {
+			plan = append(plan, v1alpha1.PlannedChange{Resource: PendingChangeScalingGroup, Action: PlanActionDelete, Detail: name})
+		}
+		if !state.HasScalingGroup() {
+			return plan
+		}
+		return append(plan,
+			v1alpha1.PlannedChange{Resource: PendingChangeScalingGroup, Action: PlanActionDelete, Detail: aws.StringValue(scalingGroup.AutoScalingGroupName)},
+			v1alpha1.PlannedChange{Resource: PendingChangeLaunchConfiguration, Action: PlanActionDelete, Detail: scalingConfig.Name()},
+		)
+	}
+
+	if instanceGroup.GetState() != v1alpha1.ReconcileInitUpdate {
+		return plan
+	}
+
+	var (
+		asgName    = aws.StringValue(scalingGroup.AutoScalingGroupName)
+		configName = scalingConfig.Name()
+		drifted    = scalingConfig.Drifted(ctx.GetDesiredConfiguration())
+	)
+	if drifted {
+		change := v1alpha1.PlannedChange{
+			Resource: PendingChangeLaunchConfiguration,
+			Action:   PlanActionCreate,
+		}
+		if configName != "" {
+			change.Detail = fmt.Sprintf("replaces %v", configName)
+		}
+		plan = append(plan, change)
+		configName = fmt.Sprintf("%v-<timestamp>", ctx.ResourcePrefix)
+	}
+
+	if changes := ctx.ScalingGroupChanges(configName); len(changes) > 0 {
+		plan = append(plan, v1alpha1.PlannedChange{
+			Resource: PendingChangeScalingGroup,
+			Action:   PlanActionUpdate,
+			Detail:   strings.Join(changes, ", "),
+		})
+	}
+
+	if ctx.TagsUpdateNeeded() {
+		plan = append(plan, v1alpha1.PlannedChange{
+			Resource: PendingChangeTags,
+			Action:   PlanActionUpdate,
+			Detail:   ctx.tagChanges(asgName),
+		})
+	}
+
+	if protect, unprotect := ctx.ScaleInProtectionUpdateNeeded(); len(protect)+len(unprotect) > 0 {
+		plan = append(plan, v1alpha1.PlannedChange{
+			Resource: PendingChangeScaleInProtection,
+			Action:   PlanActionUpdate,
+			Detail:   fmt.Sprintf("protect: %v, unprotect: %v", len(protect), len(unprotect)),
+		})
+	}
+
+	if rotate := ctx.outdatedInstances(drifted); rotate > 0 {
+		plan = append(plan, v1alpha1.PlannedChange{
+			Resource: PlanResourceNodes,
+			Action:   PlanActionRotate,
+			Detail:   fmt.Sprintf("%v nodes", rotate),
+		})
+	}
+
+	return append(plan, ctx.migrationChanges()...)
+}
+
+// migrationChanges describes the migration of nodes from the scaling groups which are replaced, and their deletion
+func (ctx *EksInstanceGroupContext) migrationChanges() []v1alpha1.PlannedChange {
+	var (
+		state   = ctx.GetDiscoveredState()
+		changes = make([]v1alpha1.PlannedChange, 0)
+	)
+
+	for _, group := range state.GetRetiringScalingGroups() {
+		name := aws.StringValue(group.AutoScalingGroupName)
+		if len(group.Instances) > 0 {
+			changes = append(changes, v1alpha1.PlannedChange{
+				Resource: PlanResourceNodes,
+				Action:   PlanActionMigrate,
+				Detail:   fmt.Sprintf("%v nodes from %v", len(group.Instances), name),
+			})
+		}
+		changes = append(changes, v1alpha1.PlannedChange{
+			Resource: PendingChangeScalingGroup,
+			Action:   PlanActionDelete,
+			Detail:   name,
+		})
+	}
+	return changes
+}
+
+// tagChanges describes the keys of the tags which would be added or changed, and removed from the scaling group
+func (ctx *EksInstanceGroupContext) tagChanges(asgName string) string {
+	var (
+		scalingGroup = ctx.GetDiscoveredState().GetScalingGroup()
+		existing     = make(map[string]string)
+		added        = make([]string, 0)
+		removed      = make([]string, 0)
+	)
+
+	for _, tag := range scalingGroup.Tags {
+		existing[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+
+	for _, tag := range ctx.GetAddedTags(asgName) {
+		if value, ok := existing[aws.StringValue(tag.Key)]; !ok || value != aws.StringValue(tag.Value) {
+			added = append(added, aws.StringValue(tag.Key))
+		}
+	}
+
+	for _, tag := range ctx.GetRemovedTags(asgName) {
+		removed = append(removed, aws.StringValue(tag.Key))
+	}
+
+	return fmt.Sprintf("set: [%v], remove: [%v]", strings.Join(added, ", "), strings.Join(removed, ", "))
+}
+
+// outdatedInstances returns the number of instances which would be rotated, all instances are rotated when a new
+// launch configuration is created
+func (ctx *EksInstanceGroupContext) outdatedInstances(drifted bool) int {
+	var (
+		state         = ctx.GetDiscoveredState()
+		scalingGroup  = state.GetScalingGroup()
+		scalingConfig = state.GetScalingConfiguration()
+	)
+
+	if drifted {
+		return len(scalingGroup.Instances)
+	}
+
+	var count int
+	for _, instance := range scalingGroup.Instances {
+		if aws.StringValue(instance.LaunchConfigurationName) != scalingConfig.Name() {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,110 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eks
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/keikoproj/instance-manager/api/v1alpha1"
+	kubeprovider "github.com/keikoproj/instance-manager/controllers/providers/kubernetes"
+	"github.com/keikoproj/instance-manager/controllers/provisioners/eks/scaling"
+	"github.com/onsi/gomega"
+)
+
+func TestPlan(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		spec    = ig.GetEKSSpec()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+	spec.MinSize = int64(3)
+	spec.MaxSize = int64(6)
+	ig.GetEKSConfiguration().SetSubnets([]string{"subnet-1", "subnet-2", "subnet-3"})
+	ig.GetEKSConfiguration().Image = "ami-123456789012"
+	ig.GetEKSConfiguration().InstanceType = "m5.large"
+
+	scalingGroup := MockScalingGroup("asg-1", MockTagDescription("console-tag", "some-value"))
+	scalingGroup.MaxSize = aws.Int64(10)
+	scalingGroup.Instances = []*autoscaling.Instance{
+		{InstanceId: aws.String("i-1111"), LaunchConfigurationName: aws.String("some-launch-configuration")},
+		{InstanceId: aws.String("i-2222"), LaunchConfigurationName: aws.String("some-launch-configuration")},
+	}
+
+	tests := []struct {
+		state    v1alpha1.ReconcileState
+		expected []v1alpha1.PlannedChange
+	}{
+		{
+			state: v1alpha1.ReconcileInitCreate,
+			expected: []v1alpha1.PlannedChange{
+				{Resource: PendingChangeLaunchConfiguration, Action: PlanActionCreate, Detail: "image: ami-123456789012, instanceType: m5.large"},
+				{Resource: PendingChangeScalingGroup, Action: PlanActionCreate, Detail: "minSize: 3, maxSize: 6"},
+			},
+		},
+		{
+			state: v1alpha1.ReconcileInitDelete,
+			expected: []v1alpha1.PlannedChange{
+				{Resource: PlanResourceNodes, Action: PlanActionDrain, Detail: "2 nodes"},
+				{Resource: PendingChangeScalingGroup, Action: PlanActionDelete, Detail: "asg-1"},
+				{Resource: PendingChangeLaunchConfiguration, Action: PlanActionDelete},
+			},
+		},
+		{
+			state: v1alpha1.ReconcileInitUpdate,
+			expected: []v1alpha1.PlannedChange{
+				{Resource: PendingChangeLaunchConfiguration, Action: PlanActionCreate},
+				{Resource: PendingChangeScalingGroup, Action: PlanActionUpdate, Detail: "launchConfigurationName: some-launch-configuration -> " + ctx.ResourcePrefix + "-<timestamp>, maxSize: 10 -> 6"},
+				{Resource: PendingChangeTags, Action: PlanActionUpdate, Detail: "set: [Name, KubernetesCluster, instancegroups.keikoproj.io/ClusterName, instancegroups.keikoproj.io/Namespace, instancegroups.keikoproj.io/InstanceGroup], remove: [console-tag]"},
+				{Resource: PlanResourceNodes, Action: PlanActionRotate, Detail: "2 nodes"},
+			},
+		},
+		{
+			state:    v1alpha1.ReconcileDeleting,
+			expected: []v1alpha1.PlannedChange{},
+		},
+	}
+
+	for i, tc := range tests {
+		t.Logf("Test #%v - %+v", i, tc.state)
+		ig.SetState(tc.state)
+		ctx.SetDiscoveredState(&DiscoveredState{
+			Publisher: kubeprovider.EventPublisher{
+				Client: k.Kubernetes,
+			},
+			ScalingGroup: scalingGroup,
+			ScalingConfiguration: &scaling.LaunchConfiguration{
+				AwsWorker: w,
+			},
+			InstanceProfile: &iam.InstanceProfile{},
+			Cluster: &eks.Cluster{
+				Version: aws.String("1.15"),
+			},
+		})
+		g.Expect(ctx.Plan()).To(gomega.Equal(tc.expected))
+	}
+}
@@ -0,0 +1,85 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eks
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/keikoproj/instance-manager/api/v1alpha1"
+	"github.com/keikoproj/instance-manager/controllers/common"
+	kubeprovider "github.com/keikoproj/instance-manager/controllers/providers/kubernetes"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// DiscoverProtectedInstances sets the instances of the scaling group which are protected from scale-in in the status,
+// with the reason of their protection, so that scale-downs which do not terminate instances can be explained
+func (ctx *EksInstanceGroupContext) DiscoverProtectedInstances() {
+	var (
+		instanceGroup = ctx.GetInstanceGroup()
+		configuration = instanceGroup.GetEKSConfiguration()
+		status        = instanceGroup.GetStatus()
+		state         = ctx.GetDiscoveredState()
+		scalingGroup  = state.GetScalingGroup()
+		nodes         = state.GetClusterNodes()
+		instanceNodes = make(map[string]corev1.Node)
+		protected     []v1alpha1.ProtectedInstance
+	)
+
+	if !state.IsProvisioned() {
+		status.SetProtectedInstances(nil)
+		return
+	}
+
+	if nodes != nil {
+		for _, node := range nodes.Items {
+			instanceID := common.GetLastElementBy(node.Spec.ProviderID, "/")
+			instanceNodes[instanceID] = node
+		}
+	}
+
+	for _, instance := range scalingGroup.Instances {
+		// instances which are terminating are no longer scaled in
+		if !aws.BoolValue(instance.ProtectedFromScaleIn) || strings.HasPrefix(aws.StringValue(instance.LifecycleState), "Terminat") {
+			continue
+		}
+
+		var (
+			instanceID = aws.StringValue(instance.InstanceId)
+			node, ok   = instanceNodes[instanceID]
+			protection = v1alpha1.ProtectedInstance{InstanceID: instanceID}
+		)
+		if ok {
+			protection.Node = node.GetName()
+		}
+
+		switch {
+		case ok && kubeprovider.IsScaleInProtected(node):
+			protection.Reason = v1alpha1.ProtectionReasonNodeAnnotation
+		case configuration.IsNewInstancesProtectedFromScaleIn():
+			protection.Reason = v1alpha1.ProtectionReasonNewInstancesProtected
+		default:
+			protection.Reason = v1alpha1.ProtectionReasonExternal
+		}
+		protected = append(protected, protection)
+	}
+
+	sort.Slice(protected, func(i, j int) bool {
+		return protected[i].InstanceID < protected[j].InstanceID
+	})
+	status.SetProtectedInstances(protected)
+}
@@ -0,0 +1,97 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eks
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/keikoproj/instance-manager/api/v1alpha1"
+	kubeprovider "github.com/keikoproj/instance-manager/controllers/providers/kubernetes"
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDiscoverProtectedInstances(t *testing.T) {
+	var (
+		g             = gomega.NewGomegaWithT(t)
+		k             = MockKubernetesClientSet()
+		ig            = MockInstanceGroup()
+		configuration = ig.GetEKSConfiguration()
+		status        = ig.GetStatus()
+		asgMock       = NewAutoScalingMocker()
+		iamMock       = NewIamMocker()
+		eksMock       = NewEksMocker()
+		ec2Mock       = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+	state := ctx.GetDiscoveredState()
+
+	scalingGroup := MockScalingGroup("asg-1")
+	scalingGroup.Instances = MockScalingInstances(5, 0)
+	for _, instance := range scalingGroup.Instances {
+		instance.ProtectedFromScaleIn = aws.Bool(true)
+	}
+	// unprotected and terminating instances are not listed
+	scalingGroup.Instances[3].ProtectedFromScaleIn = aws.Bool(false)
+	scalingGroup.Instances[4].LifecycleState = aws.String(autoscaling.LifecycleStateTerminatingWait)
+
+	node := MockNode("i-000000000", corev1.ConditionTrue)
+	node.SetAnnotations(map[string]string{kubeprovider.ScaleInProtectionAnnotationKey: "true"})
+	_, err := k.Kubernetes.CoreV1().Nodes().Create(node)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	_, err = k.Kubernetes.CoreV1().Nodes().Create(MockNode("i-000000001", corev1.ConditionTrue))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	nodes, err := k.Kubernetes.CoreV1().Nodes().List(metav1.ListOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	state.SetProvisioned(true)
+	state.SetScalingGroup(scalingGroup)
+	state.SetClusterNodes(nodes)
+
+	// instances whose nodes are not annotated were protected outside of the controller
+	ctx.DiscoverProtectedInstances()
+	g.Expect(status.GetProtectedInstances()).To(gomega.Equal([]v1alpha1.ProtectedInstance{
+		{InstanceID: "i-000000000", Node: "node-i-000000000", Reason: v1alpha1.ProtectionReasonNodeAnnotation},
+		{InstanceID: "i-000000001", Node: "node-i-000000001", Reason: v1alpha1.ProtectionReasonExternal},
+		{InstanceID: "i-000000002", Reason: v1alpha1.ProtectionReasonExternal},
+	}))
+
+	// instances whose nodes are not annotated are protected by the scaling group
+	configuration.NewInstancesProtectedFromScaleIn = true
+	ctx.DiscoverProtectedInstances()
+	g.Expect(status.GetProtectedInstances()).To(gomega.Equal([]v1alpha1.ProtectedInstance{
+		{InstanceID: "i-000000000", Node: "node-i-000000000", Reason: v1alpha1.ProtectionReasonNodeAnnotation},
+		{InstanceID: "i-000000001", Node: "node-i-000000001", Reason: v1alpha1.ProtectionReasonNewInstancesProtected},
+		{InstanceID: "i-000000002", Reason: v1alpha1.ProtectionReasonNewInstancesProtected},
+	}))
+
+	// the list is removed when no instance is protected, or the scaling group is deleted
+	for _, instance := range scalingGroup.Instances {
+		instance.ProtectedFromScaleIn = aws.Bool(false)
+	}
+	ctx.DiscoverProtectedInstances()
+	g.Expect(status.GetProtectedInstances()).To(gomega.BeNil())
+
+	scalingGroup.Instances[0].ProtectedFromScaleIn = aws.Bool(true)
+	state.SetProvisioned(false)
+	ctx.DiscoverProtectedInstances()
+	g.Expect(status.GetProtectedInstances()).To(gomega.BeNil())
+}
@@ -0,0 +1,190 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eks
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/keikoproj/instance-manager/controllers/common"
+	awsprovider "github.com/keikoproj/instance-manager/controllers/providers/aws"
+	kubeprovider "github.com/keikoproj/instance-manager/controllers/providers/kubernetes"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// MigrateScalingGroups moves the nodes of the scaling groups which are replaced to the scaling group of the instance
+// group, in batches of the rolling update's maxUnavailable. A batch is migrated once the nodes of the scaling group are
+// ready, its nodes are drained and their instances terminated, and unless the desired capacity is managed, the desired
+// capacity of the scaling group is raised by the size of the batch. A replaced scaling group is deleted once it has no
+// instances, true is returned once all of them are deleted.
+func (ctx *EksInstanceGroupContext) MigrateScalingGroups() (bool, error) {
+	var (
+		instanceGroup = ctx.GetInstanceGroup()
+		state         = ctx.GetDiscoveredState()
+		scalingGroup  = state.GetScalingGroup()
+		asgName       = aws.StringValue(scalingGroup.AutoScalingGroupName)
+		retiring      = state.GetRetiringScalingGroups()
+	)
+
+	if len(retiring) == 0 {
+		return true, nil
+	}
+
+	// replaced scaling groups are migrated one at a time
+	group := retiring[0]
+	retiringName := aws.StringValue(group.AutoScalingGroupName)
+
+	if len(group.Instances) == 0 {
+		if err := ctx.DeleteRetiringScalingGroup(group); err != nil {
+			return false, err
+		}
+		return len(retiring) == 1, nil
+	}
+
+	// the replaced scaling group must not replace the instances which are migrated
+	if aws.Int64Value(group.MinSize) > 0 {
+		if err := ctx.AwsWorker.UpdateScalingGroup(&autoscaling.UpdateAutoScalingGroupInput{
+			AutoScalingGroupName: aws.String(retiringName),
+			MinSize:              aws.Int64(0),
+		}); err != nil {
+			return false, errors.Wrapf(err, "failed to update scaling group %v", retiringName)
+		}
+	}
+
+	if !state.IsNodesReady() {
+		ctx.Log.Info("waiting for nodes to be ready before migrating the next batch", "instancegroup", instanceGroup.GetName(), "scalinggroup", asgName, "replacedscalinggroup", retiringName)
+		return false, nil
+	}
+
+	batch := ctx.MigrationBatch(group)
+	drained, err := ctx.DrainNodes(batch, false)
+	if err != nil {
+		return false, err
+	}
+	if !drained {
+		ctx.Log.Info("waiting for migrated nodes to drain", "instancegroup", instanceGroup.GetName(), "replacedscalinggroup", retiringName, "instances", batch)
+		return false, nil
+	}
+
+	if !ctx.IsDesiredCapacityManaged() {
+		var (
+			desired = aws.Int64Value(scalingGroup.DesiredCapacity) + int64(len(batch))
+			max     = aws.Int64Value(scalingGroup.MaxSize)
+		)
+		if desired > max {
+			desired = max
+		}
+		if err := ctx.AwsWorker.UpdateScalingGroup(&autoscaling.UpdateAutoScalingGroupInput{
+			AutoScalingGroupName: aws.String(asgName),
+			DesiredCapacity:      aws.Int64(desired),
+		}); err != nil {
+			return false, errors.Wrapf(err, "failed to update scaling group %v", asgName)
+		}
+	}
+
+	if err := ctx.AwsWorker.TerminateScalingInstancesWithDecrement(batch); err != nil {
+		return false, errors.Wrapf(err, "failed to terminate instances of scaling group %v", retiringName)
+	}
+	ctx.Log.Info("migrated instances from replaced scaling group", "instancegroup", instanceGroup.GetName(), "scalinggroup", asgName, "replacedscalinggroup", retiringName, "instances", batch)
+	return false, nil
+}
+
+// MigrationBatch returns the instances of a replaced scaling group which are migrated next, instances are ordered by
+// their ID so that the batch does not change while its nodes are drained
+func (ctx *EksInstanceGroupContext) MigrationBatch(group *autoscaling.Group) []string {
+	var (
+		strategy       = ctx.GetInstanceGroup().GetUpgradeStrategy().GetRollingUpdateType()
+		maxUnavailable = strategy.GetMaxUnavailable()
+		instanceIds    = make([]string, 0)
+	)
+
+	for _, instance := range group.Instances {
+		instanceIds = append(instanceIds, aws.StringValue(instance.InstanceId))
+	}
+	sort.Strings(instanceIds)
+
+	var size int
+	if maxUnavailable.Type == intstr.String {
+		size, _ = intstr.GetValueFromIntOrPercent(maxUnavailable, len(instanceIds), true)
+	} else {
+		size = maxUnavailable.IntValue()
+	}
+
+	if size < 1 {
+		size = 1
+	}
+	if size > len(instanceIds) {
+		size = len(instanceIds)
+	}
+	return instanceIds[:size]
+}
+
+// DeleteRetiringScalingGroup deletes a replaced scaling group and its launch configurations, which are detached from
+// it first when the instance group has a launch configuration, otherwise launch configurations which are still in use
+// are left behind
+func (ctx *EksInstanceGroupContext) DeleteRetiringScalingGroup(group *autoscaling.Group) error {
+	var (
+		instanceGroup = ctx.GetInstanceGroup()
+		state         = ctx.GetDiscoveredState()
+		name          = aws.StringValue(group.AutoScalingGroupName)
+		activeConfig  = aws.StringValue(state.GetScalingGroup().LaunchConfigurationName)
+	)
+
+	if activeConfig != "" && aws.StringValue(group.LaunchConfigurationName) != activeConfig {
+		if err := ctx.AwsWorker.UpdateScalingGroup(&autoscaling.UpdateAutoScalingGroupInput{
+			AutoScalingGroupName:    aws.String(name),
+			LaunchConfigurationName: aws.String(activeConfig),
+		}); err != nil {
+			return errors.Wrapf(err, "failed to update scaling group %v", name)
+		}
+	}
+
+	configs, err := ctx.AwsWorker.DescribeAutoscalingLaunchConfigs()
+	if err != nil {
+		return errors.Wrap(err, "failed to describe launch configurations")
+	}
+
+	// launch configurations are prefixed by the name of their scaling group, the resource name which replaces it can
+	// share the prefix
+	for _, config := range configs {
+		configName := aws.StringValue(config.LaunchConfigurationName)
+		if !strings.HasPrefix(configName, name+"-") || strings.HasPrefix(configName, ctx.ResourcePrefix+"-") || configName == activeConfig {
+			continue
+		}
+		if err := ctx.AwsWorker.DeleteLaunchConfig(configName); err != nil {
+			if awsErr, ok := err.(awserr.Error); ok && common.ContainsEqualFoldSubstring(awsErr.Message(), awsprovider.LaunchConfigurationNotFoundErrorMessage) {
+				continue
+			}
+			if activeConfig != "" {
+				return errors.Wrapf(err, "failed to delete launch configuration %v", configName)
+			}
+			ctx.Log.Info("failed to delete launch configuration of replaced scaling group", "instancegroup", instanceGroup.GetName(), "launchconfig", configName, "error", err)
+			continue
+		}
+		ctx.Log.Info("deleted launch configuration of replaced scaling group", "instancegroup", instanceGroup.GetName(), "launchconfig", configName)
+	}
+
+	if err := ctx.AwsWorker.DeleteScalingGroup(name); err != nil {
+		return errors.Wrapf(err, "failed to delete scaling group %v", name)
+	}
+	ctx.Log.Info("deleted replaced scaling group", "instancegroup", instanceGroup.GetName(), "scalinggroup", name)
+	state.Publisher.Publish(kubeprovider.ScalingGroupRetiredEvent, "instancegroup", instanceGroup.GetName(), "scalinggroup", name)
+	return nil
+}
@@ -0,0 +1,144 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eks
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/keikoproj/instance-manager/api/v1alpha1"
+	kubeprovider "github.com/keikoproj/instance-manager/controllers/providers/kubernetes"
+	"github.com/keikoproj/instance-manager/controllers/provisioners"
+	"github.com/keikoproj/instance-manager/controllers/provisioners/eks/scaling"
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestFindReplacedScalingGroups(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+
+	var (
+		nameTag      = MockTagDescription(provisioners.TagInstanceGroupName, ig.GetName())
+		namespaceTag = MockTagDescription(provisioners.TagInstanceGroupNamespace, ig.GetNamespace())
+		otherTag     = MockTagDescription(provisioners.TagInstanceGroupName, "other-instance-group")
+		target       = MockScalingGroup(ctx.ResourcePrefix, nameTag, namespaceTag)
+		replaced     = MockScalingGroup("some-scaling-group", nameTag, namespaceTag)
+		deleting     = MockScalingGroup("deleted-scaling-group", nameTag, namespaceTag)
+		other        = MockScalingGroup("other-scaling-group", otherTag, namespaceTag)
+	)
+	deleting.Status = aws.String(ScalingGroupDeletionStatus)
+
+	group, retiring := ctx.findReplacedScalingGroups([]*autoscaling.Group{replaced, other, deleting, target})
+	g.Expect(group).To(gomega.Equal(target))
+	g.Expect(retiring).To(gomega.Equal([]*autoscaling.Group{replaced}))
+
+	// before the replacement is created, the instance group has no scaling group
+	group, retiring = ctx.findReplacedScalingGroups([]*autoscaling.Group{replaced, other})
+	g.Expect(group).To(gomega.BeNil())
+	g.Expect(retiring).To(gomega.Equal([]*autoscaling.Group{replaced}))
+}
+
+func TestMigrateScalingGroups(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+	maxUnavailable := intstr.FromInt(2)
+	ig.SetUpgradeStrategy(MockAwsRollingUpdateStrategy(&maxUnavailable))
+	ig.GetEKSSpec().SetReplacementPolicy(v1alpha1.RecreateReplacementPolicy)
+
+	scalingGroup := MockScalingGroup(ctx.ResourcePrefix)
+	scalingGroup.DesiredCapacity = aws.Int64(1)
+	scalingGroup.Instances = MockScalingInstances(1, 0)
+
+	retiring := MockScalingGroup("some-scaling-group")
+	retiring.Instances = MockScalingInstances(0, 3)
+	retiring.LaunchConfigurationName = aws.String("some-scaling-group-1592434151")
+
+	state := &DiscoveredState{
+		Publisher: kubeprovider.EventPublisher{
+			Client: k.Kubernetes,
+		},
+		ScalingGroup: scalingGroup,
+		ScalingConfiguration: &scaling.LaunchConfiguration{
+			AwsWorker: w,
+		},
+		ClusterNodes:          &corev1.NodeList{},
+		RetiringScalingGroups: []*autoscaling.Group{retiring},
+	}
+	ctx.SetDiscoveredState(state)
+
+	// nodes are not migrated until the nodes of the scaling group are ready
+	migrated, err := ctx.MigrateScalingGroups()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(migrated).To(gomega.BeFalse())
+	g.Expect(asgMock.TerminateInstanceCallCount).To(gomega.Equal(0))
+	g.Expect(asgMock.UpdateAutoScalingGroupInput.MinSize).To(gomega.Equal(aws.Int64(0)))
+
+	// a batch of maxUnavailable instances is migrated and the desired capacity raised by the same number
+	state.SetNodesReady(true)
+	g.Expect(ctx.MigrationBatch(retiring)).To(gomega.Equal([]string{"i-100000000", "i-100000001"}))
+	migrated, err = ctx.MigrateScalingGroups()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(migrated).To(gomega.BeFalse())
+	g.Expect(asgMock.TerminateInstanceCallCount).To(gomega.Equal(2))
+	g.Expect(aws.StringValue(asgMock.UpdateAutoScalingGroupInput.AutoScalingGroupName)).To(gomega.Equal(ctx.ResourcePrefix))
+	g.Expect(asgMock.UpdateAutoScalingGroupInput.DesiredCapacity).To(gomega.Equal(aws.Int64(3)))
+
+	// a managed desired capacity is not raised
+	ig.GetEKSSpec().SetDesiredCapacityPolicy(v1alpha1.ManagedDesiredCapacityPolicy)
+	retiring.MinSize = aws.Int64(0)
+	asgMock.UpdateAutoScalingGroupInput = nil
+	migrated, err = ctx.MigrateScalingGroups()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(migrated).To(gomega.BeFalse())
+	g.Expect(asgMock.TerminateInstanceCallCount).To(gomega.Equal(4))
+	g.Expect(asgMock.UpdateAutoScalingGroupInput).To(gomega.BeNil())
+
+	// an empty scaling group is deleted with its launch configurations
+	retiring.Instances = nil
+	asgMock.LaunchConfigurations = []*autoscaling.LaunchConfiguration{
+		{LaunchConfigurationName: aws.String("some-scaling-group-1592434151")},
+		{LaunchConfigurationName: aws.String(ctx.ResourcePrefix + "-1592434152")},
+	}
+	migrated, err = ctx.MigrateScalingGroups()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(migrated).To(gomega.BeTrue())
+	g.Expect(aws.StringValue(asgMock.UpdateAutoScalingGroupInput.LaunchConfigurationName)).To(gomega.Equal("some-launch-configuration"))
+	g.Expect(asgMock.DeleteLaunchConfigurationCallCount).To(gomega.Equal(1))
+	g.Expect(asgMock.DeleteAutoScalingGroupCallCount).To(gomega.Equal(1))
+}
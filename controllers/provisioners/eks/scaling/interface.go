@@ -56,4 +56,7 @@ type CreateConfigurationInput struct {
 	Volumes               []v1alpha1.NodeVolume
 	UserData              string
 	SpotPrice             string
+	InstanceStoreVolumes  int
+	// SensitiveUserData is true when the user data includes secret values, it is not exported
+	SensitiveUserData bool
 }
@@ -16,7 +16,7 @@ limitations under the License.
 package scaling
 
 import (
-	"reflect"
+	"fmt"
 	"sort"
 	"strings"
 
@@ -26,6 +26,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/go-logr/logr"
 	awsprovider "github.com/keikoproj/instance-manager/controllers/providers/aws"
 	"github.com/pkg/errors"
 )
@@ -35,6 +36,8 @@ type LaunchConfiguration struct {
 	OwnerName      string
 	TargetResource *autoscaling.LaunchConfiguration
 	ResourceList   []*autoscaling.LaunchConfiguration
+	// Log is the logger of the owner's reconcile, the package logger is used when it is nil
+	Log logr.Logger
 }
 
 var (
@@ -74,7 +77,7 @@ func (lc *LaunchConfiguration) Discover(input *DiscoverConfigurationInput) error
 }
 
 func (lc *LaunchConfiguration) Create(input *CreateConfigurationInput) error {
-	devices := lc.blockDeviceList(input.Volumes)
+	devices := lc.blockDeviceList(input.Volumes, input.InstanceStoreVolumes)
 	opts := &autoscaling.CreateLaunchConfigurationInput{
 		LaunchConfigurationName: aws.String(input.Name),
 		IamInstanceProfile:      aws.String(input.IamInstanceProfileArn),
@@ -121,12 +124,12 @@ func (lc *LaunchConfiguration) Delete(input *DeleteConfigurationInput) error {
 			continue
 		}
 
-		log.Info("deleting launch configuration", "instancegroup", lc.OwnerName, "name", name)
+		lc.logger().Info("deleting launch configuration", "instancegroup", lc.OwnerName, "name", name)
 
 		if err := lc.DeleteLaunchConfig(name); err != nil {
 			if awsErr, ok := err.(awserr.Error); ok {
 				if common.ContainsEqualFoldSubstring(awsErr.Message(), awsprovider.LaunchConfigurationNotFoundErrorMessage) {
-					log.Info("launch configuration not found", "instancegroup", lc.OwnerName, "name", name)
+					lc.logger().Info("launch configuration not found", "instancegroup", lc.OwnerName, "name", name)
 					continue
 				}
 			}
@@ -144,12 +147,12 @@ func (lc *LaunchConfiguration) Drifted(input *CreateConfigurationInput) bool {
 	)
 
 	if existingConfig == nil {
-		log.Info("detected drift", "reason", "launchconfig does not exist", "instancegroup", lc.OwnerName)
+		lc.logger().Info("detected drift", "reason", "launchconfig does not exist", "instancegroup", lc.OwnerName)
 		return true
 	}
 
 	if aws.StringValue(existingConfig.ImageId) != input.ImageId {
-		log.Info("detected drift", "reason", "image-id has changed", "instancegroup", lc.OwnerName,
+		lc.logger().Info("detected drift", "reason", "image-id has changed", "instancegroup", lc.OwnerName,
 			"previousValue", aws.StringValue(existingConfig.ImageId),
 			"newValue", input.ImageId,
 		)
@@ -157,7 +160,7 @@ func (lc *LaunchConfiguration) Drifted(input *CreateConfigurationInput) bool {
 	}
 
 	if aws.StringValue(existingConfig.InstanceType) != input.InstanceType {
-		log.Info("detected drift", "reason", "instance-type has changed", "instancegroup", lc.OwnerName,
+		lc.logger().Info("detected drift", "reason", "instance-type has changed", "instancegroup", lc.OwnerName,
 			"previousValue", aws.StringValue(existingConfig.InstanceType),
 			"newValue", input.InstanceType,
 		)
@@ -165,7 +168,7 @@ func (lc *LaunchConfiguration) Drifted(input *CreateConfigurationInput) bool {
 	}
 
 	if aws.StringValue(existingConfig.IamInstanceProfile) != input.IamInstanceProfileArn {
-		log.Info("detected drift", "reason", "instance-profile has changed", "instancegroup", lc.OwnerName,
+		lc.logger().Info("detected drift", "reason", "instance-profile has changed", "instancegroup", lc.OwnerName,
 			"previousValue", aws.StringValue(existingConfig.IamInstanceProfile),
 			"newValue", input.IamInstanceProfileArn,
 		)
@@ -173,7 +176,7 @@ func (lc *LaunchConfiguration) Drifted(input *CreateConfigurationInput) bool {
 	}
 
 	if !common.StringSliceEquals(aws.StringValueSlice(existingConfig.SecurityGroups), input.SecurityGroups) {
-		log.Info("detected drift", "reason", "security-groups has changed", "instancegroup", lc.OwnerName,
+		lc.logger().Info("detected drift", "reason", "security-groups has changed", "instancegroup", lc.OwnerName,
 			"previousValue", aws.StringValueSlice(existingConfig.SecurityGroups),
 			"newValue", input.SecurityGroups,
 		)
@@ -181,7 +184,7 @@ func (lc *LaunchConfiguration) Drifted(input *CreateConfigurationInput) bool {
 	}
 
 	if aws.StringValue(existingConfig.SpotPrice) != input.SpotPrice {
-		log.Info("detected drift", "reason", "spot-price has changed", "instancegroup", lc.OwnerName,
+		lc.logger().Info("detected drift", "reason", "spot-price has changed", "instancegroup", lc.OwnerName,
 			"previousValue", aws.StringValue(existingConfig.SpotPrice),
 			"newValue", input.SpotPrice,
 		)
@@ -189,7 +192,7 @@ func (lc *LaunchConfiguration) Drifted(input *CreateConfigurationInput) bool {
 	}
 
 	if aws.StringValue(existingConfig.KeyName) != input.KeyName {
-		log.Info("detected drift", "reason", "key-pair has changed", "instancegroup", lc.OwnerName,
+		lc.logger().Info("detected drift", "reason", "key-pair has changed", "instancegroup", lc.OwnerName,
 			"previousValue", aws.StringValue(existingConfig.KeyName),
 			"newValue", input.KeyName,
 		)
@@ -197,29 +200,39 @@ func (lc *LaunchConfiguration) Drifted(input *CreateConfigurationInput) bool {
 	}
 
 	if aws.StringValue(existingConfig.UserData) != input.UserData {
-		log.Info("detected drift", "reason", "user-data has changed", "instancegroup", lc.OwnerName,
-			"previousValue", aws.StringValue(existingConfig.UserData),
-			"newValue", input.UserData,
+		// user data can include bootstrap secrets, only its hash is logged
+		lc.logger().Info("detected drift", "reason", "user-data has changed", "instancegroup", lc.OwnerName,
+			"previousValue", common.HashValue(aws.StringValue(existingConfig.UserData)),
+			"newValue", common.HashValue(input.UserData),
 		)
 		drift = true
 	}
 
-	devices := lc.blockDeviceList(input.Volumes)
-	if !reflect.DeepEqual(existingConfig.BlockDeviceMappings, devices) {
-		log.Info("detected drift", "reason", "volumes have changed", "instancegroup", lc.OwnerName,
-			"previousValue", existingConfig.BlockDeviceMappings,
-			"newValue", devices,
+	devices := lc.blockDeviceList(input.Volumes, input.InstanceStoreVolumes)
+	for _, diff := range BlockDeviceDrift(existingConfig.BlockDeviceMappings, devices) {
+		lc.logger().Info("detected drift", "reason", "volumes have changed", "instancegroup", lc.OwnerName,
+			"device", diff.DeviceName,
+			"field", diff.Field,
+			"previousValue", diff.PreviousValue,
+			"newValue", diff.NewValue,
 		)
 		drift = true
 	}
 
 	if !drift {
-		log.Info("no drift detected", "instancegroup", lc.OwnerName)
+		lc.logger().Info("no drift detected", "instancegroup", lc.OwnerName)
 	}
 
 	return drift
 }
 
+func (lc *LaunchConfiguration) logger() logr.Logger {
+	if lc.Log == nil {
+		return log
+	}
+	return lc.Log
+}
+
 func (lc *LaunchConfiguration) Provisioned() bool {
 	return lc.TargetResource != nil
 }
@@ -235,15 +248,123 @@ func (lc *LaunchConfiguration) Name() string {
 	return aws.StringValue(lc.TargetResource.LaunchConfigurationName)
 }
 
-func (lc *LaunchConfiguration) blockDeviceList(volumes []v1alpha1.NodeVolume) []*autoscaling.BlockDeviceMapping {
-	var devices []*autoscaling.BlockDeviceMapping
+// BlockDeviceMappings returns the block device mappings of a launch configuration created from the input
+func (lc *LaunchConfiguration) BlockDeviceMappings(input *CreateConfigurationInput) []*autoscaling.BlockDeviceMapping {
+	return lc.blockDeviceList(input.Volumes, input.InstanceStoreVolumes)
+}
+
+func (lc *LaunchConfiguration) blockDeviceList(volumes []v1alpha1.NodeVolume, instanceStoreVolumes int) []*autoscaling.BlockDeviceMapping {
+	var (
+		devices []*autoscaling.BlockDeviceMapping
+		used    = make([]string, 0)
+	)
 	for _, v := range volumes {
 		devices = append(devices, lc.GetBasicBlockDevice(v.Name, v.Type, v.SnapshotID, v.Size, v.Iops, v.DeleteOnTermination, v.Encrypted))
+		used = append(used, v.Name)
+	}
+
+	// map instance store volumes to the first device names which are not used by volumes, /dev/sdX and /dev/xvdX are the same device
+	letter := 'b'
+	for i := 0; i < instanceStoreVolumes && letter <= 'z'; letter++ {
+		sd, xvd := fmt.Sprintf("/dev/sd%c", letter), fmt.Sprintf("/dev/xvd%c", letter)
+		if common.ContainsString(used, sd) || common.ContainsString(used, xvd) {
+			continue
+		}
+		devices = append(devices, lc.GetEphemeralBlockDevice(sd, i))
+		i++
 	}
 
 	return devices
 }
 
+// BlockDeviceDiff is a difference in a single field of a block device mapping
+type BlockDeviceDiff struct {
+	DeviceName    string
+	Field         string
+	PreviousValue string
+	NewValue      string
+}
+
+func (d BlockDeviceDiff) String() string {
+	return fmt.Sprintf("%v: %v changed from '%v' to '%v'", d.DeviceName, d.Field, d.PreviousValue, d.NewValue)
+}
+
+// BlockDeviceDrift returns the field level differences between existing and desired block device mappings, mappings are matched
+// by device name regardless of their order, and unset fields are compared by the value AWS defaults them to
+func BlockDeviceDrift(existing, desired []*autoscaling.BlockDeviceMapping) []BlockDeviceDiff {
+	var (
+		diffs          = make([]BlockDeviceDiff, 0)
+		existingByName = make(map[string]*autoscaling.BlockDeviceMapping)
+		desiredByName  = make(map[string]*autoscaling.BlockDeviceMapping)
+	)
+
+	for _, d := range existing {
+		existingByName[aws.StringValue(d.DeviceName)] = d
+	}
+	for _, d := range desired {
+		desiredByName[aws.StringValue(d.DeviceName)] = d
+	}
+
+	for _, d := range desired {
+		name := aws.StringValue(d.DeviceName)
+		e, ok := existingByName[name]
+		if !ok {
+			diffs = append(diffs, BlockDeviceDiff{DeviceName: name, Field: "device", PreviousValue: "", NewValue: "present"})
+			continue
+		}
+		diffs = append(diffs, blockDeviceFieldDrift(name, e, d)...)
+	}
+
+	for _, e := range existing {
+		name := aws.StringValue(e.DeviceName)
+		if _, ok := desiredByName[name]; !ok {
+			diffs = append(diffs, BlockDeviceDiff{DeviceName: name, Field: "device", PreviousValue: "present", NewValue: ""})
+		}
+	}
+
+	return diffs
+}
+
+func blockDeviceFieldDrift(name string, existing, desired *autoscaling.BlockDeviceMapping) []BlockDeviceDiff {
+	var diffs []BlockDeviceDiff
+	compare := func(field string, previous, new interface{}) {
+		p, n := fmt.Sprint(previous), fmt.Sprint(new)
+		if p != n {
+			diffs = append(diffs, BlockDeviceDiff{DeviceName: name, Field: field, PreviousValue: p, NewValue: n})
+		}
+	}
+
+	compare("virtualName", aws.StringValue(existing.VirtualName), aws.StringValue(desired.VirtualName))
+	compare("noDevice", aws.BoolValue(existing.NoDevice), aws.BoolValue(desired.NoDevice))
+
+	if existing.Ebs == nil || desired.Ebs == nil {
+		compare("ebs", existing.Ebs != nil, desired.Ebs != nil)
+		return diffs
+	}
+
+	var (
+		e = existing.Ebs
+		d = desired.Ebs
+	)
+
+	compare("volumeType", aws.StringValue(e.VolumeType), aws.StringValue(d.VolumeType))
+	compare("volumeSize", aws.Int64Value(e.VolumeSize), aws.Int64Value(d.VolumeSize))
+	compare("iops", aws.Int64Value(e.Iops), aws.Int64Value(d.Iops))
+	compare("snapshotId", aws.StringValue(e.SnapshotId), aws.StringValue(d.SnapshotId))
+	compare("encrypted", aws.BoolValue(e.Encrypted), aws.BoolValue(d.Encrypted))
+
+	// volumes are deleted on termination unless specified otherwise
+	deleteOnTermination := func(v *bool) bool {
+		if v == nil {
+			return true
+		}
+		return aws.BoolValue(v)
+	}
+	compare("deleteOnTermination", deleteOnTermination(e.DeleteOnTermination), deleteOnTermination(d.DeleteOnTermination))
+
+	return diffs
+}
+
 func prefixedConfigurations(configs []*autoscaling.LaunchConfiguration, prefix string) []*autoscaling.LaunchConfiguration {
 	prefixed := []*autoscaling.LaunchConfiguration{}
 	for _, lc := range configs {
@@ -23,6 +23,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws/awserr"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/autoscaling"
 	"github.com/aws/aws-sdk-go/service/autoscaling/autoscalingiface"
 	"github.com/keikoproj/instance-manager/api/v1alpha1"
@@ -44,6 +45,10 @@ func (a *MockAutoScalingClient) CreateLaunchConfiguration(input *autoscaling.Cre
 	return &autoscaling.CreateLaunchConfigurationOutput{}, a.CreateLaunchConfigurationErr
 }
 
+func (a *MockAutoScalingClient) CreateLaunchConfigurationWithContext(ctx aws.Context, input *autoscaling.CreateLaunchConfigurationInput, opts ...request.Option) (*autoscaling.CreateLaunchConfigurationOutput, error) {
+	return a.CreateLaunchConfiguration(input)
+}
+
 func (a *MockAutoScalingClient) DescribeLaunchConfigurationsPages(input *autoscaling.DescribeLaunchConfigurationsInput, callback func(*autoscaling.DescribeLaunchConfigurationsOutput, bool) bool) error {
 	page, err := a.DescribeLaunchConfigurations(input)
 	if err != nil {
@@ -53,15 +58,27 @@ func (a *MockAutoScalingClient) DescribeLaunchConfigurationsPages(input *autosca
 	return nil
 }
 
+func (a *MockAutoScalingClient) DescribeLaunchConfigurationsPagesWithContext(ctx aws.Context, input *autoscaling.DescribeLaunchConfigurationsInput, callback func(*autoscaling.DescribeLaunchConfigurationsOutput, bool) bool, opts ...request.Option) error {
+	return a.DescribeLaunchConfigurationsPages(input, callback)
+}
+
 func (a *MockAutoScalingClient) DescribeLaunchConfigurations(input *autoscaling.DescribeLaunchConfigurationsInput) (*autoscaling.DescribeLaunchConfigurationsOutput, error) {
 	return &autoscaling.DescribeLaunchConfigurationsOutput{LaunchConfigurations: a.LaunchConfigurations}, a.DescribeLaunchConfigurationsErr
 }
 
+func (a *MockAutoScalingClient) DescribeLaunchConfigurationsWithContext(ctx aws.Context, input *autoscaling.DescribeLaunchConfigurationsInput, opts ...request.Option) (*autoscaling.DescribeLaunchConfigurationsOutput, error) {
+	return a.DescribeLaunchConfigurations(input)
+}
+
 func (a *MockAutoScalingClient) DeleteLaunchConfiguration(input *autoscaling.DeleteLaunchConfigurationInput) (*autoscaling.DeleteLaunchConfigurationOutput, error) {
 	a.DeleteLaunchConfigurationCallCount++
 	return &autoscaling.DeleteLaunchConfigurationOutput{}, a.DeleteLaunchConfigurationErr
 }
 
+func (a *MockAutoScalingClient) DeleteLaunchConfigurationWithContext(ctx aws.Context, input *autoscaling.DeleteLaunchConfigurationInput, opts ...request.Option) (*autoscaling.DeleteLaunchConfigurationOutput, error) {
+	return a.DeleteLaunchConfiguration(input)
+}
+
 func TestDiscover(t *testing.T) {
 	var (
 		g       = gomega.NewGomegaWithT(t)
@@ -450,6 +467,33 @@ func TestDrifted(t *testing.T) {
 					},
 				},
 			},
+			// deleteOnTermination defaults to true
+			shouldDrift: false,
+		},
+		{
+			launchConfig: &autoscaling.LaunchConfiguration{
+				LaunchConfigurationName: aws.String("my-launch-config"),
+				BlockDeviceMappings: []*autoscaling.BlockDeviceMapping{
+					{
+						DeviceName: aws.String("/dev/xvda"),
+						Ebs: &autoscaling.Ebs{
+							VolumeType: aws.String("gp2"),
+							VolumeSize: aws.Int64(32),
+						},
+					},
+				},
+			},
+			input: &CreateConfigurationInput{
+				SecurityGroups: []string{},
+				Volumes: []v1alpha1.NodeVolume{
+					{
+						Name:      "/dev/xvda",
+						Type:      "gp2",
+						Size:      32,
+						Encrypted: aws.Bool(true),
+					},
+				},
+			},
 			shouldDrift: true,
 		},
 	}
@@ -464,3 +508,90 @@ func TestDrifted(t *testing.T) {
 		g.Expect(result).To(gomega.Equal(tc.shouldDrift))
 	}
 }
+
+func TestBlockDeviceListInstanceStore(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		asgMock = &MockAutoScalingClient{}
+	)
+
+	lc := &LaunchConfiguration{
+		AwsWorker: awsprovider.AwsWorker{
+			AsgClient: asgMock,
+		},
+	}
+
+	volumes := []v1alpha1.NodeVolume{
+		{
+			Name: "/dev/xvda",
+			Type: "gp2",
+			Size: 30,
+		},
+		{
+			Name: "/dev/xvdb",
+			Type: "gp2",
+			Size: 100,
+		},
+	}
+
+	devices := lc.blockDeviceList(volumes, 0)
+	g.Expect(devices).To(gomega.HaveLen(2))
+
+	// instance store volumes skip device names used by volumes
+	devices = lc.blockDeviceList(volumes, 2)
+	g.Expect(devices).To(gomega.HaveLen(4))
+	g.Expect(devices[2]).To(gomega.Equal(&autoscaling.BlockDeviceMapping{
+		DeviceName:  aws.String("/dev/sdc"),
+		VirtualName: aws.String("ephemeral0"),
+	}))
+	g.Expect(devices[3]).To(gomega.Equal(&autoscaling.BlockDeviceMapping{
+		DeviceName:  aws.String("/dev/sdd"),
+		VirtualName: aws.String("ephemeral1"),
+	}))
+}
+
+func TestBlockDeviceDrift(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	root := func() *autoscaling.BlockDeviceMapping {
+		return &autoscaling.BlockDeviceMapping{
+			DeviceName: aws.String("/dev/xvda"),
+			Ebs: &autoscaling.Ebs{
+				VolumeType:          aws.String("gp2"),
+				VolumeSize:          aws.Int64(32),
+				DeleteOnTermination: aws.Bool(true),
+			},
+		}
+	}
+	data := func() *autoscaling.BlockDeviceMapping {
+		return &autoscaling.BlockDeviceMapping{
+			DeviceName: aws.String("/dev/xvdb"),
+			Ebs: &autoscaling.Ebs{
+				VolumeType: aws.String("gp2"),
+				SnapshotId: aws.String("snap-12345678"),
+			},
+		}
+	}
+
+	// order and defaulted fields do not drift
+	existing := []*autoscaling.BlockDeviceMapping{data(), root()}
+	existing[1].Ebs.DeleteOnTermination = nil
+	existing[0].Ebs.Encrypted = aws.Bool(false)
+	g.Expect(BlockDeviceDrift(existing, []*autoscaling.BlockDeviceMapping{root(), data()})).To(gomega.BeEmpty())
+
+	desired := []*autoscaling.BlockDeviceMapping{root(), data()}
+	desired[0].Ebs.Encrypted = aws.Bool(true)
+	desired[0].Ebs.DeleteOnTermination = aws.Bool(false)
+	desired[1].Ebs.SnapshotId = aws.String("snap-87654321")
+	g.Expect(BlockDeviceDrift([]*autoscaling.BlockDeviceMapping{root(), data()}, desired)).To(gomega.ConsistOf(
+		BlockDeviceDiff{DeviceName: "/dev/xvda", Field: "encrypted", PreviousValue: "false", NewValue: "true"},
+		BlockDeviceDiff{DeviceName: "/dev/xvda", Field: "deleteOnTermination", PreviousValue: "true", NewValue: "false"},
+		BlockDeviceDiff{DeviceName: "/dev/xvdb", Field: "snapshotId", PreviousValue: "snap-12345678", NewValue: "snap-87654321"},
+	))
+
+	// added and removed devices
+	g.Expect(BlockDeviceDrift([]*autoscaling.BlockDeviceMapping{root()}, []*autoscaling.BlockDeviceMapping{data()})).To(gomega.ConsistOf(
+		BlockDeviceDiff{DeviceName: "/dev/xvdb", Field: "device", PreviousValue: "", NewValue: "present"},
+		BlockDeviceDiff{DeviceName: "/dev/xvda", Field: "device", PreviousValue: "present", NewValue: ""},
+	))
+}
@@ -42,8 +42,8 @@ func (ctx *EksInstanceGroupContext) StateDiscovery() {
 	}
 
 	if deleted {
-		// resource is being deleted
-		if provisioned {
+		// resource is being deleted, scaling groups which are being replaced are deleted with it
+		if provisioned || state.HasRetiringScalingGroups() {
 			// scaling group still provisioned
 			if aws.StringValue(group.Status) == ScalingGroupDeletionStatus {
 				// scaling group is being deleted
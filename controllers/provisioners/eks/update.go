@@ -17,6 +17,7 @@ package eks
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -25,25 +26,26 @@ import (
 	"github.com/aws/aws-sdk-go/service/autoscaling"
 	"github.com/keikoproj/instance-manager/api/v1alpha1"
 	"github.com/keikoproj/instance-manager/controllers/common"
+	kubeprovider "github.com/keikoproj/instance-manager/controllers/providers/kubernetes"
 	"github.com/keikoproj/instance-manager/controllers/provisioners/eks/scaling"
 )
 
 func (ctx *EksInstanceGroupContext) Update() error {
 	var (
-		rotationNeeded  bool
-		instanceGroup   = ctx.GetInstanceGroup()
-		state           = ctx.GetDiscoveredState()
-		scalingConfig   = state.GetScalingConfiguration()
-		configuration   = instanceGroup.GetEKSConfiguration()
-		args            = ctx.GetBootstrapArgs()
-		userDataPayload = ctx.GetUserDataStages()
-		clusterName     = configuration.GetClusterName()
-		mounts          = ctx.GetMountOpts()
-		userData        = ctx.GetBasicUserData(clusterName, args, userDataPayload, mounts)
-		sgs             = ctx.ResolveSecurityGroups()
-		spotPrice       = configuration.GetSpotPrice()
+		rotationNeeded bool
+		instanceGroup  = ctx.GetInstanceGroup()
+		state          = ctx.GetDiscoveredState()
+		scalingConfig  = state.GetScalingConfiguration()
 	)
 
+	if err := ctx.ValidateImage(); err != nil {
+		return errors.Wrap(err, "failed to validate image")
+	}
+
+	if err := ctx.ValidatePlacement(); err != nil {
+		return errors.Wrap(err, "failed to validate placement")
+	}
+
 	instanceGroup.SetState(v1alpha1.ReconcileModifying)
 
 	// make sure our managed role exists if instance group has not provided one
@@ -51,24 +53,29 @@ func (ctx *EksInstanceGroupContext) Update() error {
 	if err != nil {
 		return errors.Wrap(err, "failed to update scaling group role")
 	}
-	instanceProfile := state.GetInstanceProfile()
 
-	config := &scaling.CreateConfigurationInput{
-		IamInstanceProfileArn: aws.StringValue(instanceProfile.Arn),
-		ImageId:               configuration.Image,
-		InstanceType:          configuration.InstanceType,
-		KeyName:               configuration.KeyPairName,
-		SecurityGroups:        sgs,
-		Volumes:               configuration.Volumes,
-		UserData:              userData,
-		SpotPrice:             spotPrice,
-	}
+	config := ctx.GetDesiredConfiguration()
 
 	var configName string
 	configName = scalingConfig.Name()
+
+	// delete old launch configurations, discovery does not delete them since it also runs for dry-run, suspended
+	// and deferred reconciles
+	if err := scalingConfig.Delete(&scaling.DeleteConfigurationInput{
+		Name:           configName,
+		Prefix:         ctx.ResourcePrefix,
+		DeleteAll:      false,
+		RetainVersions: ctx.ConfigRetention,
+	}); err != nil {
+		ctx.Log.Error(err, "failed to delete old launch configurations", "instancegroup", instanceGroup.GetName())
+	}
+
 	// create new launchconfig if it has drifted
 	if scalingConfig.Drifted(config) {
 		rotationNeeded = true
+		if err := ctx.UploadBootstrapObject(); err != nil {
+			return errors.Wrap(err, "failed to upload bootstrap object")
+		}
 		configName = fmt.Sprintf("%v-%v", ctx.ResourcePrefix, common.GetTimeString())
 		config.Name = configName
 		if err := scalingConfig.Create(config); err != nil {
@@ -78,6 +85,10 @@ func (ctx *EksInstanceGroupContext) Update() error {
 
 	if ctx.RotationNeeded() {
 		rotationNeeded = true
+	} else {
+		// excluded nodes which were replaced manually are no longer pending
+		instanceGroup.GetStatus().SetPendingManualReplacement(ctx.ManualReplacementNeeded())
+		instanceGroup.GetStatus().SetRotation(nil)
 	}
 
 	// update scaling group
@@ -86,15 +97,51 @@ func (ctx *EksInstanceGroupContext) Update() error {
 		return errors.Wrap(err, "failed to update scaling group")
 	}
 
+	// the security policy requires instances to use IMDSv2
+	if ctx.SecurityPolicy.RequireIMDSv2 {
+		if err = ctx.RequireIMDSv2(); err != nil {
+			return errors.Wrap(err, "failed to require IMDSv2")
+		}
+	}
+
 	// we should try to bootstrap the role before we wait for nodes to be ready
 	// to avoid getting locked if someone made a manual change to aws-auth
 	if err = ctx.BootstrapNodes(); err != nil {
 		ctx.Log.Info("failed to bootstrap role, will retry", "error", err, "instancegroup", instanceGroup.GetName())
 	}
 
+	// nodes which joined are labeled with their instance group
+	if err = ctx.UpdateNodeOwnership(); err != nil {
+		ctx.Log.Info("failed to label nodes with ownership, will retry", "error", err, "instancegroup", instanceGroup.GetName())
+	}
+
+	// nodes whose instances were terminated outside of the scaling group are removed
+	if err = ctx.RemoveStaleNodes(); err != nil {
+		ctx.Log.Info("failed to remove stale nodes, will retry", "error", err, "instancegroup", instanceGroup.GetName())
+	}
+
+	// instances whose nodes do not join the cluster are reported, and terminated when enabled
+	if err = ctx.DetectBootstrapFailures(); err != nil {
+		ctx.Log.Info("failed to detect bootstrap failures, will retry", "error", err, "instancegroup", instanceGroup.GetName())
+	}
+
+	// instances of nodes which stay not ready are replaced, unless all nodes are rotated
+	if !rotationNeeded {
+		if err = ctx.ReplaceUnhealthyNodes(); err != nil {
+			ctx.Log.Info("failed to replace unhealthy nodes, will retry", "error", err, "instancegroup", instanceGroup.GetName())
+		}
+	}
+
 	// update readiness conditions
 	nodesReady := ctx.UpdateNodeReadyCondition()
-	if nodesReady {
+
+	// nodes of the scaling groups which are replaced are migrated once the nodes of the scaling group are ready
+	migrated, err := ctx.MigrateScalingGroups()
+	if err != nil {
+		return errors.Wrap(err, "failed to migrate replaced scaling groups")
+	}
+
+	if nodesReady && migrated {
 		instanceGroup.SetState(v1alpha1.ReconcileModified)
 	}
 	if rotationNeeded {
@@ -104,10 +151,85 @@ func (ctx *EksInstanceGroupContext) Update() error {
 	return nil
 }
 
+// GetDesiredConfiguration returns the launch configuration input which matches the instance group spec
+func (ctx *EksInstanceGroupContext) GetDesiredConfiguration() *scaling.CreateConfigurationInput {
+	var (
+		instanceGroup   = ctx.GetInstanceGroup()
+		state           = ctx.GetDiscoveredState()
+		configuration   = instanceGroup.GetEKSConfiguration()
+		args            = ctx.GetBootstrapArgs()
+		userDataPayload = ctx.GetUserDataStages()
+		clusterName     = configuration.GetClusterName()
+		mounts          = ctx.GetMountOpts()
+		userData        = ctx.GetBasicUserData(clusterName, args, userDataPayload, mounts)
+		sgs             = ctx.ResolveSecurityGroups()
+		spotPrice       = configuration.GetSpotPrice()
+		instanceProfile = state.GetInstanceProfile()
+	)
+
+	return &scaling.CreateConfigurationInput{
+		IamInstanceProfileArn: aws.StringValue(instanceProfile.Arn),
+		ImageId:               configuration.Image,
+		InstanceType:          configuration.InstanceType,
+		KeyName:               configuration.KeyPairName,
+		SecurityGroups:        sgs,
+		Volumes:               ctx.GetVolumes(),
+		UserData:              userData,
+		SpotPrice:             spotPrice,
+		InstanceStoreVolumes:  ctx.GetInstanceStoreVolumes(),
+		SensitiveUserData:     len(configuration.GetSecretReferences()) > 0,
+	}
+}
+
+// PendingChanges returns the changes which an update would make to cloud resources, without making them
+func (ctx *EksInstanceGroupContext) PendingChanges() []string {
+	var (
+		state         = ctx.GetDiscoveredState()
+		scalingConfig = state.GetScalingConfiguration()
+		changes       = make([]string, 0)
+	)
+
+	if !state.IsProvisioned() {
+		return []string{PendingChangeCreate}
+	}
+
+	if scalingConfig.Drifted(ctx.GetDesiredConfiguration()) {
+		// a new launch configuration would be created and rolled out
+		changes = append(changes, PendingChangeLaunchConfiguration, PendingChangeScalingGroup, PendingChangeRotation)
+		if state.HasRetiringScalingGroups() {
+			changes = append(changes, PendingChangeMigration)
+		}
+		return changes
+	}
+
+	if ctx.ScalingGroupUpdateNeeded(scalingConfig.Name()) {
+		changes = append(changes, PendingChangeScalingGroup)
+	}
+
+	if ctx.TagsUpdateNeeded() {
+		changes = append(changes, PendingChangeTags)
+	}
+
+	if protect, unprotect := ctx.ScaleInProtectionUpdateNeeded(); len(protect)+len(unprotect) > 0 {
+		changes = append(changes, PendingChangeScaleInProtection)
+	}
+
+	if ctx.RotationNeeded() {
+		changes = append(changes, PendingChangeRotation)
+	}
+
+	if state.HasRetiringScalingGroups() {
+		changes = append(changes, PendingChangeMigration)
+	}
+
+	return changes
+}
+
 func (ctx *EksInstanceGroupContext) UpdateScalingGroup(configName string) error {
 	var (
 		instanceGroup = ctx.GetInstanceGroup()
 		spec          = instanceGroup.GetEKSSpec()
+		configuration = instanceGroup.GetEKSConfiguration()
 		status        = instanceGroup.GetStatus()
 		state         = ctx.GetDiscoveredState()
 		scalingGroup  = state.GetScalingGroup()
@@ -116,14 +238,31 @@ func (ctx *EksInstanceGroupContext) UpdateScalingGroup(configName string) error
 		rmTags        = ctx.GetRemovedTags(asgName)
 	)
 
+	if instanceGroup.IsStrictReconcilePolicy() {
+		if changes := ctx.OutOfBandChanges(configName); len(changes) > 0 {
+			ctx.Log.Info("reverting changes made outside of the controller", "instancegroup", instanceGroup.GetName(), "scalinggroup", asgName, "changes", changes)
+			state.Publisher.Publish(kubeprovider.ChangesRevertedEvent, "instancegroup", instanceGroup.GetName(), "scalinggroup", asgName, "changes", strings.Join(changes, ","))
+		}
+	}
+
 	if ctx.ScalingGroupUpdateNeeded(configName) {
-		err := ctx.AwsWorker.UpdateScalingGroup(&autoscaling.UpdateAutoScalingGroupInput{
-			AutoScalingGroupName:    aws.String(asgName),
-			LaunchConfigurationName: aws.String(configName),
-			MinSize:                 aws.Int64(spec.GetMinSize()),
-			MaxSize:                 aws.Int64(spec.GetMaxSize()),
-			VPCZoneIdentifier:       aws.String(common.ConcatenateList(ctx.ResolveSubnets(), ",")),
-		})
+		input := &autoscaling.UpdateAutoScalingGroupInput{
+			AutoScalingGroupName:             aws.String(asgName),
+			LaunchConfigurationName:          aws.String(configName),
+			MinSize:                          aws.Int64(spec.GetMinSize()),
+			MaxSize:                          aws.Int64(spec.GetMaxSize()),
+			VPCZoneIdentifier:                aws.String(common.ConcatenateList(ctx.ResolveSubnets(), ",")),
+			NewInstancesProtectedFromScaleIn: aws.Bool(configuration.IsNewInstancesProtectedFromScaleIn()),
+			HealthCheckType:                  aws.String(configuration.GetHealthCheckType()),
+			HealthCheckGracePeriod:           configuration.GetHealthCheckGracePeriod(),
+		}
+
+		// desired capacity is only reset when the controller owns it, otherwise the scaling group only clamps it to min/max
+		if ctx.IsDesiredCapacityManaged() {
+			input.DesiredCapacity = aws.Int64(spec.GetMinSize())
+		}
+
+		err := ctx.AwsWorker.UpdateScalingGroup(input)
 		if err != nil {
 			return err
 		}
@@ -155,9 +294,114 @@ func (ctx *EksInstanceGroupContext) UpdateScalingGroup(configName string) error
 		return err
 	}
 
+	if err := ctx.UpdateNotifications(asgName); err != nil {
+		return err
+	}
+
+	if err := ctx.UpdateAlarms(asgName); err != nil {
+		return err
+	}
+
+	if err := ctx.UpdateScaleInProtection(asgName); err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// UpdateScaleInProtection protects instances whose nodes are annotated for scale-in protection, and removes the
+// protection once the annotation is cleared, the instances protected by the controller are recorded in the status
+func (ctx *EksInstanceGroupContext) UpdateScaleInProtection(asgName string) error {
+	var (
+		instanceGroup      = ctx.GetInstanceGroup()
+		status             = instanceGroup.GetStatus()
+		scalingGroup       = ctx.GetDiscoveredState().GetScalingGroup()
+		protect, unprotect = ctx.ScaleInProtectionUpdateNeeded()
+		protected          = make([]string, 0)
+	)
+
+	if len(protect) > 0 {
+		if err := ctx.AwsWorker.SetScalingInstanceProtection(asgName, protect, true); err != nil {
+			return errors.Wrap(err, "failed to set instance scale-in protection")
+		}
+		ctx.Log.Info("protected instances from scale-in", "instancegroup", instanceGroup.GetName(), "instances", protect)
+	}
+
+	if len(unprotect) > 0 {
+		if err := ctx.AwsWorker.SetScalingInstanceProtection(asgName, unprotect, false); err != nil {
+			return errors.Wrap(err, "failed to remove instance scale-in protection")
+		}
+		ctx.Log.Info("removed scale-in protection from instances", "instancegroup", instanceGroup.GetName(), "instances", unprotect)
+	}
+
+	// instances which were terminated, or whose protection was removed, are no longer recorded
+	for _, instance := range scalingGroup.Instances {
+		instanceID := aws.StringValue(instance.InstanceId)
+		if common.ContainsString(unprotect, instanceID) {
+			continue
+		}
+		if common.ContainsString(protect, instanceID) || common.ContainsString(status.GetScaleInProtectedInstances(), instanceID) {
+			protected = append(protected, instanceID)
+		}
+	}
+	sort.Strings(protected)
+	if len(protected) == 0 {
+		protected = nil
+	}
+	status.SetScaleInProtectedInstances(protected)
+
+	return nil
+}
+
+// ScaleInProtectionUpdateNeeded returns the instances which should be protected from scale-in, and the instances which
+// should no longer be protected, based on the annotations of their nodes. Protection is only removed from instances
+// which the controller protected, and never when new instances are protected from scale-in by the scaling group
+func (ctx *EksInstanceGroupContext) ScaleInProtectionUpdateNeeded() ([]string, []string) {
+	var (
+		instanceGroup = ctx.GetInstanceGroup()
+		configuration = instanceGroup.GetEKSConfiguration()
+		status        = instanceGroup.GetStatus()
+		state         = ctx.GetDiscoveredState()
+		scalingGroup  = state.GetScalingGroup()
+		nodes         = state.GetClusterNodes()
+		annotated     = make(map[string]bool)
+		protect       = make([]string, 0)
+		unprotect     = make([]string, 0)
+	)
+
+	if nodes == nil {
+		return protect, unprotect
+	}
+
+	for _, node := range nodes.Items {
+		instanceID := common.GetLastElementBy(node.Spec.ProviderID, "/")
+		annotated[instanceID] = kubeprovider.IsScaleInProtected(node)
+	}
+
+	for _, instance := range scalingGroup.Instances {
+		var (
+			instanceID = aws.StringValue(instance.InstanceId)
+			protected  = aws.BoolValue(instance.ProtectedFromScaleIn)
+			owned      = common.ContainsString(status.GetScaleInProtectedInstances(), instanceID)
+		)
+
+		// instances which have not joined the cluster are left as they are
+		shouldProtect, ok := annotated[instanceID]
+		if !ok {
+			continue
+		}
+
+		// protection set by other tools or by the scaling group is left as it is
+		if shouldProtect && !protected {
+			protect = append(protect, instanceID)
+		} else if !shouldProtect && protected && owned && !configuration.IsNewInstancesProtectedFromScaleIn() {
+			unprotect = append(unprotect, instanceID)
+		}
+	}
+
+	return protect, unprotect
+}
+
 func (ctx *EksInstanceGroupContext) RotationNeeded() bool {
 	var (
 		state         = ctx.GetDiscoveredState()
@@ -170,8 +414,13 @@ func (ctx *EksInstanceGroupContext) RotationNeeded() bool {
 		return false
 	}
 
+	// instances of nodes excluded from rotation are pending manual replacement instead
+	excluded := ctx.GetRotationExcludedNodes()
 	configName := scalingConfig.Name()
 	for _, instance := range scalingGroup.Instances {
+		if _, ok := excluded[aws.StringValue(instance.InstanceId)]; ok {
+			continue
+		}
 		if aws.StringValue(instance.LaunchConfigurationName) != configName {
 			ctx.Log.Info("rotation needed due to launch-config diff", "instancegroup", instanceGroup.GetName(), "launchconfig", configName)
 			return true
@@ -180,6 +429,26 @@ func (ctx *EksInstanceGroupContext) RotationNeeded() bool {
 	return false
 }
 
+// ManualReplacementNeeded returns the names of nodes excluded from rotation whose instances do not use the active launch configuration
+func (ctx *EksInstanceGroupContext) ManualReplacementNeeded() []string {
+	var (
+		state         = ctx.GetDiscoveredState()
+		scalingGroup  = state.GetScalingGroup()
+		scalingConfig = state.GetScalingConfiguration()
+		excluded      = ctx.GetRotationExcludedNodes()
+		nodes         []string
+	)
+
+	configName := scalingConfig.Name()
+	for _, instance := range scalingGroup.Instances {
+		nodeName, ok := excluded[aws.StringValue(instance.InstanceId)]
+		if ok && aws.StringValue(instance.LaunchConfigurationName) != configName {
+			nodes = append(nodes, nodeName)
+		}
+	}
+	return nodes
+}
+
 func (ctx *EksInstanceGroupContext) TagsUpdateNeeded() bool {
 	var (
 		instanceGroup = ctx.GetInstanceGroup()
@@ -209,37 +478,131 @@ func (ctx *EksInstanceGroupContext) TagsUpdateNeeded() bool {
 		}
 	}
 
+	for _, tag := range ctx.GetNodeTemplateTags() {
+		if !common.StringMapSliceContains(existingTags, tag) {
+			return true
+		}
+	}
+
 	return false
 }
 
 func (ctx *EksInstanceGroupContext) ScalingGroupUpdateNeeded(configName string) bool {
+	return len(ctx.ScalingGroupChanges(configName)) > 0
+}
+
+// ScalingGroupChanges returns the fields of the scaling group which differ from the instance group spec, and
+// would be updated by an update of the scaling group with the given launch configuration
+func (ctx *EksInstanceGroupContext) ScalingGroupChanges(configName string) []string {
 	var (
 		instanceGroup  = ctx.GetInstanceGroup()
 		spec           = instanceGroup.GetEKSSpec()
+		configuration  = instanceGroup.GetEKSConfiguration()
 		state          = ctx.GetDiscoveredState()
 		scalingGroup   = state.GetScalingGroup()
 		zoneIdentifier = aws.StringValue(scalingGroup.VPCZoneIdentifier)
 		groupSubnets   = strings.Split(zoneIdentifier, ",")
 		specSubnets    = ctx.ResolveSubnets()
+		changes        = make([]string, 0)
 	)
 
 	if configName != aws.StringValue(scalingGroup.LaunchConfigurationName) {
-		return true
+		changes = append(changes, fmt.Sprintf("launchConfigurationName: %v -> %v", aws.StringValue(scalingGroup.LaunchConfigurationName), configName))
+	}
+
+	// instance groups are provisioned with a launch configuration only, a mixed instances policy or launch template
+	// set out of band is drift, updating the launch configuration name replaces it
+	if scalingGroup.MixedInstancesPolicy != nil {
+		changes = append(changes, "mixedInstancesPolicy: removed")
+	}
+	if scalingGroup.LaunchTemplate != nil {
+		changes = append(changes, "launchTemplate: removed")
 	}
 
 	if spec.GetMinSize() != aws.Int64Value(scalingGroup.MinSize) {
-		return true
+		changes = append(changes, fmt.Sprintf("minSize: %v -> %v", aws.Int64Value(scalingGroup.MinSize), spec.GetMinSize()))
 	}
 
 	if spec.GetMaxSize() != aws.Int64Value(scalingGroup.MaxSize) {
-		return true
+		changes = append(changes, fmt.Sprintf("maxSize: %v -> %v", aws.Int64Value(scalingGroup.MaxSize), spec.GetMaxSize()))
+	}
+
+	if ctx.IsDesiredCapacityManaged() && spec.GetMinSize() != aws.Int64Value(scalingGroup.DesiredCapacity) {
+		changes = append(changes, fmt.Sprintf("desiredCapacity: %v -> %v", aws.Int64Value(scalingGroup.DesiredCapacity), spec.GetMinSize()))
 	}
 
 	if !common.StringSliceEqualFold(specSubnets, groupSubnets) {
-		return true
+		changes = append(changes, fmt.Sprintf("subnets: %v -> %v", zoneIdentifier, common.ConcatenateList(specSubnets, ",")))
 	}
 
-	return false
+	if configuration.IsNewInstancesProtectedFromScaleIn() != aws.BoolValue(scalingGroup.NewInstancesProtectedFromScaleIn) {
+		changes = append(changes, fmt.Sprintf("newInstancesProtectedFromScaleIn: %v -> %v", aws.BoolValue(scalingGroup.NewInstancesProtectedFromScaleIn), configuration.IsNewInstancesProtectedFromScaleIn()))
+	}
+
+	// scaling groups which were created without a health check type use EC2 health checks
+	healthCheckType := aws.StringValue(scalingGroup.HealthCheckType)
+	if common.StringEmpty(healthCheckType) {
+		healthCheckType = v1alpha1.EC2HealthCheckType
+	}
+	if !strings.EqualFold(configuration.GetHealthCheckType(), healthCheckType) {
+		changes = append(changes, fmt.Sprintf("healthCheckType: %v -> %v", healthCheckType, configuration.GetHealthCheckType()))
+	}
+
+	if gracePeriod := configuration.GetHealthCheckGracePeriod(); gracePeriod != nil && aws.Int64Value(gracePeriod) != aws.Int64Value(scalingGroup.HealthCheckGracePeriod) {
+		changes = append(changes, fmt.Sprintf("healthCheckGracePeriod: %v -> %v", aws.Int64Value(scalingGroup.HealthCheckGracePeriod), aws.Int64Value(gracePeriod)))
+	}
+
+	return changes
+}
+
+// IsDesiredCapacityManaged returns true if the desired capacity of the scaling group is reset to minSize, either by
+// the desired capacity policy or because the instance group reverts all changes made outside of the controller
+func (ctx *EksInstanceGroupContext) IsDesiredCapacityManaged() bool {
+	instanceGroup := ctx.GetInstanceGroup()
+	return instanceGroup.IsStrictReconcilePolicy() || instanceGroup.GetEKSSpec().IsDesiredCapacityManaged()
+}
+
+// OutOfBandChanges returns the fields of the scaling group which were changed outside of the controller, they are
+// compared with the spec and the launch configuration the scaling group is updated with, differences are only out
+// of band changes while the configuration is the one which was last applied, changes to the spec are not included
+func (ctx *EksInstanceGroupContext) OutOfBandChanges(configName string) []string {
+	var (
+		instanceGroup = ctx.GetInstanceGroup()
+		spec          = instanceGroup.GetEKSSpec()
+		status        = instanceGroup.GetStatus()
+		state         = ctx.GetDiscoveredState()
+		scalingGroup  = state.GetScalingGroup()
+		asgName       = aws.StringValue(scalingGroup.AutoScalingGroupName)
+		changes       = make([]string, 0)
+	)
+
+	// nothing was applied yet, or the configuration changed since it was last applied
+	if common.StringEmpty(status.GetConfigurationHash()) || status.GetConfigurationHash() != instanceGroup.HashConfiguration() {
+		return changes
+	}
+
+	if configName != aws.StringValue(scalingGroup.LaunchConfigurationName) ||
+		scalingGroup.MixedInstancesPolicy != nil || scalingGroup.LaunchTemplate != nil {
+		changes = append(changes, "launchConfiguration")
+	}
+
+	if spec.GetMinSize() != aws.Int64Value(scalingGroup.MinSize) {
+		changes = append(changes, "minSize")
+	}
+
+	if spec.GetMaxSize() != aws.Int64Value(scalingGroup.MaxSize) {
+		changes = append(changes, "maxSize")
+	}
+
+	if ctx.IsDesiredCapacityManaged() && spec.GetMinSize() != aws.Int64Value(scalingGroup.DesiredCapacity) {
+		changes = append(changes, "desiredCapacity")
+	}
+
+	if len(ctx.GetRemovedTags(asgName)) > 0 {
+		changes = append(changes, "tags")
+	}
+
+	return changes
 }
 
 func (ctx *EksInstanceGroupContext) UpdateManagedPolicies(roleName string) error {
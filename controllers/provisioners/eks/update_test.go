@@ -19,6 +19,7 @@ import (
 	"testing"
 
 	kubeprovider "github.com/keikoproj/instance-manager/controllers/providers/kubernetes"
+	"github.com/keikoproj/instance-manager/controllers/provisioners"
 	"github.com/keikoproj/instance-manager/controllers/provisioners/eks/scaling"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -411,6 +412,24 @@ func TestScalingGroupUpdatePredicate(t *testing.T) {
 	mockScalingGroupSubnets.VPCZoneIdentifier = aws.String("subnet-0")
 	mockScalingGroupLaunchConfig := MockScalingGroup("asg-4")
 	mockScalingGroupLaunchConfig.LaunchConfigurationName = aws.String("different-name")
+	mockScalingGroupProtected := MockScalingGroup("asg-5")
+	mockScalingGroupProtected.NewInstancesProtectedFromScaleIn = aws.Bool(true)
+	mockScalingGroupMixed := MockScalingGroup("asg-6")
+	mockScalingGroupMixed.MixedInstancesPolicy = &autoscaling.MixedInstancesPolicy{
+		InstancesDistribution: &autoscaling.InstancesDistribution{
+			OnDemandPercentageAboveBaseCapacity: aws.Int64(50),
+		},
+	}
+	mockScalingGroupTemplate := MockScalingGroup("asg-7")
+	mockScalingGroupTemplate.LaunchTemplate = &autoscaling.LaunchTemplateSpecification{
+		LaunchTemplateName: aws.String("some-launch-template"),
+		Version:            aws.String("$Latest"),
+	}
+	mockScalingGroupHealthCheck := MockScalingGroup("asg-8")
+	mockScalingGroupHealthCheck.HealthCheckType = aws.String("ELB")
+	mockScalingGroupEC2 := MockScalingGroup("asg-9")
+	mockScalingGroupEC2.HealthCheckType = aws.String("EC2")
+	mockScalingGroupEC2.HealthCheckGracePeriod = aws.Int64(0)
 
 	tests := []struct {
 		input    *autoscaling.Group
@@ -421,6 +440,11 @@ func TestScalingGroupUpdatePredicate(t *testing.T) {
 		{input: mockScalingGroupMin, expected: true},
 		{input: mockScalingGroupMax, expected: true},
 		{input: mockScalingGroupSubnets, expected: true},
+		{input: mockScalingGroupProtected, expected: true},
+		{input: mockScalingGroupMixed, expected: true},
+		{input: mockScalingGroupTemplate, expected: true},
+		{input: mockScalingGroupHealthCheck, expected: true},
+		{input: mockScalingGroupEC2, expected: false},
 	}
 
 	for i, tc := range tests {
@@ -442,6 +466,43 @@ func TestScalingGroupUpdatePredicate(t *testing.T) {
 	}
 }
 
+func TestHealthCheckChanges(t *testing.T) {
+	var (
+		g             = gomega.NewGomegaWithT(t)
+		k             = MockKubernetesClientSet()
+		ig            = MockInstanceGroup()
+		configuration = ig.GetEKSConfiguration()
+		asgMock       = NewAutoScalingMocker()
+		iamMock       = NewIamMocker()
+		eksMock       = NewEksMocker()
+		ec2Mock       = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+	ig.GetEKSSpec().MinSize = int64(3)
+	ig.GetEKSSpec().MaxSize = int64(6)
+	configuration.SetSubnets([]string{"subnet-1", "subnet-2", "subnet-3"})
+	configuration.SetHealthCheckType(v1alpha1.ELBHealthCheckType)
+
+	mockScalingGroup := MockScalingGroup("asg-1")
+	mockScalingGroup.HealthCheckType = aws.String("EC2")
+	mockScalingGroup.HealthCheckGracePeriod = aws.Int64(0)
+	ctx.SetDiscoveredState(&DiscoveredState{
+		Publisher: kubeprovider.EventPublisher{
+			Client: k.Kubernetes,
+		},
+		ScalingGroup: mockScalingGroup,
+	})
+
+	changes := ctx.ScalingGroupChanges("some-launch-configuration")
+	g.Expect(changes).To(gomega.ConsistOf("healthCheckType: EC2 -> ELB", "healthCheckGracePeriod: 0 -> 300"))
+
+	mockScalingGroup.HealthCheckType = aws.String("ELB")
+	mockScalingGroup.HealthCheckGracePeriod = aws.Int64(300)
+	g.Expect(ctx.ScalingGroupChanges("some-launch-configuration")).To(gomega.BeEmpty())
+}
+
 func TestUpdateManagedPolicies(t *testing.T) {
 	var (
 		g             = gomega.NewGomegaWithT(t)
@@ -493,3 +554,305 @@ func TestUpdateManagedPolicies(t *testing.T) {
 		g.Expect(iamMock.DetachRolePolicyCallCount).To(gomega.Equal(tc.expectedDetached))
 	}
 }
+
+func TestPendingChanges(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+
+	mockScalingGroup := &autoscaling.Group{
+		AutoScalingGroupName: aws.String("some-scaling-group"),
+		DesiredCapacity:      aws.Int64(1),
+	}
+
+	// scaling group does not exist
+	ctx.SetDiscoveredState(&DiscoveredState{
+		Provisioned: false,
+		ScalingConfiguration: &scaling.LaunchConfiguration{
+			AwsWorker: w,
+		},
+	})
+	g.Expect(ctx.PendingChanges()).To(gomega.ConsistOf(PendingChangeCreate))
+
+	// missing launch config causes drift
+	ctx.SetDiscoveredState(&DiscoveredState{
+		Provisioned:  true,
+		ScalingGroup: mockScalingGroup,
+		ScalingConfiguration: &scaling.LaunchConfiguration{
+			AwsWorker: w,
+		},
+		InstanceProfile: &iam.InstanceProfile{
+			Arn: aws.String("some-instance-arn"),
+		},
+		Cluster: &eks.Cluster{
+			Version: aws.String("1.15"),
+		},
+	})
+	g.Expect(ctx.PendingChanges()).To(gomega.ConsistOf(PendingChangeLaunchConfiguration, PendingChangeScalingGroup, PendingChangeRotation))
+	g.Expect(ctx.GetState()).NotTo(gomega.Equal(v1alpha1.ReconcileModifying))
+}
+
+func TestUpdateScaleInProtection(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+
+	mockScalingGroup := &autoscaling.Group{
+		AutoScalingGroupName: aws.String("some-scaling-group"),
+		Instances: []*autoscaling.Instance{
+			{
+				InstanceId:           aws.String("i-1111"),
+				ProtectedFromScaleIn: aws.Bool(false),
+			},
+			{
+				InstanceId:           aws.String("i-2222"),
+				ProtectedFromScaleIn: aws.Bool(true),
+			},
+			{
+				InstanceId:           aws.String("i-3333"),
+				ProtectedFromScaleIn: aws.Bool(true),
+			},
+			{
+				InstanceId:           aws.String("i-4444"),
+				ProtectedFromScaleIn: aws.Bool(false),
+			},
+			{
+				InstanceId:           aws.String("i-5555"),
+				ProtectedFromScaleIn: aws.Bool(true),
+			},
+		},
+	}
+
+	nodes := &corev1.NodeList{
+		Items: []corev1.Node{
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "node-1",
+					Annotations: map[string]string{kubeprovider.ScaleInProtectionAnnotationKey: "true"},
+				},
+				Spec: corev1.NodeSpec{ProviderID: "aws:///us-west-2a/i-1111"},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "node-2",
+				},
+				Spec: corev1.NodeSpec{ProviderID: "aws:///us-west-2a/i-2222"},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "node-3",
+					Annotations: map[string]string{kubeprovider.ScaleInProtectionAnnotationKey: "true"},
+				},
+				Spec: corev1.NodeSpec{ProviderID: "aws:///us-west-2a/i-3333"},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "node-5",
+				},
+				Spec: corev1.NodeSpec{ProviderID: "aws:///us-west-2a/i-5555"},
+			},
+		},
+	}
+
+	ctx.SetDiscoveredState(&DiscoveredState{
+		ScalingGroup: mockScalingGroup,
+		ClusterNodes: nodes,
+	})
+
+	// i-5555 was protected by another tool, i-9999 was terminated
+	ig.GetStatus().SetScaleInProtectedInstances([]string{"i-2222", "i-3333", "i-9999"})
+
+	protect, unprotect := ctx.ScaleInProtectionUpdateNeeded()
+	g.Expect(protect).To(gomega.ConsistOf("i-1111"))
+	g.Expect(unprotect).To(gomega.ConsistOf("i-2222"))
+
+	err := ctx.UpdateScaleInProtection("some-scaling-group")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(asgMock.SetInstanceProtectionCallCount).To(gomega.Equal(2))
+	g.Expect(ig.GetStatus().GetScaleInProtectedInstances()).To(gomega.Equal([]string{"i-1111", "i-3333"}))
+}
+
+func TestDesiredCapacityPolicy(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		spec    = ig.GetEKSSpec()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+	spec.MinSize = int64(3)
+	spec.MaxSize = int64(6)
+	ig.GetEKSConfiguration().SetSubnets([]string{"subnet-1", "subnet-2", "subnet-3"})
+
+	tests := []struct {
+		policy          string
+		expectedCreate  *int64
+		expectedUpdate  *int64
+		expectedChanged bool
+	}{
+		{policy: v1alpha1.InitialOnlyDesiredCapacityPolicy, expectedCreate: aws.Int64(3), expectedUpdate: nil, expectedChanged: false},
+		{policy: v1alpha1.ManagedDesiredCapacityPolicy, expectedCreate: aws.Int64(3), expectedUpdate: aws.Int64(3), expectedChanged: true},
+		{policy: v1alpha1.IgnoreDesiredCapacityPolicy, expectedCreate: nil, expectedUpdate: nil, expectedChanged: false},
+	}
+
+	for i, tc := range tests {
+		t.Logf("Test #%v - %+v", i, tc)
+		spec.SetDesiredCapacityPolicy(tc.policy)
+		asgMock.CreateAutoScalingGroupInput = nil
+		asgMock.UpdateAutoScalingGroupInput = nil
+
+		ctx.SetDiscoveredState(&DiscoveredState{
+			Publisher: kubeprovider.EventPublisher{
+				Client: k.Kubernetes,
+			},
+		})
+		err := ctx.CreateScalingGroup("some-launch-configuration")
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(asgMock.CreateAutoScalingGroupInput.DesiredCapacity).To(gomega.Equal(tc.expectedCreate))
+
+		// the scaling group was scaled out externally
+		scalingGroup := MockScalingGroup("asg-1")
+		scalingGroup.DesiredCapacity = aws.Int64(5)
+		ctx.SetDiscoveredState(&DiscoveredState{
+			Publisher: kubeprovider.EventPublisher{
+				Client: k.Kubernetes,
+			},
+			ScalingGroup: scalingGroup,
+		})
+		g.Expect(ctx.ScalingGroupUpdateNeeded("some-launch-configuration")).To(gomega.Equal(tc.expectedChanged))
+
+		err = ctx.UpdateScalingGroup("different-launch-configuration")
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(asgMock.UpdateAutoScalingGroupInput).NotTo(gomega.BeNil())
+		g.Expect(asgMock.UpdateAutoScalingGroupInput.DesiredCapacity).To(gomega.Equal(tc.expectedUpdate))
+	}
+}
+
+func TestStrictReconcilePolicy(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		spec    = ig.GetEKSSpec()
+		status  = ig.GetStatus()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+	spec.MinSize = int64(3)
+	spec.MaxSize = int64(6)
+	ig.GetEKSConfiguration().SetSubnets([]string{"subnet-1", "subnet-2", "subnet-3"})
+
+	iamMock.Role = &iam.Role{
+		RoleName: aws.String("some-role"),
+		Arn:      aws.String("some-arn"),
+	}
+	iamMock.InstanceProfile = &iam.InstanceProfile{
+		InstanceProfileName: aws.String("some-profile"),
+	}
+	asgMock.LaunchConfigurations = []*autoscaling.LaunchConfiguration{
+		{LaunchConfigurationName: aws.String("some-launch-configuration")},
+		{LaunchConfigurationName: aws.String("other-launch-configuration")},
+	}
+
+	var (
+		ownershipTag = MockTagDescription(provisioners.TagClusterName, ig.GetEKSConfiguration().GetClusterName())
+		nameTag      = MockTagDescription(provisioners.TagInstanceGroupName, ig.GetName())
+		namespaceTag = MockTagDescription(provisioners.TagInstanceGroupNamespace, ig.GetNamespace())
+	)
+
+	tests := []struct {
+		policy          string
+		launchConfig    string
+		desired         int64
+		minSize         int64
+		maxSize         int64
+		specChanged     bool
+		tags            []*autoscaling.TagDescription
+		expectedChanges []string
+		expectedUpdate  *int64
+	}{
+		{policy: v1alpha1.DefaultReconcilePolicy, launchConfig: "some-launch-configuration", desired: 5, minSize: 3, maxSize: 6, expectedChanges: []string{}, expectedUpdate: nil},
+		{policy: v1alpha1.StrictReconcilePolicy, launchConfig: "some-launch-configuration", desired: 3, minSize: 3, maxSize: 6, expectedChanges: []string{}, expectedUpdate: nil},
+		{policy: v1alpha1.StrictReconcilePolicy, launchConfig: "some-launch-configuration", desired: 5, minSize: 3, maxSize: 6, expectedChanges: []string{"desiredCapacity"}, expectedUpdate: aws.Int64(3)},
+		{policy: v1alpha1.StrictReconcilePolicy, launchConfig: "some-launch-configuration", desired: 5, minSize: 3, maxSize: 10, expectedChanges: []string{"maxSize", "desiredCapacity"}, expectedUpdate: aws.Int64(3)},
+		{policy: v1alpha1.StrictReconcilePolicy, launchConfig: "some-launch-configuration", desired: 2, minSize: 2, maxSize: 6, expectedChanges: []string{"minSize", "desiredCapacity"}, expectedUpdate: aws.Int64(3)},
+		{policy: v1alpha1.StrictReconcilePolicy, launchConfig: "other-launch-configuration", desired: 3, minSize: 3, maxSize: 6, expectedChanges: []string{"launchConfiguration"}, expectedUpdate: aws.Int64(3)},
+		{policy: v1alpha1.StrictReconcilePolicy, launchConfig: "some-launch-configuration", desired: 3, minSize: 3, maxSize: 6, tags: []*autoscaling.TagDescription{MockTagDescription("console-tag", "some-value")}, expectedChanges: []string{"tags"}, expectedUpdate: nil},
+		// the spec changed since it was last applied, the differences are not out of band changes
+		{policy: v1alpha1.StrictReconcilePolicy, launchConfig: "some-launch-configuration", desired: 5, minSize: 3, maxSize: 10, specChanged: true, expectedChanges: []string{}, expectedUpdate: aws.Int64(3)},
+	}
+
+	for i, tc := range tests {
+		t.Logf("Test #%v - %+v", i, tc)
+		ig.Spec.ReconcilePolicy = tc.policy
+		asgMock.UpdateAutoScalingGroupInput = nil
+
+		scalingGroup := MockScalingGroup("asg-1", append([]*autoscaling.TagDescription{ownershipTag, nameTag, namespaceTag}, tc.tags...)...)
+		scalingGroup.LaunchConfigurationName = aws.String(tc.launchConfig)
+		scalingGroup.DesiredCapacity = aws.Int64(tc.desired)
+		scalingGroup.MinSize = aws.Int64(tc.minSize)
+		scalingGroup.MaxSize = aws.Int64(tc.maxSize)
+		asgMock.AutoScalingGroups = []*autoscaling.Group{scalingGroup}
+
+		// the configuration was applied by the previous reconcile
+		status.SetConfigurationHash(ig.HashConfiguration())
+		if tc.specChanged {
+			status.SetConfigurationHash("previous-configuration")
+		}
+
+		err := ctx.CloudDiscovery()
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(ctx.OutOfBandChanges("some-launch-configuration")).To(gomega.Equal(tc.expectedChanges))
+
+		err = ctx.UpdateScalingGroup("some-launch-configuration")
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		if tc.expectedUpdate == nil {
+			if asgMock.UpdateAutoScalingGroupInput != nil {
+				g.Expect(asgMock.UpdateAutoScalingGroupInput.DesiredCapacity).To(gomega.BeNil())
+			}
+			continue
+		}
+		g.Expect(asgMock.UpdateAutoScalingGroupInput).NotTo(gomega.BeNil())
+		g.Expect(asgMock.UpdateAutoScalingGroupInput.DesiredCapacity).To(gomega.Equal(tc.expectedUpdate))
+	}
+
+	// an event noting the reverted fields is published for each reconcile which reverted changes
+	events, err := k.Kubernetes.CoreV1().Events("").List(metav1.ListOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	reverted := make([]string, 0)
+	for _, event := range events.Items {
+		if event.Reason == string(kubeprovider.ChangesRevertedEvent) {
+			reverted = append(reverted, event.Message)
+		}
+	}
+	g.Expect(reverted).To(gomega.HaveLen(5))
+}
@@ -16,13 +16,17 @@ limitations under the License.
 package eks
 
 import (
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
 	"github.com/keikoproj/instance-manager/api/v1alpha1"
 	"github.com/keikoproj/instance-manager/controllers/common"
 	kubeprovider "github.com/keikoproj/instance-manager/controllers/providers/kubernetes"
 	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
@@ -31,16 +35,19 @@ func (ctx *EksInstanceGroupContext) UpgradeNodes() error {
 		instanceGroup = ctx.GetInstanceGroup()
 		strategy      = ctx.GetUpgradeStrategy()
 		state         = ctx.GetDiscoveredState()
+		status        = instanceGroup.GetStatus()
 		strategyType  = strings.ToLower(strategy.GetType())
+		rotation      = ctx.UpdateRotationStatus()
 	)
 
 	// process the upgrade strategy
 	switch strategyType {
 	case kubeprovider.CRDStrategyName:
-		ok, err := kubeprovider.ProcessCRDStrategy(ctx.KubernetesClient.KubeDynamic, instanceGroup)
+		ok, err := kubeprovider.ProcessCRDStrategy(ctx.ClusterKubernetesClient.KubeDynamic, instanceGroup)
 		if err != nil {
 			state.Publisher.Publish(kubeprovider.InstanceGroupUpgradeFailedEvent, "instancegroup", instanceGroup.GetName(), "type", kubeprovider.CRDStrategyName, "error", err.Error())
 			instanceGroup.SetState(v1alpha1.ReconcileErr)
+			rotation.SetLastError(err)
 			return errors.Wrap(err, "failed to process CRD strategy")
 		}
 		if ok {
@@ -49,12 +56,35 @@ func (ctx *EksInstanceGroupContext) UpgradeNodes() error {
 		return nil
 	case kubeprovider.RollingUpdateStrategyName:
 		req := ctx.NewRollingUpdateRequest()
-		ok, err := kubeprovider.ProcessRollingUpgradeStrategy(req)
+
+		// the previous batch must be verified before the next batch is rotated
+		ok, err := ctx.VerifyRotationBatch(req)
+		if err != nil {
+			state.Publisher.Publish(kubeprovider.InstanceGroupUpgradeFailedEvent, "instancegroup", instanceGroup.GetName(), "type", kubeprovider.RollingUpdateStrategyName, "error", err.Error())
+			instanceGroup.SetState(v1alpha1.ReconcileErr)
+			rotation.SetLastError(err)
+			return errors.Wrap(err, "failed to verify rotation batch")
+		}
+		if !ok {
+			return nil
+		}
+
+		if len(req.ExcludedTargets) > 0 {
+			ctx.Log.Info("nodes are excluded from rotation, pending manual replacement", "instancegroup", instanceGroup.GetName(), "nodes", req.ExcludedTargets)
+		}
+		status.SetPendingManualReplacement(req.ExcludedTargets)
+
+		ok, err = kubeprovider.ProcessRollingUpgradeStrategy(req)
 		if err != nil {
-			state.Publisher.Publish(kubeprovider.InstanceGroupUpgradeFailedEvent, "instancegroup", instanceGroup.GetName(), "type", kubeprovider.RollingUpdateStrategyName, "error", err)
+			state.Publisher.Publish(kubeprovider.InstanceGroupUpgradeFailedEvent, "instancegroup", instanceGroup.GetName(), "type", kubeprovider.RollingUpdateStrategyName, "error", err.Error())
 			instanceGroup.SetState(v1alpha1.ReconcileErr)
+			rotation.SetLastError(err)
 			return errors.Wrap(err, "failed to process rolling-update strategy")
 		}
+		if req.Terminated {
+			rotation.SetCurrentBatch(rotation.GetCurrentBatch() + 1)
+			rotation.SetLastBatchTime(&metav1.Time{Time: time.Now()})
+		}
 		if ok {
 			break
 		}
@@ -63,6 +93,7 @@ func (ctx *EksInstanceGroupContext) UpgradeNodes() error {
 		return errors.Errorf("'%v' is not an implemented upgrade type, will not process upgrade", strategy.GetType())
 	}
 	ctx.Log.Info("strategy processing completed", "instancegroup", instanceGroup.GetName(), "strategy", strategy.GetType())
+	status.SetRotation(nil)
 
 	if ctx.UpdateNodeReadyCondition() {
 		instanceGroup.SetState(v1alpha1.ReconcileModified)
@@ -71,6 +102,139 @@ func (ctx *EksInstanceGroupContext) UpgradeNodes() error {
 	return nil
 }
 
+// VerifyRotationBatch runs the verification hook for the last rotated batch once its nodes are ready, it returns true
+// when there is no pending batch to verify
+func (ctx *EksInstanceGroupContext) VerifyRotationBatch(req *kubeprovider.RollingUpdateRequest) (bool, error) {
+	var (
+		instanceGroup = ctx.GetInstanceGroup()
+		rotation      = instanceGroup.GetStatus().GetRotation()
+		batch         = rotation.GetCurrentBatch()
+		strategy      = instanceGroup.GetUpgradeStrategy().GetRollingUpdateType()
+	)
+
+	if strategy.GetVerification() == nil || batch <= rotation.GetVerifiedBatch() {
+		return true, nil
+	}
+
+	ok, err := kubeprovider.IsMinNodesReady(req.ClusterNodes, req.AllInstances, req.MaxUnavailable, req.ReadinessGates)
+	if err != nil {
+		return false, err
+	}
+	if !ok || len(req.AllInstances) < req.DesiredCapacity {
+		ctx.Log.Info("waiting for rotation batch nodes to be ready before verification", "instancegroup", instanceGroup.GetName(), "batch", batch)
+		return false, nil
+	}
+
+	ok, err = kubeprovider.ProcessVerificationHook(ctx.ClusterKubernetesClient.Kubernetes, instanceGroup, batch)
+	if err != nil {
+		return false, errors.Wrapf(err, "verification of rotation batch %v failed", batch)
+	}
+	if !ok {
+		return false, nil
+	}
+
+	ctx.Log.Info("rotation batch verified", "instancegroup", instanceGroup.GetName(), "batch", batch)
+	rotation.SetVerifiedBatch(batch)
+	return true, nil
+}
+
+// UpdateRotationStatus starts tracking the progress of the rotation in status if it has not started, and updates the
+// number of rotated nodes
+func (ctx *EksInstanceGroupContext) UpdateRotationStatus() *v1alpha1.RotationStatus {
+	var (
+		instanceGroup      = ctx.GetInstanceGroup()
+		status             = instanceGroup.GetStatus()
+		scalingGroup       = ctx.GetDiscoveredState().GetScalingGroup()
+		activeLaunchConfig = aws.StringValue(scalingGroup.LaunchConfigurationName)
+		rotation           = status.GetRotation()
+		rotated            int
+	)
+
+	if rotation == nil {
+		rotation = &v1alpha1.RotationStatus{
+			Strategy:  instanceGroup.GetUpgradeStrategy().GetType(),
+			StartedAt: &metav1.Time{Time: time.Now()},
+		}
+		status.SetRotation(rotation)
+		ctx.Log.Info("rotation started", "instancegroup", instanceGroup.GetName(), "strategy", rotation.Strategy)
+	}
+
+	for _, instance := range scalingGroup.Instances {
+		if aws.StringValue(instance.LaunchConfigurationName) == activeLaunchConfig {
+			rotated++
+		}
+	}
+	rotation.SetNodes(len(scalingGroup.Instances), rotated)
+	return rotation
+}
+
+// OrderRotationTargets returns the IDs of the instances in the order they should be rotated. Instances are ordered by
+// the creation time of their nodes, instances without a node are considered oldest. With the ZoneByZone order, only
+// the instances of the first availability zone are returned.
+func (ctx *EksInstanceGroupContext) OrderRotationTargets(instances []*autoscaling.Instance, order string) []string {
+	var (
+		state    = ctx.GetDiscoveredState()
+		nodes    = state.GetClusterNodes()
+		launched = make(map[string]time.Time)
+		targets  = make([]string, 0)
+	)
+
+	if nodes != nil {
+		for _, node := range nodes.Items {
+			instanceId := common.GetLastElementBy(node.Spec.ProviderID, "/")
+			launched[instanceId] = node.GetCreationTimestamp().Time
+		}
+	}
+
+	isOlder := func(i, j int) bool {
+		return launched[aws.StringValue(instances[i].InstanceId)].Before(launched[aws.StringValue(instances[j].InstanceId)])
+	}
+
+	switch {
+	case strings.EqualFold(order, v1alpha1.OldestFirstRotationOrder):
+		sort.SliceStable(instances, isOlder)
+	case strings.EqualFold(order, v1alpha1.ZoneByZoneRotationOrder):
+		sort.SliceStable(instances, func(i, j int) bool {
+			zoneI, zoneJ := aws.StringValue(instances[i].AvailabilityZone), aws.StringValue(instances[j].AvailabilityZone)
+			if zoneI != zoneJ {
+				return zoneI < zoneJ
+			}
+			return isOlder(i, j)
+		})
+	}
+
+	for _, instance := range instances {
+		if strings.EqualFold(order, v1alpha1.ZoneByZoneRotationOrder) &&
+			aws.StringValue(instance.AvailabilityZone) != aws.StringValue(instances[0].AvailabilityZone) {
+			break
+		}
+		targets = append(targets, aws.StringValue(instance.InstanceId))
+	}
+	return targets
+}
+
+// GetRotationExcludedNodes returns the names of nodes which are annotated to be excluded from rotation, by instance ID
+func (ctx *EksInstanceGroupContext) GetRotationExcludedNodes() map[string]string {
+	var (
+		state    = ctx.GetDiscoveredState()
+		nodes    = state.GetClusterNodes()
+		excluded = make(map[string]string)
+	)
+
+	if nodes == nil {
+		return excluded
+	}
+
+	for _, node := range nodes.Items {
+		if !strings.EqualFold(node.GetAnnotations()[v1alpha1.ExcludeFromRotationAnnotationKey], "true") {
+			continue
+		}
+		instanceId := common.GetLastElementBy(node.Spec.ProviderID, "/")
+		excluded[instanceId] = node.GetName()
+	}
+	return excluded
+}
+
 func (ctx *EksInstanceGroupContext) BootstrapNodes() error {
 	var (
 		state         = ctx.GetDiscoveredState()
@@ -84,12 +248,13 @@ func (ctx *EksInstanceGroupContext) BootstrapNodes() error {
 	ctx.Lock()
 	defer ctx.Unlock()
 
-	return common.UpsertAuthConfigMap(ctx.KubernetesClient.Kubernetes, []string{roleARN})
+	return common.UpsertAuthConfigMap(ctx.ClusterKubernetesClient.Kubernetes, []string{roleARN})
 }
 
 func (ctx *EksInstanceGroupContext) NewRollingUpdateRequest() *kubeprovider.RollingUpdateRequest {
 	var (
-		needsUpdate        []string
+		needsUpdate        []*autoscaling.Instance
+		excludedTargets    []string
 		allInstances       []string
 		instanceGroup      = ctx.GetInstanceGroup()
 		state              = ctx.GetDiscoveredState()
@@ -101,11 +266,18 @@ func (ctx *EksInstanceGroupContext) NewRollingUpdateRequest() *kubeprovider.Roll
 		asgName            = aws.StringValue(scalingGroup.AutoScalingGroupName)
 	)
 
+	excluded := ctx.GetRotationExcludedNodes()
+
 	// Get all Autoscaling Instances that needs update
 	for _, instance := range scalingGroup.Instances {
-		allInstances = append(allInstances, aws.StringValue(instance.InstanceId))
+		instanceId := aws.StringValue(instance.InstanceId)
+		allInstances = append(allInstances, instanceId)
 		if aws.StringValue(instance.LaunchConfigurationName) != activeLaunchConfig {
-			needsUpdate = append(needsUpdate, aws.StringValue(instance.InstanceId))
+			if nodeName, ok := excluded[instanceId]; ok {
+				excludedTargets = append(excludedTargets, nodeName)
+				continue
+			}
+			needsUpdate = append(needsUpdate, instance)
 		}
 	}
 	allCount := len(allInstances)
@@ -121,13 +293,24 @@ func (ctx *EksInstanceGroupContext) NewRollingUpdateRequest() *kubeprovider.Roll
 		unavailableInt = 1
 	}
 
+	var lastBatchTime time.Time
+	if rotation := instanceGroup.GetStatus().GetRotation(); rotation != nil && rotation.GetLastBatchTime() != nil {
+		lastBatchTime = rotation.GetLastBatchTime().Time
+	}
+
 	return &kubeprovider.RollingUpdateRequest{
 		AwsWorker:        ctx.AwsWorker,
+		Kubernetes:       ctx.ClusterKubernetesClient.Kubernetes,
+		Drain:            instanceGroup.GetUpgradeStrategy().GetDrain(),
 		ClusterNodes:     state.GetClusterNodes(),
 		MaxUnavailable:   unavailableInt,
 		DesiredCapacity:  desiredCount,
 		AllInstances:     allInstances,
-		UpdateTargets:    needsUpdate,
+		UpdateTargets:    ctx.OrderRotationTargets(needsUpdate, strategy.GetOrder()),
+		ExcludedTargets:  excludedTargets,
 		ScalingGroupName: asgName,
+		ReadinessGates:   strategy.GetReadinessGates(),
+		Cooldown:         time.Duration(strategy.GetCooldownSeconds()) * time.Second,
+		LastBatchTime:    lastBatchTime,
 	}
 }
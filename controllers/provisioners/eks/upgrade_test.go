@@ -16,19 +16,28 @@ limitations under the License.
 package eks
 
 import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/ghodss/yaml"
 	"github.com/keikoproj/instance-manager/api/v1alpha1"
 	kubeprovider "github.com/keikoproj/instance-manager/controllers/providers/kubernetes"
+	"github.com/keikoproj/instance-manager/controllers/provisioners/eks/scaling"
 	"github.com/onsi/gomega"
 	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
 )
 
 func TestUpgradeCRDStrategyValidation(t *testing.T) {
@@ -214,6 +223,82 @@ func TestUpgradeCRDStrategy(t *testing.T) {
 	}
 }
 
+func TestUpgradeCRDStrategyRollingUpgrade(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+		status  = ig.GetStatus()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+	ctx.SetDiscoveredState(&DiscoveredState{
+		Publisher: kubeprovider.EventPublisher{
+			Client: k.Kubernetes,
+		},
+	})
+
+	crd := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "apiextensions.k8s.io/v1beta1",
+			"kind":       "CustomResourceDefinition",
+			"metadata": map[string]interface{}{
+				"name": "rollingupgrades.upgrademgr.keikoproj.io",
+			},
+		},
+	}
+	definitionsGvr := kubeprovider.GetGVR(crd, "customresourcedefinitions")
+	_, err := k.KubeDynamic.Resource(definitionsGvr).Create(crd, metav1.CreateOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	maxUnavailable := intstr.FromString("25%")
+	ig.SetUpgradeStrategy(v1alpha1.AwsUpgradeStrategy{
+		Type: kubeprovider.CRDStrategyName,
+		CRDType: &v1alpha1.CRDUpdateStrategy{
+			RollingUpgrade: &v1alpha1.RollingUpgradeSpec{
+				NodeIntervalSeconds: 300,
+				IgnoreDrainFailures: true,
+				PostDrain:           &v1alpha1.RollingUpgradePostDrain{Script: "echo drained", WaitSeconds: 30},
+				Strategy: &v1alpha1.RollingUpgradeStrategySpec{
+					Type:           "uniformAcrossAzUpdate",
+					MaxUnavailable: &maxUnavailable,
+					DrainTimeout:   600,
+				},
+			},
+		},
+	})
+	status.SetActiveScalingGroupName("some-scaling-group")
+	status.SetActiveLaunchConfigurationName("some-launch-config-123456")
+	g.Expect(ig.GetUpgradeStrategy().GetCRDType().Validate()).To(gomega.Succeed())
+
+	ig.SetState(v1alpha1.ReconcileModifying)
+	g.Expect(ctx.UpgradeNodes()).To(gomega.Succeed())
+
+	gvr := schema.GroupVersionResource{Group: "upgrademgr.keikoproj.io", Version: "v1alpha1", Resource: v1alpha1.RollingUpgradeCRDName}
+	rollingUpgrade, err := k.KubeDynamic.Resource(gvr).Namespace(ig.GetNamespace()).Get(fmt.Sprintf("%v-123456", ig.GetName()), metav1.GetOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	spec, _, _ := unstructured.NestedMap(rollingUpgrade.Object, "spec")
+	g.Expect(spec).To(gomega.HaveKeyWithValue("asgName", "some-scaling-group"))
+	g.Expect(spec).To(gomega.HaveKeyWithValue("nodeIntervalSeconds", gomega.BeNumerically("==", 300)))
+	g.Expect(spec).To(gomega.HaveKeyWithValue("ignoreDrainFailures", true))
+	g.Expect(spec["postDrain"]).To(gomega.HaveKeyWithValue("script", "echo drained"))
+	g.Expect(spec["strategy"]).To(gomega.HaveKeyWithValue("maxUnavailable", "25%"))
+	g.Expect(spec["strategy"]).To(gomega.HaveKeyWithValue("drainTimeout", gomega.BeNumerically("==", 600)))
+
+	// the status of the RollingUpgrade is watched
+	unstructured.SetNestedField(rollingUpgrade.Object, v1alpha1.RollingUpgradeStatusSuccess, "status", "currentStatus")
+	_, err = k.KubeDynamic.Resource(gvr).Namespace(ig.GetNamespace()).Update(rollingUpgrade, metav1.UpdateOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(ctx.UpgradeNodes()).To(gomega.Succeed())
+	g.Expect(status.GetRotation()).To(gomega.BeNil())
+}
+
 func TestUpgradeRollingUpdateStrategyPositive(t *testing.T) {
 	var (
 		g       = gomega.NewGomegaWithT(t)
@@ -296,3 +381,706 @@ func TestUpgradeRollingUpdateStrategyPositive(t *testing.T) {
 		g.Expect(ctx.GetState()).To(gomega.Equal(tc.expectedState))
 	}
 }
+
+func TestUpgradeRollingUpdateReadinessGates(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+
+	var (
+		gateType         = corev1.NodeConditionType("example.com/NetworkReady")
+		maxUnavailable   = intstr.FromInt(1)
+		scalingInstances = MockScalingInstances(1, 2)
+	)
+
+	tests := []struct {
+		gateStatus      corev1.ConditionStatus
+		shouldTerminate bool
+	}{
+		{gateStatus: "", shouldTerminate: false},
+		{gateStatus: corev1.ConditionFalse, shouldTerminate: false},
+		{gateStatus: corev1.ConditionTrue, shouldTerminate: true},
+	}
+
+	for i, tc := range tests {
+		t.Logf("#%v - %+v", i, tc)
+		asgMock.TerminateInstanceCallCount = 0
+
+		nodes := &corev1.NodeList{}
+		for _, instance := range scalingInstances {
+			node := MockNode(aws.StringValue(instance.InstanceId), corev1.ConditionTrue)
+			if tc.gateStatus != "" {
+				node.Status.Conditions = append(node.Status.Conditions, corev1.NodeCondition{Type: gateType, Status: tc.gateStatus})
+			}
+			nodes.Items = append(nodes.Items, *node)
+		}
+
+		strategy := MockAwsRollingUpdateStrategy(&maxUnavailable)
+		strategy.RollingUpdateType.ReadinessGates = []v1alpha1.NodeReadinessGate{{Type: gateType, Status: corev1.ConditionTrue}}
+		ig.SetUpgradeStrategy(strategy)
+		ctx.SetDiscoveredState(&DiscoveredState{
+			Publisher: kubeprovider.EventPublisher{
+				Client: k.Kubernetes,
+			},
+			ScalingGroup: &autoscaling.Group{
+				LaunchConfigurationName: aws.String("some-launch-config"),
+				AutoScalingGroupName:    aws.String("some-scaling-group"),
+				Instances:               scalingInstances,
+				DesiredCapacity:         aws.Int64(int64(len(scalingInstances))),
+			},
+			ClusterNodes: nodes,
+		})
+
+		ig.SetState(v1alpha1.ReconcileModifying)
+		g.Expect(ctx.UpgradeNodes()).To(gomega.Succeed())
+		g.Expect(ctx.GetState()).To(gomega.Equal(v1alpha1.ReconcileModifying))
+		g.Expect(asgMock.TerminateInstanceCallCount > 0).To(gomega.Equal(tc.shouldTerminate))
+	}
+}
+
+func TestUpgradeRollingUpdateVerificationJob(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+
+	var (
+		maxUnavailable   = intstr.FromInt(1)
+		scalingInstances = MockScalingInstances(1, 2)
+		status           = ig.GetStatus()
+		nodes            = &corev1.NodeList{}
+		jobName          = kubeprovider.GetVerificationJobName(ig.GetName(), "123456", 1)
+	)
+
+	for _, instance := range scalingInstances {
+		nodes.Items = append(nodes.Items, *MockNode(aws.StringValue(instance.InstanceId), corev1.ConditionTrue))
+	}
+
+	strategy := MockAwsRollingUpdateStrategy(&maxUnavailable)
+	strategy.RollingUpdateType.Verification = &v1alpha1.VerificationHook{
+		Job: "apiVersion: batch/v1\nkind: Job\nmetadata:\n  name: verify\nspec:\n  template:\n    spec:\n      restartPolicy: Never\n      containers:\n      - name: verify\n        image: busybox\n",
+	}
+	ig.SetUpgradeStrategy(strategy)
+	status.SetActiveLaunchConfigurationName("some-launch-config-123456")
+	ctx.SetDiscoveredState(&DiscoveredState{
+		Publisher: kubeprovider.EventPublisher{
+			Client: k.Kubernetes,
+		},
+		ScalingGroup: &autoscaling.Group{
+			LaunchConfigurationName: aws.String("some-launch-config"),
+			AutoScalingGroupName:    aws.String("some-scaling-group"),
+			Instances:               scalingInstances,
+			DesiredCapacity:         aws.Int64(int64(len(scalingInstances))),
+		},
+		ClusterNodes: nodes,
+	})
+
+	// first batch is rotated without verification
+	g.Expect(ctx.UpgradeNodes()).To(gomega.Succeed())
+	g.Expect(asgMock.TerminateInstanceCallCount).To(gomega.Equal(1))
+	g.Expect(status.GetRotation().GetCurrentBatch()).To(gomega.Equal(1))
+
+	// verification job is submitted and the next batch waits
+	asgMock.TerminateInstanceCallCount = 0
+	g.Expect(ctx.UpgradeNodes()).To(gomega.Succeed())
+	g.Expect(asgMock.TerminateInstanceCallCount).To(gomega.Equal(0))
+	job, err := k.Kubernetes.BatchV1().Jobs(ig.GetNamespace()).Get(jobName, metav1.GetOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(job.GetAnnotations()).To(gomega.HaveKeyWithValue(kubeprovider.VerificationBatchAnnotationKey, "1"))
+
+	// failed verification halts the rotation
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}}
+	_, err = k.Kubernetes.BatchV1().Jobs(ig.GetNamespace()).UpdateStatus(job)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(ctx.UpgradeNodes()).NotTo(gomega.Succeed())
+	g.Expect(ctx.GetState()).To(gomega.Equal(v1alpha1.ReconcileErr))
+	g.Expect(asgMock.TerminateInstanceCallCount).To(gomega.Equal(0))
+
+	// successful verification continues the rotation
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+	_, err = k.Kubernetes.BatchV1().Jobs(ig.GetNamespace()).UpdateStatus(job)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(ctx.UpgradeNodes()).To(gomega.Succeed())
+	g.Expect(asgMock.TerminateInstanceCallCount).To(gomega.Equal(1))
+	g.Expect(status.GetRotation().GetVerifiedBatch()).To(gomega.Equal(1))
+	g.Expect(status.GetRotation().GetCurrentBatch()).To(gomega.Equal(2))
+}
+
+func TestUpgradeRollingUpdateVerificationURL(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+
+	var (
+		maxUnavailable   = intstr.FromInt(1)
+		scalingInstances = MockScalingInstances(1, 2)
+		status           = ig.GetStatus()
+		nodes            = &corev1.NodeList{}
+		responseCode     int
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(responseCode)
+	}))
+	defer server.Close()
+
+	for _, instance := range scalingInstances {
+		nodes.Items = append(nodes.Items, *MockNode(aws.StringValue(instance.InstanceId), corev1.ConditionTrue))
+	}
+
+	strategy := MockAwsRollingUpdateStrategy(&maxUnavailable)
+	strategy.RollingUpdateType.Verification = &v1alpha1.VerificationHook{
+		URL: server.URL,
+	}
+	ig.SetUpgradeStrategy(strategy)
+
+	tests := []struct {
+		responseCode    int
+		shouldTerminate bool
+		expectedState   v1alpha1.ReconcileState
+	}{
+		{responseCode: http.StatusInternalServerError, shouldTerminate: false, expectedState: v1alpha1.ReconcileErr},
+		{responseCode: http.StatusOK, shouldTerminate: true, expectedState: v1alpha1.ReconcileModifying},
+	}
+
+	for i, tc := range tests {
+		t.Logf("#%v - %+v", i, tc)
+		asgMock.TerminateInstanceCallCount = 0
+		responseCode = tc.responseCode
+		status.SetRotation(&v1alpha1.RotationStatus{CurrentBatch: 1})
+		ctx.SetDiscoveredState(&DiscoveredState{
+			Publisher: kubeprovider.EventPublisher{
+				Client: k.Kubernetes,
+			},
+			ScalingGroup: &autoscaling.Group{
+				LaunchConfigurationName: aws.String("some-launch-config"),
+				AutoScalingGroupName:    aws.String("some-scaling-group"),
+				Instances:               scalingInstances,
+				DesiredCapacity:         aws.Int64(int64(len(scalingInstances))),
+			},
+			ClusterNodes: nodes,
+		})
+
+		ig.SetState(v1alpha1.ReconcileModifying)
+		err := ctx.UpgradeNodes()
+		g.Expect(err != nil).To(gomega.Equal(!tc.shouldTerminate))
+		g.Expect(ctx.GetState()).To(gomega.Equal(tc.expectedState))
+		g.Expect(asgMock.TerminateInstanceCallCount > 0).To(gomega.Equal(tc.shouldTerminate))
+	}
+}
+
+func TestUpgradeRollingUpdatePreDrainHook(t *testing.T) {
+	var (
+		g          = gomega.NewGomegaWithT(t)
+		ig         = MockInstanceGroup()
+		asgMock    = NewAutoScalingMocker()
+		iamMock    = NewIamMocker()
+		eksMock    = NewEksMocker()
+		ec2Mock    = NewEc2Mocker()
+		targetNode = "node-i-100000000"
+		gateType   = corev1.NodeConditionType("example.com/CacheFlushed")
+		requests   int
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	maxUnavailable := intstr.FromInt(1)
+	scalingInstances := MockScalingInstances(1, 2)
+
+	reconcile := func(ctx *EksInstanceGroupContext, k kubeprovider.KubernetesClientSet) {
+		nodes, err := k.Kubernetes.CoreV1().Nodes().List(metav1.ListOptions{})
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		ctx.SetDiscoveredState(&DiscoveredState{
+			Publisher: kubeprovider.EventPublisher{
+				Client: k.Kubernetes,
+			},
+			ScalingGroup: &autoscaling.Group{
+				LaunchConfigurationName: aws.String("some-launch-config"),
+				AutoScalingGroupName:    aws.String("some-scaling-group"),
+				Instances:               scalingInstances,
+				DesiredCapacity:         aws.Int64(int64(len(scalingInstances))),
+			},
+			ClusterNodes: nodes,
+		})
+		g.Expect(ctx.UpgradeNodes()).To(gomega.Succeed())
+	}
+
+	setup := func(hook *v1alpha1.PreDrainHook) (*EksInstanceGroupContext, kubeprovider.KubernetesClientSet) {
+		k := MockKubernetesClientSet()
+		for _, instance := range scalingInstances {
+			k.Kubernetes.CoreV1().Nodes().Create(MockNode(aws.StringValue(instance.InstanceId), corev1.ConditionTrue))
+		}
+		ig.SetUpgradeStrategy(MockAwsRollingUpdateStrategy(&maxUnavailable))
+		ig.GetUpgradeStrategy().SetDrain(&v1alpha1.DrainSpec{PreDrain: hook})
+		asgMock.TerminateInstanceCallCount = 0
+		return MockContext(ig, k, w), k
+	}
+
+	// annotation hook waits until the annotation is removed
+	ctx, k := setup(&v1alpha1.PreDrainHook{Annotation: "example.com/flush-cache"})
+	reconcile(ctx, k)
+	node, err := k.Kubernetes.CoreV1().Nodes().Get(targetNode, metav1.GetOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(node.GetAnnotations()).To(gomega.HaveKeyWithValue("example.com/flush-cache", kubeprovider.PreDrainRequestedAnnotationValue))
+	g.Expect(node.Spec.Unschedulable).To(gomega.BeFalse())
+
+	reconcile(ctx, k)
+	g.Expect(asgMock.TerminateInstanceCallCount).To(gomega.Equal(0))
+
+	delete(node.Annotations, "example.com/flush-cache")
+	_, err = k.Kubernetes.CoreV1().Nodes().Update(node)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	reconcile(ctx, k)
+	g.Expect(asgMock.TerminateInstanceCallCount).To(gomega.Equal(1))
+	node, err = k.Kubernetes.CoreV1().Nodes().Get(targetNode, metav1.GetOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(node.Spec.Unschedulable).To(gomega.BeTrue())
+
+	// url hook completes on a successful response
+	ctx, k = setup(&v1alpha1.PreDrainHook{URL: server.URL})
+	reconcile(ctx, k)
+	g.Expect(requests).To(gomega.Equal(1))
+	g.Expect(asgMock.TerminateInstanceCallCount).To(gomega.Equal(1))
+
+	// condition hook waits for the node condition
+	ctx, k = setup(&v1alpha1.PreDrainHook{Condition: &v1alpha1.NodeReadinessGate{Type: gateType, Status: corev1.ConditionTrue}})
+	reconcile(ctx, k)
+	g.Expect(asgMock.TerminateInstanceCallCount).To(gomega.Equal(0))
+
+	node, err = k.Kubernetes.CoreV1().Nodes().Get(targetNode, metav1.GetOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	node.Status.Conditions = append(node.Status.Conditions, corev1.NodeCondition{Type: gateType, Status: corev1.ConditionTrue})
+	_, err = k.Kubernetes.CoreV1().Nodes().Update(node)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	reconcile(ctx, k)
+	g.Expect(asgMock.TerminateInstanceCallCount).To(gomega.Equal(1))
+
+	// hook times out and the node is drained
+	ctx, k = setup(&v1alpha1.PreDrainHook{Annotation: "example.com/flush-cache", TimeoutSeconds: 60})
+	node, err = k.Kubernetes.CoreV1().Nodes().Get(targetNode, metav1.GetOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	node.Annotations = map[string]string{
+		kubeprovider.PreDrainStartedAnnotationKey: time.Now().Add(-time.Hour).UTC().Format(time.RFC3339),
+		"example.com/flush-cache":                 kubeprovider.PreDrainRequestedAnnotationValue,
+	}
+	_, err = k.Kubernetes.CoreV1().Nodes().Update(node)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	reconcile(ctx, k)
+	g.Expect(asgMock.TerminateInstanceCallCount).To(gomega.Equal(1))
+}
+
+func TestUpgradeRollingUpdateExcludedNodes(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+
+	var (
+		maxUnavailable   = intstr.FromInt(1)
+		scalingInstances = MockScalingInstances(1, 1)
+		status           = ig.GetStatus()
+		nodes            = &corev1.NodeList{}
+	)
+
+	for _, instance := range scalingInstances {
+		node := MockNode(aws.StringValue(instance.InstanceId), corev1.ConditionTrue)
+		if aws.StringValue(instance.InstanceId) == "i-100000000" {
+			node.SetAnnotations(map[string]string{v1alpha1.ExcludeFromRotationAnnotationKey: "true"})
+		}
+		nodes.Items = append(nodes.Items, *node)
+	}
+
+	ig.SetUpgradeStrategy(MockAwsRollingUpdateStrategy(&maxUnavailable))
+	ctx.SetDiscoveredState(&DiscoveredState{
+		Publisher: kubeprovider.EventPublisher{
+			Client: k.Kubernetes,
+		},
+		ScalingGroup: &autoscaling.Group{
+			LaunchConfigurationName: aws.String("some-launch-config"),
+			AutoScalingGroupName:    aws.String("some-scaling-group"),
+			Instances:               scalingInstances,
+			DesiredCapacity:         aws.Int64(int64(len(scalingInstances))),
+		},
+		ScalingConfiguration: &scaling.LaunchConfiguration{
+			TargetResource: &autoscaling.LaunchConfiguration{
+				LaunchConfigurationName: aws.String("some-launch-config"),
+			},
+		},
+		ClusterNodes: nodes,
+	})
+
+	// the only updatable instance is excluded, rotation completes without terminating it
+	ig.SetState(v1alpha1.ReconcileInitUpgrade)
+	g.Expect(ctx.UpgradeNodes()).To(gomega.Succeed())
+	g.Expect(asgMock.TerminateInstanceCallCount).To(gomega.Equal(0))
+	g.Expect(ctx.GetState()).To(gomega.Equal(v1alpha1.ReconcileModified))
+	g.Expect(status.GetPendingManualReplacement()).To(gomega.ConsistOf("node-i-100000000"))
+
+	// a new rotation is not started for the excluded instance, which remains pending manual replacement
+	g.Expect(ctx.RotationNeeded()).To(gomega.BeFalse())
+	g.Expect(ctx.ManualReplacementNeeded()).To(gomega.ConsistOf("node-i-100000000"))
+
+	// once the annotation is removed the instance is rotated
+	nodes.Items[1].SetAnnotations(nil)
+	g.Expect(ctx.RotationNeeded()).To(gomega.BeTrue())
+	g.Expect(ctx.ManualReplacementNeeded()).To(gomega.BeEmpty())
+}
+
+func TestUpgradeRollingUpdateOrder(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+
+	var (
+		now       = time.Now()
+		instances = []struct {
+			id   string
+			zone string
+			age  time.Duration
+		}{
+			{id: "i-100000000", zone: "us-west-2b", age: time.Hour},
+			{id: "i-100000001", zone: "us-west-2a", age: time.Minute},
+			{id: "i-100000002", zone: "us-west-2b", age: 2 * time.Hour},
+			{id: "i-100000003", zone: "us-west-2a", age: 3 * time.Hour},
+		}
+		nodes = &corev1.NodeList{}
+	)
+
+	newTargets := func() []*autoscaling.Instance {
+		targets := make([]*autoscaling.Instance, 0)
+		for _, i := range instances {
+			targets = append(targets, &autoscaling.Instance{
+				InstanceId:       aws.String(i.id),
+				AvailabilityZone: aws.String(i.zone),
+			})
+		}
+		return targets
+	}
+
+	for _, i := range instances {
+		node := MockNode(i.id, corev1.ConditionTrue)
+		node.SetCreationTimestamp(metav1.NewTime(now.Add(-i.age)))
+		nodes.Items = append(nodes.Items, *node)
+	}
+	ctx.SetDiscoveredState(&DiscoveredState{
+		ClusterNodes: nodes,
+	})
+
+	tests := []struct {
+		order    string
+		expected []string
+	}{
+		{order: "", expected: []string{"i-100000000", "i-100000001", "i-100000002", "i-100000003"}},
+		{order: v1alpha1.OldestFirstRotationOrder, expected: []string{"i-100000003", "i-100000002", "i-100000000", "i-100000001"}},
+		{order: v1alpha1.ZoneByZoneRotationOrder, expected: []string{"i-100000003", "i-100000001"}},
+	}
+
+	for i, tc := range tests {
+		t.Logf("#%v - %+v", i, tc)
+		g.Expect(ctx.OrderRotationTargets(newTargets(), tc.order)).To(gomega.Equal(tc.expected))
+	}
+}
+
+func TestUpgradeRollingUpdateCooldown(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+
+	var (
+		maxUnavailable   = intstr.FromInt(1)
+		scalingInstances = MockScalingInstances(1, 2)
+		status           = ig.GetStatus()
+		nodes            = &corev1.NodeList{}
+	)
+
+	for _, instance := range scalingInstances {
+		nodes.Items = append(nodes.Items, *MockNode(aws.StringValue(instance.InstanceId), corev1.ConditionTrue))
+	}
+
+	strategy := MockAwsRollingUpdateStrategy(&maxUnavailable)
+	strategy.RollingUpdateType.CooldownSeconds = 300
+	ig.SetUpgradeStrategy(strategy)
+
+	tests := []struct {
+		lastBatchTime   *metav1.Time
+		shouldTerminate bool
+	}{
+		{lastBatchTime: nil, shouldTerminate: true},
+		{lastBatchTime: &metav1.Time{Time: time.Now().Add(-time.Minute)}, shouldTerminate: false},
+		{lastBatchTime: &metav1.Time{Time: time.Now().Add(-time.Hour)}, shouldTerminate: true},
+	}
+
+	for i, tc := range tests {
+		t.Logf("#%v - %+v", i, tc)
+		asgMock.TerminateInstanceCallCount = 0
+		status.SetRotation(&v1alpha1.RotationStatus{LastBatchTime: tc.lastBatchTime})
+		ctx.SetDiscoveredState(&DiscoveredState{
+			Publisher: kubeprovider.EventPublisher{
+				Client: k.Kubernetes,
+			},
+			ScalingGroup: &autoscaling.Group{
+				LaunchConfigurationName: aws.String("some-launch-config"),
+				AutoScalingGroupName:    aws.String("some-scaling-group"),
+				Instances:               scalingInstances,
+				DesiredCapacity:         aws.Int64(int64(len(scalingInstances))),
+			},
+			ClusterNodes: nodes,
+		})
+
+		g.Expect(ctx.UpgradeNodes()).To(gomega.Succeed())
+		g.Expect(asgMock.TerminateInstanceCallCount > 0).To(gomega.Equal(tc.shouldTerminate))
+		if tc.shouldTerminate {
+			g.Expect(status.GetRotation().GetLastBatchTime()).NotTo(gomega.BeNil())
+		}
+	}
+}
+
+func TestUpgradeRotationStatus(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+
+	var (
+		maxUnavailable = intstr.FromInt(1)
+		status         = ig.GetStatus()
+	)
+
+	ig.SetUpgradeStrategy(MockAwsRollingUpdateStrategy(&maxUnavailable))
+
+	setInstances := func(scalingInstances []*autoscaling.Instance) {
+		nodes := &corev1.NodeList{}
+		for _, instance := range scalingInstances {
+			nodes.Items = append(nodes.Items, *MockNode(aws.StringValue(instance.InstanceId), corev1.ConditionTrue))
+		}
+		ctx.SetDiscoveredState(&DiscoveredState{
+			Publisher: kubeprovider.EventPublisher{
+				Client: k.Kubernetes,
+			},
+			ScalingGroup: &autoscaling.Group{
+				LaunchConfigurationName: aws.String("some-launch-config"),
+				AutoScalingGroupName:    aws.String("some-scaling-group"),
+				Instances:               scalingInstances,
+				DesiredCapacity:         aws.Int64(int64(len(scalingInstances))),
+			},
+			ClusterNodes: nodes,
+		})
+	}
+
+	// rotation progress is published while targets are rotated
+	setInstances(MockScalingInstances(1, 2))
+	g.Expect(ctx.UpgradeNodes()).To(gomega.Succeed())
+	rotation := status.GetRotation()
+	g.Expect(rotation).NotTo(gomega.BeNil())
+	g.Expect(rotation.Strategy).To(gomega.Equal(kubeprovider.RollingUpdateStrategyName))
+	g.Expect(rotation.StartedAt).NotTo(gomega.BeNil())
+	g.Expect(rotation.TotalNodes).To(gomega.Equal(3))
+	g.Expect(rotation.RotatedNodes).To(gomega.Equal(1))
+	g.Expect(rotation.GetCurrentBatch()).To(gomega.Equal(1))
+	startedAt := rotation.StartedAt
+
+	// errors are recorded
+	asgMock.TerminateInstanceCallCount = 0
+	ig.GetUpgradeStrategy().SetDrain(&v1alpha1.DrainSpec{SkipDaemonSets: aws.Bool(false)})
+	k.Kubernetes.CoreV1().Pods("default").Create(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "daemonset-pod",
+			Namespace: "default",
+			OwnerReferences: []metav1.OwnerReference{
+				{Kind: kubeprovider.DaemonSetKind, Name: "daemonset", Controller: aws.Bool(true)},
+			},
+		},
+		Spec: corev1.PodSpec{
+			NodeName: "node-i-100000000",
+		},
+	})
+	setInstances(MockScalingInstances(2, 1))
+	g.Expect(ctx.UpgradeNodes()).NotTo(gomega.Succeed())
+	g.Expect(status.GetRotation().StartedAt).To(gomega.Equal(startedAt))
+	g.Expect(status.GetRotation().RotatedNodes).To(gomega.Equal(2))
+	g.Expect(status.GetRotation().GetLastError()).NotTo(gomega.BeEmpty())
+
+	// rotation status is cleared on completion
+	setInstances(MockScalingInstances(3, 0))
+	g.Expect(ctx.UpgradeNodes()).To(gomega.Succeed())
+	g.Expect(status.GetRotation()).To(gomega.BeNil())
+}
+
+func TestBootstrapNodesMappedRole(t *testing.T) {
+	var (
+		g       = gomega.NewGomegaWithT(t)
+		k       = MockKubernetesClientSet()
+		ig      = MockInstanceGroup()
+		asgMock = NewAutoScalingMocker()
+		iamMock = NewIamMocker()
+		eksMock = NewEksMocker()
+		ec2Mock = NewEc2Mocker()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+	ctx.SetDiscoveredState(&DiscoveredState{
+		Publisher: kubeprovider.EventPublisher{
+			Client: k.Kubernetes,
+		},
+		IAMRole: &iam.Role{
+			Arn: aws.String("some-role"),
+		},
+	})
+
+	updates := func() int {
+		var count int
+		for _, action := range k.Kubernetes.(*fake.Clientset).Actions() {
+			if action.Matches("update", "configmaps") {
+				count++
+			}
+		}
+		return count
+	}
+
+	// the role is mapped once, aws-auth is not updated while it remains mapped
+	g.Expect(ctx.BootstrapNodes()).To(gomega.Succeed())
+	g.Expect(updates()).To(gomega.Equal(1))
+	g.Expect(ctx.BootstrapNodes()).To(gomega.Succeed())
+	g.Expect(updates()).To(gomega.Equal(1))
+}
+
+func TestUpgradeRollingUpdateForceDrain(t *testing.T) {
+	var (
+		g          = gomega.NewGomegaWithT(t)
+		k          = MockKubernetesClientSet()
+		ig         = MockInstanceGroup()
+		asgMock    = NewAutoScalingMocker()
+		iamMock    = NewIamMocker()
+		eksMock    = NewEksMocker()
+		ec2Mock    = NewEc2Mocker()
+		targetNode = "node-i-100000000"
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+	maxUnavailable := intstr.FromInt(1)
+	scalingInstances := MockScalingInstances(1, 2)
+	for _, instance := range scalingInstances {
+		k.Kubernetes.CoreV1().Nodes().Create(MockNode(aws.StringValue(instance.InstanceId), corev1.ConditionTrue))
+	}
+	ig.SetUpgradeStrategy(MockAwsRollingUpdateStrategy(&maxUnavailable))
+	ig.GetUpgradeStrategy().SetDrain(&v1alpha1.DrainSpec{TimeoutSeconds: 300, Force: true})
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod-1", Namespace: "default"},
+		Spec:       corev1.PodSpec{NodeName: targetNode},
+	}
+
+	reconcile := func(drainStarted time.Time) {
+		node, err := k.Kubernetes.CoreV1().Nodes().Get(targetNode, metav1.GetOptions{})
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		node.SetAnnotations(map[string]string{kubeprovider.DrainStartedAnnotationKey: drainStarted.UTC().Format(time.RFC3339)})
+		_, err = k.Kubernetes.CoreV1().Nodes().Update(node)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+
+		nodes, err := k.Kubernetes.CoreV1().Nodes().List(metav1.ListOptions{})
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		ctx.SetDiscoveredState(&DiscoveredState{
+			Publisher: kubeprovider.EventPublisher{
+				Client: k.Kubernetes,
+			},
+			ScalingGroup: &autoscaling.Group{
+				LaunchConfigurationName: aws.String("some-launch-config"),
+				AutoScalingGroupName:    aws.String("some-scaling-group"),
+				Instances:               scalingInstances,
+				DesiredCapacity:         aws.Int64(int64(len(scalingInstances))),
+			},
+			ClusterNodes: nodes,
+		})
+		asgMock.TerminateInstanceCallCount = 0
+		g.Expect(ctx.UpgradeNodes()).To(gomega.Succeed())
+	}
+
+	// pods are deleted by force once the drain timeout expired, the instance is not terminated while they terminate
+	_, err := k.Kubernetes.CoreV1().Pods("default").Create(pod)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	reconcile(time.Now().Add(-6 * time.Minute))
+	g.Expect(asgMock.TerminateInstanceCallCount).To(gomega.Equal(0))
+	_, err = k.Kubernetes.CoreV1().Pods("default").Get(pod.GetName(), metav1.GetOptions{})
+	g.Expect(err).To(gomega.HaveOccurred())
+
+	reconcile(time.Now().Add(-6 * time.Minute))
+	g.Expect(asgMock.TerminateInstanceCallCount).To(gomega.Equal(1))
+
+	// pods which do not terminate are not waited for once the drain timeout expired again
+	terminating := pod.DeepCopy()
+	terminating.SetDeletionTimestamp(&metav1.Time{Time: time.Now().Add(-time.Minute)})
+	_, err = k.Kubernetes.CoreV1().Pods("default").Create(terminating)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	reconcile(time.Now().Add(-6 * time.Minute))
+	g.Expect(asgMock.TerminateInstanceCallCount).To(gomega.Equal(0))
+
+	reconcile(time.Now().Add(-11 * time.Minute))
+	g.Expect(asgMock.TerminateInstanceCallCount).To(gomega.Equal(1))
+}
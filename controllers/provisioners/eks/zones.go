@@ -0,0 +1,133 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eks
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/keikoproj/instance-manager/api/v1alpha1"
+	kubeprovider "github.com/keikoproj/instance-manager/controllers/providers/kubernetes"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// the scaling group does not rebalance instances since the AZRebalance process is suspended
+	AZRebalanceSuspendedReason = "AZRebalanceSuspended"
+	// the scaling group has not rebalanced instances, e.g. since launches keep failing with insufficient capacity
+	InstancesImbalancedReason = "InstancesImbalanced"
+)
+
+// DiscoverZoneDistribution sets the number of the scaling group's instances in each zone of its subnets in the
+// status, and a ZonesImbalanced condition when the difference between the zones with the most and the fewest instances
+// has been larger than the max skew for longer than the threshold
+func (ctx *EksInstanceGroupContext) DiscoverZoneDistribution(now time.Time) {
+	var (
+		instanceGroup = ctx.GetInstanceGroup()
+		configuration = instanceGroup.GetEKSConfiguration()
+		spec          = configuration.GetZoneImbalance()
+		status        = instanceGroup.GetStatus()
+		state         = ctx.GetDiscoveredState()
+		scalingGroup  = state.GetScalingGroup()
+		instances     = make(map[string]int)
+	)
+
+	if !state.IsProvisioned() {
+		status.SetZoneDistribution(nil)
+		status.SetZoneImbalancedSince(nil)
+		status.RemoveCondition(v1alpha1.ZonesImbalanced)
+		return
+	}
+
+	// zones without instances are counted, they are the zones the scaling group should rebalance instances into
+	for _, placement := range state.GetSubnetPlacements() {
+		if placement.Zone != "" {
+			instances[placement.Zone] = 0
+		}
+	}
+	for _, instance := range scalingGroup.Instances {
+		// instances which are terminating no longer count towards the distribution
+		if strings.HasPrefix(aws.StringValue(instance.LifecycleState), "Terminat") {
+			continue
+		}
+		instances[aws.StringValue(instance.AvailabilityZone)]++
+	}
+
+	distribution := make([]v1alpha1.ZoneCapacity, 0, len(instances))
+	for zone, count := range instances {
+		distribution = append(distribution, v1alpha1.ZoneCapacity{Zone: zone, Instances: count})
+	}
+	sort.Slice(distribution, func(i, j int) bool {
+		return distribution[i].Zone < distribution[j].Zone
+	})
+	status.SetZoneDistribution(distribution)
+
+	if len(distribution) < 2 || zoneSkew(distribution) <= spec.GetMaxSkew() {
+		status.SetZoneImbalancedSince(nil)
+		status.RemoveCondition(v1alpha1.ZonesImbalanced)
+		return
+	}
+
+	since := status.GetZoneImbalancedSince()
+	if since == nil {
+		since = &metav1.Time{Time: now}
+		status.SetZoneImbalancedSince(since)
+	}
+	if now.Sub(since.Time) < spec.GetThreshold() {
+		return
+	}
+
+	reason := InstancesImbalancedReason
+	for _, process := range scalingGroup.SuspendedProcesses {
+		if aws.StringValue(process.ProcessName) == v1alpha1.AZRebalanceProcess {
+			reason = AZRebalanceSuspendedReason
+		}
+	}
+
+	counts := make([]string, 0, len(distribution))
+	for _, capacity := range distribution {
+		counts = append(counts, fmt.Sprintf("%v=%v", capacity.Zone, capacity.Instances))
+	}
+	message := fmt.Sprintf("instances have been imbalanced across zones since %v: %v", since.Format(time.RFC3339), strings.Join(counts, ", "))
+
+	if existing := status.GetCondition(v1alpha1.ZonesImbalanced); existing == nil || existing.Reason != reason {
+		ctx.Log.Info("instances are imbalanced across zones", "instancegroup", instanceGroup.GetName(), "reason", reason, "zones", strings.Join(counts, ", "))
+		if spec.IsWarningEvents() {
+			state.Publisher.Publish(kubeprovider.ZonesImbalancedEvent, "instancegroup", instanceGroup.GetName(), "reason", reason, "zones", strings.Join(counts, ", "))
+		}
+	}
+	condition := v1alpha1.NewInstanceGroupCondition(v1alpha1.ZonesImbalanced, corev1.ConditionTrue)
+	condition.Reason = reason
+	condition.Message = message
+	status.SetCondition(condition)
+}
+
+func zoneSkew(distribution []v1alpha1.ZoneCapacity) int {
+	min, max := distribution[0].Instances, distribution[0].Instances
+	for _, capacity := range distribution[1:] {
+		if capacity.Instances < min {
+			min = capacity.Instances
+		}
+		if capacity.Instances > max {
+			max = capacity.Instances
+		}
+	}
+	return max - min
+}
@@ -0,0 +1,108 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eks
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/keikoproj/instance-manager/api/v1alpha1"
+	awsprovider "github.com/keikoproj/instance-manager/controllers/providers/aws"
+	kubeprovider "github.com/keikoproj/instance-manager/controllers/providers/kubernetes"
+	"github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDiscoverZoneDistribution(t *testing.T) {
+	var (
+		g             = gomega.NewGomegaWithT(t)
+		k             = MockKubernetesClientSet()
+		ig            = MockInstanceGroup()
+		configuration = ig.GetEKSConfiguration()
+		status        = ig.GetStatus()
+		asgMock       = NewAutoScalingMocker()
+		iamMock       = NewIamMocker()
+		eksMock       = NewEksMocker()
+		ec2Mock       = NewEc2Mocker()
+		now           = time.Now()
+	)
+
+	w := MockAwsWorker(asgMock, iamMock, eksMock, ec2Mock)
+	ctx := MockContext(ig, k, w)
+	state := ctx.GetDiscoveredState()
+	state.Publisher.Client = k.Kubernetes
+	configuration.ZoneImbalance = &v1alpha1.ZoneImbalanceSpec{WarningEvents: true}
+
+	scalingGroup := MockScalingGroup("asg-1")
+	scalingGroup.Instances = MockScalingInstances(4, 0)
+	for _, instance := range scalingGroup.Instances {
+		instance.AvailabilityZone = aws.String("us-west-2a")
+	}
+	scalingGroup.Instances[1].AvailabilityZone = aws.String("us-west-2b")
+	state.SetProvisioned(true)
+	state.SetScalingGroup(scalingGroup)
+	state.SetSubnetPlacements([]awsprovider.SubnetPlacement{
+		{SubnetID: "subnet-1", Zone: "us-west-2a", ZoneType: awsprovider.ZoneTypeAvailabilityZone},
+		{SubnetID: "subnet-2", Zone: "us-west-2b", ZoneType: awsprovider.ZoneTypeAvailabilityZone},
+		{SubnetID: "subnet-3", Zone: "us-west-2c", ZoneType: awsprovider.ZoneTypeAvailabilityZone},
+	})
+
+	// zones without instances are part of the distribution
+	ctx.DiscoverZoneDistribution(now)
+	g.Expect(status.GetZoneDistribution()).To(gomega.Equal([]v1alpha1.ZoneCapacity{
+		{Zone: "us-west-2a", Instances: 3},
+		{Zone: "us-west-2b", Instances: 1},
+		{Zone: "us-west-2c", Instances: 0},
+	}))
+	g.Expect(status.GetZoneImbalancedSince()).To(gomega.Equal(&metav1.Time{Time: now}))
+	g.Expect(status.GetCondition(v1alpha1.ZonesImbalanced)).To(gomega.BeNil())
+
+	// the imbalance is reported once it is sustained for longer than the threshold
+	later := now.Add(v1alpha1.DefaultZoneImbalanceThreshold)
+	ctx.DiscoverZoneDistribution(later)
+	g.Expect(status.GetZoneImbalancedSince()).To(gomega.Equal(&metav1.Time{Time: now}))
+	condition := status.GetCondition(v1alpha1.ZonesImbalanced)
+	g.Expect(condition).NotTo(gomega.BeNil())
+	g.Expect(condition.Reason).To(gomega.Equal(InstancesImbalancedReason))
+	g.Expect(condition.Message).To(gomega.ContainSubstring("us-west-2a=3, us-west-2b=1, us-west-2c=0"))
+
+	events, err := k.Kubernetes.CoreV1().Events("").List(metav1.ListOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(events.Items).To(gomega.HaveLen(1))
+	g.Expect(events.Items[0].Reason).To(gomega.Equal(string(kubeprovider.ZonesImbalancedEvent)))
+
+	// the event is only published when the reason changes
+	ctx.DiscoverZoneDistribution(later)
+	scalingGroup.SuspendedProcesses = []*autoscaling.SuspendedProcess{{ProcessName: aws.String(v1alpha1.AZRebalanceProcess)}}
+	ctx.DiscoverZoneDistribution(later)
+	g.Expect(status.GetCondition(v1alpha1.ZonesImbalanced).Reason).To(gomega.Equal(AZRebalanceSuspendedReason))
+	events, err = k.Kubernetes.CoreV1().Events("").List(metav1.ListOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(events.Items).To(gomega.HaveLen(2))
+
+	// a skew within the max skew is balanced
+	configuration.ZoneImbalance.MaxSkew = 3
+	ctx.DiscoverZoneDistribution(later)
+	g.Expect(status.GetZoneImbalancedSince()).To(gomega.BeNil())
+	g.Expect(status.GetCondition(v1alpha1.ZonesImbalanced)).To(gomega.BeNil())
+
+	// the distribution is removed when the scaling group is deleted
+	state.SetProvisioned(false)
+	ctx.DiscoverZoneDistribution(later)
+	g.Expect(status.GetZoneDistribution()).To(gomega.BeNil())
+}
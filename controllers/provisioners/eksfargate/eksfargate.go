@@ -18,22 +18,24 @@ package eksfargate
 import (
 	"fmt"
 	"hash/fnv"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/eks"
 	"github.com/aws/aws-sdk-go/service/iam"
 	v1alpha1 "github.com/keikoproj/instance-manager/api/v1alpha1"
+	"github.com/keikoproj/instance-manager/controllers/common"
 	awsprovider "github.com/keikoproj/instance-manager/controllers/providers/aws"
+	kubeprovider "github.com/keikoproj/instance-manager/controllers/providers/kubernetes"
 	"github.com/keikoproj/instance-manager/controllers/provisioners"
 	"github.com/pkg/errors"
 )
 
 const ProvisionerName = "eks-fargate"
 
-const LastAppliedConfigurationKey = "kubectl.kubernetes.io/last-applied-configuration"
-
 const (
 	PendingRolePolicyAttach = "pendingPolicyCreation"
 	PendingRoleCreation     = "pendingRoleCreation"
@@ -56,7 +58,7 @@ func hash(s string) string {
 }
 
 func becauseErrorContains(err error, code string) bool {
-	if aerr, ok := err.(awserr.Error); ok {
+	if aerr, ok := errors.Cause(err).(awserr.Error); ok {
 		if aerr.Code() == code {
 			return true
 		}
@@ -66,9 +68,10 @@ func becauseErrorContains(err error, code string) bool {
 
 func New(p provisioners.ProvisionerInput) *FargateInstanceGroupContext {
 	ctx := &FargateInstanceGroupContext{
-		InstanceGroup: p.InstanceGroup,
-		AwsWorker:     p.AwsWorker,
-		Log:           p.Log.WithName("eks-fargate"),
+		InstanceGroup:    p.InstanceGroup,
+		AwsWorker:        p.AwsWorker,
+		KubernetesClient: p.Kubernetes,
+		Log:              p.Log.WithName("eks-fargate"),
 	}
 
 	instanceGroup := ctx.GetInstanceGroup()
@@ -98,10 +101,109 @@ func (ctx *FargateInstanceGroupContext) processParameters() {
 	params["Selectors"] = CreateFargateSelectors(spec.GetSelectors())
 	params["Subnets"] = spec.GetSubnets()
 	params["Tags"] = CreateFargateTags(spec.GetTags())
-	params["DefaultRoleName"] = fmt.Sprintf("%v-role", ctx.generateUniqueName())
+	params["DefaultRoleName"] = ctx.defaultRoleName()
+	params["PermissionsBoundary"] = spec.GetPermissionsBoundary()
 	ctx.AwsWorker.Parameters = params
 }
 
+func (ctx *FargateInstanceGroupContext) defaultRoleName() string {
+	return fmt.Sprintf("%v-role", ctx.generateUniqueName())
+}
+
+// GetAdditionalPoliciesList returns the ARNs of the managed policies which are attached to the default pod execution
+// role in addition to the default policy, policy names are resolved to AWS managed policies
+func (ctx *FargateInstanceGroupContext) GetAdditionalPoliciesList() []string {
+	var (
+		spec      = ctx.GetInstanceGroup().GetEKSFargateSpec()
+		partition = ctx.AwsWorker.GetPartition()
+		policies  = make([]string, 0)
+	)
+	for _, name := range spec.GetManagedPolicies() {
+		switch {
+		case awsprovider.IsIAMArn(name):
+			policies = append(policies, name)
+		default:
+			policies = append(policies, awsprovider.ManagedPolicyArn(partition, name))
+		}
+	}
+	return policies
+}
+
+// UpdateDefaultRole remediates drift of the default pod execution role, policies which are not in the spec are
+// detached, missing policies are attached and the permissions boundary is set to the spec's boundary
+func (ctx *FargateInstanceGroupContext) UpdateDefaultRole() error {
+	var (
+		instanceGroup = ctx.GetInstanceGroup()
+		spec          = instanceGroup.GetEKSFargateSpec()
+		roleName      = ctx.defaultRoleName()
+		needsAttach   = make([]string, 0)
+		needsDetach   = make([]string, 0)
+	)
+
+	role, err := ctx.AwsWorker.GetDefaultFargateRole()
+	if err != nil {
+		return errors.Wrapf(err, "failed to get default role %v", roleName)
+	}
+
+	attachedPolicies, err := ctx.AwsWorker.ListRolePolicies(roleName)
+	if err != nil {
+		return errors.Wrapf(err, "failed to list policies of default role %v", roleName)
+	}
+
+	attachedArns := make([]string, 0)
+	for _, p := range attachedPolicies {
+		attachedArns = append(attachedArns, aws.StringValue(p.PolicyArn))
+	}
+
+	managedPolicies := append(ctx.GetAdditionalPoliciesList(), awsprovider.ManagedPolicyArn(ctx.AwsWorker.GetPartition(), awsprovider.FargatePodExecutionRolePolicyName))
+	for _, policy := range managedPolicies {
+		if !common.ContainsString(attachedArns, policy) {
+			needsAttach = append(needsAttach, policy)
+		}
+	}
+	for _, policy := range attachedArns {
+		if !common.ContainsString(managedPolicies, policy) {
+			needsDetach = append(needsDetach, policy)
+		}
+	}
+
+	if len(needsAttach) > 0 || len(needsDetach) > 0 {
+		if err := ctx.AwsWorker.AttachManagedPolicies(roleName, needsAttach); err != nil {
+			return err
+		}
+		if err := ctx.AwsWorker.DetachManagedPolicies(roleName, needsDetach); err != nil {
+			return err
+		}
+		ctx.Log.Info("Updated default role policies",
+			"instancegroup",
+			instanceGroup.GetName(),
+			"iamrole",
+			roleName,
+			"attached",
+			needsAttach,
+			"detached",
+			needsDetach)
+	}
+
+	var currentBoundary string
+	if role.PermissionsBoundary != nil {
+		currentBoundary = aws.StringValue(role.PermissionsBoundary.PermissionsBoundaryArn)
+	}
+	if boundary := spec.GetPermissionsBoundary(); boundary != currentBoundary {
+		if err := ctx.AwsWorker.UpdateRolePermissionsBoundary(roleName, boundary); err != nil {
+			return errors.Wrapf(err, "failed to update permissions boundary of default role %v", roleName)
+		}
+		ctx.Log.Info("Updated default role permissions boundary",
+			"instancegroup",
+			instanceGroup.GetName(),
+			"iamrole",
+			roleName,
+			"boundary",
+			boundary)
+	}
+	return nil
+}
+
 func CreateFargateSelectors(selectors []v1alpha1.EKSFargateSelectors) []*eks.FargateProfileSelector {
 	var eksSelectors []*eks.FargateProfileSelector
 	for _, selector := range selectors {
@@ -118,9 +220,18 @@ func CreateFargateSelectors(selectors []v1alpha1.EKSFargateSelectors) []*eks.Far
 	}
 	return eksSelectors
 }
+
+// CreateFargateTags converts the tags of the spec to the tags of a fargate profile, tags are either given as a
+// key/value pair like the tags of the other provisioners, or as a map of tag keys to values
 func CreateFargateTags(tagArray []map[string]string) map[string]*string {
 	tags := make(map[string]*string)
 	for _, t := range tagArray {
+		key, hasKey := t["key"]
+		value, hasValue := t["value"]
+		if hasKey && hasValue && len(t) == 2 {
+			tags[key] = aws.String(value)
+			continue
+		}
 		for k, v := range t {
 			vv := new(string)
 			*vv = v
@@ -130,6 +241,97 @@ func CreateFargateTags(tagArray []map[string]string) map[string]*string {
 	return tags
 }
 
+// selectorKeys returns a sorted string representation of fargate profile selectors so that they can be compared
+func selectorKeys(selectors []*eks.FargateProfileSelector) []string {
+	keys := make([]string, 0)
+	for _, selector := range selectors {
+		labels := make([]string, 0)
+		for k, v := range selector.Labels {
+			labels = append(labels, fmt.Sprintf("%v=%v", k, aws.StringValue(v)))
+		}
+		sort.Strings(labels)
+		keys = append(keys, fmt.Sprintf("%v{%v}", aws.StringValue(selector.Namespace), strings.Join(labels, ",")))
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func equalSets(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = append([]string{}, a...)
+	b = append([]string{}, b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// ProfileDrift returns the attributes of the discovered fargate profile which differ from the spec, fargate profiles
+// are immutable so the profile has to be recreated for them to be applied
+func (ctx *FargateInstanceGroupContext) ProfileDrift() []string {
+	var (
+		instanceGroup = ctx.GetInstanceGroup()
+		spec          = instanceGroup.GetEKSFargateSpec()
+		profile       = ctx.GetDiscoveredState().GetProfile()
+		drift         = make([]string, 0)
+	)
+
+	if profile == nil {
+		return drift
+	}
+
+	desired := selectorKeys(CreateFargateSelectors(spec.GetSelectors()))
+	if !equalSets(desired, selectorKeys(profile.Selectors)) {
+		drift = append(drift, "selectors")
+	}
+
+	// subnets default to the private subnets of the cluster when they are not set
+	if len(spec.GetSubnets()) > 0 && !equalSets(spec.GetSubnets(), aws.StringValueSlice(profile.Subnets)) {
+		drift = append(drift, "subnets")
+	}
+
+	if arn := spec.GetPodExecutionRoleArn(); arn != "" && arn != aws.StringValue(profile.PodExecutionRoleArn) {
+		drift = append(drift, "podExecutionRoleArn")
+	}
+	return drift
+}
+
+// TagDrift returns the tags which have to be added to and removed from the discovered fargate profile for its tags
+// to match the spec
+func (ctx *FargateInstanceGroupContext) TagDrift() (map[string]*string, []string) {
+	var (
+		instanceGroup = ctx.GetInstanceGroup()
+		spec          = instanceGroup.GetEKSFargateSpec()
+		profile       = ctx.GetDiscoveredState().GetProfile()
+		desired       = CreateFargateTags(spec.GetTags())
+		add           = make(map[string]*string)
+		remove        = make([]string, 0)
+	)
+
+	if profile == nil {
+		return add, remove
+	}
+
+	for k, v := range desired {
+		if current, ok := profile.Tags[k]; !ok || aws.StringValue(current) != aws.StringValue(v) {
+			add[k] = v
+		}
+	}
+	for k := range profile.Tags {
+		if _, ok := desired[k]; !ok {
+			remove = append(remove, k)
+		}
+	}
+	sort.Strings(remove)
+	return add, remove
+}
+
 func (ctx *FargateInstanceGroupContext) Create() error {
 	var arn string
 	instanceGroup := ctx.GetInstanceGroup()
@@ -172,6 +374,15 @@ func (ctx *FargateInstanceGroupContext) Create() error {
 			"instancegroup",
 			instanceGroup.GetName())
 
+		err = ctx.AwsWorker.AttachManagedPolicies(ctx.defaultRoleName(), ctx.GetAdditionalPoliciesList())
+		if err != nil {
+			ctx.Log.Error(err,
+				"Failed to attach the managed policies to role",
+				"instancegroup",
+				instanceGroup.GetName())
+			return err
+		}
+
 	} else {
 		arn = spec.GetPodExecutionRoleArn()
 	}
@@ -207,16 +418,45 @@ func (ctx *FargateInstanceGroupContext) Create() error {
 	return nil
 }
 func (ctx *FargateInstanceGroupContext) CloudDiscovery() error {
-	profile, err := ctx.AwsWorker.DescribeFargateProfile()
-	if err != nil {
-		profile = &eks.FargateProfile{
-			Status: nil,
-		}
+	var (
+		instanceGroup = ctx.GetInstanceGroup()
+		status        = instanceGroup.GetStatus()
+		state         = ctx.GetDiscoveredState()
+	)
+
+	state.Publisher = kubeprovider.EventPublisher{
+		Client:          ctx.KubernetesClient.Kubernetes,
+		Namespace:       instanceGroup.GetNamespace(),
+		Name:            instanceGroup.GetName(),
+		UID:             instanceGroup.GetUID(),
+		ResourceVersion: instanceGroup.GetResourceVersion(),
 	}
-	if profile.Status == nil {
-		ctx.DiscoveredState.ProfileStatus = aws.StringValue(nil)
+
+	profile, err := ctx.AwsWorker.DescribeFargateProfile()
+	if err != nil || profile == nil {
+		state.Profile = nil
+		state.ProfileStatus = aws.StringValue(nil)
 	} else {
-		ctx.DiscoveredState.ProfileStatus = *profile.Status
+		state.Profile = profile
+		state.ProfileStatus = aws.StringValue(profile.Status)
+	}
+
+	if previous := status.GetFargateProfileStatus(); previous != state.ProfileStatus {
+		ctx.Log.Info("fargate profile status changed",
+			"instancegroup",
+			instanceGroup.GetName(),
+			"profile",
+			ctx.generateUniqueName(),
+			"previous",
+			previous,
+			"status",
+			state.ProfileStatus)
+		state.Publisher.Publish(kubeprovider.FargateProfileStatusEvent,
+			"instancegroup", instanceGroup.GetName(),
+			"profile", ctx.generateUniqueName(),
+			"previous", previous,
+			"status", state.ProfileStatus)
+		status.SetFargateProfileStatus(state.ProfileStatus)
 	}
 	return nil
 }
@@ -226,6 +466,17 @@ func (ctx *FargateInstanceGroupContext) Delete() error {
 
 	worker := ctx.AwsWorker
 	if spec.GetPodExecutionRoleArn() == "" {
+		for _, policy := range ctx.GetAdditionalPoliciesList() {
+			err := worker.DetachManagedPolicies(ctx.defaultRoleName(), []string{policy})
+			if err != nil && !becauseErrorContains(err, iam.ErrCodeNoSuchEntityException) {
+				ctx.Log.Error(err,
+					"Detaching the managed policies failed.",
+					"instancegroup",
+					instanceGroup.GetName())
+				return err
+			}
+		}
+
 		err := worker.DetachDefaultPolicyFromDefaultRole()
 		// Policy was detached
 		if err == nil {
@@ -299,12 +550,61 @@ func (ctx *FargateInstanceGroupContext) Delete() error {
 
 func (ctx *FargateInstanceGroupContext) Update() error {
 	instanceGroup := ctx.GetInstanceGroup()
-	annos := instanceGroup.GetObjectMeta().GetAnnotations()
-	// If there is a last-applied-configuration then assume
-	// this is an update and throw an exception
-	if _, ok := annos[LastAppliedConfigurationKey]; ok {
-		return errors.New("update not supported")
+	spec := instanceGroup.GetEKSFargateSpec()
+
+	if spec.GetPodExecutionRoleArn() == "" {
+		if err := ctx.UpdateDefaultRole(); err != nil {
+			return errors.Wrap(err, "failed to update default role")
+		}
+	}
+
+	// Profiles are immutable, a drifted profile is deleted and
+	// created again with the spec once the deletion completes
+	if drift := ctx.ProfileDrift(); len(drift) > 0 {
+		err := ctx.AwsWorker.DeleteFargateProfile()
+		if err != nil {
+			if becauseErrorContains(err, eks.ErrCodeResourceInUseException) {
+				ctx.Log.Info("Recreation of the drifted fargate profile delayed",
+					"instancegroup",
+					instanceGroup.GetName(),
+					"cluster",
+					spec.GetClusterName(),
+					"profile",
+					ctx.generateUniqueName(),
+					"error", err)
+				return nil
+			}
+			return errors.Wrapf(err, "deletion of the drifted fargate profile %v failed", ctx.generateUniqueName())
+		}
+
+		ctx.Log.Info("Fargate profile drifted from spec, recreating",
+			"instancegroup",
+			instanceGroup.GetName(),
+			"cluster",
+			spec.GetClusterName(),
+			"profile",
+			ctx.generateUniqueName(),
+			"drift",
+			drift)
+		instanceGroup.SetState(v1alpha1.ReconcileModifying)
+		return nil
+	}
+
+	add, remove := ctx.TagDrift()
+	if len(add) > 0 || len(remove) > 0 {
+		arn := aws.StringValue(ctx.GetDiscoveredState().GetProfile().FargateProfileArn)
+		if err := ctx.AwsWorker.UpdateFargateProfileTags(arn, add, remove); err != nil {
+			return errors.Wrapf(err, "failed to update tags of fargate profile %v", ctx.generateUniqueName())
+		}
+		ctx.Log.Info("Updated fargate profile tags",
+			"instancegroup",
+			instanceGroup.GetName(),
+			"profile",
+			ctx.generateUniqueName(),
+			"removed",
+			remove)
 	}
+
 	instanceGroup.SetState(v1alpha1.ReconcileModified)
 	return nil
 }
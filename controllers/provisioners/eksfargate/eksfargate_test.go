@@ -16,21 +16,25 @@ limitations under the License.
 package eksfargate
 
 import (
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/eks"
 	"github.com/aws/aws-sdk-go/service/eks/eksiface"
 	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/aws/aws-sdk-go/service/iam/iamiface"
 	"github.com/keikoproj/instance-manager/api/v1alpha1"
 	awsprovider "github.com/keikoproj/instance-manager/controllers/providers/aws"
+	kubeprovider "github.com/keikoproj/instance-manager/controllers/providers/kubernetes"
 	"github.com/keikoproj/instance-manager/controllers/provisioners"
 	"github.com/pkg/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 )
@@ -57,6 +61,8 @@ type EksFargateUnitTest struct {
 	MakeAttachRolePolicyFail              bool
 	MakeDeleteRoleFail                    bool
 	DeleteRoleNoSuchEntityException       bool
+	AttachedPolicies                      []string
+	PermissionsBoundary                   string
 }
 
 type FakeIG struct {
@@ -79,6 +85,9 @@ type stubEKS struct {
 	MakeDeleteProfileRetry  bool
 	MakeDescribeProfileFail bool
 	CheckArnFor             string
+	ProfileDeleted          bool
+	TagsAdded               map[string]*string
+	TagsRemoved             []string
 }
 type stubIAM struct {
 	iamiface.IAMAPI
@@ -91,10 +100,16 @@ type stubIAM struct {
 	DetachRolePolicyFail                  bool
 	MakeDeleteRoleFail                    bool
 	DeleteRoleNoSuchEntityException       bool
+	AttachedPolicies                      []string
+	PermissionsBoundary                   string
+	PolicyAttached                        []string
+	PolicyDetached                        []string
+	BoundaryUpdated                       bool
 }
 
 func (s *stubIAM) DetachRolePolicy(input *iam.DetachRolePolicyInput) (*iam.DetachRolePolicyOutput, error) {
 	if s.DetachRolePolicyFail == false {
+		s.PolicyDetached = append(s.PolicyDetached, aws.StringValue(input.PolicyArn))
 		output := &iam.DetachRolePolicyOutput{}
 		return output, nil
 	} else {
@@ -104,6 +119,10 @@ func (s *stubIAM) DetachRolePolicy(input *iam.DetachRolePolicyInput) (*iam.Detac
 		return nil, errors.New("detach role policy failed")
 	}
 }
+
+func (s *stubIAM) DetachRolePolicyWithContext(ctx aws.Context, input *iam.DetachRolePolicyInput, opts ...request.Option) (*iam.DetachRolePolicyOutput, error) {
+	return s.DetachRolePolicy(input)
+}
 func (s *stubIAM) DeleteRole(input *iam.DeleteRoleInput) (*iam.DeleteRoleOutput, error) {
 	if s.MakeDeleteRoleFail == false {
 		output := &iam.DeleteRoleOutput{}
@@ -115,6 +134,10 @@ func (s *stubIAM) DeleteRole(input *iam.DeleteRoleInput) (*iam.DeleteRoleOutput,
 		return nil, errors.New("delete role failed")
 	}
 }
+
+func (s *stubIAM) DeleteRoleWithContext(ctx aws.Context, input *iam.DeleteRoleInput, opts ...request.Option) (*iam.DeleteRoleOutput, error) {
+	return s.DeleteRole(input)
+}
 func (s *stubIAM) CreateRole(input *iam.CreateRoleInput) (*iam.CreateRoleOutput, error) {
 	if s.MakeCreateRoleFail == false {
 		if s.CreateRoleDupFound {
@@ -130,6 +153,10 @@ func (s *stubIAM) CreateRole(input *iam.CreateRoleInput) (*iam.CreateRoleOutput,
 		return nil, errors.New("create role failed")
 	}
 }
+
+func (s *stubIAM) CreateRoleWithContext(ctx aws.Context, input *iam.CreateRoleInput, opts ...request.Option) (*iam.CreateRoleOutput, error) {
+	return s.CreateRole(input)
+}
 func (s *stubIAM) GetRole(input *iam.GetRoleInput) (*iam.GetRoleOutput, error) {
 	if s.MakeGetRoleFail == false {
 		output := &iam.GetRoleOutput{
@@ -137,19 +164,58 @@ func (s *stubIAM) GetRole(input *iam.GetRoleInput) (*iam.GetRoleOutput, error) {
 				Arn: aws.String("eksfargate::dummy_arn"),
 			},
 		}
+		if s.PermissionsBoundary != "" {
+			output.Role.PermissionsBoundary = &iam.AttachedPermissionsBoundary{
+				PermissionsBoundaryArn: aws.String(s.PermissionsBoundary),
+			}
+		}
 		return output, nil
 	} else {
 		return nil, errors.New("get role failed")
 	}
 }
+
+func (s *stubIAM) GetRoleWithContext(ctx aws.Context, input *iam.GetRoleInput, opts ...request.Option) (*iam.GetRoleOutput, error) {
+	return s.GetRole(input)
+}
 func (s *stubIAM) AttachRolePolicy(input *iam.AttachRolePolicyInput) (*iam.AttachRolePolicyOutput, error) {
 	if s.MakeAttachRolePolicyFail == false {
+		s.PolicyAttached = append(s.PolicyAttached, aws.StringValue(input.PolicyArn))
 		return &iam.AttachRolePolicyOutput{}, nil
 	} else {
 		return nil, errors.New("attach role policy failed")
 	}
 }
 
+func (s *stubIAM) AttachRolePolicyWithContext(ctx aws.Context, input *iam.AttachRolePolicyInput, opts ...request.Option) (*iam.AttachRolePolicyOutput, error) {
+	return s.AttachRolePolicy(input)
+}
+
+func (s *stubIAM) ListAttachedRolePoliciesPagesWithContext(ctx aws.Context, input *iam.ListAttachedRolePoliciesInput, callback func(*iam.ListAttachedRolePoliciesOutput, bool) bool, opts ...request.Option) error {
+	policies := s.AttachedPolicies
+	if policies == nil {
+		policies = []string{awsprovider.ManagedPolicyArn(awsprovider.DefaultPartition, awsprovider.FargatePodExecutionRolePolicyName)}
+	}
+	output := &iam.ListAttachedRolePoliciesOutput{}
+	for _, arn := range policies {
+		output.AttachedPolicies = append(output.AttachedPolicies, &iam.AttachedPolicy{PolicyArn: aws.String(arn)})
+	}
+	callback(output, true)
+	return nil
+}
+
+func (s *stubIAM) PutRolePermissionsBoundaryWithContext(ctx aws.Context, input *iam.PutRolePermissionsBoundaryInput, opts ...request.Option) (*iam.PutRolePermissionsBoundaryOutput, error) {
+	s.BoundaryUpdated = true
+	s.PermissionsBoundary = aws.StringValue(input.PermissionsBoundary)
+	return &iam.PutRolePermissionsBoundaryOutput{}, nil
+}
+
+func (s *stubIAM) DeleteRolePermissionsBoundaryWithContext(ctx aws.Context, input *iam.DeleteRolePermissionsBoundaryInput, opts ...request.Option) (*iam.DeleteRolePermissionsBoundaryOutput, error) {
+	s.BoundaryUpdated = true
+	s.PermissionsBoundary = ""
+	return &iam.DeleteRolePermissionsBoundaryOutput{}, nil
+}
+
 func (s *stubEKS) DescribeFargateProfile(input *eks.DescribeFargateProfileInput) (*eks.DescribeFargateProfileOutput, error) {
 	if s.MakeDescribeProfileFail {
 		return nil, awserr.New(eks.ErrCodeResourceNotFoundException, "not found", errors.New("notFound"))
@@ -161,6 +227,10 @@ func (s *stubEKS) DescribeFargateProfile(input *eks.DescribeFargateProfileInput)
 	return output, nil
 }
 
+func (s *stubEKS) DescribeFargateProfileWithContext(ctx aws.Context, input *eks.DescribeFargateProfileInput, opts ...request.Option) (*eks.DescribeFargateProfileOutput, error) {
+	return s.DescribeFargateProfile(input)
+}
+
 func (s *stubEKS) CreateFargateProfile(input *eks.CreateFargateProfileInput) (*eks.CreateFargateProfileOutput, error) {
 	output := &eks.CreateFargateProfileOutput{
 		FargateProfile: s.ProfileFromCreate,
@@ -179,11 +249,16 @@ func (s *stubEKS) CreateFargateProfile(input *eks.CreateFargateProfileInput) (*e
 	return output, nil
 }
 
+func (s *stubEKS) CreateFargateProfileWithContext(ctx aws.Context, input *eks.CreateFargateProfileInput, opts ...request.Option) (*eks.CreateFargateProfileOutput, error) {
+	return s.CreateFargateProfile(input)
+}
+
 func (s *stubEKS) DeleteFargateProfile(input *eks.DeleteFargateProfileInput) (*eks.DeleteFargateProfileOutput, error) {
 	if s.MakeDeleteProfileRetry {
 		return nil, awserr.New(eks.ErrCodeResourceInUseException, "resource in use", errors.New("resource in use"))
 	}
 	if s.MakeDeleteProfileFail == false {
+		s.ProfileDeleted = true
 		output := &eks.DeleteFargateProfileOutput{}
 		return output, nil
 	} else {
@@ -191,6 +266,20 @@ func (s *stubEKS) DeleteFargateProfile(input *eks.DeleteFargateProfileInput) (*e
 	}
 }
 
+func (s *stubEKS) DeleteFargateProfileWithContext(ctx aws.Context, input *eks.DeleteFargateProfileInput, opts ...request.Option) (*eks.DeleteFargateProfileOutput, error) {
+	return s.DeleteFargateProfile(input)
+}
+
+func (s *stubEKS) TagResourceWithContext(ctx aws.Context, input *eks.TagResourceInput, opts ...request.Option) (*eks.TagResourceOutput, error) {
+	s.TagsAdded = input.Tags
+	return &eks.TagResourceOutput{}, nil
+}
+
+func (s *stubEKS) UntagResourceWithContext(ctx aws.Context, input *eks.UntagResourceInput, opts ...request.Option) (*eks.UntagResourceOutput, error) {
+	s.TagsRemoved = aws.StringValueSlice(input.TagKeys)
+	return &eks.UntagResourceOutput{}, nil
+}
+
 func getProfile(state string) *eks.FargateProfile {
 	return &eks.FargateProfile{
 		Status: aws.String(state)}
@@ -271,12 +360,15 @@ func (u *EksFargateUnitTest) BuildProvisioner(t *testing.T) *FargateInstanceGrou
 			DetachRolePolicyFail:                  u.DetachRolePolicyFail,
 			MakeDeleteRoleFail:                    u.MakeDeleteRoleFail,
 			DeleteRoleNoSuchEntityException:       u.DeleteRoleNoSuchEntityException,
+			AttachedPolicies:                      u.AttachedPolicies,
+			PermissionsBoundary:                   u.PermissionsBoundary,
 		},
 	}
 	ctrl.SetLogger(zap.Logger(true))
 	p := provisioners.ProvisionerInput{
 		InstanceGroup: u.InstanceGroup,
 		AwsWorker:     *aws,
+		Kubernetes:    kubeprovider.KubernetesClientSet{Kubernetes: fake.NewSimpleClientset()},
 		Log:           ctrl.Log.WithName("unit-test").WithName("InstanceGroup"),
 	}
 	provisioner := New(p)
@@ -501,6 +593,12 @@ func TestCreateFargateTags(t *testing.T) {
 	if len(output) != 1 || *output["key1"] != "value1" {
 		t.Fatalf("TestCreateFargateTags: output is %v", output)
 	}
+
+	input = []map[string]string{{"key": "key1", "value": "value1"}}
+	output = CreateFargateTags(input)
+	if len(output) != 1 || *output["key1"] != "value1" {
+		t.Fatalf("TestCreateFargateTags: output is %v", output)
+	}
 }
 func TestCreateFargateSelectors(t *testing.T) {
 	input := []v1alpha1.EKSFargateSelectors{
@@ -697,17 +795,176 @@ func TestUpdate(t *testing.T) {
 func TestUpdate1(t *testing.T) {
 	ig := FakeIG{}
 	instanceGroup := ig.getInstanceGroup()
-	instanceGroup.SetAnnotations(map[string]string{LastAppliedConfigurationKey: "blah"})
+	instanceGroup.Spec.EKSFargateSpec.SetSelectors([]v1alpha1.EKSFargateSelectors{{Namespace: "team-*"}})
 	testCase := EksFargateUnitTest{
 		InstanceGroup: instanceGroup,
+		ProfileFromDescribe: &eks.FargateProfile{
+			Status:    aws.String(eks.FargateProfileStatusActive),
+			Selectors: CreateFargateSelectors([]v1alpha1.EKSFargateSelectors{{Namespace: "default"}}),
+			Subnets:   aws.StringSlice([]string{"subnet-222222", "subnet-1111111"}),
+		},
 	}
 	ctx := testCase.BuildProvisioner(t)
+	if err := ctx.CloudDiscovery(); err != nil {
+		t.Fatal(err)
+	}
 	err := ctx.Update()
-	if err == nil {
-		t.Fatal("TestUpdate1: error but got nil")
+	if err != nil {
+		t.Fatalf("TestUpdate1: expected nil but got error: %v", err)
+	}
+	if !ctx.AwsWorker.EksClient.(*stubEKS).ProfileDeleted {
+		t.Fatal("TestUpdate1: expected the drifted profile to be deleted")
+	}
+	if instanceGroup.GetState() != v1alpha1.ReconcileModifying {
+		t.Fatalf("TestUpdate1: expected ReconcileModifying state.  Got %v", instanceGroup.GetState())
+	}
+}
+func TestUpdateDefaultRole(t *testing.T) {
+	var (
+		defaultPolicy = "arn:aws:iam::aws:policy/AmazonEKSFargatePodExecutionRolePolicy"
+		s3Policy      = "arn:aws:iam::aws:policy/AmazonS3ReadOnlyAccess"
+		customPolicy  = "arn:aws:iam::123456789012:policy/custom"
+		strayPolicy   = "arn:aws:iam::123456789012:policy/stray"
+		boundary      = "arn:aws:iam::123456789012:policy/boundary"
+	)
+
+	ig := FakeIG{}
+	instanceGroup := ig.getInstanceGroup()
+	instanceGroup.Spec.EKSFargateSpec.SetManagedPolicies([]string{"AmazonS3ReadOnlyAccess", customPolicy})
+	instanceGroup.Spec.EKSFargateSpec.SetPermissionsBoundary(boundary)
+	testCase := EksFargateUnitTest{
+		InstanceGroup:    instanceGroup,
+		AttachedPolicies: []string{defaultPolicy, customPolicy, strayPolicy},
+	}
+	ctx := testCase.BuildProvisioner(t)
+	if err := ctx.Update(); err != nil {
+		t.Fatalf("TestUpdateDefaultRole: expected nil but got error: %v", err)
+	}
+
+	stub := ctx.AwsWorker.IamClient.(*stubIAM)
+	if !reflect.DeepEqual(stub.PolicyAttached, []string{s3Policy}) {
+		t.Fatalf("TestUpdateDefaultRole: bad attached policies %v", stub.PolicyAttached)
+	}
+	if !reflect.DeepEqual(stub.PolicyDetached, []string{strayPolicy}) {
+		t.Fatalf("TestUpdateDefaultRole: bad detached policies %v", stub.PolicyDetached)
+	}
+	if !stub.BoundaryUpdated || stub.PermissionsBoundary != boundary {
+		t.Fatalf("TestUpdateDefaultRole: expected permissions boundary %v.  Got %v", boundary, stub.PermissionsBoundary)
+	}
+
+	// a role without drift is not changed
+	testCase = EksFargateUnitTest{
+		InstanceGroup:       instanceGroup,
+		AttachedPolicies:    []string{defaultPolicy, customPolicy, s3Policy},
+		PermissionsBoundary: boundary,
+	}
+	ctx = testCase.BuildProvisioner(t)
+	if err := ctx.Update(); err != nil {
+		t.Fatalf("TestUpdateDefaultRole: expected nil but got error: %v", err)
+	}
+	stub = ctx.AwsWorker.IamClient.(*stubIAM)
+	if len(stub.PolicyAttached) != 0 || len(stub.PolicyDetached) != 0 || stub.BoundaryUpdated {
+		t.Fatalf("TestUpdateDefaultRole: expected no changes.  Got attached %v, detached %v", stub.PolicyAttached, stub.PolicyDetached)
+	}
+}
+func TestProfileDrift(t *testing.T) {
+	tests := []struct {
+		name      string
+		selectors []v1alpha1.EKSFargateSelectors
+		subnets   []string
+		arn       string
+		want      []string
+	}{
+		{name: "no drift", selectors: []v1alpha1.EKSFargateSelectors{{Namespace: "prod-?", Labels: map[string]string{"app": "web-*"}}}, subnets: []string{"subnet-2", "subnet-1"}, arn: "arn:role", want: []string{}},
+		{name: "selectors", selectors: []v1alpha1.EKSFargateSelectors{{Namespace: "prod-?", Labels: map[string]string{"app": "*"}}}, subnets: []string{"subnet-1", "subnet-2"}, arn: "arn:role", want: []string{"selectors"}},
+		{name: "subnets", selectors: []v1alpha1.EKSFargateSelectors{{Namespace: "prod-?", Labels: map[string]string{"app": "web-*"}}}, subnets: []string{"subnet-1"}, arn: "arn:role", want: []string{"subnets"}},
+		{name: "default subnets", selectors: []v1alpha1.EKSFargateSelectors{{Namespace: "prod-?", Labels: map[string]string{"app": "web-*"}}}, arn: "arn:role", want: []string{}},
+		{name: "role", selectors: []v1alpha1.EKSFargateSelectors{{Namespace: "prod-?", Labels: map[string]string{"app": "web-*"}}}, subnets: []string{"subnet-1", "subnet-2"}, arn: "arn:other-role", want: []string{"podExecutionRoleArn"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ig := FakeIG{}
+			instanceGroup := ig.getInstanceGroup()
+			instanceGroup.Spec.EKSFargateSpec.SetSelectors(tc.selectors)
+			instanceGroup.Spec.EKSFargateSpec.SetSubnets(tc.subnets)
+			instanceGroup.Spec.EKSFargateSpec.SetPodExecutionRoleArn(tc.arn)
+			testCase := EksFargateUnitTest{
+				InstanceGroup: instanceGroup,
+				ProfileFromDescribe: &eks.FargateProfile{
+					Status:              aws.String(eks.FargateProfileStatusActive),
+					Selectors:           CreateFargateSelectors([]v1alpha1.EKSFargateSelectors{{Namespace: "prod-?", Labels: map[string]string{"app": "web-*"}}}),
+					Subnets:             aws.StringSlice([]string{"subnet-1", "subnet-2"}),
+					PodExecutionRoleArn: aws.String("arn:role"),
+				},
+			}
+			ctx := testCase.BuildProvisioner(t)
+			if err := ctx.CloudDiscovery(); err != nil {
+				t.Fatal(err)
+			}
+			if got := ctx.ProfileDrift(); !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("TestProfileDrift: expected %v.  Got %v", tc.want, got)
+			}
+		})
+	}
+}
+func TestUpdateTags(t *testing.T) {
+	ig := FakeIG{}
+	instanceGroup := ig.getInstanceGroup()
+	instanceGroup.Spec.EKSFargateSpec.SetTags([]map[string]string{{"key": "team", "value": "platform"}, {"env": "prod"}})
+	testCase := EksFargateUnitTest{
+		InstanceGroup: instanceGroup,
+		ProfileFromDescribe: &eks.FargateProfile{
+			FargateProfileArn: aws.String("arn:aws:eks:us-west-2:123456789012:fargateprofile/EKS-Test/profile/1"),
+			Status:            aws.String(eks.FargateProfileStatusActive),
+			Subnets:           aws.StringSlice([]string{"subnet-1111111", "subnet-222222"}),
+			Tags:              aws.StringMap(map[string]string{"team": "platform", "env": "dev", "owner": "someone"}),
+		},
+	}
+	ctx := testCase.BuildProvisioner(t)
+	if err := ctx.CloudDiscovery(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ctx.Update(); err != nil {
+		t.Fatalf("TestUpdateTags: expected nil but got error: %v", err)
+	}
+
+	stub := ctx.AwsWorker.EksClient.(*stubEKS)
+	if stub.ProfileDeleted {
+		t.Fatal("TestUpdateTags: expected the profile not to be deleted")
+	}
+	if len(stub.TagsAdded) != 1 || aws.StringValue(stub.TagsAdded["env"]) != "prod" {
+		t.Fatalf("TestUpdateTags: bad added tags %v", stub.TagsAdded)
+	}
+	if !reflect.DeepEqual(stub.TagsRemoved, []string{"owner"}) {
+		t.Fatalf("TestUpdateTags: bad removed tags %v", stub.TagsRemoved)
+	}
+	if instanceGroup.GetState() != v1alpha1.ReconcileModified {
+		t.Fatalf("TestUpdateTags: expected ReconcileModified state.  Got %v", instanceGroup.GetState())
+	}
+}
+func TestCloudDiscoveryProfileStatusTransition(t *testing.T) {
+	ig := FakeIG{}
+	instanceGroup := ig.getInstanceGroup()
+	instanceGroup.GetStatus().SetFargateProfileStatus(eks.FargateProfileStatusCreating)
+	testCase := EksFargateUnitTest{
+		InstanceGroup:       instanceGroup,
+		ProfileFromDescribe: getProfile(eks.FargateProfileStatusActive),
+	}
+	ctx := testCase.BuildProvisioner(t)
+	if err := ctx.CloudDiscovery(); err != nil {
+		t.Fatal(err)
+	}
+	if instanceGroup.GetStatus().GetFargateProfileStatus() != eks.FargateProfileStatusActive {
+		t.Fatalf("TestCloudDiscoveryProfileStatusTransition: expected ACTIVE.  Got %v", instanceGroup.GetStatus().GetFargateProfileStatus())
+	}
+
+	events, err := ctx.KubernetesClient.Kubernetes.CoreV1().Events(instanceGroup.GetNamespace()).List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
 	}
-	if err.Error() != "update not supported" {
-		t.Fatalf("TestUpdate1: bad error message.  Got %v", err.Error())
+	if len(events.Items) != 1 || events.Items[0].Reason != string(kubeprovider.FargateProfileStatusEvent) {
+		t.Fatalf("TestCloudDiscoveryProfileStatusTransition: expected a status event.  Got %v", events.Items)
 	}
 }
 func TestDeleteWithArnDeleteProfileSuccess(t *testing.T) {
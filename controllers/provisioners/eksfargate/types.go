@@ -17,13 +17,21 @@ package eksfargate
 
 import (
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/eks"
 	"github.com/go-logr/logr"
 	"github.com/keikoproj/instance-manager/api/v1alpha1"
 	awsprovider "github.com/keikoproj/instance-manager/controllers/providers/aws"
+	kubeprovider "github.com/keikoproj/instance-manager/controllers/providers/kubernetes"
 )
 
 type DiscoveredState struct {
 	ProfileStatus string
+	Profile       *eks.FargateProfile
+	Publisher     kubeprovider.EventPublisher
+}
+
+func (ds *DiscoveredState) GetProfile() *eks.FargateProfile {
+	return ds.Profile
 }
 
 func (ds *DiscoveredState) GetProfileStatus() string {
@@ -34,10 +42,11 @@ func (ds *DiscoveredState) IsProvisioned() bool {
 }
 
 type FargateInstanceGroupContext struct {
-	InstanceGroup   *v1alpha1.InstanceGroup
-	AwsWorker       awsprovider.AwsWorker
-	DiscoveredState DiscoveredState
-	Log             logr.Logger
+	InstanceGroup    *v1alpha1.InstanceGroup
+	AwsWorker        awsprovider.AwsWorker
+	KubernetesClient kubeprovider.KubernetesClientSet
+	DiscoveredState  DiscoveredState
+	Log              logr.Logger
 }
 
 func (ctx *FargateInstanceGroupContext) GetDiscoveredState() *DiscoveredState {
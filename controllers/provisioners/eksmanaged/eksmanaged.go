@@ -16,13 +16,16 @@ limitations under the License.
 package eksmanaged
 
 import (
+	"fmt"
 	"reflect"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/eks"
 	"github.com/keikoproj/instance-manager/api/v1alpha1"
 	awsprovider "github.com/keikoproj/instance-manager/controllers/providers/aws"
 	"github.com/keikoproj/instance-manager/controllers/provisioners"
+	corev1 "k8s.io/api/core/v1"
 )
 
 const (
@@ -54,6 +57,7 @@ func (ctx *EksManagedInstanceGroupContext) CloudDiscovery() error {
 		status.SetCurrentMax(int(aws.Int64Value(createdResource.ScalingConfig.MaxSize)))
 		status.SetCurrentMin(int(aws.Int64Value(createdResource.ScalingConfig.MinSize)))
 		status.SetLifecycle("normal")
+		SetHealthCondition(status, createdResource.Health)
 
 		if createdResource.Resources == nil {
 			return nil
@@ -66,10 +70,34 @@ func (ctx *EksManagedInstanceGroupContext) CloudDiscovery() error {
 
 	} else {
 		discoveredState.SetProvisioned(false)
+		status.RemoveCondition(v1alpha1.NodeGroupHealthy)
 	}
 	return nil
 }
 
+// SetHealthCondition reflects the health issues of a node group in the NodeGroupHealthy condition of an instance
+// group, the reason is the code of the first issue and the message lists all issues with the affected resources
+func SetHealthCondition(status *v1alpha1.InstanceGroupStatus, health *eks.NodegroupHealth) {
+	if health == nil || len(health.Issues) == 0 {
+		status.SetCondition(v1alpha1.NewInstanceGroupCondition(v1alpha1.NodeGroupHealthy, corev1.ConditionTrue))
+		return
+	}
+
+	messages := make([]string, 0, len(health.Issues))
+	for _, issue := range health.Issues {
+		message := fmt.Sprintf("%v: %v", aws.StringValue(issue.Code), aws.StringValue(issue.Message))
+		if ids := aws.StringValueSlice(issue.ResourceIds); len(ids) > 0 {
+			message = fmt.Sprintf("%v (%v)", message, strings.Join(ids, ", "))
+		}
+		messages = append(messages, message)
+	}
+
+	condition := v1alpha1.NewInstanceGroupCondition(v1alpha1.NodeGroupHealthy, corev1.ConditionFalse)
+	condition.Reason = aws.StringValue(health.Issues[0].Code)
+	condition.Message = strings.Join(messages, "; ")
+	status.SetCondition(condition)
+}
+
 func (ctx *EksManagedInstanceGroupContext) StateDiscovery() {
 	var (
 		instanceGroup   = ctx.GetInstanceGroup()
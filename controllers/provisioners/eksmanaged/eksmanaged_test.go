@@ -23,6 +23,7 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/eks"
 	"github.com/aws/aws-sdk-go/service/eks/eksiface"
 	"github.com/keikoproj/instance-manager/api/v1alpha1"
@@ -30,6 +31,7 @@ import (
 	kubeprovider "github.com/keikoproj/instance-manager/controllers/providers/kubernetes"
 	"github.com/keikoproj/instance-manager/controllers/provisioners"
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -73,21 +75,37 @@ func (s *stubEKS) DescribeNodegroup(input *eks.DescribeNodegroupInput) (*eks.Des
 	return output, nil
 }
 
+func (s *stubEKS) DescribeNodegroupWithContext(ctx aws.Context, input *eks.DescribeNodegroupInput, opts ...request.Option) (*eks.DescribeNodegroupOutput, error) {
+	return s.DescribeNodegroup(input)
+}
+
 func (s *stubEKS) CreateNodegroup(input *eks.CreateNodegroupInput) (*eks.CreateNodegroupOutput, error) {
 	output := &eks.CreateNodegroupOutput{}
 	return output, nil
 }
 
+func (s *stubEKS) CreateNodegroupWithContext(ctx aws.Context, input *eks.CreateNodegroupInput, opts ...request.Option) (*eks.CreateNodegroupOutput, error) {
+	return s.CreateNodegroup(input)
+}
+
 func (s *stubEKS) UpdateNodegroupConfig(input *eks.UpdateNodegroupConfigInput) (*eks.UpdateNodegroupConfigOutput, error) {
 	output := &eks.UpdateNodegroupConfigOutput{}
 	return output, nil
 }
 
+func (s *stubEKS) UpdateNodegroupConfigWithContext(ctx aws.Context, input *eks.UpdateNodegroupConfigInput, opts ...request.Option) (*eks.UpdateNodegroupConfigOutput, error) {
+	return s.UpdateNodegroupConfig(input)
+}
+
 func (s *stubEKS) DeleteNodegroup(input *eks.DeleteNodegroupInput) (*eks.DeleteNodegroupOutput, error) {
 	output := &eks.DeleteNodegroupOutput{}
 	return output, nil
 }
 
+func (s *stubEKS) DeleteNodegroupWithContext(ctx aws.Context, input *eks.DeleteNodegroupInput, opts ...request.Option) (*eks.DeleteNodegroupOutput, error) {
+	return s.DeleteNodegroup(input)
+}
+
 func getNodeGroup(state string) *eks.Nodegroup {
 	return &eks.Nodegroup{
 		Status: aws.String(state),
@@ -337,3 +355,47 @@ func TestStateDiscoveryDeleted(t *testing.T) {
 	}
 	testCase.Run(t)
 }
+
+func TestHealthCondition(t *testing.T) {
+	ig := FakeIG{}
+	nodeGroup := getNodeGroup("DEGRADED")
+	nodeGroup.Health = &eks.NodegroupHealth{
+		Issues: []*eks.Issue{
+			{
+				Code:        aws.String(eks.NodegroupIssueCodeEc2launchTemplateNotFound),
+				Message:     aws.String("launch template not found"),
+				ResourceIds: aws.StringSlice([]string{"lt-0123456789abcdef0"}),
+			},
+			{
+				Code:    aws.String(eks.NodegroupIssueCodeAccessDenied),
+				Message: aws.String("access denied"),
+			},
+		},
+	}
+	testCase := EksManagedUnitTest{
+		Description:   "CloudDiscovery - health issues of a nodegroup are reflected in the NodeGroupHealthy condition",
+		InstanceGroup: ig.getInstanceGroup(),
+		NodeGroup:     nodeGroup,
+		GroupExist:    true,
+		ExpectedState: v1alpha1.ReconcileInitUpdate,
+	}
+	testCase.Run(t)
+
+	condition := testCase.InstanceGroup.GetStatus().GetCondition(v1alpha1.NodeGroupHealthy)
+	if condition == nil || condition.Status != corev1.ConditionFalse {
+		t.Fatalf("expected NodeGroupHealthy condition to be False, got %+v", condition)
+	}
+	if condition.Reason != eks.NodegroupIssueCodeEc2launchTemplateNotFound {
+		t.Fatalf("expected reason %v, got %v", eks.NodegroupIssueCodeEc2launchTemplateNotFound, condition.Reason)
+	}
+	expectedMessage := "Ec2LaunchTemplateNotFound: launch template not found (lt-0123456789abcdef0); AccessDenied: access denied"
+	if condition.Message != expectedMessage {
+		t.Fatalf("expected message %v, got %v", expectedMessage, condition.Message)
+	}
+
+	// the condition is true once the issues are resolved
+	SetHealthCondition(testCase.InstanceGroup.GetStatus(), &eks.NodegroupHealth{})
+	if condition := testCase.InstanceGroup.GetStatus().GetCondition(v1alpha1.NodeGroupHealthy); condition == nil || condition.Status != corev1.ConditionTrue {
+		t.Fatalf("expected NodeGroupHealthy condition to be True, got %+v", condition)
+	}
+}
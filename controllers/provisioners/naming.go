@@ -0,0 +1,114 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioners
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultResourceNameTemplate is the name of the scaling group, launch configuration prefix and IAM role of an instance group
+	DefaultResourceNameTemplate = "{{.ClusterName}}-{{.Namespace}}-{{.Name}}"
+
+	// AWS length limits of resource names
+	MaxRoleNameLength                = 64
+	MaxScalingGroupNameLength        = 255
+	MaxLaunchConfigurationNameLength = 255
+)
+
+var (
+	// characters which are allowed in the names of IAM roles, the most restrictive of the named resources
+	ResourceNamePattern = regexp.MustCompile(`^[\w+=,.@-]+$`)
+)
+
+// ResourceNameConfiguration is the template of the names of the cloud resources of instance groups
+type ResourceNameConfiguration struct {
+	Template string
+}
+
+// ResourceNameInput is the data a resource name template is rendered with
+type ResourceNameInput struct {
+	ClusterName string
+	Namespace   string
+	Name        string
+}
+
+// Hash returns a short hash which is unique per instance group, it allows templates to shorten names which would
+// exceed AWS length limits
+func (i ResourceNameInput) Hash() string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%v/%v/%v", i.ClusterName, i.Namespace, i.Name)))
+	return fmt.Sprintf("%x", sum)[:8]
+}
+
+// GetTemplate returns the configured template, or the default one
+func (c ResourceNameConfiguration) GetTemplate() string {
+	if strings.TrimSpace(c.Template) == "" {
+		return DefaultResourceNameTemplate
+	}
+	return c.Template
+}
+
+// Render returns the resource name of an instance group
+func (c ResourceNameConfiguration) Render(input ResourceNameInput) (string, error) {
+	tpl, err := template.New("resourceName").Parse(c.GetTemplate())
+	if err != nil {
+		return "", errors.Wrap(err, "failed to parse resource name template")
+	}
+
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, input); err != nil {
+		return "", errors.Wrap(err, "failed to render resource name template")
+	}
+
+	name := buf.String()
+	if !ResourceNamePattern.MatchString(name) {
+		return "", errors.Errorf("resource name '%v' is invalid, it must match %v", name, ResourceNamePattern.String())
+	}
+	return name, nil
+}
+
+// Validate renders the template with sample values to ensure it is valid, and that it is unique per instance group
+func (c ResourceNameConfiguration) Validate() error {
+	first, err := c.Render(ResourceNameInput{
+		ClusterName: "cluster",
+		Namespace:   "namespace",
+		Name:        "instancegroup",
+	})
+	if err != nil {
+		return err
+	}
+
+	second, err := c.Render(ResourceNameInput{
+		ClusterName: "cluster",
+		Namespace:   "other-namespace",
+		Name:        "other-instancegroup",
+	})
+	if err != nil {
+		return err
+	}
+
+	if first == second {
+		return errors.Errorf("resource name template '%v' must be unique per instance group, it should include .Name or .Hash", c.GetTemplate())
+	}
+	return nil
+}
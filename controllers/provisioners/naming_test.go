@@ -0,0 +1,84 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioners
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+)
+
+func TestResourceNameConfigurationRender(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	input := ResourceNameInput{
+		ClusterName: "my-cluster",
+		Namespace:   "my-namespace",
+		Name:        "my-instancegroup",
+	}
+
+	tests := []struct {
+		template string
+		expected string
+		wantErr  bool
+	}{
+		{template: "", expected: "my-cluster-my-namespace-my-instancegroup"},
+		{template: "{{.ClusterName}}-{{.Name}}-{{.Hash}}", expected: "my-cluster-my-instancegroup-" + input.Hash()},
+		{template: "nodes.{{.Namespace}}.{{.Name}}", expected: "nodes.my-namespace.my-instancegroup"},
+		{template: "{{.ClusterName}}/{{.Name}}", wantErr: true},
+		{template: "{{.Zone}}-{{.Name}}", wantErr: true},
+		{template: "{{.Name", wantErr: true},
+	}
+
+	for i, tc := range tests {
+		t.Logf("Test #%v - %+v", i, tc)
+		c := ResourceNameConfiguration{Template: tc.template}
+		got, err := c.Render(input)
+		if tc.wantErr {
+			g.Expect(err).To(gomega.HaveOccurred())
+			continue
+		}
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(got).To(gomega.Equal(tc.expected))
+	}
+
+	g.Expect(input.Hash()).To(gomega.HaveLen(8))
+	g.Expect(input.Hash()).NotTo(gomega.Equal(ResourceNameInput{ClusterName: "my-cluster", Namespace: "other", Name: "my-instancegroup"}.Hash()))
+}
+
+func TestResourceNameConfigurationValidate(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	tests := []struct {
+		template string
+		wantErr  bool
+	}{
+		{template: DefaultResourceNameTemplate},
+		{template: "{{.ClusterName}}-{{.Hash}}"},
+		{template: "{{.ClusterName}}-nodes", wantErr: true},
+		{template: "{{.ClusterName}} {{.Name}}", wantErr: true},
+	}
+
+	for i, tc := range tests {
+		t.Logf("Test #%v - %+v", i, tc)
+		err := ResourceNameConfiguration{Template: tc.template}.Validate()
+		if tc.wantErr {
+			g.Expect(err).To(gomega.HaveOccurred())
+		} else {
+			g.Expect(err).NotTo(gomega.HaveOccurred())
+		}
+	}
+}
@@ -0,0 +1,252 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioners
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/keikoproj/instance-manager/api/v1alpha1"
+	"github.com/keikoproj/instance-manager/controllers/common"
+)
+
+// FieldPolicy restricts the fields of the instance groups in its namespaces, or in all namespaces when none are listed
+type FieldPolicy struct {
+	Namespaces []string `yaml:"namespaces,omitempty"`
+	// AllowedFields are the only paths which may be set in the spec, fields nested below an allowed path are allowed
+	AllowedFields []string `yaml:"allowedFields,omitempty"`
+	// DeniedFields are paths which must not be set in the spec
+	DeniedFields []string `yaml:"deniedFields,omitempty"`
+	// Values restrict the values of fields
+	Values []FieldValuePolicy `yaml:"values,omitempty"`
+}
+
+// FieldValuePolicy restricts the values of a field, paths which traverse lists apply to every element of the list
+type FieldValuePolicy struct {
+	Path           string   `yaml:"path"`
+	Minimum        *float64 `yaml:"minimum,omitempty"`
+	Maximum        *float64 `yaml:"maximum,omitempty"`
+	AllowedPattern string   `yaml:"allowedPattern,omitempty"`
+	DeniedPattern  string   `yaml:"deniedPattern,omitempty"`
+}
+
+// AppliesTo returns true if the policy applies to instance groups in a namespace
+func (p FieldPolicy) AppliesTo(namespace string) bool {
+	return len(p.Namespaces) == 0 || common.ContainsString(p.Namespaces, namespace)
+}
+
+// Validate returns an error if a pattern of the policy is not a valid regular expression
+func (p FieldPolicy) Validate() error {
+	for _, v := range p.Values {
+		if common.StringEmpty(v.Path) {
+			return errors.New("value policy has no path")
+		}
+		for _, pattern := range []string{v.AllowedPattern, v.DeniedPattern} {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return errors.Wrapf(err, "invalid pattern for %v", v.Path)
+			}
+		}
+	}
+	return nil
+}
+
+// Violations returns the violations of the policy by an unstructured instance group
+func (p FieldPolicy) Violations(obj map[string]interface{}) []string {
+	violations := make([]string, 0)
+
+	if len(p.AllowedFields) > 0 {
+		for _, path := range setFieldPaths("spec", obj["spec"]) {
+			if !isAllowedPath(path, p.AllowedFields) {
+				violations = append(violations, fmt.Sprintf("%v is not allowed", path))
+			}
+		}
+	}
+
+	for _, path := range p.DeniedFields {
+		for _, value := range fieldValues(obj, common.FieldPath(path)) {
+			if !isZero(value) {
+				violations = append(violations, fmt.Sprintf("%v is denied", path))
+				break
+			}
+		}
+	}
+
+	for _, v := range p.Values {
+		violations = append(violations, v.Violations(obj)...)
+	}
+	return violations
+}
+
+// Violations returns the violations of the value policy by an unstructured instance group
+func (v FieldValuePolicy) Violations(obj map[string]interface{}) []string {
+	violations := make([]string, 0)
+	for _, value := range fieldValues(obj, common.FieldPath(v.Path)) {
+		if isZero(value) {
+			continue
+		}
+		str := fmt.Sprintf("%v", value)
+
+		if v.Minimum != nil || v.Maximum != nil {
+			if number, err := strconv.ParseFloat(str, 64); err == nil {
+				if v.Minimum != nil && number < *v.Minimum {
+					violations = append(violations, fmt.Sprintf("%v is %v, the minimum is %v", v.Path, str, *v.Minimum))
+				}
+				if v.Maximum != nil && number > *v.Maximum {
+					violations = append(violations, fmt.Sprintf("%v is %v, the maximum is %v", v.Path, str, *v.Maximum))
+				}
+			}
+		}
+
+		if !common.StringEmpty(v.AllowedPattern) {
+			if ok, _ := regexp.MatchString(v.AllowedPattern, str); !ok {
+				violations = append(violations, fmt.Sprintf("%v '%v' does not match allowed pattern '%v'", v.Path, str, v.AllowedPattern))
+			}
+		}
+		if !common.StringEmpty(v.DeniedPattern) {
+			if ok, _ := regexp.MatchString(v.DeniedPattern, str); ok {
+				violations = append(violations, fmt.Sprintf("%v '%v' matches denied pattern '%v'", v.Path, str, v.DeniedPattern))
+			}
+		}
+	}
+	return violations
+}
+
+// ValidatePolicies returns an error listing the violations of the policies which apply to the instance group
+func (c *ProvisionerConfiguration) ValidatePolicies() error {
+	if len(c.Boundaries.Policies) == 0 {
+		return nil
+	}
+
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(c.InstanceGroup)
+	if err != nil {
+		return errors.Wrap(err, "failed to convert instance group to unstructured")
+	}
+
+	violations := make([]string, 0)
+	for _, policy := range c.Boundaries.Policies {
+		if policy.AppliesTo(c.InstanceGroup.GetNamespace()) {
+			violations = append(violations, policy.Violations(obj)...)
+		}
+	}
+
+	if len(violations) > 0 {
+		return errors.Errorf("validation failed, instancegroup %v violates policies: %v", c.InstanceGroup.NamespacedName(), strings.Join(violations, ", "))
+	}
+	return nil
+}
+
+// ConfigMapPolicyValidator validates instance groups at admission against the policies of the controller configmap,
+// the configmap is read on every admission so that policy changes apply immediately
+type ConfigMapPolicyValidator struct {
+	Kubernetes kubernetes.Interface
+	Namespace  string
+	Name       string
+}
+
+// Validate implements v1alpha1.InstanceGroupValidator, defaults are applied to the instance group before the policies
+// are evaluated, like at reconcile time
+func (v *ConfigMapPolicyValidator) Validate(ig *v1alpha1.InstanceGroup) error {
+	cm, err := v.Kubernetes.CoreV1().ConfigMaps(v.Namespace).Get(v.Name, metav1.GetOptions{})
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil
+		}
+		return errors.Wrap(err, "failed to get instance-manager configmap")
+	}
+
+	config, err := NewProvisionerConfiguration(cm, ig)
+	if err != nil {
+		return err
+	}
+	if len(config.Boundaries.Policies) == 0 {
+		return nil
+	}
+	if err := config.SetDefaults(); err != nil {
+		return errors.Wrap(err, "failed to set configuration defaults")
+	}
+	return config.ValidatePolicies()
+}
+
+// fieldValues returns the values of a path in an unstructured object, lists on the path are traversed so that the
+// remaining path is resolved in each of their elements
+func fieldValues(obj interface{}, path []string) []interface{} {
+	if len(path) == 0 {
+		return []interface{}{obj}
+	}
+	switch value := obj.(type) {
+	case map[string]interface{}:
+		field, ok := value[path[0]]
+		if !ok {
+			return nil
+		}
+		return fieldValues(field, path[1:])
+	case []interface{}:
+		values := make([]interface{}, 0)
+		for _, element := range value {
+			values = append(values, fieldValues(element, path)...)
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+// setFieldPaths returns the paths of the fields below a path which are set to a non-zero value, lists are not traversed
+func setFieldPaths(prefix string, obj interface{}) []string {
+	paths := make([]string, 0)
+	if m, ok := obj.(map[string]interface{}); ok {
+		for key, value := range m {
+			paths = append(paths, setFieldPaths(common.FieldPathString(prefix, key), value)...)
+		}
+		sort.Strings(paths)
+		return paths
+	}
+	if !isZero(obj) {
+		paths = append(paths, prefix)
+	}
+	return paths
+}
+
+func isAllowedPath(path string, allowed []string) bool {
+	for _, a := range allowed {
+		if path == a || strings.HasPrefix(path, a+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func isZero(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
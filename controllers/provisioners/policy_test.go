@@ -0,0 +1,127 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioners
+
+import (
+	"testing"
+
+	"github.com/keikoproj/instance-manager/api/v1alpha1"
+	"github.com/onsi/gomega"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+const mockPolicyBoundaries = `
+policies:
+- namespaces:
+  - team-a
+  deniedFields:
+  - spec.eks.configuration.bootstrapArguments
+  values:
+  - path: spec.eks.configuration.volumes.size
+    maximum: 100
+  - path: spec.eks.configuration.image
+    allowedPattern: ^ami-(0123|4567)
+  - path: spec.eks.configuration.instanceType
+    deniedPattern: ^(p3|p4d)\.
+- namespaces:
+  - team-b
+  allowedFields:
+  - spec.provisioner
+  - spec.eks.configuration.instanceType
+  - spec.eks.configuration.volumes`
+
+func TestValidatePolicies(t *testing.T) {
+	var (
+		g = gomega.NewGomegaWithT(t)
+	)
+
+	tests := []struct {
+		namespace         string
+		bootstrapArgs     string
+		image             string
+		instanceType      string
+		volumeSize        int64
+		expectedViolation string
+	}{
+		{namespace: "team-a", image: "ami-0123abc", instanceType: "m5.large", volumeSize: 100},
+		{namespace: "team-a", image: "ami-0123abc", instanceType: "m5.large", volumeSize: 200, expectedViolation: "spec.eks.configuration.volumes.size is 200, the maximum is 100"},
+		{namespace: "team-a", image: "ami-9999abc", instanceType: "m5.large", volumeSize: 100, expectedViolation: "spec.eks.configuration.image 'ami-9999abc' does not match allowed pattern '^ami-(0123|4567)'"},
+		{namespace: "team-a", image: "ami-0123abc", instanceType: "p3.2xlarge", volumeSize: 100, expectedViolation: "spec.eks.configuration.instanceType 'p3.2xlarge' matches denied pattern"},
+		{namespace: "team-a", bootstrapArgs: "--kubelet-extra-args", image: "ami-0123abc", instanceType: "m5.large", volumeSize: 100, expectedViolation: "spec.eks.configuration.bootstrapArguments is denied"},
+		{namespace: "team-b", instanceType: "m5.large", volumeSize: 500},
+		{namespace: "team-b", image: "ami-9999abc", instanceType: "m5.large", volumeSize: 500, expectedViolation: "spec.eks.configuration.image is not allowed"},
+		{namespace: "team-c", bootstrapArgs: "--kubelet-extra-args", image: "ami-9999abc", instanceType: "p3.2xlarge", volumeSize: 500},
+	}
+
+	for i, tc := range tests {
+		t.Logf("Test #%v - %+v", i, tc)
+		cr := MockResource()
+		cr.SetNamespace(tc.namespace)
+		cr.Spec.Provisioner = "eks"
+		cr.Spec.EKSSpec.EKSConfiguration.BootstrapArguments = tc.bootstrapArgs
+		cr.Spec.EKSSpec.EKSConfiguration.Image = tc.image
+		cr.Spec.EKSSpec.EKSConfiguration.InstanceType = tc.instanceType
+		cr.Spec.EKSSpec.EKSConfiguration.Volumes = []v1alpha1.NodeVolume{MockVolume("/dev/xvda", "gp2", tc.volumeSize)}
+
+		c, err := NewProvisionerConfiguration(MockConfigMap(MockConfigData("boundaries", mockPolicyBoundaries)), cr)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+
+		err = c.ValidatePolicies()
+		if tc.expectedViolation == "" {
+			g.Expect(err).NotTo(gomega.HaveOccurred())
+			continue
+		}
+		g.Expect(err).To(gomega.HaveOccurred())
+		g.Expect(err.Error()).To(gomega.ContainSubstring(tc.expectedViolation))
+	}
+}
+
+func TestUnmarshalInvalidPolicy(t *testing.T) {
+	var (
+		g = gomega.NewGomegaWithT(t)
+	)
+
+	mockBoundaries := `
+policies:
+- values:
+  - path: spec.eks.configuration.image
+    allowedPattern: ^ami-(`
+
+	_, err := NewProvisionerConfiguration(MockConfigMap(MockConfigData("boundaries", mockBoundaries)), &v1alpha1.InstanceGroup{})
+	g.Expect(err).To(gomega.HaveOccurred())
+}
+
+func TestConfigMapPolicyValidator(t *testing.T) {
+	var (
+		g  = gomega.NewGomegaWithT(t)
+		cm = MockConfigMap(MockConfigData("boundaries", mockPolicyBoundaries))
+		cr = MockResource()
+	)
+
+	cr.SetNamespace("team-a")
+	cr.Spec.EKSSpec.EKSConfiguration.InstanceType = "p4d.24xlarge"
+
+	// instance groups are admitted while the configmap does not exist
+	v := &ConfigMapPolicyValidator{
+		Kubernetes: kubefake.NewSimpleClientset(),
+		Namespace:  cm.GetNamespace(),
+		Name:       cm.GetName(),
+	}
+	g.Expect(v.Validate(cr)).To(gomega.Succeed())
+
+	v.Kubernetes = kubefake.NewSimpleClientset(cm)
+	g.Expect(v.Validate(cr)).NotTo(gomega.Succeed())
+}
@@ -23,16 +23,40 @@ const (
 )
 
 type ProvisionerInput struct {
-	AwsWorker       awsprovider.AwsWorker
-	Kubernetes      kubeprovider.KubernetesClientSet
-	InstanceGroup   *v1alpha1.InstanceGroup
-	Configuration   *corev1.ConfigMap
-	Log             logr.Logger
-	ConfigRetention int
+	AwsWorker  awsprovider.AwsWorker
+	Kubernetes kubeprovider.KubernetesClientSet
+	// ClusterKubernetes is the client set of the cluster the nodes join, when it is not the cluster the controller
+	// runs in
+	ClusterKubernetes kubeprovider.KubernetesClientSet
+	InstanceGroup     *v1alpha1.InstanceGroup
+	Configuration     *corev1.ConfigMap
+	Log               logr.Logger
+	ConfigRetention   int
+	LifecycleManager  LifecycleManagerConfiguration
+	BootstrapBucket   BootstrapBucketConfiguration
+	ResourceNames     ResourceNameConfiguration
+	SecurityPolicy    v1alpha1.SecurityPolicy
+}
+
+// LifecycleManagerConfiguration is the default notification target of lifecycle hooks which are handled by lifecycle-manager
+type LifecycleManagerConfiguration struct {
+	NotificationArn string
+	RoleArn         string
+}
+
+// BootstrapBucketConfiguration is the S3 location where user data which exceeds the maximum size is uploaded
+type BootstrapBucketConfiguration struct {
+	Name   string
+	Prefix string
+}
+
+// Enabled returns true if a bootstrap bucket is configured
+func (c BootstrapBucketConfiguration) Enabled() bool {
+	return c.Name != ""
 }
 
 var (
-	NonRetryableStates = []v1alpha1.ReconcileState{v1alpha1.ReconcileErr, v1alpha1.ReconcileReady, v1alpha1.ReconcileDeleted}
+	NonRetryableStates = []v1alpha1.ReconcileState{v1alpha1.ReconcileErr, v1alpha1.ReconcileReady, v1alpha1.ReconcileDeleted, v1alpha1.ReconcileSuspended, v1alpha1.ReconcileDeferred}
 )
 
 func IsRetryable(instanceGroup *v1alpha1.InstanceGroup) bool {
@@ -0,0 +1,76 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioners
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/keikoproj/instance-manager/api/v1alpha1"
+	"github.com/keikoproj/instance-manager/controllers/common"
+)
+
+// TemplateFields are the fields of an InstanceGroupTemplate's spec which are inherited by instance groups, they are
+// merged into the same field of spec.eks.configuration
+var TemplateFields = []string{
+	"securityGroups",
+	"volumes",
+	"tags",
+	"userData",
+}
+
+// ApplyTemplate merges the configuration of an InstanceGroupTemplate into an instance group, volumes and tags are
+// merged by name and key with the instance group's values taking precedence, security groups and user data stages
+// of the instance group are added to those of the template
+func ApplyTemplate(instanceGroup *v1alpha1.InstanceGroup, template *v1alpha1.InstanceGroupTemplate) error {
+	unstructuredInstanceGroup, err := runtime.DefaultUnstructuredConverter.ToUnstructured(instanceGroup)
+	if err != nil {
+		return errors.Wrap(err, "failed to convert instance group to unstructured")
+	}
+
+	unstructuredTemplate, err := runtime.DefaultUnstructuredConverter.ToUnstructured(template)
+	if err != nil {
+		return errors.Wrap(err, "failed to convert instance group template to unstructured")
+	}
+
+	for _, field := range TemplateFields {
+		var (
+			pathStr     = fmt.Sprintf("%v.%v", EKSConfigurationPath, field)
+			templateVal = common.FieldValue(fmt.Sprintf("spec.%v", field), unstructuredTemplate)
+			resourceVal = common.FieldValue(pathStr, unstructuredInstanceGroup)
+		)
+
+		if templateVal == nil {
+			continue
+		}
+
+		if isConflict(templateVal, resourceVal) {
+			templateVal = Merge(templateVal, resourceVal, pathStr, true)
+		}
+		if err := common.SetFieldValue(pathStr, unstructuredInstanceGroup, templateVal); err != nil {
+			return errors.Wrapf(err, "failed to set field %v", pathStr)
+		}
+	}
+
+	err = runtime.DefaultUnstructuredConverter.FromUnstructured(unstructuredInstanceGroup, instanceGroup)
+	if err != nil {
+		return errors.Wrap(err, "failed to convert instance group from unstructured")
+	}
+
+	return nil
+}
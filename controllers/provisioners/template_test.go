@@ -0,0 +1,67 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioners
+
+import (
+	"testing"
+
+	"github.com/keikoproj/instance-manager/api/v1alpha1"
+	"github.com/onsi/gomega"
+)
+
+func TestApplyTemplate(t *testing.T) {
+	var (
+		g = gomega.NewGomegaWithT(t)
+	)
+
+	template := &v1alpha1.InstanceGroupTemplate{
+		Spec: v1alpha1.InstanceGroupTemplateSpec{
+			NodeSecurityGroups: []string{"sg-123456789012"},
+			Volumes:            []v1alpha1.NodeVolume{MockVolume("/dev/xvda", "gp2", 30), MockVolume("/dev/xvdb", "gp2", 100)},
+			Tags:               []map[string]string{MockTag("team", "platform"), MockTag("env", "prod")},
+			UserData: []v1alpha1.UserDataStage{
+				{Name: "hardening", Stage: "PreBootstrap", Data: "echo hardening"},
+			},
+		},
+	}
+
+	cr := MockResource()
+	cr.Spec.EKSSpec.EKSConfiguration.EksClusterName = "someCluster"
+	cr.Spec.EKSSpec.EKSConfiguration.NodeSecurityGroups = []string{"sg-000000000000"}
+	cr.Spec.EKSSpec.EKSConfiguration.Volumes = []v1alpha1.NodeVolume{MockVolume("/dev/xvda", "gp3", 50)}
+	cr.Spec.EKSSpec.EKSConfiguration.Tags = []map[string]string{MockTag("env", "staging")}
+
+	err := ApplyTemplate(cr, template)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	config := cr.GetEKSConfiguration()
+	g.Expect(config.NodeSecurityGroups).To(gomega.ConsistOf("sg-123456789012", "sg-000000000000"))
+	g.Expect(config.Volumes).To(gomega.ConsistOf(MockVolume("/dev/xvda", "gp3", 50), MockVolume("/dev/xvdb", "gp2", 100)))
+	g.Expect(config.Tags).To(gomega.ConsistOf(MockTag("team", "platform"), MockTag("env", "staging")))
+	g.Expect(config.UserData).To(gomega.Equal(template.Spec.UserData))
+	g.Expect(config.EksClusterName).To(gomega.Equal("someCluster"))
+
+	// the template is not changed by merging
+	g.Expect(template.Spec.Volumes).To(gomega.ConsistOf(MockVolume("/dev/xvda", "gp2", 30), MockVolume("/dev/xvdb", "gp2", 100)))
+
+	// an empty template leaves the instance group unchanged
+	cr = MockResource()
+	cr.Spec.EKSSpec.EKSConfiguration.NodeSecurityGroups = []string{"sg-000000000000"}
+	err = ApplyTemplate(cr, &v1alpha1.InstanceGroupTemplate{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(cr.GetEKSConfiguration().NodeSecurityGroups).To(gomega.Equal([]string{"sg-000000000000"}))
+	g.Expect(cr.GetEKSConfiguration().Volumes).To(gomega.BeEmpty())
+}
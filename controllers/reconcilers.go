@@ -19,10 +19,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"reflect"
 	"strings"
 	"time"
 
 	v1alpha1 "github.com/keikoproj/instance-manager/api/v1alpha1"
+	"github.com/keikoproj/instance-manager/controllers/common"
 	awsprovider "github.com/keikoproj/instance-manager/controllers/providers/aws"
 	kubeprovider "github.com/keikoproj/instance-manager/controllers/providers/kubernetes"
 	"github.com/keikoproj/instance-manager/controllers/provisioners"
@@ -30,21 +32,41 @@ import (
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 const (
 	ConfigMapName = "instance-manager"
+
+	// nodes which were created within this window are considered to have joined when their create event is seen,
+	// older nodes are seen when the controller starts
+	NodeJoinWindow = 10 * time.Minute
 )
 
 func (r *InstanceGroupReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Backoff == nil {
+		r.Backoff = NewRequeueBackoff()
+	}
+	if r.RemoteClusters == nil {
+		r.RemoteClusters = NewRemoteClusterClients()
+	}
+
+	var (
+		c   controller.Controller
+		err error
+	)
+
 	switch r.NodeRelabel {
 	case true:
-		return ctrl.NewControllerManagedBy(mgr).
+		c, err = ctrl.NewControllerManagedBy(mgr).
 			For(&v1alpha1.InstanceGroup{}).
 			Watches(&source.Kind{Type: &corev1.Event{}}, &handler.EnqueueRequestsFromMapFunc{
 				ToRequests: handler.ToRequestsFunc(r.spotEventReconciler),
@@ -55,10 +77,21 @@ func (r *InstanceGroupReconciler) SetupWithManager(mgr ctrl.Manager) error {
 			Watches(&source.Kind{Type: &corev1.ConfigMap{}}, &handler.EnqueueRequestsFromMapFunc{
 				ToRequests: handler.ToRequestsFunc(r.configMapReconciler),
 			}).
+			Watches(&source.Kind{Type: &v1alpha1.InstanceGroupTemplate{}}, &handler.EnqueueRequestsFromMapFunc{
+				ToRequests: handler.ToRequestsFunc(r.templateReconciler),
+			}).
+			Watches(&source.Kind{Type: &v1alpha1.ClusterConfiguration{}}, &handler.EnqueueRequestsFromMapFunc{
+				ToRequests: handler.ToRequestsFunc(r.clusterConfigurationReconciler),
+			}).
+			Watches(&source.Kind{Type: &corev1.Node{}}, handler.Funcs{
+				CreateFunc: r.nodeJoinReconciler,
+				UpdateFunc: r.nodeProtectionReconciler,
+			}).
+			WithEventFilter(predicate.Funcs{UpdateFunc: instanceGroupUpdateFilter}).
 			WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxParallel}).
-			Complete(r)
+			Build(r)
 	default:
-		return ctrl.NewControllerManagedBy(mgr).
+		c, err = ctrl.NewControllerManagedBy(mgr).
 			For(&v1alpha1.InstanceGroup{}).
 			Watches(&source.Kind{Type: &corev1.Event{}}, &handler.EnqueueRequestsFromMapFunc{
 				ToRequests: handler.ToRequestsFunc(r.spotEventReconciler),
@@ -66,9 +99,79 @@ func (r *InstanceGroupReconciler) SetupWithManager(mgr ctrl.Manager) error {
 			Watches(&source.Kind{Type: &corev1.ConfigMap{}}, &handler.EnqueueRequestsFromMapFunc{
 				ToRequests: handler.ToRequestsFunc(r.configMapReconciler),
 			}).
+			Watches(&source.Kind{Type: &v1alpha1.InstanceGroupTemplate{}}, &handler.EnqueueRequestsFromMapFunc{
+				ToRequests: handler.ToRequestsFunc(r.templateReconciler),
+			}).
+			Watches(&source.Kind{Type: &v1alpha1.ClusterConfiguration{}}, &handler.EnqueueRequestsFromMapFunc{
+				ToRequests: handler.ToRequestsFunc(r.clusterConfigurationReconciler),
+			}).
+			Watches(&source.Kind{Type: &corev1.Node{}}, handler.Funcs{
+				CreateFunc: r.nodeJoinReconciler,
+				UpdateFunc: r.nodeProtectionReconciler,
+			}).
+			WithEventFilter(predicate.Funcs{UpdateFunc: instanceGroupUpdateFilter}).
 			WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxParallel}).
-			Complete(r)
+			Build(r)
+	}
+	if err != nil {
+		return err
+	}
+
+	if r.CloudEvents != nil {
+		if err := c.Watch(&source.Channel{Source: r.CloudEvents}, &handler.EnqueueRequestForObject{}); err != nil {
+			return err
+		}
+	}
+
+	if r.ClusterAPIProvider {
+		if r.MachinePoolAPIVersion == "" {
+			r.MachinePoolAPIVersion = DefaultMachinePoolAPIVersion
+		}
+		machinePool := &unstructured.Unstructured{}
+		machinePool.SetGroupVersionKind(schema.FromAPIVersionAndKind(r.MachinePoolAPIVersion, v1alpha1.MachinePoolKind))
+		if err := c.Watch(&source.Kind{Type: machinePool}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.machinePoolReconciler),
+		}); err != nil {
+			return err
+		}
 	}
+
+	// critical instance groups are reconciled before others when the queue is deep
+	return SetControllerQueue(c, func() workqueue.RateLimitingInterface {
+		return NewPriorityQueue(r.reconcilePriority, workqueue.DefaultControllerRateLimiter())
+	})
+}
+
+// instanceGroupUpdateFilter drops updates of an instance group which only change its status, the controller writes
+// the status on every reconcile and reconciling on these writes would bypass the requeue backoff of failing instance
+// groups, updates of other objects are not filtered
+func instanceGroupUpdateFilter(e event.UpdateEvent) bool {
+	if _, ok := e.ObjectNew.(*v1alpha1.InstanceGroup); !ok || e.MetaOld == nil || e.MetaNew == nil {
+		return true
+	}
+	if e.MetaOld.GetGeneration() != e.MetaNew.GetGeneration() {
+		return true
+	}
+	// annotations, labels and finalizers are not part of the generation
+	return !reflect.DeepEqual(e.MetaOld.GetAnnotations(), e.MetaNew.GetAnnotations()) ||
+		!reflect.DeepEqual(e.MetaOld.GetLabels(), e.MetaNew.GetLabels()) ||
+		!reflect.DeepEqual(e.MetaOld.GetFinalizers(), e.MetaNew.GetFinalizers()) ||
+		!e.MetaOld.GetDeletionTimestamp().Equal(e.MetaNew.GetDeletionTimestamp())
+}
+
+// reconcilePriority returns the priority of a queued reconcile request based on the instance group's reconcilePriority
+func (r *InstanceGroupReconciler) reconcilePriority(item interface{}) int {
+	defaultPriority := ReconcilePriorityWeights[v1alpha1.NormalReconcilePriority]
+	req, ok := item.(ctrl.Request)
+	if !ok {
+		return defaultPriority
+	}
+
+	instanceGroup := &v1alpha1.InstanceGroup{}
+	if err := r.Get(context.Background(), req.NamespacedName, instanceGroup); err != nil {
+		return defaultPriority
+	}
+	return ReconcilePriorityWeights[instanceGroup.GetReconcilePriority()]
 }
 
 func (r *InstanceGroupReconciler) configMapReconciler(obj handler.MapObject) []ctrl.Request {
@@ -85,41 +188,52 @@ func (r *InstanceGroupReconciler) configMapReconciler(obj handler.MapObject) []c
 			Name:      name,
 		}
 
+		var (
+			previous = r.GetConfigMap()
+			current  = &corev1.ConfigMap{}
+		)
 		err := r.Get(context.Background(), namespacedName, obj.Object)
 		if err != nil {
-			if kerrors.IsNotFound(err) {
-				r.Log.Info("configmap deleted", "object", namespacedName)
-				r.ConfigMap = &corev1.ConfigMap{}
+			if !kerrors.IsNotFound(err) {
+				r.Log.Error(err, "could not get configmap")
 				return nil
 			}
-			r.Log.Error(err, "could not get configmap")
-			return nil
+			r.Log.Info("configmap deleted", "object", namespacedName)
+		} else {
+			current = obj.Object.(*corev1.ConfigMap)
 		}
 
-		r.ConfigMap = obj.Object.(*corev1.ConfigMap)
-		configHash := kubeprovider.ConfigmapHash(r.ConfigMap)
+		// the configmap is applied without a restart, reconciles which start from now on use the new configmap
+		r.SetConfigMap(current)
+		configHash := kubeprovider.ConfigmapHash(current)
 
 		ctrl.Log.Info("configmap MD5", "hash", configHash)
 
 		var instanceGroupList v1alpha1.InstanceGroupList
 		err = r.List(context.Background(), &instanceGroupList)
 		if err != nil {
-			ctrl.Log.Error(err, "failed to convert to configmap")
+			ctrl.Log.Error(err, "failed to list instance groups")
 			return nil
 		}
 
+		// only instance groups whose effective configuration changed are reconciled, changes to the defaults and
+		// boundaries of fields an instance group overrides do not affect it
 		requests := make([]ctrl.Request, 0)
-		for _, instanceGroup := range instanceGroupList.Items {
-			if instanceGroup.Status.ConfigHash != configHash {
-				namespacedName := types.NamespacedName{}
-				namespacedName.Name = instanceGroup.GetName()
-				namespacedName.Namespace = instanceGroup.GetNamespace()
-				ctrl.Log.Info("found config diff for instancegroup", "instancegroup", namespacedName, "old", instanceGroup.Status.ConfigHash, "new", configHash)
-				r := ctrl.Request{
-					NamespacedName: namespacedName,
-				}
-				requests = append(requests, r)
+		for i := range instanceGroupList.Items {
+			instanceGroup := &instanceGroupList.Items[i]
+			if instanceGroup.Status.ConfigHash == configHash {
+				continue
+			}
+			if !provisioners.EffectiveConfigurationChanged(previous, current, instanceGroup) {
+				continue
 			}
+			namespacedName := types.NamespacedName{}
+			namespacedName.Name = instanceGroup.GetName()
+			namespacedName.Namespace = instanceGroup.GetNamespace()
+			ctrl.Log.Info("found config diff for instancegroup", "instancegroup", namespacedName, "old", instanceGroup.Status.ConfigHash, "new", configHash)
+			requests = append(requests, ctrl.Request{
+				NamespacedName: namespacedName,
+			})
 		}
 
 		return requests
@@ -127,6 +241,64 @@ func (r *InstanceGroupReconciler) configMapReconciler(obj handler.MapObject) []c
 	return nil
 }
 
+// GetConfigMap returns the controller configmap, or an empty configmap if it does not exist
+func (r *InstanceGroupReconciler) GetConfigMap() *corev1.ConfigMap {
+	r.configMapLock.RLock()
+	defer r.configMapLock.RUnlock()
+	if r.ConfigMap == nil {
+		return &corev1.ConfigMap{}
+	}
+	return r.ConfigMap
+}
+
+// SetConfigMap replaces the controller configmap
+func (r *InstanceGroupReconciler) SetConfigMap(cm *corev1.ConfigMap) {
+	r.configMapLock.Lock()
+	defer r.configMapLock.Unlock()
+	r.ConfigMap = cm
+}
+
+// templateReconciler enqueues the instance groups which reference an instance group template when it changes
+func (r *InstanceGroupReconciler) templateReconciler(obj handler.MapObject) []ctrl.Request {
+	name := obj.Meta.GetName()
+	return r.referencingRequests(func(instanceGroup *v1alpha1.InstanceGroup) bool {
+		return instanceGroup.GetTemplateRef() == name
+	}, "referenced instance group template changed", "template", name)
+}
+
+// clusterConfigurationReconciler enqueues the instance groups which reference a cluster configuration when it changes
+func (r *InstanceGroupReconciler) clusterConfigurationReconciler(obj handler.MapObject) []ctrl.Request {
+	name := obj.Meta.GetName()
+	return r.referencingRequests(func(instanceGroup *v1alpha1.InstanceGroup) bool {
+		return instanceGroup.GetClusterRef() == name
+	}, "referenced cluster configuration changed", "clusterconfiguration", name)
+}
+
+// referencingRequests returns requests for the instance groups of all namespaces which reference a changed
+// cluster-scoped resource
+func (r *InstanceGroupReconciler) referencingRequests(references func(*v1alpha1.InstanceGroup) bool, msg, kind, name string) []ctrl.Request {
+	var instanceGroupList v1alpha1.InstanceGroupList
+	if err := r.List(context.Background(), &instanceGroupList); err != nil {
+		r.Log.Error(err, "failed to list instance groups")
+		return nil
+	}
+
+	requests := make([]ctrl.Request, 0)
+	for i := range instanceGroupList.Items {
+		instanceGroup := &instanceGroupList.Items[i]
+		if !references(instanceGroup) {
+			continue
+		}
+		namespacedName := types.NamespacedName{
+			Namespace: instanceGroup.GetNamespace(),
+			Name:      instanceGroup.GetName(),
+		}
+		r.Log.Info(msg, "instancegroup", namespacedName, kind, name)
+		requests = append(requests, ctrl.Request{NamespacedName: namespacedName})
+	}
+	return requests
+}
+
 type NodeLabels struct {
 	Labels map[string]string `json:"labels,omitempty"`
 }
@@ -176,6 +348,80 @@ func (r *InstanceGroupReconciler) nodeReconciler(obj handler.MapObject) []ctrl.R
 	return nil
 }
 
+// nodeProtectionReconciler enqueues the instance group of a node when its scale-in protection annotation changes
+func (r *InstanceGroupReconciler) nodeProtectionReconciler(e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+	var (
+		oldValue = e.MetaOld.GetAnnotations()[kubeprovider.ScaleInProtectionAnnotationKey]
+		newValue = e.MetaNew.GetAnnotations()[kubeprovider.ScaleInProtectionAnnotationKey]
+	)
+
+	if oldValue == newValue {
+		return
+	}
+
+	node, ok := e.ObjectNew.(*corev1.Node)
+	if !ok {
+		return
+	}
+
+	instanceID := common.GetLastElementBy(node.Spec.ProviderID, "/")
+	tags, err := awsprovider.GetScalingGroupTagsByInstanceID(instanceID, r.Auth.Aws.AsgClient)
+	if err != nil {
+		r.Log.Error(err, "failed to get scaling group of node", "node", node.GetName(), "instance", instanceID)
+		return
+	}
+
+	instanceGroup := types.NamespacedName{}
+	instanceGroup.Name = awsprovider.GetTagValueByKey(tags, provisioners.TagInstanceGroupName)
+	instanceGroup.Namespace = awsprovider.GetTagValueByKey(tags, provisioners.TagInstanceGroupNamespace)
+	if instanceGroup.Name == "" || instanceGroup.Namespace == "" {
+		return
+	}
+
+	r.Log.Info("node scale-in protection changed", "node", node.GetName(), "instancegroup", instanceGroup, "value", newValue)
+	q.Add(ctrl.Request{
+		NamespacedName: instanceGroup,
+	})
+}
+
+// nodeJoinReconciler enqueues the instance group of a node which joined the cluster, so that the node is labeled with
+// its ownership, nodes which are already labeled or were created before the join window are left to periodic reconciles
+func (r *InstanceGroupReconciler) nodeJoinReconciler(e event.CreateEvent, q workqueue.RateLimitingInterface) {
+	if _, ok := e.Meta.GetLabels()[kubeprovider.NodeGroupLabelKey]; ok {
+		return
+	}
+	if time.Since(e.Meta.GetCreationTimestamp().Time) > NodeJoinWindow {
+		return
+	}
+
+	node, ok := e.Object.(*corev1.Node)
+	if !ok {
+		return
+	}
+
+	instanceID := common.GetLastElementBy(node.Spec.ProviderID, "/")
+	if instanceID == "" {
+		return
+	}
+	tags, err := awsprovider.GetScalingGroupTagsByInstanceID(instanceID, r.Auth.Aws.AsgClient)
+	if err != nil {
+		r.Log.Error(err, "failed to get scaling group of node", "node", node.GetName(), "instance", instanceID)
+		return
+	}
+
+	instanceGroup := types.NamespacedName{}
+	instanceGroup.Name = awsprovider.GetTagValueByKey(tags, provisioners.TagInstanceGroupName)
+	instanceGroup.Namespace = awsprovider.GetTagValueByKey(tags, provisioners.TagInstanceGroupNamespace)
+	if instanceGroup.Name == "" || instanceGroup.Namespace == "" {
+		return
+	}
+
+	r.Log.Info("node joined", "node", node.GetName(), "instancegroup", instanceGroup)
+	q.Add(ctrl.Request{
+		NamespacedName: instanceGroup,
+	})
+}
+
 func (r *InstanceGroupReconciler) spotEventReconciler(obj handler.MapObject) []ctrl.Request {
 	unstructuredObj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj.Object)
 	if err != nil {
@@ -0,0 +1,73 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	v1alpha1 "github.com/keikoproj/instance-manager/api/v1alpha1"
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func updateEvent(old, new runtime.Object) event.UpdateEvent {
+	return event.UpdateEvent{
+		MetaOld:   old.(metav1.Object),
+		ObjectOld: old,
+		MetaNew:   new.(metav1.Object),
+		ObjectNew: new,
+	}
+}
+
+func TestInstanceGroupUpdateFilter(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	old := &v1alpha1.InstanceGroup{}
+	old.SetName("instance-group-1")
+	old.SetGeneration(1)
+
+	tests := []struct {
+		name   string
+		update func(ig *v1alpha1.InstanceGroup)
+		want   bool
+	}{
+		{name: "status only", update: func(ig *v1alpha1.InstanceGroup) {
+			ig.GetStatus().SetBackoff(&v1alpha1.BackoffStatus{ConsecutiveFailures: 2})
+		}, want: false},
+		{name: "spec", update: func(ig *v1alpha1.InstanceGroup) { ig.SetGeneration(2) }, want: true},
+		{name: "annotations", update: func(ig *v1alpha1.InstanceGroup) {
+			ig.SetAnnotations(map[string]string{v1alpha1.RotateAnnotationKey: "true"})
+		}, want: true},
+		{name: "labels", update: func(ig *v1alpha1.InstanceGroup) { ig.SetLabels(map[string]string{"team": "a"}) }, want: true},
+		{name: "finalizers", update: func(ig *v1alpha1.InstanceGroup) { ig.SetFinalizers([]string{"finalizer"}) }, want: true},
+		{name: "deletion", update: func(ig *v1alpha1.InstanceGroup) {
+			ig.SetDeletionTimestamp(&metav1.Time{})
+		}, want: true},
+	}
+
+	for _, tc := range tests {
+		new := old.DeepCopy()
+		tc.update(new)
+		g.Expect(instanceGroupUpdateFilter(updateEvent(old, new))).To(gomega.Equal(tc.want), tc.name)
+	}
+
+	// updates of other objects are not filtered
+	node := &corev1.Node{}
+	g.Expect(instanceGroupUpdateFilter(updateEvent(node, node.DeepCopy()))).To(gomega.BeTrue())
+}
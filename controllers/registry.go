@@ -0,0 +1,236 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	v1alpha1 "github.com/keikoproj/instance-manager/api/v1alpha1"
+	"github.com/keikoproj/instance-manager/controllers/provisioners"
+	"github.com/keikoproj/instance-manager/controllers/provisioners/eks"
+	"github.com/keikoproj/instance-manager/controllers/provisioners/eksfargate"
+	"github.com/keikoproj/instance-manager/controllers/provisioners/eksmanaged"
+	"github.com/pkg/errors"
+)
+
+// ProvisionerFactory creates the deployer of a reconcile of an instance group
+type ProvisionerFactory func(input provisioners.ProvisionerInput) CloudDeployer
+
+// ProvisionerRegistry maps the names of provisioners to the factories of their deployers, provisioners other than
+// the built-in ones are added with Register before the controller is started
+type ProvisionerRegistry struct {
+	sync.RWMutex
+	factories map[string]ProvisionerFactory
+}
+
+func NewProvisionerRegistry() *ProvisionerRegistry {
+	return &ProvisionerRegistry{
+		factories: make(map[string]ProvisionerFactory),
+	}
+}
+
+// NewDefaultProvisionerRegistry returns a registry of the built-in provisioners
+func NewDefaultProvisionerRegistry() *ProvisionerRegistry {
+	registry := NewProvisionerRegistry()
+	registry.MustRegister(eks.ProvisionerName, func(input provisioners.ProvisionerInput) CloudDeployer {
+		return eks.New(input)
+	})
+	registry.MustRegister(eksmanaged.ProvisionerName, func(input provisioners.ProvisionerInput) CloudDeployer {
+		return eksmanaged.New(input)
+	})
+	registry.MustRegister(eksfargate.ProvisionerName, func(input provisioners.ProvisionerInput) CloudDeployer {
+		return eksfargate.New(input)
+	})
+	return registry
+}
+
+// Register adds a provisioner, names are case insensitive and can only be registered once
+func (r *ProvisionerRegistry) Register(name string, factory ProvisionerFactory) error {
+	if name == "" {
+		return errors.New("provisioner name is required")
+	}
+	if factory == nil {
+		return errors.Errorf("provisioner %v has no factory", name)
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	key := strings.ToLower(name)
+	if _, ok := r.factories[key]; ok {
+		return errors.Errorf("provisioner %v is already registered", name)
+	}
+	r.factories[key] = factory
+	v1alpha1.RegisterProvisioner(key)
+	return nil
+}
+
+// MustRegister adds a provisioner and panics if it cannot be registered
+func (r *ProvisionerRegistry) MustRegister(name string, factory ProvisionerFactory) {
+	if err := r.Register(name, factory); err != nil {
+		panic(err)
+	}
+}
+
+// New returns the deployer of a reconcile of an instance group by the provisioner of the instance group
+func (r *ProvisionerRegistry) New(input provisioners.ProvisionerInput) (CloudDeployer, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	name := input.InstanceGroup.Spec.Provisioner
+	factory, ok := r.factories[strings.ToLower(name)]
+	if !ok {
+		return nil, errors.Errorf("provisioner '%v' does not exist", name)
+	}
+	return factory(input), nil
+}
+
+// Names returns the sorted names of the registered provisioners
+func (r *ProvisionerRegistry) Names() []string {
+	r.RLock()
+	defer r.RUnlock()
+
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Provisioner is the lifecycle of the cloud resources of an instance group, it is a smaller interface than
+// CloudDeployer for provisioners added by third parties, which are adapted with NewProvisionerDeployer
+type Provisioner interface {
+	Discover() error                          // Discover cloud resources
+	Plan() ([]v1alpha1.PlannedChange, error)  // Returns the changes Apply would make to the discovered resources
+	Apply() error                             // Creates or updates cloud resources
+	Delete() error                            // Deletes cloud resources
+	Status() (v1alpha1.ReconcileState, error) // Returns the state of the discovered resources
+}
+
+// provisionerDeployer adapts a Provisioner to a CloudDeployer, the status of the provisioner is one of InitCreate,
+// InitUpdate, InitUpgrade, Deleting, Deleted or Modified, the instance group is Ready once the status is Modified
+type provisionerDeployer struct {
+	instanceGroup *v1alpha1.InstanceGroup
+	provisioner   Provisioner
+	plan          []v1alpha1.PlannedChange
+}
+
+// NewProvisionerDeployer returns a deployer which reconciles an instance group with a Provisioner
+func NewProvisionerDeployer(instanceGroup *v1alpha1.InstanceGroup, provisioner Provisioner) CloudDeployer {
+	return &provisionerDeployer{
+		instanceGroup: instanceGroup,
+		provisioner:   provisioner,
+	}
+}
+
+func (d *provisionerDeployer) CloudDiscovery() error {
+	if err := d.provisioner.Discover(); err != nil {
+		return errors.Wrap(err, "discovery failed")
+	}
+
+	state, err := d.provisioner.Status()
+	if err != nil {
+		return errors.Wrap(err, "failed to get status")
+	}
+	d.SetState(deletionState(d.instanceGroup, state))
+
+	if d.plan, err = d.provisioner.Plan(); err != nil {
+		return errors.Wrap(err, "failed to plan changes")
+	}
+	return nil
+}
+
+// deletionState returns the state of an instance group which is being deleted, resources which would be created are
+// gone, and the state of resources which are being deleted is kept
+func deletionState(instanceGroup *v1alpha1.InstanceGroup, state v1alpha1.ReconcileState) v1alpha1.ReconcileState {
+	if instanceGroup.ObjectMeta.DeletionTimestamp.IsZero() {
+		return state
+	}
+	switch state {
+	case v1alpha1.ReconcileInitCreate:
+		return v1alpha1.ReconcileDeleted
+	case v1alpha1.ReconcileDeleting, v1alpha1.ReconcileDeleted:
+		return state
+	default:
+		return v1alpha1.ReconcileInitDelete
+	}
+}
+
+// StateDiscovery is a no-op, the state is returned by the provisioner's status during discovery
+func (d *provisionerDeployer) StateDiscovery() {}
+
+func (d *provisionerDeployer) Create() error {
+	return d.apply()
+}
+
+func (d *provisionerDeployer) Update() error {
+	return d.apply()
+}
+
+func (d *provisionerDeployer) UpgradeNodes() error {
+	return d.apply()
+}
+
+func (d *provisionerDeployer) apply() error {
+	if err := d.provisioner.Apply(); err != nil {
+		return err
+	}
+	d.SetState(v1alpha1.ReconcileModified)
+	return nil
+}
+
+func (d *provisionerDeployer) Delete() error {
+	if err := d.provisioner.Delete(); err != nil {
+		return err
+	}
+	d.SetState(v1alpha1.ReconcileDeleting)
+	return nil
+}
+
+// BootstrapNodes is a no-op, provisioners bootstrap nodes when resources are applied
+func (d *provisionerDeployer) BootstrapNodes() error {
+	return nil
+}
+
+func (d *provisionerDeployer) GetState() v1alpha1.ReconcileState {
+	return d.instanceGroup.GetState()
+}
+
+func (d *provisionerDeployer) SetState(state v1alpha1.ReconcileState) {
+	d.instanceGroup.SetState(state)
+}
+
+func (d *provisionerDeployer) IsReady() bool {
+	state := d.GetState()
+	return state == v1alpha1.ReconcileModified || state == v1alpha1.ReconcileReady
+}
+
+// Plan implements Planner with the changes returned by the provisioner during discovery
+func (d *provisionerDeployer) Plan() []v1alpha1.PlannedChange {
+	return d.plan
+}
+
+// PendingChanges implements DriftDetector with the planned changes
+func (d *provisionerDeployer) PendingChanges() []string {
+	pending := make([]string, 0, len(d.plan))
+	for _, change := range d.plan {
+		pending = append(pending, change.String())
+	}
+	return pending
+}
@@ -0,0 +1,79 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sync"
+
+	v1alpha1 "github.com/keikoproj/instance-manager/api/v1alpha1"
+	kubeprovider "github.com/keikoproj/instance-manager/controllers/providers/kubernetes"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// RemoteClusterClients caches the clients of remote clusters by the kubeconfig Secret they are created from, so that
+// instance groups of the same cluster share clients and clients are only created again when the kubeconfig changes
+type RemoteClusterClients struct {
+	sync.Mutex
+	clients map[string]remoteClusterClient
+}
+
+type remoteClusterClient struct {
+	hash      string
+	clientSet kubeprovider.KubernetesClientSet
+}
+
+func NewRemoteClusterClients() *RemoteClusterClients {
+	return &RemoteClusterClients{
+		clients: make(map[string]remoteClusterClient),
+	}
+}
+
+// Get returns the clients of the cluster whose kubeconfig an instance group refers to
+func (c *RemoteClusterClients) Get(kube kubernetes.Interface, instanceGroup *v1alpha1.InstanceGroup) (kubeprovider.KubernetesClientSet, error) {
+	var (
+		ref       = instanceGroup.GetKubeconfigSecretRef()
+		namespace = instanceGroup.GetNamespace()
+		key       = fmt.Sprintf("%v/%v/%v", namespace, ref.Name, ref.GetKey())
+	)
+
+	secret, err := kube.CoreV1().Secrets(namespace).Get(ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return kubeprovider.KubernetesClientSet{}, errors.Wrapf(err, "failed to get kubeconfig secret %v/%v", namespace, ref.Name)
+	}
+	kubeconfig, ok := secret.Data[ref.GetKey()]
+	if !ok {
+		return kubeprovider.KubernetesClientSet{}, errors.Errorf("kubeconfig secret %v/%v does not have key '%v'", namespace, ref.Name, ref.GetKey())
+	}
+	hash := fmt.Sprintf("%x", sha256.Sum256(kubeconfig))
+
+	c.Lock()
+	defer c.Unlock()
+
+	if cached, ok := c.clients[key]; ok && cached.hash == hash {
+		return cached.clientSet, nil
+	}
+
+	clientSet, err := kubeprovider.NewKubernetesClientSet(kubeconfig)
+	if err != nil {
+		return kubeprovider.KubernetesClientSet{}, errors.Wrapf(err, "failed to create clients from kubeconfig secret %v/%v", namespace, ref.Name)
+	}
+	c.clients[key] = remoteClusterClient{hash: hash, clientSet: clientSet}
+	return clientSet, nil
+}
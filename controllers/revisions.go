@@ -0,0 +1,194 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	v1alpha1 "github.com/keikoproj/instance-manager/api/v1alpha1"
+	kubeprovider "github.com/keikoproj/instance-manager/controllers/providers/kubernetes"
+	"github.com/keikoproj/instance-manager/controllers/provisioners/eks"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultRevisionHistoryLimit is the number of rolled out configurations kept in the revision history
+	DefaultRevisionHistoryLimit = 10
+	// DefaultAuditRevisionLimit is the number of InstanceGroupRevisions kept for each instance group
+	DefaultAuditRevisionLimit = 20
+)
+
+// recordRevision adds the configuration of an instance group which was rolled out to all nodes to its revision
+// history, the configuration of the instance group's spec is stored in its revisions ConfigMap with the image pinned
+// to the resolved image, so that rolling back to it does not resolve a newer image
+func (r *InstanceGroupReconciler) recordRevision(instanceGroup, resolved *v1alpha1.InstanceGroup) {
+	if r.RevisionHistoryLimit <= 0 || !strings.EqualFold(resolved.Spec.Provisioner, eks.ProvisionerName) {
+		return
+	}
+
+	status := resolved.GetStatus()
+	latest := status.GetLatestRevision()
+	if latest != nil && latest.ConfigurationHash == status.GetConfigurationHash() {
+		return
+	}
+
+	var (
+		resolvedConfiguration = resolved.GetEKSConfiguration()
+		configuration         = instanceGroup.GetEKSConfiguration().DeepCopy()
+		revision              = v1alpha1.ConfigurationRevision{
+			Revision:          1,
+			ConfigurationHash: status.GetConfigurationHash(),
+			Image:             resolvedConfiguration.Image,
+			InstanceType:      resolvedConfiguration.InstanceType,
+			UserDataHash:      hashUserData(resolvedConfiguration.GetUserData()),
+			RolledOutAt:       metav1.Time{Time: time.Now()},
+		}
+	)
+	if latest != nil {
+		revision.Revision = latest.Revision + 1
+	}
+	configuration.Image = revision.Image
+	configuration.AutoUpgrade = false
+	configuration.ImagePipelineArn = ""
+
+	history := resolved.DeepCopy()
+	history.GetStatus().AddRevision(revision, r.RevisionHistoryLimit)
+	if err := kubeprovider.WriteRevision(r.Auth.Kubernetes.KubeDynamic, history, revision.Revision, configuration); err != nil {
+		r.Log.Error(err, "failed to record revision", "instancegroup", resolved.NamespacedName(), "revision", revision.Revision)
+		return
+	}
+	status.AddRevision(revision, r.RevisionHistoryLimit)
+
+	r.Log.Info("recorded revision", "instancegroup", resolved.NamespacedName(), "revision", revision.Revision, "image", revision.Image, "instanceType", revision.InstanceType)
+	r.eventPublisher(instanceGroup).Publish(kubeprovider.RevisionRecordedEvent, "instancegroup", instanceGroup.GetName(), "revision", fmt.Sprint(revision.Revision))
+}
+
+// recordInstanceGroupRevision records a change applied to an instance group and the outcome of its rollout as an
+// InstanceGroupRevision, the revision of a generation and configuration is updated when its outcome changes, e.g. when
+// a failed rotation later succeeds
+func (r *InstanceGroupReconciler) recordInstanceGroupRevision(instanceGroup, resolved *v1alpha1.InstanceGroup, outcome string, rotationErr string) {
+	if r.AuditRevisionLimit <= 0 || !strings.EqualFold(resolved.Spec.Provisioner, eks.ProvisionerName) {
+		return
+	}
+
+	var (
+		status                = resolved.GetStatus()
+		resolvedConfiguration = resolved.GetEKSConfiguration()
+		configurationHash     = resolved.HashConfiguration()
+	)
+
+	revision := &v1alpha1.InstanceGroupRevision{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: fmt.Sprintf("%v-%v-%v", instanceGroup.GetName(), instanceGroup.GetGeneration(), configurationHash[:8]),
+		},
+		Spec: v1alpha1.InstanceGroupRevisionSpec{
+			InstanceGroup:           instanceGroup.GetName(),
+			Generation:              instanceGroup.GetGeneration(),
+			ConfigurationHash:       configurationHash,
+			LaunchConfigurationName: status.GetActiveLaunchConfigurationName(),
+			Image:                   resolvedConfiguration.Image,
+			InstanceType:            resolvedConfiguration.InstanceType,
+			Outcome:                 outcome,
+			Error:                   rotationErr,
+			Actor:                   kubeprovider.GetSpecManager(instanceGroup),
+			Configuration:           instanceGroup.GetEKSConfiguration().DeepCopy(),
+			RecordedAt:              metav1.Time{Time: time.Now()},
+		},
+	}
+
+	if err := kubeprovider.WriteInstanceGroupRevision(r.Auth.Kubernetes.KubeDynamic, instanceGroup, revision, r.AuditRevisionLimit); err != nil {
+		r.Log.Error(err, "failed to record instance group revision", "instancegroup", resolved.NamespacedName(), "revision", revision.GetName())
+	}
+}
+
+// handleRollbackRequest rolls back the configuration of an instance group annotated for rollback, the configuration
+// of the revision replaces spec.eks.configuration and the annotation is removed with a single patch, the next
+// reconcile creates a launch configuration or template of the revision and rotates the nodes, returns true when the
+// instance group was rolled back
+func (r *InstanceGroupReconciler) handleRollbackRequest(instanceGroup *v1alpha1.InstanceGroup) bool {
+	request := instanceGroup.GetRollbackRequest()
+	if request == "" || !instanceGroup.ObjectMeta.DeletionTimestamp.IsZero() {
+		return false
+	}
+
+	revision, err := r.rollback(instanceGroup, request)
+	if err != nil {
+		r.Log.Error(err, "failed to roll back instancegroup", "instancegroup", instanceGroup.NamespacedName(), "request", request)
+		r.eventPublisher(instanceGroup).Publish(kubeprovider.RollbackFailedEvent, "instancegroup", instanceGroup.GetName(), "request", request, "error", err.Error())
+
+		patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:null}}}`, v1alpha1.RollbackAnnotationKey)
+		if err := r.Patch(context.Background(), instanceGroup, client.ConstantPatch(types.MergePatchType, []byte(patch))); err != nil {
+			r.Log.Error(err, "failed to remove rollback annotation", "instancegroup", instanceGroup.NamespacedName())
+		}
+		return false
+	}
+
+	r.Log.Info("rolled back instancegroup", "instancegroup", instanceGroup.NamespacedName(), "revision", revision.Revision, "image", revision.Image)
+	r.eventPublisher(instanceGroup).Publish(kubeprovider.RolledBackEvent, "instancegroup", instanceGroup.GetName(), "revision", fmt.Sprint(revision.Revision), "image", revision.Image)
+	return true
+}
+
+func (r *InstanceGroupReconciler) rollback(instanceGroup *v1alpha1.InstanceGroup, request string) (*v1alpha1.ConfigurationRevision, error) {
+	if !strings.EqualFold(instanceGroup.Spec.Provisioner, eks.ProvisionerName) || instanceGroup.Spec.EKSSpec == nil {
+		return nil, errors.Errorf("rollback is only supported with the %v provisioner", eks.ProvisionerName)
+	}
+
+	rollbackRevision, err := instanceGroup.GetRollbackRevision(request)
+	if err != nil {
+		return nil, err
+	}
+	// the revision is copied since the status is replaced with the patched object
+	revision := rollbackRevision.DeepCopy()
+
+	configuration, err := kubeprovider.GetRevisionConfiguration(r.Auth.Kubernetes.KubeDynamic, instanceGroup, revision.Revision)
+	if err != nil {
+		return nil, err
+	}
+
+	annotation := strings.Replace(v1alpha1.RollbackAnnotationKey, "/", "~1", -1)
+	patch, err := json.Marshal([]map[string]interface{}{
+		{"op": "replace", "path": "/spec/eks/configuration", "value": configuration},
+		{"op": "remove", "path": fmt.Sprintf("/metadata/annotations/%v", annotation)},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal rollback patch")
+	}
+
+	if err := r.Patch(context.Background(), instanceGroup, client.ConstantPatch(types.JSONPatchType, patch)); err != nil {
+		return nil, errors.Wrapf(err, "failed to roll back to revision %v", revision.Revision)
+	}
+	return revision, nil
+}
+
+// hashUserData returns the hash of the user data stages of a configuration
+func hashUserData(userData []v1alpha1.UserDataStage) string {
+	if len(userData) == 0 {
+		return ""
+	}
+	raw, err := json.Marshal(userData)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(raw))
+}
@@ -0,0 +1,70 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// Fixtures are a snapshot of AWS state, the responses of API calls are keyed by service and operation, e.g.
+// autoscaling.DescribeAutoScalingGroups, and are in the shape of the AWS API output, services are named by their
+// endpoint prefix, e.g. autoscaling, ec2, eks, iam, ssm and monitoring for CloudWatch
+type Fixtures struct {
+	// Responses are the outputs of API calls, calls without a response return an empty output
+	Responses map[string]json.RawMessage `json:"responses,omitempty"`
+	// Errors are the errors of API calls, e.g. NoSuchEntity for iam.GetRole of a role which does not exist
+	Errors map[string]FixtureError `json:"errors,omitempty"`
+}
+
+// FixtureError is the error an API call fails with
+type FixtureError struct {
+	Code       string `json:"code"`
+	Message    string `json:"message,omitempty"`
+	StatusCode int    `json:"statusCode,omitempty"`
+}
+
+// ReadFixtures decodes fixtures from JSON
+func ReadFixtures(r io.Reader) (*Fixtures, error) {
+	raw, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read fixtures")
+	}
+	fixtures := &Fixtures{}
+	if err := json.Unmarshal(raw, fixtures); err != nil {
+		return nil, errors.Wrap(err, "failed to decode fixtures")
+	}
+	return fixtures, nil
+}
+
+// LoadFixtures decodes fixtures from a JSON file
+func LoadFixtures(path string) (*Fixtures, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open fixtures %v", path)
+	}
+	defer f.Close()
+	return ReadFixtures(f)
+}
+
+func fixtureKey(service, operation string) string {
+	return fmt.Sprintf("%v.%v", service, operation)
+}
@@ -0,0 +1,149 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+var (
+	// readOnlyPrefixes are the prefixes of operations which do not change AWS state
+	readOnlyPrefixes = []string{"Describe", "List", "Get", "Head"}
+
+	// launch configurations are named with a timestamp suffix, it is replaced so that mutations are deterministic
+	rxTimestampSuffix = regexp.MustCompile(`-\d{14}\b`)
+)
+
+// Mutation is an AWS API call which changes AWS state
+type Mutation struct {
+	Service   string          `json:"service"`
+	Operation string          `json:"operation"`
+	Input     json.RawMessage `json:"input,omitempty"`
+}
+
+// String returns the service, operation and input of the mutation
+func (m Mutation) String() string {
+	return fmt.Sprintf("%v.%v %s", m.Service, m.Operation, m.Input)
+}
+
+// Recorder serves the API calls of sessions from fixtures, calls which change AWS state are recorded in order
+// instead of being sent
+type Recorder struct {
+	sync.Mutex
+	Fixtures  *Fixtures
+	mutations []Mutation
+}
+
+func NewRecorder(fixtures *Fixtures) *Recorder {
+	if fixtures == nil {
+		fixtures = &Fixtures{}
+	}
+	return &Recorder{Fixtures: fixtures, mutations: make([]Mutation, 0)}
+}
+
+// NewSession returns a session of the region whose calls are served by the recorder, calls are never sent to AWS
+func (r *Recorder) NewSession(region string) *session.Session {
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region:      aws.String(region),
+		Credentials: credentials.NewStaticCredentials("simulator", "simulator", ""),
+		MaxRetries:  aws.Int(0),
+	}))
+
+	sess.Handlers.Sign.Clear()
+	sess.Handlers.Send.Clear()
+	sess.Handlers.Send.PushBack(r.send)
+	sess.Handlers.UnmarshalMeta.Clear()
+	sess.Handlers.ValidateResponse.Clear()
+	sess.Handlers.Unmarshal.Clear()
+	sess.Handlers.UnmarshalError.Clear()
+	return sess
+}
+
+// Mutations returns the recorded mutations in the order they were made
+func (r *Recorder) Mutations() []Mutation {
+	r.Lock()
+	defer r.Unlock()
+	mutations := make([]Mutation, len(r.mutations))
+	copy(mutations, r.mutations)
+	return mutations
+}
+
+func (r *Recorder) send(req *request.Request) {
+	var (
+		service   = req.ClientInfo.ServiceName
+		operation = req.Operation.Name
+		key       = fixtureKey(service, operation)
+	)
+
+	req.HTTPResponse = &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(bytes.NewReader(nil)),
+	}
+
+	if !isReadOnly(operation) {
+		r.record(service, operation, req.Params)
+	}
+
+	if fixtureErr, ok := r.Fixtures.Errors[key]; ok {
+		statusCode := fixtureErr.StatusCode
+		if statusCode == 0 {
+			statusCode = http.StatusBadRequest
+		}
+		req.HTTPResponse.StatusCode = statusCode
+		req.Error = awserr.NewRequestFailure(awserr.New(fixtureErr.Code, fixtureErr.Message, nil), statusCode, "simulator")
+		return
+	}
+
+	if raw, ok := r.Fixtures.Responses[key]; ok && req.Data != nil {
+		if err := json.Unmarshal(raw, req.Data); err != nil {
+			req.Error = awserr.New(request.ErrCodeSerialization, fmt.Sprintf("failed to decode fixture %v", key), err)
+		}
+	}
+}
+
+func (r *Recorder) record(service, operation string, params interface{}) {
+	var input []byte
+	if raw, err := json.Marshal(params); err == nil {
+		input = rxTimestampSuffix.ReplaceAll(compact(raw), []byte("-<timestamp>"))
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	r.mutations = append(r.mutations, Mutation{Service: service, Operation: operation, Input: input})
+}
+
+func isReadOnly(operation string) bool {
+	for _, prefix := range readOnlyPrefixes {
+		if strings.HasPrefix(operation, prefix) {
+			return true
+		}
+	}
+	return false
+}
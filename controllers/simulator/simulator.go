@@ -0,0 +1,258 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package simulator runs the reconcile of an instance group against a snapshot of AWS state, no API calls are sent
+// to AWS and the calls which would change AWS state are returned in the order the controller would make them
+package simulator
+
+import (
+	"encoding/json"
+
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/imagebuilder"
+	"github.com/aws/aws-sdk-go/service/outposts"
+	"github.com/aws/aws-sdk-go/service/pricing"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/savingsplans"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/go-logr/logr"
+	"github.com/keikoproj/instance-manager/api/v1alpha1"
+	"github.com/keikoproj/instance-manager/controllers"
+	awsprovider "github.com/keikoproj/instance-manager/controllers/providers/aws"
+	kubeprovider "github.com/keikoproj/instance-manager/controllers/providers/kubernetes"
+	"github.com/keikoproj/instance-manager/controllers/provisioners"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const DefaultRegion = "us-west-2"
+
+// Options are the settings of a simulated reconcile, they are the settings of the controller which change the
+// mutations it makes
+type Options struct {
+	// Region is the region of the AWS state, defaults to us-west-2
+	Region string
+	// Configuration is the instance-manager configmap, defaults and boundaries are applied to the instance group
+	Configuration *corev1.ConfigMap
+	// Registry is the registry of provisioners, defaults to the built-in provisioners
+	Registry         *controllers.ProvisionerRegistry
+	Log              logr.Logger
+	ConfigRetention  int
+	LifecycleManager provisioners.LifecycleManagerConfiguration
+	BootstrapBucket  provisioners.BootstrapBucketConfiguration
+	ResourceNames    provisioners.ResourceNameConfiguration
+	SecurityPolicy   v1alpha1.SecurityPolicy
+}
+
+// Result is the outcome of a simulated reconcile
+type Result struct {
+	// Mutations are the calls which would change AWS state, in the order they would be made
+	Mutations []Mutation
+	// State is the state the instance group would be in after the reconcile
+	State v1alpha1.ReconcileState
+	// InstanceGroup is the instance group after the reconcile, its status is the status the controller would write
+	InstanceGroup *v1alpha1.InstanceGroup
+}
+
+// Simulate reconciles a copy of an instance group against the fixtures, the mutations made until the reconcile
+// completes or fails are returned with the error of the reconcile, outputs which are missing from the fixtures are
+// empty, the fixtures must describe the cluster and every resource which is discovered as it exists in AWS
+func Simulate(instanceGroup *v1alpha1.InstanceGroup, fixtures *Fixtures, opts Options) (*Result, error) {
+	if opts.Region == "" {
+		opts.Region = DefaultRegion
+	}
+	if opts.Registry == nil {
+		opts.Registry = controllers.NewDefaultProvisionerRegistry()
+	}
+	if opts.Log == nil {
+		opts.Log = ctrl.Log.WithName("simulator")
+	}
+
+	recorder := NewRecorder(fixtures)
+	instanceGroup = instanceGroup.DeepCopy()
+	result := &Result{InstanceGroup: instanceGroup}
+
+	input := provisioners.ProvisionerInput{
+		AwsWorker:        recorder.NewWorker(opts.Region),
+		Kubernetes:       NewKubernetesClientSet(),
+		Configuration:    opts.Configuration,
+		InstanceGroup:    instanceGroup,
+		Log:              opts.Log,
+		ConfigRetention:  opts.ConfigRetention,
+		LifecycleManager: opts.LifecycleManager,
+		BootstrapBucket:  opts.BootstrapBucket,
+		ResourceNames:    opts.ResourceNames,
+		SecurityPolicy:   opts.SecurityPolicy,
+	}
+	if input.Configuration == nil {
+		input.Configuration = &corev1.ConfigMap{}
+	}
+
+	if len(input.Configuration.Data) > 0 {
+		config, err := provisioners.NewProvisionerConfiguration(input.Configuration, instanceGroup)
+		if err != nil {
+			return result, err
+		}
+		if err := config.SetDefaults(); err != nil {
+			return result, err
+		}
+		if err := config.ValidatePolicies(); err != nil {
+			return result, err
+		}
+		input.InstanceGroup = config.InstanceGroup
+		result.InstanceGroup = config.InstanceGroup
+	}
+
+	d, err := opts.Registry.New(input)
+	if err != nil {
+		return result, err
+	}
+
+	// validation defaults the upgrade strategy, as it does in the controller
+	if err := input.InstanceGroup.Validate(); err != nil {
+		return result, err
+	}
+
+	err = controllers.HandleReconcileRequest(d)
+	result.Mutations = recorder.Mutations()
+	result.State = d.GetState()
+	if err != nil {
+		return result, errors.Wrap(err, "reconcile failed")
+	}
+	return result, nil
+}
+
+// NewWorker returns an AWS worker whose clients make all calls through the recorder
+func (r *Recorder) NewWorker(region string) awsprovider.AwsWorker {
+	sess := r.NewSession(region)
+	partition, ok := endpoints.PartitionForRegion(endpoints.DefaultPartitions(), region)
+	if !ok {
+		partition = endpoints.AwsPartition()
+	}
+
+	return awsprovider.AwsWorker{
+		AsgClient: autoscaling.New(sess),
+		EksClient: eks.New(sess),
+		IamClient: iam.New(sess),
+		Ec2Client: ec2.New(sess),
+		S3Client:  s3.New(sess),
+		SsmClient: ssm.New(sess),
+		SqsClient: sqs.New(sess),
+		Partition: partition.ID(),
+
+		OutpostsClient:     outposts.New(sess),
+		ImageBuilderClient: imagebuilder.New(sess),
+		CloudWatchClient:   cloudwatch.New(sess),
+		PricingClient:      pricing.New(sess),
+		SavingsPlansClient: savingsplans.New(sess),
+		Region:             region,
+	}
+}
+
+// NewKubernetesClientSet returns fake kubernetes clients, objects applied with server-side apply are created or
+// replaced since the fake dynamic client does not support it
+func NewKubernetesClientSet() kubeprovider.KubernetesClientSet {
+	var (
+		scheme        = runtime.NewScheme()
+		tracker       = k8stesting.NewObjectTracker(scheme, serializer.NewCodecFactory(scheme).UniversalDecoder())
+		dynamicClient = fake.NewSimpleDynamicClient(scheme)
+	)
+
+	dynamicClient.PrependReactor("*", "*", k8stesting.ObjectReaction(tracker))
+	dynamicClient.PrependReactor("patch", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch := action.(k8stesting.PatchAction)
+		if patch.GetPatchType() != types.ApplyPatchType {
+			return false, nil, nil
+		}
+
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(patch.GetPatch()); err != nil {
+			return true, nil, err
+		}
+		if _, err := tracker.Get(patch.GetResource(), patch.GetNamespace(), patch.GetName()); kerrors.IsNotFound(err) {
+			return true, obj, tracker.Create(patch.GetResource(), obj, patch.GetNamespace())
+		} else if err != nil {
+			return true, nil, err
+		}
+		return true, obj, tracker.Update(patch.GetResource(), obj, patch.GetNamespace())
+	})
+
+	return kubeprovider.KubernetesClientSet{
+		Kubernetes:  kubefake.NewSimpleClientset(),
+		KubeDynamic: dynamicClient,
+	}
+}
+
+// compact removes the null and empty fields of a JSON document, inputs of the SDK have no omitempty tags
+func compact(raw []byte) []byte {
+	var doc interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return raw
+	}
+	doc = compactValue(doc)
+	if doc == nil {
+		return nil
+	}
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return raw
+	}
+	return out
+}
+
+func compactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if field = compactValue(field); field == nil {
+				delete(v, key)
+			} else {
+				v[key] = field
+			}
+		}
+		if len(v) == 0 {
+			return nil
+		}
+		return v
+	case []interface{}:
+		items := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			if item = compactValue(item); item != nil {
+				items = append(items, item)
+			}
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		return items
+	default:
+		return v
+	}
+}
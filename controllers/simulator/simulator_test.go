@@ -0,0 +1,145 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/keikoproj/instance-manager/api/v1alpha1"
+	awsprovider "github.com/keikoproj/instance-manager/controllers/providers/aws"
+	"github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func init() {
+	awsprovider.DefaultInstanceProfilePropagationDelay = time.Millisecond * 1
+	awsprovider.DefaultWaiterDuration = time.Millisecond * 1
+	awsprovider.DefaultWaiterRetries = 1
+}
+
+func MockInstanceGroup() *v1alpha1.InstanceGroup {
+	return &v1alpha1.InstanceGroup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "instance-group-1",
+			Namespace: "instance-manager",
+		},
+		Spec: v1alpha1.InstanceGroupSpec{
+			Provisioner: "eks",
+			EKSSpec: &v1alpha1.EKSSpec{
+				MaxSize: 3,
+				MinSize: 1,
+				EKSConfiguration: &v1alpha1.EKSConfiguration{
+					EksClusterName:     "my-cluster",
+					Image:              "ami-123456",
+					InstanceType:       "m5.large",
+					KeyPairName:        "my-key",
+					NodeSecurityGroups: []string{"sg-123456"},
+					Subnets:            []string{"subnet-123456"},
+				},
+			},
+			AwsUpgradeStrategy: v1alpha1.AwsUpgradeStrategy{
+				Type: v1alpha1.RollingUpdateStrategyName,
+				RollingUpdateType: &v1alpha1.RollingUpdateStrategy{
+					MaxUnavailable: &intstr.IntOrString{Type: intstr.Int, IntVal: 1},
+				},
+			},
+		},
+	}
+}
+
+func operations(mutations []Mutation) []string {
+	ops := make([]string, 0, len(mutations))
+	for _, m := range mutations {
+		ops = append(ops, fixtureKey(m.Service, m.Operation))
+	}
+	return ops
+}
+
+func TestSimulateCreate(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	fixtures, err := LoadFixtures("testdata/create.json")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	ig := MockInstanceGroup()
+	result, err := Simulate(ig, fixtures, Options{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(result.State).To(gomega.Equal(v1alpha1.ReconcileReady))
+	g.Expect(operations(result.Mutations)).To(gomega.Equal([]string{
+		"iam.CreateRole",
+		"iam.CreateInstanceProfile",
+		"iam.AddRoleToInstanceProfile",
+		"iam.AttachRolePolicy",
+		"iam.AttachRolePolicy",
+		"iam.AttachRolePolicy",
+		"autoscaling.CreateLaunchConfiguration",
+		"autoscaling.CreateAutoScalingGroup",
+	}))
+
+	// the timestamp of launch configuration names is replaced so that mutations are deterministic
+	g.Expect(string(result.Mutations[7].Input)).To(gomega.ContainSubstring(`"LaunchConfigurationName":"my-cluster-instance-manager-instance-group-1-<timestamp>"`))
+
+	// the instance group which is simulated is not modified
+	g.Expect(ig.GetState()).To(gomega.BeEmpty())
+}
+
+func TestSimulateUpdate(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	fixtures, err := LoadFixtures("testdata/update.json")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	result, err := Simulate(MockInstanceGroup(), fixtures, Options{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(operations(result.Mutations)).To(gomega.Equal([]string{
+		"autoscaling.CreateLaunchConfiguration",
+		"autoscaling.UpdateAutoScalingGroup",
+	}))
+	g.Expect(string(result.Mutations[0].Input)).To(gomega.ContainSubstring(`"InstanceType":"m5.large"`))
+}
+
+func TestSimulateDeterministic(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	fixtures, err := LoadFixtures("testdata/create.json")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	first, err := Simulate(MockInstanceGroup(), fixtures, Options{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	second, err := Simulate(MockInstanceGroup(), fixtures, Options{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(second.Mutations).To(gomega.Equal(first.Mutations))
+}
+
+func TestSimulateFixtureError(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	fixtures, err := ReadFixtures(strings.NewReader(`{
+		"errors": {
+			"eks.DescribeCluster": {"code": "ResourceNotFoundException", "message": "No cluster found", "statusCode": 404},
+			"iam.GetRole": {"code": "NoSuchEntity", "statusCode": 404}
+		}
+	}`))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	result, err := Simulate(MockInstanceGroup(), fixtures, Options{})
+	g.Expect(err).To(gomega.HaveOccurred())
+	g.Expect(err.Error()).To(gomega.ContainSubstring("ResourceNotFoundException"))
+	g.Expect(result.Mutations).To(gomega.BeEmpty())
+}
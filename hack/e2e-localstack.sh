@@ -0,0 +1,125 @@
+#!/usr/bin/env bash
+
+# Runs the localstack BDD scenarios against a kind cluster and localstack (or moto), the controller runs locally with
+# all AWS calls made to the endpoint, and nodes are simulated for the instances of scaling groups.
+#
+# usage: hack/e2e-localstack.sh [up|test|down|all]
+#
+# E2E_AWS_BACKEND selects the AWS emulator, localstack (default) or moto, localstack needs EKS support to describe the
+# cluster, which moto provides in its free server.
+
+set -euo pipefail
+
+ROOT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")/.." && pwd)"
+WORK_DIR="${ROOT_DIR}/.e2e"
+
+E2E_AWS_BACKEND="${E2E_AWS_BACKEND:-localstack}"
+E2E_KIND_CLUSTER="${E2E_KIND_CLUSTER:-instance-manager-e2e}"
+E2E_CONTAINER="${E2E_CONTAINER:-instance-manager-e2e-aws}"
+E2E_PORT="${E2E_PORT:-4566}"
+
+export AWS_REGION="${AWS_REGION:-us-west-2}"
+export AWS_DEFAULT_REGION="${AWS_REGION}"
+export AWS_ACCESS_KEY_ID="${AWS_ACCESS_KEY_ID:-test}"
+export AWS_SECRET_ACCESS_KEY="${AWS_SECRET_ACCESS_KEY:-test}"
+export AWS_ENDPOINT_URL="http://localhost:${E2E_PORT}"
+export KUBECONFIG="${WORK_DIR}/kubeconfig"
+
+EKS_CLUSTER="e2e-cluster"
+
+awscli() {
+  aws --endpoint-url "${AWS_ENDPOINT_URL}" --output text "$@"
+}
+
+up() {
+  mkdir -p "${WORK_DIR}"
+
+  if ! kind get clusters | grep -qx "${E2E_KIND_CLUSTER}"; then
+    kind create cluster --name "${E2E_KIND_CLUSTER}" --kubeconfig "${KUBECONFIG}"
+  fi
+  kubectl get namespace instance-manager >/dev/null 2>&1 || kubectl create namespace instance-manager
+  kubectl apply -f "${ROOT_DIR}/config/crd/bases"
+
+  if ! docker inspect "${E2E_CONTAINER}" >/dev/null 2>&1; then
+    case "${E2E_AWS_BACKEND}" in
+    localstack)
+      docker run -d --name "${E2E_CONTAINER}" -p "${E2E_PORT}:4566" \
+        -e SERVICES=autoscaling,ec2,eks,iam,ssm,sts -e DEFAULT_REGION="${AWS_REGION}" localstack/localstack
+      ;;
+    moto)
+      docker run -d --name "${E2E_CONTAINER}" -p "${E2E_PORT}:5000" motoserver/moto
+      ;;
+    *)
+      echo "unknown E2E_AWS_BACKEND ${E2E_AWS_BACKEND}, expected localstack or moto" >&2
+      exit 1
+      ;;
+    esac
+  fi
+
+  for _ in $(seq 1 60); do
+    if awscli sts get-caller-identity >/dev/null 2>&1; then
+      break
+    fi
+    sleep 2
+  done
+
+  local vpc subnet_a subnet_b sg ami role
+  vpc=$(awscli ec2 create-vpc --cidr-block 10.0.0.0/16 --query Vpc.VpcId)
+  subnet_a=$(awscli ec2 create-subnet --vpc-id "${vpc}" --cidr-block 10.0.1.0/24 --availability-zone "${AWS_REGION}a" --query Subnet.SubnetId)
+  subnet_b=$(awscli ec2 create-subnet --vpc-id "${vpc}" --cidr-block 10.0.2.0/24 --availability-zone "${AWS_REGION}b" --query Subnet.SubnetId)
+  sg=$(awscli ec2 create-security-group --group-name e2e-nodes --description "e2e nodes" --vpc-id "${vpc}" --query GroupId)
+  awscli ec2 create-key-pair --key-name e2e-key >/dev/null
+  ami=$(awscli ec2 describe-images --query "Images[0].ImageId")
+  role=$(awscli iam create-role --role-name e2e-cluster \
+    --assume-role-policy-document '{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"Service":"eks.amazonaws.com"},"Action":"sts:AssumeRole"}]}' \
+    --query Role.Arn)
+  awscli eks create-cluster --name "${EKS_CLUSTER}" --role-arn "${role}" \
+    --resources-vpc-config "subnetIds=${subnet_a},${subnet_b},securityGroupIds=${sg}" >/dev/null
+
+  cat >"${WORK_DIR}/env" <<EOF
+export EKS_CLUSTER=${EKS_CLUSTER}
+export KEYPAIR_NAME=e2e-key
+export VPC_ID=${vpc}
+export AMI_ID=${ami}
+export SECURITY_GROUPS=${sg}
+export NODE_SUBNETS=${subnet_a},${subnet_b}
+EOF
+  echo "created e2e environment, variables are in ${WORK_DIR}/env"
+}
+
+run_tests() {
+  # shellcheck disable=SC1090
+  source "${WORK_DIR}/env"
+
+  cd "${ROOT_DIR}"
+  go build -o bin/manager main.go
+  ./bin/manager --aws-endpoint-url="${AWS_ENDPOINT_URL}" --metrics-addr=:18080 --health-probe-addr=:18081 \
+    >"${WORK_DIR}/manager.log" 2>&1 &
+  local manager=$! status=0
+  echo "controller is running with pid ${manager}, logs are in ${WORK_DIR}/manager.log"
+
+  (cd "${ROOT_DIR}/test-bdd" && go test -timeout 30m -v . --godog.tags "@localstack" --godog.stop-on-failure) || status=$?
+  kill "${manager}" 2>/dev/null || true
+  return ${status}
+}
+
+down() {
+  kind delete cluster --name "${E2E_KIND_CLUSTER}" || true
+  docker rm -f "${E2E_CONTAINER}" || true
+  rm -rf "${WORK_DIR}"
+}
+
+case "${1:-all}" in
+up) up ;;
+test) run_tests ;;
+down) down ;;
+all)
+  up
+  trap down EXIT
+  run_tests
+  ;;
+*)
+  echo "usage: $0 [up|test|down|all]" >&2
+  exit 1
+  ;;
+esac
@@ -18,19 +18,28 @@ package main
 import (
 	"flag"
 	"os"
+	"path/filepath"
 	runt "runtime"
+	"strings"
+	"time"
 
 	"github.com/keikoproj/aws-sdk-go-cache/cache"
 	instancemgrv1alpha1 "github.com/keikoproj/instance-manager/api/v1alpha1"
 	"github.com/keikoproj/instance-manager/controllers"
+	"github.com/keikoproj/instance-manager/controllers/common"
 	"github.com/keikoproj/instance-manager/controllers/providers/aws"
 	kubeprovider "github.com/keikoproj/instance-manager/controllers/providers/kubernetes"
+	"github.com/keikoproj/instance-manager/controllers/provisioners"
 	corev1 "k8s.io/api/core/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
+	"k8s.io/client-go/tools/leaderelection"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	// +kubebuilder:scaffold:imports
 )
@@ -62,32 +71,143 @@ func main() {
 
 	var (
 		metricsAddr            string
+		healthProbeAddr        string
 		configNamespace        string
 		spotRecommendationTime float64
 		enableLeaderElection   bool
+		leaseDuration          time.Duration
+		renewDeadline          time.Duration
+		retryPeriod            time.Duration
+		enableWebhooks         bool
+		webhookCertManagement  bool
+		webhookService         string
+		webhookCertSecret      string
+		webhookCertDir         string
 		nodeRelabel            bool
 		maxParallel            int
 		maxAPIRetries          int
+		apiRateLimits          aws.RateLimits
+		circuitBreaker         aws.CircuitBreakerConfig
+		awsEndpoints           string
+		awsEndpointURL         string
+		awsPartition           string
+		useFIPSEndpoints       bool
+		useDualStackEndpoints  bool
+		stsRegionalEndpoints   bool
+		assumeRoleArn          string
+		assumeRoleExternalID   string
+		assumeRoleConfig       aws.AssumeRoleConfig
 		configRetention        int
+		revisionHistoryLimit   int
+		auditRevisionLimit     int
+		reconcileTimeout       time.Duration
+		dryRun                 bool
+		minReconcileInterval   time.Duration
+		maxReconcileInterval   time.Duration
+		clusterCacheTTL        time.Duration
+		lifecycleManager       provisioners.LifecycleManagerConfiguration
+		bootstrapBucket        provisioners.BootstrapBucketConfiguration
+		resourceNames          provisioners.ResourceNameConfiguration
+		securityPolicy         instancemgrv1alpha1.SecurityPolicy
+		guardNamespaces        string
+		guardSelector          string
+		cloudEventQueueURL     string
+		clusterAPIProvider     bool
+		machinePoolAPIVersion  string
+		logLevel               string
 		err                    error
 	)
 
 	flag.IntVar(&maxParallel, "max-workers", 5, "The number of maximum parallel reconciles")
 	flag.IntVar(&maxAPIRetries, "max-api-retries", 12, "The number of maximum retries for failed AWS API calls")
+	flag.Float64Var(&apiRateLimits.ReadQPS, "api-read-qps", aws.DefaultRateLimits.ReadQPS, "The maximum rate of read AWS API calls per second, per service")
+	flag.IntVar(&apiRateLimits.ReadBurst, "api-read-burst", aws.DefaultRateLimits.ReadBurst, "The maximum burst of read AWS API calls, per service")
+	flag.Float64Var(&apiRateLimits.MutateQPS, "api-mutate-qps", aws.DefaultRateLimits.MutateQPS, "The maximum rate of mutating AWS API calls per second, per service")
+	flag.IntVar(&apiRateLimits.MutateBurst, "api-mutate-burst", aws.DefaultRateLimits.MutateBurst, "The maximum burst of mutating AWS API calls, per service")
+	flag.IntVar(&circuitBreaker.FailureThreshold, "api-circuit-breaker-threshold", aws.DefaultCircuitBreakerConfig.FailureThreshold, "The number of consecutive failures of an AWS API after which calls to it fail fast, 0 disables the circuit breaker")
+	flag.DurationVar(&circuitBreaker.Cooldown, "api-circuit-breaker-cooldown", aws.DefaultCircuitBreakerConfig.Cooldown, "The duration calls to a failing AWS API fail fast before it is tried again")
+	flag.StringVar(&awsEndpoints, "aws-endpoints", "", "Comma separated service=url overrides of AWS service endpoints, e.g. ec2=https://vpce-123.ec2.us-west-2.vpce.amazonaws.com, services are autoscaling, ec2, eks, iam, s3, sqs, ssm and sts")
+	flag.StringVar(&awsEndpointURL, "aws-endpoint-url", "", "The endpoint of all AWS services without an override in --aws-endpoints, e.g. a localstack URL")
+	flag.StringVar(&awsPartition, "aws-partition", "", "The AWS partition ARNs are constructed in, derived from the region when empty")
+	flag.BoolVar(&useFIPSEndpoints, "aws-use-fips-endpoints", false, "Call AWS services without an endpoint override at their FIPS endpoints")
+	flag.BoolVar(&useDualStackEndpoints, "aws-use-dualstack-endpoints", false, "Call AWS services without an endpoint override at their dual-stack (IPv4 and IPv6) endpoints")
+	flag.BoolVar(&stsRegionalEndpoints, "aws-sts-regional-endpoints", true, "Call STS at the endpoint of the region instead of the global endpoint")
+	flag.StringVar(&assumeRoleArn, "aws-assume-role-arn", "", "The IAM role assumed for all AWS calls of the controller, the credentials of the pod are used when empty")
+	flag.StringVar(&assumeRoleExternalID, "aws-assume-role-external-id", "", "The external ID passed to the trust policy of --aws-assume-role-arn")
+	flag.DurationVar(&assumeRoleConfig.Duration, "aws-assume-role-duration", aws.DefaultAssumeRoleDuration, "The duration of the credentials of assumed IAM roles")
+	flag.DurationVar(&assumeRoleConfig.RefreshWindow, "aws-credentials-refresh-window", aws.DefaultCredentialsRefreshWindow, "How long before they expire the credentials of assumed IAM roles are refreshed")
 	flag.IntVar(&configRetention, "config-retention", 2, "The number of launch configuration/template versions to retain")
+	flag.IntVar(&revisionHistoryLimit, "revision-history-limit", controllers.DefaultRevisionHistoryLimit, "The number of rolled out configurations kept in the revision history of instance groups for rollback, 0 disables the revision history")
+	flag.IntVar(&auditRevisionLimit, "audit-revision-limit", controllers.DefaultAuditRevisionLimit, "The number of InstanceGroupRevisions recording the changes applied to an instance group which are kept, 0 disables recording them")
+	flag.DurationVar(&reconcileTimeout, "reconcile-timeout", 5*time.Minute, "The maximum duration of AWS API calls within a single reconcile, 0 disables the deadline")
+	flag.BoolVar(&dryRun, "dry-run", false, "Plan changes to cloud resources of all instance groups without making them, planned changes are published to the status and events of instance groups")
+	flag.DurationVar(&minReconcileInterval, "min-reconcile-interval", time.Minute, "The minimum reconcile interval instance groups can set with spec.reconcileInterval, 0 disables the minimum")
+	flag.DurationVar(&maxReconcileInterval, "max-reconcile-interval", 24*time.Hour, "The maximum reconcile interval instance groups can set with spec.reconcileInterval, 0 disables the maximum")
+	flag.DurationVar(&clusterCacheTTL, "cluster-cache-ttl", aws.DescribeClusterTTL, "The duration described EKS clusters are cached for, shared by all instance groups, 0 disables the cache")
+	flag.StringVar(&lifecycleManager.NotificationArn, "lifecycle-manager-notification-arn", "", "The default SQS queue or SNS topic ARN of lifecycle hooks handled by lifecycle-manager")
+	flag.StringVar(&lifecycleManager.RoleArn, "lifecycle-manager-role-arn", "", "The default IAM role ARN used to publish notifications of lifecycle hooks handled by lifecycle-manager")
+	flag.StringVar(&bootstrapBucket.Name, "bootstrap-bucket", "", "The S3 bucket where user data which exceeds the maximum size is uploaded, instances download it at boot")
+	flag.StringVar(&bootstrapBucket.Prefix, "bootstrap-bucket-prefix", "instance-manager", "The key prefix of user data uploaded to the bootstrap bucket")
+	flag.StringVar(&resourceNames.Template, "resource-name-template", provisioners.DefaultResourceNameTemplate, "The template of the names of scaling groups, launch configurations and IAM roles, fields are .ClusterName, .Namespace, .Name and .Hash")
+	flag.BoolVar(&securityPolicy.RequireEncryptedVolumes, "require-encrypted-volumes", false, "Require all volumes of instance groups to be encrypted, regardless of their spec")
+	flag.BoolVar(&securityPolicy.RequireIMDSv2, "require-imdsv2", false, "Require all instances of instance groups to use IMDSv2, instances which allow IMDSv1 are changed to require session tokens")
+	flag.StringVar(&securityPolicy.Action, "security-policy-action", instancemgrv1alpha1.PolicyActionReject, "The action taken on instance groups which violate --require-encrypted-volumes, reject or remediate")
 	flag.Float64Var(&spotRecommendationTime, "spot-recommendation-time", 10.0, "The maximum age of spot recommendation events to consider in minutes")
 	flag.StringVar(&configNamespace, "config-namespace", "instance-manager", "the namespace to watch for instance-manager configmap")
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
+	flag.StringVar(&healthProbeAddr, "health-probe-addr", ":8081", "The address the /healthz and /readyz endpoints bind to, readiness fails when AWS credentials are invalid or AWS is unreachable")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. Enabling this will ensure there is only one active controller manager.")
+	flag.DurationVar(&leaseDuration, "leader-election-lease-duration", 15*time.Second, "The duration standby replicas wait before taking over leadership from a leader which stopped renewing it")
+	flag.DurationVar(&renewDeadline, "leader-election-renew-deadline", 10*time.Second, "The duration the leader retries renewing leadership before giving it up, must be less than --leader-election-lease-duration")
+	flag.DurationVar(&retryPeriod, "leader-election-retry-period", 2*time.Second, "The interval at which replicas try to acquire or renew leadership")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false, "Enable admission webhooks for instance groups, requires serving certificates to be mounted unless --webhook-cert-management is enabled")
+	flag.BoolVar(&webhookCertManagement, "webhook-cert-management", false, "Generate and rotate self-signed serving certificates of the webhooks, and patch their CA into the webhook configurations, instead of mounting certificates issued by e.g. cert-manager")
+	flag.StringVar(&webhookService, "webhook-service", "instance-manager-webhook-service", "The service of the webhooks in --config-namespace which generated serving certificates are valid for")
+	flag.StringVar(&webhookCertSecret, "webhook-cert-secret", "instance-manager-webhook-cert", "The secret in --config-namespace which generated certificates are stored in, shared by all replicas")
+	flag.StringVar(&webhookCertDir, "webhook-cert-dir", filepath.Join(os.TempDir(), "k8s-webhook-server", "serving-certs"), "The directory the webhook server reads tls.crt and tls.key from")
+	flag.StringVar(&guardNamespaces, "deletion-guard-namespaces", "", "Comma separated namespaces whose pods block deletion of the instance group they run on, requires webhooks")
+	flag.StringVar(&guardSelector, "deletion-guard-selector", "", "Label selector of pods which block deletion of the instance group they run on, requires webhooks")
+	flag.StringVar(&cloudEventQueueURL, "cloud-event-queue-url", "", "The URL of an SQS queue which receives EventBridge events of scaling group changes, instance groups are reconciled immediately when their scaling group is changed outside of the controller")
+	flag.BoolVar(&clusterAPIProvider, "cluster-api-provider", false, "Reconcile instance groups owned by Cluster API MachinePools as their infrastructure, reporting the provider IDs and replicas of their instances")
+	flag.StringVar(&machinePoolAPIVersion, "machinepool-api-version", controllers.DefaultMachinePoolAPIVersion, "The API version of the Cluster API MachinePools which are watched")
 	flag.BoolVar(&nodeRelabel, "node-relabel", true, "relabel nodes as they join with kubernetes.io/role label via controller")
+	flag.StringVar(&logLevel, "log-level", common.LogLevelInfo, "The log level of the controller, info or debug, instance groups annotated with instancemgr.keikoproj.io/log-level=debug write debug log lines at the info level")
 	flag.Parse()
-	ctrl.SetLogger(zap.Logger(true))
+	// sensitive values such as user data are redacted from the logs of all controllers
+	ctrl.SetLogger(common.NewLeveledLogger(common.NewRedactingLogger(zap.Logger(true)), strings.EqualFold(logLevel, common.LogLevelDebug)))
+
+	if !common.ContainsEqualFold(common.LogLevels, logLevel) {
+		setupLog.Error(nil, "invalid log level", "logLevel", logLevel, "allowed", common.LogLevels)
+		os.Exit(1)
+	}
+
+	if err := resourceNames.Validate(); err != nil {
+		setupLog.Error(err, "invalid resource name template")
+		os.Exit(1)
+	}
+
+	if securityPolicy.Action != instancemgrv1alpha1.PolicyActionReject && securityPolicy.Action != instancemgrv1alpha1.PolicyActionRemediate {
+		setupLog.Error(nil, "invalid security policy action", "action", securityPolicy.Action)
+		os.Exit(1)
+	}
+
+	// client-go requires the leader to give up leadership before standby replicas may take it over
+	if renewDeadline >= leaseDuration || float64(retryPeriod)*leaderelection.JitterFactor >= float64(renewDeadline) {
+		setupLog.Error(nil, "invalid leader election durations, the retry period must be less than the renew deadline, which must be less than the lease duration",
+			"leaseDuration", leaseDuration, "renewDeadline", renewDeadline, "retryPeriod", retryPeriod)
+		os.Exit(1)
+	}
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme:             scheme,
-		MetricsBindAddress: metricsAddr,
-		LeaderElection:     enableLeaderElection,
+		Scheme:                 scheme,
+		MetricsBindAddress:     metricsAddr,
+		HealthProbeBindAddress: healthProbeAddr,
+		LeaderElection:         enableLeaderElection,
+		LeaseDuration:          &leaseDuration,
+		RenewDeadline:          &renewDeadline,
+		RetryPeriod:            &retryPeriod,
+		CertDir:                webhookCertDir,
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
@@ -112,15 +232,59 @@ func main() {
 		os.Exit(1)
 	}
 
-	cacheCfg := cache.NewConfig(aws.CacheDefaultTTL, aws.CacheMaxItems, aws.CacheItemsToPrune)
+	if err := mgr.AddHealthzCheck("ping", healthz.Ping); err != nil {
+		setupLog.Error(err, "unable to add health check")
+		os.Exit(1)
+	}
 
-	awsWorker := aws.AwsWorker{
-		Ec2Client: aws.GetAwsEc2Client(awsRegion, cacheCfg, maxAPIRetries),
-		IamClient: aws.GetAwsIamClient(awsRegion, cacheCfg, maxAPIRetries),
-		AsgClient: aws.GetAwsAsgClient(awsRegion, cacheCfg, maxAPIRetries),
-		EksClient: aws.GetAwsEksClient(awsRegion, cacheCfg, maxAPIRetries),
+	endpoints, err := aws.ParseEndpoints(awsEndpoints)
+	if err != nil {
+		setupLog.Error(err, "invalid AWS endpoints")
+		os.Exit(1)
 	}
 
+	clientConfig := aws.ClientConfig{
+		Region:      awsRegion,
+		MaxRetries:  maxAPIRetries,
+		RateLimits:  apiRateLimits,
+		Breaker:     aws.NewCircuitBreaker(circuitBreaker),
+		Endpoints:   endpoints,
+		EndpointURL: awsEndpointURL,
+		Partition:   awsPartition,
+
+		UseFIPSEndpoints:      useFIPSEndpoints,
+		UseDualStackEndpoints: useDualStackEndpoints,
+		STSRegionalEndpoints:  stsRegionalEndpoints,
+	}
+
+	refresher := aws.NewCredentialsRefresher(assumeRoleConfig.RefreshWindow)
+	if assumeRoleArn != "" {
+		creds, err := aws.NewAssumeRoleCredentials(clientConfig, assumeRoleConfig, assumeRoleArn,
+			aws.WithExternalID(assumeRoleExternalID), aws.WithRoleSessionName(aws.ControllerRoleSessionName))
+		if err != nil {
+			setupLog.Error(err, "unable to create AWS assume role credentials")
+			os.Exit(1)
+		}
+		clientConfig.Credentials = creds
+		refresher.Add("", assumeRoleArn, creds)
+	}
+	if err := mgr.Add(refresher); err != nil {
+		setupLog.Error(err, "unable to add AWS credentials refresher")
+		os.Exit(1)
+	}
+	setupLog.Info("configured AWS clients", "region", awsRegion, "partition", clientConfig.GetPartition(), "endpoints", endpoints, "endpointURL", awsEndpointURL,
+		"fips", useFIPSEndpoints, "dualStack", useDualStackEndpoints, "stsRegional", stsRegionalEndpoints, "assumeRole", assumeRoleArn)
+
+	if err := mgr.AddReadyzCheck("aws", aws.NewHealthChecker(clientConfig).Check); err != nil {
+		setupLog.Error(err, "unable to add readiness check")
+		os.Exit(1)
+	}
+
+	cacheCfg := cache.NewConfig(aws.CacheDefaultTTL, aws.CacheMaxItems, aws.CacheItemsToPrune)
+
+	awsWorker := clientConfig.NewWorker(cacheCfg)
+	awsWorker.ClusterCache = aws.NewClusterCache(clusterCacheTTL)
+
 	kube := kubeprovider.KubernetesClientSet{
 		Kubernetes:  client,
 		KubeDynamic: dynClient,
@@ -137,15 +301,47 @@ func main() {
 		setupLog.Info("instance-manager configmap does not exist, will not load defaults/boundaries")
 	}
 
+	var cloudEvents chan event.GenericEvent
+	if cloudEventQueueURL != "" {
+		cloudEvents = make(chan event.GenericEvent, 100)
+		awsWorker.SqsClient = clientConfig.GetAwsSqsClient()
+		err = mgr.Add(&controllers.CloudEventListener{
+			Client:    mgr.GetClient(),
+			AwsWorker: awsWorker,
+			QueueURL:  cloudEventQueueURL,
+			Events:    cloudEvents,
+			Log:       ctrl.Log.WithName("controllers").WithName("cloudevents"),
+		})
+		if err != nil {
+			setupLog.Error(err, "unable to create cloud event listener")
+			os.Exit(1)
+		}
+	}
+
 	err = (&controllers.InstanceGroupReconciler{
 		ConfigMap:              cm,
 		ConfigRetention:        configRetention,
+		Provisioners:           controllers.NewDefaultProvisionerRegistry(),
+		AssumedRoles:           aws.NewAssumedRoleWorkers(clientConfig, assumeRoleConfig, clusterCacheTTL, refresher),
+		RevisionHistoryLimit:   revisionHistoryLimit,
+		AuditRevisionLimit:     auditRevisionLimit,
+		ReconcileTimeout:       reconcileTimeout,
+		DryRun:                 dryRun,
+		MinReconcileInterval:   minReconcileInterval,
+		MaxReconcileInterval:   maxReconcileInterval,
+		LifecycleManager:       lifecycleManager,
+		BootstrapBucket:        bootstrapBucket,
+		ResourceNames:          resourceNames,
+		SecurityPolicy:         securityPolicy,
 		SpotRecommendationTime: spotRecommendationTime,
 		ConfigNamespace:        configNamespace,
 		NodeRelabel:            nodeRelabel,
+		ClusterAPIProvider:     clusterAPIProvider,
+		MachinePoolAPIVersion:  machinePoolAPIVersion,
 		Client:                 mgr.GetClient(),
 		Log:                    ctrl.Log.WithName("controllers").WithName("instancegroup"),
 		MaxParallel:            maxParallel,
+		CloudEvents:            cloudEvents,
 		Auth: &controllers.InstanceGroupAuthenticator{
 			Aws:        awsWorker,
 			Kubernetes: kube,
@@ -155,6 +351,52 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "instancegroup")
 		os.Exit(1)
 	}
+
+	if enableWebhooks {
+		// user data which exceeds the maximum size is uploaded to the bootstrap bucket instead of being rejected
+		instancemgrv1alpha1.EnforceUserDataSizeLimit = !bootstrapBucket.Enabled()
+		instancemgrv1alpha1.InstanceGroupSecurityPolicy = securityPolicy
+		instancemgrv1alpha1.PolicyValidator = &provisioners.ConfigMapPolicyValidator{
+			Kubernetes: client,
+			Namespace:  configNamespace,
+			Name:       controllers.ConfigMapName,
+		}
+		if guardNamespaces != "" || guardSelector != "" {
+			selector, err := labels.Parse(guardSelector)
+			if err != nil {
+				setupLog.Error(err, "invalid deletion guard selector")
+				os.Exit(1)
+			}
+			instancemgrv1alpha1.DeletionGuard = &instancemgrv1alpha1.WorkloadDeletionGuard{
+				Kubernetes: client,
+				AwsWorker:  awsWorker,
+				Namespaces: strings.Split(strings.Replace(guardNamespaces, " ", "", -1), ","),
+				Selector:   selector,
+			}
+		}
+		if webhookCertManagement {
+			certificates := &kubeprovider.WebhookCertificates{
+				Kubernetes:  client,
+				Namespace:   configNamespace,
+				SecretName:  webhookCertSecret,
+				ServiceName: webhookService,
+				CertDir:     webhookCertDir,
+			}
+			// the certificates must exist before the webhook server starts
+			if err := certificates.Ensure(); err != nil {
+				setupLog.Error(err, "unable to ensure webhook certificates")
+				os.Exit(1)
+			}
+			if err := mgr.Add(certificates); err != nil {
+				setupLog.Error(err, "unable to add webhook certificate rotation")
+				os.Exit(1)
+			}
+		}
+		if err = (&instancemgrv1alpha1.InstanceGroup{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "instancegroup")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	setupLog.Info("starting manager")
@@ -0,0 +1,34 @@
+@localstack
+Feature: Reconcile against localstack
+  In order to test instance-groups without an AWS account
+  As an instance-manager developer
+  I need to reconcile the custom resource against localstack or moto with simulated nodes
+
+  Scenario: Create an instance-group
+    Given an EKS cluster
+    When I create a resource instance-group-localstack.yaml
+    Then the resource should be created
+    And the resource should converge to selector .status.currentState=ready
+    And the scaling group should use the active launch configuration with instance type t2.small
+    And 2 nodes should be ready
+
+  Scenario: Update an instance-group
+    Given an EKS cluster
+    When I update a resource instance-group-localstack.yaml with .spec.eks.minSize set to 3
+    Then the resource should converge to selector .status.currentState=ready
+    And 3 nodes should be ready
+
+  Scenario: Rotate an instance-group with rollingUpdate strategy
+    Given an EKS cluster
+    When I update a resource instance-group-localstack.yaml with .spec.eks.configuration.instanceType set to t2.medium
+    Then the scaling group should use the active launch configuration with instance type t2.medium
+    And 3 nodes should be ready with label beta.kubernetes.io/instance-type set to t2.medium
+    And the resource should converge to selector .status.currentState=ready
+    And 3 nodes should be ready
+
+  Scenario: Delete an instance-group
+    Given an EKS cluster
+    When I delete a resource instance-group-localstack.yaml
+    Then 0 nodes should be found
+    And the resource should be deleted
+    And the scaling group should be deleted
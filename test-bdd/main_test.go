@@ -25,6 +25,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
 	"github.com/cucumber/godog"
 	"github.com/cucumber/godog/colors"
 	"github.com/cucumber/godog/gherkin"
@@ -48,6 +51,8 @@ type FunctionalTest struct {
 	RESTConfig        *rest.Config
 	ResourceName      string
 	ResourceNamespace string
+	AwsSession        *session.Session
+	ScalingGroupName  string
 }
 
 const (
@@ -61,8 +66,15 @@ const (
 	NodeStateReady = "ready"
 	NodeStateFound = "found"
 
+	DefaultWaiterRetries = 40
+)
+
+var (
 	DefaultWaiterInterval = time.Second * 30
-	DefaultWaiterRetries  = 40
+
+	// AwsEndpointURL runs the suite in localstack mode when it is set, AWS calls of the test are made to the endpoint,
+	// e.g. localstack or moto, and nodes are simulated for the instances of scaling groups
+	AwsEndpointURL = os.Getenv("AWS_ENDPOINT_URL")
 )
 
 var InstanceGroupSchema = schema.GroupVersionResource{
@@ -97,16 +109,25 @@ func TestMain(m *testing.M) {
 func FeatureContext(s *godog.Suite) {
 	t := FunctionalTest{}
 
+	stopSimulator := make(chan struct{})
+
 	s.BeforeSuite(func() {
 		log.Info("BDD >> trying to delete any existing test instance-groups")
 		t.anEKSCluster()
 		t.deleteAll()
+
+		if AwsEndpointURL != "" {
+			log.Infof("BDD >> running against AWS endpoint %v with simulated nodes", AwsEndpointURL)
+			DefaultWaiterInterval = time.Second * 5
+			go testutil.NewNodeSimulator(t.KubeClient, t.DynamicClient, t.AwsSession).Start(stopSimulator)
+		}
 	})
 
 	s.AfterSuite(func() {
 		log.Info("BDD >> trying to delete any existing test instance-groups")
 		t.anEKSCluster()
 		t.deleteAll()
+		close(stopSimulator)
 	})
 
 	s.AfterStep(func(f *gherkin.Step, err error) {
@@ -121,6 +142,8 @@ func FeatureContext(s *godog.Suite) {
 	s.Step(`^the resource condition ([^"]*) should be (true|false)$`, t.theResourceConditionShouldBe)
 	s.Step(`^I (create|delete) a resource ([^"]*)$`, t.iOperateOnResource)
 	s.Step(`^I update a resource ([^"]*) with ([^"]*) set to ([^"]*)$`, t.iUpdateResourceWithField)
+	s.Step(`^the scaling group should use the active launch configuration with instance type ([^"]*)$`, t.theScalingGroupShouldUseTheActiveLaunchConfiguration)
+	s.Step(`^the scaling group should be deleted$`, t.theScalingGroupShouldBeDeleted)
 }
 
 func (t *FunctionalTest) anEKSCluster() error {
@@ -157,9 +180,15 @@ func (t *FunctionalTest) anEKSCluster() error {
 		return err
 	}
 
+	sess, err := testutil.NewAwsSession(os.Getenv("AWS_REGION"), AwsEndpointURL)
+	if err != nil {
+		return err
+	}
+
 	t.KubeClient = client
 	t.DynamicClient = dynClient
 	t.RESTConfig = config
+	t.AwsSession = sess
 
 	return nil
 }
@@ -362,6 +391,82 @@ func (t *FunctionalTest) theResourceShouldConvergeToSelector(selector string) er
 	return nil
 }
 
+func (t *FunctionalTest) theScalingGroupShouldUseTheActiveLaunchConfiguration(instanceType string) error {
+	var (
+		counter int
+		asg     = autoscaling.New(t.AwsSession)
+	)
+
+	for {
+		if counter >= DefaultWaiterRetries {
+			return errors.New("waiter timed out waiting for launch configuration")
+		}
+		log.Infof("BDD >> waiting for scaling group of %v/%v to use a launch configuration with instance type %v", t.ResourceNamespace, t.ResourceName, instanceType)
+		resource, err := t.DynamicClient.Resource(InstanceGroupSchema).Namespace(t.ResourceNamespace).Get(t.ResourceName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		scalingGroupName, _, _ := unstructured.NestedString(resource.UnstructuredContent(), "status", "activeScalingGroupName")
+		launchConfigName, _, _ := unstructured.NestedString(resource.UnstructuredContent(), "status", "activeLaunchConfigurationName")
+		if scalingGroupName != "" && launchConfigName != "" {
+			t.ScalingGroupName = scalingGroupName
+
+			groups, err := asg.DescribeAutoScalingGroups(&autoscaling.DescribeAutoScalingGroupsInput{
+				AutoScalingGroupNames: aws.StringSlice([]string{scalingGroupName}),
+			})
+			if err != nil {
+				return err
+			}
+			configs, err := asg.DescribeLaunchConfigurations(&autoscaling.DescribeLaunchConfigurationsInput{
+				LaunchConfigurationNames: aws.StringSlice([]string{launchConfigName}),
+			})
+			if err != nil {
+				return err
+			}
+
+			if len(groups.AutoScalingGroups) == 1 && len(configs.LaunchConfigurations) == 1 &&
+				aws.StringValue(groups.AutoScalingGroups[0].LaunchConfigurationName) == launchConfigName &&
+				aws.StringValue(configs.LaunchConfigurations[0].InstanceType) == instanceType {
+				log.Infof("BDD >> scaling group %v uses launch configuration %v", scalingGroupName, launchConfigName)
+				return nil
+			}
+		}
+		counter++
+		time.Sleep(DefaultWaiterInterval)
+	}
+}
+
+func (t *FunctionalTest) theScalingGroupShouldBeDeleted() error {
+	var (
+		counter int
+		asg     = autoscaling.New(t.AwsSession)
+	)
+
+	if t.ScalingGroupName == "" {
+		return errors.New("scaling group of the resource is unknown")
+	}
+
+	for {
+		if counter >= DefaultWaiterRetries {
+			return errors.New("waiter timed out waiting for scaling group deletion")
+		}
+		log.Infof("BDD >> waiting for scaling group %v to be deleted", t.ScalingGroupName)
+		groups, err := asg.DescribeAutoScalingGroups(&autoscaling.DescribeAutoScalingGroupsInput{
+			AutoScalingGroupNames: aws.StringSlice([]string{t.ScalingGroupName}),
+		})
+		if err != nil {
+			return err
+		}
+		if len(groups.AutoScalingGroups) == 0 {
+			log.Infof("BDD >> scaling group %v is deleted", t.ScalingGroupName)
+			return nil
+		}
+		counter++
+		time.Sleep(DefaultWaiterInterval)
+	}
+}
+
 func (t *FunctionalTest) nodesShouldBe(count int, state string) error {
 	return t.waitForNodeCountState(count, state, fmt.Sprintf("test=%v", t.ResourceName))
 }
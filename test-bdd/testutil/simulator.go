@@ -0,0 +1,258 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testutil
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/autoscaling/autoscalingiface"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// SimulatedNodeLabelKey is set on the nodes registered by the node simulator
+	SimulatedNodeLabelKey = "instancemgr.keikoproj.io/simulated"
+
+	DefaultSimulatorInterval = time.Second * 10
+)
+
+var instanceGroupResource = schema.GroupVersionResource{
+	Group:    "instancemgr.keikoproj.io",
+	Version:  "v1alpha1",
+	Resource: "instancegroups",
+}
+
+// NewAwsSession returns a session of the region, calls are made to endpoint when it is set, e.g. a localstack URL
+func NewAwsSession(region, endpoint string) (*session.Session, error) {
+	config := aws.NewConfig().WithRegion(region)
+	if endpoint != "" {
+		config = config.WithEndpoint(endpoint)
+	}
+	return session.NewSession(config)
+}
+
+// NodeSimulator registers a ready node for every instance of the scaling groups of instance groups, instances of
+// localstack or moto do not run and cannot join the cluster, nodes of instances which are terminated are removed
+type NodeSimulator struct {
+	KubeClient    kubernetes.Interface
+	DynamicClient dynamic.Interface
+	AsgClient     autoscalingiface.AutoScalingAPI
+	Ec2Client     ec2iface.EC2API
+	Interval      time.Duration
+}
+
+func NewNodeSimulator(kube kubernetes.Interface, dyn dynamic.Interface, sess *session.Session) *NodeSimulator {
+	return &NodeSimulator{
+		KubeClient:    kube,
+		DynamicClient: dyn,
+		AsgClient:     autoscaling.New(sess),
+		Ec2Client:     ec2.New(sess),
+		Interval:      DefaultSimulatorInterval,
+	}
+}
+
+// Start syncs the simulated nodes at every interval until stop is closed
+func (s *NodeSimulator) Start(stop <-chan struct{}) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for {
+		if err := s.Sync(); err != nil {
+			log.Errorf("BDD >> failed to sync simulated nodes: %v", err)
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync registers the nodes of running instances, renews their heartbeat so that they are not marked unreachable, and
+// removes the nodes of instances which are terminating or terminated
+func (s *NodeSimulator) Sync() error {
+	instanceGroups, err := s.DynamicClient.Resource(instanceGroupResource).Namespace(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		return errors.Wrap(err, "failed to list instance groups")
+	}
+
+	desired := make(map[string]*corev1.Node)
+	for _, ig := range instanceGroups.Items {
+		scalingGroupName, _, _ := unstructured.NestedString(ig.Object, "status", "activeScalingGroupName")
+		if scalingGroupName == "" {
+			continue
+		}
+		labels, _, _ := unstructured.NestedStringMap(ig.Object, "spec", "eks", "configuration", "labels")
+
+		nodes, err := s.scalingGroupNodes(scalingGroupName, labels)
+		if err != nil {
+			return err
+		}
+		for _, node := range nodes {
+			desired[node.GetName()] = node
+		}
+	}
+
+	existing, err := s.KubeClient.CoreV1().Nodes().List(metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%v=true", SimulatedNodeLabelKey),
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to list simulated nodes")
+	}
+
+	for i := range existing.Items {
+		node := &existing.Items[i]
+		if _, ok := desired[node.GetName()]; ok {
+			continue
+		}
+		if err := s.KubeClient.CoreV1().Nodes().Delete(node.GetName(), &metav1.DeleteOptions{}); err != nil && !kerrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to delete simulated node %v", node.GetName())
+		}
+		log.Infof("BDD >> removed simulated node %v", node.GetName())
+	}
+
+	for _, node := range desired {
+		if err := s.register(node); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *NodeSimulator) scalingGroupNodes(scalingGroupName string, labels map[string]string) ([]*corev1.Node, error) {
+	out, err := s.AsgClient.DescribeAutoScalingGroups(&autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: aws.StringSlice([]string{scalingGroupName}),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to describe scaling group %v", scalingGroupName)
+	}
+
+	instanceIds := make([]string, 0)
+	for _, group := range out.AutoScalingGroups {
+		for _, instance := range group.Instances {
+			switch aws.StringValue(instance.LifecycleState) {
+			case autoscaling.LifecycleStateInService, autoscaling.LifecycleStatePending:
+				instanceIds = append(instanceIds, aws.StringValue(instance.InstanceId))
+			}
+		}
+	}
+	if len(instanceIds) == 0 {
+		return nil, nil
+	}
+
+	nodes := make([]*corev1.Node, 0)
+	err = s.Ec2Client.DescribeInstancesPages(&ec2.DescribeInstancesInput{InstanceIds: aws.StringSlice(instanceIds)},
+		func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+			for _, reservation := range page.Reservations {
+				for _, instance := range reservation.Instances {
+					if aws.StringValue(instance.State.Name) != ec2.InstanceStateNameRunning && aws.StringValue(instance.State.Name) != ec2.InstanceStateNamePending {
+						continue
+					}
+					nodes = append(nodes, simulatedNode(instance, labels))
+				}
+			}
+			return true
+		})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to describe instances of scaling group %v", scalingGroupName)
+	}
+	return nodes, nil
+}
+
+func simulatedNode(instance *ec2.Instance, labels map[string]string) *corev1.Node {
+	var (
+		instanceID   = aws.StringValue(instance.InstanceId)
+		instanceType = aws.StringValue(instance.InstanceType)
+		zone         = aws.StringValue(instance.Placement.AvailabilityZone)
+		name         = aws.StringValue(instance.PrivateDnsName)
+	)
+	if name == "" {
+		name = instanceID
+	}
+
+	nodeLabels := map[string]string{
+		SimulatedNodeLabelKey:                    "true",
+		"beta.kubernetes.io/instance-type":       instanceType,
+		"node.kubernetes.io/instance-type":       instanceType,
+		"failure-domain.beta.kubernetes.io/zone": zone,
+	}
+	for k, v := range labels {
+		nodeLabels[k] = v
+	}
+
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: nodeLabels,
+		},
+		Spec: corev1.NodeSpec{
+			ProviderID: fmt.Sprintf("aws:///%v/%v", zone, instanceID),
+		},
+	}
+}
+
+func (s *NodeSimulator) register(node *corev1.Node) error {
+	nodes := s.KubeClient.CoreV1().Nodes()
+
+	current, err := nodes.Get(node.GetName(), metav1.GetOptions{})
+	if kerrors.IsNotFound(err) {
+		current, err = nodes.Create(node)
+		if err != nil {
+			return errors.Wrapf(err, "failed to create simulated node %v", node.GetName())
+		}
+		log.Infof("BDD >> registered simulated node %v", node.GetName())
+	} else if err != nil {
+		return errors.Wrapf(err, "failed to get simulated node %v", node.GetName())
+	}
+
+	now := metav1.Now()
+	current.Status.Conditions = []corev1.NodeCondition{
+		{
+			Type:               corev1.NodeReady,
+			Status:             corev1.ConditionTrue,
+			Reason:             "KubeletReady",
+			Message:            "simulated node is ready",
+			LastHeartbeatTime:  now,
+			LastTransitionTime: readySince(current, now),
+		},
+	}
+	if _, err := nodes.UpdateStatus(current); err != nil && !kerrors.IsConflict(err) {
+		return errors.Wrapf(err, "failed to update status of simulated node %v", node.GetName())
+	}
+	return nil
+}
+
+func readySince(node *corev1.Node, now metav1.Time) metav1.Time {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady && condition.Status == corev1.ConditionTrue {
+			return condition.LastTransitionTime
+		}
+	}
+	return now
+}